@@ -0,0 +1,391 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	SourcifyAPI  = "https://sourcify.dev/server/v2/contract"
+	EtherscanAPI = "https://api.etherscan.io/v2/api"
+
+	// 默认同一个未知合约在近期出现多少次后去拉取 ABI
+	DefaultABIFetchThreshold = 5
+	// 默认每秒最多请求次数（Etherscan 免费 key 为 5 次/秒）
+	DefaultABIRequestsPerSecond = 4
+	// 合约未验证时，多久之后再重试
+	ABINegativeCacheTTL = 24 * time.Hour
+	// 待拉取队列长度，满了直接丢弃，下次出现时会再次入队
+	ABIFetchQueueSize = 64
+)
+
+// errABINotVerified 合约在 Sourcify 和 Etherscan 上都没有验证
+var errABINotVerified = errors.New("合约未验证")
+
+// ABIFetcher 统计区块中反复出现的未知合约，从 Sourcify / Etherscan 拉取已验证的 ABI 并热加载到 ABIRegistry
+// 拉取结果（包括"未验证"）缓存在磁盘上，重启后不会重复请求
+// 遇到代理合约时解析出实现地址，改为拉取实现合约的 ABI
+type ABIFetcher struct {
+	cfg      ABIConfig
+	client   *ethclient.Client
+	registry *ABIRegistry
+	chainID  *big.Int
+	apiKey   string
+	http     *http.Client
+
+	mu      sync.Mutex
+	seen    map[common.Address]int       // 未知合约的出现次数
+	missing map[common.Address]time.Time // 确认未验证的合约及确认时间
+	queued  map[common.Address]bool
+	queue   chan common.Address
+	proxies map[common.Address]common.Address // 已解析的代理 -> 实现，持久化到 proxies.json
+}
+
+// NewABIFetcher 创建 ABI 拉取器，并把磁盘缓存中已有的 ABI 加载到 registry
+func NewABIFetcher(cfg ABIConfig, client *ethclient.Client, registry *ABIRegistry, chainID *big.Int) *ABIFetcher {
+	f := &ABIFetcher{
+		cfg:      cfg,
+		client:   client,
+		registry: registry,
+		chainID:  chainID,
+		http:     &http.Client{Timeout: CONNECTION_TIMEOUT},
+		seen:     make(map[common.Address]int),
+		missing:  make(map[common.Address]time.Time),
+		queued:   make(map[common.Address]bool),
+		queue:    make(chan common.Address, ABIFetchQueueSize),
+		proxies:  make(map[common.Address]common.Address),
+	}
+	if cfg.EtherscanKeyEnv != "" {
+		f.apiKey = os.Getenv(cfg.EtherscanKeyEnv)
+	}
+	f.loadCache()
+	return f
+}
+
+// Enrichment 实现 Enricher：只看交易的目标地址，不需要收据
+func (f *ABIFetcher) Enrichment() EnrichLevel { return EnrichTransactions }
+
+// OnBlock 实现 BlockAnalyzer：统计带 calldata 的交易目标中没有 ABI 的合约
+func (f *ABIFetcher) OnBlock(data *BlockData) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, tx := range data.Block.Transactions() {
+		to := tx.To()
+		if to == nil || len(tx.Data()) < 4 {
+			continue
+		}
+		f.observe(*to)
+	}
+}
+
+// observe 记录一次出现，达到阈值后放入待拉取队列（调用方持有锁）
+// 已知的代理合约统计的是它的实现合约
+func (f *ABIFetcher) observe(addr common.Address) {
+	if impl, ok := f.registry.Implementation(addr); ok {
+		addr = impl
+	}
+	if _, ok := f.registry.Get(addr); ok || f.queued[addr] {
+		return
+	}
+	if t, ok := f.missing[addr]; ok && time.Since(t) < ABINegativeCacheTTL {
+		return
+	}
+	f.seen[addr]++
+	if f.seen[addr] < f.cfg.FetchThreshold {
+		return
+	}
+	f.enqueue(addr)
+}
+
+// Enqueue 不经过阈值直接安排拉取（例如代理升级后的新实现）
+func (f *ABIFetcher) Enqueue(addr common.Address) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.registry.Get(addr); ok || f.queued[addr] {
+		return
+	}
+	f.enqueue(addr)
+}
+
+// Fetch 立即拉取（不排队、不限速），代理合约同时拉取实现合约；用于 decode 等一次性的命令行工具
+func (f *ABIFetcher) Fetch(ctx context.Context, addr common.Address) {
+	known := func(a common.Address) bool {
+		_, ok := f.registry.Get(a)
+		return ok || f.Unverified(a)
+	}
+	if _, ok := f.registry.Implementation(addr); !ok && !known(addr) {
+		f.fetchAndLoad(ctx, addr)
+	}
+	if impl, ok := f.registry.Implementation(addr); ok && !known(impl) {
+		f.fetchAndLoad(ctx, impl)
+	}
+}
+
+// Unverified 是否已确认合约在 Sourcify / Etherscan 上都没有验证；f 为 nil 或还没查询过时返回 false
+func (f *ABIFetcher) Unverified(addr common.Address) bool {
+	if f == nil {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.missing[addr]
+	return ok
+}
+
+// CachedABI 磁盘缓存中合约的原始 ABI JSON，代理合约返回实现合约的 ABI；未缓存或未验证时返回 false
+func (f *ABIFetcher) CachedABI(addr common.Address) ([]byte, bool) {
+	if impl, ok := f.registry.Implementation(addr); ok {
+		addr = impl
+	}
+	raw, err := os.ReadFile(filepath.Join(f.cacheDir(), strings.ToLower(addr.Hex())+".json"))
+	if err != nil || len(raw) == 0 {
+		return nil, false
+	}
+	return raw, true
+}
+
+// enqueue 放入待拉取队列，队列满时丢弃（调用方持有锁）
+func (f *ABIFetcher) enqueue(addr common.Address) {
+	select {
+	case f.queue <- addr:
+		f.queued[addr] = true
+		delete(f.seen, addr)
+	default:
+	}
+}
+
+// Run 按限速依次拉取队列中的合约 ABI，直到 ctx 取消
+func (f *ABIFetcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / f.cfg.RequestsPerSecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case addr := <-f.queue:
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+			f.fetchAndLoad(ctx, addr)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (f *ABIFetcher) fetchAndLoad(ctx context.Context, addr common.Address) {
+	// 代理合约本身的 ABI 只有升级相关的函数，解码需要的是实现合约的 ABI
+	info, err := ResolveProxy(ctx, f.client, addr, nil)
+	if err != nil {
+		log.Printf("⚠️  检查 %s 是否为代理合约失败: %v", addr.Hex(), err)
+	}
+	raw, source, err := f.fetch(ctx, addr)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.queued, addr)
+
+	if info != nil {
+		log.Printf("🔗 %s 是 %s 代理，实现合约: %s", addr.Hex(), info.Kind, info.Implementation.Hex())
+		f.registry.SetImplementation(addr, info.Implementation)
+		f.proxies[addr] = info.Implementation
+		f.writeProxies()
+		if _, ok := f.registry.Get(info.Implementation); !ok && !f.queued[info.Implementation] {
+			f.enqueue(info.Implementation)
+		}
+	}
+
+	switch {
+	case errors.Is(err, errABINotVerified):
+		f.missing[addr] = time.Now()
+		f.writeCache(addr, nil)
+		return
+	case err != nil:
+		log.Printf("⚠️  拉取 %s 的 ABI 失败: %v", addr.Hex(), err)
+		return
+	}
+	parsed, err := abi.JSON(bytes.NewReader(raw))
+	if err != nil {
+		log.Printf("⚠️  解析 %s 的 ABI 失败: %v", addr.Hex(), err)
+		return
+	}
+	f.registry.Register(addr, &parsed)
+	f.writeCache(addr, raw)
+	log.Printf("📥 已从 %s 加载 %s 的 ABI（%d 个函数 / %d 个事件）", source, addr.Hex(), len(parsed.Methods), len(parsed.Events))
+}
+
+// fetch 先查 Sourcify（免费、不需要 key），再查 Etherscan（需要 key）
+func (f *ABIFetcher) fetch(ctx context.Context, addr common.Address) (json.RawMessage, string, error) {
+	raw, err := f.fetchSourcify(ctx, addr)
+	if err == nil {
+		return raw, "Sourcify", nil
+	}
+	if !errors.Is(err, errABINotVerified) {
+		log.Printf("⚠️  Sourcify 查询 %s 失败: %v", addr.Hex(), err)
+	}
+	if f.apiKey == "" {
+		return nil, "", err
+	}
+	raw, err = f.fetchEtherscan(ctx, addr)
+	return raw, "Etherscan", err
+}
+
+func (f *ABIFetcher) fetchSourcify(ctx context.Context, addr common.Address) (json.RawMessage, error) {
+	url := fmt.Sprintf("%s/%s/%s?fields=abi", SourcifyAPI, f.chainID, addr.Hex())
+	body, status, err := f.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound {
+		return nil, errABINotVerified
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", status)
+	}
+	var resp struct {
+		ABI json.RawMessage `json:"abi"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.ABI) == 0 || string(resp.ABI) == "null" {
+		return nil, errABINotVerified
+	}
+	return resp.ABI, nil
+}
+
+func (f *ABIFetcher) fetchEtherscan(ctx context.Context, addr common.Address) (json.RawMessage, error) {
+	url := fmt.Sprintf("%s?chainid=%s&module=contract&action=getabi&address=%s&apikey=%s",
+		EtherscanAPI, f.chainID, addr.Hex(), f.apiKey)
+	body, status, err := f.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", status)
+	}
+	// 成功时 result 是 ABI 的 JSON 字符串，失败时是错误说明
+	var resp struct {
+		Status string `json:"status"`
+		Result string `json:"result"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Status != "1" {
+		if strings.Contains(resp.Result, "not verified") {
+			return nil, errABINotVerified
+		}
+		return nil, fmt.Errorf("Etherscan: %s", resp.Result)
+	}
+	return json.RawMessage(resp.Result), nil
+}
+
+func (f *ABIFetcher) get(ctx context.Context, url string) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := f.http.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return body, resp.StatusCode, err
+}
+
+// ------------------------------------------------
+// 磁盘缓存：<cache_dir>/<chainID>/<address>.json，未验证的合约写入空文件
+// ------------------------------------------------
+
+func (f *ABIFetcher) cacheDir() string {
+	return filepath.Join(f.cfg.CacheDir, f.chainID.String())
+}
+
+// loadCache 启动时加载缓存
+func (f *ABIFetcher) loadCache() {
+	if data, err := os.ReadFile(filepath.Join(f.cacheDir(), "proxies.json")); err == nil {
+		if err := json.Unmarshal(data, &f.proxies); err != nil {
+			log.Printf("⚠️  代理缓存无法解析，已忽略: %v", err)
+		}
+		for proxy, impl := range f.proxies {
+			f.registry.SetImplementation(proxy, impl)
+		}
+	}
+
+	entries, err := os.ReadDir(f.cacheDir())
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".json")
+		if e.IsDir() || name == e.Name() || !common.IsHexAddress(name) {
+			continue
+		}
+		addr := common.HexToAddress(name)
+		path := filepath.Join(f.cacheDir(), e.Name())
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if len(raw) == 0 {
+			if info, err := e.Info(); err == nil {
+				f.missing[addr] = info.ModTime()
+			}
+			continue
+		}
+		parsed, err := abi.JSON(bytes.NewReader(raw))
+		if err != nil {
+			log.Printf("⚠️  缓存的 ABI 无法解析，已忽略: %s", path)
+			continue
+		}
+		f.registry.Register(addr, &parsed)
+	}
+	if n := f.registry.Len(); n > 0 {
+		log.Printf("📥 已从缓存加载 %d 个合约的 ABI", n)
+	}
+}
+
+func (f *ABIFetcher) writeCache(addr common.Address, raw []byte) {
+	if err := os.MkdirAll(f.cacheDir(), 0o755); err != nil {
+		log.Printf("⚠️  创建 ABI 缓存目录失败: %v", err)
+		return
+	}
+	path := filepath.Join(f.cacheDir(), strings.ToLower(addr.Hex())+".json")
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		log.Printf("⚠️  写入 ABI 缓存失败: %v", err)
+	}
+}
+
+// writeProxies 保存代理 -> 实现的映射（调用方持有锁）
+func (f *ABIFetcher) writeProxies() {
+	data, err := json.MarshalIndent(f.proxies, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(f.cacheDir(), 0o755); err != nil {
+		log.Printf("⚠️  创建 ABI 缓存目录失败: %v", err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(f.cacheDir(), "proxies.json"), data, 0o644); err != nil {
+		log.Printf("⚠️  写入代理缓存失败: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ABIRegistry 合约地址 -> ABI，运行中可以随时热加载新的 ABI，解码方立即生效
+// 代理合约可以指向实现合约，查询代理时优先返回实现合约的 ABI
+// 加载的 ABI 中的事件和函数同时汇总到 events / methods，其它合约的同签名事件和调用也能解码
+type ABIRegistry struct {
+	mu      sync.RWMutex
+	abis    map[common.Address]*abi.ABI
+	impls   map[common.Address]common.Address // 代理 -> 实现
+	events  *EventRegistry
+	methods *MethodRegistry
+}
+
+func NewABIRegistry() *ABIRegistry {
+	return &ABIRegistry{
+		abis:    make(map[common.Address]*abi.ABI),
+		impls:   make(map[common.Address]common.Address),
+		events:  NewEventRegistry(),
+		methods: NewMethodRegistry(),
+	}
+}
+
+// Register 加载（或替换）某个地址的 ABI
+func (r *ABIRegistry) Register(addr common.Address, a *abi.ABI) {
+	r.mu.Lock()
+	r.abis[addr] = a
+	r.mu.Unlock()
+	r.events.AddABI(a)
+	r.methods.AddABI(a)
+}
+
+// Events 按 topic0 汇总的事件注册表
+func (r *ABIRegistry) Events() *EventRegistry {
+	return r.events
+}
+
+// Methods 按 selector 汇总的函数注册表
+func (r *ABIRegistry) Methods() *MethodRegistry {
+	return r.methods
+}
+
+// SetImplementation 记录代理合约当前的实现地址
+func (r *ABIRegistry) SetImplementation(proxy, impl common.Address) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.impls[proxy] = impl
+}
+
+// Implementation 查询代理合约的实现地址
+func (r *ABIRegistry) Implementation(proxy common.Address) (common.Address, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	impl, ok := r.impls[proxy]
+	return impl, ok
+}
+
+// Get 查询地址的 ABI，代理合约在实现合约的 ABI 已加载时返回实现合约的 ABI
+func (r *ABIRegistry) Get(addr common.Address) (*abi.ABI, bool) {
+	if r == nil {
+		return nil, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if impl, ok := r.impls[addr]; ok {
+		if a, ok := r.abis[impl]; ok {
+			return a, true
+		}
+	}
+	a, ok := r.abis[addr]
+	return a, ok
+}
+
+// Len 已加载的 ABI 数量
+func (r *ABIRegistry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.abis)
+}
+
+// Method 按 calldata 的前 4 字节查找调用的函数
+func (r *ABIRegistry) Method(to common.Address, data []byte) (*abi.Method, bool) {
+	a, ok := r.Get(to)
+	if !ok || len(data) < 4 {
+		return nil, false
+	}
+	m, err := a.MethodById(data[:4])
+	if err != nil {
+		return nil, false
+	}
+	return m, true
+}
+
+// Event 按 topic0 查找日志对应的事件
+func (r *ABIRegistry) Event(l types.Log) (*abi.Event, bool) {
+	a, ok := r.Get(l.Address)
+	if !ok || len(l.Topics) == 0 {
+		return nil, false
+	}
+	ev, err := a.EventByID(l.Topics[0])
+	if err != nil {
+		return nil, false
+	}
+	return ev, true
+}
+
+// DecodeLog 解码日志：优先用该合约自己的 ABI（参数名最准确），其次按 topic0 查事件注册表
+// r 为 nil 时只使用内置的常见事件
+func (r *ABIRegistry) DecodeLog(l types.Log) (*DecodedLog, bool) {
+	if r == nil {
+		return builtinEvents.Decode(l)
+	}
+	if ev, ok := r.Event(l); ok {
+		if d, ok := decodeEvent(*ev, l); ok {
+			return d, true
+		}
+	}
+	return r.events.Decode(l)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ------------------------------------------------
+// 管理接口：运行中增删关注地址、修改提醒阈值和路由、重新处理历史区块、查看各处理队列的积压，
+// 长时间运行的实例不用改配置文件重启就能调整；只有配置了 admin 权限的 API key 或客户端证书（mTLS）时才启用，只有 admin 权限的调用方能访问
+// 通过接口做的修改只保存在内存中，每次修改输出一条 admin 事件留档
+// ------------------------------------------------
+
+const (
+	// ⚠️ 一次最多重新处理的区块数，范围太大时分多次请求，避免长时间占住区块拉取
+	AdminMaxReplay = 10000
+)
+
+// AdminChange 一次管理操作
+type AdminChange struct {
+	Action string      `json:"action"` // watch_add / watch_remove / alerts / replay
+	Detail interface{} `json:"detail"`
+}
+
+// WatchEntry /api/admin/watch 中的一个地址
+type WatchEntry struct {
+	Address common.Address `json:"address"`
+	Source  string         `json:"source"` // config / shadow / api
+}
+
+// AlertsUpdate PUT /api/admin/alerts 的请求体，未填写的字段不修改
+type AlertsUpdate struct {
+	AlertUSD *float64       `json:"alert_usd"` // 大额转账 / swap 提醒阈值，0 关闭
+	Routing  *RoutingConfig `json:"routing"`   // 替换整个路由矩阵
+}
+
+// AdminQueues GET /api/admin/queues 的内容
+type AdminQueues struct {
+	Bus     []BusQueueStatus  `json:"bus"`
+	Fetcher FetcherStatus     `json:"fetcher"`
+	Outputs []SinkPauseStatus `json:"outputs"`
+}
+
+// AdminAPI 运行时管理
+type AdminAPI struct {
+	watch   *Watchlist
+	alert   *ValueAlert
+	fetcher *BlockFetcher
+	bus     *EventBus
+	pause   *OutputPause
+	outputs []OutputConfig // 校验路由中的输出名称
+
+	onChange func(AdminChange)
+}
+
+// NewAdminAPI 创建管理接口；onChange 为 nil 时使用默认输出
+func NewAdminAPI(watch *Watchlist, alert *ValueAlert, fetcher *BlockFetcher, bus *EventBus, pause *OutputPause, outputs []OutputConfig, onChange func(AdminChange)) *AdminAPI {
+	if onChange == nil {
+		onChange = PrintAdminChange
+	}
+	return &AdminAPI{watch: watch, alert: alert, fetcher: fetcher, bus: bus, pause: pause, outputs: outputs, onChange: onChange}
+}
+
+// RegisterAPI 注册管理接口
+//
+//	GET    /api/admin/watch            全部关注地址及来源
+//	POST   /api/admin/watch            添加关注地址 {"addresses": ["0x..."]}
+//	DELETE /api/admin/watch/{address}  移除关注地址
+//	GET    /api/admin/alerts           当前提醒阈值和路由
+//	PUT    /api/admin/alerts           修改提醒阈值 / 路由 {"alert_usd": 50000, "routing": {...}}
+//	POST   /api/admin/replay           重新处理历史区块 {"from": 100, "to": 200}
+//	GET    /api/admin/queues           事件总线、区块拉取和输出的队列积压
+//
+// 没有配置 admin 权限的 API key 或 tls.client_ca 时不注册，无法鉴权的管理接口不对外开放
+func (a *AdminAPI) RegisterAPI(api *APIServer) {
+	if !api.AdminAllowed() {
+		if api.addr != "" {
+			log.Printf("ℹ️  没有配置 admin 权限的 api_auth.keys 或 tls.client_ca，管理接口 /api/admin/ 不启用")
+		}
+		return
+	}
+	api.Handle("GET /api/admin/watch", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, a.watchList())
+	})
+	api.Handle("POST /api/admin/watch", a.handleWatchAdd)
+	api.Handle("DELETE /api/admin/watch/{address}", a.handleWatchRemove)
+	api.Handle("GET /api/admin/alerts", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"alert_usd": a.alert.Threshold(), "routing": currentRouting().Status()})
+	})
+	api.Handle("PUT /api/admin/alerts", a.handleAlerts)
+	api.Handle("POST /api/admin/replay", a.handleReplay)
+	api.Handle("GET /api/admin/queues", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, AdminQueues{Bus: a.bus.Stats(), Fetcher: a.fetcher.Status(), Outputs: a.pause.Status()})
+	})
+}
+
+// watchList 按地址排序的关注列表
+func (a *AdminAPI) watchList() []WatchEntry {
+	snap := a.watch.Snapshot()
+	out := make([]WatchEntry, 0, len(snap))
+	for addr, src := range snap {
+		out = append(out, WatchEntry{Address: addr, Source: src})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Address.Hex() < out[j].Address.Hex() })
+	return out
+}
+
+func (a *AdminAPI) handleWatchAdd(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Addresses []string `json:"addresses"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Addresses) == 0 {
+		writeError(w, http.StatusBadRequest, `请求体应为 {"addresses": ["0x..."]}`)
+		return
+	}
+	for _, s := range req.Addresses {
+		if !common.IsHexAddress(s) {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("地址格式错误: %q", s))
+			return
+		}
+	}
+	var added []common.Address
+	for _, s := range req.Addresses {
+		if addr := common.HexToAddress(s); a.watch.Add(addr, WatchSourceAPI) {
+			added = append(added, addr)
+		}
+	}
+	if len(added) > 0 {
+		a.onChange(AdminChange{Action: "watch_add", Detail: added})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"added": len(added), "total": a.watch.Len()})
+}
+
+func (a *AdminAPI) handleWatchRemove(w http.ResponseWriter, r *http.Request) {
+	s := r.PathValue("address")
+	if !common.IsHexAddress(s) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("地址格式错误: %q", s))
+		return
+	}
+	addr := common.HexToAddress(s)
+	src := a.watch.Remove(addr)
+	if src == "" {
+		writeError(w, http.StatusNotFound, "地址不在关注列表中")
+		return
+	}
+	a.onChange(AdminChange{Action: "watch_remove", Detail: WatchEntry{Address: addr, Source: src}})
+	writeJSON(w, http.StatusOK, WatchEntry{Address: addr, Source: src})
+}
+
+func (a *AdminAPI) handleAlerts(w http.ResponseWriter, r *http.Request) {
+	var req AlertsUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "请求体格式错误: "+err.Error())
+		return
+	}
+	if req.AlertUSD != nil && *req.AlertUSD < 0 {
+		writeError(w, http.StatusBadRequest, "alert_usd 不能为负数")
+		return
+	}
+	if req.Routing != nil {
+		if err := req.Routing.validate(a.outputs); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if req.AlertUSD != nil {
+		a.alert.SetThreshold(*req.AlertUSD)
+	}
+	if req.Routing != nil {
+		SetRouting(NewRouter(*req.Routing))
+	}
+	a.onChange(AdminChange{Action: "alerts", Detail: req})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"alert_usd": a.alert.Threshold(), "routing": currentRouting().Status()})
+}
+
+func (a *AdminAPI) handleReplay(w http.ResponseWriter, r *http.Request) {
+	var req blockRange
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, `请求体应为 {"from": <区块>, "to": <区块>}`)
+		return
+	}
+	if req.To < req.From || req.To-req.From+1 > AdminMaxReplay {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("区块范围无效，需要 from <= to 且不超过 %d 个区块", AdminMaxReplay))
+		return
+	}
+	if err := a.fetcher.RequestReplay(req.From, req.To); err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	a.onChange(AdminChange{Action: "replay", Detail: req})
+	writeJSON(w, http.StatusAccepted, req)
+}
+
+// PrintAdminChange 默认的管理操作输出
+func PrintAdminChange(c AdminChange) {
+	var desc string
+	switch c.Action {
+	case "watch_add":
+		desc = fmt.Sprintf("添加关注地址 %d 个", len(c.Detail.([]common.Address)))
+	case "watch_remove":
+		e := c.Detail.(WatchEntry)
+		desc = fmt.Sprintf("移除关注地址 %s（来源 %s）", e.Address.Hex(), e.Source)
+	case "alerts":
+		desc = "修改提醒设置"
+	case "replay":
+		br := c.Detail.(blockRange)
+		desc = fmt.Sprintf("重新处理区块 %d - %d", br.From, br.To)
+	default:
+		desc = c.Action
+	}
+	summary := "🛠️  [Admin] " + desc
+	Emit(Event{Type: "admin", Summary: summary, Text: summary, Data: c})
+}
@@ -0,0 +1,442 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ------------------------------------------------
+// 聚合器路由解码：把 1inch / 0x / ParaSwap 路由合约的调用展开成实际经过的路线
+// （每一跳的场所、池子和代币）以及输入数量、最少输出
+// ------------------------------------------------
+
+const (
+	// Pending 聚合器交易达到该金额（美元）时输出
+	// ⚠️ 无法估值的代币（价格源中没有）不输出
+	AggregatorReportMinUSD = 50_000
+)
+
+// aggregatorMethodsABI 聚合器路由的函数，加入内置函数表
+// 1inch v6 用 uint256 同时编码地址和标志位，池子参数的低 160 位是池子地址
+const aggregatorMethodsABI = `[
+	{"type":"function","name":"swap","inputs":[{"name":"executor","type":"address"},{"name":"desc","type":"tuple","components":[{"name":"srcToken","type":"address"},{"name":"dstToken","type":"address"},{"name":"srcReceiver","type":"address"},{"name":"dstReceiver","type":"address"},{"name":"amount","type":"uint256"},{"name":"minReturnAmount","type":"uint256"},{"name":"flags","type":"uint256"}]},{"name":"permit","type":"bytes"},{"name":"data","type":"bytes"}]},
+	{"type":"function","name":"swap","inputs":[{"name":"executor","type":"address"},{"name":"desc","type":"tuple","components":[{"name":"srcToken","type":"address"},{"name":"dstToken","type":"address"},{"name":"srcReceiver","type":"address"},{"name":"dstReceiver","type":"address"},{"name":"amount","type":"uint256"},{"name":"minReturnAmount","type":"uint256"},{"name":"flags","type":"uint256"}]},{"name":"data","type":"bytes"}]},
+	{"type":"function","name":"unoswap","inputs":[{"name":"srcToken","type":"address"},{"name":"amount","type":"uint256"},{"name":"minReturn","type":"uint256"},{"name":"pools","type":"uint256[]"}]},
+	{"type":"function","name":"unoswapTo","inputs":[{"name":"recipient","type":"address"},{"name":"srcToken","type":"address"},{"name":"amount","type":"uint256"},{"name":"minReturn","type":"uint256"},{"name":"pools","type":"uint256[]"}]},
+	{"type":"function","name":"uniswapV3Swap","inputs":[{"name":"amount","type":"uint256"},{"name":"minReturn","type":"uint256"},{"name":"pools","type":"uint256[]"}]},
+	{"type":"function","name":"uniswapV3SwapTo","inputs":[{"name":"recipient","type":"address"},{"name":"amount","type":"uint256"},{"name":"minReturn","type":"uint256"},{"name":"pools","type":"uint256[]"}]},
+	{"type":"function","name":"unoswap","inputs":[{"name":"token","type":"uint256"},{"name":"amount","type":"uint256"},{"name":"minReturn","type":"uint256"},{"name":"dex","type":"uint256"}]},
+	{"type":"function","name":"unoswap2","inputs":[{"name":"token","type":"uint256"},{"name":"amount","type":"uint256"},{"name":"minReturn","type":"uint256"},{"name":"dex","type":"uint256"},{"name":"dex2","type":"uint256"}]},
+	{"type":"function","name":"unoswap3","inputs":[{"name":"token","type":"uint256"},{"name":"amount","type":"uint256"},{"name":"minReturn","type":"uint256"},{"name":"dex","type":"uint256"},{"name":"dex2","type":"uint256"},{"name":"dex3","type":"uint256"}]},
+	{"type":"function","name":"unoswapTo","inputs":[{"name":"to","type":"uint256"},{"name":"token","type":"uint256"},{"name":"amount","type":"uint256"},{"name":"minReturn","type":"uint256"},{"name":"dex","type":"uint256"}]},
+	{"type":"function","name":"ethUnoswap","inputs":[{"name":"minReturn","type":"uint256"},{"name":"dex","type":"uint256"}]},
+	{"type":"function","name":"ethUnoswapTo","inputs":[{"name":"to","type":"uint256"},{"name":"minReturn","type":"uint256"},{"name":"dex","type":"uint256"}]},
+	{"type":"function","name":"transformERC20","inputs":[{"name":"inputToken","type":"address"},{"name":"outputToken","type":"address"},{"name":"inputTokenAmount","type":"uint256"},{"name":"minOutputTokenAmount","type":"uint256"},{"name":"transformations","type":"tuple[]","components":[{"name":"deploymentNonce","type":"uint32"},{"name":"data","type":"bytes"}]}]},
+	{"type":"function","name":"sellToUniswap","inputs":[{"name":"tokens","type":"address[]"},{"name":"sellAmount","type":"uint256"},{"name":"minBuyAmount","type":"uint256"},{"name":"isSushi","type":"bool"}]},
+	{"type":"function","name":"sellTokenForTokenToUniswapV3","inputs":[{"name":"encodedPath","type":"bytes"},{"name":"sellAmount","type":"uint256"},{"name":"minBuyAmount","type":"uint256"},{"name":"recipient","type":"address"}]},
+	{"type":"function","name":"sellEthForTokenToUniswapV3","inputs":[{"name":"encodedPath","type":"bytes"},{"name":"minBuyAmount","type":"uint256"},{"name":"recipient","type":"address"}]},
+	{"type":"function","name":"sellTokenForEthToUniswapV3","inputs":[{"name":"encodedPath","type":"bytes"},{"name":"sellAmount","type":"uint256"},{"name":"minBuyAmount","type":"uint256"},{"name":"recipient","type":"address"}]},
+	{"type":"function","name":"multiplexBatchSellTokenForToken","inputs":[{"name":"inputToken","type":"address"},{"name":"outputToken","type":"address"},{"name":"calls","type":"tuple[]","components":[{"name":"id","type":"uint8"},{"name":"sellAmount","type":"uint256"},{"name":"data","type":"bytes"}]},{"name":"sellAmount","type":"uint256"},{"name":"minBuyAmount","type":"uint256"}]},
+	{"type":"function","name":"simpleSwap","inputs":[{"name":"data","type":"tuple","components":[{"name":"fromToken","type":"address"},{"name":"toToken","type":"address"},{"name":"fromAmount","type":"uint256"},{"name":"toAmount","type":"uint256"},{"name":"expectedAmount","type":"uint256"},{"name":"callees","type":"address[]"},{"name":"exchangeData","type":"bytes"},{"name":"startIndexes","type":"uint256[]"},{"name":"values","type":"uint256[]"},{"name":"beneficiary","type":"address"},{"name":"partner","type":"address"},{"name":"feePercent","type":"uint256"},{"name":"permit","type":"bytes"},{"name":"deadline","type":"uint256"},{"name":"uuid","type":"bytes16"}]}]},
+	{"type":"function","name":"multiSwap","inputs":[{"name":"data","type":"tuple","components":[{"name":"fromToken","type":"address"},{"name":"fromAmount","type":"uint256"},{"name":"toAmount","type":"uint256"},{"name":"expectedAmount","type":"uint256"},{"name":"beneficiary","type":"address"},{"name":"path","type":"tuple[]","components":[{"name":"to","type":"address"},{"name":"totalNetworkFee","type":"uint256"},{"name":"adapters","type":"tuple[]","components":[{"name":"adapter","type":"address"},{"name":"percent","type":"uint256"},{"name":"networkFee","type":"uint256"},{"name":"route","type":"tuple[]","components":[{"name":"index","type":"uint256"},{"name":"targetExchange","type":"address"},{"name":"percent","type":"uint256"},{"name":"payload","type":"bytes"},{"name":"networkFee","type":"uint256"}]}]}]},{"name":"partner","type":"address"},{"name":"feePercent","type":"uint256"},{"name":"permit","type":"bytes"},{"name":"deadline","type":"uint256"},{"name":"uuid","type":"bytes16"}]}]},
+	{"type":"function","name":"swapOnUniswap","inputs":[{"name":"amountIn","type":"uint256"},{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"}]},
+	{"type":"function","name":"swapOnUniswapV2Fork","inputs":[{"name":"tokenIn","type":"address"},{"name":"amountIn","type":"uint256"},{"name":"amountOutMin","type":"uint256"},{"name":"weth","type":"address"},{"name":"pools","type":"uint256[]"}]}
+]`
+
+var (
+	// 主网聚合器路由合约
+	aggregatorRouters = map[common.Address]string{
+		common.HexToAddress("0x1111111254EEB25477B68fb85Ed929f73A960582"): "1inch v5",
+		common.HexToAddress("0x111111125421cA6dc452d289314280a0f8842A65"): "1inch v6",
+		common.HexToAddress("0xDef1C0ded9bec7F1a1670819833240f027b25EfF"): "0x",
+		common.HexToAddress("0xDEF171Fe48CF0115B1d80b88dc8eAB59176FEe57"): "ParaSwap v5",
+	}
+
+	// 0x multiplex 子调用的类型（MultiplexSubcall 枚举）
+	zeroExMultiplexVenues = []string{"Invalid", "RFQ", "OTC", "UniswapV2", "UniswapV3", "LiquidityProvider", "TransformERC20", "BatchSell", "MultiHopSell"}
+
+	addressMask = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 160), big.NewInt(1))
+)
+
+// RouteHop 路线中的一跳；不知道的代币为零地址
+type RouteHop struct {
+	Venue    string         `json:"venue"`
+	Pool     common.Address `json:"pool"` // 池子或交易合约，不知道时为零地址
+	TokenIn  common.Address `json:"token_in"`
+	TokenOut common.Address `json:"token_out"`
+
+	pair bool // 池子实现了 token0() / token1()（Uniswap V2 / V3 风格），可以查询代币补全路线
+	dir  int  // 1: token0 -> token1，-1: token1 -> token0，0: 未知
+}
+
+// AggregatorRoute 一次聚合器兑换
+type AggregatorRoute struct {
+	Aggregator string         `json:"aggregator"`
+	Function   string         `json:"function"`
+	TokenIn    common.Address `json:"token_in"` // 原生 ETH 为 0xEeee...
+	TokenOut   common.Address `json:"token_out"`
+	AmountIn   *big.Int       `json:"amount_in"`
+	MinOut     *big.Int       `json:"min_out,omitempty"`
+	Recipient  common.Address `json:"recipient"` // 没有指定时为零地址（发给调用者）
+	Hops       []RouteHop     `json:"hops"`
+	ValueUSD   float64        `json:"value_usd"`
+	Priced     bool           `json:"priced"`
+	Desc       string         `json:"desc"`
+}
+
+// String 渲染为一行：1inch v5 unoswap: 1000 USDC -> ≥0.38 WETH | UniswapV2 0x... -> UniswapV3 0x...
+func (r AggregatorRoute) String() string {
+	hops := make([]string, len(r.Hops))
+	for i, h := range r.Hops {
+		hops[i] = h.Venue
+		if h.Pool != (common.Address{}) {
+			hops[i] += " " + h.Pool.Hex()
+		}
+	}
+	return fmt.Sprintf("%s %s: %s | %s", r.Aggregator, r.Function, r.Desc, strings.Join(hops, " -> "))
+}
+
+// PendingAggregatorSwap 交易池中经过聚合器的交易
+type PendingAggregatorSwap struct {
+	Hash   common.Hash       `json:"hash"`
+	From   common.Address    `json:"from"`
+	Routes []AggregatorRoute `json:"routes"`
+}
+
+// AggregatorDecoder 解码 Pending 交易中的聚合器调用，补全路线中的代币并估值
+type AggregatorDecoder struct {
+	signer types.Signer
+	abis   *ABIRegistry
+	tokens *TokenCache
+	values *Valuator
+	weth   common.Address
+
+	onSwap func(PendingAggregatorSwap)
+}
+
+// NewAggregatorDecoder 创建解码器，onSwap 为 nil 时使用默认输出
+func NewAggregatorDecoder(signer types.Signer, abis *ABIRegistry, tokens *TokenCache, values *Valuator, weth common.Address, onSwap func(PendingAggregatorSwap)) *AggregatorDecoder {
+	if onSwap == nil {
+		onSwap = PrintPendingAggregatorSwap
+	}
+	return &AggregatorDecoder{signer: signer, abis: abis, tokens: tokens, values: values, weth: weth, onSwap: onSwap}
+}
+
+// Observe 解码交易池中直接发给聚合器的交易，金额达到 AggregatorReportMinUSD 时输出（只处理完整交易）
+func (d *AggregatorDecoder) Observe(msg BusMessage) {
+	tx := msg.Tx
+	if tx == nil || tx.To() == nil || aggregatorRouters[*tx.To()] == "" {
+		return
+	}
+	call, ok := d.abis.DecodeCall(tx.To(), tx.Data())
+	if !ok {
+		return
+	}
+	routes := d.Routes(tx.To(), tx.Value(), call)
+	large := false
+	for _, r := range routes {
+		large = large || (r.Priced && r.ValueUSD >= AggregatorReportMinUSD)
+	}
+	if !large {
+		return
+	}
+	from, err := types.Sender(d.signer, tx)
+	if err != nil {
+		return
+	}
+	d.onSwap(PendingAggregatorSwap{Hash: tx.Hash(), From: from, Routes: routes})
+}
+
+// Routes 已解码调用（包括 multicall / Safe 等展开的内层调用）中发给聚合器的兑换
+// value 为交易附带的 ETH，只用于顶层调用
+func (d *AggregatorDecoder) Routes(to *common.Address, value *big.Int, call *DecodedCall) []AggregatorRoute {
+	out := aggregatorRoutes(to, value, call)
+	for i := range out {
+		d.complete(&out[i])
+	}
+	return out
+}
+
+// aggregatorRoutes 只按 calldata 取出的路线，不查询池子和代币（离线的 golden 回归也使用）
+func aggregatorRoutes(to *common.Address, value *big.Int, call *DecodedCall) []AggregatorRoute {
+	if to == nil || call == nil {
+		return nil
+	}
+	var out []AggregatorRoute
+	var walk func(c *DecodedCall, target common.Address, value *big.Int)
+	walk = func(c *DecodedCall, target common.Address, value *big.Int) {
+		if c.Target != nil {
+			target = *c.Target
+		}
+		if name := aggregatorRouters[target]; name != "" {
+			if r, ok := aggregatorRoute(c, value); ok {
+				r.Aggregator = name
+				out = append(out, r)
+			}
+		}
+		for _, in := range c.Inner {
+			walk(in, target, new(big.Int))
+		}
+	}
+	walk(call, *to, value)
+	return out
+}
+
+// complete 沿路线查询 Uniswap 风格池子的 token0 / token1，补全未知的代币，然后估值
+func (d *AggregatorDecoder) complete(r *AggregatorRoute) {
+	prev := r.TokenIn
+	if prev == curveETH {
+		prev = d.weth // 聚合器先把 ETH 包装成 WETH 再进池子
+	}
+	for i := range r.Hops {
+		h := &r.Hops[i]
+		if h.TokenIn == (common.Address{}) {
+			h.TokenIn = prev
+		}
+		if h.TokenOut == (common.Address{}) && h.pair {
+			if tokens, ok := d.values.PoolTokens(h.Pool); ok {
+				switch {
+				case h.TokenIn == (common.Address{}) && h.dir > 0:
+					h.TokenIn = tokens[0]
+				case h.TokenIn == (common.Address{}) && h.dir < 0:
+					h.TokenIn = tokens[1]
+				}
+				switch h.TokenIn {
+				case tokens[0]:
+					h.TokenOut = tokens[1]
+				case tokens[1]:
+					h.TokenOut = tokens[0]
+				}
+			}
+		}
+		prev = h.TokenOut
+	}
+	if n := len(r.Hops); n > 0 {
+		if r.TokenIn == (common.Address{}) {
+			r.TokenIn = r.Hops[0].TokenIn
+		}
+		if r.TokenOut == (common.Address{}) {
+			r.TokenOut = r.Hops[n-1].TokenOut
+		}
+	}
+
+	in := d.tokens.Meta(r.TokenIn).Format(r.AmountIn)
+	if r.TokenIn == curveETH {
+		r.ValueUSD, r.Priced = d.values.ETHValue(r.AmountIn)
+	} else if r.TokenIn != (common.Address{}) {
+		r.ValueUSD, r.Priced = d.values.TokenValue(r.TokenIn, r.AmountIn)
+	}
+	out := "?"
+	if r.TokenOut != (common.Address{}) {
+		out = d.tokens.Meta(r.TokenOut).Label()
+		if r.MinOut != nil {
+			out = "≥" + d.tokens.Meta(r.TokenOut).Format(r.MinOut)
+		}
+	}
+	r.Desc = in + " -> " + out
+	if r.Priced {
+		r.Desc += " (" + formatUSD(r.ValueUSD) + ")"
+	}
+}
+
+// aggregatorRoute 按函数名和参数取出代币、数量和路线；不是已知的聚合器兑换函数时返回 false
+func aggregatorRoute(c *DecodedCall, value *big.Int) (AggregatorRoute, bool) {
+	r := AggregatorRoute{Function: c.Name}
+	addr := func(name string) common.Address {
+		a, _ := c.Arg(name).(common.Address)
+		return a
+	}
+	amount := func(name string) *big.Int {
+		n, _ := c.Arg(name).(*big.Int)
+		return n
+	}
+	pools := func(name string) []*big.Int {
+		p, _ := c.Arg(name).([]*big.Int)
+		return p
+	}
+
+	switch c.Name {
+	case "swap": // 1inch v5 / v6：executor 执行的路线在 data 中，格式不公开
+		if c.Arg("executor") == nil {
+			return r, false
+		}
+		r.TokenIn, r.TokenOut = addr("srcToken"), addr("dstToken")
+		r.AmountIn, r.MinOut, r.Recipient = amount("amount"), amount("minReturnAmount"), addr("dstReceiver")
+		r.Hops = []RouteHop{{Venue: "1inch executor", Pool: addr("executor"), TokenIn: r.TokenIn, TokenOut: r.TokenOut}}
+
+	case "unoswap", "unoswapTo", "unoswap2", "unoswap3", "ethUnoswap", "ethUnoswapTo":
+		if c.Arg("srcToken") != nil { // 1inch v5：pools 中每项的低 160 位是 Uniswap V2 风格的 pair
+			r.TokenIn, r.AmountIn, r.MinOut, r.Recipient = addr("srcToken"), amount("amount"), amount("minReturn"), addr("recipient")
+			for _, p := range pools("pools") {
+				r.Hops = append(r.Hops, RouteHop{Venue: "UniswapV2", Pool: maskedAddress(p), pair: true})
+			}
+			break
+		}
+		// 1inch v6：token / to / dex 都是 uint256，dex 的最高 3 位是协议
+		r.MinOut = amount("minReturn")
+		if strings.HasPrefix(c.Name, "eth") {
+			r.TokenIn, r.AmountIn = curveETH, value
+		} else {
+			r.TokenIn, r.AmountIn = maskedAddress(amount("token")), amount("amount")
+		}
+		if to := amount("to"); to != nil {
+			r.Recipient = maskedAddress(to)
+		}
+		for _, name := range []string{"dex", "dex2", "dex3"} {
+			if dex := amount(name); dex != nil {
+				r.Hops = append(r.Hops, oneInchV6Hop(dex))
+			}
+		}
+
+	case "uniswapV3Swap", "uniswapV3SwapTo": // 1inch v5：最高位为 1 表示 token1 -> token0
+		r.AmountIn, r.MinOut, r.Recipient = amount("amount"), amount("minReturn"), addr("recipient")
+		for _, p := range pools("pools") {
+			dir := 1
+			if p.Bit(255) == 1 {
+				dir = -1
+			}
+			r.Hops = append(r.Hops, RouteHop{Venue: "UniswapV3", Pool: maskedAddress(p), pair: true, dir: dir})
+		}
+
+	case "transformERC20": // 0x：transformer 的数据格式各不相同，只列出经过的 transformer
+		r.TokenIn, r.TokenOut = addr("inputToken"), addr("outputToken")
+		r.AmountIn, r.MinOut = amount("inputTokenAmount"), amount("minOutputTokenAmount")
+		rv := reflect.ValueOf(c.Arg("transformations"))
+		for i := 0; rv.Kind() == reflect.Slice && i < rv.Len(); i++ {
+			nonce := rv.Index(i).FieldByName("DeploymentNonce").Interface()
+			r.Hops = append(r.Hops, RouteHop{Venue: fmt.Sprintf("0x transformer #%v", nonce)})
+		}
+
+	case "sellToUniswap":
+		tokens, _ := c.Arg("tokens").([]common.Address)
+		if len(tokens) < 2 {
+			return r, false
+		}
+		sushi, _ := c.Arg("isSushi").(bool)
+		r.TokenIn, r.TokenOut = tokens[0], tokens[len(tokens)-1]
+		r.AmountIn, r.MinOut = amount("sellAmount"), amount("minBuyAmount")
+		for i := 0; i+1 < len(tokens); i++ {
+			hop := RouteHop{Venue: "UniswapV2", TokenIn: tokens[i], TokenOut: tokens[i+1]}
+			if sushi {
+				hop.Venue = "SushiSwap" // pair 地址的 init code hash 不同，这里不计算
+			} else {
+				hop.Pool = uniV2PairAddress(tokens[i], tokens[i+1])
+			}
+			r.Hops = append(r.Hops, hop)
+		}
+
+	case "sellTokenForTokenToUniswapV3", "sellEthForTokenToUniswapV3", "sellTokenForEthToUniswapV3":
+		path, _ := c.Arg("encodedPath").([]byte)
+		hops := parseV3Path(path)
+		if len(hops) == 0 {
+			return r, false
+		}
+		r.TokenIn, r.TokenOut = hops[0].tokenIn, hops[len(hops)-1].tokenOut
+		r.AmountIn, r.MinOut, r.Recipient = amount("sellAmount"), amount("minBuyAmount"), addr("recipient")
+		if c.Name == "sellEthForTokenToUniswapV3" {
+			r.TokenIn, r.AmountIn = curveETH, value
+		}
+		for _, h := range hops {
+			r.Hops = append(r.Hops, RouteHop{Venue: fmt.Sprintf("UniswapV3(%.2f%%)", float64(h.fee)/1e4), Pool: h.pool(), TokenIn: h.tokenIn, TokenOut: h.tokenOut})
+		}
+
+	case "multiplexBatchSellTokenForToken": // 0x：按数量拆分到多个子调用
+		r.TokenIn, r.TokenOut = addr("inputToken"), addr("outputToken")
+		r.AmountIn, r.MinOut = amount("sellAmount"), amount("minBuyAmount")
+		rv := reflect.ValueOf(c.Arg("calls"))
+		for i := 0; rv.Kind() == reflect.Slice && i < rv.Len(); i++ {
+			venue := "Unknown"
+			if id := int(rv.Index(i).FieldByName("Id").Interface().(uint8)); id < len(zeroExMultiplexVenues) {
+				venue = zeroExMultiplexVenues[id]
+			}
+			r.Hops = append(r.Hops, RouteHop{Venue: "0x " + venue, TokenIn: r.TokenIn, TokenOut: r.TokenOut})
+		}
+
+	case "simpleSwap": // ParaSwap：依次调用 callees
+		r.TokenIn, r.TokenOut = addr("fromToken"), addr("toToken")
+		r.AmountIn, r.MinOut, r.Recipient = amount("fromAmount"), amount("toAmount"), addr("beneficiary")
+		callees, _ := c.Arg("callees").([]common.Address)
+		for _, callee := range callees {
+			r.Hops = append(r.Hops, RouteHop{Venue: "ParaSwap callee", Pool: callee})
+		}
+
+	case "multiSwap": // ParaSwap：path 的每一步换成 path.to，每一步可能拆分到多个 adapter 和交易所
+		r.TokenIn, r.AmountIn, r.MinOut, r.Recipient = addr("fromToken"), amount("fromAmount"), amount("toAmount"), addr("beneficiary")
+		prev := r.TokenIn
+		path := reflect.ValueOf(c.Arg("path"))
+		for i := 0; path.Kind() == reflect.Slice && i < path.Len(); i++ {
+			step := path.Index(i)
+			to := step.FieldByName("To").Interface().(common.Address)
+			adapters := step.FieldByName("Adapters")
+			for j := 0; j < adapters.Len(); j++ {
+				routes := adapters.Index(j).FieldByName("Route")
+				for k := 0; k < routes.Len(); k++ {
+					exchange := routes.Index(k).FieldByName("TargetExchange").Interface().(common.Address)
+					r.Hops = append(r.Hops, RouteHop{Venue: "ParaSwap", Pool: exchange, TokenIn: prev, TokenOut: to})
+				}
+			}
+			prev, r.TokenOut = to, to
+		}
+
+	case "swapOnUniswap": // ParaSwap：直接走 Uniswap V2
+		path, _ := c.Arg("path").([]common.Address)
+		if len(path) < 2 {
+			return r, false
+		}
+		r.TokenIn, r.TokenOut = path[0], path[len(path)-1]
+		r.AmountIn, r.MinOut = amount("amountIn"), amount("amountOutMin")
+		for i := 0; i+1 < len(path); i++ {
+			r.Hops = append(r.Hops, RouteHop{Venue: "UniswapV2", Pool: uniV2PairAddress(path[i], path[i+1]), TokenIn: path[i], TokenOut: path[i+1]})
+		}
+
+	case "swapOnUniswapV2Fork": // ParaSwap：pools 的低 160 位是 V2 分叉的 pair
+		r.TokenIn, r.AmountIn, r.MinOut = addr("tokenIn"), amount("amountIn"), amount("amountOutMin")
+		for _, p := range pools("pools") {
+			r.Hops = append(r.Hops, RouteHop{Venue: "UniswapV2 fork", Pool: maskedAddress(p), pair: true})
+		}
+
+	default:
+		return r, false
+	}
+	if r.AmountIn == nil {
+		r.AmountIn = new(big.Int)
+	}
+	return r, true
+}
+
+// oneInchV6Hop 1inch v6 的 dex 参数：最高 3 位是协议（0 UniswapV2，1 UniswapV3，2 Curve），低 160 位是池子
+func oneInchV6Hop(dex *big.Int) RouteHop {
+	hop := RouteHop{Pool: maskedAddress(dex)}
+	switch new(big.Int).Rsh(dex, 253).Uint64() {
+	case 0:
+		hop.Venue, hop.pair = "UniswapV2", true
+	case 1:
+		hop.Venue, hop.pair = "UniswapV3", true
+	case 2:
+		hop.Venue = "Curve"
+	default:
+		hop.Venue = "Unknown"
+	}
+	return hop
+}
+
+// maskedAddress 取 uint256 的低 160 位作为地址
+func maskedAddress(n *big.Int) common.Address {
+	return common.BigToAddress(new(big.Int).And(n, addressMask))
+}
+
+// PrintPendingAggregatorSwap 默认的聚合器交易输出
+func PrintPendingAggregatorSwap(p PendingAggregatorSwap) {
+	var sb strings.Builder
+	summary := fmt.Sprintf("🔀 [Aggregator] %s 通过 %s 兑换 | %s", p.From.Hex(), p.Routes[0].Aggregator, p.Hash.Hex())
+	sb.WriteString("\n" + summary + "\n")
+	for _, r := range p.Routes {
+		fmt.Fprintf(&sb, "   %s\n", r)
+	}
+	Emit(Event{Type: "aggregator_swap", Key: p.Hash.Hex(), Summary: summary, Text: sb.String(), Data: p})
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ------------------------------------------------
+// 提醒模板：每个输出可以按事件类型用 Go 模板改写提醒文本，
+// 模板中可以使用事件的字段（data 中的字段名与 json 输出相同）和区块浏览器链接等函数，
+// 不同团队按自己的习惯定制提醒格式，不需要改代码
+// ------------------------------------------------
+
+const (
+	// 默认的区块浏览器
+	DefaultExplorerURL = "https://etherscan.io"
+	// templates 中匹配全部事件类型的键，具体类型的模板优先
+	TemplateAnyType = "*"
+)
+
+// explorerURL 模板中 tx / address / block 链接使用的区块浏览器，启动时按配置设置
+var explorerURL = DefaultExplorerURL
+
+// SetExplorerURL 设置区块浏览器地址，空字符串时使用默认值
+func SetExplorerURL(url string) {
+	if url == "" {
+		url = DefaultExplorerURL
+	}
+	explorerURL = strings.TrimSuffix(url, "/")
+}
+
+// alertTemplateData 模板中的 .：与 json 输出的字段相同
+type alertTemplateData struct {
+	ID       string
+	Type     string
+	Time     time.Time
+	Severity string
+	Category string
+	Summary  string
+	Text     string
+	Data     interface{} // data 的 JSON 解码结果，例如 {{.Data.tx_hash}}
+}
+
+// templateFuncs 模板中可用的函数
+var templateFuncs = template.FuncMap{
+	"tx":      func(hash interface{}) string { return explorerURL + "/tx/" + fmt.Sprint(hash) },
+	"address": func(addr interface{}) string { return explorerURL + "/address/" + fmt.Sprint(addr) },
+	"block":   func(n interface{}) string { return explorerURL + "/block/" + fmt.Sprint(n) },
+	"short":   shortHex,
+	"usd":     func(v interface{}) string { return formatUSD(templateFloat(v)) },
+	"upper":   strings.ToUpper,
+	"json": func(v interface{}) string {
+		b, _ := json.Marshal(v)
+		return string(b)
+	},
+}
+
+// shortHex 缩短地址 / 哈希，例如 0x1234…abcd
+func shortHex(v interface{}) string {
+	s := fmt.Sprint(v)
+	if len(s) <= 12 {
+		return s
+	}
+	return s[:6] + "…" + s[len(s)-4:]
+}
+
+// templateFloat 把 data 中的数字（json.Number 或字符串）转换为 float64，无法转换时为 0
+func templateFloat(v interface{}) float64 {
+	switch x := v.(type) {
+	case json.Number:
+		f, _ := x.Float64()
+		return f
+	case float64:
+		return x
+	case string:
+		f, _ := strconv.ParseFloat(x, 64)
+		return f
+	}
+	return 0
+}
+
+// AlertTemplates 一个输出的提醒模板
+type AlertTemplates struct {
+	byType map[string]*template.Template
+}
+
+// ParseAlertTemplates 编译 templates 配置，为空时返回 nil（不改写）
+func ParseAlertTemplates(cfg map[string]string) (*AlertTemplates, error) {
+	if len(cfg) == 0 {
+		return nil, nil
+	}
+	t := &AlertTemplates{byType: make(map[string]*template.Template, len(cfg))}
+	for typ, text := range cfg {
+		tmpl, err := template.New(typ).Funcs(templateFuncs).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("事件 %s 的模板解析失败: %w", typ, err)
+		}
+		t.byType[typ] = tmpl
+	}
+	return t, nil
+}
+
+// Apply 用模板改写事件的文本，摘要取改写后的第一个非空行；没有对应模板时原样返回
+func (t *AlertTemplates) Apply(ev Event) (Event, error) {
+	if t == nil {
+		return ev, nil
+	}
+	tmpl, ok := t.byType[ev.Type]
+	if !ok {
+		if tmpl, ok = t.byType[TemplateAnyType]; !ok {
+			return ev, nil
+		}
+	}
+	data := alertTemplateData{
+		ID: ev.ID, Type: ev.Type, Time: ev.Time, Severity: ev.Severity, Category: ev.Category,
+		Summary: ev.summary(), Text: ev.Text,
+	}
+	if ev.Data != nil {
+		// 与 json 输出使用相同的字段名；数字保留原样，避免大数显示成科学计数法
+		if b, err := json.Marshal(ev.Data); err == nil {
+			dec := json.NewDecoder(bytes.NewReader(b))
+			dec.UseNumber()
+			dec.Decode(&data.Data)
+		}
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return ev, fmt.Errorf("事件 %s 的模板渲染失败: %w", ev.Type, err)
+	}
+	ev.Text = buf.String()
+	ev.Summary = ""
+	ev.Summary = ev.summary()
+	return ev, nil
+}
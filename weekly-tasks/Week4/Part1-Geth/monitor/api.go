@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// APIServer 内置的 REST API，各模块在 Start 之前通过 Handle 注册自己的路由
+type APIServer struct {
+	addr string
+	mux  *http.ServeMux
+	auth *APIAuth    // 为 nil 时不鉴权
+	tls  *tls.Config // 为 nil 时使用明文 HTTP
+}
+
+// NewAPIServer 创建 API 服务，addr 为空时 Start 不会监听
+func NewAPIServer(addr string) *APIServer {
+	return &APIServer{addr: addr, mux: http.NewServeMux()}
+}
+
+// Handle 注册路由，pattern 支持 Go 1.22 的写法，例如 "GET /api/pnl/{address}"
+func (s *APIServer) Handle(pattern string, handler http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, handler)
+}
+
+// SetAuth 设置鉴权（api_auth.go），需要在 Start 之前调用
+func (s *APIServer) SetAuth(a *APIAuth) {
+	s.auth = a
+}
+
+// SetTLS 使用 HTTPS 监听（tls.go），需要在 Start 之前调用
+func (s *APIServer) SetTLS(t *tls.Config) {
+	s.tls = t
+}
+
+// AdminAllowed 能否识别出有管理权限的调用方：配置了 admin 权限的 API key，或要求客户端证书（tls.client_ca）
+func (s *APIServer) AdminAllowed() bool {
+	return s.auth.hasAdmin() || s.tls != nil && s.tls.ClientCAs != nil
+}
+
+// Start 在后台启动服务，ctx 取消时优雅关闭
+func (s *APIServer) Start(ctx context.Context) {
+	if s.addr == "" {
+		return
+	}
+	if !isLoopback(s.addr) && (s.auth == nil || len(s.auth.keys) == 0) && (s.tls == nil || s.tls.ClientCAs == nil) {
+		log.Printf("⚠️  API 监听在 %s 且没有配置 api_auth.keys 或 tls.client_ca，任何能访问该地址的人都可以暂停输出、热加载配置", s.addr)
+	}
+	srv := &http.Server{Addr: s.addr, Handler: s.auth.Wrap(sameOriginJSON(s.mux)), TLSConfig: s.tls, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		log.Printf("🌐 API 服务已启动: %s://%s/api/", serverScheme(s.tls), s.addr)
+		var err error
+		if s.tls != nil {
+			err = srv.ListenAndServeTLS("", "") // 证书由 TLSConfig.GetCertificate 提供
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("⚠️  API 服务异常退出: %v", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+}
+
+// sameOriginJSON 拒绝可能由其它网页发起的写请求（CSRF）：GET / HEAD / OPTIONS 以外的请求
+// 必须是 Content-Type: application/json（浏览器跨站的简单请求只能是表单或 text/plain，JSON 需要先预检，而 API 不响应 CORS），
+// 带 Origin 请求头时必须与请求的 Host 相同
+func sameOriginJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+				writeError(w, http.StatusForbidden, "拒绝跨站请求，Origin: "+origin)
+				return
+			}
+		}
+		if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != "application/json" {
+			writeError(w, http.StatusUnsupportedMediaType, "写请求的 Content-Type 必须是 application/json")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeJSON 输出 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// writeError 输出 {"error": "..."}
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ------------------------------------------------
+// API 鉴权：配置 api_auth.keys 后所有 /api/ 请求都需要携带 API key，
+// 每个 key 有自己的权限（read 只能 GET，admin 可以暂停输出、热加载等写操作）和每分钟请求上限，
+// 可选记录每个请求的调用方、路径、状态码和耗时，API 才能安全地监听在 localhost 以外的地址
+// ------------------------------------------------
+
+// API key 的权限
+const (
+	ScopeRead  = "read"  // 只读：GET / HEAD，不能访问 /api/admin/
+	ScopeAdmin = "admin" // 读写：全部方法和管理接口
+)
+
+const (
+	// 限流窗口，rate_per_minute 按该窗口计数
+	APIRateWindow = time.Minute
+)
+
+// AdminPathPrefix 该前缀下的接口（包括 GET）只有 admin 权限的 key 能访问
+const AdminPathPrefix = "/api/admin/"
+
+// authExempt 不需要鉴权的路径，给负载均衡和 k8s 探针使用；看板的静态文件（/dashboard/）中没有数据，同样不需要
+var authExempt = map[string]bool{"/healthz": true, "/readyz": true, "/": true}
+
+// APIKeyConfig 一个 API key
+type APIKeyConfig struct {
+	Name          string `json:"name"`            // 日志中显示的调用方名称
+	KeyEnv        string `json:"key_env"`         // 从该环境变量读取 key，不要把 key 写进配置文件
+	Scope         string `json:"scope"`           // read（默认）| admin
+	RatePerMinute int    `json:"rate_per_minute"` // 每分钟最多请求数，0 不限
+}
+
+// APIAuthConfig API 鉴权，keys 为空时不鉴权
+type APIAuthConfig struct {
+	Keys        []APIKeyConfig `json:"keys"`
+	LogRequests bool           `json:"log_requests"` // 记录每个请求
+}
+
+// validate 检查 key 配置
+func (c APIAuthConfig) validate() error {
+	names := make(map[string]bool, len(c.Keys))
+	for _, k := range c.Keys {
+		if k.Name == "" || names[k.Name] {
+			return fmt.Errorf("api_auth.keys 中的 name 不能为空或重复: %q", k.Name)
+		}
+		names[k.Name] = true
+		if k.KeyEnv == "" {
+			return fmt.Errorf("api_auth.keys 中 %s 的 key_env 不能为空", k.Name)
+		}
+		if k.Scope != "" && k.Scope != ScopeRead && k.Scope != ScopeAdmin {
+			return fmt.Errorf("api_auth.keys 中 %s 的 scope 只能是 read 或 admin: %q", k.Name, k.Scope)
+		}
+		if k.RatePerMinute < 0 {
+			return fmt.Errorf("api_auth.keys 中 %s 的 rate_per_minute 不能为负数", k.Name)
+		}
+	}
+	return nil
+}
+
+// apiKey 一个已加载的 key 及其限流窗口
+type apiKey struct {
+	name  string
+	hash  [32]byte // 只保存哈希，比较时长度固定
+	admin bool
+	rate  int
+
+	mu          sync.Mutex
+	windowStart time.Time
+	count       int
+}
+
+// allow 当前窗口内是否还能请求
+func (k *apiKey) allow(now time.Time) bool {
+	if k.rate == 0 {
+		return true
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if now.Sub(k.windowStart) >= APIRateWindow {
+		k.windowStart, k.count = now, 0
+	}
+	if k.count >= k.rate {
+		return false
+	}
+	k.count++
+	return true
+}
+
+// APIAuth 校验 API key、权限和限流，并记录请求
+type APIAuth struct {
+	keys        []*apiKey
+	logRequests bool
+}
+
+// NewAPIAuth 从环境变量加载 key；keys 为空时返回 nil（不鉴权）
+func NewAPIAuth(cfg APIAuthConfig) (*APIAuth, error) {
+	if len(cfg.Keys) == 0 {
+		if cfg.LogRequests {
+			return &APIAuth{logRequests: true}, nil
+		}
+		return nil, nil
+	}
+	a := &APIAuth{logRequests: cfg.LogRequests}
+	for _, k := range cfg.Keys {
+		secret := os.Getenv(k.KeyEnv)
+		if secret == "" {
+			return nil, fmt.Errorf("环境变量 %s 中没有 API key（%s）", k.KeyEnv, k.Name)
+		}
+		a.keys = append(a.keys, &apiKey{
+			name:  k.Name,
+			hash:  sha256.Sum256([]byte(secret)),
+			admin: k.Scope == ScopeAdmin,
+			rate:  k.RatePerMinute,
+		})
+	}
+	return a, nil
+}
+
+// hasAdmin 是否配置了 admin 权限的 key；a 为 nil 时返回 false
+func (a *APIAuth) hasAdmin() bool {
+	if a == nil {
+		return false
+	}
+	for _, k := range a.keys {
+		if k.admin {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup 按请求头中的 key 查找，支持 Authorization: Bearer <key> 和 X-API-Key: <key>；
+// 浏览器的 EventSource 不能设置请求头，事件流（/api/stream）还支持查询参数 ?api_key=<key>
+func (a *APIAuth) lookup(r *http.Request) *apiKey {
+	secret := r.Header.Get("X-API-Key")
+	if auth := r.Header.Get("Authorization"); secret == "" && strings.HasPrefix(auth, "Bearer ") {
+		secret = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if secret == "" && r.URL.Path == StreamPath {
+		secret = r.URL.Query().Get("api_key")
+	}
+	if secret == "" {
+		return nil
+	}
+	hash := sha256.Sum256([]byte(secret))
+	var found *apiKey
+	for _, k := range a.keys { // 不提前返回，耗时与匹配到第几个 key 无关
+		if subtle.ConstantTimeCompare(hash[:], k.hash[:]) == 1 {
+			found = k
+		}
+	}
+	return found
+}
+
+// Wrap 在 handler 之前鉴权；a 为 nil 时原样返回
+func (a *APIAuth) Wrap(next http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		caller := "-"
+		defer func() {
+			if a.logRequests {
+				log.Printf("🌐 %s %s %s %s -> %d (%s)", caller, r.RemoteAddr, r.Method, r.URL.Path, rec.status, time.Since(start).Round(time.Millisecond))
+			}
+		}()
+		if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 { // mTLS：没有 API key 时用客户端证书的 CN 标识调用方
+			caller = "cn=" + r.TLS.PeerCertificates[0].Subject.CommonName
+		}
+		if len(a.keys) == 0 || authExempt[r.URL.Path] || strings.HasPrefix(r.URL.Path, DashboardPath) {
+			next.ServeHTTP(rec, r)
+			return
+		}
+		key := a.lookup(r)
+		if key == nil {
+			rec.Header().Set("WWW-Authenticate", `Bearer realm="monitor"`)
+			writeError(rec, http.StatusUnauthorized, "缺少或无效的 API key")
+			return
+		}
+		caller = key.name
+		if !key.admin && (r.Method != http.MethodGet && r.Method != http.MethodHead || strings.HasPrefix(r.URL.Path, AdminPathPrefix)) {
+			writeError(rec, http.StatusForbidden, "该 API key 只有只读权限")
+			return
+		}
+		if !key.allow(start) {
+			rec.Header().Set("Retry-After", strconv.Itoa(int(APIRateWindow.Seconds())))
+			writeError(rec, http.StatusTooManyRequests, fmt.Sprintf("超过每分钟 %d 次的请求上限", key.rate))
+			return
+		}
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// statusRecorder 记录响应状态码，用于请求日志
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush 透传给底层 ResponseWriter，流式响应需要
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// isLoopback 监听地址是否只在本机可访问
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	// 授权额度的二进制位数达到该值视为无限授权
+	// ⚠️ 不只判断 MaxUint256：UNI / COMP 等代币的额度是 uint96，前端常用 2^96-1 表示无限
+	UnlimitedApprovalBits = 96
+	// spender 在这么多个区块之前还没有代码则视为新部署的合约（约 1 天）
+	// ⚠️ 需要查询历史状态，非归档节点上会跳过这项检查
+	ApprovalNewContractBlocks = 7200
+)
+
+var (
+	erc20ApprovalTopic = crypto.Keccak256Hash([]byte("Approval(address,address,uint256)"))
+
+	selectorApprove           = common.FromHex("0x095ea7b3") // approve(address,uint256)
+	selectorIncreaseAllowance = common.FromHex("0x39509351") // increaseAllowance(address,uint256)
+	selectorPermit            = common.FromHex("0xd505accf") // permit(address,address,uint256,uint256,uint8,bytes32,bytes32)
+)
+
+// Allowance 一笔未清零的授权
+type Allowance struct {
+	Token       common.Address `json:"token"`
+	Symbol      string         `json:"symbol"`
+	Spender     common.Address `json:"spender"`
+	Amount      string         `json:"amount"`
+	Unlimited   bool           `json:"unlimited"`
+	BlockNumber uint64         `json:"block_number"`
+	TxHash      common.Hash    `json:"tx_hash"`
+}
+
+// ApprovalAlert 关注钱包给出了有风险的授权
+type ApprovalAlert struct {
+	Pending     bool // 来自交易池中的 calldata，还没有上链
+	BlockNumber uint64
+	TxHash      common.Hash
+	Method      string // Approval / approve / increaseAllowance / 签名授权的类型（见 SignedPermit.Kind）
+	Owner       common.Address
+	Token       common.Address
+	Spender     common.Address
+	Amount      *big.Int
+	Reasons     []string
+}
+
+// ApprovalMonitor 解码关注钱包相关的 Approval 事件和 approve / permit calldata：
+//   - 无限授权给未验证、新部署的合约或 EOA 时提醒
+//   - 维护每个钱包当前未清零的授权列表，通过 REST API 查询
+type ApprovalMonitor struct {
+	client  *ethclient.Client
+	watch   *Watchlist
+	tokens  *TokenCache
+	abis    *ABIRegistry
+	fetcher *ABIFetcher // 可为 nil，此时只根据 ABIRegistry 判断是否验证
+	onAlert func(ApprovalAlert)
+
+	mu         sync.Mutex
+	allowances map[common.Address]map[common.Address]map[common.Address]*Allowance // owner -> token -> spender
+}
+
+// NewApprovalMonitor 创建授权监控，onAlert 为 nil 时输出到控制台
+func NewApprovalMonitor(client *ethclient.Client, watch *Watchlist, tokens *TokenCache, abis *ABIRegistry, fetcher *ABIFetcher, onAlert func(ApprovalAlert)) *ApprovalMonitor {
+	if onAlert == nil {
+		onAlert = PrintApprovalAlert
+	}
+	return &ApprovalMonitor{
+		client:     client,
+		watch:      watch,
+		tokens:     tokens,
+		abis:       abis,
+		fetcher:    fetcher,
+		onAlert:    onAlert,
+		allowances: make(map[common.Address]map[common.Address]map[common.Address]*Allowance),
+	}
+}
+
+// watched 只关心配置文件中的钱包，影子关注的巨鲸不算
+func (a *ApprovalMonitor) watched(addr common.Address) bool {
+	return a.watch.Source(addr) == WatchSourceConfig
+}
+
+// OnBlock 实现 BlockAnalyzer：用 Approval 事件更新授权列表（approve 和 permit 上链后都会产生该事件）
+func (a *ApprovalMonitor) OnBlock(data *BlockData) {
+	for _, receipt := range data.Receipts {
+		for _, l := range receipt.Logs {
+			owner, spender, amount, ok := parseApproval(l)
+			if !ok || !a.watched(owner) {
+				continue
+			}
+			a.update(owner, l.Address, spender, amount, l.BlockNumber, l.TxHash)
+			if reasons := a.risks(spender, amount, new(big.Int).SetUint64(l.BlockNumber)); len(reasons) > 0 {
+				a.onAlert(ApprovalAlert{
+					BlockNumber: l.BlockNumber, TxHash: l.TxHash, Method: "Approval",
+					Owner: owner, Token: l.Address, Spender: spender, Amount: amount, Reasons: reasons,
+				})
+			}
+		}
+	}
+}
+
+// parseApproval 解析 ERC-20 Approval 日志；ERC-721 的 Approval 把 tokenId 放在 topic 中，返回 false
+func parseApproval(l *types.Log) (owner, spender common.Address, amount *big.Int, ok bool) {
+	if len(l.Topics) != 3 || l.Topics[0] != erc20ApprovalTopic || len(l.Data) != 32 {
+		return owner, spender, nil, false
+	}
+	return common.BytesToAddress(l.Topics[1].Bytes()), common.BytesToAddress(l.Topics[2].Bytes()), new(big.Int).SetBytes(l.Data), true
+}
+
+// LogFilters 实现 LogFilterer：owner 是关注钱包的 Approval 事件
+func (a *ApprovalMonitor) LogFilters() []ethereum.FilterQuery {
+	return []ethereum.FilterQuery{{Topics: [][]common.Hash{{erc20ApprovalTopic}, addressTopics(a.watch.BySource(WatchSourceConfig))}}}
+}
+
+// InspectPending 检查交易池中的授权交易，在上链之前提醒；需要查询链上状态时在后台进行，不阻塞主循环
+//   - approve / increaseAllowance：发送者是关注钱包
+//   - 签名授权（ERC-2612 / DAI permit、Permit2，包括 multicall 中的内层调用）：签名者是关注钱包（通常由第三方代为提交）
+func (a *ApprovalMonitor) InspectPending(tx *types.Transaction, from common.Address) {
+	data := tx.Data()
+	if tx.To() == nil || len(data) < 4 {
+		return
+	}
+	if (equalSelector(data, selectorApprove) || equalSelector(data, selectorIncreaseAllowance)) && len(data) >= 4+2*32 {
+		method := "approve"
+		if equalSelector(data, selectorIncreaseAllowance) {
+			method = "increaseAllowance"
+		}
+		spender, amount := common.BigToAddress(word(data[4:], 0)), word(data[4:], 1)
+		if !a.watched(from) || amount.BitLen() < UnlimitedApprovalBits {
+			return
+		}
+		go func() {
+			if reasons := a.risks(spender, amount, nil); len(reasons) > 0 {
+				a.onAlert(ApprovalAlert{
+					Pending: true, TxHash: tx.Hash(), Method: method,
+					Owner: from, Token: *tx.To(), Spender: spender, Amount: amount, Reasons: reasons,
+				})
+			}
+		}()
+		return
+	}
+	for _, p := range DecodePermits(a.abis, tx.To(), data, from) {
+		if a.watched(p.Owner) {
+			go a.inspectPermit(tx.Hash(), p)
+		}
+	}
+}
+
+// inspectPermit 关注钱包的签名授权：无限额度按 spender 检查风险；
+// Permit2 transfer 直接转走代币，收款地址不是自己时总是提醒
+func (a *ApprovalMonitor) inspectPermit(hash common.Hash, p SignedPermit) {
+	var reasons []string
+	if p.Transfer() && p.Recipient != p.Owner {
+		reasons = append(reasons, describePermit(p))
+	}
+	if risks := a.risks(p.Spender, p.Amount, nil); len(risks) > 0 {
+		if len(reasons) == 0 && p.Submitter != p.Owner {
+			reasons = append(reasons, describePermit(p))
+		}
+		reasons = append(reasons, risks...)
+	}
+	if len(reasons) > 0 {
+		a.onAlert(ApprovalAlert{
+			Pending: true, TxHash: hash, Method: p.Kind,
+			Owner: p.Owner, Token: p.Token, Spender: p.Spender, Amount: p.Amount, Reasons: reasons,
+		})
+	}
+}
+
+func equalSelector(data, selector []byte) bool {
+	return len(data) >= 4 && string(data[:4]) == string(selector)
+}
+
+// risks 无限授权时检查 spender，返回风险原因；block 为 nil 表示最新区块
+func (a *ApprovalMonitor) risks(spender common.Address, amount *big.Int, block *big.Int) []string {
+	if amount.BitLen() < UnlimitedApprovalBits {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), CONNECTION_TIMEOUT)
+	defer cancel()
+
+	code, err := codeAtRetry(ctx, a.client, spender, block)
+	if err != nil {
+		return []string{fmt.Sprintf("无法读取 spender 代码: %v", err)}
+	}
+	if len(code) == 0 {
+		return []string{"spender 是 EOA（常见于 permit 钓鱼）"}
+	}
+
+	var reasons []string
+	target := spender
+	if impl, ok := a.abis.Implementation(spender); ok {
+		target = impl // 代理合约看实现合约是否验证
+	}
+	if a.fetcher.Unverified(target) {
+		reasons = append(reasons, "spender 合约未验证")
+	} else if _, ok := a.abis.Get(spender); !ok {
+		if a.fetcher != nil {
+			a.fetcher.Enqueue(target) // 下次就能知道是否验证
+		}
+		reasons = append(reasons, "spender 没有已知的 ABI")
+	}
+
+	head := block
+	if head == nil {
+		n, err := blockNumberRetry(ctx, a.client)
+		if err != nil {
+			return reasons
+		}
+		head = new(big.Int).SetUint64(n)
+	}
+	if head.Uint64() > ApprovalNewContractBlocks {
+		past := new(big.Int).Sub(head, big.NewInt(ApprovalNewContractBlocks))
+		if old, err := codeAtRetry(ctx, a.client, spender, past); err == nil && len(old) == 0 {
+			reasons = append(reasons, fmt.Sprintf("spender 是最近 %d 个区块内新部署的合约", ApprovalNewContractBlocks))
+		}
+	}
+	return reasons
+}
+
+// update 记录最新的授权额度，额度为 0 时删除
+func (a *ApprovalMonitor) update(owner, token, spender common.Address, amount *big.Int, block uint64, tx common.Hash) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	byToken, ok := a.allowances[owner]
+	if !ok {
+		byToken = make(map[common.Address]map[common.Address]*Allowance)
+		a.allowances[owner] = byToken
+	}
+	if amount.Sign() == 0 {
+		delete(byToken[token], spender)
+		if len(byToken[token]) == 0 {
+			delete(byToken, token)
+		}
+		return
+	}
+	if byToken[token] == nil {
+		byToken[token] = make(map[common.Address]*Allowance)
+	}
+	byToken[token][spender] = &Allowance{
+		Token:       token,
+		Spender:     spender,
+		Amount:      amount.String(),
+		Unlimited:   amount.BitLen() >= UnlimitedApprovalBits,
+		BlockNumber: block,
+		TxHash:      tx,
+	}
+}
+
+// Allowances 某个钱包当前未清零的授权，按区块倒序
+func (a *ApprovalMonitor) Allowances(owner common.Address) []Allowance {
+	a.mu.Lock()
+	list := []Allowance{}
+	for _, bySpender := range a.allowances[owner] {
+		for _, al := range bySpender {
+			list = append(list, *al)
+		}
+	}
+	a.mu.Unlock()
+
+	for i := range list {
+		meta, ok := a.tokens.Lookup(list[i].Token)
+		list[i].Symbol = meta.Label()
+		if amount, _ := new(big.Int).SetString(list[i].Amount, 10); ok && meta.HasDecimals && !list[i].Unlimited {
+			list[i].Amount = formatUnits(amount, meta.Decimals)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].BlockNumber > list[j].BlockNumber })
+	return list
+}
+
+// RegisterAPI 注册授权查询接口
+//
+//	GET /api/approvals/{address} 钱包当前未清零的授权（监控启动之后观察到的）
+func (a *ApprovalMonitor) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/approvals/{address}", func(w http.ResponseWriter, r *http.Request) {
+		if !common.IsHexAddress(r.PathValue("address")) {
+			writeError(w, http.StatusBadRequest, "地址格式错误")
+			return
+		}
+		owner := common.HexToAddress(r.PathValue("address"))
+		if !a.watched(owner) {
+			writeError(w, http.StatusNotFound, "该地址不在 watch 中")
+			return
+		}
+		writeJSON(w, http.StatusOK, a.Allowances(owner))
+	})
+}
+
+// PrintApprovalAlert 默认的风险授权提醒
+func PrintApprovalAlert(al ApprovalAlert) {
+	where := fmt.Sprintf("区块 %d", al.BlockNumber)
+	if al.Pending {
+		where = "Pending"
+	}
+	amount := al.Amount.String()
+	if al.Amount.BitLen() >= UnlimitedApprovalBits {
+		amount = "无限"
+	}
+	Emit(Event{Type: "approval_alert", Key: eventKey(al.TxHash, al.Pending, al.Method, al.Token, al.Owner, al.Spender), Data: al, Text: fmt.Sprintf("🚨 [Risky Approval] %s | %s | %s 通过 %s 授权 %s 额度 %s 给 %s | %s",
+		where, al.TxHash.Hex(), al.Owner.Hex(), al.Method, al.Token.Hex(), amount, al.Spender.Hex(), strings.Join(al.Reasons, "; "))})
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ------------------------------------------------
+// 跨场所价差扫描：比较同一对代币在不同池子（Uniswap、Curve……）中的兑换率，
+// 在一个池子买入、另一个池子卖回的来回收益（已扣除手续费）超过阈值时报告
+// ------------------------------------------------
+
+const (
+	// 默认的报告阈值（基点）
+	// ⚠️ 只按小额兑换率计算，没有考虑 gas 和价格冲击，实际能拿到的收益更低
+	DefaultArbMinProfitBps = 10
+)
+
+// ArbConfig 价差扫描配置
+type ArbConfig struct {
+	Enabled      bool    `json:"enabled"`
+	MinProfitBps float64 `json:"min_profit_bps"` // 来回收益达到该值（基点）时报告
+}
+
+// PriceVenue 可以比较价格的交易场所（一个池子）
+type PriceVenue interface {
+	Name() string
+	Tokens() []common.Address
+	// Rate 小额 tokenIn 换 tokenOut 的兑换率（已扣除手续费，最小单位之比），不支持这两个代币时返回 false
+	Rate(tokenIn, tokenOut common.Address) (float64, bool)
+}
+
+// ArbOpportunity 两个场所之间的价差
+type ArbOpportunity struct {
+	TokenA    common.Address `json:"token_a"`
+	TokenB    common.Address `json:"token_b"`
+	Pair      string         `json:"pair"`       // 例如 "USDC/WETH"
+	BuyVenue  string         `json:"buy_venue"`  // 在这里用 TokenA 换 TokenB
+	SellVenue string         `json:"sell_venue"` // 在这里把 TokenB 换回 TokenA
+	ProfitBps float64        `json:"profit_bps"`
+	Block     uint64         `json:"block"`
+	Since     uint64         `json:"since"` // 价差出现的区块
+}
+
+// ArbScanner 每个区块比较一次所有场所的兑换率
+type ArbScanner struct {
+	tokens       *TokenCache
+	minProfitBps float64
+	sources      []func() []PriceVenue
+
+	mu   sync.Mutex
+	open map[string]ArbOpportunity // 仍然存在的价差，消失后再次出现时重新报告
+
+	onOpportunity func(ArbOpportunity)
+}
+
+// NewArbScanner 创建扫描器，onOpportunity 为 nil 时使用默认输出
+func NewArbScanner(cfg ArbConfig, tokens *TokenCache, onOpportunity func(ArbOpportunity)) *ArbScanner {
+	if onOpportunity == nil {
+		onOpportunity = PrintArbOpportunity
+	}
+	return &ArbScanner{
+		tokens:        tokens,
+		minProfitBps:  cfg.MinProfitBps,
+		open:          make(map[string]ArbOpportunity),
+		onOpportunity: onOpportunity,
+	}
+}
+
+// AddVenues 加入一类场所，每次扫描时调用 fn 取最新的状态
+func (s *ArbScanner) AddVenues(fn func() []PriceVenue) {
+	s.sources = append(s.sources, fn)
+}
+
+// OnBlock 实现 BlockAnalyzer，需要在提供场所的分析器之后注册，才能用到本区块刷新后的状态
+func (s *ArbScanner) OnBlock(data *BlockData) {
+	s.Scan(data.Block.NumberU64())
+}
+
+// Scan 比较共享代币对的每两个场所，返回新出现的价差
+func (s *ArbScanner) Scan(block uint64) []ArbOpportunity {
+	byPair := make(map[[2]common.Address][]PriceVenue)
+	for _, fn := range s.sources {
+		for _, v := range fn() {
+			tokens := v.Tokens()
+			for i := range tokens {
+				for j := i + 1; j < len(tokens); j++ {
+					a, b := sortTokens(tokens[i], tokens[j])
+					byPair[[2]common.Address{a, b}] = append(byPair[[2]common.Address{a, b}], v)
+				}
+			}
+		}
+	}
+
+	found := make(map[string]ArbOpportunity)
+	for pair, venues := range byPair {
+		a, b := pair[0], pair[1]
+		label := s.tokens.Meta(a).Label() + "/" + s.tokens.Meta(b).Label()
+		for _, x := range venues {
+			for _, y := range venues {
+				if x.Name() == y.Name() {
+					continue
+				}
+				buy, ok1 := x.Rate(a, b)
+				sell, ok2 := y.Rate(b, a)
+				if !ok1 || !ok2 {
+					continue
+				}
+				profit := (buy*sell - 1) * 1e4
+				if profit < s.minProfitBps {
+					continue
+				}
+				key := a.Hex() + b.Hex() + x.Name() + "|" + y.Name()
+				found[key] = ArbOpportunity{TokenA: a, TokenB: b, Pair: label, BuyVenue: x.Name(), SellVenue: y.Name(), ProfitBps: profit, Block: block, Since: block}
+			}
+		}
+	}
+
+	var fresh []ArbOpportunity
+	s.mu.Lock()
+	for key, op := range found {
+		if prev, ok := s.open[key]; ok {
+			op.Since = prev.Since
+		} else {
+			fresh = append(fresh, op)
+		}
+		found[key] = op
+	}
+	s.open = found
+	s.mu.Unlock()
+
+	sort.Slice(fresh, func(i, j int) bool { return fresh[i].ProfitBps > fresh[j].ProfitBps })
+	for _, op := range fresh {
+		s.onOpportunity(op)
+	}
+	return fresh
+}
+
+// Open 当前仍然存在的价差，收益高的在前
+func (s *ArbScanner) Open() []ArbOpportunity {
+	s.mu.Lock()
+	out := make([]ArbOpportunity, 0, len(s.open))
+	for _, op := range s.open {
+		out = append(out, op)
+	}
+	s.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].ProfitBps > out[j].ProfitBps })
+	return out
+}
+
+// RegisterAPI 注册价差查询接口
+//
+//	GET /api/arb 当前仍然存在的价差
+func (s *ArbScanner) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/arb", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.Open())
+	})
+}
+
+// PrintArbOpportunity 默认的价差输出
+func PrintArbOpportunity(op ArbOpportunity) {
+	summary := fmt.Sprintf("⚖️  [Arb] %s 在 %s 买入、%s 卖出，来回收益 %.1f bps（区块 %d）",
+		op.Pair, op.BuyVenue, op.SellVenue, op.ProfitBps, op.Block)
+	Emit(Event{Type: "arb_opportunity", Key: eventKey(op.Block, op.Pair, op.BuyVenue, op.SellVenue), Summary: summary, Text: summary, Data: op})
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ------------------------------------------------
+// Balancer weighted pool：解码 Vault 的 Swap 事件，用 Swap / PoolBalanceChanged 更新池子余额，
+// 按 weighted math 计算兑换率并加入价差扫描
+// ------------------------------------------------
+
+const (
+	// 主网 Balancer V2 Vault，所有池子的代币都存在这里
+	BalancerVault = "0xBA12222222228d8Ba445958a75a0704d566BF2C8"
+	// 每隔多少个区块用 eth_call 重新同步一次（手续费和资产管理者调整的余额不会产生 Swap 日志）
+	BalancerResyncBlocks = 100
+)
+
+var (
+	balancerVaultABI = mustParseABI(`[
+		{"name":"getPoolTokens","type":"function","stateMutability":"view",
+		 "inputs":[{"name":"poolId","type":"bytes32"}],
+		 "outputs":[{"name":"tokens","type":"address[]"},{"name":"balances","type":"uint256[]"},{"name":"lastChangeBlock","type":"uint256"}]},
+		{"name":"Swap","type":"event","anonymous":false,
+		 "inputs":[{"name":"poolId","type":"bytes32","indexed":true},{"name":"tokenIn","type":"address","indexed":true},{"name":"tokenOut","type":"address","indexed":true},{"name":"amountIn","type":"uint256","indexed":false},{"name":"amountOut","type":"uint256","indexed":false}]},
+		{"name":"PoolBalanceChanged","type":"event","anonymous":false,
+		 "inputs":[{"name":"poolId","type":"bytes32","indexed":true},{"name":"liquidityProvider","type":"address","indexed":true},{"name":"tokens","type":"address[]","indexed":false},{"name":"deltas","type":"int256[]","indexed":false},{"name":"protocolFeeAmounts","type":"uint256[]","indexed":false}]}
+	]`)
+	balancerPoolABI = mustParseABI(`[
+		{"name":"getNormalizedWeights","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256[]"}]},
+		{"name":"getSwapFeePercentage","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]}
+	]`)
+
+	balancerSwapTopic          = balancerVaultABI.Events["Swap"].ID
+	balancerBalanceChangeTopic = balancerVaultABI.Events["PoolBalanceChanged"].ID
+)
+
+// BalancerConfig Balancer 池子监控
+type BalancerConfig struct {
+	Pools []string `json:"pools"` // weighted pool 的 poolId（bytes32）
+}
+
+// BalancerPoolState weighted pool 的状态
+type BalancerPoolState struct {
+	PoolID   common.Hash      `json:"pool_id"`
+	Pool     common.Address   `json:"pool"` // poolId 的前 20 字节
+	Tokens   []common.Address `json:"tokens"`
+	Balances []*big.Int       `json:"balances"`
+	Weights  []*big.Int       `json:"weights"`  // 归一化权重，1e18 为 100%
+	SwapFee  *big.Int         `json:"swap_fee"` // 1e18 为 100%
+	Block    uint64           `json:"block"`
+	Updates  uint64           `json:"updates"` // 由日志更新的次数
+}
+
+// BalancerSwap Vault 中一次经过关注池子的 swap
+type BalancerSwap struct {
+	PoolID    common.Hash    `json:"pool_id"`
+	Block     uint64         `json:"block"`
+	Tx        common.Hash    `json:"tx"`
+	LogIndex  uint           `json:"log_index"`
+	TokenIn   common.Address `json:"token_in"`
+	AmountIn  *big.Int       `json:"amount_in"`
+	TokenOut  common.Address `json:"token_out"`
+	AmountOut *big.Int       `json:"amount_out"`
+	ValueUSD  float64        `json:"value_usd"`
+	Priced    bool           `json:"priced"`
+	Desc      string         `json:"desc"`
+}
+
+// BalancerMonitor 监控配置的 Balancer weighted pool
+type BalancerMonitor struct {
+	client *ethclient.Client
+	tokens *TokenCache
+	values *Valuator
+	vault  common.Address
+
+	mu    sync.RWMutex
+	pools map[common.Hash]*BalancerPoolState // 尚未同步成功时为 nil
+
+	onSwap func(BalancerSwap)
+}
+
+// NewBalancerMonitor 创建监控，onSwap 为 nil 时使用默认输出
+func NewBalancerMonitor(cfg BalancerConfig, client *ethclient.Client, tokens *TokenCache, values *Valuator, onSwap func(BalancerSwap)) *BalancerMonitor {
+	if onSwap == nil {
+		onSwap = PrintBalancerSwap
+	}
+	m := &BalancerMonitor{
+		client: client,
+		tokens: tokens,
+		values: values,
+		vault:  common.HexToAddress(BalancerVault),
+		pools:  make(map[common.Hash]*BalancerPoolState, len(cfg.Pools)),
+		onSwap: onSwap,
+	}
+	for _, id := range cfg.Pools {
+		m.pools[common.HexToHash(id)] = nil
+	}
+	return m
+}
+
+// OnBlock 实现 BlockAnalyzer：尚未同步或到了同步间隔的池子先用 eth_call 同步到上一个区块，再应用本区块的日志
+func (m *BalancerMonitor) OnBlock(data *BlockData) {
+	number := data.Block.NumberU64()
+	m.mu.RLock()
+	var stale []common.Hash
+	for id, st := range m.pools {
+		if st == nil || number%BalancerResyncBlocks == 0 {
+			stale = append(stale, id)
+		}
+	}
+	m.mu.RUnlock()
+	for _, id := range stale {
+		m.sync(id, new(big.Int).SetUint64(number-1))
+	}
+
+	for _, r := range data.Receipts {
+		if r.Status == 0 {
+			continue
+		}
+		for _, l := range r.Logs {
+			if l.Address != m.vault || len(l.Topics) < 2 {
+				continue
+			}
+			if _, ok := m.pools[l.Topics[1]]; !ok {
+				continue
+			}
+			switch l.Topics[0] {
+			case balancerSwapTopic:
+				if swap, ok := m.applySwap(l); ok {
+					m.onSwap(swap)
+				}
+			case balancerBalanceChangeTopic:
+				m.applyBalanceChange(l)
+			}
+		}
+	}
+}
+
+// applySwap 池子收到 amountIn、付出 amountOut（swap 手续费留在池子里）
+func (m *BalancerMonitor) applySwap(l *types.Log) (BalancerSwap, bool) {
+	if len(l.Topics) != 4 || len(l.Data) != 2*32 {
+		return BalancerSwap{}, false
+	}
+	swap := BalancerSwap{
+		PoolID:    l.Topics[1],
+		Block:     l.BlockNumber,
+		Tx:        l.TxHash,
+		LogIndex:  l.Index,
+		TokenIn:   common.BytesToAddress(l.Topics[2].Bytes()),
+		AmountIn:  word(l.Data, 0),
+		TokenOut:  common.BytesToAddress(l.Topics[3].Bytes()),
+		AmountOut: word(l.Data, 1),
+	}
+	m.mu.Lock()
+	if st := m.pools[swap.PoolID]; st != nil && l.BlockNumber > st.Block {
+		st.add(swap.TokenIn, swap.AmountIn)
+		st.add(swap.TokenOut, new(big.Int).Neg(swap.AmountOut))
+		st.Updates++
+	}
+	m.mu.Unlock()
+
+	swap.ValueUSD, swap.Priced = m.values.TokenValue(swap.TokenIn, swap.AmountIn)
+	swap.Desc = m.tokens.Meta(swap.TokenIn).Format(swap.AmountIn) + " -> " + m.tokens.Meta(swap.TokenOut).Format(swap.AmountOut)
+	return swap, true
+}
+
+// applyBalanceChange 加入 / 退出流动性：余额变化 delta，再扣除协议手续费
+func (m *BalancerMonitor) applyBalanceChange(l *types.Log) {
+	values, err := balancerVaultABI.Unpack("PoolBalanceChanged", l.Data)
+	if err != nil || len(values) != 3 {
+		return
+	}
+	tokens, _ := values[0].([]common.Address)
+	deltas, _ := values[1].([]*big.Int)
+	fees, _ := values[2].([]*big.Int)
+	if len(deltas) != len(tokens) || len(fees) != len(tokens) {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := m.pools[l.Topics[1]]
+	if st == nil || l.BlockNumber <= st.Block {
+		return
+	}
+	for i, t := range tokens {
+		st.add(t, new(big.Int).Sub(deltas[i], fees[i]))
+	}
+	st.Updates++
+}
+
+// add 调整某个代币的余额，调用方持有锁
+func (s *BalancerPoolState) add(token common.Address, delta *big.Int) {
+	for i, t := range s.Tokens {
+		if t == token {
+			s.Balances[i] = new(big.Int).Add(s.Balances[i], delta)
+			return
+		}
+	}
+}
+
+// sync 在 block 上查询池子的代币、余额、权重和手续费；不是 weighted pool 时查询权重会失败
+func (m *BalancerMonitor) sync(id common.Hash, block *big.Int) {
+	ctx, cancel := context.WithTimeout(context.Background(), CONNECTION_TIMEOUT)
+	defer cancel()
+	pool := common.BytesToAddress(id[:20])
+	call := func(to common.Address, contract abi.ABI, method string, args ...interface{}) ([]interface{}, error) {
+		input, err := contract.Pack(method, args...)
+		if err != nil {
+			return nil, err
+		}
+		out, err := callContractRetry(ctx, m.client, ethereum.CallMsg{To: &to, Data: input}, block)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", method, err)
+		}
+		return contract.Unpack(method, out)
+	}
+
+	st := &BalancerPoolState{PoolID: id, Pool: pool, Block: block.Uint64()}
+	out, err := call(m.vault, balancerVaultABI, "getPoolTokens", id)
+	if err == nil {
+		st.Tokens, _ = out[0].([]common.Address)
+		st.Balances, _ = out[1].([]*big.Int)
+		out, err = call(pool, balancerPoolABI, "getNormalizedWeights")
+	}
+	if err == nil {
+		st.Weights, _ = out[0].([]*big.Int)
+		out, err = call(pool, balancerPoolABI, "getSwapFeePercentage")
+	}
+	if err == nil {
+		st.SwapFee, _ = out[0].(*big.Int)
+		if len(st.Tokens) == 0 || len(st.Balances) != len(st.Tokens) || len(st.Weights) != len(st.Tokens) || st.SwapFee == nil {
+			err = fmt.Errorf("返回数据不完整（不是 weighted pool？）")
+		}
+	}
+	if err != nil {
+		log.Printf("⚠️  同步 Balancer 池子 %s 失败: %v", id.Hex(), err)
+		return
+	}
+	m.mu.Lock()
+	if prev := m.pools[id]; prev != nil {
+		st.Updates = prev.Updates
+	}
+	m.pools[id] = st
+	m.mu.Unlock()
+}
+
+// States 全部已同步池子的状态
+func (m *BalancerMonitor) States() []BalancerPoolState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]BalancerPoolState, 0, len(m.pools))
+	for _, st := range m.pools {
+		if st != nil {
+			out = append(out, *st)
+		}
+	}
+	return out
+}
+
+// Venues 已同步的池子作为价差扫描的场所（当前状态的快照）
+func (m *BalancerMonitor) Venues() []PriceVenue {
+	states := m.States()
+	out := make([]PriceVenue, len(states))
+	for i, st := range states {
+		// 余额切片在更新时整体替换元素，快照需要复制一份
+		st.Balances = append([]*big.Int(nil), st.Balances...)
+		out[i] = balancerVenue{st}
+	}
+	return out
+}
+
+// balancerVenue weighted pool 的边际兑换率：(balanceOut / weightOut) / (balanceIn / weightIn) · (1 - fee)
+type balancerVenue struct {
+	state BalancerPoolState
+}
+
+func (v balancerVenue) Name() string {
+	return "Balancer " + v.state.Pool.Hex()
+}
+
+func (v balancerVenue) Tokens() []common.Address {
+	return v.state.Tokens
+}
+
+func (v balancerVenue) Rate(tokenIn, tokenOut common.Address) (float64, bool) {
+	i, j := -1, -1
+	for k, t := range v.state.Tokens {
+		switch t {
+		case tokenIn:
+			i = k
+		case tokenOut:
+			j = k
+		}
+	}
+	if i < 0 || j < 0 || v.state.Balances[i].Sign() <= 0 {
+		return 0, false
+	}
+	st := v.state
+	num := new(big.Int).Mul(st.Balances[j], st.Weights[i])
+	den := new(big.Int).Mul(st.Balances[i], st.Weights[j])
+	if den.Sign() == 0 {
+		return 0, false
+	}
+	fee, _ := new(big.Float).Quo(new(big.Float).SetInt(st.SwapFee), big.NewFloat(1e18)).Float64()
+	return ratio(num, den) * (1 - fee), true
+}
+
+// RegisterAPI 注册 Balancer 池子查询接口
+//
+//	GET /api/balancer 全部池子的代币、余额、权重和手续费
+func (m *BalancerMonitor) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/balancer", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, m.States())
+	})
+}
+
+// PrintBalancerSwap 默认的 Balancer swap 输出
+func PrintBalancerSwap(s BalancerSwap) {
+	value := ""
+	if s.Priced {
+		value = " (" + formatUSD(s.ValueUSD) + ")"
+	}
+	summary := fmt.Sprintf("🔷 [Balancer] %s%s | 池子 %s | %s", s.Desc, value, common.BytesToAddress(s.PoolID[:20]).Hex(), s.Tx.Hex())
+	Emit(Event{Type: "balancer_swap", Key: eventKey(s.Tx, s.LogIndex), Summary: summary, Text: summary, Data: s})
+}
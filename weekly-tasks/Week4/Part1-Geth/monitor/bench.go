@@ -0,0 +1,475 @@
+package main
+
+// ------------------------------------------------
+// 吞吐量基准：抓取一段真实的交易池会话（-capture），之后离线把它按原速或全速重放进事件总线，
+// 逐个处理阶段（与实时监控相同的事件总线消费者：输出、Pending 处理、签名授权、UserOp）统计
+// 单独运行时的 tx/s、每笔交易的分配次数和字节数，以及流水线中各队列的积压、饱和度和丢弃数，
+// 性能相关的重构可以用重构前后的数字说明收益
+// ------------------------------------------------
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"runtime"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// 积压采样间隔
+	benchSampleInterval = 5 * time.Millisecond
+	// 会话文件单行的最大长度（hex 编码的交易，blob 以外的交易远小于它）
+	benchMaxLine = 4 << 20
+)
+
+// BenchSessionHeader 会话文件的第一行
+type BenchSessionHeader struct {
+	ChainID uint64    `json:"chain_id"`
+	Started time.Time `json:"started"`
+}
+
+// BenchTx 会话文件中的一笔交易
+type BenchTx struct {
+	T  int64         `json:"t"`  // 相对会话开始的毫秒数
+	Tx hexutil.Bytes `json:"tx"` // 已签名交易（MarshalBinary）
+}
+
+// BenchSession 加载到内存中的会话
+type BenchSession struct {
+	Header BenchSessionHeader
+	Txs    []BenchTx
+}
+
+// Duration 会话的抓取时长
+func (s *BenchSession) Duration() time.Duration {
+	if len(s.Txs) == 0 {
+		return 0
+	}
+	return time.Duration(s.Txs[len(s.Txs)-1].T) * time.Millisecond
+}
+
+// transactions 重新解出全部交易；每个阶段 / 每轮重放使用新的对象，避免交易上缓存的发送者影响计时
+func (s *BenchSession) transactions() ([]*types.Transaction, error) {
+	out := make([]*types.Transaction, len(s.Txs))
+	for i, t := range s.Txs {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(t.Tx); err != nil {
+			return nil, fmt.Errorf("第 %d 笔交易解析失败: %w", i+1, err)
+		}
+		out[i] = tx
+	}
+	return out, nil
+}
+
+// LoadBenchSession 读取 -capture 写入的会话文件
+func LoadBenchSession(path string) (*BenchSession, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), benchMaxLine)
+	s := &BenchSession{}
+	for line := 0; scanner.Scan(); line++ {
+		if line == 0 {
+			if err := json.Unmarshal(scanner.Bytes(), &s.Header); err != nil {
+				return nil, fmt.Errorf("会话信息解析失败: %w", err)
+			}
+			continue
+		}
+		var t BenchTx
+		if err := json.Unmarshal(scanner.Bytes(), &t); err != nil {
+			return nil, fmt.Errorf("第 %d 行解析失败: %w", line+1, err)
+		}
+		s.Txs = append(s.Txs, t)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(s.Txs) == 0 {
+		return nil, fmt.Errorf("%s 中没有交易", path)
+	}
+	return s, nil
+}
+
+// benchStage 一个处理阶段，即实时监控事件总线上的一个消费者
+type benchStage struct {
+	name string
+	fn   func(msg BusMessage)
+
+	processed atomic.Int64
+	busy      atomic.Int64 // 处理耗时（纳秒）
+
+	// 只在采样 goroutine 中访问
+	maxBacklog int
+	backlogSum int64
+	samples    int64
+}
+
+// newBenchStages 按配置创建一个不连接节点的 Monitor，返回它处理 Pending 交易的消费者：
+// 与实时监控注册到事件总线的是同一组函数（预筛、恢复发送者、解码、交易池过滤、过滤链、输出编码都在其中），
+// 需要查询节点的消费者（价格冲击、聚合器估值、授权风险检查等）不参与
+func newBenchStages(cfg *Config, signer types.Signer) ([]*benchStage, error) {
+	abis := NewABIRegistry()
+	if err := loadSignatures(abis, cfg.ABI); err != nil {
+		return nil, fmt.Errorf("加载签名失败: %w", err)
+	}
+	m := &Monitor{
+		cfg:       cfg,
+		watch:     NewWatchlist(cfg.WatchAddresses()),
+		abis:      abis,
+		signer:    signer,
+		inclusion: NewInclusionTracker(nil, NewReceiptWaiter(nil)), // 只登记关注地址的交易，不运行也就不查询节点
+	}
+	m.initPendingFilters()
+	m.prefilter = NewTxPrefilter("bench", m.signer, m.pendingRule())
+
+	var stages []*benchStage
+	for _, c := range m.coreConsumers() {
+		if slices.Contains(c.kinds, BusPendingTx) {
+			stages = append(stages, &benchStage{name: c.name, fn: c.handler})
+		}
+	}
+	if cfg.Mempool.Permits {
+		stages = append(stages, &benchStage{name: "permits", fn: NewPermitStats(signer, abis).Observe})
+	}
+	if cfg.UserOps.Enabled {
+		stages = append(stages, &benchStage{name: "userops", fn: NewUserOpMonitor(cfg.UserOps, signer, abis, m.watch, nil).Observe})
+	}
+	return stages, nil
+}
+
+// benchOutput 重放期间的默认输出：事件照常按 json 编码，写到空设备，不刷屏；返回恢复原输出的函数
+func benchOutput() (func(), error) {
+	out, err := NewOutput([]OutputConfig{{Name: "bench", Target: os.DevNull, Format: FormatJSON}})
+	if err != nil {
+		return nil, err
+	}
+	prev := SetDefaultOutput(out)
+	return func() {
+		SetDefaultOutput(prev)
+		out.Close()
+	}, nil
+}
+
+// BenchStageReport 一个阶段的结果
+type BenchStageReport struct {
+	Name string `json:"name"`
+
+	// 单独运行（单个 goroutine 顺序处理全部交易）
+	SerialTPS   float64 `json:"serial_tx_per_sec"`
+	NsPerTx     float64 `json:"ns_per_tx"`
+	AllocsPerTx float64 `json:"allocs_per_tx"`
+	BytesPerTx  float64 `json:"bytes_per_tx"`
+
+	// 流水线（全部阶段作为事件总线的消费者并行处理）
+	Processed   int64   `json:"processed"`
+	Dropped     int64   `json:"dropped"`
+	MaxBacklog  int     `json:"max_backlog"`
+	AvgBacklog  float64 `json:"avg_backlog"`
+	Saturation  float64 `json:"saturation"`  // 最大积压 / 队列长度，达到 1 时开始丢消息
+	Utilization float64 `json:"utilization"` // 处理耗时 / 流水线总耗时
+}
+
+// BenchReport 一次基准的结果
+type BenchReport struct {
+	Session      string             `json:"session"`
+	Txs          int                `json:"txs"` // 重放的交易数（会话交易数 × loop）
+	Loops        int                `json:"loops"`
+	Speed        float64            `json:"speed"` // 0 为全速
+	Buffer       int                `json:"buffer"`
+	Captured     float64            `json:"captured_seconds"`
+	CapturedTPS  float64            `json:"captured_tx_per_sec"` // 抓取时交易池的到达速率
+	Elapsed      float64            `json:"elapsed_seconds"`     // 从开始发布到全部阶段处理完
+	PipelineTPS  float64            `json:"pipeline_tx_per_sec"`
+	AllocsPerTx  float64            `json:"allocs_per_tx"` // 流水线整体
+	BytesPerTx   float64            `json:"bytes_per_tx"`
+	GCs          uint32             `json:"gcs"`
+	DroppedTotal int64              `json:"dropped_total"`
+	Stages       []BenchStageReport `json:"stages"`
+}
+
+// runBench 抓取交易池会话（-capture）或重放会话并输出各阶段的吞吐量
+func runBench(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	configPath := fs.String("config", DefaultConfigPath, "配置文件路径（过滤条件、关注地址、签名文件）")
+	capture := fs.String("capture", "", "连接节点抓取 Pending 交易写入该文件")
+	duration := fs.Duration("duration", time.Minute, "抓取时长")
+	session := fs.String("session", "", "要重放的会话文件")
+	speed := fs.Float64("speed", 0, "重放速度倍数，1 为原速，0 为全速")
+	loops := fs.Int("loop", 1, "会话重复重放的次数")
+	buffer := fs.Int("buffer", BusPendingBuffer, "每个阶段的队列长度")
+	asJSON := fs.Bool("json", false, "以 JSON 输出结果")
+	fs.Parse(args)
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if *capture != "" {
+		return captureBench(ctx, cfg, *capture, *duration)
+	}
+	if *session == "" {
+		return fmt.Errorf("用法: bench -capture session.jsonl [-duration 1m] | bench -session session.jsonl [-speed 0] [-loop 1] [-buffer 4096] [-json]")
+	}
+	if *loops < 1 || *buffer < 1 || *speed < 0 {
+		return fmt.Errorf("-loop 和 -buffer 需要大于 0，-speed 不能为负数")
+	}
+	s, err := LoadBenchSession(*session)
+	if err != nil {
+		return err
+	}
+	report, err := benchReplay(ctx, cfg, s, *speed, *loops, *buffer)
+	if err != nil {
+		return err
+	}
+	report.Session = *session
+	if *asJSON {
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+	PrintBenchReport(report)
+	return nil
+}
+
+// benchReplay 先逐个阶段单独运行测量开销，再把会话重放进事件总线测量流水线
+func benchReplay(ctx context.Context, cfg *Config, s *BenchSession, speed float64, loops, buffer int) (*BenchReport, error) {
+	signer := types.LatestSignerForChainID(new(big.Int).SetUint64(s.Header.ChainID))
+	stages, err := newBenchStages(cfg, signer)
+	if err != nil {
+		return nil, err
+	}
+	restore, err := benchOutput()
+	if err != nil {
+		return nil, err
+	}
+	defer restore()
+	report := &BenchReport{Txs: len(s.Txs) * loops, Loops: loops, Speed: speed, Buffer: buffer, Captured: s.Duration().Seconds()}
+	if report.Captured > 0 {
+		report.CapturedTPS = float64(len(s.Txs)) / report.Captured
+	}
+
+	// 单独运行：每个阶段使用新解出的交易，分配统计不受其它 goroutine 干扰
+	for _, st := range stages {
+		txs, err := s.transactions()
+		if err != nil {
+			return nil, err
+		}
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+		for _, tx := range txs {
+			st.fn(BusMessage{Kind: BusPendingTx, Received: time.Now(), Tx: tx, Source: "bench"})
+		}
+		elapsed := time.Since(start)
+		runtime.ReadMemStats(&after)
+		n := float64(len(txs))
+		report.Stages = append(report.Stages, BenchStageReport{
+			Name:        st.name,
+			SerialTPS:   n / elapsed.Seconds(),
+			NsPerTx:     float64(elapsed.Nanoseconds()) / n,
+			AllocsPerTx: float64(after.Mallocs-before.Mallocs) / n,
+			BytesPerTx:  float64(after.TotalAlloc-before.TotalAlloc) / n,
+		})
+	}
+
+	// 流水线：与实时监控相同，每个阶段是事件总线上的一个消费者
+	rounds := make([][]*types.Transaction, loops)
+	for i := range rounds {
+		if rounds[i], err = s.transactions(); err != nil {
+			return nil, err
+		}
+	}
+	busCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	bus := NewEventBus()
+	for _, st := range stages {
+		st := st
+		bus.Subscribe("bench/"+st.name, buffer, func(msg BusMessage) {
+			start := time.Now()
+			st.fn(msg)
+			st.busy.Add(int64(time.Since(start)))
+			st.processed.Add(1)
+		}, BusPendingTx)
+	}
+	go bus.Run(busCtx)
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	done := make(chan struct{})
+	go benchSample(busCtx, bus, stages, report.Txs, done)
+
+	start := time.Now()
+	round := s.Duration()
+	for i, txs := range rounds {
+		for j, tx := range txs {
+			if speed > 0 {
+				at := time.Duration(float64(time.Duration(i)*round+time.Duration(s.Txs[j].T)*time.Millisecond) / speed)
+				if wait := at - time.Since(start); wait > 0 {
+					select {
+					case <-time.After(wait):
+					case <-ctx.Done():
+						return nil, ctx.Err()
+					}
+				}
+			}
+			bus.Publish(BusMessage{Kind: BusPendingTx, Tx: tx, Source: "bench"})
+			if speed == 0 {
+				runtime.Gosched() // 全速时也让出 CPU，与从网络订阅读取时一样，否则 CPU 少时消费者在发布结束前得不到运行
+			}
+		}
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	report.Elapsed = elapsed.Seconds()
+	report.PipelineTPS = float64(report.Txs) / elapsed.Seconds()
+	report.AllocsPerTx = float64(after.Mallocs-before.Mallocs) / float64(report.Txs)
+	report.BytesPerTx = float64(after.TotalAlloc-before.TotalAlloc) / float64(report.Txs)
+	report.GCs = after.NumGC - before.NumGC
+	stats := bus.Stats()
+	for i, st := range stages {
+		r := &report.Stages[i]
+		r.Processed, r.Dropped = st.processed.Load(), stats[i].Dropped
+		r.MaxBacklog = st.maxBacklog
+		if st.samples > 0 {
+			r.AvgBacklog = float64(st.backlogSum) / float64(st.samples)
+		}
+		r.Saturation = float64(st.maxBacklog) / float64(stats[i].Capacity)
+		r.Utilization = float64(st.busy.Load()) / float64(elapsed.Nanoseconds())
+		report.DroppedTotal += r.Dropped
+	}
+	return report, nil
+}
+
+// benchSample 定期采样各阶段的积压，全部阶段处理完（处理数 + 丢弃数 = 发布数）时关闭 done；ctx 结束时退出
+func benchSample(ctx context.Context, bus *EventBus, stages []*benchStage, total int, done chan struct{}) {
+	ticker := time.NewTicker(benchSampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		finished := true
+		for i, q := range bus.Stats() {
+			st := stages[i]
+			if q.Backlog > st.maxBacklog {
+				st.maxBacklog = q.Backlog
+			}
+			st.backlogSum += int64(q.Backlog)
+			st.samples++
+			if st.processed.Load()+q.Dropped < int64(total) {
+				finished = false
+			}
+		}
+		if finished {
+			close(done)
+			return
+		}
+	}
+}
+
+// captureBench 订阅主节点的完整 Pending 交易，写入会话文件
+func captureBench(ctx context.Context, cfg *Config, path string, duration time.Duration) error {
+	clients, err := Dial(cfg)
+	if err != nil {
+		return err
+	}
+	defer clients.Close()
+	chainID, err := chainIDRetry(ctx, clients.Eth)
+	if err != nil {
+		return fmt.Errorf("查询 ChainID 失败: %w", err)
+	}
+	txs := make(chan *types.Transaction, BusPendingBuffer)
+	sub, err := clients.Geth.SubscribeFullPendingTransactions(ctx, txs)
+	if err != nil {
+		return fmt.Errorf("订阅完整 Pending 交易失败（节点需要支持 newPendingTransactions 的完整交易订阅）: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	start := time.Now()
+	if err := enc.Encode(BenchSessionHeader{ChainID: chainID.Uint64(), Started: start}); err != nil {
+		return err
+	}
+	log.Printf("🎬 开始抓取 Pending 交易（%s）-> %s", duration, path)
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	progress := time.NewTicker(10 * time.Second)
+	defer progress.Stop()
+	n := 0
+	for {
+		select {
+		case tx := <-txs:
+			raw, err := tx.MarshalBinary()
+			if err != nil {
+				continue
+			}
+			if err := enc.Encode(BenchTx{T: time.Since(start).Milliseconds(), Tx: raw}); err != nil {
+				return fmt.Errorf("写入会话文件失败: %w", err)
+			}
+			n++
+		case <-progress.C:
+			log.Printf("🎬 已抓取 %d 笔（%.0f tx/s）", n, float64(n)/time.Since(start).Seconds())
+		case err := <-sub.Err():
+			return fmt.Errorf("订阅中断: %w", err)
+		case <-timer.C:
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			log.Printf("✅ 抓取完成：%d 笔交易，%.0f tx/s", n, float64(n)/duration.Seconds())
+			return nil
+		case <-ctx.Done():
+			return w.Flush()
+		}
+	}
+}
+
+// PrintBenchReport 以表格输出基准结果
+func PrintBenchReport(r *BenchReport) {
+	speed := "全速"
+	if r.Speed > 0 {
+		speed = fmt.Sprintf("%gx 原速", r.Speed)
+	}
+	fmt.Printf("📊 [Bench] %s | %d 笔交易（%d 轮）| 抓取 %.0fs，%.0f tx/s | 重放 %s，队列 %d\n",
+		r.Session, r.Txs, r.Loops, r.Captured, r.CapturedTPS, speed, r.Buffer)
+	fmt.Printf("   流水线: %.2fs，%.0f tx/s，%.1f allocs/tx，%.0f B/tx，GC %d 次，丢弃 %d 条\n",
+		r.Elapsed, r.PipelineTPS, r.AllocsPerTx, r.BytesPerTx, r.GCs, r.DroppedTotal)
+	fmt.Println("   阶段                 单独tx/s      ns/tx  allocs/tx       B/tx |    已处理     丢弃 最大积压   饱和度    占用")
+	for _, s := range r.Stages {
+		fmt.Printf("   %-16s %12.0f %10.0f %10.1f %10.0f | %9d %8d %8d %7.0f%% %6.0f%%\n",
+			s.Name, s.SerialTPS, s.NsPerTx, s.AllocsPerTx, s.BytesPerTx,
+			s.Processed, s.Dropped, s.MaxBacklog, s.Saturation*100, s.Utilization*100)
+	}
+}
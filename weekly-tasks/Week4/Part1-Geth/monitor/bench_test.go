@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// go test -run '^$' -bench . 用 fixtures 中的小会话测各阶段的开销，重构前后各跑一次用 benchstat 比较；
+// 真实流量下的吞吐量和队列饱和度用 bench -session 重放抓取的会话
+const benchSessionFixture = "fixtures/bench-session.jsonl"
+
+// loadBenchFixture 读取会话，配置中关注会话里的几个发送者并开启 permits / userops 阶段，让 pending 阶段走到解码和输出
+func loadBenchFixture(b *testing.B) (*BenchSession, *Config, types.Signer) {
+	b.Helper()
+	s, err := LoadBenchSession(benchSessionFixture)
+	if err != nil {
+		b.Fatal(err)
+	}
+	signer := types.LatestSignerForChainID(new(big.Int).SetUint64(s.Header.ChainID))
+	cfg := &Config{}
+	cfg.Mempool.Permits = true
+	cfg.UserOps.Enabled = true
+	txs := benchTransactions(b, s)
+	for _, tx := range txs[:min(len(txs), 5)] {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			b.Fatal(err)
+		}
+		cfg.Watch = append(cfg.Watch, from.Hex())
+	}
+	return s, cfg, signer
+}
+
+// benchTransactions 重新解出会话中的交易，每轮使用新的对象，不受交易上缓存的发送者影响
+func benchTransactions(b *testing.B, s *BenchSession) []*types.Transaction {
+	b.Helper()
+	txs, err := s.transactions()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return txs
+}
+
+// quietBench 事件编码后写到空设备，结束时恢复
+func quietBench(b *testing.B) {
+	b.Helper()
+	restore, err := benchOutput()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(restore)
+}
+
+// reportTxRate 以交易为单位报告吞吐量，一轮处理 n 笔
+func reportTxRate(b *testing.B, n int) {
+	txs := float64(b.N * n)
+	b.ReportMetric(txs/b.Elapsed().Seconds(), "tx/s")
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/txs, "ns/tx")
+}
+
+// BenchmarkDecode 解出交易并恢复发送者：每笔交易进入流水线的固定开销
+func BenchmarkDecode(b *testing.B) {
+	s, _, signer := loadBenchFixture(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, t := range s.Txs {
+			tx := new(types.Transaction)
+			if err := tx.UnmarshalBinary(t.Tx); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := types.Sender(signer, tx); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	reportTxRate(b, len(s.Txs))
+}
+
+// BenchmarkEnrich calldata 解码、签名授权解码和 CEL 变量：过滤链、匹配规则和分析器共用的交易信息
+func BenchmarkEnrich(b *testing.B) {
+	s, cfg, signer := loadBenchFixture(b)
+	abis := NewABIRegistry()
+	if err := loadSignatures(abis, cfg.ABI); err != nil {
+		b.Fatal(err)
+	}
+	txs := benchTransactions(b, s)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, tx := range txs {
+			from, _ := types.Sender(signer, tx) // 第一轮之后取缓存
+			call, _ := abis.DecodeCall(tx.To(), tx.Data())
+			DecodePermits(abis, tx.To(), tx.Data(), from)
+			newCELTx(tx)
+			newCELCall(call, tx.Data())
+		}
+	}
+	reportTxRate(b, len(txs))
+}
+
+// BenchmarkAnalyzers 逐个阶段单独处理整个会话，阶段与 bench 子命令相同（实时监控事件总线上处理 Pending 交易的消费者）
+func BenchmarkAnalyzers(b *testing.B) {
+	s, cfg, signer := loadBenchFixture(b)
+	quietBench(b)
+	stages, err := newBenchStages(cfg, signer)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, st := range stages {
+		b.Run(st.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				txs := benchTransactions(b, s)
+				b.StartTimer()
+				for _, tx := range txs {
+					st.fn(BusMessage{Kind: BusPendingTx, Received: time.Now(), Tx: tx, Source: "bench"})
+				}
+			}
+			reportTxRate(b, len(s.Txs))
+		})
+	}
+}
+
+// BenchmarkPipeline 全部阶段作为事件总线的消费者并行处理整个会话，计时从第一条发布到全部阶段处理完；
+// 队列长度不小于会话的交易数，不会丢消息
+func BenchmarkPipeline(b *testing.B) {
+	s, cfg, signer := loadBenchFixture(b)
+	quietBench(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		stages, err := newBenchStages(cfg, signer)
+		if err != nil {
+			b.Fatal(err)
+		}
+		txs := benchTransactions(b, s)
+		ctx, cancel := context.WithCancel(context.Background())
+		var wg sync.WaitGroup
+		wg.Add(len(stages) * len(txs))
+		bus := NewEventBus()
+		for _, st := range stages {
+			st := st
+			bus.Subscribe("bench/"+st.name, max(len(txs), BusPendingBuffer), func(msg BusMessage) {
+				st.fn(msg)
+				wg.Done()
+			}, BusPendingTx)
+		}
+		go bus.Run(ctx)
+		b.StartTimer()
+
+		for _, tx := range txs {
+			bus.Publish(BusMessage{Kind: BusPendingTx, Tx: tx, Source: "bench"})
+		}
+		wg.Wait()
+
+		b.StopTimer()
+		cancel()
+		b.StartTimer()
+	}
+	reportTxRate(b, len(s.Txs))
+}
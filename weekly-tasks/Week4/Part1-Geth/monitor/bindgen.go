@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/abigen"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ------------------------------------------------
+// 类型化绑定生成：用 abigen（v2 绑定）为关注的合约生成 Go 代码，
+// 分析器通过生成的结构体打包调用、解码返回值和事件，不再手写 abi.Pack / Unpack 和字符串函数名
+// ABI 来源：indexer.contracts 中的 ABI 文件，以及 ABI 缓存（abi.cache_dir）中已拉取的已验证合约
+// ------------------------------------------------
+
+// bindingSpec 一个要生成绑定的合约
+type bindingSpec struct {
+	name string // Go 类型名
+	abi  string // ABI JSON
+}
+
+// runBindgen 生成类型化绑定
+//
+//	go run ./monitor bindgen -out monitor/bindings Router=0x7a25... Pair=0xB4e1...
+func runBindgen(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bindgen", flag.ExitOnError)
+	configPath := fs.String("config", DefaultConfigPath, "配置文件路径")
+	out := fs.String("out", "bindings", "输出目录，生成 <out>/<pkg>.go")
+	pkg := fs.String("pkg", "bindings", "生成代码的包名")
+	fetch := fs.Bool("fetch", false, "ABI 缓存中没有时从 Sourcify / Etherscan 拉取")
+	indexed := fs.Bool("indexer", true, "同时为 indexer.contracts 中的合约生成")
+	fs.Parse(args)
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	var specs []bindingSpec
+	if *indexed {
+		for _, c := range cfg.Indexer.Contracts {
+			raw, err := readABIFile(c.ABI)
+			if err != nil {
+				return fmt.Errorf("合约 %s: %w", c.Name, err)
+			}
+			specs = append(specs, bindingSpec{name: bindingTypeName(c.Name), abi: string(raw)})
+		}
+	}
+
+	// 其余参数为 Name=0x地址，从 ABI 缓存中取（代理合约取实现合约的 ABI）
+	targets := make(map[string]common.Address)
+	for _, arg := range fs.Args() {
+		name, addr, ok := strings.Cut(arg, "=")
+		if !ok || !common.IsHexAddress(addr) {
+			return fmt.Errorf("参数格式错误: %q（应为 Name=0x地址）", arg)
+		}
+		targets[bindingTypeName(name)] = common.HexToAddress(addr)
+	}
+	if len(targets) > 0 {
+		if cfg.ABI.CacheDir == "" {
+			return fmt.Errorf("按地址生成绑定需要配置 abi.cache_dir")
+		}
+		clients, err := Dial(cfg)
+		if err != nil {
+			return err
+		}
+		defer clients.Close()
+		chainID, err := chainIDRetry(ctx, clients.Eth)
+		if err != nil {
+			return fmt.Errorf("查询 ChainID 失败: %w", err)
+		}
+		fetcher := NewABIFetcher(cfg.ABI, clients.Eth, NewABIRegistry(), chainID)
+
+		names := make([]string, 0, len(targets))
+		for name := range targets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			addr := targets[name]
+			if *fetch {
+				fetcher.Fetch(ctx, addr)
+			}
+			raw, ok := fetcher.CachedABI(addr)
+			if !ok {
+				return fmt.Errorf("ABI 缓存中没有 %s (%s) 的 ABI（未验证，或需要加上 -fetch）", name, addr.Hex())
+			}
+			specs = append(specs, bindingSpec{name: name, abi: string(raw)})
+		}
+	}
+	if len(specs) == 0 {
+		return fmt.Errorf("没有要生成绑定的合约：配置 indexer.contracts，或传入 Name=0x地址")
+	}
+
+	code, err := generateBindings(specs, *pkg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		return fmt.Errorf("创建输出目录失败: %w", err)
+	}
+	path := filepath.Join(*out, *pkg+".go")
+	if err := os.WriteFile(path, []byte(code), 0o644); err != nil {
+		return fmt.Errorf("写入绑定失败: %w", err)
+	}
+	fmt.Printf("✅ 已生成 %d 个合约的绑定: %s\n", len(specs), path)
+	for _, s := range specs {
+		fmt.Printf("   %s.New%s()\n", *pkg, s.name)
+	}
+	return nil
+}
+
+// generateBindings 校验 ABI 后调用 abigen 生成一个文件
+func generateBindings(specs []bindingSpec, pkg string) (string, error) {
+	types := make([]string, len(specs))
+	abis := make([]string, len(specs))
+	bytecodes := make([]string, len(specs)) // 只生成调用 / 解码代码，不需要部署
+	seen := make(map[string]bool)
+	for i, s := range specs {
+		if seen[s.name] {
+			return "", fmt.Errorf("合约类型名重复: %s", s.name)
+		}
+		seen[s.name] = true
+		if _, err := abi.JSON(strings.NewReader(s.abi)); err != nil {
+			return "", fmt.Errorf("合约 %s 的 ABI 无法解析: %w", s.name, err)
+		}
+		types[i], abis[i] = s.name, s.abi
+	}
+	code, err := abigen.BindV2(types, abis, bytecodes, pkg, nil, nil)
+	if err != nil {
+		return "", fmt.Errorf("生成绑定失败: %w", err)
+	}
+	return code, nil
+}
+
+// bindingTypeName 把合约名转换为导出的 Go 类型名，例如 "uniswap-router" -> "UniswapRouter"
+func bindingTypeName(name string) string {
+	name = strings.NewReplacer("-", "_", " ", "_", ".", "_").Replace(name)
+	return abi.ToCamelCase(name)
+}
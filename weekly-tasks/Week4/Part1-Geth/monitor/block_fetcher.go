@@ -0,0 +1,654 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// EnrichLevel 区块数据的详细程度，级别越高每个区块需要的 RPC 请求越多
+type EnrichLevel int
+
+const (
+	EnrichHeader       EnrichLevel = iota // 只有区块头（Block 中没有交易），不额外请求
+	EnrichTransactions                    // + 交易和发送者（eth_getBlockByHash）
+	EnrichReceipts                        // + 回执（eth_getBlockReceipts）
+	EnrichTraces                          // + 整个区块的调用树（trace_block / debug_traceBlockByNumber）
+)
+
+var enrichLevelNames = []string{"header", "transactions", "receipts", "traces"}
+
+func (l EnrichLevel) String() string {
+	if l < 0 || int(l) >= len(enrichLevelNames) {
+		return fmt.Sprintf("EnrichLevel(%d)", int(l))
+	}
+	return enrichLevelNames[l]
+}
+
+// ParseEnrichLevel 解析配置中的级别名称
+func ParseEnrichLevel(s string) (EnrichLevel, error) {
+	for i, name := range enrichLevelNames {
+		if s == name {
+			return EnrichLevel(i), nil
+		}
+	}
+	return 0, fmt.Errorf("未知的区块数据级别 %q（可选 header / transactions / receipts / traces）", s)
+}
+
+// BlockData 一个区块的数据，按 Level 填充：区块（含交易）、发送者、回执和调用树
+// Receipts[i]、Senders[i] 对应 Block.Transactions()[i]
+type BlockData struct {
+	Block    *types.Block
+	Receipts []*types.Receipt
+	Senders  []common.Address
+	Traces   []InternalCall // Level 为 EnrichTraces 时才有
+	Level    EnrichLevel
+}
+
+// BlockAnalyzer 基于完整区块数据的分析器
+// OnBlock 在 BlockFetcher 的 goroutine 中按区块顺序调用
+type BlockAnalyzer interface {
+	OnBlock(data *BlockData)
+}
+
+// LogFilterer 只关心特定日志的分析器
+// 区块头的 logsBloom 与所有过滤条件都不可能匹配时，这个区块不会分发给它
+// 所有分析器都不需要某个区块时，整个区块和回执都不拉取
+type LogFilterer interface {
+	BlockAnalyzer
+	// LogFilters 任一条件可能匹配即需要该区块，每个区块调用一次，可以随关注列表变化
+	LogFilters() []ethereum.FilterQuery
+}
+
+// Enricher 声明分析器需要的区块数据级别，没有实现的分析器按 EnrichReceipts 处理
+// 每个区块按需要它的分析器中的最高级别拉取一次；需要 EnrichTraces 的分析器必须能处理没有调用树的区块（节点不支持 trace 或超过上限时降级）
+type Enricher interface {
+	BlockAnalyzer
+	Enrichment() EnrichLevel
+}
+
+// Warmer 启动预热：Run 开始时把最近的历史区块交给 Warm，只更新滚动窗口等内部状态、不输出提醒
+// 没有实现 Warmer 的分析器不参与预热（它们只处理启动之后的区块）
+type Warmer interface {
+	BlockAnalyzer
+	Warm(data *BlockData)
+}
+
+// headArrival 区块头及本地收到的时间，用于统计处理延迟
+type headArrival struct {
+	header   *types.Header
+	received time.Time
+}
+
+const (
+	// 记住最近这么多个处理过的区块，回补与实时订阅重叠时不重复处理
+	fetcherRecentBlocks = 128
+
+	// 启动预热的默认区块数
+	DefaultWarmupBlocks = 32
+)
+
+// BlockFetcher 每个新区块只拉取一次完整区块和回执，再分发给所有分析器，避免各自重复请求
+// 新区块的高度跳过了某些区块（订阅丢消息、拉取失败）时，先按高度补齐中间的区块，保证分析器不漏块
+type BlockFetcher struct {
+	client    *ethclient.Client
+	heads     chan headArrival
+	analyzers []BlockAnalyzer
+	signer    types.Signer // 恢复交易发送者，Run 时根据 chainID 创建
+
+	checkpoints     *CheckpointStore // 未开启断点续传时为 nil
+	maxBackfill     uint64
+	warmup          uint64        // 启动预热的区块数，0 表示不预热
+	tracer          Tracer        // 为 nil 时不提供调用树
+	noBlockReceipts bool          // 节点不支持 eth_getBlockReceipts，逐笔拉取回执
+	pool            *EndpointPool // 配置了备用节点时由它选择健康的节点，否则为 nil
+	limit           EnrichLevel   // 区块数据级别上限
+	overrides       map[string]EnrichLevel
+	levels          map[BlockAnalyzer]EnrichLevel // Run 时确定的每个分析器的级别
+	last            *IndexCursor                  // 最后一个处理完的区块，Run 的 goroutine 独占
+	recent          map[uint64]common.Hash
+	replays         chan blockRange // 管理接口请求的重新处理，在 Run 的 goroutine 中执行
+	replaying       atomic.Bool
+	lastBlock       atomic.Uint64 // 与 last 相同，给其它 goroutine 读取
+
+	fetched *metrics.Counter
+	skipped *metrics.Counter // logsBloom 预筛后跳过的区块
+}
+
+func NewBlockFetcher(client *ethclient.Client) *BlockFetcher {
+	return &BlockFetcher{
+		client:      client,
+		heads:       make(chan headArrival, 16),
+		maxBackfill: DefaultCheckpointMaxBackfill,
+		limit:       EnrichTraces,
+		recent:      make(map[uint64]common.Hash),
+		replays:     make(chan blockRange, 1),
+		fetched:     metrics.NewRegisteredCounter("monitor/fetcher/blocks_fetched", metricsRegistry),
+		skipped:     metrics.NewRegisteredCounter("monitor/fetcher/blocks_skipped", metricsRegistry),
+	}
+}
+
+// SetCheckpoint 开启断点续传：每处理完一个区块保存断点，Run 开始时先从断点回补到最新区块，需要在 Run 之前调用
+func (f *BlockFetcher) SetCheckpoint(store *CheckpointStore, maxBackfill int) {
+	f.checkpoints = store
+	f.maxBackfill = uint64(maxBackfill)
+}
+
+// SetWarmup 启动时先用最近 blocks 个区块预热实现了 Warmer 的分析器，需要在 Run 之前调用
+func (f *BlockFetcher) SetWarmup(blocks int) {
+	f.warmup = uint64(max(blocks, 0))
+}
+
+// SetEnrichment 设置区块数据级别上限、按分析器类型名覆盖的上限和 trace 实现，需要在 Run 之前调用
+func (f *BlockFetcher) SetEnrichment(limit EnrichLevel, overrides map[string]EnrichLevel, tracer Tracer) {
+	f.limit, f.overrides, f.tracer = limit, overrides, tracer
+}
+
+// SetEndpoints 区块 / 回执拉取改由 pool 选择未熔断的节点，需要在 Run 之前调用
+func (f *BlockFetcher) SetEndpoints(pool *EndpointPool) {
+	f.pool = pool
+}
+
+// Register 注册分析器，需要在 Run 之前调用
+func (f *BlockFetcher) Register(a BlockAnalyzer) {
+	f.analyzers = append(f.analyzers, a)
+}
+
+// NotifyHead 由区块头订阅调用，不会阻塞主循环
+func (f *BlockFetcher) NotifyHead(header *types.Header) {
+	select {
+	case f.heads <- headArrival{header, time.Now()}:
+	default:
+		log.Printf("⚠️  区块拉取处理不过来，区块 %d 稍后按高度补齐", header.Number)
+	}
+}
+
+// Run 在独立 goroutine 中拉取区块并分发，直到 ctx 取消
+func (f *BlockFetcher) Run(ctx context.Context) {
+	var chainID *big.Int
+	err := Retry(ctx, "eth_chainId", func() (err error) {
+		chainID, err = f.client.ChainID(ctx)
+		return err
+	})
+	if err != nil {
+		log.Printf("❌ 查询 chainID 失败，区块分析已停止: %v", err)
+		return
+	}
+	f.signer = types.LatestSignerForChainID(chainID)
+	f.resolveLevels()
+
+	if f.warmup > 0 {
+		if err := f.warm(ctx); err != nil {
+			log.Printf("⚠️  启动预热失败: %v（分析器从空状态开始）", err)
+		}
+	}
+	if f.checkpoints != nil {
+		if err := f.resume(ctx); err != nil {
+			log.Printf("⚠️  从断点回补失败: %v（收到新区块时重试）", err)
+		}
+	}
+	for {
+		select {
+		case h := <-f.heads:
+			number := h.header.Number.Uint64()
+			if hash, ok := f.recent[number]; ok && hash == h.header.Hash() {
+				continue // 回补时已经处理过
+			}
+			if f.last != nil && number > f.last.BlockNumber+1 {
+				f.backfill(ctx, f.last.BlockNumber+1, number-1)
+			}
+			f.process(ctx, h.header, h.received)
+		case r := <-f.replays:
+			f.replay(ctx, r.From, r.To)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// blockRange 一段区块高度，两端都包含
+type blockRange struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+}
+
+// FetcherStatus 区块拉取的队列状态
+type FetcherStatus struct {
+	HeadBacklog  int    `json:"head_backlog"`  // 等待处理的新区块头
+	HeadCapacity int    `json:"head_capacity"` // 新区块头队列长度
+	LastBlock    uint64 `json:"last_block"`    // 最后一个处理完的区块
+	Replaying    bool   `json:"replaying"`     // 正在重新处理区块
+	Queued       bool   `json:"queued"`        // 有重新处理的请求在排队
+}
+
+// RequestReplay 请求把 [from, to] 的区块重新分发给分析器（例如修改了关注地址后补查历史），
+// 在 Run 的 goroutine 中依次处理，期间新区块在队列中等待；已有请求在排队时返回错误
+func (f *BlockFetcher) RequestReplay(from, to uint64) error {
+	select {
+	case f.replays <- blockRange{From: from, To: to}:
+		return nil
+	default:
+		return fmt.Errorf("已有一个重新处理的请求在排队")
+	}
+}
+
+// replay 重新处理一段区块：与回补不同，不推进断点，也不记入最近处理过的区块；
+// 分析器产生的事件 ID 与第一次相同，下游可以按 ID 去重
+func (f *BlockFetcher) replay(ctx context.Context, from, to uint64) {
+	f.replaying.Store(true)
+	defer f.replaying.Store(false)
+	log.Printf("🔁 重新处理区块 %d - %d", from, to)
+	for n := from; n <= to; n++ {
+		if ctx.Err() != nil {
+			return
+		}
+		header, err := f.headerByNumber(ctx, new(big.Int).SetUint64(n))
+		if err != nil {
+			log.Printf("⚠️  重新处理区块 %d 失败: %v", n, err)
+			return
+		}
+		targets, level := f.budgeted(f.targets(header))
+		if len(targets) == 0 {
+			continue
+		}
+		data, err := f.fetch(ctx, header, level)
+		if err != nil {
+			log.Printf("⚠️  重新处理区块 %d 失败: %v", n, err)
+			return
+		}
+		for _, a := range targets {
+			a.OnBlock(data)
+		}
+	}
+	log.Printf("🔁 区块 %d - %d 重新处理完成", from, to)
+}
+
+// Status 队列积压和重新处理状态；LastBlock 在 Run 的 goroutine 中更新，这里读到的可能稍旧
+func (f *BlockFetcher) Status() FetcherStatus {
+	return FetcherStatus{
+		HeadBacklog:  len(f.heads),
+		HeadCapacity: cap(f.heads),
+		LastBlock:    f.lastBlock.Load(),
+		Replaying:    f.replaying.Load(),
+		Queued:       len(f.replays) > 0,
+	}
+}
+
+// resume 读取断点并回补到当前最新区块；断点所在的区块已被重组时从该高度重新处理
+func (f *BlockFetcher) resume(ctx context.Context) error {
+	cur, ok, err := f.checkpoints.Load(fetcherCheckpoint)
+	if err != nil || !ok {
+		return err
+	}
+	canonical, err := f.headerByNumber(ctx, new(big.Int).SetUint64(cur.BlockNumber))
+	if err != nil {
+		return fmt.Errorf("查询断点区块失败: %w", err)
+	}
+	from := cur.BlockNumber + 1
+	if canonical.Hash() != cur.BlockHash {
+		log.Printf("⚠️  断点区块 %d 已被重组，从该高度重新处理", cur.BlockNumber)
+		from = cur.BlockNumber
+	}
+	head, err := f.headerByNumber(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("查询最新区块失败: %w", err)
+	}
+	f.last = &cur
+	f.backfill(ctx, from, head.Number.Uint64())
+	return nil
+}
+
+// resolveLevels 按上限确定每个分析器实际拿到的级别：需要调用树的降级为回执，需要交易 / 回执但超过上限的停用
+func (f *BlockFetcher) resolveLevels() {
+	f.levels = make(map[BlockAnalyzer]EnrichLevel, len(f.analyzers))
+	kept := f.analyzers[:0]
+	for _, a := range f.analyzers {
+		want := EnrichReceipts
+		if e, ok := a.(Enricher); ok {
+			want = e.Enrichment()
+		}
+		name := analyzerName(a)
+		limit := f.limit
+		if l, ok := f.overrides[name]; ok {
+			limit = l
+		}
+		if f.tracer == nil {
+			limit = min(limit, EnrichReceipts)
+		}
+		switch {
+		case want <= limit:
+		case want == EnrichTraces && limit >= EnrichReceipts:
+			log.Printf("⚠️  %s 需要调用树，超过区块数据级别 %s（或节点不支持 trace），降级为 receipts", name, limit)
+			want = EnrichReceipts
+		default:
+			log.Printf("⚠️  %s 需要 %s 级别的区块数据，超过上限 %s，已停用", name, want, limit)
+			continue
+		}
+		f.levels[a] = want
+		kept = append(kept, a)
+	}
+	f.analyzers = kept
+}
+
+// analyzerName 分析器的类型名，用于 block_level_overrides
+func analyzerName(a BlockAnalyzer) string {
+	name := fmt.Sprintf("%T", a)
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// level 一组分析器需要的最高级别
+func (f *BlockFetcher) level(targets []BlockAnalyzer) EnrichLevel {
+	level := EnrichHeader
+	for _, a := range targets {
+		level = max(level, f.levels[a])
+	}
+	return level
+}
+
+// budgeted 按 RPC 预算的级别上限去掉拿不到所需数据的分析器（需要调用树的降级为回执），返回实际拉取的级别
+func (f *BlockFetcher) budgeted(targets []BlockAnalyzer) ([]BlockAnalyzer, EnrichLevel) {
+	limit := rpcBudget.Load().Cap()
+	if f.level(targets) <= limit {
+		return targets, f.level(targets)
+	}
+	kept := make([]BlockAnalyzer, 0, len(targets))
+	for _, a := range targets {
+		if l := f.levels[a]; l <= limit || (l == EnrichTraces && limit >= EnrichReceipts) {
+			kept = append(kept, a)
+		}
+	}
+	return kept, min(f.level(kept), limit)
+}
+
+// warm 把最近 warmup 个区块交给 Warmer 预热；开启断点续传时预热到断点为止（之后的区块由回补正常处理），
+// 否则预热到当前最新区块，并把它当作已处理，实时处理从下一个区块开始
+func (f *BlockFetcher) warm(ctx context.Context) error {
+	var warmers []BlockAnalyzer
+	for _, a := range f.analyzers {
+		if _, ok := a.(Warmer); ok {
+			warmers = append(warmers, a)
+		}
+	}
+	if len(warmers) == 0 {
+		return nil
+	}
+
+	var end *types.Header
+	if f.checkpoints != nil {
+		cur, ok, err := f.checkpoints.Load(fetcherCheckpoint)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if end, err = f.headerByNumber(ctx, new(big.Int).SetUint64(cur.BlockNumber)); err != nil {
+				return fmt.Errorf("查询断点区块失败: %w", err)
+			}
+		}
+	}
+	resuming := end != nil
+	if !resuming {
+		head, err := f.headerByNumber(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("查询最新区块失败: %w", err)
+		}
+		end = head
+	}
+
+	to := end.Number.Uint64()
+	from := uint64(0)
+	if to+1 > f.warmup {
+		from = to + 1 - f.warmup
+	}
+	log.Printf("🔥 预热区块 %d - %d（%d 个分析器）", from, to, len(warmers))
+	start := time.Now()
+	for n := from; n <= to; n++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		header := end
+		if n != to {
+			var err error
+			if header, err = f.headerByNumber(ctx, new(big.Int).SetUint64(n)); err != nil {
+				return fmt.Errorf("查询区块 %d 失败: %w", n, err)
+			}
+		}
+		var targets []BlockAnalyzer
+		for _, a := range f.targets(header) {
+			if _, ok := a.(Warmer); ok {
+				targets = append(targets, a)
+			}
+		}
+		if len(targets) == 0 {
+			continue
+		}
+		data, err := f.fetch(ctx, header, f.level(targets))
+		if err != nil {
+			return fmt.Errorf("拉取区块 %d 失败: %w", n, err)
+		}
+		f.fetched.Inc(1)
+		for _, a := range targets {
+			a.(Warmer).Warm(data)
+		}
+	}
+	if !resuming {
+		// 不保存断点：预热过的区块没有交给其它分析器
+		cur := IndexCursor{BlockNumber: to, BlockHash: end.Hash()}
+		f.last = &cur
+		f.recent[cur.BlockNumber] = cur.BlockHash
+	}
+	log.Printf("🔥 预热完成，用时 %s", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// backfill 按高度依次处理 [from, to] 的区块，超过 maxBackfill 时只处理最近的部分；
+// 某个区块失败时停止，下一个新区块到达时从断点继续
+func (f *BlockFetcher) backfill(ctx context.Context, from, to uint64) {
+	if to < from {
+		return
+	}
+	if to-from+1 > f.maxBackfill {
+		log.Printf("⚠️  落后 %d 个区块，超过回补上限 %d，跳过区块 %d - %d", to-from+1, f.maxBackfill, from, to-f.maxBackfill)
+		from = to - f.maxBackfill + 1
+	}
+	log.Printf("⏪ 回补区块 %d - %d", from, to)
+	for n := from; n <= to; n++ {
+		if ctx.Err() != nil {
+			return
+		}
+		header, err := f.headerByNumber(ctx, new(big.Int).SetUint64(n))
+		if err != nil {
+			log.Printf("⚠️  回补区块 %d 失败: %v", n, err)
+			return
+		}
+		if !f.process(ctx, header, time.Now()) {
+			return
+		}
+	}
+}
+
+// process 拉取区块并分发给需要它的分析器，处理完（或所有分析器都不需要）后推进断点
+func (f *BlockFetcher) process(ctx context.Context, header *types.Header, received time.Time) bool {
+	targets, level := f.budgeted(f.targets(header))
+	if len(targets) == 0 {
+		f.skipped.Inc(1)
+		f.markProcessed(header)
+		return true
+	}
+	data, err := f.fetch(ctx, header, level)
+	if err != nil {
+		log.Printf("⚠️  拉取区块 %d 失败: %v", header.Number, err)
+		return false
+	}
+	f.fetched.Inc(1)
+	for _, a := range targets {
+		a.OnBlock(data)
+	}
+	observeSince(blockProcessing, received)
+	f.markProcessed(header)
+	return true
+}
+
+// markProcessed 记录最后一个处理完的区块并保存断点
+func (f *BlockFetcher) markProcessed(header *types.Header) {
+	cur := IndexCursor{BlockNumber: header.Number.Uint64(), BlockHash: header.Hash()}
+	f.last = &cur
+	f.lastBlock.Store(cur.BlockNumber)
+	f.recent[cur.BlockNumber] = cur.BlockHash
+	if cur.BlockNumber >= fetcherRecentBlocks {
+		delete(f.recent, cur.BlockNumber-fetcherRecentBlocks)
+	}
+	if f.checkpoints != nil {
+		if err := f.checkpoints.Save(fetcherCheckpoint, cur); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+	}
+}
+
+// call 带重试地调用节点；配置了备用节点时每次尝试都由 EndpointPool 选择未熔断的节点
+func (f *BlockFetcher) call(ctx context.Context, what string, fn func(c *ethclient.Client) error) error {
+	return poolCall(ctx, f.pool, f.client, what, fn)
+}
+
+// headerByNumber 带重试的 HeaderByNumber，number 为 nil 时查询最新区块
+func (f *BlockFetcher) headerByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	var header *types.Header
+	err := f.call(ctx, "eth_getBlockByNumber", func(c *ethclient.Client) (err error) {
+		header, err = c.HeaderByNumber(ctx, number)
+		return err
+	})
+	return header, err
+}
+
+// targets 用区块头的 logsBloom 预筛出需要这个区块的分析器
+func (f *BlockFetcher) targets(header *types.Header) []BlockAnalyzer {
+	targets := make([]BlockAnalyzer, 0, len(f.analyzers))
+	for _, a := range f.analyzers {
+		lf, ok := a.(LogFilterer)
+		if !ok {
+			targets = append(targets, a)
+			continue
+		}
+		for _, q := range lf.LogFilters() {
+			if bloomMayMatch(header.Bloom, q) {
+				targets = append(targets, a)
+				break
+			}
+		}
+	}
+	return targets
+}
+
+// addressTopics 地址按 indexed 参数的编码（左补零到 32 字节）转换为 topic
+func addressTopics(addrs []common.Address) []common.Hash {
+	topics := make([]common.Hash, len(addrs))
+	for i, a := range addrs {
+		topics[i] = common.BytesToHash(a.Bytes())
+	}
+	return topics
+}
+
+// bloomMayMatch 布隆过滤器只会误报、不会漏报：返回 false 时区块中一定没有匹配的日志
+// 语义与 eth_getLogs 相同：地址任一匹配，且每个位置的 topic 任一匹配（空表示任意）
+func bloomMayMatch(bloom types.Bloom, q ethereum.FilterQuery) bool {
+	if len(q.Addresses) > 0 {
+		found := false
+		for _, a := range q.Addresses {
+			if types.BloomLookup(bloom, a) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, sub := range q.Topics {
+		if len(sub) == 0 {
+			continue
+		}
+		found := false
+		for _, t := range sub {
+			if types.BloomLookup(bloom, t) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// fetch 按 level 拉取区块数据；按区块哈希拉取（而不是高度），保证区块和回执来自同一个分叉
+// 回执和调用树不依赖区块体，三者并行请求，一个区块的延迟约等于最慢的那个请求
+func (f *BlockFetcher) fetch(ctx context.Context, header *types.Header, level EnrichLevel) (*BlockData, error) {
+	if level == EnrichHeader {
+		return &BlockData{Block: types.NewBlockWithHeader(header), Level: EnrichHeader}, nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	hash := header.Hash()
+	data := &BlockData{}
+
+	var receipts <-chan receiptsResult
+	if level >= EnrichReceipts {
+		receipts = f.startBlockReceipts(ctx, hash)
+	}
+	var traces chan error
+	if level >= EnrichTraces {
+		traces = make(chan error, 1)
+		go func() {
+			calls, err := f.tracer.TraceBlock(ctx, header.Number.Uint64())
+			data.Traces = calls
+			traces <- err
+		}()
+	}
+
+	var block *types.Block
+	err := f.call(ctx, "eth_getBlockByHash", func(c *ethclient.Client) (err error) {
+		block, err = c.BlockByHash(ctx, hash)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("查询区块失败: %w", err)
+	}
+	senders := make([]common.Address, len(block.Transactions()))
+	for i, tx := range block.Transactions() {
+		from, err := types.Sender(f.signer, tx)
+		if err != nil {
+			return nil, fmt.Errorf("恢复交易 %s 发送者失败: %w", tx.Hash().Hex(), err)
+		}
+		senders[i] = from
+	}
+	data.Block, data.Senders, data.Level = block, senders, EnrichTransactions
+	if level < EnrichReceipts {
+		return data, nil
+	}
+
+	if data.Receipts, err = f.awaitReceipts(ctx, block, receipts); err != nil {
+		return nil, err
+	}
+	data.Level = EnrichReceipts
+	if level < EnrichTraces {
+		return data, nil
+	}
+	// trace 失败不影响其它数据，需要调用树的分析器按没有调用树处理
+	if err := <-traces; err != nil {
+		log.Printf("⚠️  区块 %d trace 失败: %v（本区块没有调用树）", header.Number, err)
+		data.Traces = nil
+		return data, nil
+	}
+	data.Level = EnrichTraces
+	return data, nil
+}
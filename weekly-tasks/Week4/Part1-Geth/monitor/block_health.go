@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// 合并 (The Merge) 之后每个 slot 固定 12 秒，间隔超过 12 秒说明有 slot 没有出块
+	SlotDuration = 12 * time.Second
+	// 滚动统计窗口（区块数）
+	BlockHealthWindow = 100
+)
+
+// blockSample 窗口中的一个区块
+type blockSample struct {
+	number      uint64
+	interval    time.Duration
+	missed      int
+	utilization float64 // gasUsed / gasLimit，百分比
+	txCount     int     // 区块拉取之后才知道，-1 表示还没有拉取
+}
+
+// BlockHealth 出块健康度统计：滚动窗口内的出块间隔、丢失的 slot、gas 使用率和交易数
+// 间隔、丢失的 slot 和 gas 使用率在区块头到达时计入，并入新区块摘要（new_head）；交易数等区块拉取后补上
+type BlockHealth struct {
+	mu     sync.Mutex
+	last   *types.Header
+	window []blockSample
+
+	intervalMean   *metrics.GaugeFloat64
+	intervalP95    *metrics.GaugeFloat64
+	missedSlots    *metrics.Counter
+	gasUtilization *metrics.GaugeFloat64
+	txCount        *metrics.Gauge
+}
+
+func NewBlockHealth() *BlockHealth {
+	return &BlockHealth{
+		intervalMean:   metrics.NewRegisteredGaugeFloat64("monitor/block/interval_mean_seconds", metricsRegistry),
+		intervalP95:    metrics.NewRegisteredGaugeFloat64("monitor/block/interval_p95_seconds", metricsRegistry),
+		missedSlots:    metrics.NewRegisteredCounter("monitor/block/missed_slots", metricsRegistry),
+		gasUtilization: metrics.NewRegisteredGaugeFloat64("monitor/block/gas_utilization", metricsRegistry),
+		txCount:        metrics.NewRegisteredGauge("monitor/block/tx_count", metricsRegistry),
+	}
+}
+
+// Warm 实现 Warmer：只填充滚动窗口，预热区块中丢失的 slot 不计入 missed_slots 指标（它们发生在启动之前）
+func (b *BlockHealth) Warm(data *BlockData) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if s := b.record(data.Block.Header(), false); s != nil {
+		s.txCount = len(data.Block.Transactions())
+	}
+}
+
+// Enrichment 实现 Enricher：只看区块头和交易，不需要收据
+func (b *BlockHealth) Enrichment() EnrichLevel { return EnrichTransactions }
+
+// OnBlock 实现 BlockAnalyzer：补上窗口中这个区块的交易数
+func (b *BlockHealth) OnBlock(data *BlockData) {
+	count := len(data.Block.Transactions())
+	b.txCount.Update(int64(count))
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := len(b.window) - 1; i >= 0; i-- {
+		if b.window[i].number == data.Block.NumberU64() {
+			b.window[i].txCount = count
+			return
+		}
+	}
+}
+
+// OnHead 区块头到达时计入滚动窗口并更新指标，返回新区块摘要中的健康度部分和对应的数据；
+// 第一个区块、不相邻的区块或未开启统计（b 为 nil）时返回空
+func (b *BlockHealth) OnHead(header *types.Header) (string, map[string]interface{}) {
+	if b == nil {
+		return "", nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.record(header, true)
+	if s == nil {
+		return "", nil
+	}
+	return b.summary(s)
+}
+
+// record 把区块加入滚动窗口并更新指标，返回窗口中本区块的样本（第一个区块或不相邻的区块返回 nil）；
+// live 为 false 时（预热）不计入 missed_slots。调用方持有锁
+func (b *BlockHealth) record(header *types.Header, live bool) *blockSample {
+	utilization := 0.0
+	if header.GasLimit > 0 {
+		utilization = float64(header.GasUsed) / float64(header.GasLimit) * 100
+	}
+	b.gasUtilization.Update(utilization)
+
+	last := b.last
+	b.last = header
+	// 只有和上一个区块相邻时才能计算间隔（重组或跳块时重新开始）
+	if last == nil || header.Number.Uint64() != last.Number.Uint64()+1 || header.Time < last.Time {
+		return nil
+	}
+
+	interval := time.Duration(header.Time-last.Time) * time.Second
+	missed := int(interval/SlotDuration) - 1
+	if missed < 0 {
+		missed = 0
+	}
+	b.window = append(b.window, blockSample{
+		number:      header.Number.Uint64(),
+		interval:    interval,
+		missed:      missed,
+		utilization: utilization,
+		txCount:     -1,
+	})
+	if len(b.window) > BlockHealthWindow {
+		b.window = b.window[1:]
+	}
+
+	intervals := b.intervals()
+	b.intervalMean.Update(mean(intervals).Seconds())
+	b.intervalP95.Update(percentile(intervals, 0.95).Seconds())
+	if live {
+		b.missedSlots.Inc(int64(missed))
+	}
+	return &b.window[len(b.window)-1]
+}
+
+// intervals 窗口内的出块间隔（调用方持有锁）
+func (b *BlockHealth) intervals() []time.Duration {
+	out := make([]time.Duration, len(b.window))
+	for i, s := range b.window {
+		out[i] = s.interval
+	}
+	return out
+}
+
+// summary 新区块摘要中的出块健康度部分（调用方持有锁）
+func (b *BlockHealth) summary(s *blockSample) (string, map[string]interface{}) {
+	intervals := b.intervals()
+	windowMiss, utilSum, txSum, txBlocks := 0, 0.0, 0, 0
+	for _, w := range b.window {
+		windowMiss += w.missed
+		utilSum += w.utilization
+		if w.txCount >= 0 {
+			txSum += w.txCount
+			txBlocks++
+		}
+	}
+	n := float64(len(b.window))
+	// 丢块率 = 丢失 slot / (出块数 + 丢失 slot)
+	missRate := float64(windowMiss) / (n + float64(windowMiss)) * 100
+	// 本块的交易数要等区块拉取之后才知道，这里只显示已拉取区块的均值
+	txMean := 0.0
+	if txBlocks > 0 {
+		txMean = float64(txSum) / float64(txBlocks)
+	}
+
+	line := fmt.Sprintf("   ⏱️  间隔 %s (近 %d 块均值 %.1fs, P95 %s) | Gas 使用率 %.1f%% (均值 %.1f%%) | 交易数均值 %.0f | 丢失 slot 窗口内 %d (%.1f%%)",
+		s.interval, len(b.window), mean(intervals).Seconds(), percentile(intervals, 0.95),
+		s.utilization, utilSum/n, txMean, windowMiss, missRate)
+	if s.missed > 0 {
+		line += fmt.Sprintf("\n   🕳️  区块 %d 之前丢失了 %d 个 slot", s.number, s.missed)
+	}
+	data := map[string]interface{}{
+		"interval_seconds": s.interval.Seconds(), "interval_mean_seconds": mean(intervals).Seconds(),
+		"interval_p95_seconds": percentile(intervals, 0.95).Seconds(), "missed_slots": s.missed,
+		"window_missed_slots": windowMiss, "gas_utilization": s.utilization, "tx_count_mean": txMean,
+	}
+	return line, data
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// 发送者至少有这么多笔 Pending 交易才开始判断
+	DefaultBotMinTxs = 10
+	// 每新增多少笔交易重新计算一次指纹
+	BotReclassifyEvery = 10
+	// 超过这个时间没有新交易的发送者清理内存中的行为画像（已保存的聚类不受影响）
+	BotProfileIdle = time.Hour
+	// 每个发送者保留的最近交易间隔数
+	botIntervalWindow = 64
+	// 满足这么多条机器人特征时判定为机器人
+	botScoreThreshold = 3
+	// 每小时交易数超过该值算作"高频"
+	botHighRatePerHour = 60
+)
+
+// BotConfig 交易池发送者聚类和机器人识别
+type BotConfig struct {
+	Enabled bool   `json:"enabled"`
+	DB      string `json:"db"`      // SQLite 文件，默认与索引共用，保存发送者的聚类结果
+	MinTxs  int    `json:"min_txs"` // 发送者至少有这么多笔交易才判断
+}
+
+// SenderCluster 一个发送者的聚类结果
+type SenderCluster struct {
+	Sender      common.Address `json:"sender"`
+	Cluster     string         `json:"cluster"`     // 指纹的短哈希，指纹相同的发送者属于同一个聚类
+	Fingerprint string         `json:"fingerprint"` // 目标|gas limit|tip|时间规律
+	Bot         bool           `json:"bot"`
+	Score       int            `json:"score"` // 满足的机器人特征数
+	Txs         int            `json:"txs"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// BotActivity 已识别的机器人发出的一笔 Pending 交易
+type BotActivity struct {
+	SenderCluster
+	Hash     common.Hash     `json:"hash"`
+	To       *common.Address `json:"to"`
+	Selector string          `json:"selector,omitempty"`
+}
+
+// senderProfile 一个发送者在交易池中的行为画像
+type senderProfile struct {
+	txs       int
+	last      time.Time
+	intervals []time.Duration
+	targets   map[common.Address]int
+	gasLimit  uint64
+	fixedGas  bool
+	tip       *big.Int
+	fixedTip  bool
+	zeroTips  int
+	nonces    map[uint64]bool
+	replaced  int // 同一个 nonce 再次出现（加价替换）的次数
+}
+
+// BotClassifier 按行为指纹（gas 策略、目标合约、时间规律）给交易池中的发送者聚类，识别疑似机器人，
+// 之后由它们发出的交易都带上聚类标签；聚类结果保存在数据库中，重启后继续生效
+type BotClassifier struct {
+	minTxs int
+	signer types.Signer
+	store  *BotStore
+
+	mu        sync.Mutex
+	profiles  map[common.Address]*senderProfile
+	labels    map[common.Address]SenderCluster
+	lastPrune time.Time
+
+	onDetect   func(SenderCluster)
+	onActivity func(BotActivity)
+}
+
+// NewBotClassifier 创建分类器并加载之前保存的聚类，onDetect / onActivity 为 nil 时使用默认输出
+func NewBotClassifier(cfg BotConfig, signer types.Signer, store *BotStore, onDetect func(SenderCluster), onActivity func(BotActivity)) (*BotClassifier, error) {
+	if onDetect == nil {
+		onDetect = PrintBotDetected
+	}
+	if onActivity == nil {
+		onActivity = PrintBotActivity
+	}
+	saved, err := store.All()
+	if err != nil {
+		return nil, err
+	}
+	c := &BotClassifier{
+		minTxs:     cfg.MinTxs,
+		signer:     signer,
+		store:      store,
+		profiles:   make(map[common.Address]*senderProfile),
+		labels:     make(map[common.Address]SenderCluster, len(saved)),
+		onDetect:   onDetect,
+		onActivity: onActivity,
+	}
+	for _, s := range saved {
+		c.labels[s.Sender] = s
+	}
+	return c, nil
+}
+
+// Observe 更新发送者的行为画像（只处理完整交易），已识别为机器人的发送者输出这笔交易
+func (c *BotClassifier) Observe(msg BusMessage) {
+	tx := msg.Tx
+	if tx == nil {
+		return
+	}
+	from, err := types.Sender(c.signer, tx)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	p := c.profile(from, tx)
+	if !msg.Received.Before(p.last) && !p.last.IsZero() {
+		p.intervals = append(p.intervals, msg.Received.Sub(p.last))
+		if len(p.intervals) > botIntervalWindow {
+			p.intervals = p.intervals[1:]
+		}
+	}
+	p.last = msg.Received
+	p.txs++
+	if to := tx.To(); to != nil {
+		p.targets[*to]++
+	}
+	p.fixedGas = p.fixedGas && tx.Gas() == p.gasLimit
+	p.fixedTip = p.fixedTip && tx.GasTipCap().Cmp(p.tip) == 0
+	if tx.GasTipCap().Sign() == 0 {
+		p.zeroTips++
+	}
+	if p.nonces[tx.Nonce()] {
+		p.replaced++
+	}
+	p.nonces[tx.Nonce()] = true
+
+	var changed, detected bool
+	var cluster SenderCluster
+	if p.txs >= c.minTxs && (p.txs-c.minTxs)%BotReclassifyEvery == 0 {
+		cluster = classifySender(from, p, msg.Received)
+		prev, ok := c.labels[from]
+		changed = !ok || prev.Cluster != cluster.Cluster || prev.Bot != cluster.Bot
+		detected = cluster.Bot && (!ok || !prev.Bot)
+		c.labels[from] = cluster
+	}
+	label, labeled := c.labels[from]
+	prune := msg.Received.Sub(c.lastPrune) > time.Minute
+	if prune {
+		c.lastPrune = msg.Received
+		for addr, p := range c.profiles {
+			if msg.Received.Sub(p.last) > BotProfileIdle {
+				delete(c.profiles, addr)
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	if changed {
+		if err := c.store.Save(cluster); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+	}
+	if detected {
+		c.onDetect(cluster)
+	}
+	if labeled && label.Bot {
+		activity := BotActivity{SenderCluster: label, Hash: tx.Hash(), To: tx.To()}
+		if len(tx.Data()) >= 4 {
+			activity.Selector = hexutil.Encode(tx.Data()[:4])
+		}
+		c.onActivity(activity)
+	}
+}
+
+// profile 取出（不存在时创建）发送者的画像（需持有锁）
+func (c *BotClassifier) profile(from common.Address, tx *types.Transaction) *senderProfile {
+	p, ok := c.profiles[from]
+	if !ok {
+		p = &senderProfile{
+			targets:  make(map[common.Address]int),
+			gasLimit: tx.Gas(),
+			fixedGas: true,
+			tip:      tx.GasTipCap(),
+			fixedTip: true,
+			nonces:   make(map[uint64]bool),
+		}
+		c.profiles[from] = p
+	}
+	return p
+}
+
+// classifySender 由画像计算指纹和机器人特征数
+func classifySender(from common.Address, p *senderProfile, now time.Time) SenderCluster {
+	score := 0
+
+	// 目标：80% 以上的交易发往同一个合约时记为该合约
+	target := "diverse"
+	var top common.Address
+	topCount := 0
+	for addr, n := range p.targets {
+		if n > topCount {
+			top, topCount = addr, n
+		}
+	}
+	if topCount*5 >= p.txs*4 {
+		target = top.Hex()
+		score++
+	} else if len(p.targets) <= 3 {
+		target = "few"
+	}
+
+	gas := "varied-gas"
+	if p.fixedGas {
+		gas = "fixed-gas"
+		score++
+	}
+
+	tip := "varied-tip"
+	switch {
+	case p.zeroTips == p.txs:
+		tip = "zero-tip"
+		score++
+	case p.fixedTip:
+		tip = "fixed-tip"
+		score++
+	}
+	if p.replaced*5 >= p.txs {
+		tip += "+replace"
+		score++
+	}
+
+	timing := "irregular"
+	if len(p.intervals) >= 2 {
+		var sum, sq float64
+		for _, d := range p.intervals {
+			sum += d.Seconds()
+		}
+		avg := sum / float64(len(p.intervals))
+		for _, d := range p.intervals {
+			sq += (d.Seconds() - avg) * (d.Seconds() - avg)
+		}
+		cv := math.Sqrt(sq/float64(len(p.intervals))) / math.Max(avg, 1e-9)
+		switch {
+		case avg < 3:
+			timing = "burst"
+			score++
+		case cv < 0.3:
+			timing = "periodic"
+			score++
+		}
+		if sum > 0 && float64(len(p.intervals))/(sum/3600) >= botHighRatePerHour {
+			score++
+		}
+	}
+
+	fingerprint := strings.Join([]string{target, gas, tip, timing}, "|")
+	return SenderCluster{
+		Sender:      from,
+		Cluster:     crypto.Keccak256Hash([]byte(fingerprint)).Hex()[2:10],
+		Fingerprint: fingerprint,
+		Bot:         score >= botScoreThreshold,
+		Score:       score,
+		Txs:         p.txs,
+		UpdatedAt:   now,
+	}
+}
+
+// Label 发送者的聚类结果（本次运行或之前保存的）
+func (c *BotClassifier) Label(addr common.Address) (SenderCluster, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.labels[addr]
+	return s, ok
+}
+
+// BotClusterSummary 一个聚类的汇总
+type BotClusterSummary struct {
+	Cluster     string `json:"cluster"`
+	Fingerprint string `json:"fingerprint"`
+	Bot         bool   `json:"bot"`
+	Senders     int    `json:"senders"`
+	Txs         int    `json:"txs"`
+}
+
+// Clusters 各聚类的汇总，按发送者数量排序
+func (c *BotClassifier) Clusters() []BotClusterSummary {
+	c.mu.Lock()
+	byCluster := make(map[string]*BotClusterSummary)
+	for _, s := range c.labels {
+		sum, ok := byCluster[s.Cluster]
+		if !ok {
+			sum = &BotClusterSummary{Cluster: s.Cluster, Fingerprint: s.Fingerprint, Bot: s.Bot}
+			byCluster[s.Cluster] = sum
+		}
+		sum.Senders++
+		sum.Txs += s.Txs
+	}
+	c.mu.Unlock()
+	out := make([]BotClusterSummary, 0, len(byCluster))
+	for _, s := range byCluster {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Senders > out[j].Senders })
+	return out
+}
+
+// RegisterAPI 注册机器人识别查询接口
+//
+//	GET /api/bots             各聚类的汇总
+//	GET /api/bots/{address}   发送者的聚类结果
+func (c *BotClassifier) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/bots", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, c.Clusters())
+	})
+	api.Handle("GET /api/bots/{address}", func(w http.ResponseWriter, r *http.Request) {
+		addr := r.PathValue("address")
+		if !common.IsHexAddress(addr) {
+			writeError(w, http.StatusBadRequest, "地址格式错误")
+			return
+		}
+		s, ok := c.Label(common.HexToAddress(addr))
+		if !ok {
+			writeError(w, http.StatusNotFound, "该地址还没有足够的交易用于聚类")
+			return
+		}
+		writeJSON(w, http.StatusOK, s)
+	})
+}
+
+// PrintBotDetected 默认的机器人识别输出
+func PrintBotDetected(s SenderCluster) {
+	summary := fmt.Sprintf("🤖 [Bot] %s 疑似机器人（聚类 %s，%d 条特征，%d 笔交易）", s.Sender.Hex(), s.Cluster, s.Score, s.Txs)
+	Emit(Event{Type: "bot_detected", Key: eventKey(s.Sender, s.Cluster), Summary: summary, Text: summary + "\n   指纹: " + s.Fingerprint, Data: s})
+}
+
+// PrintBotActivity 默认的机器人交易输出
+func PrintBotActivity(a BotActivity) {
+	to := "合约创建"
+	if a.To != nil {
+		to = a.To.Hex()
+	}
+	summary := fmt.Sprintf("🤖 [Bot:%s] %s -> %s %s", a.Cluster, a.Sender.Hex(), to, a.Selector)
+	Emit(Event{Type: "bot_tx", Key: a.Hash.Hex(), Summary: summary, Text: summary + "\n   交易: " + a.Hash.Hex(), Data: a})
+}
+
+// ------------------------------------------------
+// 聚类结果的 SQLite 存储
+// ------------------------------------------------
+
+const botSchema = `
+CREATE TABLE IF NOT EXISTS sender_clusters (
+	sender      TEXT PRIMARY KEY,
+	cluster     TEXT    NOT NULL,
+	fingerprint TEXT    NOT NULL,
+	bot         INTEGER NOT NULL,
+	score       INTEGER NOT NULL,
+	txs         INTEGER NOT NULL,
+	updated_at  INTEGER NOT NULL
+);`
+
+// BotStore 发送者聚类的 SQLite 存储
+type BotStore struct {
+	db *sql.DB
+}
+
+func OpenBotStore(path string) (*BotStore, error) {
+	db, err := openSQLite(path, botSchema)
+	if err != nil {
+		return nil, err
+	}
+	return &BotStore{db: db}, nil
+}
+
+// Save 写入（覆盖）一个发送者的聚类结果
+func (s *BotStore) Save(c SenderCluster) error {
+	if _, err := s.db.Exec(`INSERT OR REPLACE INTO sender_clusters
+		(sender, cluster, fingerprint, bot, score, txs, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		c.Sender.Hex(), c.Cluster, c.Fingerprint, c.Bot, c.Score, c.Txs, c.UpdatedAt.Unix()); err != nil {
+		return fmt.Errorf("保存发送者聚类失败: %w", err)
+	}
+	return nil
+}
+
+// All 读取全部聚类结果
+func (s *BotStore) All() ([]SenderCluster, error) {
+	rows, err := s.db.Query(`SELECT sender, cluster, fingerprint, bot, score, txs, updated_at FROM sender_clusters`)
+	if err != nil {
+		return nil, fmt.Errorf("读取发送者聚类失败: %w", err)
+	}
+	defer rows.Close()
+	var out []SenderCluster
+	for rows.Next() {
+		var c SenderCluster
+		var sender string
+		var updated int64
+		if err := rows.Scan(&sender, &c.Cluster, &c.Fingerprint, &c.Bot, &c.Score, &c.Txs, &updated); err != nil {
+			return nil, err
+		}
+		c.Sender = common.HexToAddress(sender)
+		c.UpdatedAt = time.Unix(updated, 0)
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (s *BotStore) Close() error {
+	return s.db.Close()
+}
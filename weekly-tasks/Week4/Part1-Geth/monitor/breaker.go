@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ------------------------------------------------
+// 多节点熔断：每个节点一个熔断器，窗口内错误率或平均延迟超过阈值时熔断，
+// 读请求改发其它健康节点；熔断的节点定期用 eth_blockNumber 探测，成功后恢复
+// 订阅仍然只连主节点，这里只分流区块 / 回执等非订阅请求；按权重分流见 load_balance.go
+// ------------------------------------------------
+
+const (
+	DefaultBreakerErrorRate     = 0.5
+	DefaultBreakerLatencyMs     = 3000
+	DefaultBreakerMinRequests   = 10
+	DefaultBreakerWindowSeconds = 60
+	DefaultBreakerProbeSeconds  = 30
+)
+
+// RPCPoolConfig 多节点分流，endpoints 为空时不启用（所有请求发往主节点）
+type RPCPoolConfig struct {
+	Endpoints        []PoolEndpointConfig `json:"endpoints"`         // 主节点之外的备用节点，ws_url 也可以是 http 地址
+	Strategy         string               `json:"strategy"`          // failover（默认）| weighted
+	PrimaryWeight    int                  `json:"primary_weight"`    // strategy 为 weighted 时主节点的权重
+	PinSubscriptions bool                 `json:"pin_subscriptions"` // 启动时把订阅固定到延迟最低的 WebSocket 节点
+	ErrorRate        float64              `json:"error_rate"`        // 窗口内错误率超过时熔断
+	LatencyMs        int                  `json:"latency_ms"`        // 窗口内平均延迟超过时熔断
+	MinRequests      int                  `json:"min_requests"`      // 窗口内请求数达到后才判断，避免一两次失败就熔断
+	WindowSeconds    int                  `json:"window_seconds"`    // 统计窗口
+	ProbeSeconds     int                  `json:"probe_seconds"`     // 熔断后每隔多久探测一次
+}
+
+// EndpointStatus /api/endpoints 中一个节点的状态
+type EndpointStatus struct {
+	Name       string        `json:"name"`
+	Weight     int           `json:"weight"`
+	Total      int           `json:"total"` // 启动以来发往这个节点的请求数
+	Open       bool          `json:"open"`  // true 表示已熔断
+	Requests   int           `json:"requests"`
+	Failures   int           `json:"failures"`
+	AvgLatency time.Duration `json:"avg_latency"`
+	Trips      int           `json:"trips"`
+	OpenedAt   *time.Time    `json:"opened_at,omitempty"`
+	Reason     string        `json:"reason,omitempty"`
+}
+
+// BreakerEvent 熔断 / 恢复
+type BreakerEvent struct {
+	Endpoint string `json:"endpoint"`
+	Open     bool   `json:"open"`
+	Reason   string `json:"reason"`
+}
+
+// poolEndpoint 一个节点及其熔断器状态
+type poolEndpoint struct {
+	name    string
+	rpc     *rpc.Client
+	client  *ethclient.Client
+	metered bool // HTTP 节点，请求已经在传输层统计
+	weight  int
+	total   int
+
+	current int // 平滑加权轮询的当前值
+
+	open     bool
+	openedAt time.Time
+	reason   string
+	trips    int
+
+	windowStart time.Time
+	requests    int
+	failures    int
+	latency     time.Duration // 窗口内的总延迟
+}
+
+// EndpointPool failover 时按顺序选择第一个未熔断的节点（主节点在最前面），weighted 时按权重轮流选择，
+// 失败时依次尝试其它节点
+type EndpointPool struct {
+	cfg       RPCPoolConfig
+	mu        sync.Mutex
+	endpoints []*poolEndpoint
+
+	trips *metrics.Counter
+
+	onEvent func(BreakerEvent)
+}
+
+// NewEndpointPool 连接备用节点并确认和主节点在同一条链上，onEvent 为 nil 时使用默认输出
+func NewEndpointPool(ctx context.Context, cfg RPCPoolConfig, primary *Clients, onEvent func(BreakerEvent)) (*EndpointPool, error) {
+	if onEvent == nil {
+		onEvent = PrintBreakerEvent
+	}
+	chainID, err := chainIDRetry(ctx, primary.Eth)
+	if err != nil {
+		return nil, fmt.Errorf("查询主节点 chainID 失败: %w", err)
+	}
+	p := &EndpointPool{
+		cfg:       cfg,
+		endpoints: []*poolEndpoint{{name: "primary", rpc: primary.RPC, client: primary.Eth, metered: !retryCounts.Load(), weight: endpointWeight(cfg.PrimaryWeight), windowStart: time.Now()}},
+		trips:     metrics.NewRegisteredCounter("monitor/rpc/breaker_trips", metricsRegistry),
+		onEvent:   onEvent,
+	}
+	for _, ep := range cfg.Endpoints {
+		dialCtx, cancel := context.WithTimeout(ctx, CONNECTION_TIMEOUT)
+		c, err := dialRPC(dialCtx, ep.WSURL)
+		cancel()
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("连接节点 %s 失败: %w", ep.Name, err)
+		}
+		client := ethclient.NewClient(c)
+		id, err := chainIDRetry(ctx, client)
+		if err == nil && id.Cmp(chainID) != 0 {
+			err = fmt.Errorf("chainID %s 与主节点的 %s 不一致", id, chainID)
+		}
+		if err != nil {
+			c.Close()
+			p.Close()
+			return nil, fmt.Errorf("节点 %s: %w", ep.Name, err)
+		}
+		p.endpoints = append(p.endpoints, &poolEndpoint{name: ep.Name, rpc: c, client: client, metered: transportMetered(ep.WSURL), weight: endpointWeight(ep.Weight), windowStart: time.Now()})
+	}
+	return p, nil
+}
+
+// Close 关闭备用节点的连接（主节点由调用方关闭）
+func (p *EndpointPool) Close() {
+	for _, ep := range p.endpoints[1:] {
+		ep.rpc.Close()
+	}
+}
+
+// Do 在 pick 选出的第一个节点上执行 fn，what 为方法名（不走 HTTP 的节点在这里计入 RPC 用量统计）；临时错误（以及节点还没有同步到的 not found）换下一个节点再试
+// 所有节点都熔断时仍按顺序尝试，不会因为熔断而拒绝请求
+func (p *EndpointPool) Do(ctx context.Context, what string, fn func(c *ethclient.Client) error) error {
+	var err error
+	for _, ep := range p.pick() {
+		start := time.Now()
+		err = fn(ep.client)
+		elapsed := time.Since(start)
+		if !ep.metered {
+			rpcUsage.Observe(what, elapsed, err)
+		}
+		transient := err != nil && isTransient(ctx, err)
+		p.record(ep, transient, elapsed)
+		if err == nil || ctx.Err() != nil || (!transient && !errors.Is(err, ethereum.NotFound)) {
+			return err
+		}
+	}
+	return err
+}
+
+// pick 未熔断的节点在前（weighted 时按权重轮流排在最前），熔断的节点在后
+func (p *EndpointPool) pick() []*poolEndpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	healthy := make([]*poolEndpoint, 0, len(p.endpoints))
+	var open []*poolEndpoint
+	for _, ep := range p.endpoints {
+		if ep.open {
+			open = append(open, ep)
+		} else {
+			healthy = append(healthy, ep)
+		}
+	}
+	if p.cfg.Strategy == BalanceWeighted && len(healthy) > 1 {
+		healthy = p.weighted(healthy)
+	}
+	return append(healthy, open...)
+}
+
+// record 记录一次请求，窗口内错误率或平均延迟超过阈值时熔断
+func (p *EndpointPool) record(ep *poolEndpoint, failed bool, latency time.Duration) {
+	p.mu.Lock()
+	now := time.Now()
+	if now.Sub(ep.windowStart) > time.Duration(p.cfg.WindowSeconds)*time.Second {
+		ep.windowStart, ep.requests, ep.failures, ep.latency = now, 0, 0, 0
+	}
+	ep.requests++
+	ep.total++
+	ep.latency += latency
+	if failed {
+		ep.failures++
+	}
+	var reason string
+	if !ep.open && ep.requests >= p.cfg.MinRequests {
+		rate := float64(ep.failures) / float64(ep.requests)
+		avg := ep.latency / time.Duration(ep.requests)
+		switch {
+		case rate > p.cfg.ErrorRate:
+			reason = fmt.Sprintf("错误率 %.0f%%（%d/%d）", rate*100, ep.failures, ep.requests)
+		case avg > time.Duration(p.cfg.LatencyMs)*time.Millisecond:
+			reason = fmt.Sprintf("平均延迟 %s", avg.Round(time.Millisecond))
+		}
+	}
+	if reason != "" {
+		ep.open, ep.openedAt, ep.reason = true, now, reason
+		ep.trips++
+		p.trips.Inc(1)
+	}
+	p.mu.Unlock()
+	if reason != "" {
+		p.onEvent(BreakerEvent{Endpoint: ep.name, Open: true, Reason: reason})
+	}
+}
+
+// Run 定期探测熔断的节点，直到 ctx 取消
+func (p *EndpointPool) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(p.cfg.ProbeSeconds) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.probe(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// probe 对熔断超过 probe_seconds 的节点发一次 eth_blockNumber，成功则恢复并清空窗口
+func (p *EndpointPool) probe(ctx context.Context) {
+	p.mu.Lock()
+	var due []*poolEndpoint
+	for _, ep := range p.endpoints {
+		if ep.open && time.Since(ep.openedAt) >= time.Duration(p.cfg.ProbeSeconds)*time.Second {
+			due = append(due, ep)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, ep := range due {
+		// 探测不重试：重试成功会让仍在抖动的节点过早恢复
+		probeCtx, cancel := context.WithTimeout(ctx, time.Duration(p.cfg.LatencyMs)*time.Millisecond)
+		_, err := ep.client.BlockNumber(probeCtx)
+		cancel()
+		if err != nil {
+			log.Printf("⚠️  节点 %s 探测失败: %v（保持熔断）", ep.name, err)
+			p.mu.Lock()
+			ep.openedAt = time.Now()
+			p.mu.Unlock()
+			continue
+		}
+		p.mu.Lock()
+		ep.open, ep.reason = false, ""
+		ep.windowStart, ep.requests, ep.failures, ep.latency = time.Now(), 0, 0, 0
+		p.mu.Unlock()
+		p.onEvent(BreakerEvent{Endpoint: ep.name, Open: false, Reason: "探测成功"})
+	}
+}
+
+// Status 每个节点当前的熔断状态和窗口统计
+func (p *EndpointPool) Status() []EndpointStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]EndpointStatus, 0, len(p.endpoints))
+	for _, ep := range p.endpoints {
+		s := EndpointStatus{Name: ep.name, Weight: ep.weight, Total: ep.total, Open: ep.open, Requests: ep.requests, Failures: ep.failures, Trips: ep.trips, Reason: ep.reason}
+		if ep.open {
+			openedAt := ep.openedAt
+			s.OpenedAt = &openedAt
+		}
+		if ep.requests > 0 {
+			s.AvgLatency = ep.latency / time.Duration(ep.requests)
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// RegisterAPI 注册节点状态查询接口
+//
+//	GET /api/endpoints 每个节点的权重、累计请求数、熔断状态、窗口内请求数 / 失败数 / 平均延迟
+func (p *EndpointPool) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/endpoints", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, p.Status())
+	})
+}
+
+// PrintBreakerEvent 默认的熔断 / 恢复输出
+func PrintBreakerEvent(e BreakerEvent) {
+	var summary, severity string
+	if e.Open {
+		summary = fmt.Sprintf("🔌 [Breaker] 节点 %s 已熔断: %s，请求改发其它节点", e.Endpoint, e.Reason)
+	} else {
+		summary = fmt.Sprintf("🔌 [Breaker] 节点 %s 已恢复: %s", e.Endpoint, e.Reason)
+		severity = SeverityInfo // 恢复不需要提醒，熔断按默认级别
+	}
+	Emit(Event{Type: "breaker", Summary: summary, Text: summary, Data: e, Severity: severity})
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ------------------------------------------------
+// 跨链桥监控：解码以太坊主网上 OP Stack / Arbitrum 官方桥和 Across 的存款、取款事件，
+// 关注地址发起或收到的跨链转账输出金额、代币和对端链
+// ------------------------------------------------
+
+const (
+	// /api/bridges 中保留的最近转账数量
+	BridgeHistory = 200
+)
+
+// 跨链方向
+const (
+	BridgeDeposit    = "deposit"    // 从本链转出
+	BridgeWithdrawal = "withdrawal" // 转入本链
+)
+
+// 对端链的 chainId
+const (
+	ChainOptimism = 10
+	ChainBase     = 8453
+	ChainArbitrum = 42161
+)
+
+// bridgeContract 主网上的桥合约，chain 为对端链（Across 的目标链在事件中）
+type bridgeContract struct {
+	name  string
+	chain uint64
+}
+
+// ⚠️ 只包含以太坊主网的地址；其它链上运行时这些地址不会匹配
+var bridgeContracts = map[common.Address]bridgeContract{
+	common.HexToAddress("0x99C9fc46f92E8a1c0deC1b1747d010903E884bE1"): {"Optimism", ChainOptimism}, // L1StandardBridge
+	common.HexToAddress("0x3154Cf16ccdb4C6d922629664174b904d80F2C35"): {"Base", ChainBase},         // L1StandardBridge
+	common.HexToAddress("0xa3A7B6F88361F48403514059F1F16C8E78d60EeC"): {"Arbitrum", ChainArbitrum}, // L1ERC20Gateway
+	common.HexToAddress("0xcEe284F754E854890e311e3280b767F80797180d"): {"Arbitrum", ChainArbitrum}, // L1CustomGateway
+	common.HexToAddress("0xd92023E9d9911199a6711321D1Ae2D51d2C1e3c5"): {"Arbitrum", ChainArbitrum}, // L1WethGateway
+	common.HexToAddress("0x8315177aB297bA92A06054cE80a67Ed4DBd7ed3a"): {"Arbitrum", ChainArbitrum}, // Bridge（ETH 取款）
+	common.HexToAddress("0x5c7BCd6E7De5423a257D81B442095A1a6ced35C5"): {"Across", 0},               // SpokePool
+}
+
+var (
+	// Arbitrum Inbox：ETH 存款直接调用 depositEth()，没有专门的事件
+	arbitrumInbox      = common.HexToAddress("0x4Dbd4fc535Ac27206064B68FfCf827b0A60BAB3f")
+	arbitrumDepositEth = common.FromHex("0x439370b1")
+)
+
+const bridgeEventsABI = `[
+	{"type":"event","name":"ETHDepositInitiated","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"amount","type":"uint256","indexed":false},{"name":"extraData","type":"bytes","indexed":false}]},
+	{"type":"event","name":"ERC20DepositInitiated","inputs":[{"name":"l1Token","type":"address","indexed":true},{"name":"l2Token","type":"address","indexed":true},{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":false},{"name":"amount","type":"uint256","indexed":false},{"name":"extraData","type":"bytes","indexed":false}]},
+	{"type":"event","name":"ETHWithdrawalFinalized","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"amount","type":"uint256","indexed":false},{"name":"extraData","type":"bytes","indexed":false}]},
+	{"type":"event","name":"ERC20WithdrawalFinalized","inputs":[{"name":"l1Token","type":"address","indexed":true},{"name":"l2Token","type":"address","indexed":true},{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":false},{"name":"amount","type":"uint256","indexed":false},{"name":"extraData","type":"bytes","indexed":false}]},
+	{"type":"event","name":"DepositInitiated","inputs":[{"name":"l1Token","type":"address","indexed":false},{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"sequenceNumber","type":"uint256","indexed":true},{"name":"amount","type":"uint256","indexed":false}]},
+	{"type":"event","name":"WithdrawalFinalized","inputs":[{"name":"l1Token","type":"address","indexed":false},{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"exitNum","type":"uint256","indexed":true},{"name":"amount","type":"uint256","indexed":false}]},
+	{"type":"event","name":"BridgeCallTriggered","inputs":[{"name":"outbox","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false},{"name":"data","type":"bytes","indexed":false}]},
+	{"type":"event","name":"V3FundsDeposited","inputs":[{"name":"inputToken","type":"address","indexed":false},{"name":"outputToken","type":"address","indexed":false},{"name":"inputAmount","type":"uint256","indexed":false},{"name":"outputAmount","type":"uint256","indexed":false},{"name":"destinationChainId","type":"uint256","indexed":true},{"name":"depositId","type":"uint32","indexed":true},{"name":"quoteTimestamp","type":"uint32","indexed":false},{"name":"fillDeadline","type":"uint32","indexed":false},{"name":"exclusivityDeadline","type":"uint32","indexed":false},{"name":"depositor","type":"address","indexed":true},{"name":"recipient","type":"address","indexed":false},{"name":"exclusiveRelayer","type":"address","indexed":false},{"name":"message","type":"bytes","indexed":false}]}
+]`
+
+var bridgeABI = mustParseABI(bridgeEventsABI)
+
+// BridgeConfig 跨链桥监控
+type BridgeConfig struct {
+	Enabled bool `json:"enabled"`
+}
+
+// BridgeTransfer 一次跨链转账
+type BridgeTransfer struct {
+	Bridge    string         `json:"bridge"`
+	Direction string         `json:"direction"`
+	Token     common.Address `json:"token"` // 零地址表示 ETH
+	Symbol    string         `json:"symbol"`
+	Amount    *big.Int       `json:"amount"`
+	Display   string         `json:"display"` // 按 decimals 换算后的金额
+	ValueUSD  float64        `json:"value_usd"`
+	Priced    bool           `json:"priced"`
+	From      common.Address `json:"from"`
+	To        common.Address `json:"to"`
+	SrcChain  uint64         `json:"src_chain"`
+	DstChain  uint64         `json:"dst_chain"`
+	Block     uint64         `json:"block"`
+	TxHash    common.Hash    `json:"tx_hash"`
+	Watched   string         `json:"watched"`
+}
+
+// BridgeMonitor 解码桥合约事件，输出关注地址的跨链转账
+type BridgeMonitor struct {
+	chainID uint64
+	watch   *Watchlist
+	tokens  *TokenCache
+	values  *Valuator
+
+	mu     sync.RWMutex
+	recent []BridgeTransfer
+
+	onTransfer func(BridgeTransfer)
+}
+
+// NewBridgeMonitor 创建跨链桥监控，onTransfer 为 nil 时使用默认输出
+func NewBridgeMonitor(chainID *big.Int, watch *Watchlist, tokens *TokenCache, values *Valuator, onTransfer func(BridgeTransfer)) *BridgeMonitor {
+	if onTransfer == nil {
+		onTransfer = PrintBridgeTransfer
+	}
+	return &BridgeMonitor{chainID: chainID.Uint64(), watch: watch, tokens: tokens, values: values, onTransfer: onTransfer}
+}
+
+// OnBlock 实现 BlockAnalyzer
+func (m *BridgeMonitor) OnBlock(data *BlockData) {
+	for i, tx := range data.Block.Transactions() {
+		r := data.Receipts[i]
+		if r.Status == 0 {
+			continue
+		}
+		var transfers []BridgeTransfer
+		if tx.To() != nil && *tx.To() == arbitrumInbox && len(tx.Data()) >= 4 && equalSelector(tx.Data(), arbitrumDepositEth) {
+			// ⚠️ 合约调用 depositEth 时 L2 上的收款地址是别名地址（+0x1111...1111）
+			transfers = append(transfers, BridgeTransfer{
+				Bridge: "Arbitrum", Direction: BridgeDeposit, Amount: tx.Value(),
+				From: data.Senders[i], To: data.Senders[i], SrcChain: m.chainID, DstChain: ChainArbitrum,
+			})
+		}
+		for _, l := range r.Logs {
+			if t, ok := m.decode(l); ok {
+				transfers = append(transfers, t)
+			}
+		}
+		for _, t := range transfers {
+			t.Watched = m.watch.Source(t.From)
+			if t.Watched == "" {
+				t.Watched = m.watch.Source(t.To)
+			}
+			if t.Watched == "" {
+				continue
+			}
+			t.Block, t.TxHash = data.Block.NumberU64(), tx.Hash()
+			m.price(&t)
+			m.record(t)
+			m.onTransfer(t)
+		}
+	}
+}
+
+// decode 把桥合约的一条日志解码为跨链转账
+func (m *BridgeMonitor) decode(l *types.Log) (BridgeTransfer, bool) {
+	bridge, ok := bridgeContracts[l.Address]
+	if !ok || len(l.Topics) == 0 {
+		return BridgeTransfer{}, false
+	}
+	ev, err := bridgeABI.EventByID(l.Topics[0])
+	if err != nil || len(l.Topics) != 1+countIndexed(ev.Inputs) {
+		return BridgeTransfer{}, false
+	}
+	values, err := bridgeABI.Unpack(ev.Name, l.Data)
+	if err != nil {
+		return BridgeTransfer{}, false
+	}
+	topic := func(i int) common.Address { return common.BytesToAddress(l.Topics[i].Bytes()) }
+	t := BridgeTransfer{Bridge: bridge.name, Direction: BridgeDeposit, SrcChain: m.chainID, DstChain: bridge.chain}
+	withdrawal := func() {
+		t.Direction, t.SrcChain, t.DstChain = BridgeWithdrawal, bridge.chain, m.chainID
+	}
+
+	switch ev.Name {
+	case "ETHDepositInitiated", "ETHWithdrawalFinalized":
+		t.From, t.To = topic(1), topic(2)
+		t.Amount, _ = values[0].(*big.Int)
+		if ev.Name == "ETHWithdrawalFinalized" {
+			withdrawal()
+		}
+	case "ERC20DepositInitiated", "ERC20WithdrawalFinalized":
+		t.Token, t.From = topic(1), topic(3)
+		t.To, _ = values[0].(common.Address)
+		t.Amount, _ = values[1].(*big.Int)
+		if ev.Name == "ERC20WithdrawalFinalized" {
+			withdrawal()
+		}
+	case "DepositInitiated", "WithdrawalFinalized":
+		t.Token, _ = values[0].(common.Address)
+		t.From, t.To = topic(1), topic(2)
+		t.Amount, _ = values[1].(*big.Int)
+		if ev.Name == "WithdrawalFinalized" {
+			withdrawal()
+		}
+	case "BridgeCallTriggered":
+		// Outbox 通过 Bridge 执行 L2 -> L1 的消息，data 为空的是 ETH 取款
+		data, _ := values[1].([]byte)
+		if len(data) != 0 {
+			return BridgeTransfer{}, false
+		}
+		t.To = topic(2)
+		t.Amount, _ = values[0].(*big.Int)
+		withdrawal()
+	case "V3FundsDeposited":
+		t.Token, _ = values[0].(common.Address)
+		t.Amount, _ = values[2].(*big.Int)
+		t.DstChain = l.Topics[1].Big().Uint64()
+		t.From = topic(3)
+		t.To, _ = values[7].(common.Address) // 存入 ETH 时 inputToken 是 WETH
+	default:
+		return BridgeTransfer{}, false
+	}
+	if t.Amount == nil || t.Amount.Sign() == 0 {
+		return BridgeTransfer{}, false
+	}
+	return t, true
+}
+
+// countIndexed 事件的 indexed 参数个数
+func countIndexed(args abi.Arguments) int {
+	n := 0
+	for _, a := range args {
+		if a.Indexed {
+			n++
+		}
+	}
+	return n
+}
+
+// price 填充代币符号、显示金额和美元价值
+func (m *BridgeMonitor) price(t *BridgeTransfer) {
+	if t.Token == (common.Address{}) {
+		t.Symbol, t.Display = "ETH", formatUnits(t.Amount, 18)
+		t.ValueUSD, t.Priced = m.values.ETHValue(t.Amount)
+		return
+	}
+	meta, _ := m.tokens.Lookup(t.Token)
+	t.Symbol = meta.Symbol
+	if t.Symbol == "" {
+		t.Symbol = t.Token.Hex()
+	}
+	t.Display = t.Amount.String()
+	if meta.HasDecimals {
+		t.Display = formatUnits(t.Amount, meta.Decimals)
+	}
+	t.ValueUSD, t.Priced = m.values.TokenValue(t.Token, t.Amount)
+}
+
+// record 保存最近的转账
+func (m *BridgeMonitor) record(t BridgeTransfer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recent = append(m.recent, t)
+	if len(m.recent) > BridgeHistory {
+		m.recent = m.recent[len(m.recent)-BridgeHistory:]
+	}
+}
+
+// Recent 最近的跨链转账（新的在前）
+func (m *BridgeMonitor) Recent() []BridgeTransfer {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]BridgeTransfer, len(m.recent))
+	for i, t := range m.recent {
+		out[len(m.recent)-1-i] = t
+	}
+	return out
+}
+
+// RegisterAPI 注册跨链转账查询接口
+//
+//	GET /api/bridges 关注地址最近的跨链存款和取款
+func (m *BridgeMonitor) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/bridges", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, m.Recent())
+	})
+}
+
+// PrintBridgeTransfer 默认的跨链转账输出
+func PrintBridgeTransfer(t BridgeTransfer) {
+	arrow := fmt.Sprintf("链 %d -> 链 %d", t.SrcChain, t.DstChain)
+	what := "存入"
+	if t.Direction == BridgeWithdrawal {
+		what = "取出"
+	}
+	value := ""
+	if t.Priced {
+		value = " (" + formatUSD(t.ValueUSD) + ")"
+	}
+	summary := fmt.Sprintf("🌉 [Bridge][Watched:%s] 区块 %d | %s %s %s %s%s | %s -> %s | %s | %s",
+		t.Watched, t.Block, t.Bridge, what, t.Display, t.Symbol, value, t.From.Hex(), t.To.Hex(), arrow, t.TxHash.Hex())
+	Emit(Event{Type: "bridge", Key: eventKey(t.TxHash, t.Bridge, t.Direction, t.Token, t.From, t.To, t.Amount), Summary: summary, Text: summary, Data: t})
+}
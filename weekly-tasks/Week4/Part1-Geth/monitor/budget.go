@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// ------------------------------------------------
+// RPC 预算：按服务商的计费方式（请求数或 compute unit）估算当天的用量，
+// 接近预算时逐级降级——先停掉调用树，再停掉回执，同时拉长轮询间隔——而不是把额度一口气用完
+// 用量来自 RPC 用量统计（rpc_usage.go），按 UTC 自然日重置
+// ------------------------------------------------
+
+const (
+	DefaultBudgetDegradeAt  = 0.8
+	DefaultBudgetCriticalAt = 0.95
+
+	// 没有出现在 compute_units 和内置表中的方法按这个 CU 计
+	DefaultMethodComputeUnits = 20
+)
+
+// defaultComputeUnits 常用方法的 CU，参考 Alchemy 的计价表
+// ⚠️ 各服务商的计价差别很大，请按自己的服务商在 budget.compute_units 中覆盖
+var defaultComputeUnits = map[string]int{
+	"eth_blockNumber":           10,
+	"eth_chainId":               0,
+	"eth_call":                  26,
+	"eth_getCode":               26,
+	"eth_getBlockByHash":        16,
+	"eth_getBlockByNumber":      16,
+	"eth_getBlockReceipts":      500,
+	"eth_getTransactionReceipt": 15,
+	"eth_getLogs":               75,
+	"trace_block":               24,
+	"trace_transaction":         26,
+	"trace_filter":              75,
+	"debug_traceTransaction":    309,
+	"debug_traceBlockByNumber":  497,
+}
+
+// BudgetConfig 每天的 RPC 预算，daily_requests 和 daily_compute_units 都为 0 时不启用
+type BudgetConfig struct {
+	DailyRequests     int            `json:"daily_requests"`      // 每天的请求数上限，0 表示不限
+	DailyComputeUnits int            `json:"daily_compute_units"` // 每天的 CU 上限，0 表示不限
+	ComputeUnits      map[string]int `json:"compute_units"`       // 按方法覆盖内置的 CU
+	DegradeAt         float64        `json:"degrade_at"`          // 用量达到预算的这个比例时降级：停掉调用树，轮询间隔 ×2
+	CriticalAt        float64        `json:"critical_at"`         // 达到这个比例时进一步降级：停掉回执，轮询间隔 ×4
+}
+
+// BudgetState 预算状态，越大降级越多
+type BudgetState int
+
+const (
+	BudgetNormal BudgetState = iota
+	BudgetDegraded
+	BudgetCritical
+)
+
+func (s BudgetState) String() string {
+	return [...]string{"normal", "degraded", "critical"}[s]
+}
+
+// BudgetStatus /api/budget 的返回内容
+type BudgetStatus struct {
+	Day          string  `json:"day"` // UTC 日期
+	State        string  `json:"state"`
+	Requests     int     `json:"requests"`
+	ComputeUnits int     `json:"compute_units"`
+	Used         float64 `json:"used"` // 请求数和 CU 中占预算比例较高的那个
+}
+
+// BudgetEvent 预算状态变化
+type BudgetEvent struct {
+	BudgetStatus
+	Previous string `json:"previous"`
+}
+
+// Budget 当天的用量和预算状态
+type Budget struct {
+	cfg BudgetConfig
+
+	mu       sync.Mutex
+	day      string
+	requests int
+	units    int
+	state    BudgetState
+	lastPoll map[string]time.Time // 每个轮询任务上次执行的时间，用于拉长间隔
+
+	used *metrics.GaugeFloat64
+
+	onChange func(BudgetEvent)
+}
+
+// rpcBudget 全局预算，未配置时为 nil（不限制）；RPC 统计和轮询任务在各自的协程中读取
+var rpcBudget atomic.Pointer[Budget]
+
+// NewBudget 创建预算，onChange 为 nil 时使用默认输出
+func NewBudget(cfg BudgetConfig, onChange func(BudgetEvent)) *Budget {
+	if onChange == nil {
+		onChange = PrintBudgetEvent
+	}
+	return &Budget{
+		cfg:      cfg,
+		day:      time.Now().UTC().Format(time.DateOnly),
+		lastPoll: make(map[string]time.Time),
+		used:     metrics.NewRegisteredGaugeFloat64("monitor/rpc/budget_used", metricsRegistry),
+		onChange: onChange,
+	}
+}
+
+// SetBudget 设置全局预算，b 为 nil 时不限制
+func SetBudget(b *Budget) {
+	rpcBudget.Store(b)
+}
+
+// Add 计入一次调用，由 RPCUsage.Observe 调用
+func (b *Budget) Add(method string) {
+	if b == nil {
+		return
+	}
+	units, ok := b.cfg.ComputeUnits[method]
+	if !ok {
+		if units, ok = defaultComputeUnits[method]; !ok {
+			units = DefaultMethodComputeUnits
+		}
+	}
+	b.mu.Lock()
+	var event *BudgetEvent
+	if day := time.Now().UTC().Format(time.DateOnly); day != b.day {
+		b.day, b.requests, b.units = day, 0, 0
+	}
+	b.requests++
+	b.units += units
+	used := b.usedLocked()
+	state := BudgetNormal
+	switch {
+	case used >= b.cfg.CriticalAt:
+		state = BudgetCritical
+	case used >= b.cfg.DegradeAt:
+		state = BudgetDegraded
+	}
+	if state != b.state {
+		prev := b.state
+		b.state = state
+		event = &BudgetEvent{BudgetStatus: b.statusLocked(), Previous: prev.String()}
+	}
+	b.mu.Unlock()
+
+	b.used.Update(used)
+	if event != nil {
+		b.onChange(*event)
+	}
+}
+
+// usedLocked 请求数和 CU 中占预算比例较高的那个，调用方持有锁
+func (b *Budget) usedLocked() float64 {
+	var used float64
+	if b.cfg.DailyRequests > 0 {
+		used = float64(b.requests) / float64(b.cfg.DailyRequests)
+	}
+	if b.cfg.DailyComputeUnits > 0 {
+		used = max(used, float64(b.units)/float64(b.cfg.DailyComputeUnits))
+	}
+	return used
+}
+
+// statusLocked 调用方持有锁
+func (b *Budget) statusLocked() BudgetStatus {
+	return BudgetStatus{Day: b.day, State: b.state.String(), Requests: b.requests, ComputeUnits: b.units, Used: b.usedLocked()}
+}
+
+// State 当前预算状态；跨过 UTC 零点后即使还没有新的调用也恢复 normal
+func (b *Budget) State() BudgetState {
+	if b == nil {
+		return BudgetNormal
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if time.Now().UTC().Format(time.DateOnly) != b.day {
+		return BudgetNormal
+	}
+	return b.state
+}
+
+// Cap 当前允许的最高区块数据级别
+func (b *Budget) Cap() EnrichLevel {
+	switch b.State() {
+	case BudgetCritical:
+		return EnrichTransactions
+	case BudgetDegraded:
+		return EnrichReceipts
+	default:
+		return EnrichTraces
+	}
+}
+
+// Allow 轮询任务每次到点时调用：降级后只有距上次执行超过 interval × 倍数时才返回 true，相当于拉长了轮询间隔
+func (b *Budget) Allow(name string, interval time.Duration) bool {
+	if b == nil {
+		return true
+	}
+	factor := time.Duration(1)
+	switch b.State() {
+	case BudgetCritical:
+		factor = 4
+	case BudgetDegraded:
+		factor = 2
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// 留出 10% 的余量，避免和 ticker 的抖动叠加后多跳过一次
+	if last, ok := b.lastPoll[name]; ok && time.Since(last) < interval*factor*9/10 {
+		return false
+	}
+	b.lastPoll[name] = time.Now()
+	return true
+}
+
+// Status 当天的用量
+func (b *Budget) Status() BudgetStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.statusLocked()
+}
+
+// RegisterAPI 注册预算查询接口
+//
+//	GET /api/budget 当天的请求数、CU、占预算比例和降级状态
+func (b *Budget) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/budget", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, b.Status())
+	})
+}
+
+// PrintBudgetEvent 默认的预算状态变化输出
+func PrintBudgetEvent(e BudgetEvent) {
+	var effect string
+	switch e.State {
+	case BudgetCritical.String():
+		effect = "停止拉取回执和调用树，轮询间隔 ×4"
+	case BudgetDegraded.String():
+		effect = "停止拉取调用树，轮询间隔 ×2"
+	default:
+		effect = "恢复正常"
+	}
+	summary := fmt.Sprintf("💸 [Budget] %s -> %s | 已用 %.0f%%（%d 次请求，%d CU）| %s",
+		e.Previous, e.State, e.Used*100, e.Requests, e.ComputeUnits, effect)
+	Emit(Event{Type: "budget", Summary: summary, Text: summary, Data: e})
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ------------------------------------------------
+// Bundle 模拟：把一组有序交易放在最近的区块状态上整体执行，
+// 评估整个候选 bundle 的合并效果和利润，而不只是单笔交易
+//   - callBundle: Flashbots 中继 / builder 的 eth_callBundle（需要签名后的交易）
+//   - callMany:   Erigon 的 trace_callMany（不需要签名，直接用 call 对象）
+// ------------------------------------------------
+
+// BundleTxResult bundle 中单笔交易的模拟结果
+type BundleTxResult struct {
+	TxHash       common.Hash
+	From         common.Address
+	To           *common.Address
+	GasUsed      uint64
+	CoinbaseDiff *big.Int // 这笔交易让出块者多得的 ETH（gas 费 + 直接转账），callMany 下为 nil
+	Output       []byte
+	Error        string // 非空表示执行失败（revert 等）
+}
+
+// BundleSimResult bundle 的整体模拟结果
+type BundleSimResult struct {
+	StateBlock   uint64
+	TotalGasUsed uint64
+	// 出块者从整个 bundle 中得到的 ETH，callBundle 直接返回；callMany 下为 nil
+	CoinbaseDiff *big.Int
+	// 每个地址在整个 bundle 执行后的 ETH 余额变化（来自 stateDiff），callBundle 下为 nil
+	BalanceDeltas map[common.Address]*big.Int
+	Txs           []BundleTxResult
+}
+
+// Profit 某个地址（例如搜索者的 EOA 或合约）在 bundle 中的 ETH 收益
+func (r *BundleSimResult) Profit(addr common.Address) *big.Int {
+	if d, ok := r.BalanceDeltas[addr]; ok {
+		return new(big.Int).Set(d)
+	}
+	return new(big.Int)
+}
+
+// Failed bundle 中是否有交易执行失败
+func (r *BundleSimResult) Failed() bool {
+	for _, tx := range r.Txs {
+		if tx.Error != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// BundleSimulator bundle 模拟器
+type BundleSimulator interface {
+	// SimulateBundle 在 stateBlock 之上按顺序执行 txs；stateBlock 为 nil 表示最新区块
+	SimulateBundle(ctx context.Context, txs []*types.Transaction, stateBlock *big.Int) (*BundleSimResult, error)
+}
+
+// NewBundleSimulator 根据配置创建模拟器，fallback 为主连接（URL 为空时使用）
+func NewBundleSimulator(cfg SimulationConfig, fallback *rpc.Client, signer types.Signer) (BundleSimulator, error) {
+	switch cfg.Method {
+	case "callBundle":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("callBundle 需要配置 simulation.url（例如 https://relay.flashbots.net）")
+		}
+		key, err := loadAuthKey(cfg.AuthKeyEnv)
+		if err != nil {
+			return nil, err
+		}
+		return &callBundleSimulator{url: cfg.URL, authKey: key, http: &http.Client{Timeout: CONNECTION_TIMEOUT, Transport: newMeteredTransport(nil)}}, nil
+	case "callMany":
+		client := fallback
+		if cfg.URL != "" {
+			ctx, cancel := context.WithTimeout(context.Background(), CONNECTION_TIMEOUT)
+			defer cancel()
+			c, err := dialRPC(ctx, cfg.URL)
+			if err != nil {
+				return nil, fmt.Errorf("连接 trace_callMany 节点失败: %w", err)
+			}
+			client = c
+		}
+		return &callManySimulator{client: client, signer: signer}, nil
+	default:
+		return nil, fmt.Errorf("未知的 simulation.method: %q（可选 callBundle / callMany）", cfg.Method)
+	}
+}
+
+// loadAuthKey 读取 Flashbots 身份签名私钥
+// 这个私钥只用来给请求签名、积累中继信誉，不需要也不应该存放资金；未配置时随机生成
+func loadAuthKey(env string) (*ecdsa.PrivateKey, error) {
+	if env == "" || os.Getenv(env) == "" {
+		return crypto.GenerateKey()
+	}
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(os.Getenv(env), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("解析 %s 中的身份私钥失败: %w", env, err)
+	}
+	return key, nil
+}
+
+// ------------------------------------------------
+// eth_callBundle
+// ------------------------------------------------
+
+type callBundleSimulator struct {
+	url     string
+	authKey *ecdsa.PrivateKey
+	http    *http.Client
+}
+
+// callBundleResponse eth_callBundle 的返回值，金额都是十进制字符串
+type callBundleResponse struct {
+	CoinbaseDiff     string `json:"coinbaseDiff"`
+	StateBlockNumber uint64 `json:"stateBlockNumber"`
+	TotalGasUsed     uint64 `json:"totalGasUsed"`
+	Results          []struct {
+		TxHash       common.Hash     `json:"txHash"`
+		FromAddress  common.Address  `json:"fromAddress"`
+		ToAddress    *common.Address `json:"toAddress"`
+		GasUsed      uint64          `json:"gasUsed"`
+		CoinbaseDiff string          `json:"coinbaseDiff"`
+		Value        string          `json:"value"`
+		Error        string          `json:"error"`
+		Revert       string          `json:"revert"`
+	} `json:"results"`
+}
+
+func (s *callBundleSimulator) SimulateBundle(ctx context.Context, txs []*types.Transaction, stateBlock *big.Int) (*BundleSimResult, error) {
+	rawTxs := make([]string, len(txs))
+	for i, tx := range txs {
+		raw, err := tx.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("编码交易失败: %w", err)
+		}
+		rawTxs[i] = hexutil.Encode(raw)
+	}
+	state := "latest"
+	target := "latest"
+	if stateBlock != nil {
+		state = hexutil.EncodeBig(stateBlock)
+		// 目标区块是状态区块的下一个
+		target = hexutil.EncodeBig(new(big.Int).Add(stateBlock, common.Big1))
+	}
+	params := map[string]interface{}{
+		"txs":              rawTxs,
+		"blockNumber":      target,
+		"stateBlockNumber": state,
+	}
+	var resp callBundleResponse
+	if err := s.call(ctx, "eth_callBundle", params, &resp); err != nil {
+		return nil, err
+	}
+
+	result := &BundleSimResult{
+		StateBlock:   resp.StateBlockNumber,
+		TotalGasUsed: resp.TotalGasUsed,
+		CoinbaseDiff: parseDecimal(resp.CoinbaseDiff),
+	}
+	for _, r := range resp.Results {
+		errMsg := r.Error
+		if r.Revert != "" {
+			errMsg = strings.TrimSpace(errMsg + " revert: " + r.Revert)
+		}
+		out, _ := hexutil.Decode(r.Value)
+		result.Txs = append(result.Txs, BundleTxResult{
+			TxHash:       r.TxHash,
+			From:         r.FromAddress,
+			To:           r.ToAddress,
+			GasUsed:      r.GasUsed,
+			CoinbaseDiff: parseDecimal(r.CoinbaseDiff),
+			Output:       out,
+			Error:        errMsg,
+		})
+	}
+	return result, nil
+}
+
+// call 发送带 X-Flashbots-Signature 的 JSON-RPC 请求
+func (s *callBundleSimulator) call(ctx context.Context, method string, param interface{}, out interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  method,
+		"params":  []interface{}{param},
+	})
+	if err != nil {
+		return err
+	}
+	// 签名内容是 body 的 keccak256 十六进制字符串（EIP-191 personal message）
+	hashHex := crypto.Keccak256Hash(body).Hex()
+	sig, err := crypto.Sign(accounts.TextHash([]byte(hashHex)), s.authKey)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Flashbots-Signature", crypto.PubkeyToAddress(s.authKey.PublicKey).Hex()+":"+hexutil.Encode(sig))
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s 请求失败: %w", method, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s 返回 HTTP %d: %s", method, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	var envelope struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return fmt.Errorf("解析 %s 返回值失败: %w", method, err)
+	}
+	if envelope.Error != nil {
+		return fmt.Errorf("%s 返回错误 %d: %s", method, envelope.Error.Code, envelope.Error.Message)
+	}
+	return json.Unmarshal(envelope.Result, out)
+}
+
+// parseDecimal 解析十进制金额字符串，失败返回 0
+func parseDecimal(s string) *big.Int {
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return new(big.Int)
+	}
+	return v
+}
+
+// ------------------------------------------------
+// trace_callMany (Erigon / Nethermind)
+// ------------------------------------------------
+
+type callManySimulator struct {
+	client *rpc.Client
+	signer types.Signer
+}
+
+// callManyResult trace_callMany 中单个 call 的结果（只解析需要的字段）
+type callManyResult struct {
+	Output    hexutil.Bytes                          `json:"output"`
+	StateDiff map[common.Address]callManyAccountDiff `json:"stateDiff"`
+	Trace     []struct {
+		Error  string `json:"error"`
+		Result *struct {
+			GasUsed hexutil.Uint64 `json:"gasUsed"`
+		} `json:"result"`
+	} `json:"trace"`
+}
+
+// callManyAccountDiff 账户的状态变化，balance 可能是 "="、{"+": x}、{"-": x} 或 {"*": {"from": a, "to": b}}
+type callManyAccountDiff struct {
+	Balance json.RawMessage `json:"balance"`
+}
+
+func (s *callManySimulator) SimulateBundle(ctx context.Context, txs []*types.Transaction, stateBlock *big.Int) (*BundleSimResult, error) {
+	calls := make([]interface{}, len(txs))
+	froms := make([]common.Address, len(txs))
+	for i, tx := range txs {
+		from, err := types.Sender(s.signer, tx)
+		if err != nil {
+			return nil, fmt.Errorf("恢复交易发送者失败: %w", err)
+		}
+		froms[i] = from
+		call := map[string]interface{}{
+			"from":  from,
+			"gas":   hexutil.Uint64(tx.Gas()),
+			"value": (*hexutil.Big)(tx.Value()),
+			"data":  hexutil.Bytes(tx.Data()),
+		}
+		if tx.To() != nil {
+			call["to"] = tx.To()
+		}
+		if tx.Type() == types.LegacyTxType || tx.Type() == types.AccessListTxType {
+			call["gasPrice"] = (*hexutil.Big)(tx.GasPrice())
+		} else {
+			call["maxFeePerGas"] = (*hexutil.Big)(tx.GasFeeCap())
+			call["maxPriorityFeePerGas"] = (*hexutil.Big)(tx.GasTipCap())
+		}
+		calls[i] = []interface{}{call, []string{"trace", "stateDiff"}}
+	}
+
+	block := "latest"
+	if stateBlock != nil {
+		block = hexutil.EncodeBig(stateBlock)
+	}
+	var results []callManyResult
+	if err := callRetry(ctx, s.client, &results, "trace_callMany", calls, block); err != nil {
+		return nil, fmt.Errorf("trace_callMany 失败: %w", err)
+	}
+	if len(results) != len(txs) {
+		return nil, fmt.Errorf("trace_callMany 返回 %d 个结果，期望 %d 个", len(results), len(txs))
+	}
+
+	out := &BundleSimResult{BalanceDeltas: make(map[common.Address]*big.Int)}
+	if stateBlock != nil {
+		out.StateBlock = stateBlock.Uint64()
+	}
+	for i, r := range results {
+		txr := BundleTxResult{TxHash: txs[i].Hash(), From: froms[i], To: txs[i].To(), Output: r.Output}
+		if len(r.Trace) > 0 {
+			txr.Error = r.Trace[0].Error
+			if r.Trace[0].Result != nil {
+				txr.GasUsed = uint64(r.Trace[0].Result.GasUsed)
+			}
+		}
+		out.TotalGasUsed += txr.GasUsed
+		out.Txs = append(out.Txs, txr)
+
+		for addr, diff := range r.StateDiff {
+			delta := parseBalanceDiff(diff.Balance)
+			if delta.Sign() == 0 {
+				continue
+			}
+			if acc, ok := out.BalanceDeltas[addr]; ok {
+				acc.Add(acc, delta)
+			} else {
+				out.BalanceDeltas[addr] = delta
+			}
+		}
+	}
+	return out, nil
+}
+
+// parseBalanceDiff 把 stateDiff 中的 balance 变化转换为有符号的增量
+func parseBalanceDiff(raw json.RawMessage) *big.Int {
+	var changed map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &changed); err != nil {
+		return new(big.Int) // "=" 表示没有变化
+	}
+	if v, ok := changed["+"]; ok {
+		var b hexutil.Big
+		if json.Unmarshal(v, &b) == nil {
+			return b.ToInt()
+		}
+	}
+	if v, ok := changed["-"]; ok {
+		var b hexutil.Big
+		if json.Unmarshal(v, &b) == nil {
+			return new(big.Int).Neg(b.ToInt())
+		}
+	}
+	if v, ok := changed["*"]; ok {
+		var ft struct {
+			From hexutil.Big `json:"from"`
+			To   hexutil.Big `json:"to"`
+		}
+		if json.Unmarshal(v, &ft) == nil {
+			return new(big.Int).Sub(ft.To.ToInt(), ft.From.ToInt())
+		}
+	}
+	return new(big.Int)
+}
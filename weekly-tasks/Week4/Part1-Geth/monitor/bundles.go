@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// 从 bundle 标记交易向前 / 向后最多找多少笔交易，超过后不再认为是同一个 bundle
+	BundleMaxSpan = 4
+	// API 保留的最近 bundle 数
+	BundleHistory = 500
+)
+
+// bundle 的识别依据
+const (
+	BundleReasonPayment  = "coinbase_payment" // 交易中直接给出块者转账
+	BundleReasonZeroTip  = "zero_tip"         // 优先费为 0，只能通过 coinbase 转账付费
+	BundleReasonSender   = "shared_sender"    // 与标记交易同一个发送者
+	BundleReasonContract = "shared_contract"  // 与标记交易调用同一个（searcher）合约
+)
+
+// Bundle 区块中一组相邻的、疑似由同一个 searcher 打包提交的交易
+type Bundle struct {
+	Block    uint64          `json:"block"`
+	Builder  string          `json:"builder"`
+	Start    int             `json:"start"` // 区块内的起止下标（含）
+	End      int             `json:"end"`
+	Txs      []common.Hash   `json:"txs"`
+	Searcher common.Address  `json:"searcher"`           // 标记交易的发送者
+	Contract *common.Address `json:"contract,omitempty"` // 标记交易调用的合约
+	Payment  *big.Int        `json:"payment"`            // bundle 内给出块者的转账合计
+	Reasons  []string        `json:"reasons"`
+}
+
+// bundleTx 区块中一笔交易的特征
+type bundleTx struct {
+	sender  common.Address
+	to      *common.Address
+	zeroTip bool
+	payment *big.Int // 给出块者的转账，没有时为 nil
+}
+
+// BundleDetector 启发式地把区块中相邻的交易归组为 bundle：
+// 以给出块者转账或零优先费的交易为标记，向前后 BundleMaxSpan 笔内找同一发送者或调用同一合约的交易，
+// 中间夹着的交易（三明治的受害者、被尾随的交易）一起归入，便于按 bundle 而不是按交易分析区块内容
+type BundleDetector struct {
+	payments *CoinbasePaymentAnalyzer
+
+	mu     sync.Mutex
+	recent []Bundle
+
+	onBundle func(Bundle)
+}
+
+// NewBundleDetector 创建检测器，payments 用于取区块中的 coinbase 转账，onBundle 为 nil 时使用默认输出
+func NewBundleDetector(payments *CoinbasePaymentAnalyzer, onBundle func(Bundle)) *BundleDetector {
+	if onBundle == nil {
+		onBundle = PrintBundle
+	}
+	return &BundleDetector{payments: payments, onBundle: onBundle}
+}
+
+// Enrichment 实现 Enricher：和 CoinbasePaymentAnalyzer 一样需要内部调用
+func (d *BundleDetector) Enrichment() EnrichLevel { return EnrichTraces }
+
+// OnBlock 实现 BlockAnalyzer
+func (d *BundleDetector) OnBlock(data *BlockData) {
+	bundles := d.Detect(data)
+	if len(bundles) == 0 {
+		return
+	}
+	d.mu.Lock()
+	d.recent = append(d.recent, bundles...)
+	if over := len(d.recent) - BundleHistory; over > 0 {
+		d.recent = d.recent[over:]
+	}
+	d.mu.Unlock()
+	for _, b := range bundles {
+		d.onBundle(b)
+	}
+}
+
+// Detect 识别区块中的 bundle，按区块内的顺序返回
+func (d *BundleDetector) Detect(data *BlockData) []Bundle {
+	header := data.Block.Header()
+	bp := d.payments.Collect(data)
+	txs := data.Block.Transactions()
+	features := make([]bundleTx, len(txs))
+	for i, tx := range txs {
+		features[i] = bundleTx{sender: data.Senders[i], to: tx.To()}
+		if data.Senders[i] != header.Coinbase && header.BaseFee != nil &&
+			data.Receipts[i].EffectiveGasPrice.Cmp(header.BaseFee) <= 0 {
+			features[i].zeroTip = true
+		}
+	}
+	for _, p := range bp.Payments {
+		f := &features[p.TxIndex]
+		if f.payment == nil {
+			f.payment = new(big.Int)
+		}
+		f.payment.Add(f.payment, p.Value)
+	}
+
+	var bundles []Bundle
+	for i, f := range features {
+		if f.payment == nil && !f.zeroTip {
+			continue
+		}
+		b := Bundle{Block: bp.Number, Builder: bp.Builder, Start: i, End: i, Searcher: f.sender, Contract: f.to, Payment: new(big.Int)}
+		reasons := map[string]bool{}
+		if f.payment != nil {
+			reasons[BundleReasonPayment] = true
+		}
+		if f.zeroTip {
+			reasons[BundleReasonZeroTip] = true
+		}
+		for j := i - 1; j >= 0 && j >= i-BundleMaxSpan; j-- {
+			if r := linkReason(f, features[j]); r != "" {
+				b.Start, reasons[r] = j, true
+			}
+		}
+		for j := i + 1; j < len(features) && j <= i+BundleMaxSpan; j++ {
+			if r := linkReason(f, features[j]); r != "" {
+				b.End, reasons[r] = j, true
+			}
+		}
+		// 与前一个 bundle 重叠时合并（同一个 bundle 中可能有多笔标记交易）
+		if n := len(bundles); n > 0 && b.Start <= bundles[n-1].End {
+			prev := &bundles[n-1]
+			prev.End = max(prev.End, b.End)
+			for _, r := range prev.Reasons {
+				reasons[r] = true
+			}
+			prev.Reasons = sortedReasons(reasons)
+			continue
+		}
+		b.Reasons = sortedReasons(reasons)
+		bundles = append(bundles, b)
+	}
+	for k := range bundles {
+		b := &bundles[k]
+		for i := b.Start; i <= b.End; i++ {
+			b.Txs = append(b.Txs, txs[i].Hash())
+			if features[i].payment != nil {
+				b.Payment.Add(b.Payment, features[i].payment)
+			}
+		}
+	}
+	return bundles
+}
+
+// linkReason 交易 g 与标记交易 f 是否属于同一个 searcher，返回依据，不相关时返回空
+func linkReason(f, g bundleTx) string {
+	if g.sender == f.sender {
+		return BundleReasonSender
+	}
+	if f.to != nil && g.to != nil && *g.to == *f.to {
+		return BundleReasonContract
+	}
+	return ""
+}
+
+func sortedReasons(set map[string]bool) []string {
+	out := make([]string, 0, len(set))
+	for r := range set {
+		out = append(out, r)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Recent 最近识别出的 bundle，最新的在前
+func (d *BundleDetector) Recent(limit int) []Bundle {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]Bundle, 0, min(limit, len(d.recent)))
+	for i := len(d.recent) - 1; i >= 0 && len(out) < limit; i-- {
+		out = append(out, d.recent[i])
+	}
+	return out
+}
+
+// RegisterAPI 注册 bundle 查询接口
+//
+//	GET /api/mev/bundles 最近识别出的 bundle（最多 BundleHistory 个）
+func (d *BundleDetector) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/mev/bundles", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, d.Recent(BundleHistory))
+	})
+}
+
+// PrintBundle 默认的 bundle 输出
+func PrintBundle(b Bundle) {
+	var sb strings.Builder
+	summary := fmt.Sprintf("📦 [Bundle] 区块 %d #%d-#%d (%s): %d 笔交易，searcher %s，付给出块者 %.6f ETH",
+		b.Block, b.Start, b.End, b.Builder, len(b.Txs), b.Searcher.Hex(), weiToEther(b.Payment))
+	sb.WriteString("\n" + summary + "\n")
+	fmt.Fprintf(&sb, "   依据: %s\n", strings.Join(b.Reasons, ", "))
+	for i, h := range b.Txs {
+		fmt.Fprintf(&sb, "   #%-4d %s\n", b.Start+i, h.Hex())
+	}
+	Emit(Event{Type: "bundle", Key: eventKey(b.Block, b.Start), Summary: summary, Text: sb.String(), Data: b})
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// ------------------------------------------------
+// 事件总线：主循环只负责把订阅收到的数据发布出去，
+// 输出、分析器、告警等消费者各自订阅，在自己的 goroutine 中按自己的缓冲队列处理，
+// 某个消费者处理慢时只会丢弃它自己的消息，不会拖慢主循环和其它消费者
+// ------------------------------------------------
+
+// BusKind 总线消息的类型
+type BusKind string
+
+const (
+	BusHead        BusKind = "head"         // 新区块头
+	BusPendingTx   BusKind = "pending_tx"   // 完整的 Pending 交易
+	BusPendingHash BusKind = "pending_hash" // 只有 Hash 的 Pending 交易
+	BusLog         BusKind = "log"          // 订阅的合约日志
+)
+
+const (
+	// 各消费者的默认队列长度
+	// ⚠️ Pending 交易在主网上每秒上百笔，队列太短时慢消费者会频繁丢消息
+	BusHeadBuffer    = 64
+	BusPendingBuffer = 4096
+	BusLogBuffer     = 1024
+	// 每丢弃这么多条消息输出一次警告
+	busDropLogEvery = 1000
+)
+
+// BusMessage 总线上的一条消息，按 Kind 只有对应的字段有值
+type BusMessage struct {
+	Kind     BusKind
+	Received time.Time // 主循环从订阅收到的时间
+	Source   string    // Pending 交易最先由哪个交易池来源送达（见 MempoolMerger）
+	Header   *types.Header
+	Tx       *types.Transaction
+	Hash     common.Hash
+	Log      *types.Log
+}
+
+// busSubscriber 一个消费者：独立的队列和 goroutine
+type busSubscriber struct {
+	name    string
+	kinds   map[BusKind]bool
+	queue   chan BusMessage
+	handler func(BusMessage)
+
+	dropped *metrics.Counter
+	backlog *metrics.Gauge
+}
+
+// EventBus 多消费者的事件总线，Publish 不会阻塞
+type EventBus struct {
+	mu   sync.Mutex
+	subs []*busSubscriber
+	ctx  context.Context // Run 之后不为 nil，之后的订阅立即启动
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe 注册一个消费者，只接收 kinds 中的消息；Run 之前或之后都可以调用
+// handler 在该消费者自己的 goroutine 中按发布顺序调用
+func (b *EventBus) Subscribe(name string, buffer int, handler func(BusMessage), kinds ...BusKind) {
+	s := &busSubscriber{
+		name:    name,
+		kinds:   make(map[BusKind]bool, len(kinds)),
+		queue:   make(chan BusMessage, buffer),
+		handler: handler,
+		dropped: metrics.NewRegisteredCounter("monitor/bus/"+name+"/dropped", metricsRegistry),
+		backlog: metrics.NewRegisteredGauge("monitor/bus/"+name+"/backlog", metricsRegistry),
+	}
+	for _, k := range kinds {
+		s.kinds[k] = true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(b.subs, s)
+	if b.ctx != nil {
+		go s.run(b.ctx)
+	}
+}
+
+// Publish 把消息放入所有订阅了该类型的消费者队列，队列已满时丢弃并计数
+func (b *EventBus) Publish(msg BusMessage) {
+	if msg.Received.IsZero() {
+		msg.Received = time.Now()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, s := range b.subs {
+		if !s.kinds[msg.Kind] {
+			continue
+		}
+		select {
+		case s.queue <- msg:
+		default:
+			s.dropped.Inc(1)
+			if n := s.dropped.Snapshot().Count(); n%busDropLogEvery == 1 {
+				log.Printf("⚠️  [%s] 处理太慢，队列已满，已丢弃 %d 条消息", s.name, n)
+			}
+		}
+		s.backlog.Update(int64(len(s.queue)))
+	}
+}
+
+// Run 启动全部消费者，直到 ctx 取消；队列中剩余的消息直接丢弃
+func (b *EventBus) Run(ctx context.Context) {
+	b.mu.Lock()
+	b.ctx = ctx
+	for _, s := range b.subs {
+		go s.run(ctx)
+	}
+	b.mu.Unlock()
+	<-ctx.Done()
+}
+
+func (s *busSubscriber) run(ctx context.Context) {
+	for {
+		select {
+		case msg := <-s.queue:
+			s.handler(msg)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// BusQueueStatus 一个消费者的队列状态
+type BusQueueStatus struct {
+	Name     string `json:"name"`
+	Backlog  int    `json:"backlog"`  // 队列中等待处理的消息数
+	Capacity int    `json:"capacity"` // 队列长度
+	Dropped  int64  `json:"dropped"`  // 累计丢弃的消息数
+}
+
+// Stats 全部消费者的队列积压和丢弃数
+func (b *EventBus) Stats() []BusQueueStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]BusQueueStatus, 0, len(b.subs))
+	for _, s := range b.subs {
+		out = append(out, BusQueueStatus{Name: s.name, Backlog: len(s.queue), Capacity: cap(s.queue), Dropped: s.dropped.Snapshot().Count()})
+	}
+	return out
+}
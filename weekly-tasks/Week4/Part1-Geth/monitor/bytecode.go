@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ------------------------------------------------
+// 合约字节码变化检测：定期对关注合约的 eth_getCode 取哈希，代码变化、被销毁或在同一地址重新部署时提醒
+// 代理合约同时检查实现合约的代码，能发现实现槽检查发现不了的情况：
+// 非标准代理 / beacon 换实现、CREATE2 + SELFDESTRUCT 的"变形合约"在同一地址换成不同的代码
+// ------------------------------------------------
+
+const (
+	// 默认每隔多少个区块检查一次（区块中有交易直接调用的合约当块就检查）
+	DefaultBytecodeIntervalBlocks = 10
+)
+
+// BytecodeConfig 字节码变化检测，abi.watch_proxies 中的代理合约也会检查
+type BytecodeConfig struct {
+	Contracts      []string `json:"contracts"`
+	IntervalBlocks uint64   `json:"interval_blocks"`
+}
+
+// 字节码变化的类型
+const (
+	CodeChanged       = "changed"        // 代码直接变化（同一地址的代码正常情况下不可变）
+	CodeDestroyed     = "destroyed"      // 代码被清空（SELFDESTRUCT）
+	CodeRedeployed    = "redeployed"     // 被销毁后在同一地址部署了新代码（CREATE2 变形合约）
+	CodeImplUpgraded  = "impl_upgraded"  // 代理指向了新的实现合约
+	CodeImplChanged   = "impl_changed"   // 实现地址没变，但实现合约的代码变了
+	CodeImplDestroyed = "impl_destroyed" // 实现合约被销毁，代理的调用全部变成空操作
+)
+
+// CodeState 一个合约在某个区块的代码
+type CodeState struct {
+	Address        common.Address `json:"address"`
+	Block          uint64         `json:"block"`
+	CodeHash       common.Hash    `json:"code_hash"` // 没有代码时为零哈希
+	Size           int            `json:"size"`
+	Flags          []string       `json:"flags,omitempty"`
+	Implementation common.Address `json:"implementation"` // 不是代理时为零地址
+	ImplHash       common.Hash    `json:"impl_hash"`
+	ImplSize       int            `json:"impl_size"`
+	ImplFlags      []string       `json:"impl_flags,omitempty"`
+}
+
+// BytecodeChange 一次代码变化
+type BytecodeChange struct {
+	Kind     string         `json:"kind"`
+	Address  common.Address `json:"address"`
+	Block    uint64         `json:"block"`
+	Old      CodeState      `json:"old"`
+	New      CodeState      `json:"new"`
+	NewFlags []string       `json:"new_flags,omitempty"` // 新代码中出现、旧代码中没有的危险指令
+}
+
+// BytecodeWatcher 定期检查关注合约的代码哈希
+// ⚠️ 坎昆升级（EIP-6780）之后 SELFDESTRUCT 只在创建合约的同一笔交易中删除代码，主网上的变形合约基本不再可能；
+// 其它链和升级之前部署的合约仍然需要关注
+type BytecodeWatcher struct {
+	client    *ethclient.Client
+	pool      *EndpointPool // 未配置备用节点时为 nil
+	contracts []common.Address
+	interval  uint64
+
+	mu        sync.RWMutex
+	states    map[common.Address]*CodeState
+	destroyed map[common.Address]CodeState // 被销毁前最后一次看到的代码
+
+	onChange func(BytecodeChange)
+}
+
+// NewBytecodeWatcher 创建字节码检测，onChange 为 nil 时使用默认输出
+func NewBytecodeWatcher(cfg BytecodeConfig, client *ethclient.Client, proxies []common.Address, onChange func(BytecodeChange)) *BytecodeWatcher {
+	if onChange == nil {
+		onChange = PrintBytecodeChange
+	}
+	interval := cfg.IntervalBlocks
+	if interval == 0 {
+		interval = DefaultBytecodeIntervalBlocks
+	}
+	seen := make(map[common.Address]bool)
+	var contracts []common.Address
+	for _, a := range cfg.Contracts {
+		addr := common.HexToAddress(a)
+		if !seen[addr] {
+			seen[addr] = true
+			contracts = append(contracts, addr)
+		}
+	}
+	for _, addr := range proxies {
+		if !seen[addr] {
+			seen[addr] = true
+			contracts = append(contracts, addr)
+		}
+	}
+	return &BytecodeWatcher{
+		client:    client,
+		contracts: contracts,
+		interval:  interval,
+		states:    make(map[common.Address]*CodeState),
+		destroyed: make(map[common.Address]CodeState),
+		onChange:  onChange,
+	}
+}
+
+// SetEndpoints 代码查询改由 pool 选择节点，需要在注册到 BlockFetcher 之前调用
+func (w *BytecodeWatcher) SetEndpoints(pool *EndpointPool) {
+	w.pool = pool
+}
+
+// Enrichment 实现 Enricher：只看交易，不需要收据
+func (w *BytecodeWatcher) Enrichment() EnrichLevel { return EnrichTransactions }
+
+// OnBlock 实现 BlockAnalyzer
+func (w *BytecodeWatcher) OnBlock(data *BlockData) {
+	number := data.Block.Number()
+	touched := make(map[common.Address]bool)
+	for _, tx := range data.Block.Transactions() {
+		if tx.To() != nil {
+			touched[*tx.To()] = true
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), CONNECTION_TIMEOUT)
+	defer cancel()
+	periodic := number.Uint64()%w.interval == 0
+	for _, addr := range w.contracts {
+		w.mu.RLock()
+		prev, known := w.states[addr]
+		w.mu.RUnlock()
+		if known && !periodic && !touched[addr] {
+			continue
+		}
+		cur, err := w.snapshot(ctx, addr, number)
+		if err != nil {
+			log.Printf("⚠️  读取 %s 的代码失败: %v", addr.Hex(), err)
+			continue
+		}
+
+		w.mu.Lock()
+		w.states[addr] = cur
+		last, wasDestroyed := w.destroyed[addr]
+		if cur.Size == 0 && known && prev.Size > 0 {
+			w.destroyed[addr] = *prev
+		}
+		if cur.Size > 0 {
+			delete(w.destroyed, addr)
+		}
+		w.mu.Unlock()
+
+		if !known {
+			if cur.Size == 0 {
+				log.Printf("⚠️  关注的合约 %s 在区块 %d 没有代码", addr.Hex(), cur.Block)
+			} else if flags := append(append([]string(nil), cur.Flags...), cur.ImplFlags...); len(flags) > 0 {
+				log.Printf("🧬 合约 %s 的代码包含 %s", addr.Hex(), strings.Join(flags, " / "))
+			}
+			continue
+		}
+		if c, ok := compareCode(prev, cur, last, wasDestroyed); ok {
+			w.onChange(c)
+		}
+	}
+}
+
+// compareCode 比较同一个合约前后两次的代码；last 为被销毁前最后一次看到的代码
+func compareCode(prev, cur *CodeState, last CodeState, wasDestroyed bool) (BytecodeChange, bool) {
+	c := BytecodeChange{Address: cur.Address, Block: cur.Block, Old: *prev, New: *cur}
+	switch {
+	case prev.CodeHash != cur.CodeHash:
+		switch {
+		case cur.Size == 0:
+			c.Kind = CodeDestroyed
+		case prev.Size == 0 && wasDestroyed:
+			c.Kind, c.Old = CodeRedeployed, last
+		case prev.Size == 0:
+			return c, false // 第一次看到时还没部署，正常部署不提醒
+		default:
+			c.Kind = CodeChanged
+		}
+		c.NewFlags = addedFlags(c.Old.Flags, cur.Flags)
+	case prev.Implementation != cur.Implementation:
+		c.Kind = CodeImplUpgraded
+		c.NewFlags = addedFlags(prev.ImplFlags, cur.ImplFlags)
+	case prev.ImplHash != cur.ImplHash:
+		c.Kind = CodeImplChanged
+		if cur.ImplSize == 0 {
+			c.Kind = CodeImplDestroyed
+		}
+		c.NewFlags = addedFlags(prev.ImplFlags, cur.ImplFlags)
+	default:
+		return c, false
+	}
+	return c, true
+}
+
+// snapshot 读取合约（以及代理的实现合约）在指定区块的代码
+func (w *BytecodeWatcher) snapshot(ctx context.Context, addr common.Address, block *big.Int) (*CodeState, error) {
+	code, err := w.codeAt(ctx, addr, block)
+	if err != nil {
+		return nil, err
+	}
+	s := &CodeState{Address: addr, Block: block.Uint64(), CodeHash: codeHash(code), Size: len(code), Flags: codeFlags(code)}
+	if len(code) == 0 {
+		return s, nil
+	}
+	info, err := ResolveProxy(ctx, w.client, addr, block)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return s, nil
+	}
+	impl, err := w.codeAt(ctx, info.Implementation, block)
+	if err != nil {
+		return nil, err
+	}
+	s.Implementation = info.Implementation
+	s.ImplHash, s.ImplSize, s.ImplFlags = codeHash(impl), len(impl), codeFlags(impl)
+	return s, nil
+}
+
+// codeAt 带重试的 CodeAt，配置了备用节点时按 pool 分流
+func (w *BytecodeWatcher) codeAt(ctx context.Context, addr common.Address, block *big.Int) (code []byte, err error) {
+	err = poolCall(ctx, w.pool, w.client, "eth_getCode", func(c *ethclient.Client) error {
+		code, err = c.CodeAt(ctx, addr, block)
+		return err
+	})
+	return code, err
+}
+
+// codeHash 代码的 keccak256，没有代码时返回零哈希
+func codeHash(code []byte) common.Hash {
+	if len(code) == 0 {
+		return common.Hash{}
+	}
+	return crypto.Keccak256Hash(code)
+}
+
+// codeFlags 扫描代码中的危险指令（跳过 PUSH 的数据和 Solidity 末尾的 CBOR 元数据）
+// 只是静态出现，不代表一定能执行到
+func codeFlags(code []byte) []string {
+	if _, ok := types.ParseDelegation(code); ok {
+		return []string{"eip7702"}
+	}
+	// Solidity 元数据：最后 2 字节是长度，前面是 0xa1 / 0xa2 开头的 CBOR
+	if n := len(code); n > 2 {
+		m := int(code[n-2])<<8 | int(code[n-1])
+		if m+2 < n && (code[n-2-m] == 0xa1 || code[n-2-m] == 0xa2) {
+			code = code[:n-2-m]
+		}
+	}
+	var selfdestruct, delegatecall, create2 bool
+	for i := 0; i < len(code); i++ {
+		op := vm.OpCode(code[i])
+		switch {
+		case op.IsPush():
+			i += int(op - vm.PUSH0)
+		case op == vm.SELFDESTRUCT:
+			selfdestruct = true
+		case op == vm.DELEGATECALL:
+			delegatecall = true
+		case op == vm.CREATE2:
+			create2 = true
+		}
+	}
+	var flags []string
+	if selfdestruct {
+		flags = append(flags, "selfdestruct")
+	}
+	if delegatecall {
+		flags = append(flags, "delegatecall")
+	}
+	if create2 {
+		flags = append(flags, "create2")
+	}
+	return flags
+}
+
+// addedFlags after 中有、before 中没有的标记
+func addedFlags(before, after []string) []string {
+	var out []string
+	for _, f := range after {
+		found := false
+		for _, b := range before {
+			found = found || b == f
+		}
+		if !found {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// States 所有关注合约当前的代码
+func (w *BytecodeWatcher) States() []CodeState {
+	w.mu.RLock()
+	out := make([]CodeState, 0, len(w.states))
+	for _, s := range w.states {
+		out = append(out, *s)
+	}
+	w.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Address.Hex() < out[j].Address.Hex() })
+	return out
+}
+
+// RegisterAPI 注册字节码查询接口
+//
+//	GET /api/bytecode 关注合约当前的代码哈希、大小和危险指令
+func (w *BytecodeWatcher) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/bytecode", func(rw http.ResponseWriter, r *http.Request) {
+		writeJSON(rw, http.StatusOK, w.States())
+	})
+}
+
+// PrintBytecodeChange 默认的字节码变化提醒
+func PrintBytecodeChange(c BytecodeChange) {
+	var detail string
+	switch c.Kind {
+	case CodeDestroyed:
+		detail = fmt.Sprintf("代码被销毁（之前 %d 字节）", c.Old.Size)
+	case CodeRedeployed:
+		detail = fmt.Sprintf("销毁后重新部署了不同的代码 %s -> %s（变形合约）", shortHash(c.Old.CodeHash), shortHash(c.New.CodeHash))
+	case CodeChanged:
+		detail = fmt.Sprintf("代码变化 %s -> %s", shortHash(c.Old.CodeHash), shortHash(c.New.CodeHash))
+	case CodeImplUpgraded:
+		detail = fmt.Sprintf("实现合约 %s -> %s", c.Old.Implementation.Hex(), c.New.Implementation.Hex())
+	case CodeImplChanged:
+		detail = fmt.Sprintf("实现合约 %s 的代码变化 %s -> %s", c.New.Implementation.Hex(), shortHash(c.Old.ImplHash), shortHash(c.New.ImplHash))
+	case CodeImplDestroyed:
+		detail = fmt.Sprintf("实现合约 %s 被销毁", c.New.Implementation.Hex())
+	}
+	summary := fmt.Sprintf("🧬 [Bytecode] 区块 %d | %s %s", c.Block, c.Address.Hex(), detail)
+	text := summary
+	if len(c.NewFlags) > 0 {
+		text += fmt.Sprintf("\n   ⚠️  新代码包含 %s", strings.Join(c.NewFlags, " / "))
+	}
+	Emit(Event{Type: "bytecode_change", Key: eventKey(c.Address, c.Block, c.Kind), Summary: summary, Text: text, Data: c})
+}
+
+// shortHash 0x1234abcd… 形式的短哈希
+func shortHash(h common.Hash) string {
+	return h.Hex()[:10] + "…"
+}
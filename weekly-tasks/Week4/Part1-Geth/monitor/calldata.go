@@ -0,0 +1,385 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// ------------------------------------------------
+// Calldata 解码：selector -> 函数定义的注册表，解码函数参数（包括嵌套 tuple），
+// 并展开 multicall / Multicall3 / Safe 等批量调用中的内层调用
+// ------------------------------------------------
+
+const (
+	// 内层调用最多展开的层数（multicall 里再套 multicall）
+	MaxCalldataDepth = 3
+)
+
+// builtinMethodsABI 常见函数（带参数名），没有合约 ABI 时也能解码
+// 同名不同参数的函数（例如两个版本的 exactInputSingle）在 abi.JSON 中会被改名，显示时使用 RawName
+const builtinMethodsABI = `[
+	{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}]},
+	{"type":"function","name":"transferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"amount","type":"uint256"}]},
+	{"type":"function","name":"approve","inputs":[{"name":"spender","type":"address"},{"name":"amount","type":"uint256"}]},
+	{"type":"function","name":"increaseAllowance","inputs":[{"name":"spender","type":"address"},{"name":"addedValue","type":"uint256"}]},
+	{"type":"function","name":"permit","inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"},{"name":"value","type":"uint256"},{"name":"deadline","type":"uint256"},{"name":"v","type":"uint8"},{"name":"r","type":"bytes32"},{"name":"s","type":"bytes32"}]},
+	{"type":"function","name":"deposit","inputs":[]},
+	{"type":"function","name":"withdraw","inputs":[{"name":"wad","type":"uint256"}]},
+	{"type":"function","name":"swapExactTokensForTokens","inputs":[{"name":"amountIn","type":"uint256"},{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}]},
+	{"type":"function","name":"swapExactTokensForTokens","inputs":[{"name":"amountIn","type":"uint256"},{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"}]},
+	{"type":"function","name":"swapTokensForExactTokens","inputs":[{"name":"amountOut","type":"uint256"},{"name":"amountInMax","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}]},
+	{"type":"function","name":"swapExactETHForTokens","inputs":[{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}]},
+	{"type":"function","name":"swapExactTokensForETH","inputs":[{"name":"amountIn","type":"uint256"},{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}]},
+	{"type":"function","name":"exactInputSingle","inputs":[{"name":"params","type":"tuple","components":[{"name":"tokenIn","type":"address"},{"name":"tokenOut","type":"address"},{"name":"fee","type":"uint24"},{"name":"recipient","type":"address"},{"name":"deadline","type":"uint256"},{"name":"amountIn","type":"uint256"},{"name":"amountOutMinimum","type":"uint256"},{"name":"sqrtPriceLimitX96","type":"uint160"}]}]},
+	{"type":"function","name":"exactInputSingle","inputs":[{"name":"params","type":"tuple","components":[{"name":"tokenIn","type":"address"},{"name":"tokenOut","type":"address"},{"name":"fee","type":"uint24"},{"name":"recipient","type":"address"},{"name":"amountIn","type":"uint256"},{"name":"amountOutMinimum","type":"uint256"},{"name":"sqrtPriceLimitX96","type":"uint160"}]}]},
+	{"type":"function","name":"exactInput","inputs":[{"name":"params","type":"tuple","components":[{"name":"path","type":"bytes"},{"name":"recipient","type":"address"},{"name":"deadline","type":"uint256"},{"name":"amountIn","type":"uint256"},{"name":"amountOutMinimum","type":"uint256"}]}]},
+	{"type":"function","name":"exactInput","inputs":[{"name":"params","type":"tuple","components":[{"name":"path","type":"bytes"},{"name":"recipient","type":"address"},{"name":"amountIn","type":"uint256"},{"name":"amountOutMinimum","type":"uint256"}]}]},
+	{"type":"function","name":"exchange","inputs":[{"name":"i","type":"int128"},{"name":"j","type":"int128"},{"name":"dx","type":"uint256"},{"name":"min_dy","type":"uint256"}]},
+	{"type":"function","name":"exchange","inputs":[{"name":"i","type":"uint256"},{"name":"j","type":"uint256"},{"name":"dx","type":"uint256"},{"name":"min_dy","type":"uint256"}]},
+	{"type":"function","name":"exchange_underlying","inputs":[{"name":"i","type":"int128"},{"name":"j","type":"int128"},{"name":"dx","type":"uint256"},{"name":"min_dy","type":"uint256"}]},
+	{"type":"function","name":"swap","inputs":[{"name":"singleSwap","type":"tuple","components":[{"name":"poolId","type":"bytes32"},{"name":"kind","type":"uint8"},{"name":"assetIn","type":"address"},{"name":"assetOut","type":"address"},{"name":"amount","type":"uint256"},{"name":"userData","type":"bytes"}]},{"name":"funds","type":"tuple","components":[{"name":"sender","type":"address"},{"name":"fromInternalBalance","type":"bool"},{"name":"recipient","type":"address"},{"name":"toInternalBalance","type":"bool"}]},{"name":"limit","type":"uint256"},{"name":"deadline","type":"uint256"}]},
+	{"type":"function","name":"batchSwap","inputs":[{"name":"kind","type":"uint8"},{"name":"swaps","type":"tuple[]","components":[{"name":"poolId","type":"bytes32"},{"name":"assetInIndex","type":"uint256"},{"name":"assetOutIndex","type":"uint256"},{"name":"amount","type":"uint256"},{"name":"userData","type":"bytes"}]},{"name":"assets","type":"address[]"},{"name":"funds","type":"tuple","components":[{"name":"sender","type":"address"},{"name":"fromInternalBalance","type":"bool"},{"name":"recipient","type":"address"},{"name":"toInternalBalance","type":"bool"}]},{"name":"limits","type":"int256[]"},{"name":"deadline","type":"uint256"}]},
+	{"type":"function","name":"multicall","inputs":[{"name":"data","type":"bytes[]"}]},
+	{"type":"function","name":"multicall","inputs":[{"name":"deadline","type":"uint256"},{"name":"data","type":"bytes[]"}]},
+	{"type":"function","name":"multicall","inputs":[{"name":"previousBlockhash","type":"bytes32"},{"name":"data","type":"bytes[]"}]},
+	{"type":"function","name":"aggregate","inputs":[{"name":"calls","type":"tuple[]","components":[{"name":"target","type":"address"},{"name":"callData","type":"bytes"}]}]},
+	{"type":"function","name":"tryAggregate","inputs":[{"name":"requireSuccess","type":"bool"},{"name":"calls","type":"tuple[]","components":[{"name":"target","type":"address"},{"name":"callData","type":"bytes"}]}]},
+	{"type":"function","name":"aggregate3","inputs":[{"name":"calls","type":"tuple[]","components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}]}]},
+	{"type":"function","name":"aggregate3Value","inputs":[{"name":"calls","type":"tuple[]","components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"value","type":"uint256"},{"name":"callData","type":"bytes"}]}]},
+	{"type":"function","name":"execTransaction","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"},{"name":"data","type":"bytes"},{"name":"operation","type":"uint8"},{"name":"safeTxGas","type":"uint256"},{"name":"baseGas","type":"uint256"},{"name":"gasPrice","type":"uint256"},{"name":"gasToken","type":"address"},{"name":"refundReceiver","type":"address"},{"name":"signatures","type":"bytes"}]}
+]`
+
+// DecodedCall 解码后的一次函数调用
+// 没有找到函数定义时 Name 为空，只有 Selector 和原始数据长度
+type DecodedCall struct {
+	Target   *common.Address `json:"target,omitempty"` // 内层调用的目标合约；与外层相同时为空
+	Selector string          `json:"selector"`
+	Name     string          `json:"name,omitempty"`
+	Sig      string          `json:"signature,omitempty"`
+	Args     []DecodedArg    `json:"args,omitempty"`
+	Inner    []*DecodedCall  `json:"inner,omitempty"` // multicall 等批量调用展开后的内层调用
+	Size     int             `json:"size"`
+
+	inputs   abi.Arguments
+	values   []interface{}
+	expanded map[int]bool // 已展开为内层调用的参数下标，显示时不再输出原始字节
+}
+
+// String 渲染为一行：name(a=1, b=0x...)，有内层调用时附在后面
+func (c *DecodedCall) String() string {
+	if c.Name == "" {
+		return fmt.Sprintf("%s(%d bytes)", c.Selector, c.Size)
+	}
+	parts := make([]string, len(c.Args))
+	for i, a := range c.Args {
+		parts[i] = a.Name + "=" + a.Value
+	}
+	s := c.Name + "(" + strings.Join(parts, ", ") + ")"
+	if len(c.Inner) > 0 {
+		inner := make([]string, len(c.Inner))
+		for i, in := range c.Inner {
+			inner[i] = in.String()
+		}
+		s += " => [" + strings.Join(inner, "; ") + "]"
+	}
+	return s
+}
+
+// Arg 按参数名取解码后的原始值，tuple 参数按字段名查找（例如 exactInputSingle 的 params.amountIn），
+// 没有该参数时返回 nil
+func (c *DecodedCall) Arg(name string) interface{} {
+	for i, in := range c.inputs {
+		if in.Name == name {
+			return c.values[i]
+		}
+		if in.Type.T != abi.TupleTy {
+			continue
+		}
+		for k, field := range in.Type.TupleRawNames {
+			if field == name {
+				return reflect.ValueOf(c.values[i]).Field(k).Interface()
+			}
+		}
+	}
+	return nil
+}
+
+// Targets 内层调用涉及的其它合约（递归，去重）
+func (c *DecodedCall) Targets() []common.Address {
+	var out []common.Address
+	seen := make(map[common.Address]bool)
+	var walk func(*DecodedCall)
+	walk = func(c *DecodedCall) {
+		for _, in := range c.Inner {
+			if in.Target != nil && !seen[*in.Target] {
+				seen[*in.Target] = true
+				out = append(out, *in.Target)
+			}
+			walk(in)
+		}
+	}
+	walk(c)
+	return out
+}
+
+// Pretty 多行缩进的形式：每个参数一行，tuple 按字段展开，内层调用递归缩进
+func (c *DecodedCall) Pretty() string {
+	var sb strings.Builder
+	c.writePretty(&sb, "")
+	return sb.String()
+}
+
+func (c *DecodedCall) writePretty(sb *strings.Builder, indent string) {
+	if c.Target != nil {
+		fmt.Fprintf(sb, "%s-> %s\n", indent, c.Target.Hex())
+	}
+	if c.Name == "" {
+		fmt.Fprintf(sb, "%s%s  (未知函数, %d bytes)\n", indent, c.Selector, c.Size)
+		return
+	}
+	fmt.Fprintf(sb, "%s%s  [%s]\n", indent, c.Sig, c.Selector)
+	for i, in := range c.inputs {
+		if c.expanded[i] {
+			fmt.Fprintf(sb, "%s  %s: (%s, 见下方内层调用)\n", indent, c.Args[i].Name, in.Type.String())
+			continue
+		}
+		writeABIValue(sb, indent+"  ", c.Args[i].Name, in.Type, c.values[i])
+	}
+	for i, in := range c.Inner {
+		fmt.Fprintf(sb, "%s  #%d\n", indent, i)
+		in.writePretty(sb, indent+"    ")
+	}
+}
+
+// writeABIValue 按 abi 类型输出一个值：tuple 逐字段、数组逐元素展开，其余一行
+func writeABIValue(sb *strings.Builder, indent, name string, t abi.Type, v interface{}) {
+	rv := reflect.ValueOf(v)
+	switch {
+	case t.T == abi.TupleTy && rv.Kind() == reflect.Struct:
+		fmt.Fprintf(sb, "%s%s: (%s)\n", indent, name, t.String())
+		for i, elem := range t.TupleElems {
+			writeABIValue(sb, indent+"  ", t.TupleRawNames[i], *elem, rv.Field(i).Interface())
+		}
+	case (t.T == abi.SliceTy || t.T == abi.ArrayTy) && t.Elem.T == abi.TupleTy && rv.Kind() != reflect.Invalid:
+		fmt.Fprintf(sb, "%s%s: (%s, %d 项)\n", indent, name, t.String(), rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			writeABIValue(sb, indent+"  ", fmt.Sprintf("[%d]", i), *t.Elem, rv.Index(i).Interface())
+		}
+	default:
+		fmt.Fprintf(sb, "%s%s: %s\n", indent, name, formatABIArg(t, v))
+	}
+}
+
+// formatABIArg 按 abi 类型把值格式化为一行，tuple 使用 ABI 中的原始字段名
+func formatABIArg(t abi.Type, v interface{}) string {
+	rv := reflect.ValueOf(v)
+	switch {
+	case t.T == abi.TupleTy && rv.Kind() == reflect.Struct:
+		parts := make([]string, len(t.TupleElems))
+		for i, elem := range t.TupleElems {
+			parts[i] = t.TupleRawNames[i] + ": " + formatABIArg(*elem, rv.Field(i).Interface())
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	case (t.T == abi.SliceTy || t.T == abi.ArrayTy) && (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array):
+		parts := make([]string, rv.Len())
+		for i := range parts {
+			parts[i] = formatABIArg(*t.Elem, rv.Index(i).Interface())
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+	return formatABIValue(v)
+}
+
+// MethodRegistry selector -> 函数定义，同一个 selector 可能有多个候选（签名碰撞或参数名不同）
+// 来源：内置的常见函数、ABIRegistry 中加载的所有 ABI、函数签名文件
+type MethodRegistry struct {
+	mu         sync.RWMutex
+	bySelector map[[4]byte][]abi.Method
+}
+
+// NewMethodRegistry 创建注册表并加载内置的常见函数
+func NewMethodRegistry() *MethodRegistry {
+	r := &MethodRegistry{bySelector: make(map[[4]byte][]abi.Method)}
+	for _, def := range []string{builtinMethodsABI, aggregatorMethodsABI, permitMethodsABI, userOpAccountABI} {
+		builtin, err := abi.JSON(strings.NewReader(def))
+		if err != nil {
+			panic(err) // 内置 ABI 写错了
+		}
+		r.AddABI(&builtin)
+	}
+	return r
+}
+
+// builtinMethods 没有 ABIRegistry 时使用的内置函数表
+var builtinMethods = NewMethodRegistry()
+
+// AddABI 加入 ABI 中的全部函数，签名相同的只保留一份
+func (r *MethodRegistry) AddABI(a *abi.ABI) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range a.Methods {
+		r.add(m)
+	}
+}
+
+// add 调用方持有锁
+func (r *MethodRegistry) add(m abi.Method) {
+	sel := [4]byte(m.ID)
+	for _, existing := range r.bySelector[sel] {
+		if existing.Sig == m.Sig {
+			return
+		}
+	}
+	r.bySelector[sel] = append(r.bySelector[sel], m)
+}
+
+// AddSignature 加入文本签名，例如 "transfer(address,uint256)"，参数命名为 arg0、arg1...
+func (r *MethodRegistry) AddSignature(sig string) error {
+	name, argTypes, err := parseSignature(sig)
+	if err != nil {
+		return fmt.Errorf("函数签名错误: %w", err)
+	}
+	inputs := make(abi.Arguments, len(argTypes))
+	for i, typ := range argTypes {
+		inputs[i] = abi.Argument{Name: fmt.Sprintf("arg%d", i), Type: typ}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.add(abi.NewMethod(name, name, abi.Function, "", false, false, inputs, nil))
+	return nil
+}
+
+// LoadSignatures 从文件加载函数签名，每行一个，# 开头为注释
+func (r *MethodRegistry) LoadSignatures(path string) (int, error) {
+	return readSignatureFile(path, r.AddSignature)
+}
+
+// Lookup selector 对应的全部候选函数
+func (r *MethodRegistry) Lookup(data []byte) []abi.Method {
+	if len(data) < 4 {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.bySelector[[4]byte(data[:4])]
+}
+
+// DecodeCall 解码 calldata：优先用 to 合约自己的 ABI，其次按 selector 查函数注册表，
+// 并递归展开内层调用；to 为 nil（合约创建）或 calldata 不足 4 字节时返回 false
+// r 为 nil 时只使用内置的常见函数
+func (r *ABIRegistry) DecodeCall(to *common.Address, data []byte) (*DecodedCall, bool) {
+	if to == nil || len(data) < 4 {
+		return nil, false
+	}
+	return r.decodeCall(*to, data, 0), true
+}
+
+func (r *ABIRegistry) decodeCall(to common.Address, data []byte, depth int) *DecodedCall {
+	var candidates []abi.Method
+	if r == nil {
+		candidates = builtinMethods.Lookup(data)
+	} else {
+		if m, ok := r.Method(to, data); ok {
+			candidates = append(candidates, *m)
+		}
+		candidates = append(candidates, r.methods.Lookup(data)...)
+	}
+
+	c := &DecodedCall{Selector: hexutil.Encode(data[:4]), Size: len(data)}
+	for _, m := range candidates {
+		values, err := m.Inputs.Unpack(data[4:])
+		if err != nil {
+			continue
+		}
+		c.Name, c.Sig, c.inputs, c.values = m.RawName, m.Sig, m.Inputs, values
+		c.Args = make([]DecodedArg, len(m.Inputs))
+		for i, in := range m.Inputs {
+			name := in.Name
+			if name == "" {
+				name = fmt.Sprintf("arg%d", i)
+			}
+			c.Args[i] = DecodedArg{Name: name, Type: in.Type.String(), Value: formatABIArg(in.Type, values[i])}
+		}
+		if depth < MaxCalldataDepth {
+			c.Inner, c.expanded = r.innerCalls(to, m, values, depth+1)
+			for i := range c.expanded {
+				c.Args[i].Value = "<内层调用>"
+			}
+		}
+		break
+	}
+	return c
+}
+
+// innerCalls 展开批量调用中的内层调用：
+//   - multicall*(…, bytes[] data)：每一项都是对同一个合约的调用（Uniswap 路由、NFT 管理合约等）
+//   - (address target, …, bytes callData)[]：Multicall3 aggregate 系列，每一项调用 target
+//   - 顶层的 address to + bytes data：Safe execTransaction 等代执行
+func (r *ABIRegistry) innerCalls(to common.Address, m abi.Method, values []interface{}, depth int) ([]*DecodedCall, map[int]bool) {
+	var inner []*DecodedCall
+	expanded := make(map[int]bool)
+	add := func(target common.Address, data []byte) {
+		if len(data) < 4 {
+			return
+		}
+		c := r.decodeCall(target, data, depth)
+		if target != to {
+			c.Target = &target
+		}
+		inner = append(inner, c)
+	}
+
+	var innerTo *common.Address
+	var innerData []byte
+	dataArg := -1
+	for i, in := range m.Inputs {
+		switch {
+		case in.Type.T == abi.SliceTy && in.Type.Elem.T == abi.BytesTy && strings.HasPrefix(m.RawName, "multicall"):
+			for _, data := range values[i].([][]byte) {
+				add(to, data)
+			}
+			expanded[i] = true
+		case in.Type.T == abi.SliceTy && in.Type.Elem.T == abi.TupleTy:
+			ti, di := tupleCallFields(*in.Type.Elem)
+			if ti < 0 || di < 0 {
+				continue
+			}
+			rv := reflect.ValueOf(values[i])
+			for k := 0; k < rv.Len(); k++ {
+				item := rv.Index(k)
+				add(item.Field(ti).Interface().(common.Address), item.Field(di).Interface().([]byte))
+			}
+			expanded[i] = true
+		case in.Type.T == abi.AddressTy && (in.Name == "to" || in.Name == "target"):
+			addr := values[i].(common.Address)
+			innerTo = &addr
+		case in.Type.T == abi.BytesTy && (in.Name == "data" || in.Name == "callData"):
+			innerData, dataArg = values[i].([]byte), i
+		}
+	}
+	if innerTo != nil && len(innerData) >= 4 {
+		add(*innerTo, innerData)
+		expanded[dataArg] = true
+	}
+	return inner, expanded
+}
+
+// tupleCallFields 在 tuple 中找 (address target, bytes callData) 字段，找不到返回 -1
+func tupleCallFields(t abi.Type) (int, int) {
+	ti, di := -1, -1
+	for i, elem := range t.TupleElems {
+		switch {
+		case elem.T == abi.AddressTy && ti < 0:
+			ti = i
+		case elem.T == abi.BytesTy && di < 0:
+			di = i
+		}
+	}
+	return ti, di
+}
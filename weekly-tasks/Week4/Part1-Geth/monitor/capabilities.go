@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ------------------------------------------------
+// 节点能力探测：连接后逐个实际调用一次依赖的接口（参数尽量便宜），
+// 不可用的功能在启动时就关闭并打印能力报告，而不是等到第一次使用时才报错
+// rpc_modules 只说明模块开启了，托管节点经常开启模块却屏蔽其中的方法，所以以实际调用为准
+// ------------------------------------------------
+
+// Capabilities 实际探测到的节点能力
+type Capabilities struct {
+	TxPool         bool `json:"txpool"`          // txpool_status
+	DebugTrace     bool `json:"debug_trace"`     // debug_traceTransaction（callTracer）
+	ParityTrace    bool `json:"parity_trace"`    // trace_transaction
+	BlockReceipts  bool `json:"block_receipts"`  // eth_getBlockReceipts
+	FullPendingSub bool `json:"full_pending_tx"` // eth_subscribe newPendingTransactions（完整交易）
+	PendingHashSub bool `json:"pending_tx_hash"` // eth_subscribe newPendingTransactions（只有哈希）
+}
+
+// ProbeCapabilities 逐个探测节点能力，单个探测失败只影响对应的能力
+func ProbeCapabilities(ctx context.Context, client *rpc.Client) *Capabilities {
+	caps := &Capabilities{}
+	probe := func(method string, args ...interface{}) bool {
+		ctx, cancel := context.WithTimeout(ctx, CONNECTION_TIMEOUT)
+		defer cancel()
+		var raw interface{}
+		return !isUnsupported(callRetry(ctx, client, &raw, method, args...))
+	}
+	// 查询不存在的交易：方法存在时返回 null 或 "not found"，不存在时返回 -32601
+	var missing common.Hash
+	caps.TxPool = probe("txpool_status")
+	caps.DebugTrace = probe("debug_traceTransaction", missing, map[string]string{"tracer": "callTracer"})
+	caps.ParityTrace = probe("trace_transaction", missing)
+	caps.BlockReceipts = probe("eth_getBlockReceipts", "0x0") // 创世区块没有交易，返回空数组
+
+	subscribe := func(ch interface{}, args ...interface{}) bool {
+		ctx, cancel := context.WithTimeout(ctx, CONNECTION_TIMEOUT)
+		defer cancel()
+		sub, err := client.EthSubscribe(ctx, ch, append([]interface{}{"newPendingTransactions"}, args...)...)
+		if err != nil {
+			return false
+		}
+		sub.Unsubscribe()
+		return true
+	}
+	caps.FullPendingSub = subscribe(make(chan *types.Transaction), true)
+	caps.PendingHashSub = subscribe(make(chan common.Hash))
+	return caps
+}
+
+// Apply 用探测结果修正按节点实现推断的 trace 能力
+func (c *Capabilities) Apply(info *NodeInfo) {
+	info.ParityTrace = info.ParityTrace && c.ParityTrace
+	info.DebugTrace = info.DebugTrace && c.DebugTrace
+	// 不认识的节点实现（或 web3_clientVersion 被屏蔽）完全按探测结果
+	if info.Kind == NodeUnknown {
+		info.ParityTrace, info.DebugTrace = c.ParityTrace, c.DebugTrace
+	}
+}
+
+// String 启动时的能力报告
+func (c *Capabilities) String() string {
+	flag := func(b bool) string {
+		if b {
+			return "✅"
+		}
+		return "❌"
+	}
+	rows := []struct {
+		name string
+		ok   bool
+		uses string
+	}{
+		{"txpool_status", c.TxPool, "交易池时间序列、节点健康检查的交易池大小"},
+		{"debug_trace*", c.DebugTrace, "调用树（Coinbase 支付、Bundle 识别）"},
+		{"trace_*", c.ParityTrace, "调用树、trace_filter 回扫"},
+		{"eth_getBlockReceipts", c.BlockReceipts, "一次拉取整个区块的回执（否则逐笔拉取）"},
+		{"pending tx (full)", c.FullPendingSub, "关注地址的 Pending 分析"},
+		{"pending tx (hash)", c.PendingHashSub, "完整交易订阅不可用时的退化（只有哈希）"},
+	}
+	var b strings.Builder
+	b.WriteString("🧪 节点能力:")
+	for _, r := range rows {
+		fmt.Fprintf(&b, "\n   %s %-24s %s", flag(r.ok), r.name, r.uses)
+	}
+	return b.String()
+}
+
+// RegisterAPI 注册能力查询接口
+//
+//	GET /api/capabilities 启动时探测到的节点能力
+func (c *Capabilities) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/capabilities", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, c)
+	})
+}
+
+// isUnsupported 方法不存在或被服务商屏蔽
+// 除了标准的 -32601，托管节点常用其它错误码，只能按错误信息判断
+func isUnsupported(err error) bool {
+	if err == nil {
+		return false
+	}
+	if isMethodNotFound(err) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"not supported", "unsupported", "does not exist", "not available", "not found"} {
+		if strings.Contains(msg, s) && strings.Contains(msg, "method") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,218 @@
+package main
+
+// ------------------------------------------------
+// CEL 表达式：过滤链的 cel 条件和路由规则的 when 条件可以写成 Google CEL 表达式
+// (https://github.com/google/cel-go)，在下面 CELTx / CELCall / CELEvent 描述的结构上求值，
+// 加载配置时编译并做类型检查（字段名写错、类型不匹配、结果不是 bool 都会在启动时报错）
+// ------------------------------------------------
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/google/cel-go/cel"
+	celtypes "github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/ext"
+)
+
+// CELTx 表达式中的 tx：交易本身的字段，地址和哈希为小写十六进制
+type CELTx struct {
+	Hash       string  `json:"hash"`
+	To         string  `json:"to"` // 创建合约时为 ""
+	ValueETH   float64 `json:"value_eth"`
+	Nonce      int64   `json:"nonce"`
+	Gas        int64   `json:"gas"`
+	FeeCapGwei float64 `json:"fee_cap_gwei"` // maxFeePerGas，legacy 交易为 gasPrice
+	TipGwei    float64 `json:"tip_gwei"`     // maxPriorityFeePerGas，legacy 交易为 gasPrice
+	Type       int64   `json:"type"`
+	Selector   string  `json:"selector"` // calldata 前 4 字节，0x 开头；不足 4 字节时为 ""
+	DataSize   int64   `json:"data_size"`
+}
+
+// CELCall 表达式中的 call：解码后的调用，ABI 未知时只有 selector
+type CELCall struct {
+	Name     string              `json:"name"`
+	Selector string              `json:"selector"`
+	Args     map[string][]string `json:"args"`    // 参数名（小写，tuple 字段写 a.b）-> 值（小写），展开方式与搜索索引相同，数组参数有多个值
+	Methods  []string            `json:"methods"` // 本调用和 multicall 等展开后全部内层调用的函数名
+}
+
+// CELEvent 路由规则中的 event：已补上级别和分类的事件
+type CELEvent struct {
+	Type     string `json:"type"`
+	Severity string `json:"severity"`
+	Category string `json:"category"`
+	Summary  string `json:"summary"`
+	Text     string `json:"text"`
+	HasTx    bool   `json:"has_tx"` // 事件是否带有交易；为 false 时 tx / from / call 为零值
+}
+
+var (
+	celOnce    sync.Once
+	celTxEnv   *cel.Env // 过滤链：tx / from / call
+	celEvEnv   *cel.Env // 路由规则：再加上 event / data
+	celEnvErr  error
+	celErrors  *metrics.Counter
+	celTxType  = cel.ObjectType(celTypeName(CELTx{}))
+	celCallTyp = cel.ObjectType(celTypeName(CELCall{}))
+)
+
+// celTypeName 结构体在 CEL 中的类型名（包路径的最后一段 + 类型名，与 ext.NativeTypes 一致）
+func celTypeName(v interface{}) string {
+	t := reflect.TypeOf(v)
+	return path.Base(t.PkgPath()) + "." + t.Name()
+}
+
+// celEnvs 创建两个表达式环境，全局只创建一次
+func celEnvs() (*cel.Env, *cel.Env, error) {
+	celOnce.Do(func() {
+		celErrors = metrics.NewRegisteredCounter("monitor/cel/errors", metricsRegistry)
+		opts := []cel.EnvOption{
+			ext.NativeTypes(reflect.TypeOf(CELTx{}), reflect.TypeOf(CELCall{}), reflect.TypeOf(CELEvent{}), ext.ParseStructTag("json")),
+			ext.Strings(),
+			cel.CrossTypeNumericComparisons(true), // tx.value_eth > 10 不需要写成 10.0
+			cel.Variable("tx", celTxType),
+			cel.Variable("from", cel.StringType),
+			cel.Variable("call", celCallTyp),
+			// watched(addr) 地址是否在关注列表中
+			cel.Function("watched", cel.Overload("watched_string", []*cel.Type{cel.StringType}, cel.BoolType,
+				cel.UnaryBinding(func(v ref.Val) ref.Val {
+					s, _ := v.Value().(string)
+					set := currentFilters()
+					return celtypes.Bool(set != nil && set.watch != nil && common.IsHexAddress(s) && set.watch.Contains(common.HexToAddress(s)))
+				}))),
+		}
+		if celTxEnv, celEnvErr = cel.NewEnv(opts...); celEnvErr != nil {
+			return
+		}
+		celEvEnv, celEnvErr = celTxEnv.Extend(
+			cel.Variable("event", cel.ObjectType(celTypeName(CELEvent{}))),
+			cel.Variable("data", cel.DynType),
+		)
+	})
+	return celTxEnv, celEvEnv, celEnvErr
+}
+
+// compileCEL 编译并检查表达式；withEvent 为 true 时可以使用 event 和 data（路由规则）
+func compileCEL(expr string, withEvent bool) (cel.Program, error) {
+	txEnv, evEnv, err := celEnvs()
+	if err != nil {
+		return nil, fmt.Errorf("创建 CEL 环境失败: %w", err)
+	}
+	env := txEnv
+	if withEvent {
+		env = evEnv
+	}
+	ast, iss := env.Compile(expr)
+	if iss.Err() != nil {
+		return nil, fmt.Errorf("CEL 表达式错误: %w", iss.Err())
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("CEL 表达式的结果需要是 bool，实际为 %s", ast.OutputType())
+	}
+	prg, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("CEL 表达式错误: %w", err)
+	}
+	return prg, nil
+}
+
+// evalCEL 求值；出错（例如 map 中没有该键）按不满足处理并计入 monitor/cel/errors
+func evalCEL(prg cel.Program, vars map[string]interface{}) bool {
+	out, _, err := prg.Eval(vars)
+	if err != nil {
+		celErrors.Inc(1)
+		return false
+	}
+	ok, _ := out.Value().(bool)
+	return ok
+}
+
+// celVars 交易相关的变量，from 和 call 在表达式用到时才计算；s 为 nil 时三者都是零值
+func celVars(set *FilterSet, s *filterSubject) map[string]interface{} {
+	if s == nil {
+		return map[string]interface{}{"tx": CELTx{}, "from": "", "call": CELCall{Args: map[string][]string{}, Methods: []string{}}}
+	}
+	return map[string]interface{}{
+		"tx": func() interface{} { return newCELTx(s.tx) },
+		"from": func() interface{} {
+			if from := s.sender(set); from != nil {
+				return strings.ToLower(from.Hex())
+			}
+			return ""
+		},
+		"call": func() interface{} { return newCELCall(s.decode(set), s.tx.Data()) },
+	}
+}
+
+// celEventVars 路由规则的变量
+func celEventVars(set *FilterSet, ev Event) map[string]interface{} {
+	var s *filterSubject
+	if ev.tx != nil {
+		s = &filterSubject{tx: ev.tx, from: ev.txFrom}
+	}
+	vars := celVars(set, s)
+	vars["event"] = CELEvent{Type: ev.Type, Severity: ev.Severity, Category: ev.Category, Summary: ev.summary(), Text: ev.Text, HasTx: ev.tx != nil}
+	vars["data"] = func() interface{} {
+		var data interface{}
+		if body, err := json.Marshal(ev.Data); err == nil {
+			json.Unmarshal(body, &data)
+		}
+		return data
+	}
+	return vars
+}
+
+func newCELTx(tx *types.Transaction) CELTx {
+	c := CELTx{
+		Hash:       strings.ToLower(tx.Hash().Hex()),
+		ValueETH:   weiToEther(tx.Value()),
+		Nonce:      int64(tx.Nonce()),
+		Gas:        int64(tx.Gas()),
+		FeeCapGwei: weiToGwei(tx.GasFeeCap()),
+		TipGwei:    weiToGwei(tx.GasTipCap()),
+		Type:       int64(tx.Type()),
+		DataSize:   int64(len(tx.Data())),
+	}
+	if to := tx.To(); to != nil {
+		c.To = strings.ToLower(to.Hex())
+	}
+	if data := tx.Data(); len(data) >= 4 {
+		c.Selector = fmt.Sprintf("%#x", data[:4])
+	}
+	return c
+}
+
+func newCELCall(call *DecodedCall, data []byte) CELCall {
+	c := CELCall{Args: map[string][]string{}, Methods: []string{}}
+	if call == nil {
+		if len(data) >= 4 {
+			c.Selector = fmt.Sprintf("%#x", data[:4])
+		}
+		return c
+	}
+	c.Name, c.Selector = call.Name, call.Selector
+	for _, a := range searchArgs(call.Args) {
+		name := strings.ToLower(a.name)
+		c.Args[name] = append(c.Args[name], a.value)
+	}
+	var walk func(call *DecodedCall)
+	walk = func(call *DecodedCall) {
+		if call.Name != "" {
+			c.Methods = append(c.Methods, call.Name)
+		}
+		for _, in := range call.Inner {
+			walk(in)
+		}
+	}
+	walk(call)
+	return c
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// 重启后最多回补的区块数，停机太久时只回补最近的部分
+	// ⚠️ 每个区块都要拉取完整区块和回执，主网 1000 个区块约 3 小时
+	DefaultCheckpointMaxBackfill = 1000
+	// BlockFetcher 的断点名
+	fetcherCheckpoint = "block_fetcher"
+)
+
+// CheckpointConfig 断点续传：记录最后一个处理完的区块，重启后从断点回补到最新区块再切换到实时模式
+type CheckpointConfig struct {
+	Enabled     bool   `json:"enabled"`
+	DB          string `json:"db"`           // SQLite 文件，默认与索引共用
+	MaxBackfill int    `json:"max_backfill"` // 最多回补的区块数
+}
+
+const checkpointSchema = `
+CREATE TABLE IF NOT EXISTS checkpoints (
+	name         TEXT PRIMARY KEY,
+	block_number INTEGER NOT NULL,
+	block_hash   TEXT    NOT NULL,
+	updated_at   INTEGER NOT NULL
+);`
+
+// CheckpointStore 断点的 SQLite 存储
+type CheckpointStore struct {
+	db *sql.DB
+}
+
+func OpenCheckpointStore(path string) (*CheckpointStore, error) {
+	db, err := openSQLite(path, checkpointSchema)
+	if err != nil {
+		return nil, err
+	}
+	return &CheckpointStore{db: db}, nil
+}
+
+// Load 读取断点，从未保存过时 ok 为 false
+func (s *CheckpointStore) Load(name string) (cur IndexCursor, ok bool, err error) {
+	var hash string
+	err = s.db.QueryRow(`SELECT block_number, block_hash FROM checkpoints WHERE name = ?`, name).
+		Scan(&cur.BlockNumber, &hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return cur, false, nil
+	}
+	if err != nil {
+		return cur, false, fmt.Errorf("读取断点失败: %w", err)
+	}
+	cur.BlockHash = common.HexToHash(hash)
+	return cur, true, nil
+}
+
+// Save 更新断点
+func (s *CheckpointStore) Save(name string, cur IndexCursor) error {
+	if _, err := s.db.Exec(`INSERT INTO checkpoints (name, block_number, block_hash, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET
+		block_number = excluded.block_number, block_hash = excluded.block_hash, updated_at = excluded.updated_at`,
+		name, cur.BlockNumber, cur.BlockHash.Hex(), time.Now().Unix()); err != nil {
+		return fmt.Errorf("保存断点失败: %w", err)
+	}
+	return nil
+}
+
+func (s *CheckpointStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Clients 同一条 RPC 连接上的三层客户端
+// RPC: 原始 JSON-RPC 调用；Eth: 通用查询与区块头订阅；Geth: Geth 特有的订阅（如 Pending 交易）
+type Clients struct {
+	RPC  *rpc.Client
+	Eth  *ethclient.Client
+	Geth *gethclient.Client
+}
+
+// Dial 配置代理并连接到 WebSocket 节点
+func Dial(cfg *Config) (*Clients, error) {
+	if err := setupProxy(cfg.ProxyPort); err != nil {
+		return nil, err
+	}
+
+	// 需要在代理设置之后测量；每个节点各自有连接超时
+	PinFastestEndpoint(context.Background(), cfg)
+
+	// 注意：dialRPC 会自动使用环境变量中的代理设置
+	ctx, cancel := context.WithTimeout(context.Background(), CONNECTION_TIMEOUT)
+	defer cancel()
+
+	SetRetryPolicy(cfg.Retry)
+	retryCounts.Store(!transportMetered(cfg.WSURL))
+	rpcClient, err := dialRPC(ctx, cfg.WSURL)
+	if err != nil {
+		return nil, fmt.Errorf("无法连接到 WebSocket 节点 (代理端口: %s): %w", cfg.ProxyPort, err)
+	}
+	return &Clients{
+		RPC:  rpcClient,
+		Eth:  ethclient.NewClient(rpcClient),
+		Geth: gethclient.New(rpcClient),
+	}, nil
+}
+
+// Close 关闭底层 RPC 连接
+func (c *Clients) Close() {
+	c.RPC.Close()
+}
+
+// setupProxy 通过设置环境变量让 dialRPC 走本地代理
+func setupProxy(port string) error {
+	if port == "" || port == "YOUR_PROXY_PORT" {
+		log.Println("✅ 未配置代理（直接连接）")
+		return nil
+	}
+	proxyUrlString := fmt.Sprintf("http://127.0.0.1:%s", port)
+	if _, err := url.Parse(proxyUrlString); err != nil {
+		return fmt.Errorf("解析代理 URL 失败: %w", err)
+	}
+	os.Setenv("HTTP_PROXY", proxyUrlString)
+	os.Setenv("HTTPS_PROXY", proxyUrlString)
+	log.Printf("✅ 代理配置: %s", proxyUrlString)
+	return nil
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// 排行榜默认返回的付款方数量
+const DefaultCoinbaseTop = 20
+
+// MEVConfig 区块内 MEV 相关的分析
+type MEVConfig struct {
+	// 统计区块内直接转给出块者（coinbase）的 ETH，节点支持 trace 时包括合约内部的转账
+	CoinbasePayments bool `json:"coinbase_payments"`
+	// 把区块中相邻的交易按 searcher 归组为 bundle
+	Bundles bool `json:"bundles"`
+	// 关注地址的 swap 被三明治攻击时提醒（需要配置 watch）
+	Sandwich bool `json:"sandwich"`
+	// 检测针对这些合约（例如自己的协议）的抢跑
+	FrontrunContracts []string `json:"frontrun_contracts"`
+}
+
+// CoinbasePayment 一笔直接转给出块者的 ETH
+type CoinbasePayment struct {
+	TxHash   common.Hash    `json:"tx_hash"`
+	TxIndex  int            `json:"tx_index"`
+	Payer    common.Address `json:"payer"`    // 转账的发起方：内部转账为 searcher 合约，顶层转账为 EOA
+	Sender   common.Address `json:"sender"`   // 交易的发送者
+	Internal bool           `json:"internal"` // 是否为合约内部转账
+	Value    *big.Int       `json:"value"`
+}
+
+// BlockPayments 一个区块的 coinbase 转账汇总
+type BlockPayments struct {
+	Number       uint64            `json:"number"`
+	Coinbase     common.Address    `json:"coinbase"`
+	Builder      string            `json:"builder"`
+	Payments     []CoinbasePayment `json:"payments"`
+	Total        *big.Int          `json:"total"`         // coinbase 转账合计
+	PriorityFees *big.Int          `json:"priority_fees"` // 同一区块的优先费合计，用于对比
+	Traced       bool              `json:"traced"`        // 是否包含内部转账（false 时只统计了顶层转账）
+}
+
+// PayerStats 一个付款方的累计统计
+type PayerStats struct {
+	Payer  common.Address `json:"payer"`
+	Count  int            `json:"count"`
+	Blocks int            `json:"blocks"`
+	Total  *big.Int       `json:"total"`
+}
+
+// CoinbasePaymentAnalyzer 找出区块中直接转给出块者的 ETH（MEV bundle 的典型特征：searcher 在合约里给 builder 付费），
+// 按区块汇总并归属到付款的 searcher 合约，估算每个区块的链上 MEV 支付
+type CoinbasePaymentAnalyzer struct {
+	mu       sync.Mutex
+	payers   map[common.Address]*PayerStats
+	last     *BlockPayments // 最近一个区块的结果
+	lastHash common.Hash
+
+	lastTotal *metrics.GaugeFloat64
+	onBlock   func(BlockPayments)
+}
+
+// NewCoinbasePaymentAnalyzer 创建分析器，onBlock 为 nil 时使用默认输出；
+// 内部转账来自 BlockData.Traces，节点不支持 trace 时只能看到顶层转账
+func NewCoinbasePaymentAnalyzer(onBlock func(BlockPayments)) *CoinbasePaymentAnalyzer {
+	if onBlock == nil {
+		onBlock = PrintBlockPayments
+	}
+	return &CoinbasePaymentAnalyzer{
+		payers:    make(map[common.Address]*PayerStats),
+		lastTotal: metrics.NewRegisteredGaugeFloat64("monitor/mev/coinbase_payment_eth", metricsRegistry),
+		onBlock:   onBlock,
+	}
+}
+
+// Enrichment 实现 Enricher：需要内部调用
+func (a *CoinbasePaymentAnalyzer) Enrichment() EnrichLevel { return EnrichTraces }
+
+// OnBlock 实现 BlockAnalyzer
+func (a *CoinbasePaymentAnalyzer) OnBlock(data *BlockData) {
+	bp := a.Collect(data)
+	if len(bp.Payments) == 0 {
+		a.lastTotal.Update(0)
+		return
+	}
+
+	a.mu.Lock()
+	counted := make(map[common.Address]bool)
+	for _, p := range bp.Payments {
+		s, ok := a.payers[p.Payer]
+		if !ok {
+			s = &PayerStats{Payer: p.Payer, Total: new(big.Int)}
+			a.payers[p.Payer] = s
+		}
+		s.Count++
+		s.Total.Add(s.Total, p.Value)
+		if !counted[p.Payer] {
+			counted[p.Payer] = true
+			s.Blocks++
+		}
+	}
+	a.mu.Unlock()
+
+	a.lastTotal.Update(weiToEther(bp.Total))
+	a.onBlock(bp)
+}
+
+// Collect 找出区块中的 coinbase 转账；结果按区块缓存，同一区块的其它分析器（如 BundleDetector）再次调用时不会重复 trace
+func (a *CoinbasePaymentAnalyzer) Collect(data *BlockData) BlockPayments {
+	header := data.Block.Header()
+	a.mu.Lock()
+	if a.last != nil && a.lastHash == data.Block.Hash() {
+		bp := *a.last
+		a.mu.Unlock()
+		return bp
+	}
+	a.mu.Unlock()
+
+	coinbase := header.Coinbase
+	bp := BlockPayments{
+		Number:       header.Number.Uint64(),
+		Coinbase:     coinbase,
+		Builder:      builderName(header),
+		Total:        new(big.Int),
+		PriorityFees: new(big.Int),
+	}
+	txs := data.Block.Transactions()
+	for _, r := range data.Receipts {
+		tip := new(big.Int).Set(r.EffectiveGasPrice)
+		if header.BaseFee != nil {
+			tip.Sub(tip, header.BaseFee)
+		}
+		bp.PriorityFees.Add(bp.PriorityFees, tip.Mul(tip, new(big.Int).SetUint64(r.GasUsed)))
+	}
+
+	if data.Level >= EnrichTraces {
+		bp.Traced = true
+		for _, c := range data.Traces {
+			if c.To != coinbase || c.From == coinbase || c.Value == nil || c.Value.Sign() == 0 || c.Error != "" ||
+				c.Type == "delegatecall" || c.Type == "staticcall" || c.TxHash == (common.Hash{}) || c.TxIndex >= len(txs) {
+				continue
+			}
+			if data.Receipts[c.TxIndex].Status == 0 {
+				continue // 交易回滚，转账没有生效
+			}
+			bp.Payments = append(bp.Payments, CoinbasePayment{
+				TxHash: c.TxHash, TxIndex: c.TxIndex, Payer: c.From, Sender: data.Senders[c.TxIndex],
+				Internal: c.IsInternal(), Value: c.Value,
+			})
+		}
+	}
+	if !bp.Traced {
+		for i, tx := range txs {
+			if to := tx.To(); to == nil || *to != coinbase || tx.Value().Sign() == 0 ||
+				data.Senders[i] == coinbase || data.Receipts[i].Status == 0 {
+				continue
+			}
+			bp.Payments = append(bp.Payments, CoinbasePayment{
+				TxHash: tx.Hash(), TxIndex: i, Payer: data.Senders[i], Sender: data.Senders[i], Value: tx.Value(),
+			})
+		}
+	}
+	for _, p := range bp.Payments {
+		bp.Total.Add(bp.Total, p.Value)
+	}
+
+	a.mu.Lock()
+	a.last, a.lastHash = &bp, data.Block.Hash()
+	a.mu.Unlock()
+	return bp
+}
+
+// TopPayers 累计付款最多的 n 个付款方
+func (a *CoinbasePaymentAnalyzer) TopPayers(n int) []PayerStats {
+	a.mu.Lock()
+	out := make([]PayerStats, 0, len(a.payers))
+	for _, s := range a.payers {
+		out = append(out, PayerStats{Payer: s.Payer, Count: s.Count, Blocks: s.Blocks, Total: new(big.Int).Set(s.Total)})
+	}
+	a.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Total.Cmp(out[j].Total) > 0 })
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// RegisterAPI 注册 coinbase 转账查询接口
+//
+//	GET /api/mev/payers?top=20 累计给出块者付款最多的付款方
+func (a *CoinbasePaymentAnalyzer) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/mev/payers", func(w http.ResponseWriter, r *http.Request) {
+		top := DefaultCoinbaseTop
+		if s := r.URL.Query().Get("top"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				writeError(w, http.StatusBadRequest, "top 必须是正整数")
+				return
+			}
+			top = n
+		}
+		writeJSON(w, http.StatusOK, a.TopPayers(top))
+	})
+}
+
+// PrintBlockPayments 默认的 coinbase 转账输出
+func PrintBlockPayments(bp BlockPayments) {
+	var sb strings.Builder
+	summary := fmt.Sprintf("💰 [Coinbase] 区块 %d (%s): %d 笔直接转账共 %.6f ETH，优先费 %.6f ETH",
+		bp.Number, bp.Builder, len(bp.Payments), weiToEther(bp.Total), weiToEther(bp.PriorityFees))
+	sb.WriteString("\n" + summary + "\n")
+	for _, p := range bp.Payments {
+		kind := "顶层"
+		if p.Internal {
+			kind = "内部"
+		}
+		fmt.Fprintf(&sb, "   #%-4d %s %s %.6f ETH（发送者 %s）\n", p.TxIndex, kind, p.Payer.Hex(), weiToEther(p.Value), p.Sender.Hex())
+	}
+	if !bp.Traced {
+		sb.WriteString("   ⚠️  没有 trace 数据，只统计了顶层转账\n")
+	}
+	Emit(Event{Type: "coinbase_payments", Key: eventKey(bp.Number, bp.Coinbase), Summary: summary, Text: sb.String(), Data: bp})
+}
@@ -0,0 +1,799 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// ------------------------------------------------
+// ⚠️ 关键配置：默认值（可以被 JSON 配置文件覆盖）
+// ------------------------------------------------
+
+const (
+	// ⚠️ 请替换为你的 WebSocket 节点地址
+	// Infura: wss://mainnet.infura.io/ws/v3/YOUR_API_KEY
+	// Alchemy: wss://eth-mainnet.g.alchemy.com/v2/YOUR_API_KEY
+	DefaultWSURL = "wss://mainnet.infura.io/ws/v3/YOUR_API_KEY"
+
+	// ⚠️ 请根据你的代理软件修改端口号（Clash: 7890, V2Ray: 10808）
+	// 如果不需要代理，可以设为空字符串 ""
+	DefaultProxyPort = "YOUR_PROXY_PORT"
+
+	// 默认配置文件路径，文件不存在时直接使用上面的默认值
+	DefaultConfigPath = "monitor.json"
+
+	// 事件索引默认的数据库文件和确认数
+	DefaultIndexDB            = "index.db"
+	DefaultIndexConfirmations = 2
+
+	// ABI 磁盘缓存目录
+	DefaultABICacheDir = "abi_cache"
+	// 代币元数据缓存文件
+	DefaultTokenCache = "tokens.json"
+
+	// 设置较大的超时时间，应对代理连接延迟
+	CONNECTION_TIMEOUT = 45 * time.Second
+)
+
+// Config 监控程序的完整配置，对应 monitor.json
+type Config struct {
+	WSURL     string       `json:"ws_url"`
+	ProxyPort string       `json:"proxy_port"`
+	Watch     []string     `json:"watch"` // 关注的地址列表
+	Signer    SignerConfig `json:"signer"`
+
+	// Prometheus 指标监听地址，例如 "127.0.0.1:9100"，留空不启动
+	MetricsAddr string `json:"metrics_addr"`
+
+	// 区块健康度 / Gas 排行 / 巨鲸统计，需要每个区块的全部回执
+	// 关闭后其余分析器按 logsBloom 预筛，只拉取可能有匹配日志的区块，关注范围很窄时能大幅减少 RPC 请求
+	BlockStats bool `json:"block_stats"`
+
+	// 启动时先用最近这么多个区块预热分析器的滚动窗口（出块健康度、Gas 排行、巨鲸、撤池检测），0 表示不预热
+	WarmupBlocks int `json:"warmup_blocks"`
+
+	// 每个区块最多拉取到哪一级：header / transactions / receipts / traces，每级包含前一级
+	// 分析器按自己需要的级别拉取，超过上限的分析器停用（需要 traces 的降级为 receipts）
+	BlockLevel string `json:"block_level"`
+
+	// 按分析器类型名覆盖上限，例如 {"CoinbasePaymentAnalyzer": "receipts"} 只关掉它的 trace
+	BlockLevelOverrides map[string]string `json:"block_level_overrides"`
+
+	Whale      WhaleConfig      `json:"whale"`
+	Reports    ReportsConfig    `json:"reports"`
+	Simulation SimulationConfig `json:"simulation"`
+	Logs       LogsConfig       `json:"logs"`
+	Indexer    IndexerConfig    `json:"indexer"`
+	History    HistoryConfig    `json:"history"`
+	Search     SearchConfig     `json:"search"`
+	ABI        ABIConfig        `json:"abi"`
+
+	// 关注分组：每个分组有自己的地址、过滤条件和输出目标，与 watch 互相独立
+	Profiles []ProfileConfig `json:"profiles"`
+
+	// 命名的过滤链（filter_chain.go），由 mempool.filter、分组、大额交易提醒、outputs 和 routing.routes 按名称引用
+	Filters map[string]FilterExpr `json:"filters"`
+
+	// ERC-20 元数据（name / symbol / decimals）缓存文件
+	TokenCache string `json:"token_cache"`
+
+	Prices PriceConfig `json:"prices"`
+	PnL    PnLConfig   `json:"pnl"`
+
+	Portfolio PortfolioConfig `json:"portfolio"`
+	Honeypot  HoneypotConfig  `json:"honeypot"`
+	RugPull   RugPullConfig   `json:"rug_pull"`
+	Sanctions SanctionsConfig `json:"sanctions"`
+
+	Propagation PropagationConfig `json:"propagation"`
+	NodeHealth  NodeHealthConfig  `json:"node_health"`
+	Txpool      TxpoolConfig      `json:"txpool"`
+	Checkpoint  CheckpointConfig  `json:"checkpoint"`
+	Mempool     MempoolConfig     `json:"mempool"`
+	Bots        BotConfig         `json:"bots"`
+	MEV         MEVConfig         `json:"mev"`
+	CopyTrade   CopyTradeConfig   `json:"copy_trade"`
+	Pools       PoolsConfig       `json:"pools"`
+	Curve       CurveConfig       `json:"curve"`
+	Balancer    BalancerConfig    `json:"balancer"`
+	Arb         ArbConfig         `json:"arb"`
+	UserOps     UserOpConfig      `json:"userops"`
+	EIP7702     DelegationConfig  `json:"eip7702"`
+	Bytecode    BytecodeConfig    `json:"bytecode"`
+	Governance  GovernanceConfig  `json:"governance"`
+	Safe        SafeConfig        `json:"safe"`
+	Timelock    TimelockConfig    `json:"timelock"`
+	Bridges     BridgeConfig      `json:"bridges"`
+	CrossChain  CrossChainConfig  `json:"crosschain"`
+
+	// 轻量模式（lite 子命令）只订阅区块头
+	Lite LiteConfig `json:"lite"`
+
+	// 非订阅 RPC 调用遇到临时错误（超时、限流、连接重置）时的重试策略
+	Retry RetryConfig `json:"retry"`
+
+	// 备用 RPC 节点与熔断 / 加权分流：主节点出错或变慢时，区块 / 回执拉取改发其它节点
+	RPCPool RPCPoolConfig `json:"rpc_pool"`
+
+	// 每隔多少分钟输出一次按 RPC 方法统计的调用次数和延迟，0 表示不输出（/api/rpc-usage 和指标不受影响）
+	RPCUsageReportMinutes int `json:"rpc_usage_report_minutes"`
+
+	// 每天的 RPC 预算（请求数或 CU），接近时自动降级
+	Budget BudgetConfig `json:"budget"`
+
+	// 配置文件变化时自动热加载（不开启时仍可以通过 SIGHUP 或 POST /api/reload 加载）
+	ReloadOnChange bool `json:"reload_on_change"`
+
+	// 通过 API / SIGUSR2 暂停输出时对事件的处理
+	Pause PauseConfig `json:"pause"`
+
+	// 启动时开启哪些订阅（新区块 / Pending / 日志 / finalized），运行中可以通过 API 或 SIGUSR1 切换
+	Subscriptions SubscriptionsConfig `json:"subscriptions"`
+
+	// 按事件类型限流提醒，窗口内重复的提醒合并成一条带次数的事件
+	Throttle ThrottleConfig `json:"throttle"`
+
+	// 内置 REST API 监听地址，例如 "127.0.0.1:8080"，留空不启动
+	APIAddr string `json:"api_addr"`
+	// API key 鉴权、权限、限流和请求日志，keys 为空时不鉴权
+	APIAuth APIAuthConfig `json:"api_auth"`
+	// API 和指标服务的 TLS 证书，留空使用明文 HTTP
+	TLS TLSConfig `json:"tls"`
+	// /readyz 要求最近一次新区块头在这么多秒以内
+	ReadyMaxHeadAge int `json:"ready_max_head_age"`
+
+	// 输出目标，每个目标可以选择格式并按事件类型调整详细程度；留空时输出到 stdout（pretty）
+	Outputs []OutputConfig `json:"outputs"`
+	// 按事件级别和分类把事件路由到部分输出，例如 critical 发到 telegram
+	Routing RoutingConfig `json:"routing"`
+	// 提醒模板中 tx / address / block 链接使用的区块浏览器，默认 https://etherscan.io
+	ExplorerURL string `json:"explorer_url"`
+}
+
+// HoneypotConfig 新代币的骗局风险检查
+type HoneypotConfig struct {
+	Enabled bool   `json:"enabled"`
+	Router  string `json:"router"` // 模拟买卖使用的 Uniswap V2 兼容路由
+}
+
+// RugPullConfig 关注代币的撤池检测，tokens 为空时不启动
+type RugPullConfig struct {
+	Tokens       []string `json:"tokens"`
+	WindowBlocks uint64   `json:"window_blocks"` // 统计窗口（区块数）
+	ThresholdPct float64  `json:"threshold_pct"` // 窗口内移除的流动性超过该百分比时提醒
+}
+
+// SanctionsConfig 制裁 / 黑名单地址筛查，lists 为空时不启动
+type SanctionsConfig struct {
+	// 名单文件路径（txt / csv / json 均可，提取其中所有 0x 地址），修改后自动重新加载
+	Lists []string `json:"lists"`
+}
+
+// PortfolioConfig 多钱包组合监控，wallets 为空时不启动
+type PortfolioConfig struct {
+	Wallets     []string `json:"wallets"`
+	Tokens      []string `json:"tokens"`       // 需要统计的 ERC-20，ETH 总是统计
+	Every       uint64   `json:"every"`        // 每隔多少个区块统计一次
+	DrawdownPct float64  `json:"drawdown_pct"` // 组合总值相对最高点回撤超过该百分比时提醒，0 表示关闭
+}
+
+// PnLConfig 交易地址的盈亏跟踪，addresses 为空时不启动
+type PnLConfig struct {
+	Addresses []string `json:"addresses"`
+	DB        string   `json:"db"` // SQLite 数据库文件，默认和事件索引共用
+}
+
+// PriceConfig 美元估值使用的价格源
+type PriceConfig struct {
+	ETHUSDFeed  string            `json:"eth_usd_feed"` // Chainlink ETH/USD 聚合器
+	WETH        string            `json:"weth"`         // 按 ETH 价格计价
+	Feeds       map[string]string `json:"feeds"`        // 代币地址 -> Chainlink USD 聚合器
+	Stablecoins []string          `json:"stablecoins"`  // 按 $1 计价
+	CoinGecko   bool              `json:"coingecko"`    // 没有链上喂价的代币查询 CoinGecko
+
+	// 单笔转账 / swap 的美元价值超过该值时提醒，0 表示关闭
+	AlertUSD float64 `json:"alert_usd"`
+}
+
+// ABIConfig 自动拉取已验证合约 ABI 的配置
+type ABIConfig struct {
+	CacheDir          string  `json:"cache_dir"`           // ABI 磁盘缓存目录
+	EtherscanKeyEnv   string  `json:"etherscan_key_env"`   // 从该环境变量读取 Etherscan API key，留空只查 Sourcify
+	FetchThreshold    int     `json:"fetch_threshold"`     // 未知合约出现多少次后拉取，<= 0 表示关闭
+	RequestsPerSecond float64 `json:"requests_per_second"` // 请求限速
+
+	// 事件签名文件（每行一个，例如 Transfer(address,address,uint256)），用于解码没有 ABI 的合约的日志
+	EventSignatures []string `json:"event_signatures"`
+	// 函数签名文件（每行一个，例如 transfer(address,uint256)），用于解码没有 ABI 的合约的 calldata
+	FunctionSignatures []string `json:"function_signatures"`
+
+	// 关注的代理合约：每个区块读取实现槽，实现地址变化（合约升级）时发出提醒
+	WatchProxies []string `json:"watch_proxies"`
+}
+
+// IndexerConfig 事件索引器配置，contracts 为空时不启动
+type IndexerConfig struct {
+	DB            string                  `json:"db"`            // SQLite 数据库文件
+	Confirmations uint64                  `json:"confirmations"` // 只索引到 最新区块 - confirmations，减少重组回退
+	Contracts     []IndexedContractConfig `json:"contracts"`
+}
+
+// IndexedContractConfig 需要索引的合约
+type IndexedContractConfig struct {
+	Name       string   `json:"name"` // 合约名，同时作为索引游标的 key，不要随意修改
+	Address    string   `json:"address"`
+	ABI        string   `json:"abi"`         // ABI 文件路径（纯 ABI 数组或 Hardhat / Foundry 编译产物）
+	Events     []string `json:"events"`      // 需要索引的事件名，留空索引 ABI 中的全部事件
+	StartBlock uint64   `json:"start_block"` // 首次运行时从哪个区块开始回扫（通常填合约部署区块）
+}
+
+// LogsConfig 需要订阅 / 回扫的合约日志
+type LogsConfig struct {
+	Addresses []string `json:"addresses"` // 合约地址，留空不订阅日志
+	Topics    []string `json:"topics"`    // 事件 topic0 过滤（任一匹配），留空表示全部事件
+}
+
+// FilterQuery 转换为 eth_getLogs / eth_subscribe("logs") 的过滤条件
+func (c LogsConfig) FilterQuery() ethereum.FilterQuery {
+	var q ethereum.FilterQuery
+	for _, a := range c.Addresses {
+		q.Addresses = append(q.Addresses, common.HexToAddress(a))
+	}
+	if len(c.Topics) > 0 {
+		topic0 := make([]common.Hash, 0, len(c.Topics))
+		for _, t := range c.Topics {
+			topic0 = append(topic0, common.HexToHash(t))
+		}
+		q.Topics = [][]common.Hash{topic0}
+	}
+	return q
+}
+
+// SimulationConfig bundle 模拟配置
+type SimulationConfig struct {
+	// 模拟方式: "callBundle"（Flashbots eth_callBundle）| "callMany"（Erigon trace_callMany）
+	Method string `json:"method"`
+
+	// callBundle: 中继 / builder 的 HTTP 地址（例如 https://relay.flashbots.net）
+	// callMany: 支持 trace_callMany 的节点地址，留空复用 ws_url 的连接
+	URL string `json:"url"`
+
+	// 从该环境变量读取 Flashbots 身份签名私钥，留空则每次随机生成（不影响模拟结果，只影响中继信誉）
+	AuthKeyEnv string `json:"auth_key_env"`
+}
+
+// WhaleConfig 巨鲸报告配置
+type WhaleConfig struct {
+	Window      int `json:"window"`       // 统计窗口（区块数）
+	ReportEvery int `json:"report_every"` // 每隔多少个区块输出一次报告
+	Top         int `json:"top"`          // 每个榜单的条目数
+
+	// 窗口内转出超过该数量 ETH 的新发送者自动加入影子关注列表，0 表示关闭
+	ShadowThresholdETH float64 `json:"shadow_threshold_eth"`
+
+	// 单笔交易超过阈值时立即提醒（whale_tx），与报告互相独立
+	Alert WhaleAlertConfig `json:"alert"`
+}
+
+// SignerConfig 发送模块使用的签名方式
+type SignerConfig struct {
+	// 签名类型: "ledger" | "trezor" | "key"
+	// 推荐使用硬件钱包，私钥永远不会出现在运行监控程序的机器上
+	Type string `json:"type"`
+
+	// 硬件钱包的派生路径，留空使用 m/44'/60'/0'/0/0
+	DerivationPath string `json:"derivation_path"`
+
+	// Type 为 "key" 时，从该环境变量读取十六进制私钥（仅建议在测试网使用）
+	KeyEnv string `json:"key_env"`
+}
+
+// LoadConfig 读取 JSON 配置文件并补全默认值
+// 功能：path 为默认路径且文件不存在时，不报错，直接返回默认配置
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{
+		WSURL:           DefaultWSURL,
+		ProxyPort:       DefaultProxyPort,
+		BlockStats:      true,
+		WarmupBlocks:    DefaultWarmupBlocks,
+		BlockLevel:      EnrichTraces.String(),
+		ReadyMaxHeadAge: DefaultReadyMaxHeadAge,
+		Whale: WhaleConfig{
+			Window:      DefaultWhaleWindow,
+			ReportEvery: DefaultWhaleReportEvery,
+			Top:         DefaultWhaleTop,
+		},
+		Reports:    ReportsConfig{Top: DefaultReportTop, DigestFormat: DigestMarkdown},
+		Simulation: SimulationConfig{Method: "callMany"},
+		Indexer:    IndexerConfig{DB: DefaultIndexDB, Confirmations: DefaultIndexConfirmations},
+		History:    HistoryConfig{DB: DefaultIndexDB, Retention: DefaultHistoryRetention},
+		Search:     SearchConfig{DB: DefaultIndexDB, Retention: DefaultSearchRetention},
+		TokenCache: DefaultTokenCache,
+		PnL:        PnLConfig{DB: DefaultIndexDB},
+		Portfolio:  PortfolioConfig{Every: DefaultPortfolioEvery},
+		Honeypot:   HoneypotConfig{Router: DefaultUniswapV2Router},
+		RugPull:    RugPullConfig{WindowBlocks: DefaultRugWindowBlocks, ThresholdPct: DefaultRugThresholdPct},
+		NodeHealth: NodeHealthConfig{
+			Interval:     DefaultNodeHealthInterval,
+			MinPeers:     DefaultNodeMinPeers,
+			PeerChurnPct: DefaultNodePeerChurnPct,
+		},
+		Txpool:     TxpoolConfig{Interval: DefaultTxpoolInterval, DB: DefaultIndexDB, Retention: DefaultTxpoolRetention},
+		Checkpoint: CheckpointConfig{DB: DefaultIndexDB, MaxBackfill: DefaultCheckpointMaxBackfill},
+		Bots:       BotConfig{DB: DefaultIndexDB, MinTxs: DefaultBotMinTxs},
+		Arb:        ArbConfig{MinProfitBps: DefaultArbMinProfitBps},
+		Bytecode:   BytecodeConfig{IntervalBlocks: DefaultBytecodeIntervalBlocks},
+		Timelock:   TimelockConfig{AlertHours: DefaultTimelockAlertHours, BackfillBlocks: DefaultTimelockBackfillBlocks},
+		CrossChain: CrossChainConfig{DepositMinutes: DefaultCrossChainDepositMinutes, WithdrawalHours: DefaultCrossChainWithdrawalHours},
+		Lite: LiteConfig{
+			StallSeconds:      DefaultLiteStallSeconds,
+			FinalityLagBlocks: DefaultLiteFinalityLagBlocks,
+			FinalityInterval:  DefaultLiteFinalityInterval,
+			MemoryLimitMB:     DefaultLiteMemoryLimitMB,
+		},
+		Retry: RetryConfig{
+			MaxAttempts: DefaultRetryAttempts,
+			BaseDelayMs: DefaultRetryBaseMs,
+			MaxDelayMs:  DefaultRetryMaxMs,
+		},
+		RPCUsageReportMinutes: DefaultRPCUsageReportMinutes,
+		Budget: BudgetConfig{
+			DegradeAt:  DefaultBudgetDegradeAt,
+			CriticalAt: DefaultBudgetCriticalAt,
+		},
+		Pause: PauseConfig{Policy: PauseBuffer, MaxBuffer: DefaultPauseMaxBuffer},
+		Subscriptions: SubscriptionsConfig{
+			Heads:             true,
+			Pending:           true,
+			Logs:              true,
+			FinalizedInterval: DefaultFinalizedInterval,
+			SignalToggle:      []string{StreamPending},
+		},
+		Throttle: ThrottleConfig{DB: DefaultIndexDB},
+		RPCPool: RPCPoolConfig{
+			Strategy:      BalanceFailover,
+			PrimaryWeight: DefaultEndpointWeight,
+			ErrorRate:     DefaultBreakerErrorRate,
+			LatencyMs:     DefaultBreakerLatencyMs,
+			MinRequests:   DefaultBreakerMinRequests,
+			WindowSeconds: DefaultBreakerWindowSeconds,
+			ProbeSeconds:  DefaultBreakerProbeSeconds,
+		},
+		Prices: PriceConfig{
+			ETHUSDFeed:  DefaultETHUSDFeed,
+			WETH:        DefaultWETH,
+			Stablecoins: DefaultStablecoins,
+		},
+		ABI: ABIConfig{
+			CacheDir:          DefaultABICacheDir,
+			FetchThreshold:    DefaultABIFetchThreshold,
+			RequestsPerSecond: DefaultABIRequestsPerSecond,
+		},
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && path == DefaultConfigPath {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("解析配置文件失败: %w", err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// validate 检查配置中的地址格式等基本错误，尽早在启动时暴露问题
+func (c *Config) validate() error {
+	for _, addr := range c.Watch {
+		if !common.IsHexAddress(addr) {
+			return fmt.Errorf("watch 中的地址格式错误: %q", addr)
+		}
+	}
+	for _, addr := range c.Logs.Addresses {
+		if !common.IsHexAddress(addr) {
+			return fmt.Errorf("logs.addresses 中的地址格式错误: %q", addr)
+		}
+	}
+	names := make(map[string]bool)
+	for _, ic := range c.Indexer.Contracts {
+		if ic.Name == "" || names[ic.Name] {
+			return fmt.Errorf("indexer.contracts 中的合约名不能为空且不能重复: %q", ic.Name)
+		}
+		names[ic.Name] = true
+		if !common.IsHexAddress(ic.Address) {
+			return fmt.Errorf("indexer.contracts[%s] 的地址格式错误: %q", ic.Name, ic.Address)
+		}
+	}
+	if c.Whale.Window <= 0 || c.Whale.ReportEvery <= 0 || c.Whale.Top <= 0 {
+		return fmt.Errorf("whale.window / report_every / top 必须大于 0")
+	}
+	if c.Whale.Alert.MinETH < 0 || c.Whale.Alert.MinUSD < 0 {
+		return fmt.Errorf("whale.alert.min_eth / min_usd 不能为负数")
+	}
+	if c.Whale.Alert.CriticalMultiple != 0 && c.Whale.Alert.CriticalMultiple < 1 {
+		return fmt.Errorf("whale.alert.critical_multiple 不能小于 1")
+	}
+	for _, minutes := range c.Reports.Windows {
+		if minutes <= 0 {
+			return fmt.Errorf("reports.windows 中的窗口（分钟）必须大于 0: %d", minutes)
+		}
+	}
+	if c.Reports.Top <= 0 {
+		return fmt.Errorf("reports.top 必须大于 0")
+	}
+	if c.Reports.DigestFormat != DigestMarkdown && c.Reports.DigestFormat != DigestHTML {
+		return fmt.Errorf("reports.digest_format 只能是 markdown 或 html: %q", c.Reports.DigestFormat)
+	}
+	for _, minutes := range c.Reports.DigestWindows {
+		found := false
+		for _, w := range c.Reports.Windows {
+			found = found || w == minutes
+		}
+		if !found {
+			return fmt.Errorf("reports.digest_windows 中的 %d 不在 reports.windows 中", minutes)
+		}
+	}
+	for _, addr := range c.ABI.WatchProxies {
+		if !common.IsHexAddress(addr) {
+			return fmt.Errorf("abi.watch_proxies 中的地址格式错误: %q", addr)
+		}
+	}
+	for _, addr := range c.Bytecode.Contracts {
+		if !common.IsHexAddress(addr) {
+			return fmt.Errorf("bytecode.contracts 中的地址格式错误: %q", addr)
+		}
+	}
+	if c.Bytecode.IntervalBlocks == 0 {
+		return fmt.Errorf("bytecode.interval_blocks 必须大于 0")
+	}
+	for _, addr := range append(append([]string(nil), c.Governance.Governors...), c.Governance.Targets...) {
+		if !common.IsHexAddress(addr) {
+			return fmt.Errorf("governance 中的地址格式错误: %q", addr)
+		}
+	}
+	for _, addr := range c.Safe.Safes {
+		if !common.IsHexAddress(addr) {
+			return fmt.Errorf("safe.safes 中的地址格式错误: %q", addr)
+		}
+	}
+	for _, addr := range append(append([]string(nil), c.Timelock.Timelocks...), c.Timelock.Targets...) {
+		if !common.IsHexAddress(addr) {
+			return fmt.Errorf("timelock 中的地址格式错误: %q", addr)
+		}
+	}
+	for _, h := range c.Timelock.AlertHours {
+		if h <= 0 {
+			return fmt.Errorf("timelock.alert_hours 必须大于 0: %v", h)
+		}
+	}
+	for _, l2 := range c.CrossChain.L2s {
+		if l2.RPC == "" {
+			return fmt.Errorf("crosschain.l2s 中的 %q 缺少 rpc", l2.Name)
+		}
+		if l2.Type != "" && l2.Type != L2TypeOptimism && l2.Type != L2TypeArbitrum {
+			return fmt.Errorf("crosschain.l2s 中的 type 只能是 optimism 或 arbitrum: %q", l2.Type)
+		}
+		for _, addr := range []string{l2.Portal, l2.Bridge, l2.Outbox} {
+			if addr != "" && !common.IsHexAddress(addr) {
+				return fmt.Errorf("crosschain.l2s 中的地址格式错误: %q", addr)
+			}
+		}
+	}
+	if c.CrossChain.DepositMinutes <= 0 || c.CrossChain.WithdrawalHours <= 0 {
+		return fmt.Errorf("crosschain.deposit_minutes 和 withdrawal_hours 必须大于 0")
+	}
+	if c.WarmupBlocks < 0 {
+		return fmt.Errorf("warmup_blocks 不能小于 0")
+	}
+	if _, _, err := c.EnrichmentLevels(); err != nil {
+		return err
+	}
+	if c.Retry.MaxAttempts < 1 || c.Retry.BaseDelayMs < 0 || c.Retry.MaxDelayMs < c.Retry.BaseDelayMs {
+		return fmt.Errorf("retry.max_attempts 至少为 1，max_delay_ms 不能小于 base_delay_ms")
+	}
+	if c.RPCPool.ErrorRate <= 0 || c.RPCPool.ErrorRate > 1 || c.RPCPool.LatencyMs <= 0 || c.RPCPool.MinRequests <= 0 ||
+		c.RPCPool.WindowSeconds <= 0 || c.RPCPool.ProbeSeconds <= 0 {
+		return fmt.Errorf("rpc_pool.error_rate 必须在 (0, 1] 之间，latency_ms / min_requests / window_seconds / probe_seconds 必须大于 0")
+	}
+	if c.RPCUsageReportMinutes < 0 {
+		return fmt.Errorf("rpc_usage_report_minutes 不能小于 0")
+	}
+	if c.Budget.DailyRequests < 0 || c.Budget.DailyComputeUnits < 0 {
+		return fmt.Errorf("budget.daily_requests / daily_compute_units 不能小于 0")
+	}
+	if c.Budget.DegradeAt <= 0 || c.Budget.CriticalAt < c.Budget.DegradeAt || c.Budget.CriticalAt > 1 {
+		return fmt.Errorf("budget 需要满足 0 < degrade_at <= critical_at <= 1")
+	}
+	if c.RPCPool.Strategy != BalanceFailover && c.RPCPool.Strategy != BalanceWeighted {
+		return fmt.Errorf("rpc_pool.strategy 只能是 failover 或 weighted: %q", c.RPCPool.Strategy)
+	}
+	if c.RPCPool.PrimaryWeight < 0 {
+		return fmt.Errorf("rpc_pool.primary_weight 不能小于 0")
+	}
+	if c.Pause.Policy != PauseBuffer && c.Pause.Policy != PauseDrop {
+		return fmt.Errorf("pause.policy 只能是 buffer 或 drop: %q", c.Pause.Policy)
+	}
+	if c.Pause.MaxBuffer <= 0 {
+		return fmt.Errorf("pause.max_buffer 必须大于 0")
+	}
+	if c.Subscriptions.FinalizedInterval <= 0 {
+		return fmt.Errorf("subscriptions.finalized_interval 必须大于 0")
+	}
+	for _, name := range c.Subscriptions.SignalToggle {
+		known := false
+		for _, s := range streamNames {
+			known = known || s == name
+		}
+		if !known {
+			return fmt.Errorf("subscriptions.signal_toggle 中的订阅只能是 heads / pending / logs / finalized: %q", name)
+		}
+	}
+	throttled := make(map[string]bool, len(c.Throttle.Rules))
+	for _, r := range c.Throttle.Rules {
+		if r.Type == "" || throttled[r.Type] {
+			return fmt.Errorf("throttle.rules 中的 type 不能为空或重复: %q", r.Type)
+		}
+		if r.Key != "" && r.Key != ThrottleByType && r.Key != ThrottleByID {
+			return fmt.Errorf("throttle.rules 中 %s 的 key 只能是 type 或 id: %q", r.Type, r.Key)
+		}
+		if r.IntervalSeconds <= 0 {
+			return fmt.Errorf("throttle.rules 中 %s 的 interval_seconds 必须大于 0", r.Type)
+		}
+		throttled[r.Type] = true
+	}
+	poolNames := map[string]bool{"primary": true, PinnedPrimaryName: true}
+	for _, ep := range c.RPCPool.Endpoints {
+		if ep.Name == "" || ep.WSURL == "" {
+			return fmt.Errorf("rpc_pool.endpoints 中的 name 和 ws_url 不能为空")
+		}
+		if poolNames[ep.Name] {
+			return fmt.Errorf("rpc_pool.endpoints 中的 name 重复: %q（primary / ws_url 为保留名称）", ep.Name)
+		}
+		if ep.Weight < 0 {
+			return fmt.Errorf("rpc_pool.endpoints 中 %s 的 weight 不能小于 0", ep.Name)
+		}
+		poolNames[ep.Name] = true
+	}
+	if c.Lite.StallSeconds <= 0 || c.Lite.FinalityLagBlocks == 0 || c.Lite.FinalityInterval <= 0 || c.Lite.MemoryLimitMB < 0 {
+		return fmt.Errorf("lite.stall_seconds / finality_lag_blocks / finality_interval 必须大于 0")
+	}
+	for _, addr := range c.RugPull.Tokens {
+		if !common.IsHexAddress(addr) {
+			return fmt.Errorf("rug_pull.tokens 中的地址格式错误: %q", addr)
+		}
+	}
+	if c.RugPull.WindowBlocks == 0 || c.RugPull.ThresholdPct <= 0 || c.RugPull.ThresholdPct > 100 {
+		return fmt.Errorf("rug_pull.window_blocks 必须大于 0，threshold_pct 必须在 0 - 100 之间")
+	}
+	if !common.IsHexAddress(c.Honeypot.Router) {
+		return fmt.Errorf("honeypot.router 地址格式错误: %q", c.Honeypot.Router)
+	}
+	addrs := append([]string{c.Prices.ETHUSDFeed, c.Prices.WETH}, c.Prices.Stablecoins...)
+	for token, feed := range c.Prices.Feeds {
+		addrs = append(addrs, token, feed)
+	}
+	for _, addr := range addrs {
+		if !common.IsHexAddress(addr) {
+			return fmt.Errorf("prices 中的地址格式错误: %q", addr)
+		}
+	}
+	for _, addr := range c.PnL.Addresses {
+		if !common.IsHexAddress(addr) {
+			return fmt.Errorf("pnl.addresses 中的地址格式错误: %q", addr)
+		}
+	}
+	for _, addr := range append(append([]string{}, c.Portfolio.Wallets...), c.Portfolio.Tokens...) {
+		if !common.IsHexAddress(addr) {
+			return fmt.Errorf("portfolio 中的地址格式错误: %q", addr)
+		}
+	}
+	if c.Portfolio.Every == 0 || c.Portfolio.DrawdownPct < 0 || c.Portfolio.DrawdownPct >= 100 {
+		return fmt.Errorf("portfolio.every 必须大于 0，drawdown_pct 必须在 0 - 100 之间")
+	}
+	if c.ABI.RequestsPerSecond <= 0 {
+		return fmt.Errorf("abi.requests_per_second 必须大于 0")
+	}
+	switch c.Simulation.Method {
+	case "callBundle", "callMany":
+	default:
+		return fmt.Errorf("未知的 simulation.method: %q（可选 callBundle / callMany）", c.Simulation.Method)
+	}
+	switch c.Signer.Type {
+	case "", "ledger", "trezor", "key":
+	default:
+		return fmt.Errorf("未知的 signer.type: %q（可选 ledger / trezor / key）", c.Signer.Type)
+	}
+	if len(c.Propagation.Endpoints) > 63 {
+		return fmt.Errorf("propagation.endpoints 最多 63 个")
+	}
+	endpoints := map[string]bool{"primary": true}
+	for _, ep := range c.Propagation.Endpoints {
+		if ep.Name == "" || ep.WSURL == "" {
+			return fmt.Errorf("propagation.endpoints 中的 name 和 ws_url 不能为空")
+		}
+		if endpoints[ep.Name] {
+			return fmt.Errorf("propagation.endpoints 中的 name 重复: %q（primary 为主节点保留）", ep.Name)
+		}
+		endpoints[ep.Name] = true
+	}
+	if c.NodeHealth.Interval <= 0 {
+		return fmt.Errorf("node_health.interval 必须大于 0")
+	}
+	if c.NodeHealth.PeerChurnPct < 0 || c.NodeHealth.PeerChurnPct > 100 {
+		return fmt.Errorf("node_health.peer_churn_pct 必须在 0 到 100 之间")
+	}
+	if c.Txpool.Interval <= 0 || c.Txpool.Retention <= 0 {
+		return fmt.Errorf("txpool.interval 和 txpool.retention_days 必须大于 0")
+	}
+	if c.History.Retention <= 0 {
+		return fmt.Errorf("history.retention_days 必须大于 0")
+	}
+	if c.Search.Retention <= 0 {
+		return fmt.Errorf("search.retention_days 必须大于 0")
+	}
+	for _, a := range c.Search.Contracts {
+		if !common.IsHexAddress(a) {
+			return fmt.Errorf("search.contracts 中的地址格式错误: %q", a)
+		}
+	}
+	if c.Checkpoint.MaxBackfill <= 0 {
+		return fmt.Errorf("checkpoint.max_backfill 必须大于 0")
+	}
+	sources := map[string]bool{"primary": true}
+	for _, s := range c.Mempool.Sources {
+		if s.Name == "" || s.WSURL == "" {
+			return fmt.Errorf("mempool.sources 中的 name 和 ws_url 不能为空")
+		}
+		if sources[s.Name] {
+			return fmt.Errorf("mempool.sources 中的 name 重复: %q（primary 为主节点保留）", s.Name)
+		}
+		sources[s.Name] = true
+	}
+	if err := c.Mempool.Fees.validate(); err != nil {
+		return fmt.Errorf("mempool.fees: %w", err)
+	}
+	if err := c.Mempool.To.validate(); err != nil {
+		return fmt.Errorf("mempool.to: %w", err)
+	}
+	if err := c.Mempool.Selectors.validate(); err != nil {
+		return fmt.Errorf("mempool.selectors: %w", err)
+	}
+	if _, err := NewCallMatcher(c.Mempool.Match); err != nil {
+		return fmt.Errorf("mempool.match: %w", err)
+	}
+	if c.Bots.MinTxs <= 0 {
+		return fmt.Errorf("bots.min_txs 必须大于 0")
+	}
+	for _, addr := range c.MEV.FrontrunContracts {
+		if !common.IsHexAddress(addr) {
+			return fmt.Errorf("mev.frontrun_contracts 中的地址格式错误: %q", addr)
+		}
+	}
+	for _, addr := range []string{c.CopyTrade.Leader, c.CopyTrade.Follower} {
+		if addr != "" && !common.IsHexAddress(addr) {
+			return fmt.Errorf("copy_trade 中的地址格式错误: %q", addr)
+		}
+	}
+	if c.CopyTrade.Armed && c.Signer.Type == "" {
+		return fmt.Errorf("copy_trade.armed 需要配置 signer")
+	}
+	for _, addr := range c.Pools.Addresses {
+		if !common.IsHexAddress(addr) {
+			return fmt.Errorf("pools.addresses 中的地址格式错误: %q", addr)
+		}
+	}
+	for _, p := range c.Curve.Pools {
+		if !common.IsHexAddress(p.Address) {
+			return fmt.Errorf("curve.pools 中的地址格式错误: %q", p.Address)
+		}
+		if p.Kind != CurveStable && p.Kind != CurveCrypto {
+			return fmt.Errorf("curve 池子 %s 的 kind 必须是 stable 或 crypto: %q", p.Address, p.Kind)
+		}
+	}
+	for _, id := range c.Balancer.Pools {
+		if b, err := hexutil.Decode(id); err != nil || len(b) != 32 {
+			return fmt.Errorf("balancer.pools 中的 poolId 格式错误（应为 32 字节十六进制）: %q", id)
+		}
+	}
+	if err := c.APIAuth.validate(); err != nil {
+		return err
+	}
+	if err := c.TLS.validate(); err != nil {
+		return err
+	}
+	if c.ReadyMaxHeadAge <= 0 {
+		return fmt.Errorf("ready_max_head_age 必须大于 0")
+	}
+	if err := validateFilters(c.Filters); err != nil {
+		return err
+	}
+	for _, ref := range c.filterRefs() {
+		if _, ok := c.Filters[ref.name]; !ok {
+			return fmt.Errorf("%s 引用的过滤链不在 filters 中: %q", ref.field, ref.name)
+		}
+	}
+	for _, o := range c.Outputs {
+		if err := o.validate(); err != nil {
+			return err
+		}
+	}
+	if err := c.Routing.validate(c.Outputs); err != nil {
+		return err
+	}
+	profiles := make(map[string]bool, len(c.Profiles))
+	for _, p := range c.Profiles {
+		if err := p.validate(c.Outputs); err != nil {
+			return err
+		}
+		if profiles[p.Name] {
+			return fmt.Errorf("profiles 中的 name 重复: %q", p.Name)
+		}
+		profiles[p.Name] = true
+	}
+	return nil
+}
+
+// filterRef 配置中对过滤链的一处引用
+type filterRef struct {
+	field, name string
+}
+
+// filterRefs 配置中全部对过滤链的引用
+func (c *Config) filterRefs() []filterRef {
+	var refs []filterRef
+	add := func(field, name string) {
+		if name != "" {
+			refs = append(refs, filterRef{field, name})
+		}
+	}
+	add("mempool.filter", c.Mempool.Filter)
+	add("whale.alert.filter", c.Whale.Alert.Filter)
+	for _, p := range c.Profiles {
+		add("profiles."+p.Name+".filter", p.Filter)
+	}
+	for i, o := range c.Outputs {
+		add(fmt.Sprintf("outputs[%d].filter", i), o.Filter)
+	}
+	for i, r := range c.Routing.Routes {
+		add(fmt.Sprintf("routing.routes[%d].filter", i), r.Filter)
+	}
+	return refs
+}
+
+// EnrichmentLevels 解析 block_level 和 block_level_overrides
+func (c *Config) EnrichmentLevels() (EnrichLevel, map[string]EnrichLevel, error) {
+	limit, err := ParseEnrichLevel(c.BlockLevel)
+	if err != nil {
+		return 0, nil, fmt.Errorf("block_level: %w", err)
+	}
+	overrides := make(map[string]EnrichLevel, len(c.BlockLevelOverrides))
+	for name, s := range c.BlockLevelOverrides {
+		level, err := ParseEnrichLevel(s)
+		if err != nil {
+			return 0, nil, fmt.Errorf("block_level_overrides.%s: %w", name, err)
+		}
+		overrides[name] = level
+	}
+	return limit, overrides, nil
+}
+
+// WatchAddresses 将配置中的地址字符串转换为 common.Address
+func (c *Config) WatchAddresses() []common.Address {
+	addrs := make([]common.Address, 0, len(c.Watch))
+	for _, a := range c.Watch {
+		addrs = append(addrs, common.HexToAddress(a))
+	}
+	return addrs
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ------------------------------------------------
+// 交互式控制台：连接节点后逐行读取命令，临时查询交易、区块、余额或解码 calldata，
+// 复用监控的客户端、代币缓存、ABI 注册表和 calldata 解码器，输出格式与实时监控一致
+// 关注列表只在本次会话中生效，区块中涉及关注地址的交易会单独列出
+// ------------------------------------------------
+
+const (
+	// ⚠️ 单条命令的超时，避免节点无响应时控制台卡住
+	ConsoleCommandTimeout = 30 * time.Second
+
+	ConsolePrompt = "> "
+)
+
+// errConsoleExit exit / quit 命令
+var errConsoleExit = errors.New("exit")
+
+// consoleCommand 控制台中的一条命令
+type consoleCommand struct {
+	name  string
+	usage string
+	desc  string
+	run   func(ctx context.Context, args []string) error
+}
+
+// Console 交互式控制台
+type Console struct {
+	clients  *Clients
+	abis     *ABIRegistry
+	tokens   *TokenCache
+	watch    *Watchlist
+	signer   types.Signer
+	commands []consoleCommand
+}
+
+// NewConsole 创建控制台，关注列表初始为配置中的 watch
+func NewConsole(clients *Clients, abis *ABIRegistry, tokens *TokenCache, watch *Watchlist, chainID *big.Int) *Console {
+	c := &Console{clients: clients, abis: abis, tokens: tokens, watch: watch, signer: types.LatestSignerForChainID(chainID)}
+	c.commands = []consoleCommand{
+		{"tx", "tx <hash>", "查询交易、回执、解码后的调用和日志", c.cmdTx},
+		{"block", "block <number | latest>", "查询区块，列出涉及关注地址的交易", c.cmdBlock},
+		{"balance", "balance <address> [token]", "查询 ETH 余额，指定代币时查询 ERC-20 余额", c.cmdBalance},
+		{"decode", "decode <calldata> [to]", "解码 calldata（包括 multicall 内层调用）", c.cmdDecode},
+		{"watch", "watch add <address> | watch list", "在本次会话中添加 / 列出关注地址", c.cmdWatch},
+		{"help", "help", "显示命令列表", c.cmdHelp},
+		{"exit", "exit | quit", "退出控制台", func(context.Context, []string) error { return errConsoleExit }},
+	}
+	return c
+}
+
+// Run 逐行读取并执行命令，直到输入结束、exit 或 ctx 取消
+func (c *Console) Run(ctx context.Context) error {
+	// 读取 stdin 会阻塞，放在单独的 goroutine 中，Ctrl+C 时不用等下一行输入
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	for {
+		fmt.Print(ConsolePrompt)
+		var line string
+		select {
+		case l, ok := <-lines:
+			if !ok {
+				fmt.Println()
+				return nil
+			}
+			line = l
+		case <-ctx.Done():
+			fmt.Println()
+			return nil
+		}
+		if err := c.Exec(ctx, line); err != nil {
+			if errors.Is(err, errConsoleExit) {
+				return nil
+			}
+			fmt.Printf("❌ %v\n", err)
+		}
+	}
+}
+
+// Exec 执行一行命令，空行什么也不做
+func (c *Console) Exec(ctx context.Context, line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+	name := strings.ToLower(fields[0])
+	if name == "quit" {
+		name = "exit"
+	}
+	for _, cmd := range c.commands {
+		if cmd.name != name {
+			continue
+		}
+		cmdCtx, cancel := context.WithTimeout(ctx, ConsoleCommandTimeout)
+		defer cancel()
+		return cmd.run(cmdCtx, fields[1:])
+	}
+	return fmt.Errorf("未知命令: %s（输入 help 查看命令列表）", fields[0])
+}
+
+// cmdHelp 显示命令列表
+func (c *Console) cmdHelp(context.Context, []string) error {
+	for _, cmd := range c.commands {
+		fmt.Printf("  %-36s %s\n", cmd.usage, cmd.desc)
+	}
+	return nil
+}
+
+// cmdTx 查询交易：已打包时附带回执状态和解码后的日志
+func (c *Console) cmdTx(ctx context.Context, args []string) error {
+	if len(args) != 1 || len(strings.TrimPrefix(args[0], "0x")) != 2*common.HashLength {
+		return fmt.Errorf("用法: tx <hash>")
+	}
+	hash := common.HexToHash(args[0])
+	var tx *types.Transaction
+	var pending bool
+	err := Retry(ctx, "eth_getTransactionByHash", func() (err error) {
+		tx, pending, err = c.clients.Eth.TransactionByHash(ctx, hash)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("查询交易失败: %w", err)
+	}
+
+	fmt.Printf("🧾 [Tx] %s\n", tx.Hash().Hex())
+	if from, err := types.Sender(c.signer, tx); err == nil {
+		fmt.Printf("   From:  %s%s\n", from.Hex(), c.watchMark(from))
+	}
+	if tx.To() != nil {
+		fmt.Printf("   To:    %s%s\n", tx.To().Hex(), c.watchMark(*tx.To()))
+	} else {
+		fmt.Println("   To:    (合约创建)")
+	}
+	fmt.Printf("   Value: %.6f ETH | Nonce: %d | Gas: %d | Type: %d\n", weiToEther(tx.Value()), tx.Nonce(), tx.Gas(), tx.Type())
+	if tx.Type() >= types.DynamicFeeTxType {
+		fmt.Printf("   Fee:   maxFee %.2f gwei | tip %.2f gwei\n", weiToGwei(tx.GasFeeCap()), weiToGwei(tx.GasTipCap()))
+	} else {
+		fmt.Printf("   Fee:   gasPrice %.2f gwei\n", weiToGwei(tx.GasPrice()))
+	}
+	if tx.To() != nil && len(tx.Data()) >= 4 {
+		call, _ := c.abis.DecodeCall(tx.To(), tx.Data())
+		fmt.Print(indentLines(call.Pretty(), "   "))
+	}
+	if pending {
+		fmt.Println("   ⏳ 尚未打包")
+		return nil
+	}
+
+	var receipt *types.Receipt
+	err = Retry(ctx, "eth_getTransactionReceipt", func() (err error) {
+		receipt, err = c.clients.Eth.TransactionReceipt(ctx, hash)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("查询回执失败: %w", err)
+	}
+	status := "✅ 成功"
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		status = "❌ 失败"
+	}
+	fmt.Printf("   Block: %d | %s | gasUsed %d | effectiveGasPrice %.2f gwei\n",
+		receipt.BlockNumber, status, receipt.GasUsed, weiToGwei(receipt.EffectiveGasPrice))
+	for _, l := range receipt.Logs {
+		if decoded, ok := c.abis.DecodeLog(*l); ok {
+			fmt.Printf("   📜 #%d %s %s\n", l.Index, c.tokenLabel(l.Address), decoded)
+		} else {
+			fmt.Printf("   📜 #%d %s topic0=%s\n", l.Index, l.Address.Hex(), topic0(l))
+		}
+	}
+	return nil
+}
+
+// cmdBlock 查询区块头信息和交易数，列出涉及关注地址的交易
+func (c *Console) cmdBlock(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("用法: block <number | latest>")
+	}
+	var number *big.Int
+	if args[0] != "latest" {
+		n, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("区块号格式错误: %q", args[0])
+		}
+		number = new(big.Int).SetUint64(n)
+	}
+	var block *types.Block
+	err := Retry(ctx, "eth_getBlockByNumber", func() (err error) {
+		block, err = c.clients.Eth.BlockByNumber(ctx, number)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("查询区块失败: %w", err)
+	}
+
+	fmt.Printf("📦 [Block] Height: %d | Hash: %s\n", block.Number(), block.Hash().Hex())
+	fmt.Printf("   Time: %s | Txs: %d | Gas: %d / %d (%.1f%%)\n",
+		time.Unix(int64(block.Time()), 0).Format(time.DateTime), len(block.Transactions()),
+		block.GasUsed(), block.GasLimit(), 100*float64(block.GasUsed())/float64(block.GasLimit()))
+	if block.BaseFee() != nil {
+		fmt.Printf("   BaseFee: %.2f gwei | Miner: %s\n", weiToGwei(block.BaseFee()), block.Coinbase().Hex())
+	}
+	for _, tx := range block.Transactions() {
+		from, _ := types.Sender(c.signer, tx)
+		if !c.watch.Contains(from) && (tx.To() == nil || !c.watch.Contains(*tx.To())) {
+			continue
+		}
+		to := "(合约创建)"
+		if tx.To() != nil {
+			to = tx.To().Hex()
+		}
+		fmt.Printf("   👀 %s | %s -> %s | %.6f ETH\n", tx.Hash().Hex(), from.Hex(), to, weiToEther(tx.Value()))
+	}
+	return nil
+}
+
+// cmdBalance 查询 ETH 余额，指定代币时查询 ERC-20 余额并按 decimals 格式化
+func (c *Console) cmdBalance(ctx context.Context, args []string) error {
+	if len(args) < 1 || len(args) > 2 || !common.IsHexAddress(args[0]) {
+		return fmt.Errorf("用法: balance <address> [token]")
+	}
+	owner := common.HexToAddress(args[0])
+	if len(args) == 1 {
+		var bal *big.Int
+		err := Retry(ctx, "eth_getBalance", func() (err error) {
+			bal, err = c.clients.Eth.BalanceAt(ctx, owner, nil)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("查询余额失败: %w", err)
+		}
+		fmt.Printf("💰 %s%s: %s ETH\n", owner.Hex(), c.watchMark(owner), formatUnits(bal, 18))
+		return nil
+	}
+
+	if !common.IsHexAddress(args[1]) {
+		return fmt.Errorf("代币地址格式错误: %q", args[1])
+	}
+	token := common.HexToAddress(args[1])
+	data := append(append([]byte{}, selectorBalanceOf...), common.LeftPadBytes(owner.Bytes(), 32)...)
+	var out []byte
+	err := Retry(ctx, "eth_call", func() (err error) {
+		out, err = c.clients.Eth.CallContract(ctx, ethereum.CallMsg{To: &token, Data: data}, nil)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("查询 %s 的 %s 余额失败: %w", owner.Hex(), token.Hex(), err)
+	}
+	if len(out) < 32 {
+		return fmt.Errorf("%s 的 balanceOf 返回值长度错误", token.Hex())
+	}
+	bal := new(big.Int).SetBytes(out[:32])
+	meta, err := c.tokens.Get(ctx, token)
+	if err != nil {
+		fmt.Printf("💰 %s%s: %s（%s，未知 decimals）\n", owner.Hex(), c.watchMark(owner), bal, token.Hex())
+		return nil
+	}
+	fmt.Printf("💰 %s%s: %s\n", owner.Hex(), c.watchMark(owner), meta.Format(bal))
+	return nil
+}
+
+// cmdDecode 解码 calldata，不指定合约地址时只按 selector 查找
+func (c *Console) cmdDecode(_ context.Context, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("用法: decode <calldata> [to]")
+	}
+	data, err := decodeHex(args[0])
+	if err != nil {
+		return fmt.Errorf("calldata 格式错误: %w", err)
+	}
+	if len(data) < 4 {
+		return fmt.Errorf("calldata 不足 4 字节，不是函数调用")
+	}
+	target := &common.Address{}
+	if len(args) == 2 {
+		if !common.IsHexAddress(args[1]) {
+			return fmt.Errorf("合约地址格式错误: %q", args[1])
+		}
+		addr := common.HexToAddress(args[1])
+		target = &addr
+		fmt.Printf("-> %s\n", target.Hex())
+	}
+	call, _ := c.abis.DecodeCall(target, data)
+	fmt.Print(call.Pretty())
+	return nil
+}
+
+// cmdWatch 添加或列出本次会话的关注地址
+func (c *Console) cmdWatch(_ context.Context, args []string) error {
+	switch {
+	case len(args) == 2 && args[0] == "add":
+		if !common.IsHexAddress(args[1]) {
+			return fmt.Errorf("地址格式错误: %q", args[1])
+		}
+		addr := common.HexToAddress(args[1])
+		if !c.watch.AddShadow(addr) {
+			fmt.Printf("👀 %s 已在关注列表中\n", addr.Hex())
+			return nil
+		}
+		fmt.Printf("👀 已关注 %s（仅本次会话，共 %d 个）\n", addr.Hex(), c.watch.Len())
+	case len(args) == 1 && args[0] == "list":
+		for _, source := range []string{WatchSourceConfig, WatchSourceShadow} {
+			label := map[string]string{WatchSourceConfig: "配置", WatchSourceShadow: "本次会话"}[source]
+			for _, addr := range c.watch.BySource(source) {
+				fmt.Printf("   %s (%s)\n", addr.Hex(), label)
+			}
+		}
+		fmt.Printf("👀 共 %d 个关注地址\n", c.watch.Len())
+	default:
+		return fmt.Errorf("用法: watch add <address> | watch list")
+	}
+	return nil
+}
+
+// watchMark 关注地址后面加上标记
+func (c *Console) watchMark(addr common.Address) string {
+	if c.watch.Contains(addr) {
+		return " 👀"
+	}
+	return ""
+}
+
+// tokenLabel 已缓存的代币显示符号，否则显示地址
+func (c *Console) tokenLabel(addr common.Address) string {
+	if meta, ok := c.tokens.Lookup(addr); ok {
+		return meta.Label()
+	}
+	return addr.Hex()
+}
+
+// topic0 日志的事件签名哈希，匿名事件返回空字符串
+func topic0(l *types.Log) string {
+	if len(l.Topics) == 0 {
+		return ""
+	}
+	return l.Topics[0].Hex()
+}
+
+// indentLines 每一行前面加上缩进
+func indentLines(s, indent string) string {
+	lines := strings.SplitAfter(s, "\n")
+	var sb strings.Builder
+	for _, line := range lines {
+		if line != "" {
+			sb.WriteString(indent + line)
+		}
+	}
+	return sb.String()
+}
+
+// runConsole 连接节点并启动交互式控制台
+func runConsole(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("console", flag.ExitOnError)
+	configPath := fs.String("config", DefaultConfigPath, "配置文件路径")
+	fs.Parse(args)
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	abis := NewABIRegistry()
+	if err := loadSignatures(abis, cfg.ABI); err != nil {
+		return fmt.Errorf("加载签名失败: %w", err)
+	}
+	clients, err := Dial(cfg)
+	if err != nil {
+		return err
+	}
+	defer clients.Close()
+	chainID, err := chainIDRetry(ctx, clients.Eth)
+	if err != nil {
+		return fmt.Errorf("查询 ChainID 失败: %w", err)
+	}
+
+	console := NewConsole(clients, abis, NewTokenCache(clients.Eth, cfg.TokenCache), NewWatchlist(cfg.WatchAddresses()), chainID)
+	fmt.Printf("🖥️  控制台已连接（ChainID %s），输入 help 查看命令列表，exit 退出\n", chainID)
+	return console.Run(ctx)
+}
@@ -0,0 +1,454 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	// dry-run 默认的跟单地址：没有私钥、没有余额的空账户，模拟时临时给它充值
+	copyTradeDryRunFollower = common.BytesToAddress(crypto.Keccak256([]byte("monitor.copytrade.follower"))[12:])
+	// eth_simulateV1 开启 traceTransfers 后，ETH 转账以这个地址的 Transfer 日志表示
+	simETHAddress = common.HexToAddress("0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE")
+	// dry-run 模拟时给跟单地址的 ETH 余额
+	copyTradeSimBalance = new(big.Int).Mul(big.NewInt(1_000_000), big.NewInt(1e18))
+)
+
+// CopyTradeConfig 跟单：把领投地址的每笔 swap 按原样（把领投地址换成跟单地址）在其上链之后的状态上模拟执行，
+// 跟踪假设跟单的盈亏和相对领投的滑点
+type CopyTradeConfig struct {
+	Leader   string `json:"leader"`   // 跟单的地址，留空不启动
+	Follower string `json:"follower"` // dry-run 时模拟的跟单地址，默认为一个空账户
+	// 真正发送跟单交易，还需要在启动时加 -arm 参数，并配置 signer（跟单地址为 signer 的地址）
+	// ⚠️ 会用真实资金照抄领投地址的交易，包括它被骗、被夹的交易
+	Armed bool `json:"armed"`
+}
+
+// CopyTrade 一笔跟单的结果
+type CopyTrade struct {
+	LeaderTx    common.Hash                 `json:"leader_tx"`
+	Block       uint64                      `json:"block"`
+	Leader      map[common.Address]*big.Int `json:"leader"`       // 领投的代币净变化（ethToken 为 ETH）
+	Follower    map[common.Address]*big.Int `json:"follower"`     // 跟单的代币净变化
+	Simulated   bool                        `json:"simulated"`    // false 时为按领投成交价估算的卖出（跟单地址在模拟状态中没有持仓）
+	SlippagePct float64                     `json:"slippage_pct"` // 跟单收到的主要代币比领投少多少（负数表示更少）
+	GasUsed     uint64                      `json:"gas_used"`
+	SentTx      *common.Hash                `json:"sent_tx,omitempty"` // armed 时真正发出的跟单交易
+	Error       string                      `json:"error,omitempty"`
+}
+
+// CopyTradeSummary 假设跟单的累计盈亏（ETH 计价，平均成本法）
+type CopyTradeSummary struct {
+	Leader         common.Address `json:"leader"`
+	Follower       common.Address `json:"follower"`
+	Armed          bool           `json:"armed"`
+	Trades         int            `json:"trades"`
+	Failed         int            `json:"failed"`
+	FollowerPnLETH float64        `json:"follower_pnl_eth"` // 已实现，已扣除 gas
+	LeaderPnLETH   float64        `json:"leader_pnl_eth"`
+	AvgSlippagePct float64        `json:"avg_slippage_pct"`
+	OpenPositions  int            `json:"open_positions"` // 跟单仍持有的代币数
+}
+
+// copyHolding 一个代币的持仓和 ETH 成本
+type copyHolding struct {
+	qty  *big.Int
+	cost *big.Int // wei
+}
+
+// copyBook 一方（领投或跟单）的持仓和已实现盈亏
+type copyBook struct {
+	holdings map[common.Address]*copyHolding
+	realized *big.Int // wei
+}
+
+func newCopyBook() *copyBook {
+	return &copyBook{holdings: make(map[common.Address]*copyHolding), realized: new(big.Int)}
+}
+
+// apply 按 ETH 计价的平均成本法记账：ETH 换代币计入成本，代币换 ETH 结转盈亏，代币换代币时成本随之转移
+func (b *copyBook) apply(deltas map[common.Address]*big.Int, gas *big.Int) {
+	basis := new(big.Int)
+	for token, d := range deltas {
+		if token == ethToken || d.Sign() >= 0 {
+			continue
+		}
+		h, ok := b.holdings[token]
+		if !ok || h.qty.Sign() == 0 {
+			continue // 跟踪开始之前的持仓，成本未知，按 0 计
+		}
+		sold := new(big.Int).Neg(d)
+		if sold.Cmp(h.qty) > 0 {
+			sold.Set(h.qty)
+		}
+		part := new(big.Int).Div(new(big.Int).Mul(h.cost, sold), h.qty)
+		basis.Add(basis, part)
+		h.cost.Sub(h.cost, part)
+		h.qty.Sub(h.qty, sold)
+		if h.qty.Sign() == 0 {
+			delete(b.holdings, token)
+		}
+	}
+	eth := deltas[ethToken]
+	var bought []common.Address
+	for token, d := range deltas {
+		if token != ethToken && d.Sign() > 0 {
+			bought = append(bought, token)
+		}
+	}
+	switch {
+	case len(bought) == 0: // 卖出换回 ETH
+		if eth != nil {
+			b.realized.Add(b.realized, eth)
+		}
+		b.realized.Sub(b.realized, basis)
+	default: // 买入：花掉的 ETH 和卖出代币的成本平摊到买入的代币上
+		cost := new(big.Int).Set(basis)
+		if eth != nil && eth.Sign() < 0 {
+			cost.Sub(cost, eth)
+		} else if eth != nil {
+			b.realized.Add(b.realized, eth)
+		}
+		share := new(big.Int).Div(cost, big.NewInt(int64(len(bought))))
+		for _, token := range bought {
+			h, ok := b.holdings[token]
+			if !ok {
+				h = &copyHolding{qty: new(big.Int), cost: new(big.Int)}
+				b.holdings[token] = h
+			}
+			h.qty.Add(h.qty, deltas[token])
+			h.cost.Add(h.cost, share)
+		}
+	}
+	b.realized.Sub(b.realized, gas)
+}
+
+// CopyTrader 跟单（默认 dry-run）：领投地址的 swap 上链后，在该区块的状态上用 eth_simulateV1 执行同样的调用，
+// 比较跟单与领投的成交结果；只有 armed 且启动时加了 -arm 才会真正发送交易
+type CopyTrader struct {
+	leader   common.Address
+	follower common.Address
+	weth     common.Address
+	rpc      *rpc.Client
+	client   *ethclient.Client
+	sender   *Sender // dry-run 时为 nil
+
+	mu          sync.Mutex
+	leaderBook  *copyBook
+	followBook  *copyBook
+	trades      int
+	failed      int
+	slippageSum float64
+	slippageN   int
+
+	onTrade func(CopyTrade)
+}
+
+// NewCopyTrader 创建跟单器，sender 为 nil 时只做 dry-run；onTrade 为 nil 时使用默认输出
+func NewCopyTrader(cfg CopyTradeConfig, weth common.Address, rpcClient *rpc.Client, client *ethclient.Client, sender *Sender, onTrade func(CopyTrade)) *CopyTrader {
+	if onTrade == nil {
+		onTrade = PrintCopyTrade
+	}
+	follower := copyTradeDryRunFollower
+	if cfg.Follower != "" {
+		follower = common.HexToAddress(cfg.Follower)
+	}
+	if sender != nil {
+		follower = sender.From()
+	}
+	return &CopyTrader{
+		leader:     common.HexToAddress(cfg.Leader),
+		follower:   follower,
+		weth:       weth,
+		rpc:        rpcClient,
+		client:     client,
+		sender:     sender,
+		leaderBook: newCopyBook(),
+		followBook: newCopyBook(),
+		onTrade:    onTrade,
+	}
+}
+
+// OnBlock 实现 BlockAnalyzer
+func (c *CopyTrader) OnBlock(data *BlockData) {
+	for i, tx := range data.Block.Transactions() {
+		r := data.Receipts[i]
+		if data.Senders[i] != c.leader || tx.To() == nil || r.Status != types.ReceiptStatusSuccessful {
+			continue
+		}
+		deltas := netTokenDeltas(c.leader, c.weth, tx, r)
+		var in, out bool
+		for _, d := range deltas {
+			in = in || d.Sign() > 0
+			out = out || d.Sign() < 0
+		}
+		if !in || !out {
+			continue // 不是 swap
+		}
+		c.follow(data.Block.NumberU64(), tx, r, deltas)
+	}
+}
+
+// follow 模拟（armed 时发送）一笔跟单并记账
+func (c *CopyTrader) follow(block uint64, tx *types.Transaction, r *types.Receipt, leader map[common.Address]*big.Int) {
+	ctx, cancel := context.WithTimeout(context.Background(), CONNECTION_TIMEOUT)
+	defer cancel()
+	trade := CopyTrade{LeaderTx: tx.Hash(), Block: block, Leader: leader}
+	data := replaceAddress(tx.Data(), c.leader, c.follower)
+
+	follower, gasUsed, err := c.simulate(ctx, block, *tx.To(), tx.Value(), data)
+	switch {
+	case err == nil:
+		trade.Simulated, trade.Follower, trade.GasUsed = true, follower, gasUsed
+		trade.SlippagePct = copySlippage(leader, follower)
+	case c.sender == nil && tx.Value().Sign() == 0:
+		// dry-run 的跟单地址在真实状态中没有代币，卖出无法模拟，按领投的成交价和跟单的持仓比例估算
+		trade.Follower, err = c.estimateSell(ctx, block, leader)
+		if err != nil {
+			trade.Error = err.Error()
+		}
+	default:
+		trade.Error = err.Error()
+	}
+
+	if trade.Error == "" && c.sender != nil && trade.Simulated {
+		sent, err := c.send(ctx, *tx.To(), tx.Value(), data)
+		if err != nil {
+			trade.Error = err.Error()
+		} else {
+			h := sent.Hash()
+			trade.SentTx = &h
+		}
+	}
+
+	gasPrice := r.EffectiveGasPrice
+	c.mu.Lock()
+	c.leaderBook.apply(leader, txFee(r))
+	if trade.Error != "" {
+		c.failed++
+	} else {
+		c.trades++
+		c.followBook.apply(trade.Follower, new(big.Int).Mul(new(big.Int).SetUint64(trade.GasUsed), gasPrice))
+		if trade.Simulated {
+			c.slippageSum += trade.SlippagePct
+			c.slippageN++
+		}
+	}
+	c.mu.Unlock()
+	c.onTrade(trade)
+}
+
+// simulate 在 block 的状态之上以跟单地址执行调用，返回跟单地址的代币净变化
+func (c *CopyTrader) simulate(ctx context.Context, block uint64, to common.Address, value *big.Int, data []byte) (map[common.Address]*big.Int, uint64, error) {
+	blockCall := map[string]interface{}{
+		"calls": []simCall{{From: c.follower, To: to, Data: data, Value: (*hexutil.Big)(value)}},
+	}
+	if c.sender == nil {
+		blockCall["stateOverrides"] = map[common.Address]interface{}{
+			c.follower: map[string]interface{}{"balance": (*hexutil.Big)(copyTradeSimBalance)},
+		}
+	}
+	params := map[string]interface{}{
+		"blockStateCalls": []interface{}{blockCall},
+		"traceTransfers":  true,
+		"validation":      false,
+	}
+	var blocks []struct {
+		Calls []struct {
+			simCallResult
+			GasUsed hexutil.Uint64 `json:"gasUsed"`
+			Logs    []types.Log    `json:"logs"`
+		} `json:"calls"`
+	}
+	if err := callRetry(ctx, c.rpc, &blocks, "eth_simulateV1", params, hexutil.Uint64(block)); err != nil {
+		return nil, 0, fmt.Errorf("模拟跟单失败: %w", err)
+	}
+	if len(blocks) != 1 || len(blocks[0].Calls) != 1 {
+		return nil, 0, fmt.Errorf("eth_simulateV1 返回的结果数量不匹配")
+	}
+	res := blocks[0].Calls[0]
+	if !simOK(res.simCallResult) {
+		msg := "revert"
+		if res.Error != nil {
+			msg = res.Error.Message
+		}
+		return nil, 0, fmt.Errorf("跟单调用执行失败: %s", msg)
+	}
+	deltas := make(map[common.Address]*big.Int)
+	for _, l := range res.Logs {
+		if len(l.Topics) != 3 || l.Topics[0] != erc20TransferTopic || len(l.Data) != 32 {
+			continue
+		}
+		token := l.Address
+		if token == simETHAddress || token == c.weth {
+			token = ethToken
+		}
+		amount := new(big.Int).SetBytes(l.Data)
+		if common.BytesToAddress(l.Topics[2].Bytes()) == c.follower {
+			addDelta(deltas, token, amount)
+		}
+		if common.BytesToAddress(l.Topics[1].Bytes()) == c.follower {
+			addDelta(deltas, token, new(big.Int).Neg(amount))
+		}
+	}
+	return deltas, uint64(res.GasUsed), nil
+}
+
+// estimateSell 估算 dry-run 跟单的卖出：按领投卖出的仓位比例卖出跟单持仓，按领投的成交价换回
+func (c *CopyTrader) estimateSell(ctx context.Context, block uint64, leader map[common.Address]*big.Int) (map[common.Address]*big.Int, error) {
+	follower := make(map[common.Address]*big.Int)
+	var leaderSold, followerSold *big.Int
+	for token, d := range leader {
+		if token == ethToken || d.Sign() >= 0 {
+			continue
+		}
+		c.mu.Lock()
+		h, ok := c.followBook.holdings[token]
+		var held *big.Int
+		if ok {
+			held = new(big.Int).Set(h.qty)
+		}
+		c.mu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("跟单没有 %s 的持仓，跳过卖出", token.Hex())
+		}
+		// 领投卖出的比例 = 卖出数量 / (卖出数量 + 卖出后的余额)
+		sold := new(big.Int).Neg(d)
+		left, err := erc20BalanceAt(ctx, c.client, token, c.leader, block)
+		if err != nil {
+			return nil, err
+		}
+		qty := new(big.Int).Div(new(big.Int).Mul(held, sold), new(big.Int).Add(sold, left))
+		follower[token] = new(big.Int).Neg(qty)
+		leaderSold, followerSold = sold, qty
+	}
+	if leaderSold == nil || leaderSold.Sign() == 0 {
+		return nil, fmt.Errorf("无法估算卖出")
+	}
+	for token, d := range leader {
+		if d.Sign() > 0 {
+			follower[token] = new(big.Int).Div(new(big.Int).Mul(d, followerSold), leaderSold)
+		}
+	}
+	return follower, nil
+}
+
+// send 真正发送跟单交易（只在 armed 时调用）
+func (c *CopyTrader) send(ctx context.Context, to common.Address, value *big.Int, data []byte) (*types.Transaction, error) {
+	tx, err := c.sender.BuildTx(ctx, to, value, data)
+	if err != nil {
+		return nil, err
+	}
+	return c.sender.SignAndSend(ctx, tx)
+}
+
+// Summary 累计结果
+func (c *CopyTrader) Summary() CopyTradeSummary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := CopyTradeSummary{
+		Leader:         c.leader,
+		Follower:       c.follower,
+		Armed:          c.sender != nil,
+		Trades:         c.trades,
+		Failed:         c.failed,
+		FollowerPnLETH: weiToEther(c.followBook.realized),
+		LeaderPnLETH:   weiToEther(c.leaderBook.realized),
+		OpenPositions:  len(c.followBook.holdings),
+	}
+	if c.slippageN > 0 {
+		s.AvgSlippagePct = c.slippageSum / float64(c.slippageN)
+	}
+	return s
+}
+
+// RegisterAPI 注册跟单查询接口
+//
+//	GET /api/copytrade 跟单的累计结果
+func (c *CopyTrader) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/copytrade", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, c.Summary())
+	})
+}
+
+// copySlippage 跟单与领投收到的主要代币（收到数量最多的一侧）相差的百分比，负数表示跟单收到的更少
+func copySlippage(leader, follower map[common.Address]*big.Int) float64 {
+	var token common.Address
+	var best *big.Int
+	for t, d := range leader {
+		if d.Sign() > 0 && (best == nil || d.Cmp(best) > 0) {
+			token, best = t, d
+		}
+	}
+	got, ok := follower[token]
+	if best == nil || !ok {
+		return 0
+	}
+	return (ratio(got, best) - 1) * 100
+}
+
+// replaceAddress 把 calldata 参数中按 32 字节对齐的 from 地址替换为 to（收款地址等）
+func replaceAddress(data []byte, from, to common.Address) []byte {
+	out := bytes.Clone(data)
+	if len(out) < 4 {
+		return out
+	}
+	old, repl := common.LeftPadBytes(from.Bytes(), 32), common.LeftPadBytes(to.Bytes(), 32)
+	for i := 4; i+32 <= len(out); i += 32 {
+		if bytes.Equal(out[i:i+32], old) {
+			copy(out[i:i+32], repl)
+		}
+	}
+	return out
+}
+
+func addDelta(deltas map[common.Address]*big.Int, token common.Address, v *big.Int) {
+	if d, ok := deltas[token]; ok {
+		d.Add(d, v)
+	} else {
+		deltas[token] = new(big.Int).Set(v)
+	}
+}
+
+// erc20BalanceAt 查询地址在某个区块的 ERC-20 余额
+func erc20BalanceAt(ctx context.Context, client *ethclient.Client, token, owner common.Address, block uint64) (*big.Int, error) {
+	data := append(common.FromHex("0x70a08231"), common.LeftPadBytes(owner.Bytes(), 32)...)
+	out, err := callContractRetry(ctx, client, ethereum.CallMsg{To: &token, Data: data}, new(big.Int).SetUint64(block))
+	if err != nil {
+		return nil, fmt.Errorf("查询 %s 余额失败: %w", token.Hex(), err)
+	}
+	if len(out) < 32 {
+		return nil, fmt.Errorf("查询 %s 余额返回格式错误", token.Hex())
+	}
+	return new(big.Int).SetBytes(out[:32]), nil
+}
+
+// PrintCopyTrade 默认的跟单输出
+func PrintCopyTrade(t CopyTrade) {
+	summary := fmt.Sprintf("🪞 [CopyTrade] 跟单 %s（区块 %d）", t.LeaderTx.Hex(), t.Block)
+	text := "\n" + summary + "\n"
+	switch {
+	case t.Error != "":
+		text += fmt.Sprintf("   ❌ %s\n", t.Error)
+	case t.Simulated:
+		text += fmt.Sprintf("   模拟成交，相对领投滑点 %+.2f%%，gas %d\n", t.SlippagePct, t.GasUsed)
+	default:
+		text += "   按领投成交价估算卖出\n"
+	}
+	if t.SentTx != nil {
+		text += fmt.Sprintf("   🚀 已发送跟单交易 %s\n", t.SentTx.Hex())
+	}
+	Emit(Event{Type: "copy_trade", Key: t.LeaderTx.Hex(), Summary: summary, Text: text, Data: t})
+}
@@ -0,0 +1,732 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ------------------------------------------------
+// 跨链消息生命周期：同时连接 L2 节点，把 L1 -> L2 存款和 L2 -> L1 取款的各个阶段串成一条记录
+// OP Stack：存款按 TransactionDeposited 算出 L2 交易哈希；取款按 withdrawalHash 对应 证明 / 最终确认
+// Arbitrum：存款按延迟收件箱序号对应 L2 区块头的 nonce；取款按 L2ToL1Tx 的 position 对应 Outbox 执行
+// ------------------------------------------------
+
+const (
+	// L2 轮询间隔（拉取取款日志、检查存款是否已在 L2 执行、检查超时）
+	CrossChainPollInterval = 15 * time.Second
+
+	// 每次 eth_getLogs 的最大区块跨度（L2 出块快，落后太多时分批追赶）
+	CrossChainMaxRange = 2000
+
+	// /api/crosschain 中保留的已完成消息数量
+	CrossChainHistory = 200
+
+	// ⚠️ 预计延迟：存款通常几分钟内在 L2 执行；取款要经过 7 天挑战期，再留 1 天余量
+	DefaultCrossChainDepositMinutes  = 30
+	DefaultCrossChainWithdrawalHours = 8 * 24
+)
+
+// L2 类型
+const (
+	L2TypeOptimism = "optimism" // OP Stack（Optimism、Base 等）
+	L2TypeArbitrum = "arbitrum"
+)
+
+// 消息方向
+const (
+	CrossChainL1ToL2 = "l1_to_l2"
+	CrossChainL2ToL1 = "l2_to_l1"
+)
+
+// 消息阶段
+const (
+	MsgInitiated = "initiated" // 已在源链发起
+	MsgRelayed   = "relayed"   // 存款已在 L2 执行
+	MsgProven    = "proven"    // OP 取款已在 L1 证明，等待挑战期
+	MsgFinalized = "finalized" // 取款已在 L1 执行
+	MsgFailed    = "failed"    // 目标链上执行失败
+)
+
+// L2ChainConfig 一条 L2，主网上的已知链（Optimism / Base / Arbitrum One）只需要填 rpc
+type L2ChainConfig struct {
+	Name   string `json:"name"`
+	RPC    string `json:"rpc"`    // L2 节点地址（HTTP 或 WebSocket）
+	Type   string `json:"type"`   // "optimism" | "arbitrum"，留空按 chainId 推断
+	Portal string `json:"portal"` // OP Stack：L1 上的 OptimismPortal
+	Bridge string `json:"bridge"` // Arbitrum：L1 上的 Bridge
+	Outbox string `json:"outbox"` // Arbitrum：L1 上的 Outbox
+}
+
+// CrossChainConfig 跨链消息跟踪，l2s 为空时不启动
+type CrossChainConfig struct {
+	L2s []L2ChainConfig `json:"l2s"`
+
+	DepositMinutes  int `json:"deposit_minutes"`  // 存款超过该时间仍未在 L2 执行时提醒
+	WithdrawalHours int `json:"withdrawal_hours"` // 取款超过该时间仍未在 L1 最终确认时提醒
+}
+
+// knownL2s 以太坊主网上已知 L2 的合约地址，按 L2 chainId 查找
+var knownL2s = map[uint64]L2ChainConfig{
+	ChainOptimism: {Name: "Optimism", Type: L2TypeOptimism, Portal: "0xbEb5Fc579115071764c7423A4f12eDde41f106Ed"},
+	ChainBase:     {Name: "Base", Type: L2TypeOptimism, Portal: "0x49048044D57e1C92A77f79988d21Fa8fAF74E97e"},
+	ChainArbitrum: {
+		Name: "Arbitrum", Type: L2TypeArbitrum,
+		Bridge: "0x8315177aB297bA92A06054cE80a67Ed4DBd7ed3a",
+		Outbox: "0x0B9857ae2D4A3DBe74ffE1d7DF045bb7F96E4840",
+	},
+}
+
+var (
+	// L2 上发起取款的预编译 / 预部署合约
+	opMessagePasser = common.HexToAddress("0x4200000000000000000000000000000000000016")
+	arbSys          = common.HexToAddress("0x0000000000000000000000000000000000000064")
+
+	// L1 合约调用 L2 时发送方地址加上的偏移
+	l1ToL2AliasOffset = new(big.Int).SetBytes(common.FromHex("0x1111000000000000000000000000000000001111"))
+)
+
+const crossChainEventsABI = `[
+	{"type":"event","name":"TransactionDeposited","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"version","type":"uint256","indexed":true},{"name":"opaqueData","type":"bytes","indexed":false}]},
+	{"type":"event","name":"WithdrawalProven","inputs":[{"name":"withdrawalHash","type":"bytes32","indexed":true},{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true}]},
+	{"type":"event","name":"WithdrawalFinalized","inputs":[{"name":"withdrawalHash","type":"bytes32","indexed":true},{"name":"success","type":"bool","indexed":false}]},
+	{"type":"event","name":"MessagePassed","inputs":[{"name":"nonce","type":"uint256","indexed":true},{"name":"sender","type":"address","indexed":true},{"name":"target","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false},{"name":"gasLimit","type":"uint256","indexed":false},{"name":"data","type":"bytes","indexed":false},{"name":"withdrawalHash","type":"bytes32","indexed":false}]},
+	{"type":"event","name":"MessageDelivered","inputs":[{"name":"messageIndex","type":"uint256","indexed":true},{"name":"beforeInboxAcc","type":"bytes32","indexed":true},{"name":"inbox","type":"address","indexed":false},{"name":"kind","type":"uint8","indexed":false},{"name":"sender","type":"address","indexed":false},{"name":"messageDataHash","type":"bytes32","indexed":false},{"name":"baseFeeL1","type":"uint256","indexed":false},{"name":"timestamp","type":"uint64","indexed":false}]},
+	{"type":"event","name":"L2ToL1Tx","inputs":[{"name":"caller","type":"address","indexed":false},{"name":"destination","type":"address","indexed":true},{"name":"hash","type":"uint256","indexed":true},{"name":"position","type":"uint256","indexed":true},{"name":"arbBlockNum","type":"uint256","indexed":false},{"name":"ethBlockNum","type":"uint256","indexed":false},{"name":"timestamp","type":"uint256","indexed":false},{"name":"callvalue","type":"uint256","indexed":false},{"name":"data","type":"bytes","indexed":false}]},
+	{"type":"event","name":"OutBoxTransactionExecuted","inputs":[{"name":"to","type":"address","indexed":true},{"name":"l2Sender","type":"address","indexed":true},{"name":"zero","type":"uint256","indexed":true},{"name":"transactionIndex","type":"uint256","indexed":false}]}
+]`
+
+var crossChainABI = mustParseABI(crossChainEventsABI)
+
+// CrossChainMessage 一条跨链消息从发起到完成的全过程
+type CrossChainMessage struct {
+	ID        string         `json:"id"`
+	Chain     string         `json:"chain"`
+	ChainID   uint64         `json:"chain_id"`
+	Direction string         `json:"direction"`
+	Stage     string         `json:"stage"`
+	From      common.Address `json:"from"`
+	To        common.Address `json:"to"`
+	Value     *big.Int       `json:"value"`              // 随消息转移的 ETH（wei），ERC-20 桥接为 0
+	L1Tx      common.Hash    `json:"l1_tx"`              // 存款的发起交易 / 取款的证明或执行交易
+	L2Tx      common.Hash    `json:"l2_tx"`              // OP 存款为预先算出的 L2 交易哈希
+	Sequence  uint64         `json:"sequence,omitempty"` // Arbitrum 存款在延迟收件箱中的序号
+	Started   time.Time      `json:"started"`
+	Updated   time.Time      `json:"updated"`
+	Expected  time.Time      `json:"expected"` // 超过该时间仍未完成视为卡住
+	Stuck     bool           `json:"stuck"`
+	Watched   string         `json:"watched"`
+}
+
+// l2Chain 一条已连接的 L2
+type l2Chain struct {
+	cfg    L2ChainConfig
+	id     uint64
+	client *ethclient.Client
+	cursor uint64 // 已处理到的 L2 区块
+}
+
+// CrossChainTracker 关联 L1 和 L2 两侧的事件，跟踪关注地址的跨链消息
+type CrossChainTracker struct {
+	cfg    CrossChainConfig
+	watch  *Watchlist
+	chains []*l2Chain
+
+	portals  map[common.Address]*l2Chain
+	bridges  map[common.Address]*l2Chain
+	outboxes map[common.Address]*l2Chain
+
+	mu      sync.Mutex
+	pending map[string]*CrossChainMessage
+	recent  []CrossChainMessage
+
+	onUpdate func(CrossChainMessage)
+}
+
+// NewCrossChainTracker 创建跨链消息跟踪，onUpdate 为 nil 时使用默认输出
+func NewCrossChainTracker(cfg CrossChainConfig, watch *Watchlist, onUpdate func(CrossChainMessage)) *CrossChainTracker {
+	if onUpdate == nil {
+		onUpdate = PrintCrossChainMessage
+	}
+	return &CrossChainTracker{
+		cfg:      cfg,
+		watch:    watch,
+		portals:  make(map[common.Address]*l2Chain),
+		bridges:  make(map[common.Address]*l2Chain),
+		outboxes: make(map[common.Address]*l2Chain),
+		pending:  make(map[string]*CrossChainMessage),
+		onUpdate: onUpdate,
+	}
+}
+
+// Connect 连接所有 L2 节点并补全已知链的合约地址，连接失败的链跳过
+func (t *CrossChainTracker) Connect(ctx context.Context) error {
+	var errs []error
+	for _, cfg := range t.cfg.L2s {
+		c, err := t.connect(ctx, cfg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", cfg.Name, err))
+			continue
+		}
+		t.chains = append(t.chains, c)
+		switch c.cfg.Type {
+		case L2TypeOptimism:
+			t.portals[common.HexToAddress(c.cfg.Portal)] = c
+		case L2TypeArbitrum:
+			t.bridges[common.HexToAddress(c.cfg.Bridge)] = c
+			t.outboxes[common.HexToAddress(c.cfg.Outbox)] = c
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t *CrossChainTracker) connect(ctx context.Context, cfg L2ChainConfig) (*l2Chain, error) {
+	dialCtx, cancel := context.WithTimeout(ctx, CONNECTION_TIMEOUT)
+	defer cancel()
+	c, err := dialRPC(dialCtx, cfg.RPC)
+	if err != nil {
+		return nil, fmt.Errorf("连接 L2 节点失败: %w", err)
+	}
+	client := ethclient.NewClient(c)
+	id, err := chainIDRetry(dialCtx, client)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("读取 chainId 失败: %w", err)
+	}
+	head, err := blockNumberRetry(dialCtx, client)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("读取最新区块失败: %w", err)
+	}
+
+	// 已知链补全留空的字段
+	if known, ok := knownL2s[id.Uint64()]; ok {
+		if cfg.Name == "" {
+			cfg.Name = known.Name
+		}
+		if cfg.Type == "" {
+			cfg.Type = known.Type
+		}
+		if cfg.Portal == "" {
+			cfg.Portal = known.Portal
+		}
+		if cfg.Bridge == "" {
+			cfg.Bridge = known.Bridge
+		}
+		if cfg.Outbox == "" {
+			cfg.Outbox = known.Outbox
+		}
+	}
+	if cfg.Name == "" {
+		cfg.Name = fmt.Sprintf("chain-%d", id.Uint64())
+	}
+	switch {
+	case cfg.Type == L2TypeOptimism && cfg.Portal != "":
+	case cfg.Type == L2TypeArbitrum && cfg.Bridge != "" && cfg.Outbox != "":
+	default:
+		client.Close()
+		return nil, fmt.Errorf("未知的 L2 (chainId %d)，需要填写 type 和对应的 L1 合约地址", id.Uint64())
+	}
+	return &l2Chain{cfg: cfg, id: id.Uint64(), client: client, cursor: head}, nil
+}
+
+// Chains 已连接的 L2 数量
+func (t *CrossChainTracker) Chains() int {
+	return len(t.chains)
+}
+
+// Run 定期轮询每条 L2 并检查超时的消息，直到 ctx 取消
+func (t *CrossChainTracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(CrossChainPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for _, c := range t.chains {
+				if err := t.poll(ctx, c); err != nil && ctx.Err() == nil {
+					log.Printf("⚠️  [%s] 跨链消息轮询失败: %v", c.cfg.Name, err)
+				}
+			}
+			t.checkStuck(time.Now())
+		case <-ctx.Done():
+			for _, c := range t.chains {
+				c.client.Close()
+			}
+			return
+		}
+	}
+}
+
+// OnBlock 实现 BlockAnalyzer：处理 L1 上的存款发起、取款证明和执行
+func (t *CrossChainTracker) OnBlock(data *BlockData) {
+	now := time.Unix(int64(data.Block.Time()), 0)
+	for i, tx := range data.Block.Transactions() {
+		r := data.Receipts[i]
+		if r.Status == 0 {
+			continue
+		}
+		for _, l := range r.Logs {
+			if len(l.Topics) == 0 {
+				continue
+			}
+			if c, ok := t.portals[l.Address]; ok {
+				t.handlePortal(c, l, data.Senders[i], now)
+			}
+			if c, ok := t.bridges[l.Address]; ok {
+				t.handleDelivered(c, l, tx, data.Senders[i], now)
+			}
+			if c, ok := t.outboxes[l.Address]; ok {
+				t.handleOutbox(c, l, data.Senders[i], now)
+			}
+		}
+	}
+}
+
+// handlePortal OptimismPortal：存款发起、取款证明、取款最终确认
+func (t *CrossChainTracker) handlePortal(c *l2Chain, l *types.Log, sender common.Address, now time.Time) {
+	switch l.Topics[0] {
+	case crossChainABI.Events["TransactionDeposited"].ID:
+		if len(l.Topics) != 4 || l.Topics[3] != (common.Hash{}) {
+			return // 只支持 version 0
+		}
+		values, err := crossChainABI.Unpack("TransactionDeposited", l.Data)
+		if err != nil {
+			return
+		}
+		from, to := common.BytesToAddress(l.Topics[1].Bytes()), common.BytesToAddress(l.Topics[2].Bytes())
+		opaque, _ := values[0].([]byte)
+		l2Hash, mint, ok := opDepositHash(l, from, to, opaque)
+		if !ok {
+			return
+		}
+		watched := t.watchedOf(sender, from, undoL1ToL2Alias(from), to)
+		if watched == "" {
+			return
+		}
+		t.start(&CrossChainMessage{
+			ID: c.cfg.Name + "/" + l2Hash.Hex(), Chain: c.cfg.Name, ChainID: c.id, Direction: CrossChainL1ToL2,
+			From: sender, To: to, Value: mint, L1Tx: l.TxHash, L2Tx: l2Hash,
+			Started: now, Expected: now.Add(time.Duration(t.cfg.DepositMinutes) * time.Minute), Watched: watched,
+		})
+
+	case crossChainABI.Events["WithdrawalProven"].ID:
+		if len(l.Topics) != 4 {
+			return
+		}
+		from, to := common.BytesToAddress(l.Topics[2].Bytes()), common.BytesToAddress(l.Topics[3].Bytes())
+		t.advance(c, CrossChainL2ToL1, l.Topics[1].Hex(), MsgProven, l.TxHash, now, func() string {
+			return t.watchedOf(sender, from, to)
+		}, from, to)
+
+	case crossChainABI.Events["WithdrawalFinalized"].ID:
+		if len(l.Topics) != 2 {
+			return
+		}
+		values, err := crossChainABI.Unpack("WithdrawalFinalized", l.Data)
+		if err != nil {
+			return
+		}
+		stage := MsgFinalized
+		if ok, _ := values[0].(bool); !ok {
+			stage = MsgFailed
+		}
+		t.advance(c, CrossChainL2ToL1, l.Topics[1].Hex(), stage, l.TxHash, now, func() string {
+			return t.watchedOf(sender)
+		}, sender, common.Address{})
+	}
+}
+
+// handleDelivered Arbitrum Bridge：延迟收件箱收到 L1 -> L2 消息
+func (t *CrossChainTracker) handleDelivered(c *l2Chain, l *types.Log, tx *types.Transaction, sender common.Address, now time.Time) {
+	if l.Topics[0] != crossChainABI.Events["MessageDelivered"].ID || len(l.Topics) != 3 {
+		return
+	}
+	values, err := crossChainABI.Unpack("MessageDelivered", l.Data)
+	if err != nil {
+		return
+	}
+	from, _ := values[2].(common.Address)
+	watched := t.watchedOf(sender, from, undoL1ToL2Alias(from))
+	if watched == "" {
+		return
+	}
+	index := l.Topics[1].Big()
+	// ⚠️ 消息内容只有哈希，随消息转移的 ETH 近似取发起交易的 value
+	t.start(&CrossChainMessage{
+		ID: fmt.Sprintf("%s/delayed/%s", c.cfg.Name, index), Chain: c.cfg.Name, ChainID: c.id, Direction: CrossChainL1ToL2,
+		From: sender, To: from, Value: tx.Value(), L1Tx: l.TxHash, Sequence: index.Uint64(),
+		Started: now, Expected: now.Add(time.Duration(t.cfg.DepositMinutes) * time.Minute), Watched: watched,
+	})
+}
+
+// handleOutbox Arbitrum Outbox：L2 -> L1 消息在 L1 执行
+func (t *CrossChainTracker) handleOutbox(c *l2Chain, l *types.Log, sender common.Address, now time.Time) {
+	if l.Topics[0] != crossChainABI.Events["OutBoxTransactionExecuted"].ID || len(l.Topics) != 4 {
+		return
+	}
+	values, err := crossChainABI.Unpack("OutBoxTransactionExecuted", l.Data)
+	if err != nil {
+		return
+	}
+	position, _ := values[0].(*big.Int)
+	if position == nil {
+		return
+	}
+	to, l2Sender := common.BytesToAddress(l.Topics[1].Bytes()), common.BytesToAddress(l.Topics[2].Bytes())
+	t.advance(c, CrossChainL2ToL1, "position/"+position.String(), MsgFinalized, l.TxHash, now, func() string {
+		return t.watchedOf(sender, to, l2Sender)
+	}, l2Sender, to)
+}
+
+// poll 拉取 L2 上新的取款日志，并检查该链上未执行的存款
+func (t *CrossChainTracker) poll(ctx context.Context, c *l2Chain) error {
+	head, err := headerByNumberRetry(ctx, c.client, nil)
+	if err != nil {
+		return fmt.Errorf("读取最新区块失败: %w", err)
+	}
+	if err := t.relayDeposits(ctx, c, head); err != nil {
+		return err
+	}
+
+	latest := head.Number.Uint64()
+	for c.cursor < latest {
+		from, to := c.cursor+1, min(c.cursor+CrossChainMaxRange, latest)
+		q := ethereum.FilterQuery{FromBlock: new(big.Int).SetUint64(from), ToBlock: new(big.Int).SetUint64(to)}
+		if c.cfg.Type == L2TypeOptimism {
+			q.Addresses = []common.Address{opMessagePasser}
+			q.Topics = [][]common.Hash{{crossChainABI.Events["MessagePassed"].ID}}
+		} else {
+			q.Addresses = []common.Address{arbSys}
+			q.Topics = [][]common.Hash{{crossChainABI.Events["L2ToL1Tx"].ID}}
+		}
+		var logs []types.Log
+		err := Retry(ctx, "eth_getLogs", func() (err error) {
+			logs, err = c.client.FilterLogs(ctx, q)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("拉取 L2 取款日志失败 (%d-%d): %w", from, to, err)
+		}
+		for i := range logs {
+			t.handleWithdrawal(ctx, c, &logs[i])
+		}
+		c.cursor = to
+	}
+	return nil
+}
+
+// handleWithdrawal L2 上发起的取款（MessagePassed / L2ToL1Tx）
+func (t *CrossChainTracker) handleWithdrawal(ctx context.Context, c *l2Chain, l *types.Log) {
+	if l.Removed || len(l.Topics) != 4 {
+		return
+	}
+	msg := &CrossChainMessage{Chain: c.cfg.Name, ChainID: c.id, Direction: CrossChainL2ToL1, L2Tx: l.TxHash}
+	var parties []common.Address
+	switch c.cfg.Type {
+	case L2TypeOptimism:
+		values, err := crossChainABI.Unpack("MessagePassed", l.Data)
+		if err != nil {
+			return
+		}
+		hash, _ := values[3].([32]byte)
+		msg.ID = c.cfg.Name + "/" + common.Hash(hash).Hex()
+		msg.From, msg.To = common.BytesToAddress(l.Topics[2].Bytes()), common.BytesToAddress(l.Topics[3].Bytes())
+		msg.Value, _ = values[0].(*big.Int)
+		parties = []common.Address{msg.From, msg.To}
+	case L2TypeArbitrum:
+		values, err := crossChainABI.Unpack("L2ToL1Tx", l.Data)
+		if err != nil {
+			return
+		}
+		msg.ID = fmt.Sprintf("%s/position/%s", c.cfg.Name, l.Topics[3].Big())
+		msg.From, _ = values[0].(common.Address)
+		msg.To = common.BytesToAddress(l.Topics[1].Bytes())
+		msg.Value, _ = values[4].(*big.Int)
+		parties = []common.Address{msg.From, msg.To}
+	}
+
+	// 经过官方桥取款时 sender 是桥合约，按 L2 交易的发送方判断
+	msg.Watched = t.watchedOf(parties...)
+	if msg.Watched == "" {
+		var tx *types.Transaction
+		err := Retry(ctx, "eth_getTransactionByHash", func() (err error) {
+			tx, _, err = c.client.TransactionByHash(ctx, l.TxHash)
+			return err
+		})
+		if err != nil {
+			return
+		}
+		var sender common.Address
+		err = Retry(ctx, "eth_getTransactionByBlockHashAndIndex", func() (err error) {
+			sender, err = c.client.TransactionSender(ctx, tx, l.BlockHash, l.TxIndex)
+			return err
+		})
+		if err != nil {
+			return
+		}
+		if msg.Watched = t.watchedOf(sender); msg.Watched == "" {
+			return
+		}
+		msg.From = sender
+	}
+	msg.Started = time.Now()
+	if l.BlockTimestamp != 0 {
+		msg.Started = time.Unix(int64(l.BlockTimestamp), 0)
+	}
+	msg.Expected = msg.Started.Add(time.Duration(t.cfg.WithdrawalHours) * time.Hour)
+	t.start(msg)
+}
+
+// relayDeposits 检查该链上未执行的存款是否已经在 L2 执行
+func (t *CrossChainTracker) relayDeposits(ctx context.Context, c *l2Chain, head *types.Header) error {
+	t.mu.Lock()
+	var waiting []CrossChainMessage
+	for _, msg := range t.pending {
+		if msg.Chain == c.cfg.Name && msg.Direction == CrossChainL1ToL2 {
+			waiting = append(waiting, *msg)
+		}
+	}
+	t.mu.Unlock()
+
+	for _, msg := range waiting {
+		stage := ""
+		switch c.cfg.Type {
+		case L2TypeOptimism:
+			var r *types.Receipt
+			err := Retry(ctx, "eth_getTransactionReceipt", func() (err error) {
+				r, err = c.client.TransactionReceipt(ctx, msg.L2Tx)
+				return err
+			})
+			if errors.Is(err, ethereum.NotFound) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("查询存款回执失败: %w", err)
+			}
+			stage = MsgRelayed
+			if r.Status == 0 {
+				stage = MsgFailed
+			}
+		case L2TypeArbitrum:
+			// Arbitrum 区块头的 nonce 字段是已读取的延迟消息数量
+			if head.Nonce.Uint64() <= msg.Sequence {
+				continue
+			}
+			stage = MsgRelayed
+		}
+		t.update(msg.ID, stage, common.Hash{}, time.Now())
+	}
+	return nil
+}
+
+// start 记录新发起的消息
+func (t *CrossChainTracker) start(msg *CrossChainMessage) {
+	msg.Stage, msg.Updated = MsgInitiated, msg.Started
+	if msg.Value == nil {
+		msg.Value = new(big.Int)
+	}
+	t.mu.Lock()
+	if _, ok := t.pending[msg.ID]; ok {
+		t.mu.Unlock()
+		return
+	}
+	t.pending[msg.ID] = msg
+	out := *msg
+	t.mu.Unlock()
+	t.onUpdate(out)
+}
+
+// advance 取款在 L1 上进入新阶段；监控启动前发起的取款第一次出现时按 watched 判断是否跟踪（发起时间未知）
+func (t *CrossChainTracker) advance(c *l2Chain, direction, key, stage string, l1Tx common.Hash, now time.Time, watched func() string, from, to common.Address) {
+	id := c.cfg.Name + "/" + key
+	t.mu.Lock()
+	_, ok := t.pending[id]
+	t.mu.Unlock()
+	if !ok {
+		w := watched()
+		if w == "" {
+			return
+		}
+		t.mu.Lock()
+		t.pending[id] = &CrossChainMessage{
+			ID: id, Chain: c.cfg.Name, ChainID: c.id, Direction: direction, Stage: MsgInitiated,
+			From: from, To: to, Value: new(big.Int),
+			Expected: now.Add(time.Duration(t.cfg.WithdrawalHours) * time.Hour), Watched: w,
+		}
+		t.mu.Unlock()
+	}
+	t.update(id, stage, l1Tx, now)
+}
+
+// update 推进消息阶段，完成的消息移入历史
+func (t *CrossChainTracker) update(id, stage string, l1Tx common.Hash, now time.Time) {
+	t.mu.Lock()
+	msg, ok := t.pending[id]
+	if !ok || msg.Stage == stage {
+		t.mu.Unlock()
+		return
+	}
+	msg.Stage, msg.Updated, msg.Stuck = stage, now, false
+	if l1Tx != (common.Hash{}) {
+		msg.L1Tx = l1Tx
+	}
+	if stage != MsgProven {
+		delete(t.pending, id)
+		t.recent = append(t.recent, *msg)
+		if len(t.recent) > CrossChainHistory {
+			t.recent = t.recent[len(t.recent)-CrossChainHistory:]
+		}
+	}
+	out := *msg
+	t.mu.Unlock()
+	t.onUpdate(out)
+}
+
+// checkStuck 超过预计时间仍未完成的消息提醒一次
+func (t *CrossChainTracker) checkStuck(now time.Time) {
+	var stuck []CrossChainMessage
+	t.mu.Lock()
+	for _, msg := range t.pending {
+		if !msg.Stuck && now.After(msg.Expected) {
+			msg.Stuck = true
+			stuck = append(stuck, *msg)
+		}
+	}
+	t.mu.Unlock()
+	for _, msg := range stuck {
+		t.onUpdate(msg)
+	}
+}
+
+// watchedOf 返回第一个在关注列表中的地址的来源
+func (t *CrossChainTracker) watchedOf(addrs ...common.Address) string {
+	for _, a := range addrs {
+		if s := t.watch.Source(a); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// CrossChainSnapshot /api/crosschain 的返回内容
+type CrossChainSnapshot struct {
+	Pending []CrossChainMessage `json:"pending"`
+	Recent  []CrossChainMessage `json:"recent"` // 新的在前
+}
+
+// Snapshot 未完成的消息（按发起时间排序）和最近完成的消息
+func (t *CrossChainTracker) Snapshot() CrossChainSnapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var s CrossChainSnapshot
+	for _, msg := range t.pending {
+		s.Pending = append(s.Pending, *msg)
+	}
+	sort.Slice(s.Pending, func(i, j int) bool { return s.Pending[i].Started.Before(s.Pending[j].Started) })
+	for i := len(t.recent) - 1; i >= 0; i-- {
+		s.Recent = append(s.Recent, t.recent[i])
+	}
+	return s
+}
+
+// RegisterAPI 注册跨链消息查询接口
+//
+//	GET /api/crosschain 未完成和最近完成的跨链消息
+func (t *CrossChainTracker) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/crosschain", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, t.Snapshot())
+	})
+}
+
+// opDepositHash 按 OptimismPortal 的 TransactionDeposited 日志算出 L2 上存款交易（类型 0x7E）的哈希
+// opaqueData = abi.encodePacked(mint, value, gasLimit, isCreation, data)
+func opDepositHash(l *types.Log, from, to common.Address, opaque []byte) (common.Hash, *big.Int, bool) {
+	if len(opaque) < 73 {
+		return common.Hash{}, nil, false
+	}
+	mint := new(big.Int).SetBytes(opaque[:32])
+	tx := struct {
+		SourceHash common.Hash
+		From       common.Address
+		To         *common.Address `rlp:"nil"`
+		Mint       *big.Int        `rlp:"nil"`
+		Value      *big.Int
+		Gas        uint64
+		IsSystemTx bool
+		Data       []byte
+	}{
+		From:  from,
+		To:    &to,
+		Mint:  mint,
+		Value: new(big.Int).SetBytes(opaque[32:64]),
+		Gas:   new(big.Int).SetBytes(opaque[64:72]).Uint64(),
+		Data:  opaque[73:],
+	}
+	if opaque[72] != 0 {
+		tx.To = nil
+	}
+	// 用户存款的 sourceHash = keccak256(bytes32(0) ++ keccak256(l1BlockHash ++ bytes32(logIndex)))
+	depositID := crypto.Keccak256(l.BlockHash.Bytes(), common.BigToHash(new(big.Int).SetUint64(uint64(l.Index))).Bytes())
+	tx.SourceHash = crypto.Keccak256Hash(common.Hash{}.Bytes(), depositID)
+
+	enc, err := rlp.EncodeToBytes(&tx)
+	if err != nil {
+		return common.Hash{}, nil, false
+	}
+	return crypto.Keccak256Hash([]byte{0x7E}, enc), mint, true
+}
+
+// undoL1ToL2Alias 还原 L1 合约在 L2 上的别名地址
+func undoL1ToL2Alias(addr common.Address) common.Address {
+	n := new(big.Int).Sub(new(big.Int).SetBytes(addr.Bytes()), l1ToL2AliasOffset)
+	n.Mod(n, new(big.Int).Lsh(big.NewInt(1), 160))
+	return common.BigToAddress(n)
+}
+
+// PrintCrossChainMessage 默认的跨链消息输出
+func PrintCrossChainMessage(msg CrossChainMessage) {
+	arrow := "L1 -> " + msg.Chain
+	if msg.Direction == CrossChainL2ToL1 {
+		arrow = msg.Chain + " -> L1"
+	}
+	what := map[string]string{
+		MsgInitiated: "已发起",
+		MsgRelayed:   "已在 L2 执行",
+		MsgProven:    "已证明，等待挑战期",
+		MsgFinalized: "已在 L1 最终确认",
+		MsgFailed:    "⚠️ 目标链执行失败",
+	}[msg.Stage]
+	if msg.Stage != MsgInitiated && !msg.Started.IsZero() {
+		what += fmt.Sprintf("（用时 %s）", msg.Updated.Sub(msg.Started).Round(time.Second))
+	}
+	if msg.Stuck {
+		what = fmt.Sprintf("⚠️ 卡住：超过预计时间 %s 仍处于 %s", msg.Expected.Format(time.DateTime), msg.Stage)
+	}
+	value := ""
+	if msg.Value != nil && msg.Value.Sign() > 0 {
+		value = " | " + formatUnits(msg.Value, 18) + " ETH"
+	}
+	summary := fmt.Sprintf("🔗 [CrossChain][Watched:%s] %s %s | %s -> %s%s",
+		msg.Watched, arrow, what, msg.From.Hex(), msg.To.Hex(), value)
+	text := summary
+	if msg.L1Tx != (common.Hash{}) {
+		text += "\n   L1 " + msg.L1Tx.Hex()
+	}
+	if msg.L2Tx != (common.Hash{}) {
+		text += "\n   L2 " + msg.L2Tx.Hex()
+	}
+	Emit(Event{Type: "crosschain", Key: eventKey(msg.ID, msg.Stage, msg.Stuck), Summary: summary, Text: text, Data: msg})
+}
@@ -0,0 +1,579 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ------------------------------------------------
+// Curve 池子：解码 TokenExchange，跟踪余额、A 参数和 virtual price；
+// stable 池子按 StableSwap 不变量在本地计算兑换结果，crypto 池子的兑换率用链上 get_dy 查询
+// ------------------------------------------------
+
+const (
+	// 没有成交的池子每隔多少个区块刷新一次状态（A 在 ramp 期间随时间变化，不会产生日志）
+	CurveRefreshBlocks = 50
+	// 池子最多支持的币种数
+	CurveMaxCoins = 8
+	// StableSwap 牛顿迭代的最大次数（与合约相同）
+	curveMaxIterations = 255
+	// 手续费的分母：fee() 返回值 / 1e10
+	curveFeeDenominator = 1e10
+)
+
+// Curve 池子类型
+const (
+	CurveStable = "stable" // StableSwap（3pool、stETH 等）
+	CurveCrypto = "crypto" // CryptoSwap（tricrypto 等）
+)
+
+var (
+	curveStableExchangeTopic = crypto.Keccak256Hash([]byte("TokenExchange(address,int128,uint256,int128,uint256)"))
+	curveCryptoExchangeTopic = crypto.Keccak256Hash([]byte("TokenExchange(address,uint256,uint256,uint256,uint256)"))
+
+	// 池子中代表原生 ETH 的地址
+	curveETH = common.HexToAddress("0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE")
+
+	selectorCurveA            = common.FromHex("0xf446c1d0") // A()
+	selectorCurveVirtualPrice = common.FromHex("0xbb7b8b80") // get_virtual_price()
+	selectorCurveCoins        = common.FromHex("0xc6610657") // coins(uint256)
+	selectorCurveCoins128     = common.FromHex("0x23746eb8") // coins(int128)，早期池子
+	selectorCurveBalances     = common.FromHex("0x4903b0d1") // balances(uint256)
+	selectorCurveBalances128  = common.FromHex("0x065a80d8") // balances(int128)，早期池子
+	selectorCurveGetDy        = common.FromHex("0x556d6e9f") // get_dy(uint256,uint256,uint256)，crypto 池子
+)
+
+// CurveConfig Curve 池子监控
+type CurveConfig struct {
+	Pools []CurvePoolConfig `json:"pools"`
+}
+
+// CurvePoolConfig 一个 Curve 池子
+type CurvePoolConfig struct {
+	Address string `json:"address"`
+	Kind    string `json:"kind"` // "stable" | "crypto"
+	Name    string `json:"name"` // 显示名，例如 "3pool"
+}
+
+// CurvePoolState 池子的状态
+type CurvePoolState struct {
+	Name         string           `json:"name"`
+	Pool         common.Address   `json:"pool"`
+	Kind         string           `json:"kind"`
+	Coins        []common.Address `json:"coins"`
+	Balances     []*big.Int       `json:"balances"`
+	A            *big.Int         `json:"a"`
+	Fee          *big.Int         `json:"fee"` // 1e10 为 100%
+	VirtualPrice *big.Int         `json:"virtual_price"`
+	Block        uint64           `json:"block"`
+}
+
+// CurveExchange 池子中的一次兑换
+type CurveExchange struct {
+	Name      string         `json:"name"`
+	Pool      common.Address `json:"pool"`
+	Block     uint64         `json:"block"`
+	Tx        common.Hash    `json:"tx"`
+	LogIndex  uint           `json:"log_index"`
+	Buyer     common.Address `json:"buyer"`
+	Sold      common.Address `json:"sold"`
+	SoldAmt   *big.Int       `json:"sold_amount"`
+	Bought    common.Address `json:"bought"`
+	BoughtAmt *big.Int       `json:"bought_amount"`
+	ValueUSD  float64        `json:"value_usd"`
+	Priced    bool           `json:"priced"`
+	Desc      string         `json:"desc"`
+}
+
+// CurveParamChange A 参数变化或 virtual price 下降（正常情况下只增不减，下降说明池子亏损）
+type CurveParamChange struct {
+	Name  string         `json:"name"`
+	Pool  common.Address `json:"pool"`
+	Block uint64         `json:"block"`
+	Param string         `json:"param"` // "A" | "virtual_price"
+	Old   *big.Int       `json:"old"`
+	New   *big.Int       `json:"new"`
+}
+
+// curvePool 池子的静态信息和最近一次刷新的状态
+type curvePool struct {
+	cfg      CurvePoolConfig
+	addr     common.Address
+	idx128   bool // coins / balances 的参数是 int128
+	coins    []common.Address
+	decimals []uint8
+
+	state CurvePoolState
+	rates [][]float64 // crypto 池子：链上 get_dy 得到的边际兑换率 rates[i][j]（最小单位之比）
+	ready bool
+}
+
+// CurveMonitor 监控配置的 Curve 池子
+type CurveMonitor struct {
+	client *ethclient.Client
+	tokens *TokenCache
+	values *Valuator
+	weth   common.Address
+
+	mu    sync.RWMutex
+	pools map[common.Address]*curvePool
+
+	onExchange func(CurveExchange)
+	onChange   func(CurveParamChange)
+}
+
+// NewCurveMonitor 创建监控，回调为 nil 时使用默认输出
+func NewCurveMonitor(cfg CurveConfig, client *ethclient.Client, tokens *TokenCache, values *Valuator, weth common.Address, onExchange func(CurveExchange), onChange func(CurveParamChange)) *CurveMonitor {
+	if onExchange == nil {
+		onExchange = PrintCurveExchange
+	}
+	if onChange == nil {
+		onChange = PrintCurveParamChange
+	}
+	m := &CurveMonitor{
+		client:     client,
+		tokens:     tokens,
+		values:     values,
+		weth:       weth,
+		pools:      make(map[common.Address]*curvePool, len(cfg.Pools)),
+		onExchange: onExchange,
+		onChange:   onChange,
+	}
+	for _, p := range cfg.Pools {
+		addr := common.HexToAddress(p.Address)
+		if p.Name == "" {
+			p.Name = addr.Hex()
+		}
+		m.pools[addr] = &curvePool{cfg: p, addr: addr}
+	}
+	return m
+}
+
+// OnBlock 实现 BlockAnalyzer：解码池子的兑换，有成交、尚未初始化或到了刷新间隔的池子重新查询状态
+func (m *CurveMonitor) OnBlock(data *BlockData) {
+	number := data.Block.NumberU64()
+	active := make(map[common.Address]bool)
+	for _, r := range data.Receipts {
+		if r.Status == 0 {
+			continue
+		}
+		for _, l := range r.Logs {
+			p, ok := m.pools[l.Address]
+			if !ok {
+				continue
+			}
+			active[l.Address] = true
+			if ex, ok := m.decodeExchange(p, l); ok {
+				m.onExchange(ex)
+			}
+		}
+	}
+	for addr, p := range m.pools {
+		if active[addr] || !p.ready || number%CurveRefreshBlocks == 0 {
+			m.refresh(p, data.Block.Number())
+		}
+	}
+}
+
+// decodeExchange 解码 TokenExchange（stable 池子的币种下标是 int128，crypto 池子是 uint256）
+func (m *CurveMonitor) decodeExchange(p *curvePool, l *types.Log) (CurveExchange, bool) {
+	if len(l.Topics) != 2 || len(l.Data) != 4*32 ||
+		(l.Topics[0] != curveStableExchangeTopic && l.Topics[0] != curveCryptoExchangeTopic) {
+		return CurveExchange{}, false
+	}
+	coins := p.coins
+	sold, bought := word(l.Data, 0).Uint64(), word(l.Data, 2).Uint64()
+	if sold >= uint64(len(coins)) || bought >= uint64(len(coins)) {
+		return CurveExchange{}, false
+	}
+	ex := CurveExchange{
+		Name:      p.cfg.Name,
+		Pool:      p.addr,
+		Block:     l.BlockNumber,
+		Tx:        l.TxHash,
+		LogIndex:  l.Index,
+		Buyer:     common.BytesToAddress(l.Topics[1].Bytes()),
+		Sold:      coins[sold],
+		SoldAmt:   word(l.Data, 1),
+		Bought:    coins[bought],
+		BoughtAmt: word(l.Data, 3),
+	}
+	if ex.Sold == curveETH {
+		ex.ValueUSD, ex.Priced = m.values.ETHValue(ex.SoldAmt)
+	} else {
+		ex.ValueUSD, ex.Priced = m.values.TokenValue(ex.Sold, ex.SoldAmt)
+	}
+	ex.Desc = m.tokens.Meta(ex.Sold).Format(ex.SoldAmt) + " -> " + m.tokens.Meta(ex.Bought).Format(ex.BoughtAmt)
+	return ex, true
+}
+
+// refresh 在 block 上查询池子的余额、A、手续费和 virtual price，与上一次相比 A 变化或 virtual price 下降时输出
+func (m *CurveMonitor) refresh(p *curvePool, block *big.Int) {
+	ctx, cancel := context.WithTimeout(context.Background(), CONNECTION_TIMEOUT)
+	defer cancel()
+	call := func(data []byte) (*big.Int, error) {
+		out, err := callContractRetry(ctx, m.client, ethereum.CallMsg{To: &p.addr, Data: data}, block)
+		if err != nil {
+			return nil, err
+		}
+		if len(out) < 32 {
+			return nil, fmt.Errorf("返回数据为空")
+		}
+		return word(out, 0), nil
+	}
+	indexed := func(sel []byte, i int) []byte {
+		return append(append([]byte{}, sel...), common.LeftPadBytes(big.NewInt(int64(i)).Bytes(), 32)...)
+	}
+
+	if p.coins == nil {
+		if err := m.loadCoins(ctx, p, call, indexed); err != nil {
+			log.Printf("⚠️  初始化 Curve 池子 %s 失败: %v", p.cfg.Name, err)
+			return
+		}
+	}
+	st := CurvePoolState{Name: p.cfg.Name, Pool: p.addr, Kind: p.cfg.Kind, Coins: p.coins, Block: block.Uint64()}
+	balancesSel := selectorCurveBalances
+	if p.idx128 {
+		balancesSel = selectorCurveBalances128
+	}
+	var err error
+	for i := range p.coins {
+		var b *big.Int
+		if b, err = call(indexed(balancesSel, i)); err != nil {
+			break
+		}
+		st.Balances = append(st.Balances, b)
+	}
+	if err == nil {
+		st.A, err = call(selectorCurveA)
+	}
+	if err == nil {
+		st.Fee, err = call(selectorFee)
+	}
+	if err == nil {
+		st.VirtualPrice, err = call(selectorCurveVirtualPrice)
+	}
+	if err != nil {
+		log.Printf("⚠️  刷新 Curve 池子 %s 失败: %v", p.cfg.Name, err)
+		return
+	}
+	var rates [][]float64
+	if p.cfg.Kind == CurveCrypto {
+		rates = m.cryptoRates(p, st.Balances, func(i, j int, dx *big.Int) (*big.Int, error) {
+			data := append(indexed(selectorCurveGetDy, i), common.LeftPadBytes(big.NewInt(int64(j)).Bytes(), 32)...)
+			return call(append(data, common.LeftPadBytes(dx.Bytes(), 32)...))
+		})
+	}
+
+	m.mu.Lock()
+	prev, wasReady := p.state, p.ready
+	p.state, p.rates, p.ready = st, rates, true
+	m.mu.Unlock()
+	if !wasReady {
+		return
+	}
+	if prev.A.Cmp(st.A) != 0 {
+		m.onChange(CurveParamChange{Name: p.cfg.Name, Pool: p.addr, Block: st.Block, Param: "A", Old: prev.A, New: st.A})
+	}
+	if st.VirtualPrice.Cmp(prev.VirtualPrice) < 0 {
+		m.onChange(CurveParamChange{Name: p.cfg.Name, Pool: p.addr, Block: st.Block, Param: "virtual_price", Old: prev.VirtualPrice, New: st.VirtualPrice})
+	}
+}
+
+// loadCoins 查询池子的币种（先按 coins(uint256)，失败再按早期池子的 coins(int128)）和各自的 decimals
+func (m *CurveMonitor) loadCoins(ctx context.Context, p *curvePool, call func([]byte) (*big.Int, error), indexed func([]byte, int) []byte) error {
+	if _, err := call(indexed(selectorCurveCoins, 0)); err != nil {
+		p.idx128 = true
+	}
+	sel := selectorCurveCoins
+	if p.idx128 {
+		sel = selectorCurveCoins128
+	}
+	var coins []common.Address
+	for i := 0; i < CurveMaxCoins; i++ {
+		v, err := call(indexed(sel, i))
+		if err != nil {
+			break
+		}
+		coins = append(coins, common.BigToAddress(v))
+	}
+	if len(coins) < 2 {
+		return fmt.Errorf("查询 coins 失败（不是 Curve 池子？）")
+	}
+	decimals := make([]uint8, len(coins))
+	for i, c := range coins {
+		if c == curveETH {
+			decimals[i] = 18
+			continue
+		}
+		meta, err := m.tokens.Get(ctx, c)
+		if err != nil {
+			return fmt.Errorf("查询代币 %s 的元数据失败: %w", c.Hex(), err)
+		}
+		if !meta.HasDecimals {
+			return fmt.Errorf("代币 %s 没有 decimals", c.Hex())
+		}
+		decimals[i] = meta.Decimals
+	}
+	p.coins, p.decimals = coins, decimals
+	return nil
+}
+
+// cryptoRates crypto 池子的不变量涉及 gamma 和内部价格，不在本地计算，直接用 get_dy 查询每个方向的小额兑换率
+func (m *CurveMonitor) cryptoRates(p *curvePool, balances []*big.Int, getDy func(i, j int, dx *big.Int) (*big.Int, error)) [][]float64 {
+	rates := make([][]float64, len(p.coins))
+	for i := range p.coins {
+		rates[i] = make([]float64, len(p.coins))
+		dx := curveProbeAmount(balances[i])
+		for j := range p.coins {
+			if i == j {
+				continue
+			}
+			if dy, err := getDy(i, j, dx); err == nil {
+				rates[i][j] = ratio(dy, dx)
+			}
+		}
+	}
+	return rates
+}
+
+// curveProbeAmount 计算边际兑换率使用的数量：池子余额的百万分之一，对价格几乎没有影响
+func curveProbeAmount(balance *big.Int) *big.Int {
+	dx := new(big.Int).Quo(balance, big.NewInt(1e6))
+	if dx.Sign() == 0 {
+		dx.SetInt64(1)
+	}
+	return dx
+}
+
+// rate 小额 tokenIn 换 tokenOut 的兑换率（已扣除手续费，最小单位之比），池子没有这两个币种或尚未初始化时返回 false
+func (p *curvePool) rate(tokenIn, tokenOut common.Address) (float64, bool) {
+	if !p.ready {
+		return 0, false
+	}
+	i, j := -1, -1
+	for k, c := range p.coins {
+		switch c {
+		case tokenIn:
+			i = k
+		case tokenOut:
+			j = k
+		}
+	}
+	if i < 0 || j < 0 {
+		return 0, false
+	}
+	if p.cfg.Kind == CurveCrypto {
+		return p.rates[i][j], p.rates[i][j] > 0
+	}
+	dx := curveProbeAmount(p.state.Balances[i])
+	dy := curveGetDy(p.state.Balances, p.decimals, p.state.A, p.state.Fee, i, j, dx)
+	if dy == nil || dy.Sign() <= 0 {
+		return 0, false
+	}
+	return ratio(dy, dx), true
+}
+
+// curveGetDy StableSwap 的 get_dy：余额按 decimals 统一到 18 位精度后求解不变量，扣除手续费后换回 j 的精度
+// 迭代不收敛时返回 nil
+func curveGetDy(balances []*big.Int, decimals []uint8, amp, fee *big.Int, i, j int, dx *big.Int) *big.Int {
+	mult := make([]*big.Int, len(balances))
+	xp := make([]*big.Int, len(balances))
+	for k, b := range balances {
+		mult[k] = new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(18-int(decimals[k]))), nil)
+		xp[k] = new(big.Int).Mul(b, mult[k])
+	}
+	x := new(big.Int).Add(xp[i], new(big.Int).Mul(dx, mult[i]))
+	y := curveGetY(i, j, x, xp, amp)
+	if y == nil {
+		return nil
+	}
+	// dy = (xp[j] - y - 1) / mult[j]，再扣除手续费
+	dy := new(big.Int).Sub(xp[j], y)
+	dy.Sub(dy, common.Big1).Quo(dy, mult[j])
+	charged := new(big.Int).Mul(dy, fee)
+	charged.Quo(charged, big.NewInt(curveFeeDenominator))
+	return dy.Sub(dy, charged)
+}
+
+// curveGetD 求解 StableSwap 不变量 D：A·n^n·Σx + D = A·n^n·D + D^(n+1) / (n^n·Πx)
+func curveGetD(xp []*big.Int, amp *big.Int) *big.Int {
+	n := big.NewInt(int64(len(xp)))
+	s := new(big.Int)
+	for _, x := range xp {
+		if x.Sign() == 0 {
+			return nil
+		}
+		s.Add(s, x)
+	}
+	if s.Sign() == 0 {
+		return new(big.Int)
+	}
+	ann := new(big.Int).Mul(amp, n)
+	d := new(big.Int).Set(s)
+	for it := 0; it < curveMaxIterations; it++ {
+		// D_P = D^(n+1) / (n^n·Πx)
+		dp := new(big.Int).Set(d)
+		for _, x := range xp {
+			dp.Mul(dp, d).Quo(dp, new(big.Int).Mul(x, n))
+		}
+		prev := d
+		// D = (Ann·S + D_P·n)·D / ((Ann - 1)·D + (n + 1)·D_P)
+		num := new(big.Int).Mul(ann, s)
+		num.Add(num, new(big.Int).Mul(dp, n)).Mul(num, d)
+		den := new(big.Int).Mul(new(big.Int).Sub(ann, common.Big1), d)
+		den.Add(den, new(big.Int).Mul(new(big.Int).Add(n, common.Big1), dp))
+		d = num.Quo(num, den)
+		if new(big.Int).Sub(d, prev).CmpAbs(common.Big1) <= 0 {
+			return d
+		}
+	}
+	return nil
+}
+
+// curveGetY 币种 i 的余额变为 x 后，保持 D 不变时币种 j 的余额
+func curveGetY(i, j int, x *big.Int, xp []*big.Int, amp *big.Int) *big.Int {
+	d := curveGetD(xp, amp)
+	if d == nil {
+		return nil
+	}
+	n := big.NewInt(int64(len(xp)))
+	ann := new(big.Int).Mul(amp, n)
+	c := new(big.Int).Set(d)
+	s := new(big.Int)
+	for k := range xp {
+		var xk *big.Int
+		switch k {
+		case i:
+			xk = x
+		case j:
+			continue
+		default:
+			xk = xp[k]
+		}
+		s.Add(s, xk)
+		c.Mul(c, d).Quo(c, new(big.Int).Mul(xk, n))
+	}
+	c.Mul(c, d).Quo(c, new(big.Int).Mul(ann, n))
+	b := new(big.Int).Add(s, new(big.Int).Quo(d, ann))
+	y := new(big.Int).Set(d)
+	for it := 0; it < curveMaxIterations; it++ {
+		// y = (y² + c) / (2y + b - D)
+		prev := y
+		num := new(big.Int).Mul(y, y)
+		num.Add(num, c)
+		den := new(big.Int).Lsh(y, 1)
+		den.Add(den, b).Sub(den, d)
+		if den.Sign() <= 0 {
+			return nil
+		}
+		y = num.Quo(num, den)
+		if new(big.Int).Sub(y, prev).CmpAbs(common.Big1) <= 0 {
+			return y
+		}
+	}
+	return nil
+}
+
+// States 全部已初始化池子的状态
+func (m *CurveMonitor) States() []CurvePoolState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]CurvePoolState, 0, len(m.pools))
+	for _, p := range m.pools {
+		if p.ready {
+			out = append(out, p.state)
+		}
+	}
+	return out
+}
+
+// Venues 已初始化的池子作为价差扫描的场所（当前状态的快照），原生 ETH 按 WETH 与其它场所比较
+func (m *CurveMonitor) Venues() []PriceVenue {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []PriceVenue
+	for _, p := range m.pools {
+		if p.ready {
+			out = append(out, curveVenue{pool: *p, weth: m.weth})
+		}
+	}
+	return out
+}
+
+// curveVenue Curve 池子的边际兑换率
+type curveVenue struct {
+	pool curvePool
+	weth common.Address
+}
+
+func (v curveVenue) Name() string {
+	return "Curve:" + v.pool.cfg.Name
+}
+
+func (v curveVenue) Tokens() []common.Address {
+	out := make([]common.Address, len(v.pool.coins))
+	for i, c := range v.pool.coins {
+		out[i] = c
+		if c == curveETH {
+			out[i] = v.weth
+		}
+	}
+	return out
+}
+
+func (v curveVenue) Rate(tokenIn, tokenOut common.Address) (float64, bool) {
+	for _, c := range v.pool.coins {
+		if c != curveETH {
+			continue
+		}
+		if tokenIn == v.weth {
+			tokenIn = curveETH
+		}
+		if tokenOut == v.weth {
+			tokenOut = curveETH
+		}
+	}
+	return v.pool.rate(tokenIn, tokenOut)
+}
+
+// RegisterAPI 注册 Curve 池子查询接口
+//
+//	GET /api/curve 全部池子的余额、A、手续费和 virtual price
+func (m *CurveMonitor) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/curve", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, m.States())
+	})
+}
+
+// PrintCurveExchange 默认的 Curve 兑换输出
+func PrintCurveExchange(ex CurveExchange) {
+	value := ""
+	if ex.Priced {
+		value = " (" + formatUSD(ex.ValueUSD) + ")"
+	}
+	summary := fmt.Sprintf("🌀 [Curve:%s] %s%s | %s", ex.Name, ex.Desc, value, ex.Tx.Hex())
+	Emit(Event{Type: "curve_exchange", Key: eventKey(ex.Tx, ex.LogIndex), Summary: summary, Text: summary, Data: ex})
+}
+
+// PrintCurveParamChange 默认的 Curve 参数变化输出
+func PrintCurveParamChange(c CurveParamChange) {
+	var summary string
+	if c.Param == "A" {
+		summary = fmt.Sprintf("🌀 [Curve:%s] A 参数 %s -> %s（区块 %d）", c.Name, c.Old, c.New, c.Block)
+	} else {
+		summary = fmt.Sprintf("🚨🌀 [Curve:%s] virtual price 下降 %s -> %s（区块 %d），池子可能出现亏损",
+			c.Name, formatUnits(c.Old, 18), formatUnits(c.New, 18), c.Block)
+	}
+	Emit(Event{Type: "curve_param", Key: eventKey(c.Pool, c.Block, c.Param), Summary: summary, Text: "\n" + summary, Data: c})
+}
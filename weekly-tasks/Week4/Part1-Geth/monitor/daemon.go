@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ------------------------------------------------
+// 守护进程支持：PID 文件（daemon_unix.go）、systemd 的 sd_notify（READY / WATCHDOG / STOPPING）
+// 对应的 unit 示例见 README
+// ------------------------------------------------
+
+const (
+	// 最近一次收到新区块头超过该时间视为卡住，停止 WATCHDOG 心跳，让 systemd 重启进程
+	// ⚠️ 出块很慢的测试网可以调大；主网约 12 秒一个区块
+	WatchdogMaxHeadAge = 2 * time.Minute
+)
+
+// Notifier 通过 NOTIFY_SOCKET 向 systemd 报告状态（Type=notify）
+// 不是由 systemd 启动时为 nil，所有方法都是空操作
+type Notifier struct {
+	addr     *net.UnixAddr
+	watchdog time.Duration // WatchdogSec，0 表示未开启
+}
+
+// NewNotifier 读取 systemd 传入的环境变量，没有 NOTIFY_SOCKET 时返回 nil
+func NewNotifier() *Notifier {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	if strings.HasPrefix(socket, "@") { // 抽象命名空间
+		socket = "\x00" + socket[1:]
+	}
+	n := &Notifier{addr: &net.UnixAddr{Name: socket, Net: "unixgram"}}
+	// WATCHDOG_PID 指定了其它进程时不属于本进程
+	if pid := os.Getenv("WATCHDOG_PID"); pid == "" || pid == strconv.Itoa(os.Getpid()) {
+		if usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64); err == nil && usec > 0 {
+			n.watchdog = time.Duration(usec) * time.Microsecond
+		}
+	}
+	return n
+}
+
+// Notify 发送状态，例如 "READY=1"、"STATUS=..."
+func (n *Notifier) Notify(state string) {
+	if n == nil {
+		return
+	}
+	conn, err := net.DialUnix(n.addr.Net, nil, n.addr)
+	if err != nil {
+		log.Printf("⚠️  sd_notify 失败: %v", err)
+		return
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		log.Printf("⚠️  sd_notify 失败: %v", err)
+	}
+}
+
+// RunWatchdog 按 WatchdogSec 的一半发送心跳，lastHead 超过 WatchdogMaxHeadAge 时停止心跳，
+// 订阅卡住（连接还在但不再有新区块）的进程会被 systemd 判定超时并重启
+func (n *Notifier) RunWatchdog(ctx context.Context, lastHead func() time.Time) {
+	if n == nil || n.watchdog == 0 {
+		return
+	}
+	ticker := time.NewTicker(n.watchdog / 2)
+	defer ticker.Stop()
+	stale := false
+	for {
+		select {
+		case <-ticker.C:
+			age := time.Since(lastHead())
+			if age > WatchdogMaxHeadAge {
+				if !stale {
+					log.Printf("⚠️  已经 %s 没有收到新区块，停止 watchdog 心跳", age.Round(time.Second))
+					n.Notify(fmt.Sprintf("STATUS=已经 %s 没有收到新区块", age.Round(time.Second)))
+				}
+				stale = true
+				continue
+			}
+			stale = false
+			n.Notify("WATCHDOG=1")
+		case <-ctx.Done():
+			return
+		}
+	}
+}
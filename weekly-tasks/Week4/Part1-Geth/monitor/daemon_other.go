@@ -0,0 +1,13 @@
+//go:build !unix
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// WritePIDFile 检查旧进程是否存活依赖 Unix 信号，其它平台不支持 -pidfile
+func WritePIDFile(path string) (func(), error) {
+	return nil, fmt.Errorf("当前平台 (%s) 不支持 PID 文件", runtime.GOOS)
+}
@@ -0,0 +1,36 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// WritePIDFile 写入 PID 文件，返回删除函数；文件中的进程仍在运行时返回错误，防止重复启动
+func WritePIDFile(path string) (func(), error) {
+	if data, err := os.ReadFile(path); err == nil {
+		if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && pid != os.Getpid() && processAlive(pid) {
+			return nil, fmt.Errorf("PID 文件 %s 中的进程 %d 仍在运行", path, pid)
+		}
+	}
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0o644); err != nil {
+		return nil, fmt.Errorf("写入 PID 文件失败: %w", err)
+	}
+	return func() {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("⚠️  删除 PID 文件失败: %v", err)
+		}
+	}, nil
+}
+
+// processAlive 向进程发送 0 号信号检查是否存在
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
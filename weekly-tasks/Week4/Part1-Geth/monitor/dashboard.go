@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ------------------------------------------------
+// 网页看板：监控进程自己提供一个单页看板（静态文件编译进二进制），打开 /dashboard/ 即可看到
+// 最新区块、交易池速率、gas 走势和实时事件（关注地址的交易单独一栏），不需要另外部署前端；
+// 数据通过 /api/stream（Server-Sent Events）推送，其它程序也可以直接订阅这个流
+// ------------------------------------------------
+
+const (
+	DashboardPath = "/dashboard/"
+	StreamPath    = "/api/stream"
+	// 新连接先收到最近这么多个区块 / 速率点 / 事件，图表和列表打开就有数据
+	DashboardHistory = 120
+	// 每个连接的发送队列，浏览器处理不过来时丢弃该连接的消息，不影响其它连接
+	DashboardClientBuffer = 256
+	// 交易池速率的统计间隔
+	DashboardRateInterval = 5 * time.Second
+	// 没有数据时发送注释行，避免代理断开空闲连接
+	DashboardKeepAlive = 15 * time.Second
+)
+
+//go:embed dashboard
+var dashboardAssets embed.FS
+
+// DashboardBlock 看板中的一个区块
+type DashboardBlock struct {
+	Number      uint64    `json:"number"`
+	Hash        string    `json:"hash"`
+	Time        time.Time `json:"time"`
+	Txs         int       `json:"txs"`
+	GasUsed     uint64    `json:"gas_used"`
+	GasLimit    uint64    `json:"gas_limit"`
+	BaseFeeGwei float64   `json:"base_fee_gwei"`
+	TipP50Gwei  float64   `json:"tip_p50_gwei"` // 区块内交易实际小费的中位数
+}
+
+// DashboardRate 一个统计间隔内的交易池速率
+type DashboardRate struct {
+	Time          time.Time `json:"time"`
+	PendingPerSec float64   `json:"pending_per_sec"` // 完整的 Pending 交易
+	HashesPerSec  float64   `json:"hashes_per_sec"`  // 只有 Hash 的 Pending 交易
+}
+
+// streamFrame 一条 SSE 消息
+type streamFrame struct {
+	kind string // block / mempool / event
+	data []byte
+}
+
+// Dashboard 收集区块、交易池速率和事件，推送给 /api/stream 的连接
+type Dashboard struct {
+	mu      sync.Mutex
+	clients map[chan streamFrame]bool
+	blocks  []streamFrame // 最近的区块，最旧的在前
+	rates   []streamFrame
+	events  []streamFrame
+
+	pendingTx, pendingHash atomic.Int64 // 当前统计间隔内的计数
+	dropped                atomic.Int64 // 因连接队列满丢弃的消息数
+}
+
+func NewDashboard() *Dashboard {
+	return &Dashboard{clients: make(map[chan streamFrame]bool)}
+}
+
+// Enrichment 计算小费需要区块中的交易
+func (d *Dashboard) Enrichment() EnrichLevel { return EnrichTransactions }
+
+// OnBlock 推送区块和 gas 数据
+func (d *Dashboard) OnBlock(data *BlockData) {
+	header := data.Block.Header()
+	b := DashboardBlock{
+		Number:   header.Number.Uint64(),
+		Hash:     data.Block.Hash().Hex(),
+		Time:     time.Unix(int64(header.Time), 0),
+		Txs:      len(data.Block.Transactions()),
+		GasUsed:  header.GasUsed,
+		GasLimit: header.GasLimit,
+	}
+	if header.BaseFee != nil {
+		b.BaseFeeGwei = weiToGwei(header.BaseFee)
+		tips := make([]*big.Int, 0, b.Txs)
+		for _, tx := range data.Block.Transactions() {
+			if tip, err := tx.EffectiveGasTip(header.BaseFee); err == nil {
+				tips = append(tips, tip)
+			}
+		}
+		if len(tips) > 0 {
+			sort.Slice(tips, func(i, j int) bool { return tips[i].Cmp(tips[j]) < 0 })
+			b.TipP50Gwei = weiToGwei(tips[len(tips)/2])
+		}
+	}
+	d.publish(&d.blocks, "block", b)
+}
+
+// Observe 统计交易池速率，作为事件总线的订阅者调用
+func (d *Dashboard) Observe(msg BusMessage) {
+	if msg.Kind == BusPendingTx {
+		d.pendingTx.Add(1)
+	} else {
+		d.pendingHash.Add(1)
+	}
+}
+
+// Run 每个统计间隔推送一次交易池速率，直到 ctx 取消
+func (d *Dashboard) Run(ctx context.Context) {
+	ticker := time.NewTicker(DashboardRateInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			secs := DashboardRateInterval.Seconds()
+			d.publish(&d.rates, "mempool", DashboardRate{
+				Time:          now,
+				PendingPerSec: float64(d.pendingTx.Swap(0)) / secs,
+				HashesPerSec:  float64(d.pendingHash.Swap(0)) / secs,
+			})
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// publishEvent 推送一条输出事件，由 Output.Emit 在路由之前调用，看板收到全部事件
+func (d *Dashboard) publishEvent(ev Event) {
+	d.publishRaw(&d.events, streamFrame{kind: "event", data: marshalEvent(ev, VerbosityFull)})
+}
+
+// publish 编码后推送，并保存到对应的历史中
+func (d *Dashboard) publish(history *[]streamFrame, kind string, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	d.publishRaw(history, streamFrame{kind: kind, data: b})
+}
+
+func (d *Dashboard) publishRaw(history *[]streamFrame, f streamFrame) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	*history = append(*history, f)
+	if len(*history) > DashboardHistory {
+		*history = (*history)[len(*history)-DashboardHistory:]
+	}
+	for ch := range d.clients {
+		select {
+		case ch <- f:
+		default:
+			d.dropped.Add(1)
+		}
+	}
+}
+
+// subscribe 注册一个连接，返回它的队列和连接前的历史
+func (d *Dashboard) subscribe() (chan streamFrame, []streamFrame) {
+	ch := make(chan streamFrame, DashboardClientBuffer)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clients[ch] = true
+	backlog := make([]streamFrame, 0, len(d.blocks)+len(d.rates)+len(d.events))
+	backlog = append(backlog, d.blocks...)
+	backlog = append(backlog, d.rates...)
+	backlog = append(backlog, d.events...)
+	return ch, backlog
+}
+
+func (d *Dashboard) unsubscribe(ch chan streamFrame) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.clients, ch)
+}
+
+// RegisterAPI 注册看板和事件流
+//
+//	GET /dashboard/  网页看板
+//	GET /api/stream  SSE 事件流：block / mempool / event 三种消息，连接时先补发最近的历史
+func (d *Dashboard) RegisterAPI(api *APIServer) {
+	assets, _ := fs.Sub(dashboardAssets, "dashboard")
+	api.Handle("GET "+DashboardPath, http.StripPrefix(DashboardPath, http.FileServer(http.FS(assets))).ServeHTTP)
+	api.Handle("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, DashboardPath, http.StatusFound)
+	})
+	api.Handle("GET "+StreamPath, d.handleStream)
+}
+
+func (d *Dashboard) handleStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "当前连接不支持流式响应")
+		return
+	}
+	ch, backlog := d.subscribe()
+	defer d.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx 不缓冲
+	w.WriteHeader(http.StatusOK)
+	for _, f := range backlog {
+		writeFrame(w, f)
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(DashboardKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case f := <-ch:
+			writeFrame(w, f)
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeFrame 按 SSE 格式写出一条消息，JSON 中没有换行
+func writeFrame(w http.ResponseWriter, f streamFrame) {
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", f.kind, f.data)
+}
+
+// dashboard 接收输出事件的看板，未启动 API 时为 nil
+var (
+	dashboardMu sync.RWMutex
+	dashboard   *Dashboard
+)
+
+// SetDashboard 设置接收输出事件的看板
+func SetDashboard(d *Dashboard) {
+	dashboardMu.Lock()
+	defer dashboardMu.Unlock()
+	dashboard = d
+}
+
+func currentDashboard() *Dashboard {
+	dashboardMu.RLock()
+	defer dashboardMu.RUnlock()
+	return dashboard
+}
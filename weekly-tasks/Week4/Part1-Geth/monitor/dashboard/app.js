@@ -0,0 +1,170 @@
+// 看板前端：订阅 /api/stream，不依赖任何第三方库
+// 配置了 api_auth 时在地址后加 #key=<API key>（EventSource 不能设置请求头，key 通过查询参数传给事件流）
+"use strict";
+
+const MAX_POINTS = 120; // 图表保留的点数，与服务端 DashboardHistory 一致
+const MAX_ROWS = 200;   // 每个列表保留的行数
+const WATCHED_TYPES = new Set(["watched_tx", "profile_tx"]);
+const SEVERITY_RANK = { info: 1, warn: 2, critical: 3 };
+
+const blocks = [];
+const rates = [];
+let eventCount = 0;
+
+const $ = (id) => document.getElementById(id);
+
+function streamURL() {
+  const key = new URLSearchParams(location.hash.slice(1)).get("key");
+  return key ? "../api/stream?api_key=" + encodeURIComponent(key) : "../api/stream";
+}
+
+function push(list, item) {
+  list.push(item);
+  if (list.length > MAX_POINTS) list.shift();
+}
+
+function fmt(n, digits) {
+  return Number(n).toFixed(digits);
+}
+
+function clock(t) {
+  return new Date(t).toLocaleTimeString("zh-CN", { hour12: false });
+}
+
+// drawChart 折线图，series 为 [{values, color}]
+function drawChart(canvas, series, maxValue) {
+  const ratio = window.devicePixelRatio || 1;
+  const w = canvas.clientWidth, h = canvas.clientHeight;
+  canvas.width = w * ratio;
+  canvas.height = h * ratio;
+  const ctx = canvas.getContext("2d");
+  ctx.scale(ratio, ratio);
+  ctx.clearRect(0, 0, w, h);
+
+  let max = maxValue || 0;
+  if (!maxValue) {
+    for (const s of series) for (const v of s.values) max = Math.max(max, v);
+  }
+  max = max || 1;
+  ctx.fillStyle = "#8b949e";
+  ctx.font = "10px monospace";
+  ctx.fillText(fmt(max, max < 10 ? 2 : 0), 2, 10);
+  ctx.strokeStyle = "#21262d";
+  ctx.beginPath();
+  ctx.moveTo(0, h - 0.5);
+  ctx.lineTo(w, h - 0.5);
+  ctx.stroke();
+
+  for (const s of series) {
+    if (s.values.length < 2) continue;
+    ctx.strokeStyle = s.color;
+    ctx.lineWidth = 1.5;
+    ctx.beginPath();
+    s.values.forEach((v, i) => {
+      const x = (i / (MAX_POINTS - 1)) * w;
+      const y = h - (v / max) * (h - 14);
+      if (i === 0) ctx.moveTo(x, y); else ctx.lineTo(x, y);
+    });
+    ctx.stroke();
+  }
+}
+
+function redraw() {
+  drawChart($("gas"), [
+    { values: blocks.map((b) => b.base_fee_gwei), color: "#58a6ff" },
+    { values: blocks.map((b) => b.tip_p50_gwei), color: "#f0883e" },
+  ]);
+  drawChart($("usage"), [
+    { values: blocks.map((b) => (b.gas_limit ? b.gas_used / b.gas_limit : 0)), color: "#3fb950" },
+  ], 1);
+  drawChart($("mempool"), [
+    { values: rates.map((r) => r.pending_per_sec), color: "#58a6ff" },
+    { values: rates.map((r) => r.hashes_per_sec), color: "#f0883e" },
+  ]);
+}
+
+// prepend 在列表顶部插入一行，超过 MAX_ROWS 时删除最旧的
+function prepend(list, el) {
+  list.insertBefore(el, list.firstChild);
+  while (list.children.length > MAX_ROWS) list.removeChild(list.lastChild);
+}
+
+function onBlock(b) {
+  push(blocks, b);
+  $("head").textContent = "#" + b.number;
+  $("basefee").textContent = fmt(b.base_fee_gwei, 2) + " gwei";
+
+  const tr = document.createElement("tr");
+  const cells = [
+    "#" + b.number,
+    b.txs,
+    fmt((b.gas_limit ? b.gas_used / b.gas_limit : 0) * 100, 1) + "%",
+    fmt(b.base_fee_gwei, 2),
+    fmt(b.tip_p50_gwei, 2),
+  ];
+  for (const c of cells) {
+    const td = document.createElement("td");
+    td.textContent = c;
+    tr.appendChild(td);
+  }
+  prepend($("blocks"), tr);
+}
+
+function onMempool(r) {
+  push(rates, r);
+  $("pending").textContent = fmt(r.pending_per_sec + r.hashes_per_sec, 1) + " /s";
+}
+
+function onEvent(ev) {
+  eventCount++;
+  $("events").textContent = eventCount;
+
+  const li = document.createElement("li");
+  li.className = ev.severity || "info";
+  li.dataset.severity = ev.severity || "info";
+  const t = document.createElement("time");
+  t.textContent = clock(ev.time);
+  li.appendChild(t);
+  li.appendChild(document.createTextNode(ev.summary));
+  li.title = ev.type + (ev.category ? " / " + ev.category : "");
+
+  if (WATCHED_TYPES.has(ev.type)) {
+    prepend($("watched"), li);
+  } else {
+    applyFilter(li);
+    prepend($("alerts"), li);
+  }
+}
+
+function applyFilter(li) {
+  const min = SEVERITY_RANK[$("severity").value];
+  li.hidden = (SEVERITY_RANK[li.dataset.severity] || 1) < min;
+}
+
+$("severity").addEventListener("change", () => {
+  for (const li of $("alerts").children) applyFilter(li);
+});
+
+function connect() {
+  const es = new EventSource(streamURL());
+  es.onopen = () => {
+    // 重新连接时服务端会补发历史，先清空避免重复
+    blocks.length = 0;
+    rates.length = 0;
+    for (const id of ["blocks", "watched", "alerts"]) $(id).textContent = "";
+    eventCount = 0;
+    $("conn").textContent = "已连接";
+    $("conn").className = "good";
+  };
+  es.onerror = () => {
+    $("conn").textContent = "重连中";
+    $("conn").className = "bad";
+  };
+  es.addEventListener("block", (e) => onBlock(JSON.parse(e.data)));
+  es.addEventListener("mempool", (e) => onMempool(JSON.parse(e.data)));
+  es.addEventListener("event", (e) => onEvent(JSON.parse(e.data)));
+}
+
+connect();
+setInterval(redraw, 1000);
+window.addEventListener("resize", redraw);
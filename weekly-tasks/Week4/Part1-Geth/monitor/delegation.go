@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ------------------------------------------------
+// EIP-7702 委托追踪：从 type-4（SetCode）交易的授权列表维护 EOA -> 委托合约 的登记表，
+// 关注钱包获得、更换或清除委托时提醒（交易池中的授权在上链之前就提醒）
+// ------------------------------------------------
+
+const (
+	// /api/delegations 中列出的委托合约数量
+	DelegationTopDelegates = 20
+)
+
+// DelegationConfig EIP-7702 委托追踪
+type DelegationConfig struct {
+	Enabled bool `json:"enabled"`
+}
+
+// Delegation 一次委托变化
+type Delegation struct {
+	Authority common.Address `json:"authority"` // 签名授权的 EOA
+	Delegate  common.Address `json:"delegate"`  // 新的委托合约，零地址表示清除委托
+	Previous  common.Address `json:"previous"`  // 之前的委托合约，零地址表示之前没有
+	Block     uint64         `json:"block,omitempty"`
+	TxHash    common.Hash    `json:"tx_hash"`
+	Submitter common.Address `json:"submitter"` // 发送 type-4 交易的地址，与 Authority 不同时说明由第三方代为提交
+	Pending   bool           `json:"pending"`
+	Watched   string         `json:"watched,omitempty"`
+}
+
+// DelegateCount 委托给同一个合约的 EOA 数量
+type DelegateCount struct {
+	Delegate common.Address `json:"delegate"`
+	Accounts int            `json:"accounts"`
+}
+
+// DelegationTracker 维护 EOA 的委托登记表
+// ⚠️ 授权在执行时才检查 nonce，无效的授权会被跳过且没有日志；关注钱包的委托上链后用 eth_getCode 确认，其它地址按授权内容记录
+type DelegationTracker struct {
+	client  *ethclient.Client
+	pool    *EndpointPool // 未配置备用节点时为 nil
+	signer  types.Signer
+	chainID *big.Int
+	watch   *Watchlist
+
+	mu        sync.RWMutex
+	delegates map[common.Address]common.Address // authority -> 委托合约
+
+	onChange func(Delegation)
+}
+
+// NewDelegationTracker 创建追踪器，onChange 为 nil 时使用默认输出
+func NewDelegationTracker(client *ethclient.Client, signer types.Signer, chainID *big.Int, watch *Watchlist, onChange func(Delegation)) *DelegationTracker {
+	if onChange == nil {
+		onChange = PrintDelegation
+	}
+	return &DelegationTracker{
+		client:    client,
+		signer:    signer,
+		chainID:   chainID,
+		watch:     watch,
+		delegates: make(map[common.Address]common.Address),
+		onChange:  onChange,
+	}
+}
+
+// SetEndpoints 代码查询改由 pool 选择节点，需要在注册到 BlockFetcher 之前调用
+func (t *DelegationTracker) SetEndpoints(pool *EndpointPool) {
+	t.pool = pool
+}
+
+// Seed 读取关注钱包当前的代码，记录已有的委托（启动前设置的委托不会出现在之后的区块中）
+func (t *DelegationTracker) Seed(ctx context.Context) {
+	for _, addr := range t.watch.BySource(WatchSourceConfig) {
+		if delegate, ok := t.codeDelegate(ctx, addr, nil); ok && delegate != (common.Address{}) {
+			t.mu.Lock()
+			t.delegates[addr] = delegate
+			t.mu.Unlock()
+			log.Printf("🪪 关注钱包 %s 当前委托给 %s", addr.Hex(), delegate.Hex())
+		}
+	}
+}
+
+// codeDelegate 查询地址的代码，返回其中的委托合约；没有代码时返回零地址，普通合约或查询失败时返回 false
+func (t *DelegationTracker) codeDelegate(ctx context.Context, addr common.Address, block *big.Int) (common.Address, bool) {
+	ctx, cancel := context.WithTimeout(ctx, CONNECTION_TIMEOUT)
+	defer cancel()
+	var code []byte
+	err := poolCall(ctx, t.pool, t.client, "eth_getCode", func(c *ethclient.Client) (err error) {
+		code, err = c.CodeAt(ctx, addr, block)
+		return err
+	})
+	if err != nil {
+		return common.Address{}, false
+	}
+	if len(code) == 0 {
+		return common.Address{}, true
+	}
+	return types.ParseDelegation(code)
+}
+
+// authorizations type-4 交易中对本链有效的授权（chainId 为 0 表示所有链），返回签名者和委托合约
+func (t *DelegationTracker) authorizations(tx *types.Transaction) (authorities, delegates []common.Address) {
+	for _, auth := range tx.SetCodeAuthorizations() {
+		if !auth.ChainID.IsZero() && auth.ChainID.ToBig().Cmp(t.chainID) != 0 {
+			continue
+		}
+		authority, err := auth.Authority()
+		if err != nil {
+			continue
+		}
+		authorities = append(authorities, authority)
+		delegates = append(delegates, auth.Address)
+	}
+	return authorities, delegates
+}
+
+// Enrichment 实现 Enricher：只看交易和发送者，不需要收据
+func (t *DelegationTracker) Enrichment() EnrichLevel { return EnrichTransactions }
+
+// OnBlock 实现 BlockAnalyzer：按顺序应用区块中的授权（同一个 EOA 以最后一个为准）
+func (t *DelegationTracker) OnBlock(data *BlockData) {
+	number := data.Block.Number()
+	for i, tx := range data.Block.Transactions() {
+		if tx.Type() != types.SetCodeTxType {
+			continue
+		}
+		authorities, delegates := t.authorizations(tx)
+		for k, authority := range authorities {
+			d := Delegation{Authority: authority, Delegate: delegates[k], Block: number.Uint64(), TxHash: tx.Hash(), Submitter: data.Senders[i]}
+			d.Watched = t.watch.Source(authority)
+			if d.Watched != "" {
+				// 以链上实际的代码为准：nonce 不匹配的授权不会生效
+				actual, ok := t.codeDelegate(context.Background(), authority, number)
+				if !ok {
+					continue
+				}
+				d.Delegate = actual
+			}
+			if prev, changed := t.apply(authority, d.Delegate); changed {
+				d.Previous = prev
+				if d.Watched != "" {
+					t.onChange(d)
+				}
+			}
+		}
+	}
+}
+
+// apply 更新登记表，返回之前的委托合约以及是否有变化
+func (t *DelegationTracker) apply(authority, delegate common.Address) (common.Address, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	prev := t.delegates[authority]
+	if prev == delegate {
+		return prev, false
+	}
+	if delegate == (common.Address{}) {
+		delete(t.delegates, authority)
+	} else {
+		t.delegates[authority] = delegate
+	}
+	return prev, true
+}
+
+// Observe 交易池中签名者是关注钱包的授权，上链之前提醒（只处理完整交易）
+func (t *DelegationTracker) Observe(msg BusMessage) {
+	tx := msg.Tx
+	if tx == nil || tx.Type() != types.SetCodeTxType {
+		return
+	}
+	authorities, delegates := t.authorizations(tx)
+	for k, authority := range authorities {
+		watched := t.watch.Source(authority)
+		if watched == "" {
+			continue
+		}
+		submitter, err := types.Sender(t.signer, tx)
+		if err != nil {
+			return
+		}
+		t.mu.RLock()
+		prev := t.delegates[authority]
+		t.mu.RUnlock()
+		if prev == delegates[k] {
+			continue
+		}
+		t.onChange(Delegation{Authority: authority, Delegate: delegates[k], Previous: prev, TxHash: tx.Hash(), Submitter: submitter, Pending: true, Watched: watched})
+	}
+}
+
+// Delegate 地址当前的委托合约
+func (t *DelegationTracker) Delegate(addr common.Address) (common.Address, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	d, ok := t.delegates[addr]
+	return d, ok
+}
+
+// TopDelegates 被委托最多的合约
+func (t *DelegationTracker) TopDelegates(limit int) (int, []DelegateCount) {
+	t.mu.RLock()
+	counts := make(map[common.Address]int)
+	for _, d := range t.delegates {
+		counts[d]++
+	}
+	total := len(t.delegates)
+	t.mu.RUnlock()
+
+	out := make([]DelegateCount, 0, len(counts))
+	for d, n := range counts {
+		out = append(out, DelegateCount{Delegate: d, Accounts: n})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Accounts > out[j].Accounts })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return total, out
+}
+
+// RegisterAPI 注册委托查询接口
+//
+//	GET /api/delegations            启动以来观察到的委托数量和被委托最多的合约
+//	GET /api/delegations/{address}  地址当前的委托合约
+func (t *DelegationTracker) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/delegations", func(w http.ResponseWriter, r *http.Request) {
+		total, top := t.TopDelegates(DelegationTopDelegates)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"accounts": total, "top_delegates": top})
+	})
+	api.Handle("GET /api/delegations/{address}", func(w http.ResponseWriter, r *http.Request) {
+		if !common.IsHexAddress(r.PathValue("address")) {
+			writeError(w, http.StatusBadRequest, "地址格式错误")
+			return
+		}
+		d, ok := t.Delegate(common.HexToAddress(r.PathValue("address")))
+		if !ok {
+			writeError(w, http.StatusNotFound, "没有观察到该地址的委托")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"delegate": d})
+	})
+}
+
+// PrintDelegation 默认的委托变化提醒
+func PrintDelegation(d Delegation) {
+	where := fmt.Sprintf("区块 %d", d.Block)
+	if d.Pending {
+		where = "Pending"
+	}
+	var change string
+	switch {
+	case d.Delegate == (common.Address{}):
+		change = fmt.Sprintf("清除了委托（之前为 %s）", d.Previous.Hex())
+	case d.Previous == (common.Address{}):
+		change = fmt.Sprintf("委托给 %s", d.Delegate.Hex())
+	default:
+		change = fmt.Sprintf("委托从 %s 改为 %s", d.Previous.Hex(), d.Delegate.Hex())
+	}
+	summary := fmt.Sprintf("🪪 [EIP-7702][Watched:%s] %s | %s %s | %s", d.Watched, where, d.Authority.Hex(), change, d.TxHash.Hex())
+	text := summary
+	if d.Submitter != d.Authority {
+		text += fmt.Sprintf("\n   ⚠️  授权由 %s 提交：委托合约可以完全控制该账户的资产，确认不是钓鱼签名", d.Submitter.Hex())
+	}
+	Emit(Event{Type: "delegation", Key: eventKey(d.TxHash, d.Authority, d.Pending), Summary: summary, Text: text, Data: d})
+}
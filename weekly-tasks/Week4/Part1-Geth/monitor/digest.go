@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ------------------------------------------------
+// 汇总摘要：把定时汇总报告渲染成 Markdown 或 HTML 文件写到磁盘，
+// 给不看仪表盘的人每天一份链上活动摘要；生成后再输出一条 digest 事件（带文件路径），
+// webhook 等输出目标可以据此转发或通知
+// ------------------------------------------------
+
+const (
+	DigestMarkdown = "markdown"
+	DigestHTML     = "html"
+)
+
+// DigestEvent 一份摘要已生成
+type DigestEvent struct {
+	Window string `json:"window"`
+	Path   string `json:"path"`
+	Format string `json:"format"`
+}
+
+// digestView 模板使用的数据：报告本身加上合约的显示名称
+type digestView struct {
+	SummaryReport
+	Labels map[common.Address]string
+}
+
+// Label 合约的显示名称，代币显示符号，其它显示地址
+func (v digestView) Label(addr common.Address) string {
+	if l, ok := v.Labels[addr]; ok {
+		return l
+	}
+	return addr.Hex()
+}
+
+const digestMarkdownTemplate = `# 链上活动摘要（{{.Window}}）
+
+{{.From.Format "2006-01-02 15:04"}} - {{.To.Format "2006-01-02 15:04"}}{{if .Partial}}（窗口不完整）{{end}}
+
+| 指标 | 数值 |
+| --- | --- |
+| 区块 | {{.Blocks}}{{if .Blocks}}（{{.FirstBlock}} - {{.LastBlock}}）{{end}} |
+| 交易 | {{.Txs}} |
+| 平均每块 gas | {{.AvgGasUsed}} |
+| Gas 利用率 | {{printf "%.1f" .AvgGasPct}}% |
+| 平均 BaseFee | {{printf "%.2f" .AvgBaseFee}} gwei |
+| 交易池吞吐 | {{printf "%.0f" .PendingPerMin}} 笔/分钟（共 {{.Pending}} 笔） |
+{{if .TopContracts}}
+## 调用最多的合约
+
+| # | 合约 | 调用次数 |
+| --- | --- | --- |
+{{range $i, $c := .TopContracts}}| {{inc $i}} | {{$.Label $c.Address}} | {{$c.Calls}} |
+{{end}}{{end}}{{if .Watched}}
+## 关注地址活动
+
+| 地址 | 发出 | 发出 ETH | 收到 | 收到 ETH |
+| --- | --- | --- | --- | --- |
+{{range .Watched}}| {{.Address.Hex}} | {{.Sent}} | {{printf "%.4f" .SentETH}} | {{.Received}} | {{printf "%.4f" .ReceivedETH}} |
+{{end}}{{end}}`
+
+const digestHTMLTemplate = `<!DOCTYPE html>
+<html lang="zh">
+<head>
+<meta charset="utf-8">
+<title>链上活动摘要（{{.Window}}）</title>
+<style>
+body { font-family: sans-serif; max-width: 960px; margin: 2em auto; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; text-align: left; }
+th { background: #f4f4f4; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+code { font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>链上活动摘要（{{.Window}}）</h1>
+<p>{{.From.Format "2006-01-02 15:04"}} - {{.To.Format "2006-01-02 15:04"}}{{if .Partial}}（窗口不完整）{{end}}</p>
+<table>
+<tr><th>指标</th><th>数值</th></tr>
+<tr><td>区块</td><td class="num">{{.Blocks}}{{if .Blocks}}（{{.FirstBlock}} - {{.LastBlock}}）{{end}}</td></tr>
+<tr><td>交易</td><td class="num">{{.Txs}}</td></tr>
+<tr><td>平均每块 gas</td><td class="num">{{.AvgGasUsed}}</td></tr>
+<tr><td>Gas 利用率</td><td class="num">{{printf "%.1f" .AvgGasPct}}%</td></tr>
+<tr><td>平均 BaseFee</td><td class="num">{{printf "%.2f" .AvgBaseFee}} gwei</td></tr>
+<tr><td>交易池吞吐</td><td class="num">{{printf "%.0f" .PendingPerMin}} 笔/分钟（共 {{.Pending}} 笔）</td></tr>
+</table>
+{{if .TopContracts}}<h2>调用最多的合约</h2>
+<table>
+<tr><th>#</th><th>合约</th><th>调用次数</th></tr>
+{{range $i, $c := .TopContracts}}<tr><td class="num">{{inc $i}}</td><td><code>{{$.Label $c.Address}}</code></td><td class="num">{{$c.Calls}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Watched}}<h2>关注地址活动</h2>
+<table>
+<tr><th>地址</th><th>发出</th><th>发出 ETH</th><th>收到</th><th>收到 ETH</th></tr>
+{{range .Watched}}<tr><td><code>{{.Address.Hex}}</code></td><td class="num">{{.Sent}}</td><td class="num">{{printf "%.4f" .SentETH}}</td><td class="num">{{.Received}}</td><td class="num">{{printf "%.4f" .ReceivedETH}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`
+
+// DigestWriter 把汇总报告渲染为摘要文件
+type DigestWriter struct {
+	cfg    ReportsConfig
+	tokens *TokenCache // 可以为 nil，此时合约只显示地址
+
+	render  func(v digestView) ([]byte, error)
+	windows map[int]bool // 为空时所有窗口都生成摘要
+}
+
+// NewDigestWriter 创建摘要生成器；HTML 用 html/template 渲染，代币符号等外部数据会被转义
+func NewDigestWriter(cfg ReportsConfig, tokens *TokenCache) *DigestWriter {
+	funcs := map[string]interface{}{"inc": func(i int) int { return i + 1 }}
+	d := &DigestWriter{cfg: cfg, tokens: tokens, windows: make(map[int]bool)}
+	for _, minutes := range cfg.DigestWindows {
+		d.windows[minutes] = true
+	}
+	if cfg.DigestFormat == DigestHTML {
+		tmpl := htmltemplate.Must(htmltemplate.New("digest").Funcs(funcs).Parse(digestHTMLTemplate))
+		d.render = func(v digestView) ([]byte, error) {
+			var buf bytes.Buffer
+			err := tmpl.Execute(&buf, v)
+			return buf.Bytes(), err
+		}
+	} else {
+		tmpl := template.Must(template.New("digest").Funcs(funcs).Parse(digestMarkdownTemplate))
+		d.render = func(v digestView) ([]byte, error) {
+			var buf bytes.Buffer
+			err := tmpl.Execute(&buf, v)
+			return buf.Bytes(), err
+		}
+	}
+	return d
+}
+
+// Handle 窗口需要摘要时渲染并写入 digest_dir，失败只记录日志
+func (d *DigestWriter) Handle(r SummaryReport) {
+	if len(d.windows) > 0 && !d.windows[r.Minutes] {
+		return
+	}
+	path, err := d.Write(r)
+	if err != nil {
+		log.Printf("⚠️  生成 %s 摘要失败: %v", r.Window, err)
+		return
+	}
+	PrintDigestEvent(DigestEvent{Window: r.Window, Path: path, Format: d.cfg.DigestFormat})
+}
+
+// Write 渲染报告并写入文件，返回文件路径；文件名包含窗口和结束时间，例如 digest-1d-20261015-0000.md
+func (d *DigestWriter) Write(r SummaryReport) (string, error) {
+	view := digestView{SummaryReport: r, Labels: make(map[common.Address]string)}
+	if d.tokens != nil {
+		for _, c := range r.TopContracts {
+			if meta, ok := d.tokens.Lookup(c.Address); ok {
+				view.Labels[c.Address] = fmt.Sprintf("%s (%s)", meta.Label(), c.Address.Hex())
+			}
+		}
+	}
+	out, err := d.render(view)
+	if err != nil {
+		return "", fmt.Errorf("渲染摘要失败: %w", err)
+	}
+	if err := os.MkdirAll(d.cfg.DigestDir, 0o755); err != nil {
+		return "", fmt.Errorf("创建摘要目录失败: %w", err)
+	}
+	ext := ".md"
+	if d.cfg.DigestFormat == DigestHTML {
+		ext = ".html"
+	}
+	path := filepath.Join(d.cfg.DigestDir, fmt.Sprintf("digest-%s-%s%s", r.Window, r.To.UTC().Format("20060102-1504"), ext))
+	if err := os.WriteFile(path, out, 0o644); err != nil {
+		return "", fmt.Errorf("写入摘要失败: %w", err)
+	}
+	return path, nil
+}
+
+// PrintDigestEvent 默认的摘要生成输出
+func PrintDigestEvent(e DigestEvent) {
+	summary := fmt.Sprintf("📰 [Digest] 已生成 %s 摘要: %s", e.Window, e.Path)
+	Emit(Event{Type: "digest", Summary: summary, Text: summary, Data: e})
+}
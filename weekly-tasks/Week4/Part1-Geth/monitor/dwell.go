@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// 首次看到之后超过这个时间仍未上链的交易不再跟踪（被替换、丢弃或出价太低）
+	DwellMaxAge = time.Hour
+	// 每隔多少个区块输出一次统计
+	DwellReportEvery = 100
+)
+
+// firstSeenTx 一笔 Pending 交易的首次送达记录
+type firstSeenTx struct {
+	at   time.Time
+	head uint64 // 首次看到时已处理的最新区块高度
+}
+
+// FeeLevelReport 一个出价档位的停留时间统计
+type FeeLevelReport struct {
+	Level       string        `json:"level"`
+	Count       int           `json:"count"`
+	Mean        time.Duration `json:"mean"`
+	P50         time.Duration `json:"p50"`
+	P95         time.Duration `json:"p95"`
+	MeanBlocks  float64       `json:"mean_blocks"`
+	MeanPaidTip float64       `json:"mean_paid_tip_gwei"`
+}
+
+// DwellReport 交易池停留时间报告
+type DwellReport struct {
+	Levels   []FeeLevelReport `json:"levels"`
+	Tracking int              `json:"tracking"` // 仍在等待上链的交易数
+	Mined    int              `json:"mined"`    // 期间上链、且之前在交易池中见过的交易数
+	Unseen   int              `json:"unseen"`   // 期间上链、但从未在交易池中见过的交易数
+	Expired  int              `json:"expired"`  // 超过 DwellMaxAge 未上链、停止跟踪的交易数
+}
+
+// DwellTracker 记录每笔 Pending 交易首次被看到的时间（合并所有交易池来源），
+// 交易上链时计算在交易池中的停留时间，按实际支付的优先费分档汇总，量化出价与上链速度的关系
+// 与 InclusionTracker 不同，统计的是所有交易而不只是关注地址的交易
+type DwellTracker struct {
+	mu                sync.Mutex
+	seen              map[common.Hash]firstSeenTx
+	levels            []*feeLevelStats
+	head              uint64
+	mined             int
+	unseen            int
+	expired           int
+	blocksSinceReport int
+
+	dwell metrics.Histogram
+}
+
+func NewDwellTracker() *DwellTracker {
+	return &DwellTracker{
+		seen:   make(map[common.Hash]firstSeenTx),
+		levels: newFeeLevels(),
+		dwell:  newLagHistogram("monitor/mempool/dwell_ms"),
+	}
+}
+
+// Observe 记录交易首次被看到的时间（事件总线上每笔交易只发布一次，完整交易补发时保留最早的时间）
+func (d *DwellTracker) Observe(msg BusMessage) {
+	hash := msg.Hash
+	if msg.Tx != nil {
+		hash = msg.Tx.Hash()
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.seen[hash]; !ok {
+		d.seen[hash] = firstSeenTx{at: msg.Received, head: d.head}
+	}
+}
+
+// FirstSeen 交易首次被看到的时间（上链或停止跟踪后返回 false）
+func (d *DwellTracker) FirstSeen(hash common.Hash) (time.Time, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s, ok := d.seen[hash]
+	return s.at, ok
+}
+
+// OnBlock 实现 BlockAnalyzer：区块中在交易池见过的交易计算停留时间，并清理超时的交易
+func (d *DwellTracker) OnBlock(data *BlockData) {
+	header := data.Block.Header()
+	blockTime := time.Unix(int64(header.Time), 0)
+	number := header.Number.Uint64()
+
+	d.mu.Lock()
+	d.head = number
+	for i, tx := range data.Block.Transactions() {
+		s, ok := d.seen[tx.Hash()]
+		if !ok {
+			d.unseen++
+			continue
+		}
+		delete(d.seen, tx.Hash())
+		d.mined++
+		// 区块时间只精确到秒，同一秒内看到并被打包时可能为负
+		dwell := max(blockTime.Sub(s.at), 0)
+		var blocks uint64
+		if number > s.head {
+			blocks = number - s.head
+		}
+		paidTip := new(big.Int).Set(data.Receipts[i].EffectiveGasPrice)
+		if header.BaseFee != nil {
+			paidTip.Sub(paidTip, header.BaseFee)
+		}
+		feeLevelFor(d.levels, paidTip).add(dwell, blocks, weiToGwei(paidTip))
+		d.dwell.Update(dwell.Milliseconds())
+	}
+	for hash, s := range d.seen {
+		if blockTime.Sub(s.at) > DwellMaxAge {
+			delete(d.seen, hash)
+			d.expired++
+		}
+	}
+	d.blocksSinceReport++
+	report := d.blocksSinceReport >= DwellReportEvery
+	if report {
+		d.blocksSinceReport = 0
+	}
+	d.mu.Unlock()
+
+	if report {
+		PrintDwellReport(d.Report())
+	}
+}
+
+// Report 当前的统计
+func (d *DwellTracker) Report() DwellReport {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	r := DwellReport{Tracking: len(d.seen), Mined: d.mined, Unseen: d.unseen, Expired: d.expired}
+	for _, lv := range d.levels {
+		if lv.count == 0 {
+			continue
+		}
+		r.Levels = append(r.Levels, FeeLevelReport{
+			Level:       lv.label,
+			Count:       lv.count,
+			Mean:        mean(lv.latencies),
+			P50:         percentile(lv.latencies, 0.5),
+			P95:         percentile(lv.latencies, 0.95),
+			MeanBlocks:  float64(lv.blocksTotal) / float64(lv.count),
+			MeanPaidTip: lv.paidTipTotal / float64(lv.count),
+		})
+	}
+	return r
+}
+
+// RegisterAPI 注册停留时间查询接口
+//
+//	GET /api/mempool/dwell 各出价档位的停留时间统计（时间单位为纳秒）
+func (d *DwellTracker) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/mempool/dwell", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, d.Report())
+	})
+}
+
+// PrintDwellReport 默认的停留时间报告输出
+func PrintDwellReport(r DwellReport) {
+	var sb strings.Builder
+	summary := fmt.Sprintf("⏳ [Mempool Dwell] 上链 %d 笔（交易池中未见过 %d 笔），仍在跟踪 %d 笔", r.Mined, r.Unseen, r.Tracking)
+	sb.WriteString("\n" + summary + "\n")
+	fmt.Fprintf(&sb, "   %-14s %8s %10s %10s %10s %10s %12s\n", "实付tip档位", "样本", "平均停留", "P50", "P95", "平均区块", "平均实付tip")
+	for _, lv := range r.Levels {
+		fmt.Fprintf(&sb, "   %-14s %8d %10s %10s %10s %10.1f %10.2f gwei\n",
+			lv.Level, lv.Count, lv.Mean.Round(time.Second), lv.P50.Round(time.Second), lv.P95.Round(time.Second),
+			lv.MeanBlocks, lv.MeanPaidTip)
+	}
+	if len(r.Levels) == 0 {
+		sb.WriteString("   （暂无样本）\n")
+	}
+	Emit(Event{Type: "dwell_report", Summary: summary, Text: sb.String(), Data: r})
+}
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// ------------------------------------------------
+// EIP-712 结构化签名工具
+// 既用于生成我们自己的签名（Permit / Permit2 / Seaport 订单），
+// 也用于校验在 mempool calldata 中观察到的签名到底是谁签的
+// ------------------------------------------------
+
+var (
+	// Permit2 在所有 EVM 链上的部署地址相同
+	Permit2Address = common.HexToAddress("0x000000000022D473030F116dDEE9F6B43aC78BA3")
+	// Seaport 1.6
+	SeaportAddress = common.HexToAddress("0x0000000000000068F116a894984e2DB1123eB395")
+)
+
+const SeaportVersion = "1.6"
+
+// HashTypedData 计算 EIP-712 签名摘要 keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message))
+func HashTypedData(td apitypes.TypedData) (common.Hash, error) {
+	hash, _, err := apitypes.TypedDataAndHash(td)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("计算 EIP-712 哈希失败: %w", err)
+	}
+	return common.BytesToHash(hash), nil
+}
+
+// RecoverTypedDataSigner 从签名中恢复签名者地址
+// 支持 65 字节签名（v 为 0/1 或 27/28）和 64 字节的 EIP-2098 紧凑签名（Seaport 常用）
+func RecoverTypedDataSigner(td apitypes.TypedData, sig []byte) (common.Address, error) {
+	hash, err := HashTypedData(td)
+	if err != nil {
+		return common.Address{}, err
+	}
+	normalized, err := normalizeSignature(sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	pub, err := crypto.SigToPub(hash.Bytes(), normalized)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("恢复公钥失败: %w", err)
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// VerifyTypedData 校验签名是否由 expected 签出
+func VerifyTypedData(td apitypes.TypedData, sig []byte, expected common.Address) error {
+	signer, err := RecoverTypedDataSigner(td, sig)
+	if err != nil {
+		return err
+	}
+	if signer != expected {
+		return fmt.Errorf("签名者不匹配: 期望 %s，实际 %s", expected.Hex(), signer.Hex())
+	}
+	return nil
+}
+
+// normalizeSignature 把各种格式的签名统一为 crypto 包要求的 [R ‖ S ‖ V]，V 为 0/1
+func normalizeSignature(sig []byte) ([]byte, error) {
+	switch len(sig) {
+	case 65:
+		out := common.CopyBytes(sig)
+		if out[64] >= 27 {
+			out[64] -= 27
+		}
+		if out[64] > 1 {
+			return nil, fmt.Errorf("签名 v 值非法: %d", sig[64])
+		}
+		return out, nil
+	case 64:
+		// EIP-2098: yParityAndS 的最高位是 v，剩余 255 位是 s
+		out := make([]byte, 65)
+		copy(out[:32], sig[:32])
+		copy(out[32:64], sig[32:])
+		out[64] = out[32] >> 7
+		out[32] &= 0x7f
+		return out, nil
+	default:
+		return nil, fmt.Errorf("签名长度非法: %d（应为 64 或 65 字节）", len(sig))
+	}
+}
+
+// ------------------------------------------------
+// 常见的 EIP-712 消息构造
+// ------------------------------------------------
+
+var eip712DomainType = []apitypes.Type{
+	{Name: "name", Type: "string"},
+	{Name: "version", Type: "string"},
+	{Name: "chainId", Type: "uint256"},
+	{Name: "verifyingContract", Type: "address"},
+}
+
+// Permit2 的 domain 没有 version 字段
+var eip712DomainNoVersionType = []apitypes.Type{
+	{Name: "name", Type: "string"},
+	{Name: "chainId", Type: "uint256"},
+	{Name: "verifyingContract", Type: "address"},
+}
+
+// ERC2612Permit ERC-20 permit(owner, spender, value, deadline, v, r, s) 对应的签名内容
+type ERC2612Permit struct {
+	Token    common.Address // verifyingContract
+	Name     string         // token 的 EIP-712 domain name（通常等于 name()）
+	Version  string         // domain version，大多数 token 为 "1"（USDC 为 "2"）
+	ChainID  *big.Int
+	Owner    common.Address
+	Spender  common.Address
+	Value    *big.Int
+	Nonce    *big.Int
+	Deadline *big.Int
+}
+
+// NewPermitTypedData 构造 ERC-2612 Permit 的 TypedData
+func NewPermitTypedData(p ERC2612Permit) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": eip712DomainType,
+			"Permit": {
+				{Name: "owner", Type: "address"},
+				{Name: "spender", Type: "address"},
+				{Name: "value", Type: "uint256"},
+				{Name: "nonce", Type: "uint256"},
+				{Name: "deadline", Type: "uint256"},
+			},
+		},
+		PrimaryType: "Permit",
+		Domain: apitypes.TypedDataDomain{
+			Name:              p.Name,
+			Version:           p.Version,
+			ChainId:           (*math.HexOrDecimal256)(p.ChainID),
+			VerifyingContract: p.Token.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"owner":    p.Owner.Hex(),
+			"spender":  p.Spender.Hex(),
+			"value":    p.Value,
+			"nonce":    p.Nonce,
+			"deadline": p.Deadline,
+		},
+	}
+}
+
+func permit2Domain(chainID *big.Int) apitypes.TypedDataDomain {
+	return apitypes.TypedDataDomain{
+		Name:              "Permit2",
+		ChainId:           (*math.HexOrDecimal256)(chainID),
+		VerifyingContract: Permit2Address.Hex(),
+	}
+}
+
+// Permit2Single AllowanceTransfer.permit(owner, PermitSingle, signature) 的签名内容
+type Permit2Single struct {
+	ChainID     *big.Int
+	Token       common.Address
+	Amount      *big.Int // uint160
+	Expiration  *big.Int // uint48
+	Nonce       *big.Int // uint48
+	Spender     common.Address
+	SigDeadline *big.Int
+}
+
+// NewPermit2SingleTypedData 构造 Permit2 PermitSingle 的 TypedData
+func NewPermit2SingleTypedData(p Permit2Single) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": eip712DomainNoVersionType,
+			"PermitSingle": {
+				{Name: "details", Type: "PermitDetails"},
+				{Name: "spender", Type: "address"},
+				{Name: "sigDeadline", Type: "uint256"},
+			},
+			"PermitDetails": {
+				{Name: "token", Type: "address"},
+				{Name: "amount", Type: "uint160"},
+				{Name: "expiration", Type: "uint48"},
+				{Name: "nonce", Type: "uint48"},
+			},
+		},
+		PrimaryType: "PermitSingle",
+		Domain:      permit2Domain(p.ChainID),
+		Message: apitypes.TypedDataMessage{
+			"details": map[string]interface{}{
+				"token":      p.Token.Hex(),
+				"amount":     p.Amount,
+				"expiration": p.Expiration,
+				"nonce":      p.Nonce,
+			},
+			"spender":     p.Spender.Hex(),
+			"sigDeadline": p.SigDeadline,
+		},
+	}
+}
+
+// Permit2TransferFrom SignatureTransfer.permitTransferFrom 的签名内容
+// 注意：spender 不在 calldata 里，而是调用 Permit2 的 msg.sender
+type Permit2TransferFrom struct {
+	ChainID  *big.Int
+	Token    common.Address
+	Amount   *big.Int
+	Spender  common.Address
+	Nonce    *big.Int
+	Deadline *big.Int
+}
+
+// NewPermit2TransferFromTypedData 构造 Permit2 PermitTransferFrom 的 TypedData
+func NewPermit2TransferFromTypedData(p Permit2TransferFrom) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": eip712DomainNoVersionType,
+			"PermitTransferFrom": {
+				{Name: "permitted", Type: "TokenPermissions"},
+				{Name: "spender", Type: "address"},
+				{Name: "nonce", Type: "uint256"},
+				{Name: "deadline", Type: "uint256"},
+			},
+			"TokenPermissions": {
+				{Name: "token", Type: "address"},
+				{Name: "amount", Type: "uint256"},
+			},
+		},
+		PrimaryType: "PermitTransferFrom",
+		Domain:      permit2Domain(p.ChainID),
+		Message: apitypes.TypedDataMessage{
+			"permitted": map[string]interface{}{
+				"token":  p.Token.Hex(),
+				"amount": p.Amount,
+			},
+			"spender":  p.Spender.Hex(),
+			"nonce":    p.Nonce,
+			"deadline": p.Deadline,
+		},
+	}
+}
+
+// SafeTx Safe 多签 execTransaction 的签名内容，签名摘要即 ExecutionSuccess / ExecutionFailure 中的 txHash
+type SafeTx struct {
+	ChainID        *big.Int // Safe 1.3.0 之前的版本 domain 中没有 chainId，此时留空
+	Safe           common.Address
+	To             common.Address
+	Value          *big.Int
+	Data           []byte
+	Operation      uint8 // 0 CALL, 1 DELEGATECALL
+	SafeTxGas      *big.Int
+	BaseGas        *big.Int
+	GasPrice       *big.Int
+	GasToken       common.Address
+	RefundReceiver common.Address
+	Nonce          *big.Int
+}
+
+// NewSafeTxTypedData 构造 Safe SafeTx 的 TypedData
+func NewSafeTxTypedData(t SafeTx) apitypes.TypedData {
+	domainType := []apitypes.Type{{Name: "verifyingContract", Type: "address"}}
+	domain := apitypes.TypedDataDomain{VerifyingContract: t.Safe.Hex()}
+	if t.ChainID != nil {
+		domainType = append([]apitypes.Type{{Name: "chainId", Type: "uint256"}}, domainType...)
+		domain.ChainId = (*math.HexOrDecimal256)(t.ChainID)
+	}
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": domainType,
+			"SafeTx": {
+				{Name: "to", Type: "address"},
+				{Name: "value", Type: "uint256"},
+				{Name: "data", Type: "bytes"},
+				{Name: "operation", Type: "uint8"},
+				{Name: "safeTxGas", Type: "uint256"},
+				{Name: "baseGas", Type: "uint256"},
+				{Name: "gasPrice", Type: "uint256"},
+				{Name: "gasToken", Type: "address"},
+				{Name: "refundReceiver", Type: "address"},
+				{Name: "nonce", Type: "uint256"},
+			},
+		},
+		PrimaryType: "SafeTx",
+		Domain:      domain,
+		Message: apitypes.TypedDataMessage{
+			"to":             t.To.Hex(),
+			"value":          t.Value,
+			"data":           t.Data,
+			"operation":      big.NewInt(int64(t.Operation)),
+			"safeTxGas":      t.SafeTxGas,
+			"baseGas":        t.BaseGas,
+			"gasPrice":       t.GasPrice,
+			"gasToken":       t.GasToken.Hex(),
+			"refundReceiver": t.RefundReceiver.Hex(),
+			"nonce":          t.Nonce,
+		},
+	}
+}
+
+// SeaportItem Seaport 订单中的 offer / consideration 条目
+// Recipient 只在 consideration 中使用
+type SeaportItem struct {
+	ItemType             uint8 // 0 ETH, 1 ERC20, 2 ERC721, 3 ERC1155, 4/5 带 criteria 的 NFT
+	Token                common.Address
+	IdentifierOrCriteria *big.Int
+	StartAmount          *big.Int
+	EndAmount            *big.Int
+	Recipient            common.Address
+}
+
+// SeaportOrder Seaport OrderComponents
+type SeaportOrder struct {
+	ChainID       *big.Int
+	Offerer       common.Address
+	Zone          common.Address
+	Offer         []SeaportItem
+	Consideration []SeaportItem
+	OrderType     uint8
+	StartTime     *big.Int
+	EndTime       *big.Int
+	ZoneHash      common.Hash
+	Salt          *big.Int
+	ConduitKey    common.Hash
+	Counter       *big.Int
+}
+
+// NewSeaportOrderTypedData 构造 Seaport OrderComponents 的 TypedData
+func NewSeaportOrderTypedData(o SeaportOrder) apitypes.TypedData {
+	offer := make([]interface{}, 0, len(o.Offer))
+	for _, it := range o.Offer {
+		offer = append(offer, map[string]interface{}{
+			"itemType":             big.NewInt(int64(it.ItemType)),
+			"token":                it.Token.Hex(),
+			"identifierOrCriteria": it.IdentifierOrCriteria,
+			"startAmount":          it.StartAmount,
+			"endAmount":            it.EndAmount,
+		})
+	}
+	consideration := make([]interface{}, 0, len(o.Consideration))
+	for _, it := range o.Consideration {
+		consideration = append(consideration, map[string]interface{}{
+			"itemType":             big.NewInt(int64(it.ItemType)),
+			"token":                it.Token.Hex(),
+			"identifierOrCriteria": it.IdentifierOrCriteria,
+			"startAmount":          it.StartAmount,
+			"endAmount":            it.EndAmount,
+			"recipient":            it.Recipient.Hex(),
+		})
+	}
+
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": eip712DomainType,
+			"OrderComponents": {
+				{Name: "offerer", Type: "address"},
+				{Name: "zone", Type: "address"},
+				{Name: "offer", Type: "OfferItem[]"},
+				{Name: "consideration", Type: "ConsiderationItem[]"},
+				{Name: "orderType", Type: "uint8"},
+				{Name: "startTime", Type: "uint256"},
+				{Name: "endTime", Type: "uint256"},
+				{Name: "zoneHash", Type: "bytes32"},
+				{Name: "salt", Type: "uint256"},
+				{Name: "conduitKey", Type: "bytes32"},
+				{Name: "counter", Type: "uint256"},
+			},
+			"OfferItem": {
+				{Name: "itemType", Type: "uint8"},
+				{Name: "token", Type: "address"},
+				{Name: "identifierOrCriteria", Type: "uint256"},
+				{Name: "startAmount", Type: "uint256"},
+				{Name: "endAmount", Type: "uint256"},
+			},
+			"ConsiderationItem": {
+				{Name: "itemType", Type: "uint8"},
+				{Name: "token", Type: "address"},
+				{Name: "identifierOrCriteria", Type: "uint256"},
+				{Name: "startAmount", Type: "uint256"},
+				{Name: "endAmount", Type: "uint256"},
+				{Name: "recipient", Type: "address"},
+			},
+		},
+		PrimaryType: "OrderComponents",
+		Domain: apitypes.TypedDataDomain{
+			Name:              "Seaport",
+			Version:           SeaportVersion,
+			ChainId:           (*math.HexOrDecimal256)(o.ChainID),
+			VerifyingContract: SeaportAddress.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"offerer":       o.Offerer.Hex(),
+			"zone":          o.Zone.Hex(),
+			"offer":         offer,
+			"consideration": consideration,
+			"orderType":     big.NewInt(int64(o.OrderType)),
+			"startTime":     o.StartTime,
+			"endTime":       o.EndTime,
+			"zoneHash":      o.ZoneHash.Bytes(),
+			"salt":          o.Salt,
+			"conduitKey":    o.ConduitKey.Bytes(),
+			"counter":       o.Counter,
+		},
+	}
+}
+
+// SplitSignature 把 65 字节签名拆成合约 permit(..., v, r, s) 需要的三个参数
+func SplitSignature(sig []byte) (v uint8, r, s common.Hash, err error) {
+	if len(sig) != 65 {
+		return 0, common.Hash{}, common.Hash{}, errors.New("签名长度必须为 65 字节")
+	}
+	v = sig[64]
+	if v < 27 {
+		v += 27
+	}
+	return v, common.BytesToHash(sig[:32]), common.BytesToHash(sig[32:64]), nil
+}
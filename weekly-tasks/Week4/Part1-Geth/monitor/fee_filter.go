@@ -0,0 +1,197 @@
+package main
+
+// ------------------------------------------------
+// 交易池 gas 出价过滤：只输出 maxFeePerGas / maxPriorityFeePerGas 在指定范围内、
+// 或小费高于最近区块某个百分位的 Pending 交易。出价激进的交易往往就是 MEV 相关的交易
+// ------------------------------------------------
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	// 计算小费百分位默认使用的区块数
+	FeeFilterDefaultBlocks = 20
+)
+
+// FeeFilterConfig 交易池输出的 gas 出价过滤，全部为 0 时不过滤
+type FeeFilterConfig struct {
+	MinFeeCapGwei float64 `json:"min_fee_cap_gwei"` // maxFeePerGas 下限（legacy 交易为 gasPrice）
+	MaxFeeCapGwei float64 `json:"max_fee_cap_gwei"` // maxFeePerGas 上限，0 表示不限
+	MinTipGwei    float64 `json:"min_tip_gwei"`     // maxPriorityFeePerGas 下限（legacy 交易为 gasPrice）
+	MaxTipGwei    float64 `json:"max_tip_gwei"`     // maxPriorityFeePerGas 上限，0 表示不限
+	TipPercentile float64 `json:"tip_percentile"`   // 只输出实际小费高于最近区块该百分位的交易，例如 90；0 表示不按百分位过滤
+	Blocks        int     `json:"blocks"`           // 计算百分位的区块数，默认 FeeFilterDefaultBlocks
+}
+
+// Enabled 是否配置了任何过滤条件
+func (c FeeFilterConfig) Enabled() bool {
+	return c.MinFeeCapGwei > 0 || c.MaxFeeCapGwei > 0 || c.MinTipGwei > 0 || c.MaxTipGwei > 0 || c.TipPercentile > 0
+}
+
+// validate 检查范围和百分位
+func (c FeeFilterConfig) validate() error {
+	for _, v := range []float64{c.MinFeeCapGwei, c.MaxFeeCapGwei, c.MinTipGwei, c.MaxTipGwei} {
+		if v < 0 {
+			return fmt.Errorf("gwei 不能为负数")
+		}
+	}
+	if c.MaxFeeCapGwei > 0 && c.MaxFeeCapGwei < c.MinFeeCapGwei {
+		return fmt.Errorf("max_fee_cap_gwei 不能小于 min_fee_cap_gwei")
+	}
+	if c.MaxTipGwei > 0 && c.MaxTipGwei < c.MinTipGwei {
+		return fmt.Errorf("max_tip_gwei 不能小于 min_tip_gwei")
+	}
+	if c.TipPercentile < 0 || c.TipPercentile >= 100 {
+		return fmt.Errorf("tip_percentile 必须在 0~100 之间（不含 100）")
+	}
+	if c.Blocks < 0 {
+		return fmt.Errorf("blocks 不能为负数")
+	}
+	return nil
+}
+
+// FeeFilterStatus /api/mempool/fees 的返回值
+type FeeFilterStatus struct {
+	Config        FeeFilterConfig `json:"config"`
+	BaseFeeGwei   float64         `json:"base_fee_gwei"`            // 最近区块的 base fee
+	ThresholdGwei float64         `json:"threshold_gwei,omitempty"` // 当前的小费百分位门槛
+	Blocks        int             `json:"blocks"`                   // 参与计算百分位的区块数
+	Passed        int64           `json:"passed"`
+	Rejected      int64           `json:"rejected"`
+}
+
+// FeeFilter 按 gas 出价过滤 Pending 交易
+// 配置了 tip_percentile 时作为区块分析器注册，用最近 blocks 个区块的实际小费计算门槛
+type FeeFilter struct {
+	cfg                  FeeFilterConfig
+	minFeeCap, maxFeeCap *big.Int // nil 表示不限
+	minTip, maxTip       *big.Int
+
+	mu        sync.RWMutex
+	window    [][]*big.Int // 每个区块的实际小费，最旧的在前
+	baseFee   *big.Int     // 最近区块的 base fee
+	threshold *big.Int     // 小费百分位门槛，还没有区块数据时为 nil
+
+	passed, rejected atomic.Int64
+}
+
+// NewFeeFilter 创建出价过滤器
+func NewFeeFilter(cfg FeeFilterConfig) *FeeFilter {
+	if cfg.Blocks <= 0 {
+		cfg.Blocks = FeeFilterDefaultBlocks
+	}
+	gwei := func(v float64) *big.Int {
+		if v <= 0 {
+			return nil
+		}
+		wei, _ := new(big.Float).Mul(big.NewFloat(v), big.NewFloat(params.GWei)).Int(nil)
+		return wei
+	}
+	return &FeeFilter{
+		cfg:       cfg,
+		minFeeCap: gwei(cfg.MinFeeCapGwei),
+		maxFeeCap: gwei(cfg.MaxFeeCapGwei),
+		minTip:    gwei(cfg.MinTipGwei),
+		maxTip:    gwei(cfg.MaxTipGwei),
+	}
+}
+
+// Enrichment 需要区块中的交易计算实际小费
+func (f *FeeFilter) Enrichment() EnrichLevel { return EnrichTransactions }
+
+// OnBlock 记录区块中每笔交易的实际小费（effectiveGasPrice - baseFee），重新计算百分位门槛
+func (f *FeeFilter) OnBlock(data *BlockData) {
+	baseFee := data.Block.BaseFee()
+	if baseFee == nil {
+		return
+	}
+	txs := data.Block.Transactions()
+	tips := make([]*big.Int, 0, len(txs))
+	for _, tx := range txs {
+		if tip, err := tx.EffectiveGasTip(baseFee); err == nil {
+			tips = append(tips, tip)
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.baseFee = baseFee
+	f.window = append(f.window, tips)
+	if len(f.window) > f.cfg.Blocks {
+		f.window = f.window[len(f.window)-f.cfg.Blocks:]
+	}
+	var all []*big.Int
+	for _, block := range f.window {
+		all = append(all, block...)
+	}
+	if len(all) == 0 {
+		return
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Cmp(all[j]) < 0 })
+	idx := int(float64(len(all)-1) * f.cfg.TipPercentile / 100)
+	f.threshold = all[idx]
+}
+
+// Match 交易的出价是否满足条件
+// 百分位门槛与交易按最近区块的 base fee 计算的实际小费比较；还没有区块数据时不按百分位过滤
+func (f *FeeFilter) Match(tx *types.Transaction) bool {
+	ok := f.match(tx)
+	if ok {
+		f.passed.Add(1)
+	} else {
+		f.rejected.Add(1)
+	}
+	return ok
+}
+
+func (f *FeeFilter) match(tx *types.Transaction) bool {
+	feeCap, tip := tx.GasFeeCap(), tx.GasTipCap()
+	if (f.minFeeCap != nil && feeCap.Cmp(f.minFeeCap) < 0) || (f.maxFeeCap != nil && feeCap.Cmp(f.maxFeeCap) > 0) {
+		return false
+	}
+	if (f.minTip != nil && tip.Cmp(f.minTip) < 0) || (f.maxTip != nil && tip.Cmp(f.maxTip) > 0) {
+		return false
+	}
+	if f.cfg.TipPercentile <= 0 {
+		return true
+	}
+	f.mu.RLock()
+	baseFee, threshold := f.baseFee, f.threshold
+	f.mu.RUnlock()
+	if threshold == nil {
+		return true
+	}
+	effective, err := tx.EffectiveGasTip(baseFee)
+	return err == nil && effective.Cmp(threshold) > 0
+}
+
+// Status 当前的门槛和通过 / 过滤的交易数
+func (f *FeeFilter) Status() FeeFilterStatus {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	s := FeeFilterStatus{Config: f.cfg, Blocks: len(f.window), Passed: f.passed.Load(), Rejected: f.rejected.Load()}
+	if f.baseFee != nil {
+		s.BaseFeeGwei = weiToGwei(f.baseFee)
+	}
+	if f.threshold != nil {
+		s.ThresholdGwei = weiToGwei(f.threshold)
+	}
+	return s
+}
+
+// RegisterAPI 注册查询接口
+//
+//	GET /api/mempool/fees 出价过滤条件、当前的小费百分位门槛和通过 / 过滤的交易数
+func (f *FeeFilter) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/mempool/fees", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, f.Status())
+	})
+}
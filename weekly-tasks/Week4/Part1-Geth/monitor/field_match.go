@@ -0,0 +1,214 @@
+package main
+
+// ------------------------------------------------
+// 解码后字段匹配：在 calldata 解码之后按参数值过滤（字符串正则、数值比较、地址相等），
+// 例如 "path 中包含代币 X 的任意 Uniswap swap"。参数展开方式与搜索索引（search.go）相同：
+// 数组逐个元素比较，tuple 字段写成 params.tokenIn，multicall 等批量调用的内层调用也会检查
+// ------------------------------------------------
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// 字段匹配的比较方式
+const (
+	MatchEq       = "eq" // 相等（地址、字符串不区分大小写，两边都是整数时按数值比较）；数组中任意元素相等即可
+	MatchNe       = "ne" // 参数存在且所有元素都不相等
+	MatchGt       = "gt" // gt / gte / lt / lte 为数值比较，值可以写十进制或 0x 十六进制
+	MatchGte      = "gte"
+	MatchLt       = "lt"
+	MatchLte      = "lte"
+	MatchRegex    = "regex"    // 正则匹配（不区分大小写）
+	MatchContains = "contains" // 子串匹配；值以 0x 开头时去掉 0x，可以在 bytes（例如 Uniswap V3 的 path）中查找地址
+)
+
+// MatchCondition 一个参数条件
+type MatchCondition struct {
+	Param string `json:"param"` // 参数名（不区分大小写），tuple 字段写 a.b，"*" 表示任意参数
+	Op    string `json:"op"`
+	Value string `json:"value"`
+}
+
+// MatchRuleConfig 一条匹配规则：函数和全部条件都满足时命中
+type MatchRuleConfig struct {
+	Name       string           `json:"name"`
+	Methods    []string         `json:"methods"` // 函数名或 0x 开头的 selector，为空不限
+	Conditions []MatchCondition `json:"conditions"`
+}
+
+type matchCond struct {
+	MatchCondition
+	num *big.Int       // 数值比较和整数相等使用
+	re  *regexp.Regexp // regex 使用
+}
+
+type matchRule struct {
+	name      string
+	names     map[string]bool
+	selectors map[string]bool
+	conds     []matchCond
+}
+
+// CallMatcher 一组按解码后字段匹配的规则
+type CallMatcher struct {
+	rules []matchRule
+}
+
+// NewCallMatcher 编译匹配规则，配置校验也使用它
+func NewCallMatcher(rules []MatchRuleConfig) (*CallMatcher, error) {
+	m := &CallMatcher{}
+	seen := make(map[string]bool, len(rules))
+	for _, cfg := range rules {
+		if cfg.Name == "" || seen[cfg.Name] {
+			return nil, fmt.Errorf("规则的 name 为空或重复: %q", cfg.Name)
+		}
+		seen[cfg.Name] = true
+		if len(cfg.Methods)+len(cfg.Conditions) == 0 {
+			return nil, fmt.Errorf("规则 %s 至少需要 methods 或 conditions 之一", cfg.Name)
+		}
+		r := matchRule{name: cfg.Name, names: make(map[string]bool), selectors: make(map[string]bool)}
+		for _, method := range cfg.Methods {
+			if selectorPattern.MatchString(method) {
+				r.selectors[strings.ToLower(method)] = true
+			} else {
+				r.names[method] = true
+			}
+		}
+		for _, c := range cfg.Conditions {
+			cond, err := compileCondition(c)
+			if err != nil {
+				return nil, fmt.Errorf("规则 %s: %w", cfg.Name, err)
+			}
+			r.conds = append(r.conds, cond)
+		}
+		m.rules = append(m.rules, r)
+	}
+	return m, nil
+}
+
+func compileCondition(c MatchCondition) (matchCond, error) {
+	if c.Param == "" {
+		return matchCond{}, fmt.Errorf("条件缺少 param")
+	}
+	pattern := c.Value
+	c.Value = strings.ToLower(c.Value)
+	cond := matchCond{MatchCondition: c}
+	num, isNum := new(big.Int).SetString(c.Value, 0)
+	if isNum {
+		cond.num = num
+	}
+	switch c.Op {
+	case MatchEq, MatchNe:
+	case MatchGt, MatchGte, MatchLt, MatchLte:
+		if !isNum {
+			return matchCond{}, fmt.Errorf("%s 条件的值不是整数: %q", c.Op, c.Value)
+		}
+	case MatchRegex:
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return matchCond{}, fmt.Errorf("正则表达式错误: %w", err)
+		}
+		cond.re = re
+	case MatchContains:
+		cond.Value = strings.TrimPrefix(c.Value, "0x")
+	default:
+		return matchCond{}, fmt.Errorf("未知的比较方式 %q", c.Op)
+	}
+	return cond, nil
+}
+
+// test 单个值是否满足条件（ne 按 eq 判断，由调用方取反）
+func (c *matchCond) test(value string) bool {
+	switch c.Op {
+	case MatchEq, MatchNe:
+		if c.num != nil {
+			if v, ok := new(big.Int).SetString(value, 0); ok {
+				return v.Cmp(c.num) == 0
+			}
+		}
+		return value == c.Value
+	case MatchRegex:
+		return c.re.MatchString(value)
+	case MatchContains:
+		return strings.Contains(value, c.Value)
+	}
+	v, ok := new(big.Int).SetString(value, 0)
+	if !ok {
+		return false
+	}
+	switch cmp := v.Cmp(c.num); c.Op {
+	case MatchGt:
+		return cmp > 0
+	case MatchGte:
+		return cmp >= 0
+	case MatchLt:
+		return cmp < 0
+	default:
+		return cmp <= 0
+	}
+}
+
+// match 条件在展开后的参数上是否成立
+func (c *matchCond) match(args []searchArg) bool {
+	found := false
+	for _, a := range args {
+		if c.Param != "*" && !strings.EqualFold(a.name, c.Param) {
+			continue
+		}
+		found = true
+		hit := c.test(a.value)
+		if c.Op == MatchNe && hit {
+			return false
+		}
+		if c.Op != MatchNe && hit {
+			return true
+		}
+	}
+	return c.Op == MatchNe && found
+}
+
+// matchCall 单个调用（不含内层调用）是否命中规则
+func (r *matchRule) matchCall(call *DecodedCall) bool {
+	if len(r.names)+len(r.selectors) > 0 && !r.names[call.Name] && !r.selectors[call.Selector] {
+		return false
+	}
+	if len(r.conds) == 0 {
+		return true
+	}
+	if call.Name == "" { // ABI 未知，没有参数
+		return false
+	}
+	args := searchArgs(call.Args)
+	for i := range r.conds {
+		if !r.conds[i].match(args) {
+			return false
+		}
+	}
+	return true
+}
+
+// Match 解码后的调用命中的规则名，外层或任意一个内层调用命中即可
+func (m *CallMatcher) Match(call *DecodedCall) []string {
+	var hits []string
+	for i := range m.rules {
+		if m.rules[i].matchTree(call) {
+			hits = append(hits, m.rules[i].name)
+		}
+	}
+	return hits
+}
+
+func (r *matchRule) matchTree(call *DecodedCall) bool {
+	if r.matchCall(call) {
+		return true
+	}
+	for _, in := range call.Inner {
+		if r.matchTree(in) {
+			return true
+		}
+	}
+	return false
+}
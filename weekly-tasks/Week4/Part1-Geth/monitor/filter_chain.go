@@ -0,0 +1,405 @@
+package main
+
+// ------------------------------------------------
+// 过滤链：把接收方 / 发送方 / 函数 / 出价 / 金额 / 字段匹配这些过滤条件用 and / or / not 组合成命名的过滤链，
+// 由交易池输出、关注分组、大额交易提醒、输出（outputs）和路由规则按名称引用，
+// 每条链有自己的命中 / 未命中计数（指标和 /api/filters）；更复杂的逻辑可以写成 CEL 表达式（cel.go）
+// ------------------------------------------------
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/google/cel-go/cel"
+)
+
+// FilterExpr 过滤表达式，每个节点只能填写一项
+type FilterExpr struct {
+	And    []FilterExpr `json:"and,omitempty"`    // 全部满足
+	Or     []FilterExpr `json:"or,omitempty"`     // 任意一个满足
+	Not    *FilterExpr  `json:"not,omitempty"`    // 取反
+	Filter string       `json:"filter,omitempty"` // 引用另一条过滤链
+
+	To        *ToFilterConfig       `json:"to,omitempty"`        // 接收方
+	From      *ToFilterConfig       `json:"from,omitempty"`      // 发送方，格式与 to 相同；交易池交易需要恢复发送者
+	Selectors *SelectorFilterConfig `json:"selectors,omitempty"` // 调用的函数
+	Fees      *FeeFilterConfig      `json:"fees,omitempty"`      // gas 出价
+	Value     *ValueRangeConfig     `json:"value,omitempty"`     // 交易附带的 ETH
+	Match     []MatchRuleConfig     `json:"match,omitempty"`     // 解码后的参数值，命中任意一条规则即满足
+	CEL       string                `json:"cel,omitempty"`       // CEL 表达式，可用变量见 cel.go
+}
+
+// ValueRangeConfig 交易附带 ETH 的范围，0 表示不限
+type ValueRangeConfig struct {
+	MinETH float64 `json:"min_eth"`
+	MaxETH float64 `json:"max_eth"`
+}
+
+// validateFilters 检查表达式结构、引用的过滤链是否存在以及是否循环引用
+func validateFilters(filters map[string]FilterExpr) error {
+	for name, expr := range filters {
+		if name == "" {
+			return fmt.Errorf("filters 的名称不能为空")
+		}
+		if err := expr.validate(filters); err != nil {
+			return fmt.Errorf("filters.%s: %w", name, err)
+		}
+	}
+	state := make(map[string]int, len(filters)) // 1 访问中，2 已完成
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("filters.%s 循环引用", name)
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		expr := filters[name]
+		for _, ref := range expr.refs() {
+			if err := visit(ref); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		return nil
+	}
+	for name := range filters {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate 检查一个节点及其子节点
+func (e FilterExpr) validate(filters map[string]FilterExpr) error {
+	set := 0
+	for _, ok := range []bool{len(e.And) > 0, len(e.Or) > 0, e.Not != nil, e.Filter != "",
+		e.To != nil, e.From != nil, e.Selectors != nil, e.Fees != nil, e.Value != nil, len(e.Match) > 0, e.CEL != ""} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("每个节点只能填写 and / or / not / filter / to / from / selectors / fees / value / match / cel 中的一项（当前 %d 项）", set)
+	}
+	for _, sub := range append(append([]FilterExpr{}, e.And...), e.Or...) {
+		if err := sub.validate(filters); err != nil {
+			return err
+		}
+	}
+	switch {
+	case e.Not != nil:
+		return e.Not.validate(filters)
+	case e.Filter != "":
+		if _, ok := filters[e.Filter]; !ok {
+			return fmt.Errorf("引用的过滤链不存在: %q", e.Filter)
+		}
+	case e.To != nil:
+		return e.To.validate()
+	case e.From != nil:
+		return e.From.validate()
+	case e.Selectors != nil:
+		return e.Selectors.validate()
+	case e.Fees != nil:
+		return e.Fees.validate()
+	case e.Value != nil:
+		if e.Value.MinETH < 0 || e.Value.MaxETH < 0 || (e.Value.MaxETH > 0 && e.Value.MaxETH < e.Value.MinETH) {
+			return fmt.Errorf("value 的范围无效")
+		}
+	case len(e.Match) > 0:
+		_, err := NewCallMatcher(e.Match)
+		return err
+	case e.CEL != "":
+		_, err := compileCEL(e.CEL, false)
+		return err
+	}
+	return nil
+}
+
+// refs 表达式中直接或间接（在 and / or / not 内）引用的过滤链
+func (e FilterExpr) refs() []string {
+	var out []string
+	if e.Filter != "" {
+		out = append(out, e.Filter)
+	}
+	for _, sub := range append(append([]FilterExpr{}, e.And...), e.Or...) {
+		out = append(out, sub.refs()...)
+	}
+	if e.Not != nil {
+		out = append(out, e.Not.refs()...)
+	}
+	return out
+}
+
+// filterSubject 一次求值的输入：发送者和解码结果按需计算，同一次求值中只计算一次
+type filterSubject struct {
+	tx      *types.Transaction
+	from    *common.Address // 已知发送者（区块中的交易）时不为 nil
+	call    *DecodedCall
+	decoded bool
+}
+
+// sender 发送者，第一次用到时从签名恢复；set 为 nil 或恢复失败时返回 nil
+func (s *filterSubject) sender(set *FilterSet) *common.Address {
+	if s.from == nil && set != nil {
+		if from, err := types.Sender(set.signer, s.tx); err == nil {
+			s.from = &from
+		}
+	}
+	return s.from
+}
+
+// decode 解码后的调用，第一次用到时解码；set 为 nil 或无法解码时返回 nil
+func (s *filterSubject) decode(set *FilterSet) *DecodedCall {
+	if !s.decoded && set != nil {
+		s.decoded = true
+		if call, ok := set.abis.DecodeCall(s.tx.To(), s.tx.Data()); ok {
+			s.call = call
+		}
+	}
+	return s.call
+}
+
+type filterNode interface {
+	eval(set *FilterSet, s *filterSubject) bool
+}
+
+type andNode []filterNode
+
+func (n andNode) eval(set *FilterSet, s *filterSubject) bool {
+	for _, sub := range n {
+		if !sub.eval(set, s) {
+			return false
+		}
+	}
+	return true
+}
+
+type orNode []filterNode
+
+func (n orNode) eval(set *FilterSet, s *filterSubject) bool {
+	for _, sub := range n {
+		if sub.eval(set, s) {
+			return true
+		}
+	}
+	return false
+}
+
+type notNode struct{ sub filterNode }
+
+func (n notNode) eval(set *FilterSet, s *filterSubject) bool { return !n.sub.eval(set, s) }
+
+// refNode 引用另一条链，计入被引用链的计数
+type refNode string
+
+func (n refNode) eval(set *FilterSet, s *filterSubject) bool {
+	return set.chains[string(n)].eval(set, s)
+}
+
+// txNode 只看交易本身的条件（接收方、函数、出价、金额）
+type txNode struct {
+	match func(tx *types.Transaction) bool
+}
+
+func (n txNode) eval(set *FilterSet, s *filterSubject) bool { return n.match(s.tx) }
+
+type fromNode struct{ f *ToFilter }
+
+func (n fromNode) eval(set *FilterSet, s *filterSubject) bool {
+	from := s.sender(set)
+	return from != nil && n.f.MatchAddress(from)
+}
+
+type callNode struct{ m *CallMatcher }
+
+func (n callNode) eval(set *FilterSet, s *filterSubject) bool {
+	call := s.decode(set)
+	return call != nil && len(n.m.Match(call)) > 0
+}
+
+type celNode struct{ prg cel.Program }
+
+func (n celNode) eval(set *FilterSet, s *filterSubject) bool {
+	return evalCEL(n.prg, celVars(set, s))
+}
+
+// FilterChain 一条命名的过滤链
+type FilterChain struct {
+	name              string
+	root              filterNode
+	matched, rejected *metrics.Counter
+}
+
+func (c *FilterChain) eval(set *FilterSet, s *filterSubject) bool {
+	ok := c.root.eval(set, s)
+	if ok {
+		c.matched.Inc(1)
+	} else {
+		c.rejected.Inc(1)
+	}
+	return ok
+}
+
+// FilterChainStatus /api/filters 中的一条过滤链
+type FilterChainStatus struct {
+	Name     string `json:"name"`
+	Matched  int64  `json:"matched"`
+	Rejected int64  `json:"rejected"`
+}
+
+// FilterSet 全部命名的过滤链
+type FilterSet struct {
+	chains map[string]*FilterChain
+	signer types.Signer
+	abis   *ABIRegistry
+	watch  *Watchlist
+	fees   []*FeeFilter // 按百分位过滤的出价条件，需要注册为区块分析器
+}
+
+// NewFilterSet 编译过滤链，配置需要先通过 validateFilters
+func NewFilterSet(filters map[string]FilterExpr, signer types.Signer, abis *ABIRegistry, watch *Watchlist) *FilterSet {
+	set := &FilterSet{chains: make(map[string]*FilterChain, len(filters)), signer: signer, abis: abis, watch: watch}
+	for name, expr := range filters {
+		set.chains[name] = &FilterChain{
+			name:     name,
+			root:     set.compile(expr, watch),
+			matched:  metrics.NewRegisteredCounter("monitor/filters/"+name+"/matched", metricsRegistry),
+			rejected: metrics.NewRegisteredCounter("monitor/filters/"+name+"/rejected", metricsRegistry),
+		}
+	}
+	return set
+}
+
+func (set *FilterSet) compile(e FilterExpr, watch *Watchlist) filterNode {
+	list := func(exprs []FilterExpr) []filterNode {
+		nodes := make([]filterNode, len(exprs))
+		for i, sub := range exprs {
+			nodes[i] = set.compile(sub, watch)
+		}
+		return nodes
+	}
+	switch {
+	case len(e.And) > 0:
+		return andNode(list(e.And))
+	case len(e.Or) > 0:
+		return orNode(list(e.Or))
+	case e.Not != nil:
+		return notNode{set.compile(*e.Not, watch)}
+	case e.Filter != "":
+		return refNode(e.Filter)
+	case e.To != nil:
+		return txNode{NewToFilter(*e.To, watch).Match}
+	case e.From != nil:
+		return fromNode{NewToFilter(*e.From, watch)}
+	case e.Selectors != nil:
+		return txNode{NewSelectorFilter(*e.Selectors).Match}
+	case e.Fees != nil:
+		f := NewFeeFilter(*e.Fees)
+		if e.Fees.TipPercentile > 0 {
+			set.fees = append(set.fees, f)
+		}
+		return txNode{f.Match}
+	case e.Value != nil:
+		return txNode{valueRange(*e.Value)}
+	case e.CEL != "":
+		prg, _ := compileCEL(e.CEL, false)
+		return celNode{prg}
+	default:
+		m, _ := NewCallMatcher(e.Match)
+		return callNode{m}
+	}
+}
+
+// valueRange 交易附带 ETH 的范围条件
+func valueRange(cfg ValueRangeConfig) func(tx *types.Transaction) bool {
+	wei := func(eth float64) *big.Int {
+		if eth <= 0 {
+			return nil
+		}
+		v, _ := new(big.Float).Mul(big.NewFloat(eth), big.NewFloat(params.Ether)).Int(nil)
+		return v
+	}
+	lo, hi := wei(cfg.MinETH), wei(cfg.MaxETH)
+	return func(tx *types.Transaction) bool {
+		v := tx.Value()
+		return (lo == nil || v.Cmp(lo) >= 0) && (hi == nil || v.Cmp(hi) <= 0)
+	}
+}
+
+// Analyzers 需要注册到 BlockFetcher 的分析器（按百分位过滤的出价条件）
+func (set *FilterSet) Analyzers() []*FeeFilter {
+	return set.fees
+}
+
+// Has 过滤链是否存在
+func (set *FilterSet) Has(name string) bool {
+	if set == nil {
+		return false
+	}
+	_, ok := set.chains[name]
+	return ok
+}
+
+// Match 交易是否满足过滤链；from 为 nil 时按需从签名恢复发送者；过滤链不存在时返回 false
+func (set *FilterSet) Match(name string, tx *types.Transaction, from *common.Address) bool {
+	if set == nil {
+		return false
+	}
+	chain, ok := set.chains[name]
+	if !ok {
+		return false
+	}
+	return chain.eval(set, &filterSubject{tx: tx, from: from})
+}
+
+// Status 各过滤链的计数，按名称排序
+func (set *FilterSet) Status() []FilterChainStatus {
+	out := []FilterChainStatus{}
+	if set == nil {
+		return out
+	}
+	for name, c := range set.chains {
+		out = append(out, FilterChainStatus{Name: name, Matched: c.matched.Snapshot().Count(), Rejected: c.rejected.Snapshot().Count()})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// RegisterAPI 注册查询接口
+//
+//	GET /api/filters 各过滤链的命中 / 未命中次数
+func (set *FilterSet) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/filters", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, set.Status())
+	})
+}
+
+// filterSet 全局过滤链，输出和路由规则通过名称引用；未配置时为 nil
+var (
+	filterSetMu sync.RWMutex
+	filterSet   *FilterSet
+)
+
+// SetFilters 设置全局过滤链
+func SetFilters(set *FilterSet) {
+	filterSetMu.Lock()
+	defer filterSetMu.Unlock()
+	filterSet = set
+}
+
+// currentFilters 当前的全局过滤链
+func currentFilters() *FilterSet {
+	filterSetMu.RLock()
+	defer filterSetMu.RUnlock()
+	return filterSet
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// weiToGwei 把 wei 转换为 gwei（用于展示，精度足够）
+func weiToGwei(wei *big.Int) float64 {
+	if wei == nil {
+		return 0
+	}
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(params.GWei)).Float64()
+	return f
+}
+
+// weiToEther 把 wei 转换为 ETH（用于展示）
+func weiToEther(wei *big.Int) float64 {
+	if wei == nil {
+		return 0
+	}
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(params.Ether)).Float64()
+	return f
+}
+
+// formatUnits 把最小单位的整数金额按 decimals 转换为十进制字符串，最多保留 6 位小数
+// 用整数运算，避免大额代币在 float64 下丢失精度
+func formatUnits(amount *big.Int, decimals uint8) string {
+	if amount == nil {
+		return "0"
+	}
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	q, r := new(big.Int).QuoRem(new(big.Int).Abs(amount), unit, new(big.Int))
+	s := q.String()
+	if r.Sign() != 0 {
+		frac := fmt.Sprintf("%0*s", int(decimals), r.String())
+		if len(frac) > 6 {
+			frac = frac[:6]
+		}
+		if frac = strings.TrimRight(frac, "0"); frac != "" {
+			s += "." + frac
+		}
+	}
+	if amount.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// percentile 计算分位数（p 取 0~1），不修改入参
+func percentile(samples []time.Duration, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := int(p * float64(len(sorted)-1))
+	return sorted[idx]
+}
+
+// mean 计算平均值
+func mean(samples []time.Duration) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, s := range samples {
+		sum += s
+	}
+	return sum / time.Duration(len(samples))
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// calldata 相似度达到该值时认为是模仿调用
+	FrontrunMinSimilarity = 0.8
+	// Pending 调用保留多久，超过后不再参与比较
+	FrontrunWindow = 10 * time.Minute
+)
+
+// pendingCall 交易池中一笔调用关注合约的交易
+type pendingCall struct {
+	hash    common.Hash
+	sender  common.Address
+	to      common.Address
+	data    []byte
+	seen    time.Time
+	flagged bool // 已经报告过被抢跑
+}
+
+// FrontrunEvidence 一次疑似抢跑的证据
+type FrontrunEvidence struct {
+	Contract     common.Address `json:"contract"`
+	Block        uint64         `json:"block"`
+	VictimTx     common.Hash    `json:"victim_tx"` // 先出现在交易池中的用户调用
+	Victim       common.Address `json:"victim"`
+	FrontrunTx   common.Hash    `json:"frontrun_tx"` // 模仿它、先上链的交易
+	Frontrunner  common.Address `json:"frontrunner"`
+	Selector     string         `json:"selector"`
+	Similarity   float64        `json:"similarity"`    // calldata 相似度，0 - 1
+	TimeDelta    time.Duration  `json:"time_delta"`    // 抢跑交易比用户调用晚出现多久（未在交易池出现时按区块时间计算）
+	FrontrunSeen bool           `json:"frontrun_seen"` // 抢跑交易是否在交易池中出现过（false 时多半走私有通道）
+	VictimMined  bool           `json:"victim_mined"`  // 用户调用是否在同一区块中排在后面上链
+}
+
+// FrontrunDetector 检测针对关注合约的抢跑：第三方的交易模仿交易池中某个用户对该合约的调用（相同 selector、
+// 参数基本相同，只把用户地址换成自己的），并且先于用户的交易上链
+type FrontrunDetector struct {
+	signer    types.Signer
+	contracts map[common.Address]bool
+
+	mu      sync.Mutex
+	pending map[common.Hash]*pendingCall
+
+	onEvidence func(FrontrunEvidence)
+}
+
+// NewFrontrunDetector 创建检测器，onEvidence 为 nil 时使用默认输出
+func NewFrontrunDetector(signer types.Signer, contracts []common.Address, onEvidence func(FrontrunEvidence)) *FrontrunDetector {
+	if onEvidence == nil {
+		onEvidence = PrintFrontrunEvidence
+	}
+	d := &FrontrunDetector{
+		signer:     signer,
+		contracts:  make(map[common.Address]bool, len(contracts)),
+		pending:    make(map[common.Hash]*pendingCall),
+		onEvidence: onEvidence,
+	}
+	for _, c := range contracts {
+		d.contracts[c] = true
+	}
+	return d
+}
+
+// Observe 记录交易池中调用关注合约的交易（只处理完整交易）
+func (d *FrontrunDetector) Observe(msg BusMessage) {
+	tx := msg.Tx
+	if tx == nil || tx.To() == nil || !d.contracts[*tx.To()] || len(tx.Data()) < 4 {
+		return
+	}
+	from, err := types.Sender(d.signer, tx)
+	if err != nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.pending[tx.Hash()]; !ok {
+		d.pending[tx.Hash()] = &pendingCall{hash: tx.Hash(), sender: from, to: *tx.To(), data: tx.Data(), seen: msg.Received}
+	}
+}
+
+// OnBlock 实现 BlockAnalyzer：区块中调用关注合约的交易与更早出现在交易池中、尚未上链的相似调用比较
+func (d *FrontrunDetector) OnBlock(data *BlockData) {
+	header := data.Block.Header()
+	blockTime := time.Unix(int64(header.Time), 0)
+	txs := data.Block.Transactions()
+
+	var found []FrontrunEvidence
+	d.mu.Lock()
+	minedAt := make(map[common.Hash]int)
+	for i, tx := range txs {
+		if _, ok := d.pending[tx.Hash()]; ok {
+			minedAt[tx.Hash()] = i
+		}
+	}
+	for i, tx := range txs {
+		to := tx.To()
+		if to == nil || !d.contracts[*to] || len(tx.Data()) < 4 || data.Receipts[i].Status == 0 {
+			continue
+		}
+		seenAt, seen := blockTime, false
+		if p, ok := d.pending[tx.Hash()]; ok {
+			seenAt, seen = p.seen, true
+		}
+		for _, p := range d.pending {
+			if p.flagged || p.hash == tx.Hash() || p.to != *to || p.sender == data.Senders[i] || !p.seen.Before(seenAt) {
+				continue
+			}
+			// 用户的交易在同一区块中排在前面，不算抢跑
+			idx, mined := minedAt[p.hash]
+			if mined && idx < i {
+				continue
+			}
+			score := calldataSimilarity(p.data, p.sender, tx.Data(), data.Senders[i])
+			if score < FrontrunMinSimilarity {
+				continue
+			}
+			p.flagged = true
+			found = append(found, FrontrunEvidence{
+				Contract:     *to,
+				Block:        header.Number.Uint64(),
+				VictimTx:     p.hash,
+				Victim:       p.sender,
+				FrontrunTx:   tx.Hash(),
+				Frontrunner:  data.Senders[i],
+				Selector:     hexutil.Encode(tx.Data()[:4]),
+				Similarity:   score,
+				TimeDelta:    seenAt.Sub(p.seen),
+				FrontrunSeen: seen,
+				VictimMined:  mined,
+			})
+		}
+	}
+	for hash := range minedAt {
+		delete(d.pending, hash)
+	}
+	for hash, p := range d.pending {
+		if blockTime.Sub(p.seen) > FrontrunWindow {
+			delete(d.pending, hash)
+		}
+	}
+	d.mu.Unlock()
+
+	for _, ev := range found {
+		d.onEvidence(ev)
+	}
+}
+
+// calldataSimilarity 两段 calldata 的相似度：selector 必须相同，之后按 32 字节逐个比较参数，
+// 各自发送者的地址视为相同（抢跑者通常只把收款地址换成自己的）
+func calldataSimilarity(a []byte, senderA common.Address, b []byte, senderB common.Address) float64 {
+	if len(a) < 4 || len(b) < 4 || !bytes.Equal(a[:4], b[:4]) {
+		return 0
+	}
+	a, b = a[4:], b[4:]
+	words := max(len(a), len(b)) / 32
+	if words == 0 {
+		return 1
+	}
+	wordA := common.LeftPadBytes(senderA.Bytes(), 32)
+	wordB := common.LeftPadBytes(senderB.Bytes(), 32)
+	same := 0
+	for i := 0; i+32 <= len(a) && i+32 <= len(b); i += 32 {
+		wa, wb := a[i:i+32], b[i:i+32]
+		if bytes.Equal(wa, wb) || (bytes.Equal(wa, wordA) && bytes.Equal(wb, wordB)) {
+			same++
+		}
+	}
+	return float64(same) / float64(words)
+}
+
+// PrintFrontrunEvidence 默认的抢跑证据输出
+func PrintFrontrunEvidence(ev FrontrunEvidence) {
+	summary := fmt.Sprintf("🏃 [Frontrun] 合约 %s 的调用 %s 疑似被抢跑 | 区块 %d | 相似度 %.0f%% | 晚出现 %s",
+		ev.Contract.Hex(), ev.Selector, ev.Block, ev.Similarity*100, ev.TimeDelta.Round(time.Millisecond))
+	text := fmt.Sprintf("\n%s\n   用户交易: %s (%s)\n   抢跑交易: %s (%s)\n",
+		summary, ev.VictimTx.Hex(), ev.Victim.Hex(), ev.FrontrunTx.Hex(), ev.Frontrunner.Hex())
+	if !ev.FrontrunSeen {
+		text += "   抢跑交易没有在交易池中出现过（可能走私有通道）\n"
+	}
+	Emit(Event{Type: "frontrun", Key: eventKey(ev.VictimTx, ev.FrontrunTx), Summary: summary, Text: text, Data: ev})
+}
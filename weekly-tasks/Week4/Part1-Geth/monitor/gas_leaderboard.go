@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// 滑动窗口大小（区块数），50 个区块约 10 分钟
+	GasLeaderboardWindow = 50
+	// 排行榜展示的合约数量
+	GasLeaderboardTop = 10
+	// 每隔多少个区块打印一次排行榜
+	GasLeaderboardReportEvery = 10
+)
+
+// contractCreation 合约创建交易没有 to，用零地址在排行榜中代表
+var contractCreation = common.Address{}
+
+// GasEntry 排行榜中的一项
+type GasEntry struct {
+	To      common.Address
+	GasUsed uint64
+	TxCount int
+}
+
+// blockGasUsage 一个区块内按 to 聚合的 gas 消耗
+type blockGasUsage map[common.Address]*GasEntry
+
+// GasLeaderboard 按 to 地址聚合最近 GasLeaderboardWindow 个区块的 gas 消耗，
+// 直接回答"现在是谁在堵链"
+type GasLeaderboard struct {
+	mu       sync.Mutex
+	blocks   []blockGasUsage // 窗口内每个区块的聚合，最旧的在前
+	totals   map[common.Address]*GasEntry
+	totalGas uint64
+	counter  int
+}
+
+func NewGasLeaderboard() *GasLeaderboard {
+	return &GasLeaderboard{totals: make(map[common.Address]*GasEntry)}
+}
+
+// Warm 实现 Warmer：累计 gas 用量，不计入报告周期
+func (g *GasLeaderboard) Warm(data *BlockData) {
+	usage := blockUsage(data)
+	g.mu.Lock()
+	g.add(usage)
+	g.mu.Unlock()
+}
+
+// OnBlock 实现 BlockAnalyzer
+func (g *GasLeaderboard) OnBlock(data *BlockData) {
+	usage := blockUsage(data)
+
+	g.mu.Lock()
+	g.add(usage)
+	g.counter++
+	report := g.counter%GasLeaderboardReportEvery == 0
+	g.mu.Unlock()
+
+	if report {
+		g.PrintReport()
+	}
+}
+
+// blockUsage 按接收合约汇总一个区块的 gas 用量
+func blockUsage(data *BlockData) blockGasUsage {
+	usage := make(blockGasUsage)
+	for i, tx := range data.Block.Transactions() {
+		to := contractCreation
+		if tx.To() != nil {
+			to = *tx.To()
+		}
+		e, ok := usage[to]
+		if !ok {
+			e = &GasEntry{To: to}
+			usage[to] = e
+		}
+		e.GasUsed += data.Receipts[i].GasUsed
+		e.TxCount++
+	}
+	return usage
+}
+
+// add 把一个区块加入窗口，并把滑出窗口的区块从总量中扣除（调用方持有锁）
+func (g *GasLeaderboard) add(usage blockGasUsage) {
+	g.apply(usage, 1)
+	g.blocks = append(g.blocks, usage)
+	if len(g.blocks) > GasLeaderboardWindow {
+		g.apply(g.blocks[0], -1)
+		g.blocks = g.blocks[1:]
+	}
+}
+
+// apply 把一个区块的聚合加到（sign=1）或减出（sign=-1）总量
+func (g *GasLeaderboard) apply(usage blockGasUsage, sign int) {
+	for to, e := range usage {
+		t, ok := g.totals[to]
+		if !ok {
+			t = &GasEntry{To: to}
+			g.totals[to] = t
+		}
+		if sign > 0 {
+			t.GasUsed += e.GasUsed
+			t.TxCount += e.TxCount
+			g.totalGas += e.GasUsed
+		} else {
+			t.GasUsed -= e.GasUsed
+			t.TxCount -= e.TxCount
+			g.totalGas -= e.GasUsed
+		}
+		if t.TxCount == 0 {
+			delete(g.totals, to)
+		}
+	}
+}
+
+// Top 返回窗口内 gas 消耗最多的 n 个地址
+func (g *GasLeaderboard) Top(n int) []GasEntry {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	entries := make([]GasEntry, 0, len(g.totals))
+	for _, e := range g.totals {
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].GasUsed > entries[j].GasUsed })
+	if len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries
+}
+
+// PrintReport 打印排行榜
+func (g *GasLeaderboard) PrintReport() {
+	top := g.Top(GasLeaderboardTop)
+	g.mu.Lock()
+	blocks, totalGas := len(g.blocks), g.totalGas
+	g.mu.Unlock()
+	if totalGas == 0 {
+		return
+	}
+
+	var sb strings.Builder
+	summary := fmt.Sprintf("🔥 [Gas Leaderboard] 近 %d 个区块 gas 消耗 Top %d", blocks, len(top))
+	sb.WriteString("\n" + summary + "\n")
+	for i, e := range top {
+		name := e.To.Hex()
+		if e.To == contractCreation {
+			name = "(合约创建)"
+		}
+		fmt.Fprintf(&sb, "   %2d. %-42s %14d gas  %5.1f%%  %5d 笔\n",
+			i+1, name, e.GasUsed, float64(e.GasUsed)/float64(totalGas)*100, e.TxCount)
+	}
+	Emit(Event{Type: "gas_leaderboard", Summary: summary, Text: sb.String(), Data: top})
+}
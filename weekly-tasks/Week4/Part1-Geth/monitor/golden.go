@@ -0,0 +1,535 @@
+package main
+
+// ------------------------------------------------
+// Golden 回归：把录制的真实交易和日志（fixtures/*.json）离线跑一遍解码器和分析器
+// （calldata / 日志解码、签名授权、聚合器路线、UserOp、CEL 变量、授权、大额交易、三明治、bundle），结果与 *.golden.json 逐字比较，
+// 扩展解码逻辑之后运行一次，就能看出哪些已有的输出发生了变化
+// ------------------------------------------------
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// DefaultGoldenDir 默认的 fixture 目录（相对 Part1-Geth）
+	DefaultGoldenDir = "monitor/fixtures"
+	goldenSuffix     = ".golden.json"
+)
+
+// goldenWhale 回放时大额交易提醒使用的阈值，与配置文件无关
+var goldenWhale = WhaleAlertConfig{MinETH: 10}
+
+// GoldenFixture 一笔录制的交易及其回执中的日志
+// 有 raw_tx 时从中取出发送者、接收方、金额和 calldata；手写的 fixture 可以只填 from / to / value / input
+type GoldenFixture struct {
+	Note    string                             `json:"note,omitempty"` // 来源说明
+	ChainID uint64                             `json:"chain_id"`
+	RawTx   hexutil.Bytes                      `json:"raw_tx,omitempty"` // 已签名交易（MarshalBinary）
+	From    common.Address                     `json:"from"`
+	To      *common.Address                    `json:"to,omitempty"`
+	Value   string                             `json:"value,omitempty"` // wei，十进制
+	Input   hexutil.Bytes                      `json:"input,omitempty"`
+	Logs    []GoldenLog                        `json:"logs,omitempty"`
+	ABIs    map[common.Address]json.RawMessage `json:"abis,omitempty"`  // 录制时拉取的合约 ABI，回放时不联网
+	Block   *GoldenBlock                       `json:"block,omitempty"` // 同一区块中相邻的交易，三明治和 bundle 检测需要
+}
+
+// GoldenBlock 录制时交易所在区块的上下文（-context 指定前后各几笔）
+type GoldenBlock struct {
+	Number   uint64          `json:"number"`
+	Coinbase common.Address  `json:"coinbase"`
+	BaseFee  *hexutil.Big    `json:"base_fee,omitempty"`
+	Extra    hexutil.Bytes   `json:"extra,omitempty"` // 用于识别 builder
+	Index    int             `json:"index"`           // fixture 中的交易在 txs 中的下标
+	Txs      []GoldenBlockTx `json:"txs"`
+}
+
+// GoldenBlockTx 区块上下文中的一笔交易及其回执
+type GoldenBlockTx struct {
+	RawTx             hexutil.Bytes `json:"raw_tx"`
+	Status            uint64        `json:"status"`
+	GasUsed           uint64        `json:"gas_used"`
+	EffectiveGasPrice *hexutil.Big  `json:"effective_gas_price"`
+	Logs              []GoldenLog   `json:"logs,omitempty"`
+}
+
+// GoldenLog 录制的一条日志
+type GoldenLog struct {
+	Address common.Address `json:"address"`
+	Topics  []common.Hash  `json:"topics"`
+	Data    hexutil.Bytes  `json:"data"`
+}
+
+// GoldenResult 解码器和分析器对一个 fixture 的输出，即 *.golden.json 的内容
+type GoldenResult struct {
+	From       common.Address    `json:"from"`
+	Call       *DecodedCall      `json:"call"`
+	Logs       []*DecodedLog     `json:"logs"` // 与 fixture 的日志一一对应，无法解码的为 null
+	Permits    []SignedPermit    `json:"permits"`
+	Aggregator []AggregatorRoute `json:"aggregator"`
+	UserOps    []UserOperation   `json:"user_ops"`
+	CEL        struct {
+		Tx   CELTx   `json:"tx"`
+		Call CELCall `json:"call"`
+	} `json:"cel"` // 表达式中 tx / call 变量的值
+	Approvals []GoldenApproval `json:"approvals"` // 日志中的 ERC-20 Approval，授权监控据此维护授权列表
+	Whale     *WhaleTx         `json:"whale"`     // 按 goldenWhale 的阈值判断
+	Sandwich  []SandwichAlert  `json:"sandwich"`  // 发送者作为受害者被夹，不查询池子的代币（token 为空）
+	Bundles   []Bundle         `json:"bundles"`   // 区块上下文中的 bundle，没有调用树，只看顶层的 coinbase 转账
+}
+
+// GoldenApproval 一条 Approval 日志
+type GoldenApproval struct {
+	Token     common.Address `json:"token"`
+	Owner     common.Address `json:"owner"`
+	Spender   common.Address `json:"spender"`
+	Amount    string         `json:"amount"`
+	Unlimited bool           `json:"unlimited"`
+}
+
+// runGolden 回放全部 fixture 并与 golden 文件比较；-update 时改为重新生成 golden 文件，-record 时录制一笔交易
+func runGolden(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("golden", flag.ExitOnError)
+	dir := fs.String("dir", DefaultGoldenDir, "fixture 目录")
+	update := fs.Bool("update", false, "重新生成 golden 文件（确认输出的变化是预期的之后使用）")
+	only := fs.String("run", "", "只运行名称包含该字符串的 fixture")
+	record := fs.String("record", "", "连接节点录制这笔交易（交易哈希）及其日志")
+	name := fs.String("name", "", "录制的 fixture 名称，默认使用交易哈希的前 10 个字符")
+	fetch := fs.Bool("fetch", false, "录制时拉取涉及合约的已验证 ABI 并保存到 fixture 中")
+	around := fs.Int("context", 0, "录制时同时保存同一区块中前后各几笔交易（三明治和 bundle 检测需要）")
+	configPath := fs.String("config", DefaultConfigPath, "配置文件路径（录制时使用）")
+	fs.Parse(args)
+
+	if *record != "" {
+		return recordGolden(ctx, *configPath, *dir, *record, *name, *fetch, *around)
+	}
+
+	paths, err := goldenFixtures(*dir)
+	if err != nil {
+		return err
+	}
+	var failed []string
+	ran := 0
+	for _, path := range paths {
+		fixture := strings.TrimSuffix(filepath.Base(path), ".json")
+		if *only != "" && !strings.Contains(fixture, *only) {
+			continue
+		}
+		ran++
+		got, err := goldenOutput(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", fixture, err)
+		}
+		goldenPath := strings.TrimSuffix(path, ".json") + goldenSuffix
+		if *update {
+			if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+				return fmt.Errorf("写入 %s 失败: %w", goldenPath, err)
+			}
+			fmt.Printf("📝 %s 已更新\n", fixture)
+			continue
+		}
+		want, err := os.ReadFile(goldenPath)
+		if err != nil {
+			return fmt.Errorf("%s: 读取 golden 文件失败（新的 fixture 先用 -update 生成）: %w", fixture, err)
+		}
+		if diff := goldenDiff(want, got); diff != "" {
+			failed = append(failed, fixture)
+			fmt.Printf("❌ %s\n%s", fixture, diff)
+			continue
+		}
+		fmt.Printf("✅ %s\n", fixture)
+	}
+	if ran == 0 {
+		return fmt.Errorf("%s 中没有匹配的 fixture", *dir)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("golden: %d / %d 个 fixture 的输出发生变化: %s（确认是预期的变化后用 -update 更新）", len(failed), ran, strings.Join(failed, ", "))
+	}
+	if !*update {
+		log.Printf("✅ golden: %d 个 fixture 全部一致", ran)
+	}
+	return nil
+}
+
+// goldenFixtures 目录中的 fixture 文件，按名称排序
+func goldenFixtures(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, p := range matches {
+		if !strings.HasSuffix(p, goldenSuffix) {
+			out = append(out, p)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// goldenOutput 读取 fixture 并生成格式化的输出
+func goldenOutput(path string) ([]byte, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f GoldenFixture
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, fmt.Errorf("解析 fixture 失败: %w", err)
+	}
+	res, err := f.Run()
+	if err != nil {
+		return nil, err
+	}
+	out, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// Run 离线运行解码器和分析器；只使用内置的函数 / 事件定义和 fixture 中的 ABI，不读取配置中的签名文件，
+// 保证同一个 fixture 在任何机器上的输出都相同
+func (f GoldenFixture) Run() (*GoldenResult, error) {
+	abis := NewABIRegistry()
+	for addr, raw := range f.ABIs {
+		parsed, err := abi.JSON(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("解析 %s 的 ABI 失败: %w", addr.Hex(), err)
+		}
+		abis.Register(addr, &parsed)
+	}
+
+	tx, from, err := f.transaction()
+	if err != nil {
+		return nil, err
+	}
+	res := &GoldenResult{From: from, Logs: []*DecodedLog{}}
+	to, data := tx.To(), tx.Data()
+	if to != nil && len(data) >= 4 {
+		res.Call, _ = abis.DecodeCall(to, data)
+	}
+	for _, l := range f.Logs {
+		d, _ := abis.Events().Decode(types.Log{Address: l.Address, Topics: l.Topics, Data: l.Data})
+		res.Logs = append(res.Logs, d)
+	}
+	res.Permits = DecodePermits(abis, to, data, from)
+	res.Aggregator = aggregatorRoutes(to, tx.Value(), res.Call)
+	if to != nil {
+		if method, ok := entryPointHandleOps[*to]; ok && equalSelector(data, method.ID) {
+			if res.UserOps, err = decodeHandleOps(*to, method, data); err != nil {
+				return nil, err
+			}
+		}
+	}
+	res.CEL.Tx, res.CEL.Call = newCELTx(tx), newCELCall(res.Call, data)
+
+	// 分析器：交易所在的区块只有这一笔或录制的相邻交易，发送者作为关注地址
+	block, err := f.blockData(tx, from)
+	if err != nil {
+		return nil, err
+	}
+	watch := NewWatchlist([]common.Address{from})
+	for _, l := range block.Receipts[f.index()].Logs {
+		if owner, spender, amount, ok := parseApproval(l); ok {
+			res.Approvals = append(res.Approvals, GoldenApproval{
+				Token: l.Address, Owner: owner, Spender: spender, Amount: amount.String(), Unlimited: amount.BitLen() >= UnlimitedApprovalBits,
+			})
+		}
+	}
+	if w, ok := NewWhaleAlert(goldenWhale, nil, abis, watch, nil, nil).check(tx, from); ok {
+		w.Stage, w.Block = WhaleStageMined, block.Block.NumberU64()
+		res.Whale = &w
+	}
+	NewSandwichDetector(watch, nil, func(a SandwichAlert) { res.Sandwich = append(res.Sandwich, a) }).OnBlock(block)
+	res.Bundles = NewBundleDetector(NewCoinbasePaymentAnalyzer(nil), nil).Detect(block)
+	return res, nil
+}
+
+// index fixture 中的交易在区块上下文中的下标
+func (f GoldenFixture) index() int {
+	if f.Block == nil {
+		return 0
+	}
+	return f.Block.Index
+}
+
+// blockData 组装分析器使用的区块：有 block 时为录制的相邻交易和回执，否则只有这一笔交易（执行成功）
+func (f GoldenFixture) blockData(tx *types.Transaction, from common.Address) (*BlockData, error) {
+	header := &types.Header{Number: new(big.Int)}
+	txs, senders := []*types.Transaction{tx}, []common.Address{from}
+	receipts := []*types.Receipt{{Status: types.ReceiptStatusSuccessful, EffectiveGasPrice: tx.GasPrice(), Logs: goldenLogs(f.Logs)}}
+	if b := f.Block; b != nil {
+		header.Number.SetUint64(b.Number)
+		header.Coinbase, header.Extra = b.Coinbase, b.Extra
+		if b.BaseFee != nil {
+			header.BaseFee = b.BaseFee.ToInt()
+		}
+		signer := types.LatestSignerForChainID(new(big.Int).SetUint64(f.ChainID))
+		txs, senders, receipts = nil, nil, nil
+		for i, bt := range b.Txs {
+			t := new(types.Transaction)
+			if err := t.UnmarshalBinary(bt.RawTx); err != nil {
+				return nil, fmt.Errorf("解析 block.txs[%d] 失败: %w", i, err)
+			}
+			sender, err := types.Sender(signer, t)
+			if err != nil {
+				return nil, fmt.Errorf("恢复 block.txs[%d] 的发送者失败: %w", i, err)
+			}
+			r := &types.Receipt{Status: bt.Status, GasUsed: bt.GasUsed, EffectiveGasPrice: t.GasPrice(), Logs: goldenLogs(bt.Logs)}
+			if bt.EffectiveGasPrice != nil {
+				r.EffectiveGasPrice = bt.EffectiveGasPrice.ToInt()
+			}
+			txs, senders, receipts = append(txs, t), append(senders, sender), append(receipts, r)
+		}
+		if b.Index < 0 || b.Index >= len(txs) || txs[b.Index].Hash() != tx.Hash() {
+			return nil, fmt.Errorf("block.txs[%d] 不是 fixture 中的交易", b.Index)
+		}
+	}
+	block := types.NewBlockWithHeader(header).WithBody(types.Body{Transactions: txs})
+	for i, r := range receipts {
+		r.TxHash, r.BlockNumber, r.TransactionIndex = txs[i].Hash(), header.Number, uint(i)
+		for _, l := range r.Logs {
+			l.TxHash, l.TxIndex, l.BlockNumber = r.TxHash, r.TransactionIndex, header.Number.Uint64()
+		}
+	}
+	return &BlockData{Block: block, Receipts: receipts, Senders: senders, Level: EnrichReceipts}, nil
+}
+
+// goldenLogs 转换为回执中的日志
+func goldenLogs(logs []GoldenLog) []*types.Log {
+	out := make([]*types.Log, len(logs))
+	for i, l := range logs {
+		out[i] = &types.Log{Address: l.Address, Topics: l.Topics, Data: l.Data}
+	}
+	return out
+}
+
+// transaction 解出录制的交易和发送者；手写的 fixture 构造一笔未签名的交易
+func (f GoldenFixture) transaction() (*types.Transaction, common.Address, error) {
+	if len(f.RawTx) > 0 {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(f.RawTx); err != nil {
+			return nil, common.Address{}, fmt.Errorf("解析 raw_tx 失败: %w", err)
+		}
+		from, err := types.Sender(types.LatestSignerForChainID(new(big.Int).SetUint64(f.ChainID)), tx)
+		if err != nil {
+			return nil, common.Address{}, fmt.Errorf("恢复发送者失败: %w", err)
+		}
+		return tx, from, nil
+	}
+	value := new(big.Int)
+	if f.Value != "" {
+		if _, ok := value.SetString(f.Value, 10); !ok {
+			return nil, common.Address{}, fmt.Errorf("value 不是十进制整数: %q", f.Value)
+		}
+	}
+	return types.NewTx(&types.LegacyTx{To: f.To, Value: value, Data: f.Input}), f.From, nil
+}
+
+// goldenDiff 逐行比较，返回前几处不同；相同时返回空字符串
+func goldenDiff(want, got []byte) string {
+	if bytes.Equal(want, got) {
+		return ""
+	}
+	const maxLines = 5
+	wl, gl := strings.Split(string(want), "\n"), strings.Split(string(got), "\n")
+	var sb strings.Builder
+	shown := 0
+	for i := 0; i < len(wl) || i < len(gl); i++ {
+		var w, g string
+		if i < len(wl) {
+			w = wl[i]
+		}
+		if i < len(gl) {
+			g = gl[i]
+		}
+		if w == g {
+			continue
+		}
+		if shown == maxLines {
+			sb.WriteString("   ...\n")
+			break
+		}
+		fmt.Fprintf(&sb, "   第 %d 行\n   - %s\n   + %s\n", i+1, strings.TrimSpace(w), strings.TrimSpace(g))
+		shown++
+	}
+	return sb.String()
+}
+
+// recordGolden 连接节点录制交易和回执中的日志，写入 fixture 并生成 golden 文件；around > 0 时同时录制区块上下文
+func recordGolden(ctx context.Context, configPath, dir, hash, name string, fetch bool, around int) error {
+	if len(strings.TrimPrefix(hash, "0x")) != 2*common.HashLength {
+		return fmt.Errorf("-record 需要 32 字节的交易哈希: %q", hash)
+	}
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	clients, err := Dial(cfg)
+	if err != nil {
+		return err
+	}
+	defer clients.Close()
+
+	h := common.HexToHash(hash)
+	var tx *types.Transaction
+	var pending bool
+	err = Retry(ctx, "eth_getTransactionByHash", func() (err error) {
+		tx, pending, err = clients.Eth.TransactionByHash(ctx, h)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("查询交易失败: %w", err)
+	}
+	if pending {
+		return fmt.Errorf("交易 %s 还未上链", h.Hex())
+	}
+	var receipt *types.Receipt
+	err = Retry(ctx, "eth_getTransactionReceipt", func() (err error) {
+		receipt, err = clients.Eth.TransactionReceipt(ctx, h)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("查询回执失败: %w", err)
+	}
+	chainID, err := chainIDRetry(ctx, clients.Eth)
+	if err != nil {
+		return fmt.Errorf("查询 ChainID 失败: %w", err)
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(chainID), tx)
+	if err != nil {
+		return fmt.Errorf("恢复发送者失败: %w", err)
+	}
+
+	f := GoldenFixture{
+		Note:    fmt.Sprintf("区块 %d 中的交易 %s", receipt.BlockNumber, h.Hex()),
+		ChainID: chainID.Uint64(),
+		RawTx:   raw,
+		From:    from,
+		To:      tx.To(),
+		Value:   tx.Value().String(),
+		Input:   tx.Data(),
+	}
+	for _, l := range receipt.Logs {
+		f.Logs = append(f.Logs, GoldenLog{Address: l.Address, Topics: l.Topics, Data: l.Data})
+	}
+	if fetch {
+		f.ABIs = fetchGoldenABIs(ctx, cfg, clients, chainID, tx, receipt.Logs)
+	}
+	if around > 0 {
+		if f.Block, err = recordGoldenBlock(ctx, clients, receipt, around); err != nil {
+			return fmt.Errorf("录制区块上下文失败: %w", err)
+		}
+	}
+
+	if name == "" {
+		name = strings.ToLower(h.Hex()[:10])
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, name+".json")
+	body, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(body, '\n'), 0o644); err != nil {
+		return err
+	}
+	out, err := goldenOutput(path)
+	if err != nil {
+		return err
+	}
+	goldenPath := strings.TrimSuffix(path, ".json") + goldenSuffix
+	if err := os.WriteFile(goldenPath, out, 0o644); err != nil {
+		return err
+	}
+	log.Printf("✅ 已录制 %s（%d 条日志，%d 个 ABI），请检查 %s 中的输出是否正确", path, len(f.Logs), len(f.ABIs), goldenPath)
+	return nil
+}
+
+// recordGoldenBlock 录制交易所在区块的头部字段，以及前后各 around 笔交易和它们的回执
+func recordGoldenBlock(ctx context.Context, clients *Clients, receipt *types.Receipt, around int) (*GoldenBlock, error) {
+	var block *types.Block
+	err := Retry(ctx, "eth_getBlockByHash", func() (err error) {
+		block, err = clients.Eth.BlockByHash(ctx, receipt.BlockHash)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	header, txs := block.Header(), block.Transactions()
+	index := int(receipt.TransactionIndex)
+	start, end := max(index-around, 0), min(index+around+1, len(txs))
+	b := &GoldenBlock{Number: header.Number.Uint64(), Coinbase: header.Coinbase, Extra: header.Extra, Index: index - start}
+	if header.BaseFee != nil {
+		b.BaseFee = (*hexutil.Big)(header.BaseFee)
+	}
+	for _, tx := range txs[start:end] {
+		raw, err := tx.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		var r *types.Receipt
+		err = Retry(ctx, "eth_getTransactionReceipt", func() (err error) {
+			r, err = clients.Eth.TransactionReceipt(ctx, tx.Hash())
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		bt := GoldenBlockTx{RawTx: raw, Status: r.Status, GasUsed: r.GasUsed, EffectiveGasPrice: (*hexutil.Big)(r.EffectiveGasPrice)}
+		for _, l := range r.Logs {
+			bt.Logs = append(bt.Logs, GoldenLog{Address: l.Address, Topics: l.Topics, Data: l.Data})
+		}
+		b.Txs = append(b.Txs, bt)
+	}
+	return b, nil
+}
+
+// fetchGoldenABIs 拉取接收方、内层调用目标和日志合约的已验证 ABI，返回原始 JSON
+func fetchGoldenABIs(ctx context.Context, cfg *Config, clients *Clients, chainID *big.Int, tx *types.Transaction, logs []*types.Log) map[common.Address]json.RawMessage {
+	abis := NewABIRegistry()
+	fetcher := NewABIFetcher(cfg.ABI, clients.Eth, abis, chainID)
+	addrs := make(map[common.Address]bool)
+	if to := tx.To(); to != nil {
+		addrs[*to] = true
+		fetcher.Fetch(ctx, *to)
+		if call, ok := abis.DecodeCall(to, tx.Data()); ok {
+			for _, t := range call.Targets() {
+				addrs[t] = true
+			}
+		}
+	}
+	for _, l := range logs {
+		addrs[l.Address] = true
+	}
+	out := make(map[common.Address]json.RawMessage)
+	for addr := range addrs {
+		fetcher.Fetch(ctx, addr)
+		if raw, ok := fetcher.CachedABI(addr); ok {
+			out[addr] = raw
+		}
+	}
+	return out
+}
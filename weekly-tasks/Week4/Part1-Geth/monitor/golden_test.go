@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// go test -run TestGolden -update 重新生成 golden 文件（确认输出的变化是预期的之后使用）
+var updateGolden = flag.Bool("update", false, "重新生成 fixtures 中的 golden 文件")
+
+// TestGolden 回放 fixtures 中的全部交易，输出与 *.golden.json 逐字比较
+func TestGolden(t *testing.T) {
+	paths, err := goldenFixtures("fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("fixtures 中没有 fixture")
+	}
+	// 每条解码路径和每个分析器至少要有一个 fixture 覆盖，否则对应的输出恒为 null，golden 比较发现不了回归
+	var rawTx, permits, aggregator, userOps, approvals, whale, sandwich, bundles bool
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		t.Run(name, func(t *testing.T) {
+			got, err := goldenOutput(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			goldenPath := strings.TrimSuffix(path, ".json") + goldenSuffix
+			if *updateGolden {
+				if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				want, err := os.ReadFile(goldenPath)
+				if err != nil {
+					t.Fatalf("读取 golden 文件失败（新的 fixture 先用 -update 生成）: %v", err)
+				}
+				if diff := goldenDiff(want, got); diff != "" {
+					t.Errorf("输出发生变化（确认是预期的变化后用 -update 更新）:\n%s", diff)
+				}
+			}
+
+			var f GoldenFixture
+			var res GoldenResult
+			raw, _ := os.ReadFile(path)
+			if json.Unmarshal(raw, &f) != nil || json.Unmarshal(got, &res) != nil {
+				t.Fatal("解析 fixture / 输出失败")
+			}
+			rawTx = rawTx || len(f.RawTx) > 0
+			permits = permits || len(res.Permits) > 0
+			aggregator = aggregator || len(res.Aggregator) > 0
+			userOps = userOps || len(res.UserOps) > 0
+			approvals = approvals || len(res.Approvals) > 0
+			whale = whale || res.Whale != nil
+			sandwich = sandwich || len(res.Sandwich) > 0
+			bundles = bundles || len(res.Bundles) > 0
+		})
+	}
+	coverage := map[string]bool{
+		"raw_tx": rawTx, "permits": permits, "aggregator": aggregator, "user_ops": userOps,
+		"approvals": approvals, "whale": whale, "sandwich": sandwich, "bundles": bundles,
+	}
+	for path, covered := range coverage {
+		if !covered {
+			t.Errorf("没有 fixture 覆盖 %s，用 monitor golden -record 录制一笔相关的交易", path)
+		}
+	}
+}
@@ -0,0 +1,550 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ------------------------------------------------
+// 治理提案监控：解码 OpenZeppelin Governor / Compound GovernorBravo 的提案、投票和排队事件，
+// 跟踪每个提案的投票进度和法定人数，提案操作涉及关注合约时在创建、达到法定人数、排队、可以执行、执行时提醒
+// ------------------------------------------------
+
+const (
+	// 涉及关注合约的未结束提案，每隔多少个区块查询一次 state()
+	GovernanceStateBlocks = 10
+	// /api/governance 中保留的已结束提案数量
+	GovernanceHistory = 100
+)
+
+// OZ Governor 和 GovernorBravo 的事件签名相同（Bravo 的 uint 即 uint256）
+// quorum(timepoint) 只有 OZ 有，quorumVotes() 只有 Bravo 有；state() 的枚举顺序两者一致
+const governorABIJSON = `[
+	{"type":"event","name":"ProposalCreated","inputs":[{"name":"proposalId","type":"uint256","indexed":false},{"name":"proposer","type":"address","indexed":false},{"name":"targets","type":"address[]","indexed":false},{"name":"values","type":"uint256[]","indexed":false},{"name":"signatures","type":"string[]","indexed":false},{"name":"calldatas","type":"bytes[]","indexed":false},{"name":"voteStart","type":"uint256","indexed":false},{"name":"voteEnd","type":"uint256","indexed":false},{"name":"description","type":"string","indexed":false}]},
+	{"type":"event","name":"VoteCast","inputs":[{"name":"voter","type":"address","indexed":true},{"name":"proposalId","type":"uint256","indexed":false},{"name":"support","type":"uint8","indexed":false},{"name":"weight","type":"uint256","indexed":false},{"name":"reason","type":"string","indexed":false}]},
+	{"type":"event","name":"VoteCastWithParams","inputs":[{"name":"voter","type":"address","indexed":true},{"name":"proposalId","type":"uint256","indexed":false},{"name":"support","type":"uint8","indexed":false},{"name":"weight","type":"uint256","indexed":false},{"name":"reason","type":"string","indexed":false},{"name":"params","type":"bytes","indexed":false}]},
+	{"type":"event","name":"ProposalQueued","inputs":[{"name":"proposalId","type":"uint256","indexed":false},{"name":"eta","type":"uint256","indexed":false}]},
+	{"type":"event","name":"ProposalExecuted","inputs":[{"name":"proposalId","type":"uint256","indexed":false}]},
+	{"type":"event","name":"ProposalCanceled","inputs":[{"name":"proposalId","type":"uint256","indexed":false}]},
+	{"type":"function","name":"state","inputs":[{"name":"proposalId","type":"uint256"}],"outputs":[{"name":"","type":"uint8"}],"stateMutability":"view"},
+	{"type":"function","name":"quorum","inputs":[{"name":"timepoint","type":"uint256"}],"outputs":[{"name":"","type":"uint256"}],"stateMutability":"view"},
+	{"type":"function","name":"quorumVotes","inputs":[],"outputs":[{"name":"","type":"uint256"}],"stateMutability":"view"}
+]`
+
+var governorABI = mustParseABI(governorABIJSON)
+
+// state() 的返回值
+var proposalStates = []string{"Pending", "Active", "Canceled", "Defeated", "Succeeded", "Queued", "Expired", "Executed"}
+
+const (
+	ProposalSucceeded = "Succeeded"
+	ProposalQueued    = "Queued"
+)
+
+// 治理提醒的类型
+const (
+	GovCreated    = "created"
+	GovQuorum     = "quorum"
+	GovQueued     = "queued"
+	GovExecutable = "executable"
+	GovExecuted   = "executed"
+	GovCanceled   = "canceled"
+	GovDefeated   = "defeated"
+)
+
+// GovernanceConfig 治理合约监控，governors 为空时不启动
+type GovernanceConfig struct {
+	Governors []string `json:"governors"`
+	// 额外关注的目标合约；关注列表中的地址也算，提案操作涉及这些合约时提醒
+	Targets []string `json:"targets"`
+}
+
+// GovAction 提案中的一个操作
+type GovAction struct {
+	Target    common.Address `json:"target"`
+	Value     *big.Int       `json:"value"`
+	Signature string         `json:"signature,omitempty"` // Bravo 风格：函数签名和参数分开存放
+	Call      string         `json:"call"`
+}
+
+// Proposal 一个提案的投票进度
+type Proposal struct {
+	Governor    common.Address   `json:"governor"`
+	ID          *big.Int         `json:"id"`
+	Proposer    common.Address   `json:"proposer"`
+	Title       string           `json:"title"` // 描述的第一行
+	Actions     []GovAction      `json:"actions"`
+	VoteStart   uint64           `json:"vote_start"` // 区块号或时间戳，取决于治理代币的 clock
+	VoteEnd     uint64           `json:"vote_end"`
+	For         *big.Int         `json:"for"`
+	Against     *big.Int         `json:"against"`
+	Abstain     *big.Int         `json:"abstain"`
+	Voters      int              `json:"voters"`
+	Quorum      *big.Int         `json:"quorum"` // 还没查询到时为 nil
+	State       string           `json:"state"`
+	ETA         uint64           `json:"eta,omitempty"` // 排队后可以执行的时间戳
+	Watched     []common.Address `json:"watched,omitempty"`
+	Block       uint64           `json:"block"`
+	TxHash      common.Hash      `json:"tx_hash"`
+	quorumVotes *big.Int         // 计入法定人数的票数：OZ 为赞成 + 弃权，Bravo 只算赞成
+	executable  bool             // 已经提醒过可以执行
+}
+
+// GovernanceAlert 一次治理提醒
+type GovernanceAlert struct {
+	Kind     string   `json:"kind"`
+	Block    uint64   `json:"block"`
+	Proposal Proposal `json:"proposal"`
+}
+
+// governorInfo 每个治理合约的计票方式
+type governorInfo struct {
+	bravo bool // 有 quorumVotes()，法定人数只算赞成票
+}
+
+// GovernanceMonitor 监控治理合约的提案
+type GovernanceMonitor struct {
+	client    *ethclient.Client
+	abis      *ABIRegistry
+	watch     *Watchlist
+	targets   map[common.Address]bool
+	governors map[common.Address]*governorInfo
+
+	mu        sync.RWMutex
+	proposals map[string]*Proposal // governor/id
+	finished  []*Proposal
+
+	onAlert func(GovernanceAlert)
+}
+
+// NewGovernanceMonitor 创建治理监控，onAlert 为 nil 时使用默认输出
+func NewGovernanceMonitor(cfg GovernanceConfig, client *ethclient.Client, abis *ABIRegistry, watch *Watchlist, onAlert func(GovernanceAlert)) *GovernanceMonitor {
+	if onAlert == nil {
+		onAlert = PrintGovernanceAlert
+	}
+	m := &GovernanceMonitor{
+		client:    client,
+		abis:      abis,
+		watch:     watch,
+		targets:   make(map[common.Address]bool),
+		governors: make(map[common.Address]*governorInfo),
+		proposals: make(map[string]*Proposal),
+		onAlert:   onAlert,
+	}
+	for _, g := range cfg.Governors {
+		m.governors[common.HexToAddress(g)] = &governorInfo{}
+	}
+	for _, t := range cfg.Targets {
+		m.targets[common.HexToAddress(t)] = true
+	}
+	return m
+}
+
+// Probe 判断每个治理合约是 OZ Governor 还是 GovernorBravo（决定法定人数的计票方式）
+func (m *GovernanceMonitor) Probe(ctx context.Context) {
+	for addr, info := range m.governors {
+		if _, err := m.call(ctx, addr, nil, "quorumVotes"); err == nil {
+			info.bravo = true
+		}
+		kind := "OpenZeppelin Governor"
+		if info.bravo {
+			kind = "GovernorBravo"
+		}
+		log.Printf("🏛️  治理合约 %s: %s", addr.Hex(), kind)
+	}
+}
+
+// call 调用治理合约的只读函数，返回第一个返回值
+func (m *GovernanceMonitor) call(ctx context.Context, governor common.Address, block *big.Int, method string, args ...interface{}) (interface{}, error) {
+	input, err := governorABI.Pack(method, args...)
+	if err != nil {
+		return nil, err
+	}
+	out, err := callContractRetry(ctx, m.client, ethereum.CallMsg{To: &governor, Data: input}, block)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	values, err := governorABI.Unpack(method, out)
+	if err != nil || len(values) == 0 {
+		return nil, fmt.Errorf("%s 返回数据格式错误", method)
+	}
+	return values[0], nil
+}
+
+// OnBlock 实现 BlockAnalyzer
+func (m *GovernanceMonitor) OnBlock(data *BlockData) {
+	number := data.Block.NumberU64()
+	for _, r := range data.Receipts {
+		if r.Status == 0 {
+			continue
+		}
+		for _, l := range r.Logs {
+			if _, ok := m.governors[l.Address]; !ok || len(l.Topics) == 0 {
+				continue
+			}
+			m.handleLog(l)
+		}
+	}
+	m.checkStates(data.Block)
+	if number%GovernanceStateBlocks == 0 {
+		m.prune()
+	}
+}
+
+// handleLog 处理治理合约的一条日志
+func (m *GovernanceMonitor) handleLog(l *types.Log) {
+	ev, err := governorABI.EventByID(l.Topics[0])
+	if err != nil {
+		return
+	}
+	values, err := governorABI.Unpack(ev.Name, l.Data)
+	if err != nil {
+		log.Printf("⚠️  解码治理事件 %s 失败: %v", ev.Name, err)
+		return
+	}
+	// 所有事件的第一个非 indexed 字段都是 proposalId
+	id, _ := values[0].(*big.Int)
+	if id == nil {
+		return
+	}
+
+	switch ev.Name {
+	case "ProposalCreated":
+		p := m.newProposal(l, values)
+		m.mu.Lock()
+		m.proposals[proposalKey(l.Address, id)] = p
+		m.mu.Unlock()
+		if len(p.Watched) > 0 {
+			m.onAlert(GovernanceAlert{Kind: GovCreated, Block: l.BlockNumber, Proposal: p.snapshot()})
+		}
+	case "VoteCast", "VoteCastWithParams":
+		support, _ := values[1].(uint8)
+		weight, _ := values[2].(*big.Int)
+		m.vote(l, id, support, weight)
+	case "ProposalQueued":
+		eta, _ := values[1].(*big.Int)
+		m.update(l.Address, id, l.BlockNumber, GovQueued, func(p *Proposal) {
+			p.State = ProposalQueued
+			if eta != nil {
+				p.ETA = eta.Uint64()
+			}
+		})
+	case "ProposalExecuted":
+		m.update(l.Address, id, l.BlockNumber, GovExecuted, func(p *Proposal) { p.State = "Executed" })
+	case "ProposalCanceled":
+		m.update(l.Address, id, l.BlockNumber, GovCanceled, func(p *Proposal) { p.State = "Canceled" })
+	}
+}
+
+// newProposal 从 ProposalCreated 构造提案，解码每个操作并标记涉及的关注合约
+func (m *GovernanceMonitor) newProposal(l *types.Log, values []interface{}) *Proposal {
+	id, _ := values[0].(*big.Int)
+	proposer, _ := values[1].(common.Address)
+	targets, _ := values[2].([]common.Address)
+	amounts, _ := values[3].([]*big.Int)
+	signatures, _ := values[4].([]string)
+	calldatas, _ := values[5].([][]byte)
+	start, _ := values[6].(*big.Int)
+	end, _ := values[7].(*big.Int)
+	description, _ := values[8].(string)
+
+	p := &Proposal{
+		Governor: l.Address, ID: id, Proposer: proposer,
+		Title: strings.TrimLeft(strings.SplitN(strings.TrimSpace(description), "\n", 2)[0], "# "),
+		For:   new(big.Int), Against: new(big.Int), Abstain: new(big.Int), quorumVotes: new(big.Int),
+		State: "Pending", Block: l.BlockNumber, TxHash: l.TxHash,
+	}
+	if start != nil && end != nil {
+		p.VoteStart, p.VoteEnd = start.Uint64(), end.Uint64()
+	}
+	seen := make(map[common.Address]bool)
+	mark := func(addr common.Address) {
+		if !seen[addr] && (m.targets[addr] || m.watch.Contains(addr)) {
+			seen[addr] = true
+			p.Watched = append(p.Watched, addr)
+		}
+	}
+	for i, target := range targets {
+		a := GovAction{Target: target, Value: new(big.Int)}
+		if i < len(amounts) && amounts[i] != nil {
+			a.Value = amounts[i]
+		}
+		var data []byte
+		if i < len(calldatas) {
+			data = calldatas[i]
+		}
+		if i < len(signatures) && signatures[i] != "" {
+			// Bravo：calldata 只有参数，需要补上函数签名的 selector
+			a.Signature = signatures[i]
+			data = append(crypto.Keccak256([]byte(signatures[i]))[:4], data...)
+		}
+		mark(target)
+		if call, ok := m.abis.DecodeCall(&target, data); ok {
+			a.Call = call.String()
+			for _, inner := range call.Targets() {
+				mark(inner)
+			}
+		} else if len(data) == 0 {
+			a.Call = "(转账)"
+		}
+		p.Actions = append(p.Actions, a)
+	}
+	return p
+}
+
+// vote 记录一张投票，计入法定人数的票数首次达到法定人数时提醒
+func (m *GovernanceMonitor) vote(l *types.Log, id *big.Int, support uint8, weight *big.Int) {
+	if weight == nil {
+		return
+	}
+	bravo := m.governors[l.Address].bravo
+	m.mu.Lock()
+	p := m.proposals[proposalKey(l.Address, id)]
+	if p == nil {
+		m.mu.Unlock()
+		return // 启动之前创建的提案，没有操作和计票基数
+	}
+	before := new(big.Int).Set(p.quorumVotes)
+	switch support {
+	case 0:
+		p.Against.Add(p.Against, weight)
+	case 1:
+		p.For.Add(p.For, weight)
+		p.quorumVotes.Add(p.quorumVotes, weight)
+	case 2:
+		p.Abstain.Add(p.Abstain, weight)
+		if !bravo {
+			p.quorumVotes.Add(p.quorumVotes, weight)
+		}
+	}
+	p.Voters++
+	if p.State == "Pending" {
+		p.State = "Active"
+	}
+	needQuorum := p.Quorum == nil && len(p.Watched) > 0
+	m.mu.Unlock()
+
+	if needQuorum {
+		m.fetchQuorum(p, bravo, new(big.Int).SetUint64(l.BlockNumber))
+	}
+	m.mu.Lock()
+	reached := p.Quorum != nil && p.Quorum.Sign() > 0 && before.Cmp(p.Quorum) < 0 && p.quorumVotes.Cmp(p.Quorum) >= 0
+	var snap Proposal
+	if reached {
+		snap = p.snapshot()
+	}
+	m.mu.Unlock()
+	if reached && len(snap.Watched) > 0 {
+		m.onAlert(GovernanceAlert{Kind: GovQuorum, Block: l.BlockNumber, Proposal: snap})
+	}
+}
+
+// fetchQuorum 查询提案的法定人数：OZ 按投票开始的时间点查询，Bravo 是固定值
+func (m *GovernanceMonitor) fetchQuorum(p *Proposal, bravo bool, block *big.Int) {
+	ctx, cancel := context.WithTimeout(context.Background(), CONNECTION_TIMEOUT)
+	defer cancel()
+	var out interface{}
+	var err error
+	if bravo {
+		out, err = m.call(ctx, p.Governor, block, "quorumVotes")
+	} else {
+		out, err = m.call(ctx, p.Governor, block, "quorum", new(big.Int).SetUint64(p.VoteStart))
+	}
+	if err != nil {
+		log.Printf("⚠️  查询提案 %s 的法定人数失败: %v", p.ID, err)
+		return
+	}
+	if q, ok := out.(*big.Int); ok {
+		m.mu.Lock()
+		p.Quorum = q
+		m.mu.Unlock()
+	}
+}
+
+// update 按事件更新提案状态，涉及关注合约时提醒
+func (m *GovernanceMonitor) update(governor common.Address, id *big.Int, block uint64, kind string, apply func(*Proposal)) {
+	m.mu.Lock()
+	p := m.proposals[proposalKey(governor, id)]
+	if p == nil {
+		m.mu.Unlock()
+		return
+	}
+	apply(p)
+	snap := p.snapshot()
+	m.mu.Unlock()
+	if len(snap.Watched) > 0 {
+		m.onAlert(GovernanceAlert{Kind: kind, Block: block, Proposal: snap})
+	}
+}
+
+// checkStates 涉及关注合约的未结束提案：定期查询 state()，投票结束后提醒结果；
+// 排队的提案到达 eta、或没有时间锁的提案投票通过时提醒可以执行
+func (m *GovernanceMonitor) checkStates(block *types.Block) {
+	number := block.NumberU64()
+	m.mu.RLock()
+	var pending []*Proposal
+	for _, p := range m.proposals {
+		if len(p.Watched) > 0 && !p.executable {
+			pending = append(pending, p)
+		}
+	}
+	m.mu.RUnlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), CONNECTION_TIMEOUT)
+	defer cancel()
+	for _, p := range pending {
+		m.mu.RLock()
+		state, eta := p.State, p.ETA
+		m.mu.RUnlock()
+		if state == ProposalQueued {
+			if eta > 0 && block.Time() >= eta {
+				m.markExecutable(p, number)
+			}
+			continue
+		}
+		if number%GovernanceStateBlocks != 0 {
+			continue
+		}
+		out, err := m.call(ctx, p.Governor, block.Number(), "state", p.ID)
+		if err != nil {
+			continue
+		}
+		idx, _ := out.(uint8)
+		if int(idx) >= len(proposalStates) || proposalStates[idx] == state {
+			continue
+		}
+		next := proposalStates[idx]
+		m.mu.Lock()
+		p.State = next
+		snap := p.snapshot()
+		m.mu.Unlock()
+		switch next {
+		case ProposalSucceeded:
+			// 没有时间锁的 Governor 投票通过后就可以执行；有时间锁的要先排队
+			m.markExecutable(p, number)
+		case "Defeated", "Expired":
+			m.onAlert(GovernanceAlert{Kind: GovDefeated, Block: number, Proposal: snap})
+		}
+	}
+}
+
+// markExecutable 提醒提案可以执行（每个提案只提醒一次）
+func (m *GovernanceMonitor) markExecutable(p *Proposal, block uint64) {
+	m.mu.Lock()
+	if p.executable {
+		m.mu.Unlock()
+		return
+	}
+	p.executable = true
+	snap := p.snapshot()
+	m.mu.Unlock()
+	m.onAlert(GovernanceAlert{Kind: GovExecutable, Block: block, Proposal: snap})
+}
+
+// prune 把已结束的提案移到历史记录
+func (m *GovernanceMonitor) prune() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, p := range m.proposals {
+		switch p.State {
+		case "Executed", "Canceled", "Defeated", "Expired":
+			delete(m.proposals, key)
+			m.finished = append(m.finished, p)
+		}
+	}
+	if n := len(m.finished); n > GovernanceHistory {
+		m.finished = m.finished[n-GovernanceHistory:]
+	}
+}
+
+// snapshot 复制提案（调用方持有锁）
+func (p *Proposal) snapshot() Proposal {
+	c := *p
+	c.For, c.Against, c.Abstain = new(big.Int).Set(p.For), new(big.Int).Set(p.Against), new(big.Int).Set(p.Abstain)
+	return c
+}
+
+func proposalKey(governor common.Address, id *big.Int) string {
+	return governor.Hex() + "/" + id.String()
+}
+
+// Proposals 进行中的提案（按创建区块倒序）和最近结束的提案
+func (m *GovernanceMonitor) Proposals() (active, finished []Proposal) {
+	m.mu.RLock()
+	for _, p := range m.proposals {
+		active = append(active, p.snapshot())
+	}
+	for i := len(m.finished) - 1; i >= 0; i-- {
+		finished = append(finished, m.finished[i].snapshot())
+	}
+	m.mu.RUnlock()
+	sort.Slice(active, func(i, j int) bool { return active[i].Block > active[j].Block })
+	return active, finished
+}
+
+// RegisterAPI 注册治理提案查询接口
+//
+//	GET /api/governance 启动以来观察到的提案、投票进度和法定人数
+func (m *GovernanceMonitor) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/governance", func(w http.ResponseWriter, r *http.Request) {
+		active, finished := m.Proposals()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"active": active, "finished": finished})
+	})
+}
+
+// PrintGovernanceAlert 默认的治理提醒
+func PrintGovernanceAlert(a GovernanceAlert) {
+	p := a.Proposal
+	var what string
+	switch a.Kind {
+	case GovCreated:
+		what = fmt.Sprintf("新提案，投票 %d - %d", p.VoteStart, p.VoteEnd)
+	case GovQuorum:
+		what = fmt.Sprintf("达到法定人数 %s（赞成 %s / 反对 %s / 弃权 %s）", p.Quorum, p.For, p.Against, p.Abstain)
+	case GovQueued:
+		what = fmt.Sprintf("已排队，eta %d", p.ETA)
+	case GovExecutable:
+		what = "⚠️ 现在可以执行"
+	case GovExecuted:
+		what = "已执行"
+	case GovCanceled:
+		what = "已取消"
+	case GovDefeated:
+		what = fmt.Sprintf("未通过（%s）", p.State)
+	}
+	watched := make([]string, len(p.Watched))
+	for i, w := range p.Watched {
+		watched[i] = w.Hex()
+	}
+	summary := fmt.Sprintf("🏛️  [Governance] 区块 %d | %s 提案 %s %q | %s | 涉及 %s",
+		a.Block, p.Governor.Hex(), p.ID, p.Title, what, strings.Join(watched, ", "))
+	var sb strings.Builder
+	sb.WriteString(summary)
+	if a.Kind == GovCreated || a.Kind == GovExecutable {
+		for i, act := range p.Actions {
+			fmt.Fprintf(&sb, "\n   #%d -> %s", i, act.Target.Hex())
+			if act.Value.Sign() > 0 {
+				fmt.Fprintf(&sb, " (%s ETH)", formatUnits(act.Value, 18))
+			}
+			if act.Call != "" {
+				sb.WriteString(" " + act.Call)
+			}
+		}
+	}
+	Emit(Event{Type: "governance", Key: eventKey(a.Proposal.Governor, a.Proposal.ID, a.Kind, a.Block), Summary: summary, Text: sb.String(), Data: a})
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// ------------------------------------------------
+// 区块头链连续性校验：本地保留最近的区块头，检查每个新区块头都能通过 parentHash 接到已知区块上、
+// 高度在同一条分叉上逐个递增；节点推送的数据前后矛盾时提醒（可能是 RPC 服务商的数据完整性问题）
+// ------------------------------------------------
+
+const (
+	// 本地保留的区块头深度（区块数），超过后从最旧的开始删除
+	HeaderChainDepth = 128
+
+	// 父区块未知时（节点漏推了区块头）最多向前补拉的区块头数
+	HeaderChainBackfill = 16
+
+	// /api/headers 中保留的最近问题数量
+	HeaderChainIssueHistory = 50
+)
+
+// 不一致的类型
+const (
+	HeaderNumberMismatch = "number_mismatch" // 高度不等于父区块 + 1
+	HeaderTimeMismatch   = "time_mismatch"   // 时间戳不大于父区块
+	HeaderHashMismatch   = "hash_mismatch"   // 按哈希查询返回的区块头哈希不一致
+	HeaderUnlinked       = "unlinked"        // 向前补拉后仍接不上本地链
+	HeaderStale          = "stale"           // 推送了远低于当前链头的区块头
+)
+
+// HeaderIssue 一次区块头不一致
+type HeaderIssue struct {
+	Kind       string      `json:"kind"`
+	Number     uint64      `json:"number"`
+	Hash       common.Hash `json:"hash"`
+	ParentHash common.Hash `json:"parent_hash"`
+	Detail     string      `json:"detail"`
+	Time       time.Time   `json:"time"`
+}
+
+// HeaderChainStats /api/headers 的返回内容
+type HeaderChainStats struct {
+	TipNumber     uint64        `json:"tip_number"`
+	TipHash       common.Hash   `json:"tip_hash"`
+	Kept          int           `json:"kept"`
+	Headers       uint64        `json:"headers"`    // 收到的区块头数
+	Backfilled    uint64        `json:"backfilled"` // 漏推后补拉的区块头数
+	Reorgs        uint64        `json:"reorgs"`
+	MaxReorgDepth uint64        `json:"max_reorg_depth"`
+	Issues        []HeaderIssue `json:"issues"` // 新的在前
+}
+
+// HeaderChain 本地区块头链，在独立 goroutine 中按到达顺序校验
+type HeaderChain struct {
+	client *ethclient.Client
+	heads  chan *types.Header
+
+	mu     sync.RWMutex
+	byHash map[common.Hash]*types.Header
+	tip    *types.Header
+	stats  HeaderChainStats
+
+	issues *metrics.Counter
+
+	onIssue func(HeaderIssue)
+}
+
+// NewHeaderChain 创建区块头链校验，onIssue 为 nil 时使用默认输出
+func NewHeaderChain(client *ethclient.Client, onIssue func(HeaderIssue)) *HeaderChain {
+	if onIssue == nil {
+		onIssue = PrintHeaderIssue
+	}
+	return &HeaderChain{
+		client:  client,
+		heads:   make(chan *types.Header, 64),
+		byHash:  make(map[common.Hash]*types.Header),
+		issues:  metrics.NewRegisteredCounter("monitor/headers/integrity_issues", metricsRegistry),
+		onIssue: onIssue,
+	}
+}
+
+// NotifyHead 收到新区块头，非阻塞
+func (c *HeaderChain) NotifyHead(header *types.Header) {
+	select {
+	case c.heads <- header:
+	default:
+		log.Printf("⚠️  区块头校验处理不过来，跳过区块 %d", header.Number)
+	}
+}
+
+// Run 在独立 goroutine 中校验区块头，直到 ctx 取消
+func (c *HeaderChain) Run(ctx context.Context) {
+	for {
+		select {
+		case h := <-c.heads:
+			c.process(ctx, h)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// process 把一个新区块头接到本地链上；补拉区块头时不持有锁，/api/headers 不会等待 RPC 请求
+func (c *HeaderChain) process(ctx context.Context, h *types.Header) {
+	c.mu.Lock()
+	c.stats.Headers++
+	if _, ok := c.byHash[h.Hash()]; ok {
+		c.mu.Unlock()
+		return // 重复推送
+	}
+	if c.tip == nil {
+		c.insert(h)
+		c.mu.Unlock()
+		return
+	}
+	tip := c.tip.Number.Uint64()
+	if h.Number.Uint64()+HeaderChainDepth <= tip {
+		c.flag(HeaderStale, h, fmt.Sprintf("当前链头 %d", tip))
+		c.mu.Unlock()
+		return
+	}
+	_, known := c.byHash[h.ParentHash]
+	c.mu.Unlock()
+
+	var missing []*types.Header
+	linked := true
+	if !known {
+		missing, linked = c.backfill(ctx, h, tip)
+	}
+
+	// 补拉期间只有这个 goroutine 修改本地链，这里再确认一次补拉的区块头仍能接上
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.byHash[h.Hash()]; ok {
+		return
+	}
+	if n := len(missing); n > 0 {
+		if _, ok := c.byHash[missing[n-1].ParentHash]; !ok {
+			linked = false
+		}
+	}
+	if !linked {
+		// 接不上本地链：以这个区块头重新开始
+		c.byHash = make(map[common.Hash]*types.Header)
+		c.tip = nil
+		c.insert(h)
+		return
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		if !c.link(missing[i]) {
+			return
+		}
+		c.stats.Backfilled++
+	}
+	c.link(h)
+}
+
+// backfill 按 parentHash 向前补拉漏掉的区块头，返回从新到旧的区块头；接不上时返回 false
+// 调用方不持有锁：查询本地链时加读锁，记录不一致时加写锁
+func (c *HeaderChain) backfill(ctx context.Context, h *types.Header, tip uint64) ([]*types.Header, bool) {
+	var missing []*types.Header
+	want := h.ParentHash
+	for i := 0; i < HeaderChainBackfill; i++ {
+		var parent *types.Header
+		err := Retry(ctx, "eth_getBlockByHash", func() (err error) {
+			fetchCtx, cancel := context.WithTimeout(ctx, CONNECTION_TIMEOUT)
+			defer cancel()
+			parent, err = c.client.HeaderByHash(fetchCtx, want)
+			return err
+		})
+		if err != nil {
+			log.Printf("⚠️  补拉区块头 %s 失败: %v", want.Hex(), err)
+			return nil, false
+		}
+		if parent.Hash() != want {
+			c.mu.Lock()
+			c.flag(HeaderHashMismatch, parent, fmt.Sprintf("按哈希 %s 查询返回了 %s", want.Hex(), parent.Hash().Hex()))
+			c.mu.Unlock()
+			return nil, false
+		}
+		missing = append(missing, parent)
+		c.mu.RLock()
+		_, ok := c.byHash[parent.ParentHash]
+		c.mu.RUnlock()
+		if ok {
+			return missing, true
+		}
+		want = parent.ParentHash
+	}
+	c.mu.Lock()
+	c.flag(HeaderUnlinked, h, fmt.Sprintf("向前补拉 %d 个区块头仍接不上本地链（链头 %d），重新开始", HeaderChainBackfill, tip))
+	c.mu.Unlock()
+	return nil, false
+}
+
+// link 校验区块头和父区块的关系后加入本地链，父区块必须已知
+func (c *HeaderChain) link(h *types.Header) bool {
+	parent := c.byHash[h.ParentHash]
+	if h.Number.Uint64() != parent.Number.Uint64()+1 {
+		c.flag(HeaderNumberMismatch, h, fmt.Sprintf("父区块 %s 的高度为 %d", parent.Hash().Hex(), parent.Number))
+		return false
+	}
+	if h.Time <= parent.Time {
+		c.flag(HeaderTimeMismatch, h, fmt.Sprintf("时间戳 %d 不大于父区块的 %d", h.Time, parent.Time))
+	}
+	if c.tip != nil && h.ParentHash != c.tip.Hash() {
+		depth := c.reorgDepth(h)
+		c.stats.Reorgs++
+		c.stats.MaxReorgDepth = max(c.stats.MaxReorgDepth, depth)
+		log.Printf("♻️  [Reorg] 区块 %d 切换到分叉 %s，回滚 %d 个区块", h.Number, h.Hash().Hex(), depth)
+	}
+	c.insert(h)
+	return true
+}
+
+// reorgDepth 旧链头回滚到新分叉共同祖先的区块数
+func (c *HeaderChain) reorgDepth(h *types.Header) uint64 {
+	ancestors := make(map[common.Hash]bool)
+	for p := c.byHash[h.ParentHash]; p != nil; p = c.byHash[p.ParentHash] {
+		ancestors[p.Hash()] = true
+	}
+	var depth uint64
+	for p := c.tip; p != nil && !ancestors[p.Hash()]; p = c.byHash[p.ParentHash] {
+		depth++
+	}
+	return depth
+}
+
+// insert 加入本地链并设为链头（节点推送的最新区块头就是它认为的主链），删除过旧的区块头
+func (c *HeaderChain) insert(h *types.Header) {
+	c.byHash[h.Hash()] = h
+	c.tip = h
+	for hash, old := range c.byHash {
+		if old.Number.Uint64()+HeaderChainDepth <= h.Number.Uint64() {
+			delete(c.byHash, hash)
+		}
+	}
+}
+
+// flag 记录并输出一次不一致，调用方持有锁
+func (c *HeaderChain) flag(kind string, h *types.Header, detail string) {
+	issue := HeaderIssue{Kind: kind, Number: h.Number.Uint64(), Hash: h.Hash(), ParentHash: h.ParentHash, Detail: detail, Time: time.Now()}
+	c.stats.Issues = append([]HeaderIssue{issue}, c.stats.Issues...)
+	if len(c.stats.Issues) > HeaderChainIssueHistory {
+		c.stats.Issues = c.stats.Issues[:HeaderChainIssueHistory]
+	}
+	c.issues.Inc(1)
+	c.onIssue(issue)
+}
+
+// Stats 当前链头、保留的区块头数和统计
+func (c *HeaderChain) Stats() HeaderChainStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	s := c.stats
+	s.Issues = append([]HeaderIssue(nil), c.stats.Issues...)
+	s.Kept = len(c.byHash)
+	if c.tip != nil {
+		s.TipNumber, s.TipHash = c.tip.Number.Uint64(), c.tip.Hash()
+	}
+	return s
+}
+
+// RegisterAPI 注册区块头链查询接口
+//
+//	GET /api/headers 本地链头、重组统计和最近的不一致
+func (c *HeaderChain) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/headers", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, c.Stats())
+	})
+}
+
+// PrintHeaderIssue 默认的区块头不一致输出
+func PrintHeaderIssue(issue HeaderIssue) {
+	summary := fmt.Sprintf("⛓️  [HeaderChain] ⚠️ %s | 区块 %d %s | %s", issue.Kind, issue.Number, shortHash(issue.Hash), issue.Detail)
+	Emit(Event{Type: "header_integrity", Key: eventKey(issue.Kind, issue.Hash), Summary: summary, Text: summary, Data: issue})
+}
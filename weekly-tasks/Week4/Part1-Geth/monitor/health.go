@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	// 默认的就绪判定：最近一次新区块头在这么多秒以内
+	DefaultReadyMaxHeadAge = 60
+)
+
+// registerHealth 注册健康检查接口，供容器编排 / 负载均衡探测
+//
+//	GET /healthz 进程存活（能响应就返回 200）
+//	GET /readyz  订阅已建立且最近 ready_max_head_age 秒内收到过新区块头时返回 200，否则 503
+func (m *Monitor) registerHealth(api *APIServer) {
+	registerHealthChecks(api, time.Duration(m.cfg.ReadyMaxHeadAge)*time.Second, m.subscribed.Load, m.LastHead)
+}
+
+// registerHealthChecks 注册 /healthz 和 /readyz，实时监控和 watchdog 共用
+func registerHealthChecks(api *APIServer, maxAge time.Duration, subscribed func() bool, lastHead func() time.Time) {
+	api.Handle("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	api.Handle("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		last := lastHead()
+		age := time.Since(last)
+		resp := map[string]interface{}{
+			"subscribed":       subscribed(),
+			"last_head":        last,
+			"head_age_seconds": age.Seconds(),
+		}
+		switch {
+		case !subscribed():
+			resp["reason"] = "订阅尚未建立或已中断"
+		case age > maxAge:
+			resp["reason"] = "新区块头超时，订阅可能卡住或节点落后"
+		default:
+			resp["status"] = "ready"
+			writeJSON(w, http.StatusOK, resp)
+			return
+		}
+		resp["status"] = "not_ready"
+		writeJSON(w, http.StatusServiceUnavailable, resp)
+	})
+}
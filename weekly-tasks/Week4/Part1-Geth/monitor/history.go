@@ -0,0 +1,556 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// ------------------------------------------------
+// 历史查询：把关注地址的交易和输出的全部事件写入 SQLite，通过 /api/history/ 按时间 / 区块范围查询，
+// 结果按时间倒序分页，每页返回下一页的游标（cursor），翻页期间有新数据写入也不会重复或遗漏；
+// 代币转账直接查询事件索引（indexer）中已经写入的 Transfer 事件
+// ------------------------------------------------
+
+const (
+	// 历史数据默认保留天数
+	DefaultHistoryRetention = 30
+	// 每页默认 / 最多返回的条数
+	HistoryDefaultLimit = 100
+	HistoryMaxLimit     = 1000
+	// 事件写入队列，数据库写得慢时丢弃超出的事件，不阻塞输出
+	HistoryEventBuffer = 4096
+)
+
+// HistoryConfig 历史记录，enabled 为 false 时不记录也不提供 /api/history/txs、/api/history/alerts
+type HistoryConfig struct {
+	Enabled   bool   `json:"enabled"`
+	DB        string `json:"db"`             // SQLite 数据库文件，默认与事件索引共用
+	Retention int    `json:"retention_days"` // 保留天数
+}
+
+// HistoryTx 一笔关注地址的已打包交易
+type HistoryTx struct {
+	Hash     common.Hash     `json:"hash"`
+	Block    uint64          `json:"block"`
+	Index    int             `json:"index"` // 区块内的序号
+	Time     time.Time       `json:"time"`
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to"`
+	ValueETH float64         `json:"value_eth"`
+	Method   string          `json:"method,omitempty"` // 函数名，ABI 未知时为 selector
+}
+
+// HistoryAlert 一条已输出的事件
+type HistoryAlert struct {
+	ID       string          `json:"id"`
+	Time     time.Time       `json:"time"`
+	Type     string          `json:"type"`
+	Severity string          `json:"severity"`
+	Category string          `json:"category"`
+	Summary  string          `json:"summary"`
+	Data     json.RawMessage `json:"data,omitempty"`
+}
+
+// HistoryPage 一页查询结果，next_cursor 为空表示没有更多
+type HistoryPage struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor"`
+}
+
+// historyCursor 翻页位置：上一页最后一条的排序键，编码后对调用方不透明
+type historyCursor struct {
+	Key   int64  `json:"k"`           // 区块号或毫秒时间戳
+	Index int64  `json:"i"`           // 同一区块内的交易 / 日志序号
+	ID    string `json:"d,omitempty"` // 同一毫秒内的事件 ID
+}
+
+func (c historyCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// historyQuery 查询参数
+//
+//	limit                 每页条数，默认 100，最多 1000
+//	cursor                上一页返回的 next_cursor
+//	from_block / to_block 区块范围（含两端）
+//	since / until         时间范围，RFC3339 或 Unix 秒
+type historyQuery struct {
+	Limit     int
+	Cursor    *historyCursor
+	FromBlock uint64
+	ToBlock   uint64
+	Since     time.Time
+	Until     time.Time
+}
+
+// parseHistoryQuery 解析通用的分页和范围参数
+func parseHistoryQuery(r *http.Request) (historyQuery, error) {
+	v := r.URL.Query()
+	q := historyQuery{Limit: HistoryDefaultLimit, ToBlock: math.MaxInt64, Until: time.UnixMilli(math.MaxInt64)}
+	if s := v.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > HistoryMaxLimit {
+			return q, fmt.Errorf("limit 需要在 1 到 %d 之间", HistoryMaxLimit)
+		}
+		q.Limit = n
+	}
+	if s := v.Get("cursor"); s != "" {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		var c historyCursor
+		if err != nil || json.Unmarshal(b, &c) != nil {
+			return q, fmt.Errorf("cursor 无效")
+		}
+		q.Cursor = &c
+	}
+	for _, p := range []struct {
+		name string
+		dst  *uint64
+	}{{"from_block", &q.FromBlock}, {"to_block", &q.ToBlock}} {
+		if s := v.Get(p.name); s != "" {
+			n, err := strconv.ParseUint(s, 10, 63)
+			if err != nil {
+				return q, fmt.Errorf("%s 格式错误: %q", p.name, s)
+			}
+			*p.dst = n
+		}
+	}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &q.Since}, {"until", &q.Until}} {
+		if s := v.Get(p.name); s != "" {
+			t, err := parseHistoryTime(s)
+			if err != nil {
+				return q, fmt.Errorf("%s 格式错误: %q（RFC3339 或 Unix 秒）", p.name, s)
+			}
+			*p.dst = t
+		}
+	}
+	return q, nil
+}
+
+// parseHistoryTime 解析 RFC3339 或 Unix 秒
+func parseHistoryTime(s string) (time.Time, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(n, 0), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// queryAddress 解析可选的地址参数
+func queryAddress(r *http.Request, name string) (*common.Address, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return nil, nil
+	}
+	if !common.IsHexAddress(s) {
+		return nil, fmt.Errorf("%s 格式错误: %q", name, s)
+	}
+	addr := common.HexToAddress(s)
+	return &addr, nil
+}
+
+const historySchema = `
+CREATE TABLE IF NOT EXISTS history_txs (
+	hash         TEXT    PRIMARY KEY,
+	block_number INTEGER NOT NULL,
+	block_hash   TEXT    NOT NULL,
+	tx_index     INTEGER NOT NULL,
+	time         INTEGER NOT NULL,
+	from_addr    TEXT    NOT NULL,
+	to_addr      TEXT,
+	value_eth    REAL    NOT NULL,
+	method       TEXT    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS history_txs_from ON history_txs (from_addr, block_number, tx_index);
+CREATE INDEX IF NOT EXISTS history_txs_to ON history_txs (to_addr, block_number, tx_index);
+CREATE INDEX IF NOT EXISTS history_txs_block ON history_txs (block_number, tx_index);
+CREATE TABLE IF NOT EXISTS history_alerts (
+	id       TEXT    PRIMARY KEY,
+	time     INTEGER NOT NULL,
+	type     TEXT    NOT NULL,
+	severity TEXT    NOT NULL,
+	category TEXT    NOT NULL,
+	summary  TEXT    NOT NULL,
+	data     TEXT
+);
+CREATE INDEX IF NOT EXISTS history_alerts_time ON history_alerts (time, id);`
+
+// HistoryStore 历史记录的 SQLite 存储，和事件索引共用同一个数据库文件
+type HistoryStore struct {
+	db *sql.DB
+}
+
+func OpenHistoryStore(path string) (*HistoryStore, error) {
+	db, err := openSQLite(path, historySchema)
+	if err != nil {
+		return nil, err
+	}
+	return &HistoryStore{db: db}, nil
+}
+
+// SaveBlock 写入一个区块中的关注交易；同一高度之前写入的其它区块（重组前的旧区块）的交易先删除
+func (s *HistoryStore) SaveBlock(number uint64, hash common.Hash, txs []HistoryTx) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM history_txs WHERE block_number = ? AND block_hash != ?`, number, hash.Hex()); err != nil {
+		return fmt.Errorf("删除重组区块的交易失败: %w", err)
+	}
+	for _, t := range txs {
+		var to interface{}
+		if t.To != nil {
+			to = t.To.Hex()
+		}
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO history_txs
+			(hash, block_number, block_hash, tx_index, time, from_addr, to_addr, value_eth, method) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			t.Hash.Hex(), t.Block, hash.Hex(), t.Index, t.Time.Unix(), t.From.Hex(), to, t.ValueETH, t.Method); err != nil {
+			return fmt.Errorf("写入交易失败: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// SaveAlerts 写入一批事件，ID 相同的事件（重新处理区块时再次产生）只保留第一次
+func (s *HistoryStore) SaveAlerts(alerts []HistoryAlert) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, a := range alerts {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO history_alerts (id, time, type, severity, category, summary, data) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			a.ID, a.Time.UnixMilli(), a.Type, a.Severity, a.Category, a.Summary, string(a.Data)); err != nil {
+			return fmt.Errorf("写入事件失败: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// QueryTxs 按区块倒序查询交易，addr 不为 nil 时只查该地址发出或收到的
+func (s *HistoryStore) QueryTxs(addr *common.Address, q historyQuery) (HistoryPage, error) {
+	where := []string{"block_number BETWEEN ? AND ?", "time BETWEEN ? AND ?"}
+	args := []interface{}{q.FromBlock, q.ToBlock, q.Since.Unix(), q.Until.Unix()}
+	if addr != nil {
+		where = append(where, "(from_addr = ? OR to_addr = ?)")
+		args = append(args, addr.Hex(), addr.Hex())
+	}
+	if q.Cursor != nil {
+		where = append(where, "(block_number, tx_index) < (?, ?)")
+		args = append(args, q.Cursor.Key, q.Cursor.Index)
+	}
+	args = append(args, q.Limit)
+	rows, err := s.db.Query(`SELECT hash, block_number, tx_index, time, from_addr, to_addr, value_eth, method FROM history_txs
+		WHERE `+strings.Join(where, " AND ")+` ORDER BY block_number DESC, tx_index DESC LIMIT ?`, args...)
+	if err != nil {
+		return HistoryPage{}, fmt.Errorf("查询交易历史失败: %w", err)
+	}
+	defer rows.Close()
+	txs := []HistoryTx{}
+	for rows.Next() {
+		var t HistoryTx
+		var hash, from string
+		var to sql.NullString
+		var secs int64
+		if err := rows.Scan(&hash, &t.Block, &t.Index, &secs, &from, &to, &t.ValueETH, &t.Method); err != nil {
+			return HistoryPage{}, err
+		}
+		t.Hash, t.From, t.Time = common.HexToHash(hash), common.HexToAddress(from), time.Unix(secs, 0)
+		if to.Valid {
+			addr := common.HexToAddress(to.String)
+			t.To = &addr
+		}
+		txs = append(txs, t)
+	}
+	page := HistoryPage{Items: txs}
+	if len(txs) == q.Limit {
+		last := txs[len(txs)-1]
+		page.NextCursor = historyCursor{Key: int64(last.Block), Index: int64(last.Index)}.encode()
+	}
+	return page, rows.Err()
+}
+
+// QueryAlerts 按时间倒序查询事件，severities / types / categories 为空时不限
+func (s *HistoryStore) QueryAlerts(severities, types, categories []string, q historyQuery) (HistoryPage, error) {
+	where := []string{"time BETWEEN ? AND ?"}
+	args := []interface{}{q.Since.UnixMilli(), q.Until.UnixMilli()}
+	for _, f := range []struct {
+		column string
+		values []string
+	}{{"severity", severities}, {"type", types}, {"category", categories}} {
+		if len(f.values) == 0 {
+			continue
+		}
+		where = append(where, f.column+" IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(f.values)), ", ")+")")
+		for _, v := range f.values {
+			args = append(args, v)
+		}
+	}
+	if q.Cursor != nil {
+		where = append(where, "(time, id) < (?, ?)")
+		args = append(args, q.Cursor.Key, q.Cursor.ID)
+	}
+	args = append(args, q.Limit)
+	rows, err := s.db.Query(`SELECT id, time, type, severity, category, summary, data FROM history_alerts
+		WHERE `+strings.Join(where, " AND ")+` ORDER BY time DESC, id DESC LIMIT ?`, args...)
+	if err != nil {
+		return HistoryPage{}, fmt.Errorf("查询事件历史失败: %w", err)
+	}
+	defer rows.Close()
+	alerts := []HistoryAlert{}
+	for rows.Next() {
+		var a HistoryAlert
+		var ms int64
+		var data sql.NullString
+		if err := rows.Scan(&a.ID, &ms, &a.Type, &a.Severity, &a.Category, &a.Summary, &data); err != nil {
+			return HistoryPage{}, err
+		}
+		a.Time = time.UnixMilli(ms)
+		if data.String != "" {
+			a.Data = json.RawMessage(data.String)
+		}
+		alerts = append(alerts, a)
+	}
+	page := HistoryPage{Items: alerts}
+	if len(alerts) == q.Limit {
+		last := alerts[len(alerts)-1]
+		page.NextCursor = historyCursor{Key: last.Time.UnixMilli(), ID: last.ID}.encode()
+	}
+	return page, rows.Err()
+}
+
+// Prune 删除某个时间之前的交易和事件
+func (s *HistoryStore) Prune(before time.Time) error {
+	if _, err := s.db.Exec(`DELETE FROM history_txs WHERE time < ?`, before.Unix()); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`DELETE FROM history_alerts WHERE time < ?`, before.UnixMilli())
+	return err
+}
+
+func (s *HistoryStore) Close() error {
+	return s.db.Close()
+}
+
+// HistoryRecorder 记录关注地址的交易（区块分析器）和输出的全部事件
+type HistoryRecorder struct {
+	cfg   HistoryConfig
+	store *HistoryStore
+	watch *Watchlist
+	abis  *ABIRegistry
+
+	events  chan HistoryAlert
+	dropped *metrics.Counter
+	warned  atomic.Bool // 队列满时只警告一次
+}
+
+// NewHistoryRecorder 创建历史记录器，watch 为需要记录交易的地址
+func NewHistoryRecorder(cfg HistoryConfig, store *HistoryStore, watch *Watchlist, abis *ABIRegistry) *HistoryRecorder {
+	return &HistoryRecorder{
+		cfg:     cfg,
+		store:   store,
+		watch:   watch,
+		abis:    abis,
+		events:  make(chan HistoryAlert, HistoryEventBuffer),
+		dropped: metrics.GetOrRegisterCounter("monitor/history/dropped", metricsRegistry),
+	}
+}
+
+// Enrichment 只需要交易和发送者
+func (h *HistoryRecorder) Enrichment() EnrichLevel { return EnrichTransactions }
+
+// OnBlock 写入区块中关注地址发出或收到的交易
+func (h *HistoryRecorder) OnBlock(data *BlockData) {
+	if h.watch.Len() == 0 {
+		return
+	}
+	block := data.Block
+	var txs []HistoryTx
+	for i, tx := range block.Transactions() {
+		from, to := data.Senders[i], tx.To()
+		if !h.watch.Contains(from) && (to == nil || !h.watch.Contains(*to)) {
+			continue
+		}
+		txs = append(txs, HistoryTx{
+			Hash:     tx.Hash(),
+			Block:    block.NumberU64(),
+			Index:    i,
+			Time:     time.Unix(int64(block.Time()), 0),
+			From:     from,
+			To:       to,
+			ValueETH: weiToEther(tx.Value()),
+			Method:   h.method(tx),
+		})
+	}
+	if err := h.store.SaveBlock(block.NumberU64(), block.Hash(), txs); err != nil {
+		log.Printf("⚠️  写入区块 %d 的交易历史失败: %v", block.NumberU64(), err)
+	}
+}
+
+// method 函数名，ABI 未知时为 selector，纯转账为空
+func (h *HistoryRecorder) method(tx *types.Transaction) string {
+	data := tx.Data()
+	if len(data) < 4 {
+		return ""
+	}
+	if call, ok := h.abis.DecodeCall(tx.To(), data); ok && call.Name != "" {
+		return call.Name
+	}
+	return "0x" + hex.EncodeToString(data[:4])
+}
+
+// RecordEvent 由 Output.Emit 调用，放入写入队列后立即返回
+func (h *HistoryRecorder) RecordEvent(ev Event) {
+	a := HistoryAlert{ID: ev.ID, Time: ev.Time, Type: ev.Type, Severity: ev.Severity, Category: ev.Category, Summary: ev.summary()}
+	if ev.Data != nil {
+		if b, err := json.Marshal(ev.Data); err == nil {
+			a.Data = b
+		}
+	}
+	select {
+	case h.events <- a:
+	default:
+		h.dropped.Inc(1)
+		if h.warned.CompareAndSwap(false, true) {
+			log.Printf("⚠️  事件历史写入队列已满，丢弃新事件（之后不再提示，见指标 monitor/history/dropped）")
+		}
+	}
+}
+
+// Run 批量写入事件，每小时清理一次过期数据，直到 ctx 取消
+func (h *HistoryRecorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	lastPrune := time.Time{}
+	var batch []HistoryAlert
+	for {
+		select {
+		case a := <-h.events:
+			batch = append(batch, a)
+			if len(batch) < HistoryEventBuffer/4 {
+				continue
+			}
+		case <-ticker.C:
+		case <-ctx.Done():
+			for len(h.events) > 0 {
+				batch = append(batch, <-h.events)
+			}
+			if len(batch) > 0 {
+				h.store.SaveAlerts(batch)
+			}
+			return
+		}
+		if len(batch) > 0 {
+			if err := h.store.SaveAlerts(batch); err != nil {
+				log.Printf("⚠️  写入事件历史失败: %v", err)
+			}
+			batch = batch[:0]
+		}
+		if time.Since(lastPrune) > time.Hour {
+			lastPrune = time.Now()
+			if err := h.store.Prune(lastPrune.AddDate(0, 0, -h.cfg.Retention)); err != nil {
+				log.Printf("⚠️  清理历史数据失败: %v", err)
+			}
+		}
+	}
+}
+
+// RegisterAPI 注册历史查询接口，参数见 historyQuery
+//
+//	GET /api/history/txs?address=0x...           关注地址的交易，address 为空时查全部关注地址
+//	GET /api/history/alerts?severity=warn&type=  输出的事件，severity 为最低级别，type / category 可以用逗号分隔多个
+func (h *HistoryRecorder) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/history/txs", func(w http.ResponseWriter, r *http.Request) {
+		q, err := parseHistoryQuery(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		addr, err := queryAddress(r, "address")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		page, err := h.store.QueryTxs(addr, q)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, page)
+	})
+	api.Handle("GET /api/history/alerts", func(w http.ResponseWriter, r *http.Request) {
+		q, err := parseHistoryQuery(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		var severities []string
+		if least := r.URL.Query().Get("severity"); least != "" {
+			rank, ok := severityRank[least]
+			if !ok {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("severity 无效: %q（可选 info / warn / critical）", least))
+				return
+			}
+			for s, n := range severityRank {
+				if n >= rank {
+					severities = append(severities, s)
+				}
+			}
+		}
+		page, err := h.store.QueryAlerts(severities, splitList(r.URL.Query().Get("type")), splitList(r.URL.Query().Get("category")), q)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, page)
+	})
+}
+
+// splitList 解析逗号分隔的参数，空字符串返回 nil
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// history 接收输出事件的历史记录器，未开启时为 nil
+var (
+	historyMu sync.RWMutex
+	history   *HistoryRecorder
+)
+
+// SetHistory 设置接收输出事件的历史记录器
+func SetHistory(h *HistoryRecorder) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	history = h
+}
+
+func currentHistory() *HistoryRecorder {
+	historyMu.RLock()
+	defer historyMu.RUnlock()
+	return history
+}
@@ -0,0 +1,512 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// 主网 Uniswap V2 Router02，模拟买卖走这个路由
+	DefaultUniswapV2Router = "0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D"
+
+	// 模拟买入使用的 ETH 数量（0.1 ETH）
+	// ⚠️ 太大会在浅池子里产生很大的滑点，被误判为高税率
+	HoneypotBuyWei = 1e17
+	// 税率超过该百分比视为高税
+	HoneypotMaxTaxPct = 10.0
+	// owner 持有超过总量该百分比视为筹码集中
+	HoneypotOwnerSharePct = 20.0
+	// 评分达到该值判定为危险，交易类分析器应忽略该代币
+	HoneypotDangerScore = 60
+	// 评分达到该值需要谨慎
+	HoneypotCautionScore = 30
+	// 检查结果的有效期，过期后再次遇到会重新检查（税率可能被 owner 修改）
+	TokenRiskTTL = time.Hour
+	// 待检查队列长度，满了之后新代币会被丢弃，下次出现时再排队
+	HoneypotQueueSize = 256
+)
+
+// 风险等级
+const (
+	RiskSafe    = "safe"
+	RiskCaution = "caution"
+	RiskDanger  = "danger"
+)
+
+var (
+	honeypotRouterABI = mustParseABI(`[
+		{"name":"getAmountsOut","type":"function","stateMutability":"view",
+		 "inputs":[{"name":"amountIn","type":"uint256"},{"name":"path","type":"address[]"}],
+		 "outputs":[{"name":"amounts","type":"uint256[]"}]},
+		{"name":"swapExactETHForTokensSupportingFeeOnTransferTokens","type":"function","stateMutability":"payable",
+		 "inputs":[{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}],
+		 "outputs":[]},
+		{"name":"swapExactTokensForTokensSupportingFeeOnTransferTokens","type":"function","stateMutability":"nonpayable",
+		 "inputs":[{"name":"amountIn","type":"uint256"},{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}],
+		 "outputs":[]}
+	]`)
+	honeypotERC20ABI = mustParseABI(`[
+		{"name":"balanceOf","type":"function","stateMutability":"view","inputs":[{"name":"a","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+		{"name":"totalSupply","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+		{"name":"owner","type":"function","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"address"}]},
+		{"name":"transfer","type":"function","stateMutability":"nonpayable","inputs":[{"name":"to","type":"address"},{"name":"v","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+		{"name":"approve","type":"function","stateMutability":"nonpayable","inputs":[{"name":"s","type":"address"},{"name":"v","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]}
+	]`)
+
+	// 模拟使用的两个虚拟账户（没有代码的 EOA，部分代币会拒绝合约地址）
+	honeypotBuyer    = common.BytesToAddress(crypto.Keccak256([]byte("monitor.honeypot.buyer"))[12:])
+	honeypotReceiver = common.BytesToAddress(crypto.Keccak256([]byte("monitor.honeypot.receiver"))[12:])
+
+	// 字节码中出现这些函数的 selector 说明 owner 可以拉黑地址
+	blacklistSignatures = []string{
+		"blacklist(address)",
+		"addBlackList(address)",
+		"addToBlacklist(address)",
+		"isBlacklisted(address)",
+		"isBlackListed(address)",
+		"setBlacklist(address,bool)",
+		"blockBots(address[])",
+		"setBots(address[],bool)",
+	}
+)
+
+func mustParseABI(s string) abi.ABI {
+	a, err := abi.JSON(strings.NewReader(s))
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// TokenRisk 代币的骗局风险评估
+type TokenRisk struct {
+	Token          common.Address `json:"token"`
+	Score          int            `json:"score"` // 0 - 100，越高越危险
+	Level          string         `json:"level"`
+	Simulated      bool           `json:"simulated"` // 是否完成了买入 -> 卖出模拟
+	BuyTaxPct      float64        `json:"buy_tax_pct"`
+	SellTaxPct     float64        `json:"sell_tax_pct"`
+	TransferTaxPct float64        `json:"transfer_tax_pct"`
+	SellReverted   bool           `json:"sell_reverted"`
+	Blacklist      []string       `json:"blacklist,omitempty"` // 字节码中的拉黑函数
+	Owner          common.Address `json:"owner"`
+	OwnerSharePct  float64        `json:"owner_share_pct"`
+	Reasons        []string       `json:"reasons"`
+	CheckedAt      time.Time      `json:"checked_at"`
+}
+
+// TokenScreener 新出现的代币在交易类分析器使用之前先打分：
+//   - 在最新状态上模拟 买入 -> 转账 -> 卖出（eth_simulateV1），检测卖出失败和买 / 卖 / 转账税
+//   - 扫描字节码中的拉黑函数
+//   - owner 的持仓占比
+//
+// Risk 不会阻塞调用方，未检查过的代币返回 false 并排队检查
+type TokenScreener struct {
+	rpc     *rpc.Client
+	client  *ethclient.Client
+	router  common.Address
+	weth    common.Address
+	skip    map[common.Address]bool // WETH、稳定币等不需要检查的代币
+	onRisk  func(TokenRisk)
+	noSim   bool // 节点不支持 eth_simulateV1
+	queue   chan common.Address
+	selects map[[4]byte]string
+
+	mu      sync.Mutex
+	results map[common.Address]TokenRisk
+	queued  map[common.Address]bool
+}
+
+// NewTokenScreener 创建代币风险检查，onRisk 在每次检查完成后调用，nil 时只输出有风险的代币
+func NewTokenScreener(cfg HoneypotConfig, rpcClient *rpc.Client, client *ethclient.Client, prices PriceConfig, onRisk func(TokenRisk)) *TokenScreener {
+	if onRisk == nil {
+		onRisk = PrintTokenRisk
+	}
+	s := &TokenScreener{
+		rpc:     rpcClient,
+		client:  client,
+		router:  common.HexToAddress(cfg.Router),
+		weth:    common.HexToAddress(prices.WETH),
+		skip:    make(map[common.Address]bool),
+		onRisk:  onRisk,
+		queue:   make(chan common.Address, HoneypotQueueSize),
+		selects: make(map[[4]byte]string),
+		results: make(map[common.Address]TokenRisk),
+		queued:  make(map[common.Address]bool),
+	}
+	s.skip[s.weth] = true
+	for _, a := range prices.Stablecoins {
+		s.skip[common.HexToAddress(a)] = true
+	}
+	for token := range prices.Feeds {
+		s.skip[common.HexToAddress(token)] = true // 有 Chainlink 喂价的都是成熟代币
+	}
+	for _, sig := range blacklistSignatures {
+		var sel [4]byte
+		copy(sel[:], crypto.Keccak256([]byte(sig))[:4])
+		s.selects[sel] = sig
+	}
+	return s
+}
+
+// OnBlock 实现 BlockAnalyzer：ERC-20 Transfer 中第一次出现的代币排队检查
+func (s *TokenScreener) OnBlock(data *BlockData) {
+	for _, receipt := range data.Receipts {
+		for _, l := range receipt.Logs {
+			if len(l.Topics) == 3 && l.Topics[0] == erc20TransferTopic && len(l.Data) == 32 {
+				s.Risk(l.Address)
+			}
+		}
+	}
+}
+
+// LogFilters 实现 LogFilterer：任意 ERC-20 Transfer
+func (s *TokenScreener) LogFilters() []ethereum.FilterQuery {
+	return []ethereum.FilterQuery{{Topics: [][]common.Hash{{erc20TransferTopic}}}}
+}
+
+// Risk 返回代币的检查结果；没有结果或已过期时排队检查，s 为 nil 时总是返回 false
+func (s *TokenScreener) Risk(token common.Address) (TokenRisk, bool) {
+	if s == nil || s.skip[token] {
+		return TokenRisk{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.results[token]
+	if (!ok || time.Since(r.CheckedAt) > TokenRiskTTL) && !s.queued[token] {
+		select {
+		case s.queue <- token:
+			s.queued[token] = true
+		default:
+		}
+	}
+	return r, ok
+}
+
+// Dangerous 代币是否被判定为危险（蜜罐、高税等），交易类分析器在处理之前调用
+func (s *TokenScreener) Dangerous(token common.Address) bool {
+	r, ok := s.Risk(token)
+	return ok && r.Level == RiskDanger
+}
+
+// Run 逐个检查排队的代币，直到 ctx 取消
+func (s *TokenScreener) Run(ctx context.Context) {
+	for {
+		select {
+		case token := <-s.queue:
+			r := s.check(ctx, token)
+			if ctx.Err() != nil {
+				return
+			}
+			s.mu.Lock()
+			s.results[token] = r
+			delete(s.queued, token)
+			s.mu.Unlock()
+			s.onRisk(r)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// check 执行全部检查并打分
+func (s *TokenScreener) check(ctx context.Context, token common.Address) TokenRisk {
+	ctx, cancel := context.WithTimeout(ctx, CONNECTION_TIMEOUT)
+	defer cancel()
+
+	r := TokenRisk{Token: token, Reasons: []string{}, CheckedAt: time.Now()}
+	s.simulate(ctx, &r)
+	s.scanBytecode(ctx, &r)
+	s.ownership(ctx, &r)
+
+	switch {
+	case r.SellReverted:
+		r.Score += 60
+		r.Reasons = append(r.Reasons, "买入后无法卖出（蜜罐）")
+	case r.SellTaxPct >= HoneypotMaxTaxPct:
+		r.Score += 30
+		r.Reasons = append(r.Reasons, fmt.Sprintf("卖出税 %.1f%%", r.SellTaxPct))
+	}
+	if r.BuyTaxPct >= HoneypotMaxTaxPct {
+		r.Score += 20
+		r.Reasons = append(r.Reasons, fmt.Sprintf("买入税 %.1f%%", r.BuyTaxPct))
+	}
+	if r.TransferTaxPct >= 1 {
+		r.Score += 10
+		r.Reasons = append(r.Reasons, fmt.Sprintf("转账税 %.1f%%", r.TransferTaxPct))
+	}
+	if len(r.Blacklist) > 0 {
+		r.Score += 20
+		r.Reasons = append(r.Reasons, "合约包含拉黑函数: "+strings.Join(r.Blacklist, ", "))
+	}
+	if r.OwnerSharePct >= HoneypotOwnerSharePct {
+		r.Score += 20
+		r.Reasons = append(r.Reasons, fmt.Sprintf("owner 持有 %.1f%% 的供应量", r.OwnerSharePct))
+	}
+	r.Score = min(r.Score, 100)
+	switch {
+	case r.Score >= HoneypotDangerScore:
+		r.Level = RiskDanger
+	case r.Score >= HoneypotCautionScore:
+		r.Level = RiskCaution
+	default:
+		r.Level = RiskSafe
+	}
+	return r
+}
+
+// simCall eth_simulateV1 中的一个调用
+type simCall struct {
+	From  common.Address `json:"from"`
+	To    common.Address `json:"to"`
+	Data  hexutil.Bytes  `json:"input"`
+	Value *hexutil.Big   `json:"value,omitempty"`
+}
+
+// simCallResult eth_simulateV1 中单个调用的结果（只解析需要的字段）
+type simCallResult struct {
+	ReturnData hexutil.Bytes  `json:"returnData"`
+	Status     hexutil.Uint64 `json:"status"`
+	Error      *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// simulateCalls 在最新区块之上的同一个模拟区块中依次执行 calls，买家账户预先充值
+func (s *TokenScreener) simulateCalls(ctx context.Context, calls []simCall) ([]simCallResult, error) {
+	params := map[string]interface{}{
+		"blockStateCalls": []interface{}{map[string]interface{}{
+			"stateOverrides": map[common.Address]interface{}{
+				honeypotBuyer: map[string]interface{}{"balance": (*hexutil.Big)(new(big.Int).Mul(big.NewInt(HoneypotBuyWei), big.NewInt(100)))},
+			},
+			"calls": calls,
+		}},
+		"validation": false,
+	}
+	var blocks []struct {
+		Calls []simCallResult `json:"calls"`
+	}
+	if err := callRetry(ctx, s.rpc, &blocks, "eth_simulateV1", params, "latest"); err != nil {
+		return nil, err
+	}
+	if len(blocks) != 1 || len(blocks[0].Calls) != len(calls) {
+		return nil, fmt.Errorf("eth_simulateV1 返回的结果数量不匹配")
+	}
+	return blocks[0].Calls, nil
+}
+
+// simulate 两轮模拟：先买入得到实际到账数量，再用这个数量测试转账和卖出
+func (s *TokenScreener) simulate(ctx context.Context, r *TokenRisk) {
+	if s.noSim {
+		return
+	}
+	router, token := s.router, r.Token
+	deadline := big.NewInt(time.Now().Add(time.Hour).Unix())
+	buyPath := []common.Address{s.weth, token}
+	sellPath := []common.Address{token, s.weth}
+	pack := func(a abi.ABI, method string, args ...interface{}) hexutil.Bytes {
+		data, err := a.Pack(method, args...)
+		if err != nil {
+			panic(err) // 参数由本文件构造，出错说明代码有问题
+		}
+		return data
+	}
+	buy := simCall{
+		From: honeypotBuyer, To: router, Value: (*hexutil.Big)(big.NewInt(HoneypotBuyWei)),
+		Data: pack(honeypotRouterABI, "swapExactETHForTokensSupportingFeeOnTransferTokens", common.Big0, buyPath, honeypotBuyer, deadline),
+	}
+
+	// 第一轮：报价 -> 买入 -> 到账数量
+	res, err := s.simulateCalls(ctx, []simCall{
+		{From: honeypotBuyer, To: router, Data: pack(honeypotRouterABI, "getAmountsOut", big.NewInt(HoneypotBuyWei), buyPath)},
+		buy,
+		{From: honeypotBuyer, To: token, Data: pack(honeypotERC20ABI, "balanceOf", honeypotBuyer)},
+	})
+	if isMethodNotFound(err) {
+		log.Println("⚠️  节点不支持 eth_simulateV1，代币检查将跳过买卖模拟")
+		s.noSim = true
+		return
+	}
+	if err != nil {
+		r.Reasons = append(r.Reasons, fmt.Sprintf("模拟失败: %v", err))
+		return
+	}
+	if !simOK(res[0]) {
+		r.Reasons = append(r.Reasons, "没有 Uniswap V2 WETH 池子，跳过买卖模拟")
+		return
+	}
+	if !simOK(res[1]) {
+		r.Score += 40
+		r.Reasons = append(r.Reasons, "模拟买入失败（可能尚未开放交易）")
+		return
+	}
+	quoted := lastAmount(res[0].ReturnData)
+	received := new(big.Int).SetBytes(res[2].ReturnData)
+	if received.Sign() == 0 || quoted == nil {
+		r.Score += 40
+		r.Reasons = append(r.Reasons, "模拟买入没有收到代币")
+		return
+	}
+	r.BuyTaxPct = lossPct(received, quoted)
+
+	// 第二轮：买入 -> 转一半给另一个账户 -> 卖出四分之一
+	// 卖出数量取到账的 1/4，即使转账时对发送方额外扣费也足够
+	half := new(big.Int).Rsh(received, 1)
+	sell := new(big.Int).Rsh(received, 2)
+	res, err = s.simulateCalls(ctx, []simCall{
+		buy,
+		{From: honeypotBuyer, To: token, Data: pack(honeypotERC20ABI, "transfer", honeypotReceiver, half)},
+		{From: honeypotBuyer, To: token, Data: pack(honeypotERC20ABI, "balanceOf", honeypotReceiver)},
+		{From: honeypotBuyer, To: token, Data: pack(honeypotERC20ABI, "approve", router, math.MaxBig256)},
+		{From: honeypotBuyer, To: router, Data: pack(honeypotRouterABI, "getAmountsOut", sell, sellPath)},
+		{From: honeypotBuyer, To: router, Data: pack(honeypotRouterABI, "swapExactTokensForTokensSupportingFeeOnTransferTokens", sell, common.Big0, sellPath, honeypotBuyer, deadline)},
+		{From: honeypotBuyer, To: s.weth, Data: pack(honeypotERC20ABI, "balanceOf", honeypotBuyer)},
+	})
+	if err != nil {
+		r.Reasons = append(r.Reasons, fmt.Sprintf("模拟失败: %v", err))
+		return
+	}
+	r.Simulated = true
+	if simOK(res[1]) && half.Sign() > 0 {
+		r.TransferTaxPct = lossPct(new(big.Int).SetBytes(res[2].ReturnData), half)
+	}
+	if !simOK(res[3]) || !simOK(res[5]) {
+		r.SellReverted = true
+		return
+	}
+	if quoted := lastAmount(res[4].ReturnData); quoted != nil && quoted.Sign() > 0 {
+		r.SellTaxPct = lossPct(new(big.Int).SetBytes(res[6].ReturnData), quoted)
+	}
+}
+
+func simOK(r simCallResult) bool {
+	return r.Status == 1 && r.Error == nil
+}
+
+// lastAmount 解码 getAmountsOut 返回的 uint256[] 的最后一个元素
+func lastAmount(data []byte) *big.Int {
+	out, err := honeypotRouterABI.Unpack("getAmountsOut", data)
+	if err != nil || len(out) == 0 {
+		return nil
+	}
+	amounts, ok := out[0].([]*big.Int)
+	if !ok || len(amounts) == 0 {
+		return nil
+	}
+	return amounts[len(amounts)-1]
+}
+
+// lossPct 实际数量比预期少了百分之多少（包含滑点之外的所有损耗）
+func lossPct(actual, expected *big.Int) float64 {
+	if expected.Sign() == 0 || actual.Cmp(expected) >= 0 {
+		return 0
+	}
+	return (1 - ratio(actual, expected)) * 100
+}
+
+// scanBytecode 按操作码遍历字节码（跳过 PUSH 的数据），查找 PUSH4 <拉黑函数 selector>
+func (s *TokenScreener) scanBytecode(ctx context.Context, r *TokenRisk) {
+	code, err := codeAtRetry(ctx, s.client, r.Token, nil)
+	if err != nil {
+		return
+	}
+	found := make(map[string]bool)
+	for i := 0; i < len(code); i++ {
+		op := code[i]
+		if op < 0x60 || op > 0x7f { // 不是 PUSH1 - PUSH32
+			continue
+		}
+		n := int(op - 0x5f)
+		if op == 0x63 && i+4 < len(code) {
+			var sel [4]byte
+			copy(sel[:], code[i+1:i+5])
+			if sig, ok := s.selects[sel]; ok && !found[sig] {
+				found[sig] = true
+				r.Blacklist = append(r.Blacklist, sig)
+			}
+		}
+		i += n
+	}
+}
+
+// ownership 读取 owner() 及其持仓占比，已放弃所有权（owner 为零地址）或没有 owner() 时跳过
+func (s *TokenScreener) ownership(ctx context.Context, r *TokenRisk) {
+	call := func(data []byte) []byte {
+		out, err := callContractRetry(ctx, s.client, ethereum.CallMsg{To: &r.Token, Data: data}, nil)
+		if err != nil || len(out) < 32 {
+			return nil
+		}
+		return out
+	}
+	out := call(honeypotERC20ABI.Methods["owner"].ID)
+	if out == nil {
+		return
+	}
+	r.Owner = common.BytesToAddress(out[:32])
+	if r.Owner == (common.Address{}) {
+		return
+	}
+	supply := call(honeypotERC20ABI.Methods["totalSupply"].ID)
+	data, _ := honeypotERC20ABI.Pack("balanceOf", r.Owner)
+	balance := call(data)
+	if supply == nil || balance == nil {
+		return
+	}
+	total := new(big.Int).SetBytes(supply[:32])
+	if total.Sign() > 0 {
+		r.OwnerSharePct = ratio(new(big.Int).SetBytes(balance[:32]), total) * 100
+	}
+}
+
+// RegisterAPI 注册代币风险查询接口
+//
+//	GET /api/tokens/{address}/risk 检查结果；还没有结果时返回 202 并排队检查
+func (s *TokenScreener) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/tokens/{address}/risk", func(w http.ResponseWriter, r *http.Request) {
+		if !common.IsHexAddress(r.PathValue("address")) {
+			writeError(w, http.StatusBadRequest, "地址格式错误")
+			return
+		}
+		token := common.HexToAddress(r.PathValue("address"))
+		if s.skip[token] {
+			writeError(w, http.StatusNotFound, "WETH / 稳定币 / 有喂价的代币不做检查")
+			return
+		}
+		risk, ok := s.Risk(token)
+		if !ok {
+			writeError(w, http.StatusAccepted, "已排队检查，稍后再查询")
+			return
+		}
+		writeJSON(w, http.StatusOK, risk)
+	})
+}
+
+// PrintTokenRisk 默认输出：只输出需要注意的代币
+func PrintTokenRisk(r TokenRisk) {
+	var tag string
+	switch r.Level {
+	case RiskDanger:
+		tag = "🚨 [Token Risk]"
+	case RiskCaution:
+		tag = "⚠️  [Token Risk]"
+	default:
+		return
+	}
+	Emit(Event{Type: "token_risk", Data: r,
+		Text: fmt.Sprintf("%s %s 评分 %d (%s) | %s", tag, r.Token.Hex(), r.Score, r.Level, strings.Join(r.Reasons, "; "))})
+}
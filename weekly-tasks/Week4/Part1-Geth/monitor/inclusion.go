@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	// 超过这个时间仍未上链（多半被替换或丢弃），停止跟踪
+	InclusionPendingTTL = 30 * time.Minute
+	// 每隔多少个区块打印一次统计
+	InclusionReportEvery = 100
+	// 每个出价档位保留的最近样本数（用于计算分位数）
+	InclusionSampleSize = 500
+)
+
+// inclusionFeeLevels 按出价的优先费（gwei）分档，数值为每档上限，最后一档不设上限
+var inclusionFeeLevels = []float64{0.5, 1, 2, 5, 10}
+
+// trackedTx 一笔正在等待上链的关注交易
+type trackedTx struct {
+	hash      common.Hash
+	from      common.Address
+	firstSeen time.Time
+	seenHead  uint64   // 首次看到时的区块高度
+	tipCap    *big.Int // 出价的 maxPriorityFeePerGas（legacy 交易为 gasPrice）
+	feeCap    *big.Int // 出价的 maxFeePerGas（legacy 交易为 gasPrice）
+}
+
+// feeLevelStats 一个出价档位的统计
+type feeLevelStats struct {
+	label        string
+	latencies    []time.Duration // 最近 InclusionSampleSize 个样本
+	count        int
+	blocksTotal  uint64
+	paidTipTotal float64 // gwei
+}
+
+func (s *feeLevelStats) add(latency time.Duration, blocks uint64, paidTip float64) {
+	s.count++
+	s.blocksTotal += blocks
+	s.paidTipTotal += paidTip
+	s.latencies = append(s.latencies, latency)
+	if len(s.latencies) > InclusionSampleSize {
+		s.latencies = s.latencies[1:]
+	}
+}
+
+// InclusionTracker 跟踪关注地址发出的 Pending 交易：
+// 统计从首次看到到上链的耗时、等待的区块数、实际成交价 vs 出价，并按出价档位汇总
+type InclusionTracker struct {
+	client *ethclient.Client
+	waiter *ReceiptWaiter
+
+	mu               sync.Mutex
+	pending          map[common.Hash]*trackedTx
+	levels           []*feeLevelStats
+	head             uint64
+	headsSinceReport int
+}
+
+// newFeeLevels 按 inclusionFeeLevels 创建各出价档位
+func newFeeLevels() []*feeLevelStats {
+	levels := make([]*feeLevelStats, 0, len(inclusionFeeLevels)+1)
+	lower := 0.0
+	for _, upper := range inclusionFeeLevels {
+		levels = append(levels, &feeLevelStats{label: fmt.Sprintf("%g-%g gwei", lower, upper)})
+		lower = upper
+	}
+	return append(levels, &feeLevelStats{label: fmt.Sprintf(">%g gwei", lower)})
+}
+
+// feeLevelFor 根据优先费找到对应档位
+func feeLevelFor(levels []*feeLevelStats, tip *big.Int) *feeLevelStats {
+	gwei := weiToGwei(tip)
+	for i, upper := range inclusionFeeLevels {
+		if gwei < upper {
+			return levels[i]
+		}
+	}
+	return levels[len(levels)-1]
+}
+
+func NewInclusionTracker(client *ethclient.Client, waiter *ReceiptWaiter) *InclusionTracker {
+	return &InclusionTracker{
+		client:  client,
+		waiter:  waiter,
+		pending: make(map[common.Hash]*trackedTx),
+		levels:  newFeeLevels(),
+	}
+}
+
+// Track 开始跟踪一笔 Pending 交易
+func (t *InclusionTracker) Track(tx *types.Transaction, from common.Address) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.pending[tx.Hash()]; ok {
+		return
+	}
+	t.pending[tx.Hash()] = &trackedTx{
+		hash:      tx.Hash(),
+		from:      from,
+		firstSeen: time.Now(),
+		seenHead:  t.head,
+		tipCap:    tx.GasTipCap(),
+		feeCap:    tx.GasFeeCap(),
+	}
+	// 深度为 1：交易被打包进区块就通知
+	t.waiter.WaitFunc(tx.Hash(), 1, t.onConfirmed)
+}
+
+// OnHead 记录最新高度，清理超时的交易，并定期打印统计
+func (t *InclusionTracker) OnHead(header *types.Header) {
+	t.mu.Lock()
+	t.head = header.Number.Uint64()
+	for hash, tx := range t.pending {
+		if time.Since(tx.firstSeen) > InclusionPendingTTL {
+			delete(t.pending, hash)
+			t.waiter.Cancel(hash)
+			log.Printf("⌛ 交易 %s 超过 %s 未上链，停止跟踪（可能已被替换或丢弃）", hash.Hex(), InclusionPendingTTL)
+		}
+	}
+	t.headsSinceReport++
+	report := t.headsSinceReport >= InclusionReportEvery
+	if report {
+		t.headsSinceReport = 0
+	}
+	t.mu.Unlock()
+
+	if report {
+		t.PrintReport()
+	}
+}
+
+// onConfirmed 交易上链回调（在 ReceiptWaiter 的 goroutine 中执行）
+func (t *InclusionTracker) onConfirmed(ev ConfirmationEvent) {
+	if ev.Revoked || ev.Final {
+		return
+	}
+	t.mu.Lock()
+	tx, ok := t.pending[ev.TxHash]
+	delete(t.pending, ev.TxHash)
+	t.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	// 区块时间和 baseFee 需要从区块头拿，用来计算真实的上链耗时和实际支付的小费
+	ctx, cancel := context.WithTimeout(context.Background(), CONNECTION_TIMEOUT)
+	defer cancel()
+	var header *types.Header
+	err := Retry(ctx, "eth_getBlockByHash", func() (err error) {
+		header, err = t.client.HeaderByHash(ctx, ev.Receipt.BlockHash)
+		return err
+	})
+	if err != nil {
+		log.Printf("⚠️  查询区块头失败 %s: %v", ev.Receipt.BlockHash.Hex(), err)
+		return
+	}
+
+	latency := time.Unix(int64(header.Time), 0).Sub(tx.firstSeen)
+	if latency < 0 {
+		// 区块时间只精确到秒，同一秒内看到并被打包时可能为负
+		latency = 0
+	}
+	var blocks uint64
+	if mined := ev.Receipt.BlockNumber.Uint64(); mined > tx.seenHead {
+		blocks = mined - tx.seenHead
+	}
+	effective := ev.Receipt.EffectiveGasPrice
+	paidTip := new(big.Int).Set(effective)
+	if header.BaseFee != nil {
+		paidTip.Sub(paidTip, header.BaseFee)
+	}
+
+	t.mu.Lock()
+	feeLevelFor(t.levels, tx.tipCap).add(latency, blocks, weiToGwei(paidTip))
+	t.mu.Unlock()
+
+	Emit(Event{
+		Type: "included",
+		Key:  eventKey(tx.hash, ev.Receipt.BlockHash),
+		Text: fmt.Sprintf("⛏️  [Included] %s | 区块 %d | 耗时 %s / %d 个区块 | 出价 tip %.2f maxFee %.2f gwei | 实际成交 %.2f gwei (tip %.2f)",
+			tx.hash.Hex(), ev.Receipt.BlockNumber, latency.Round(time.Second), blocks,
+			weiToGwei(tx.tipCap), weiToGwei(tx.feeCap), weiToGwei(effective), weiToGwei(paidTip)),
+		Data: map[string]interface{}{
+			"tx_hash": tx.hash, "block_number": ev.Receipt.BlockNumber, "latency_seconds": latency.Seconds(), "blocks": blocks,
+			"tip_cap_gwei": weiToGwei(tx.tipCap), "fee_cap_gwei": weiToGwei(tx.feeCap),
+			"effective_gwei": weiToGwei(effective), "paid_tip_gwei": weiToGwei(paidTip),
+		},
+	})
+}
+
+// PrintReport 打印各出价档位的上链耗时统计
+func (t *InclusionTracker) PrintReport() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var sb strings.Builder
+	sb.WriteString("\n📊 [Inclusion Latency] 关注地址交易上链统计\n")
+	fmt.Fprintf(&sb, "   %-14s %6s %10s %10s %10s %10s %12s\n", "出价档位", "样本", "平均耗时", "P50", "P95", "平均区块", "平均实付tip")
+	total := 0
+	for _, lv := range t.levels {
+		if lv.count == 0 {
+			continue
+		}
+		total += lv.count
+		fmt.Fprintf(&sb, "   %-14s %6d %10s %10s %10s %10.1f %10.2f gwei\n",
+			lv.label, lv.count,
+			mean(lv.latencies).Round(time.Second),
+			percentile(lv.latencies, 0.5).Round(time.Second),
+			percentile(lv.latencies, 0.95).Round(time.Second),
+			float64(lv.blocksTotal)/float64(lv.count),
+			lv.paidTipTotal/float64(lv.count))
+	}
+	if total == 0 {
+		sb.WriteString("   （暂无样本）\n")
+	}
+	fmt.Fprintf(&sb, "   仍在等待上链: %d 笔\n", len(t.pending))
+	Emit(Event{Type: "inclusion_report", Text: sb.String(),
+		Summary: fmt.Sprintf("📊 [Inclusion Latency] %d 个样本，仍在等待上链 %d 笔", total, len(t.pending))})
+}
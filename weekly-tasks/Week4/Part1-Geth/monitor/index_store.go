@@ -0,0 +1,198 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	_ "modernc.org/sqlite" // 纯 Go 的 SQLite 驱动，不需要 cgo
+)
+
+// IndexedEvent 一条已解码并写入索引的事件
+type IndexedEvent struct {
+	Contract    string // 配置中的合约名
+	Event       string // 事件名，例如 Transfer
+	BlockNumber uint64
+	BlockHash   common.Hash
+	TxHash      common.Hash
+	LogIndex    uint
+	Args        string // 解码后的参数（JSON）
+}
+
+// IndexCursor 某个合约已经索引到的位置
+type IndexCursor struct {
+	BlockNumber uint64
+	BlockHash   common.Hash
+}
+
+const indexSchema = `
+CREATE TABLE IF NOT EXISTS events (
+	contract     TEXT    NOT NULL,
+	event        TEXT    NOT NULL,
+	block_number INTEGER NOT NULL,
+	block_hash   TEXT    NOT NULL,
+	tx_hash      TEXT    NOT NULL,
+	log_index    INTEGER NOT NULL,
+	args         TEXT    NOT NULL,
+	PRIMARY KEY (block_hash, log_index)
+);
+CREATE INDEX IF NOT EXISTS events_contract_block ON events (contract, block_number);
+CREATE TABLE IF NOT EXISTS cursors (
+	contract     TEXT PRIMARY KEY,
+	block_number INTEGER NOT NULL,
+	block_hash   TEXT    NOT NULL
+);`
+
+// IndexStore 事件索引的 SQLite 存储
+// 事件与游标在同一个事务中写入，进程在任何时刻退出都能从游标处无缝恢复
+type IndexStore struct {
+	db *sql.DB
+}
+
+// openSQLite 打开 SQLite 数据库并建表
+// 开启 WAL 和 busy_timeout，多个模块共用同一个数据库文件时不会互相报 "database is locked"
+func openSQLite(path, schema string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库 %s 失败: %w", path, err)
+	}
+	db.SetMaxOpenConns(1) // SQLite 只允许一个写者
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化数据表失败: %w", err)
+	}
+	return db, nil
+}
+
+// OpenIndexStore 打开（不存在时创建）索引数据库
+func OpenIndexStore(path string) (*IndexStore, error) {
+	db, err := openSQLite(path, indexSchema)
+	if err != nil {
+		return nil, err
+	}
+	return &IndexStore{db: db}, nil
+}
+
+// Cursor 读取合约的游标，从未索引过时 ok 为 false
+func (s *IndexStore) Cursor(contract string) (cur IndexCursor, ok bool, err error) {
+	var hash string
+	err = s.db.QueryRow(`SELECT block_number, block_hash FROM cursors WHERE contract = ?`, contract).
+		Scan(&cur.BlockNumber, &hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return cur, false, nil
+	}
+	if err != nil {
+		return cur, false, fmt.Errorf("读取游标失败: %w", err)
+	}
+	cur.BlockHash = common.HexToHash(hash)
+	return cur, true, nil
+}
+
+// Commit 在一个事务中写入一批事件并推进游标
+func (s *IndexStore) Commit(contract string, events []IndexedEvent, cur IndexCursor) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, e := range events {
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO events
+			(contract, event, block_number, block_hash, tx_hash, log_index, args) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			e.Contract, e.Event, e.BlockNumber, e.BlockHash.Hex(), e.TxHash.Hex(), e.LogIndex, e.Args); err != nil {
+			return fmt.Errorf("写入事件失败: %w", err)
+		}
+	}
+	if err := setCursor(tx, contract, cur); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Rewind 重组时回退：删除 cur 之后的事件并把游标移到 cur
+func (s *IndexStore) Rewind(contract string, cur IndexCursor) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM events WHERE contract = ? AND block_number > ?`, contract, cur.BlockNumber); err != nil {
+		return fmt.Errorf("删除重组区块的事件失败: %w", err)
+	}
+	if err := setCursor(tx, contract, cur); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// IndexedTransfer /api/history/transfers 中的一条转账
+type IndexedTransfer struct {
+	Contract    string          `json:"contract"`
+	BlockNumber uint64          `json:"block"`
+	TxHash      common.Hash     `json:"tx_hash"`
+	LogIndex    uint            `json:"log_index"`
+	Args        json.RawMessage `json:"args"` // 解码后的参数，例如 {"from": ..., "to": ..., "value": ...}
+}
+
+// QueryEvents 按区块倒序查询某些合约的某个事件，addr 不为 nil 时只查参数 from / to 为该地址的
+func (s *IndexStore) QueryEvents(contracts []string, event string, addr *common.Address, q historyQuery) (HistoryPage, error) {
+	where := []string{"event = ?", "block_number BETWEEN ? AND ?"}
+	args := []interface{}{event, q.FromBlock, q.ToBlock}
+	if len(contracts) > 0 {
+		where = append(where, "contract IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(contracts)), ", ")+")")
+		for _, c := range contracts {
+			args = append(args, c)
+		}
+	}
+	if addr != nil {
+		// 参数中的地址按 JSON 编码为小写
+		where = append(where, "(json_extract(args, '$.from') = ? OR json_extract(args, '$.to') = ?)")
+		lower := strings.ToLower(addr.Hex())
+		args = append(args, lower, lower)
+	}
+	if q.Cursor != nil {
+		where = append(where, "(block_number, log_index) < (?, ?)")
+		args = append(args, q.Cursor.Key, q.Cursor.Index)
+	}
+	args = append(args, q.Limit)
+	rows, err := s.db.Query(`SELECT contract, block_number, tx_hash, log_index, args FROM events
+		WHERE `+strings.Join(where, " AND ")+` ORDER BY block_number DESC, log_index DESC LIMIT ?`, args...)
+	if err != nil {
+		return HistoryPage{}, fmt.Errorf("查询事件索引失败: %w", err)
+	}
+	defer rows.Close()
+	items := []IndexedTransfer{}
+	for rows.Next() {
+		var t IndexedTransfer
+		var hash, encoded string
+		if err := rows.Scan(&t.Contract, &t.BlockNumber, &hash, &t.LogIndex, &encoded); err != nil {
+			return HistoryPage{}, err
+		}
+		t.TxHash, t.Args = common.HexToHash(hash), json.RawMessage(encoded)
+		items = append(items, t)
+	}
+	page := HistoryPage{Items: items}
+	if len(items) == q.Limit {
+		last := items[len(items)-1]
+		page.NextCursor = historyCursor{Key: int64(last.BlockNumber), Index: int64(last.LogIndex)}.encode()
+	}
+	return page, rows.Err()
+}
+
+// Close 关闭数据库
+func (s *IndexStore) Close() error {
+	return s.db.Close()
+}
+
+func setCursor(tx *sql.Tx, contract string, cur IndexCursor) error {
+	if _, err := tx.Exec(`INSERT INTO cursors (contract, block_number, block_hash) VALUES (?, ?, ?)
+		ON CONFLICT (contract) DO UPDATE SET block_number = excluded.block_number, block_hash = excluded.block_hash`,
+		contract, cur.BlockNumber, cur.BlockHash.Hex()); err != nil {
+		return fmt.Errorf("更新游标失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	// 每批回扫多少个区块后提交一次（事件 + 游标），决定中断后最多需要重做多少工作
+	IndexerBatchBlocks = 5000
+	// 发现重组时游标回退的区块数，与回执等待器的观察深度保持一致
+	IndexerReorgRewind = ReorgWatchBlocks
+)
+
+// indexedContract 一个需要索引的合约
+type indexedContract struct {
+	name    string
+	address common.Address
+	abi     *abi.ABI
+	events  map[common.Hash]abi.Event // topic0 -> 事件
+	start   uint64
+}
+
+// Indexer 事件索引器：按配置的合约和事件维护一份完整、能处理重组的本地索引
+//   - 启动时从游标处回扫到最新区块（分段 eth_getLogs，与 logs 子命令同一套扫描器）
+//   - 之后每个新区块只增量扫描新增的区间
+//   - 每批提交前记录游标区块的哈希，下次扫描前核对，不一致说明发生了重组，回退后重新索引
+type Indexer struct {
+	client        *ethclient.Client
+	store         *IndexStore
+	contracts     []*indexedContract
+	confirmations uint64
+
+	mu   sync.Mutex
+	head *types.Header // 只保留最新的区块头
+	wake chan struct{}
+}
+
+// NewIndexer 加载 ABI 并打开索引数据库
+func NewIndexer(cfg IndexerConfig, client *ethclient.Client) (*Indexer, error) {
+	idx := &Indexer{
+		client:        client,
+		confirmations: cfg.Confirmations,
+		wake:          make(chan struct{}, 1),
+	}
+	for _, c := range cfg.Contracts {
+		ic, err := loadIndexedContract(c)
+		if err != nil {
+			return nil, err
+		}
+		idx.contracts = append(idx.contracts, ic)
+	}
+	store, err := OpenIndexStore(cfg.DB)
+	if err != nil {
+		return nil, err
+	}
+	idx.store = store
+	return idx, nil
+}
+
+// LoadABIs 把索引合约的 ABI 加载到 registry，实时解码也能用上
+func (idx *Indexer) LoadABIs(reg *ABIRegistry) {
+	for _, c := range idx.contracts {
+		reg.Register(c.address, c.abi)
+	}
+}
+
+// loadIndexedContract 读取 ABI 文件并挑出需要索引的事件
+func loadIndexedContract(c IndexedContractConfig) (*indexedContract, error) {
+	parsed, err := loadABIFile(c.ABI)
+	if err != nil {
+		return nil, fmt.Errorf("合约 %s: %w", c.Name, err)
+	}
+	ic := &indexedContract{
+		name:    c.Name,
+		address: common.HexToAddress(c.Address),
+		abi:     parsed,
+		events:  make(map[common.Hash]abi.Event),
+		start:   c.StartBlock,
+	}
+	names := c.Events
+	if len(names) == 0 {
+		for name := range parsed.Events {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		ev, ok := parsed.Events[name]
+		if !ok {
+			return nil, fmt.Errorf("合约 %s 的 ABI 中没有事件 %s", c.Name, name)
+		}
+		if ev.Anonymous {
+			continue // 匿名事件没有 topic0，无法按签名过滤
+		}
+		ic.events[ev.ID] = ev
+	}
+	return ic, nil
+}
+
+// loadABIFile 读取 ABI 文件，兼容纯 ABI 数组和 Hardhat / Foundry 编译产物（{"abi": [...]}）
+func loadABIFile(path string) (*abi.ABI, error) {
+	data, err := readABIFile(path)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := abi.JSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("解析 ABI 失败: %w", err)
+	}
+	return &parsed, nil
+}
+
+// readABIFile 读取 ABI 文件的原始 JSON，编译产物只取其中的 abi 字段
+func readABIFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取 ABI 文件失败: %w", err)
+	}
+	var artifact struct {
+		ABI json.RawMessage `json:"abi"`
+	}
+	if json.Unmarshal(data, &artifact) == nil && len(artifact.ABI) > 0 {
+		data = artifact.ABI
+	}
+	return data, nil
+}
+
+// RegisterAPI 注册转账历史查询接口，分页和范围参数见 historyQuery（只支持区块范围）
+//
+//	GET /api/history/transfers?token=USDC&address=0x... 已索引的 Transfer 事件，token 为合约名或地址，为空时查全部合约
+func (idx *Indexer) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/history/transfers", func(w http.ResponseWriter, r *http.Request) {
+		q, err := parseHistoryQuery(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		addr, err := queryAddress(r, "address")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		var contracts []string
+		if token := r.URL.Query().Get("token"); token != "" {
+			c := idx.contract(token)
+			if c == nil {
+				writeError(w, http.StatusNotFound, fmt.Sprintf("indexer 中没有合约 %s", token))
+				return
+			}
+			contracts = append(contracts, c.name)
+		}
+		page, err := idx.store.QueryEvents(contracts, "Transfer", addr, q)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, page)
+	})
+}
+
+// contract 按合约名或地址查找索引的合约
+func (idx *Indexer) contract(token string) *indexedContract {
+	for _, c := range idx.contracts {
+		if c.name == token || (common.IsHexAddress(token) && c.address == common.HexToAddress(token)) {
+			return c
+		}
+	}
+	return nil
+}
+
+// NotifyHead 由区块头订阅调用，不会阻塞主循环；处理不过来时只保留最新的区块头
+func (idx *Indexer) NotifyHead(header *types.Header) {
+	idx.mu.Lock()
+	idx.head = header
+	idx.mu.Unlock()
+	select {
+	case idx.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run 先追上最新区块，再随新区块增量索引，直到 ctx 取消
+func (idx *Indexer) Run(ctx context.Context) {
+	defer idx.store.Close()
+
+	var head uint64
+	err := Retry(ctx, "eth_blockNumber", func() (err error) {
+		head, err = idx.client.BlockNumber(ctx)
+		return err
+	})
+	if err != nil {
+		log.Printf("❌ 查询最新区块失败，事件索引已停止: %v", err)
+		return
+	}
+	idx.sync(ctx, head)
+
+	for {
+		select {
+		case <-idx.wake:
+			idx.mu.Lock()
+			header := idx.head
+			idx.mu.Unlock()
+			idx.sync(ctx, header.Number.Uint64())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sync 把所有合约索引到 head - confirmations
+func (idx *Indexer) sync(ctx context.Context, head uint64) {
+	if head < idx.confirmations {
+		return
+	}
+	target := head - idx.confirmations
+	for _, c := range idx.contracts {
+		if err := idx.syncContract(ctx, c, target); err != nil {
+			if ctx.Err() == nil {
+				log.Printf("⚠️  索引合约 %s 失败: %v（下个区块重试）", c.name, err)
+			}
+		}
+	}
+}
+
+func (idx *Indexer) syncContract(ctx context.Context, c *indexedContract, target uint64) error {
+	cur, ok, err := idx.store.Cursor(c.name)
+	if err != nil {
+		return err
+	}
+	next := c.start
+	if ok {
+		if cur, err = idx.checkReorg(ctx, c, cur); err != nil {
+			return err
+		}
+		next = cur.BlockNumber + 1
+	}
+
+	for next <= target {
+		end := min(next+IndexerBatchBlocks-1, target)
+		var batch []IndexedEvent
+		scanner := NewLogScanner(idx.client, NewLogPipeline(func(l types.Log) {
+			if e, ok := c.decode(l); ok {
+				batch = append(batch, e)
+			}
+		}))
+		scanner.progress = end-next >= LogScanInitialChunk // 增量扫描时不打印进度
+		if _, err := scanner.Scan(ctx, c.filterQuery(), next, end); err != nil {
+			return err
+		}
+		header, err := idx.headerByNumber(ctx, new(big.Int).SetUint64(end))
+		if err != nil {
+			return fmt.Errorf("查询区块 %d 失败: %w", end, err)
+		}
+		if err := idx.store.Commit(c.name, batch, IndexCursor{BlockNumber: end, BlockHash: header.Hash()}); err != nil {
+			return err
+		}
+		if len(batch) > 0 {
+			log.Printf("🗂️  [Indexer] %s: 区块 %d - %d 写入 %d 条事件", c.name, next, end, len(batch))
+		}
+		next = end + 1
+	}
+	return nil
+}
+
+// headerByNumber 带重试的 HeaderByNumber
+func (idx *Indexer) headerByNumber(ctx context.Context, number *big.Int) (header *types.Header, err error) {
+	err = Retry(ctx, "eth_getBlockByNumber", func() error {
+		header, err = idx.client.HeaderByNumber(ctx, number)
+		return err
+	})
+	return header, err
+}
+
+// checkReorg 核对游标区块是否仍在主链上，不在则回退 IndexerReorgRewind 个区块
+func (idx *Indexer) checkReorg(ctx context.Context, c *indexedContract, cur IndexCursor) (IndexCursor, error) {
+	header, err := idx.headerByNumber(ctx, new(big.Int).SetUint64(cur.BlockNumber))
+	if err != nil {
+		return cur, fmt.Errorf("查询游标区块 %d 失败: %w", cur.BlockNumber, err)
+	}
+	if header.Hash() == cur.BlockHash {
+		return cur, nil
+	}
+
+	back := IndexCursor{}
+	if cur.BlockNumber > IndexerReorgRewind {
+		back.BlockNumber = cur.BlockNumber - IndexerReorgRewind
+	}
+	h, err := idx.headerByNumber(ctx, new(big.Int).SetUint64(back.BlockNumber))
+	if err != nil {
+		return cur, fmt.Errorf("查询回退区块 %d 失败: %w", back.BlockNumber, err)
+	}
+	back.BlockHash = h.Hash()
+	log.Printf("⚠️  [Indexer] %s: 游标区块 %d 已被重组，回退到 %d 重新索引", c.name, cur.BlockNumber, back.BlockNumber)
+	if err := idx.store.Rewind(c.name, back); err != nil {
+		return cur, err
+	}
+	return back, nil
+}
+
+// filterQuery 只拉取该合约中需要索引的事件
+func (c *indexedContract) filterQuery() ethereum.FilterQuery {
+	topic0 := make([]common.Hash, 0, len(c.events))
+	for id := range c.events {
+		topic0 = append(topic0, id)
+	}
+	return ethereum.FilterQuery{Addresses: []common.Address{c.address}, Topics: [][]common.Hash{topic0}}
+}
+
+// decode 按 ABI 解码日志，indexed 参数从 topics 中解出，其余从 data 中解出
+func (c *indexedContract) decode(l types.Log) (IndexedEvent, bool) {
+	if len(l.Topics) == 0 {
+		return IndexedEvent{}, false
+	}
+	ev, ok := c.events[l.Topics[0]]
+	if !ok {
+		return IndexedEvent{}, false
+	}
+	args := make(map[string]interface{})
+	if err := ev.Inputs.NonIndexed().UnpackIntoMap(args, l.Data); err != nil {
+		log.Printf("⚠️  [Indexer] 解码 %s 失败 (%s #%d): %v", ev.Name, l.TxHash.Hex(), l.Index, err)
+		return IndexedEvent{}, false
+	}
+	var indexed abi.Arguments
+	for _, arg := range ev.Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+	if err := abi.ParseTopicsIntoMap(args, indexed, l.Topics[1:]); err != nil {
+		log.Printf("⚠️  [Indexer] 解码 %s 的 indexed 参数失败 (%s #%d): %v", ev.Name, l.TxHash.Hex(), l.Index, err)
+		return IndexedEvent{}, false
+	}
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		return IndexedEvent{}, false
+	}
+	return IndexedEvent{
+		Contract:    c.name,
+		Event:       ev.Name,
+		BlockNumber: l.BlockNumber,
+		BlockHash:   l.BlockHash,
+		TxHash:      l.TxHash,
+		LogIndex:    l.Index,
+		Args:        string(encoded),
+	}, true
+}
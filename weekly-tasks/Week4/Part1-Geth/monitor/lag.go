@@ -0,0 +1,44 @@
+package main
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// ------------------------------------------------
+// 延迟统计（毫秒，Prometheus 中以分位数导出）：
+//   - 订阅延迟：本地收到区块头的时间 - header.Time，持续偏大说明节点同步落后或网络 / 代理慢
+//   - 处理延迟：事件到达到处理完成的时间，持续偏大说明监控程序自己处理不过来
+// ------------------------------------------------
+
+const (
+	// 直方图的采样池大小和衰减系数（偏向最近 5 分钟左右的数据）
+	lagSampleSize  = 1028
+	lagSampleAlpha = 0.015
+)
+
+var (
+	headLag           = newLagHistogram("monitor/lag/head_ms")
+	headProcessing    = newLagHistogram("monitor/processing/head_ms")
+	blockProcessing   = newLagHistogram("monitor/processing/block_ms") // 收到区块头到所有区块分析器处理完
+	logProcessing     = newLagHistogram("monitor/processing/log_ms")
+	pendingProcessing = newLagHistogram("monitor/processing/pending_ms")
+)
+
+func newLagHistogram(name string) metrics.Histogram {
+	return metrics.NewRegisteredHistogram(name, metricsRegistry, metrics.NewExpDecaySample(lagSampleSize, lagSampleAlpha))
+}
+
+// observeHeadLag 记录区块头的订阅延迟并返回；header.Time 只精确到秒，单个样本有 ±1 秒的误差
+func observeHeadLag(header *types.Header, received time.Time) time.Duration {
+	lag := received.Sub(time.Unix(int64(header.Time), 0))
+	headLag.Update(lag.Milliseconds())
+	return lag
+}
+
+// observeSince 记录从 start 到现在的处理耗时
+func observeSince(h metrics.Histogram, start time.Time) {
+	h.Update(time.Since(start).Milliseconds())
+}
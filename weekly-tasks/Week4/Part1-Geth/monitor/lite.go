@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ------------------------------------------------
+// 轻量模式（lite 子命令）：只订阅新区块头，不拉取区块体和回执，不订阅交易池，
+// 内存固定在很小的范围内，适合在小 VPS 上单独作为出块 / 最终性看门狗运行
+// ------------------------------------------------
+
+const (
+	// ⚠️ 默认值：超过 60 秒没有新区块头视为停滞；finalized 落后链头超过 4 个 epoch（128 个区块）视为最终性停滞
+	DefaultLiteStallSeconds      = 60
+	DefaultLiteFinalityLagBlocks = 128
+	DefaultLiteFinalityInterval  = 60 // 查询 finalized / safe 区块的间隔（秒）
+	DefaultLiteMemoryLimitMB     = 32
+
+	// 检查区块头停滞的间隔
+	LiteCheckInterval = 5 * time.Second
+)
+
+// 提醒类型
+const (
+	LiteHeadStall       = "head_stall"       // 长时间没有新区块头
+	LiteHeadResumed     = "head_resumed"     // 停滞后恢复
+	LiteFinalityStall   = "finality_stall"   // finalized 落后链头太多
+	LiteFinalityResumed = "finality_resumed" // 最终性恢复
+	LiteFinalizedReorg  = "finalized_reorg"  // finalized 区块后退或同一高度哈希变化
+)
+
+// LiteConfig 轻量模式配置
+type LiteConfig struct {
+	StallSeconds      int    `json:"stall_seconds"`       // 超过该秒数没有新区块头时提醒
+	FinalityLagBlocks uint64 `json:"finality_lag_blocks"` // finalized 落后链头超过该区块数时提醒
+	FinalityInterval  int    `json:"finality_interval"`   // 查询 finalized / safe 区块的间隔（秒）
+	MemoryLimitMB     int    `json:"memory_limit_mb"`     // Go 运行时的软内存上限，0 表示不限制
+}
+
+// LiteAlert 一次出块 / 最终性提醒
+type LiteAlert struct {
+	Kind      string    `json:"kind"`
+	Head      uint64    `json:"head"`
+	Finalized uint64    `json:"finalized"`
+	Detail    string    `json:"detail"`
+	Time      time.Time `json:"time"`
+}
+
+// LiteStatus /api/lite 的返回内容
+type LiteStatus struct {
+	Head            uint64    `json:"head"`
+	HeadTime        time.Time `json:"head_time"` // 区块时间戳
+	LastHead        time.Time `json:"last_head"` // 本地收到的时间
+	Safe            uint64    `json:"safe"`
+	Finalized       uint64    `json:"finalized"`
+	FinalityLag     uint64    `json:"finality_lag"`
+	HeadStalled     bool      `json:"head_stalled"`
+	FinalityStalled bool      `json:"finality_stalled"`
+	HeapMB          float64   `json:"heap_mb"`
+}
+
+// LiteMonitor 只看区块头的看门狗：区块头连续性、出块停滞和最终性停滞
+type LiteMonitor struct {
+	cfg    LiteConfig
+	client *ethclient.Client
+	chain  *HeaderChain
+
+	lastHead   atomic.Int64 // 最近一次收到区块头的时间（UnixNano）
+	subscribed atomic.Bool
+
+	mu              sync.Mutex
+	head            *types.Header
+	safe, finalized *types.Header
+	headStalled     bool
+	finalityStalled bool
+	noFinality      bool // 节点不支持 finalized 标签
+	finalityFailing bool // 最近一次查询 finalized 区块失败（临时错误），只在开始失败时输出日志
+
+	onAlert func(LiteAlert)
+}
+
+// NewLiteMonitor 创建轻量模式监控，onAlert 为 nil 时使用默认输出
+func NewLiteMonitor(cfg LiteConfig, client *ethclient.Client, onAlert func(LiteAlert)) *LiteMonitor {
+	if onAlert == nil {
+		onAlert = PrintLiteAlert
+	}
+	m := &LiteMonitor{cfg: cfg, client: client, chain: NewHeaderChain(client, nil), onAlert: onAlert}
+	m.lastHead.Store(time.Now().UnixNano())
+	return m
+}
+
+// LastHead 最近一次收到区块头的时间
+func (m *LiteMonitor) LastHead() time.Time { return time.Unix(0, m.lastHead.Load()) }
+
+// Run 订阅新区块头并定期检查停滞和最终性，直到 ctx 取消
+// 订阅中断后按 RPC 重试策略的退避时间重新订阅，期间停滞检查照常进行；节点不支持订阅（HTTP）时返回错误
+func (m *LiteMonitor) Run(ctx context.Context) error {
+	heads := make(chan *types.Header, 16)
+	var sub ethereum.Subscription
+	var subErr <-chan error
+	var resubscribe <-chan time.Time
+	attempt := 0
+	subscribe := func() error {
+		s, err := m.client.SubscribeNewHead(ctx, heads)
+		if err != nil {
+			if errors.Is(err, rpc.ErrNotificationsUnsupported) {
+				return fmt.Errorf("订阅新区块失败: %w", err)
+			}
+			attempt++
+			delay := currentRetryPolicy().backoff(attempt)
+			log.Printf("⚠️  订阅新区块失败: %v（%s 后第 %d 次重试）", err, delay, attempt)
+			resubscribe = time.After(delay)
+			return nil
+		}
+		sub, subErr, resubscribe, attempt = s, s.Err(), nil, 0
+		m.subscribed.Store(true)
+		return nil
+	}
+	if err := subscribe(); err != nil {
+		return err
+	}
+	defer func() {
+		if sub != nil {
+			sub.Unsubscribe()
+		}
+	}()
+	defer m.subscribed.Store(false)
+	go m.chain.Run(ctx)
+
+	check := time.NewTicker(LiteCheckInterval)
+	defer check.Stop()
+	finality := time.NewTicker(time.Duration(m.cfg.FinalityInterval) * time.Second)
+	defer finality.Stop()
+	m.checkFinality(ctx)
+
+	for {
+		select {
+		case h := <-heads:
+			m.onHead(h)
+		case <-check.C:
+			m.checkStall(time.Now())
+		case <-finality.C:
+			m.checkFinality(ctx)
+		case err := <-subErr:
+			sub.Unsubscribe()
+			sub, subErr = nil, nil
+			m.subscribed.Store(false)
+			if err == nil {
+				err = errors.New("连接已关闭") // 客户端断开时 Err 通道直接关闭
+			}
+			log.Printf("⚠️  区块订阅中断: %v，重新订阅", err)
+			if err := subscribe(); err != nil {
+				return err
+			}
+		case <-resubscribe:
+			if err := subscribe(); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// onHead 记录新区块头，停滞后恢复时提醒
+func (m *LiteMonitor) onHead(h *types.Header) {
+	now := time.Now()
+	m.lastHead.Store(now.UnixNano())
+	observeHeadLag(h, now)
+	m.chain.NotifyHead(h)
+
+	m.mu.Lock()
+	m.head = h
+	resumed := m.headStalled
+	m.headStalled = false
+	m.mu.Unlock()
+	if resumed {
+		m.alert(LiteHeadResumed, "收到新区块头，出块恢复")
+	}
+}
+
+// checkStall 超过 stall_seconds 没有新区块头时提醒一次
+func (m *LiteMonitor) checkStall(now time.Time) {
+	age := now.Sub(m.LastHead())
+	m.mu.Lock()
+	stalled := !m.headStalled && age > time.Duration(m.cfg.StallSeconds)*time.Second
+	if stalled {
+		m.headStalled = true
+	}
+	m.mu.Unlock()
+	if stalled {
+		m.alert(LiteHeadStall, fmt.Sprintf("已经 %s 没有收到新区块头", age.Round(time.Second)))
+	}
+}
+
+// checkFinality 查询 safe / finalized 区块，检查最终性是否停滞或回退
+func (m *LiteMonitor) checkFinality(ctx context.Context) {
+	if m.noFinality {
+		return
+	}
+	finalized, err := headerByNumberRetry(ctx, m.client, big.NewInt(int64(rpc.FinalizedBlockNumber)))
+	if err != nil {
+		switch {
+		case isFinalityUnsupported(err):
+			// 不支持 finalized 标签的节点，只做出块检查
+			log.Printf("⚠️  节点不支持 finalized 标签: %v（只检查出块停滞）", err)
+			m.noFinality = true
+		case !m.finalityFailing:
+			// 临时错误或节点还没有 finalized 区块（合并前的链、同步中），下个周期再查
+			log.Printf("⚠️  查询 finalized 区块失败: %v（每 %ds 重试）", err, m.cfg.FinalityInterval)
+		}
+		m.finalityFailing = true
+		return
+	}
+	if m.finalityFailing {
+		m.finalityFailing = false
+		log.Printf("✅ 查询 finalized 区块恢复正常")
+	}
+	safe, err := headerByNumberRetry(ctx, m.client, big.NewInt(int64(rpc.SafeBlockNumber)))
+	if err != nil {
+		safe = nil
+	}
+
+	m.mu.Lock()
+	prev := m.finalized
+	m.finalized = finalized
+	if safe != nil {
+		m.safe = safe
+	}
+	var lag uint64
+	if m.head != nil && m.head.Number.Cmp(finalized.Number) > 0 {
+		lag = m.head.Number.Uint64() - finalized.Number.Uint64()
+	}
+	stalled := lag > m.cfg.FinalityLagBlocks
+	changed := stalled != m.finalityStalled
+	m.finalityStalled = stalled
+	m.mu.Unlock()
+
+	if prev != nil {
+		switch {
+		case finalized.Number.Cmp(prev.Number) < 0:
+			m.alert(LiteFinalizedReorg, fmt.Sprintf("finalized 从 %d 后退到 %d", prev.Number, finalized.Number))
+		case finalized.Number.Cmp(prev.Number) == 0 && finalized.Hash() != prev.Hash():
+			m.alert(LiteFinalizedReorg, fmt.Sprintf("finalized 区块 %d 的哈希从 %s 变为 %s", finalized.Number, prev.Hash().Hex(), finalized.Hash().Hex()))
+		}
+	}
+	if changed && stalled {
+		m.alert(LiteFinalityStall, fmt.Sprintf("finalized 落后链头 %d 个区块（阈值 %d）", lag, m.cfg.FinalityLagBlocks))
+	} else if changed {
+		m.alert(LiteFinalityResumed, fmt.Sprintf("finalized 落后链头 %d 个区块，最终性恢复", lag))
+	}
+}
+
+// isFinalityUnsupported 节点明确表示不认识 finalized 标签：方法或参数不支持（-32601 / -32602），
+// 或错误信息说明不支持该标签；找不到 finalized 区块、超时等不算
+func isFinalityUnsupported(err error) bool {
+	if isMethodNotFound(err) {
+		return true
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32602 {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "finalized") && (strings.Contains(msg, "not supported") || strings.Contains(msg, "unsupported"))
+}
+
+// alert 带上当前链头和 finalized 高度输出提醒
+func (m *LiteMonitor) alert(kind, detail string) {
+	a := LiteAlert{Kind: kind, Detail: detail, Time: time.Now()}
+	m.mu.Lock()
+	if m.head != nil {
+		a.Head = m.head.Number.Uint64()
+	}
+	if m.finalized != nil {
+		a.Finalized = m.finalized.Number.Uint64()
+	}
+	m.mu.Unlock()
+	m.onAlert(a)
+}
+
+// Status 当前链头、最终性和内存占用
+func (m *LiteMonitor) Status() LiteStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := LiteStatus{LastHead: m.LastHead(), HeadStalled: m.headStalled, FinalityStalled: m.finalityStalled}
+	if m.head != nil {
+		s.Head, s.HeadTime = m.head.Number.Uint64(), time.Unix(int64(m.head.Time), 0)
+	}
+	if m.safe != nil {
+		s.Safe = m.safe.Number.Uint64()
+	}
+	if m.finalized != nil {
+		s.Finalized = m.finalized.Number.Uint64()
+		if s.Head > s.Finalized {
+			s.FinalityLag = s.Head - s.Finalized
+		}
+	}
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	s.HeapMB = float64(ms.HeapAlloc) / (1 << 20)
+	return s
+}
+
+// RegisterAPI 注册轻量模式的查询接口
+//
+//	GET /api/lite    链头、safe / finalized 高度、停滞状态和内存占用
+//	GET /api/headers 区块头链校验（见 header_chain.go）
+func (m *LiteMonitor) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/lite", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, m.Status())
+	})
+	m.chain.RegisterAPI(api)
+}
+
+// PrintLiteAlert 默认的轻量模式提醒输出
+func PrintLiteAlert(a LiteAlert) {
+	icon := "⚠️"
+	if a.Kind == LiteHeadResumed || a.Kind == LiteFinalityResumed {
+		icon = "✅"
+	}
+	summary := fmt.Sprintf("🩺 [Lite] %s %s | 链头 %d | finalized %d | %s", icon, a.Kind, a.Head, a.Finalized, a.Detail)
+	Emit(Event{Type: "lite", Key: eventKey(a.Kind, a.Head, a.Finalized), Summary: summary, Text: summary, Data: a})
+}
+
+// runLite 轻量模式：只订阅区块头的出块 / 最终性看门狗
+func runLite(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("lite", flag.ExitOnError)
+	configPath := fs.String("config", DefaultConfigPath, "配置文件路径")
+	pidFile := fs.String("pidfile", "", "写入 PID 文件（守护进程模式），退出时删除")
+	fs.Parse(args)
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if cfg.Lite.MemoryLimitMB > 0 {
+		debug.SetMemoryLimit(int64(cfg.Lite.MemoryLimitMB) << 20)
+	}
+	if *pidFile != "" {
+		remove, err := WritePIDFile(*pidFile)
+		if err != nil {
+			return err
+		}
+		defer remove()
+	}
+	clients, err := Dial(cfg)
+	if err != nil {
+		return err
+	}
+	defer clients.Close()
+
+	output, err := NewOutput(cfg.Outputs)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+	if err := output.Start(ctx); err != nil {
+		return err
+	}
+	SetDefaultOutput(output)
+	SetRouting(NewRouter(cfg.Routing))
+	SetExplorerURL(cfg.ExplorerURL)
+	serverTLS, err := NewServerTLS(cfg.TLS)
+	if err != nil {
+		return err
+	}
+	StartMetricsServer(cfg.MetricsAddr, serverTLS)
+
+	lite := NewLiteMonitor(cfg.Lite, clients.Eth, nil)
+	api := NewAPIServer(cfg.APIAddr)
+	auth, err := NewAPIAuth(cfg.APIAuth)
+	if err != nil {
+		return err
+	}
+	api.SetAuth(auth)
+	api.SetTLS(serverTLS)
+	registerHealthChecks(api, time.Duration(cfg.ReadyMaxHeadAge)*time.Second, lite.subscribed.Load, lite.LastHead)
+	lite.RegisterAPI(api)
+	api.Start(ctx)
+
+	notifier := NewNotifier()
+	notifier.Notify("READY=1\nSTATUS=轻量模式：只订阅区块头")
+	go notifier.RunWatchdog(ctx, lite.LastHead)
+	fmt.Printf("🩺 轻量模式已启动：只订阅区块头（停滞 %ds / 最终性落后 %d 个区块时提醒）\n",
+		cfg.Lite.StallSeconds, cfg.Lite.FinalityLagBlocks)
+	return lite.Run(ctx)
+}
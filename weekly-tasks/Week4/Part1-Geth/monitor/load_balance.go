@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ------------------------------------------------
+// 多节点加权分流：区块 / 回执 / 代码等读请求按权重在未熔断的节点之间轮流发送（平滑加权轮询），
+// 分摊单个服务商的限流和账单；订阅则固定在启动时测得延迟最低的 WebSocket 节点上
+// 熔断判断沿用 breaker.go，熔断的节点不参与分流，只在所有节点都不可用时兜底
+// ------------------------------------------------
+
+const (
+	BalanceFailover = "failover" // 始终优先主节点，熔断时才改发备用节点
+	BalanceWeighted = "weighted" // 按权重在未熔断的节点之间分流
+
+	DefaultEndpointWeight = 1
+
+	// 选择订阅节点时每个节点测几次 eth_blockNumber，取中位数
+	PinSamples = 3
+
+	// 订阅改到备用节点后，配置中原来的 ws_url 在节点池中的名称
+	PinnedPrimaryName = "ws_url"
+)
+
+// PoolEndpointConfig rpc_pool 中的一个备用节点
+type PoolEndpointConfig struct {
+	EndpointConfig
+	Weight int `json:"weight"` // strategy 为 weighted 时的权重，0 按 1 处理
+}
+
+// endpointWeight 配置中的权重，0 按默认值处理
+func endpointWeight(w int) int {
+	if w <= 0 {
+		return DefaultEndpointWeight
+	}
+	return w
+}
+
+// weighted 平滑加权轮询：每次给所有节点加上各自的权重，选出当前值最大的节点并减去总权重，
+// 权重 3:1 时依次选出 A A B A，而不是 A A A B；选中的节点在最前，其余按权重从大到小排在后面作为重试顺序
+// 调用方持有锁
+func (p *EndpointPool) weighted(healthy []*poolEndpoint) []*poolEndpoint {
+	var total int
+	var best *poolEndpoint
+	for _, ep := range healthy {
+		ep.current += ep.weight
+		total += ep.weight
+		if best == nil || ep.current > best.current {
+			best = ep
+		}
+	}
+	best.current -= total
+	rest := make([]*poolEndpoint, 0, len(healthy)-1)
+	for _, ep := range healthy {
+		if ep != best {
+			rest = append(rest, ep)
+		}
+	}
+	sort.SliceStable(rest, func(i, j int) bool { return rest[i].weight > rest[j].weight })
+	return append([]*poolEndpoint{best}, rest...)
+}
+
+// poolCall 带重试地执行一次读请求：pool 为 nil 时直接用 client，否则每次尝试都由 pool 选择节点
+// （不走 HTTP 的节点由 pool 按实际选中的节点计入 RPC 用量统计）
+func poolCall(ctx context.Context, pool *EndpointPool, client *ethclient.Client, what string, fn func(c *ethclient.Client) error) error {
+	if pool == nil {
+		return Retry(ctx, what, func() error { return fn(client) })
+	}
+	return retry(ctx, what, false, func() error {
+		return pool.Do(ctx, what, fn)
+	})
+}
+
+// pinCandidate 一个可以承载订阅的节点及测得的延迟
+type pinCandidate struct {
+	name    string
+	url     string
+	index   int // 在 rpc_pool.endpoints 中的下标，主节点为 -1
+	latency time.Duration
+	err     error
+}
+
+// PinFastestEndpoint 测量主节点和 rpc_pool 中 WebSocket 节点的延迟，
+// 备用节点更快时把它换成 ws_url（订阅都建立在 ws_url 上），原来的 ws_url 连同主节点权重换进节点池
+// 只有开启 rpc_pool.pin_subscriptions 时才测量；所有节点都测量失败时保持原配置
+func PinFastestEndpoint(ctx context.Context, cfg *Config) {
+	if !cfg.RPCPool.PinSubscriptions || len(cfg.RPCPool.Endpoints) == 0 {
+		return
+	}
+	candidates := []*pinCandidate{{name: "primary", url: cfg.WSURL, index: -1}}
+	for i, ep := range cfg.RPCPool.Endpoints {
+		if u, err := url.Parse(ep.WSURL); err == nil && (u.Scheme == "ws" || u.Scheme == "wss") {
+			candidates = append(candidates, &pinCandidate{name: ep.Name, url: ep.WSURL, index: i})
+		}
+	}
+	if len(candidates) == 1 {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, c := range candidates {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.latency, c.err = measureLatency(ctx, c.url)
+		}()
+	}
+	wg.Wait()
+
+	var best *pinCandidate
+	for _, c := range candidates {
+		if c.err != nil {
+			log.Printf("⚠️  测量节点 %s 的延迟失败: %v", c.name, c.err)
+			continue
+		}
+		if best == nil || c.latency < best.latency {
+			best = c
+		}
+	}
+	if best == nil || best.index < 0 {
+		if best != nil {
+			log.Printf("📌 订阅保持在主节点（延迟 %s 最低）", best.latency.Round(time.Millisecond))
+		}
+		return
+	}
+
+	ep := &cfg.RPCPool.Endpoints[best.index]
+	primaryURL, primaryWeight := cfg.WSURL, cfg.RPCPool.PrimaryWeight
+	cfg.WSURL, cfg.RPCPool.PrimaryWeight = ep.WSURL, ep.Weight
+	*ep = PoolEndpointConfig{EndpointConfig: EndpointConfig{Name: PinnedPrimaryName, WSURL: primaryURL}, Weight: primaryWeight}
+	log.Printf("📌 订阅改到延迟最低的节点 %s（%s），原 ws_url 转为备用节点 %s", best.name, best.latency.Round(time.Millisecond), PinnedPrimaryName)
+}
+
+// measureLatency 连接节点并测 PinSamples 次 eth_blockNumber，返回中位数（不含建立连接的时间）
+func measureLatency(ctx context.Context, rawURL string) (time.Duration, error) {
+	dialCtx, cancel := context.WithTimeout(ctx, CONNECTION_TIMEOUT)
+	defer cancel()
+	client, err := dialRPC(dialCtx, rawURL)
+	if err != nil {
+		return 0, fmt.Errorf("连接失败: %w", err)
+	}
+	defer client.Close()
+
+	samples := make([]time.Duration, 0, PinSamples)
+	for i := 0; i < PinSamples; i++ {
+		var head string
+		start := time.Now()
+		// 测延迟不重试，否则退避的等待会算进延迟
+		if err := client.CallContext(dialCtx, &head, "eth_blockNumber"); err != nil {
+			return 0, err
+		}
+		samples = append(samples, time.Since(start))
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	return samples[len(samples)/2], nil
+}
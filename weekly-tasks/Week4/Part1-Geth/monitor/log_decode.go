@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ------------------------------------------------
+// 日志解码：topic0 -> 事件定义的注册表，把原始日志渲染成
+// Swap(sender=0x..., amount0In=..., ...) 这样的可读形式
+// ------------------------------------------------
+
+// builtinEventsABI 常见事件（带参数名和 indexed 信息），没有合约 ABI 时也能解码
+// ERC-20 和 ERC-721 的 Transfer / Approval 签名相同，靠 indexed 参数个数（topic 数）区分
+const builtinEventsABI = `[
+	{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256"}]},
+	{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"tokenId","type":"uint256","indexed":true}]},
+	{"type":"event","name":"Approval","inputs":[{"name":"owner","type":"address","indexed":true},{"name":"spender","type":"address","indexed":true},{"name":"value","type":"uint256"}]},
+	{"type":"event","name":"Approval","inputs":[{"name":"owner","type":"address","indexed":true},{"name":"approved","type":"address","indexed":true},{"name":"tokenId","type":"uint256","indexed":true}]},
+	{"type":"event","name":"ApprovalForAll","inputs":[{"name":"owner","type":"address","indexed":true},{"name":"operator","type":"address","indexed":true},{"name":"approved","type":"bool"}]},
+	{"type":"event","name":"Deposit","inputs":[{"name":"dst","type":"address","indexed":true},{"name":"wad","type":"uint256"}]},
+	{"type":"event","name":"Withdrawal","inputs":[{"name":"src","type":"address","indexed":true},{"name":"wad","type":"uint256"}]},
+	{"type":"event","name":"Sync","inputs":[{"name":"reserve0","type":"uint112"},{"name":"reserve1","type":"uint112"}]},
+	{"type":"event","name":"Swap","inputs":[{"name":"sender","type":"address","indexed":true},{"name":"amount0In","type":"uint256"},{"name":"amount1In","type":"uint256"},{"name":"amount0Out","type":"uint256"},{"name":"amount1Out","type":"uint256"},{"name":"to","type":"address","indexed":true}]},
+	{"type":"event","name":"Swap","inputs":[{"name":"sender","type":"address","indexed":true},{"name":"recipient","type":"address","indexed":true},{"name":"amount0","type":"int256"},{"name":"amount1","type":"int256"},{"name":"sqrtPriceX96","type":"uint160"},{"name":"liquidity","type":"uint128"},{"name":"tick","type":"int24"}]},
+	{"type":"event","name":"Mint","inputs":[{"name":"sender","type":"address","indexed":true},{"name":"amount0","type":"uint256"},{"name":"amount1","type":"uint256"}]},
+	{"type":"event","name":"Burn","inputs":[{"name":"sender","type":"address","indexed":true},{"name":"amount0","type":"uint256"},{"name":"amount1","type":"uint256"},{"name":"to","type":"address","indexed":true}]},
+	{"type":"event","name":"Burn","inputs":[{"name":"owner","type":"address","indexed":true},{"name":"tickLower","type":"int24","indexed":true},{"name":"tickUpper","type":"int24","indexed":true},{"name":"amount","type":"uint128"},{"name":"amount0","type":"uint256"},{"name":"amount1","type":"uint256"}]},
+	{"type":"event","name":"PairCreated","inputs":[{"name":"token0","type":"address","indexed":true},{"name":"token1","type":"address","indexed":true},{"name":"pair","type":"address"},{"name":"","type":"uint256"}]},
+	{"type":"event","name":"OwnershipTransferred","inputs":[{"name":"previousOwner","type":"address","indexed":true},{"name":"newOwner","type":"address","indexed":true}]},
+	{"type":"event","name":"Upgraded","inputs":[{"name":"implementation","type":"address","indexed":true}]}
+]`
+
+// DecodedArg 解码后的一个参数
+type DecodedArg struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// DecodedLog 解码后的日志
+type DecodedLog struct {
+	Name string       `json:"name"`
+	Sig  string       `json:"signature"`
+	Args []DecodedArg `json:"args"`
+}
+
+// String 渲染为 Name(a=1, b=0x...)
+func (d *DecodedLog) String() string {
+	parts := make([]string, len(d.Args))
+	for i, a := range d.Args {
+		parts[i] = a.Name + "=" + a.Value
+	}
+	return d.Name + "(" + strings.Join(parts, ", ") + ")"
+}
+
+// EventRegistry topic0 -> 事件定义，同一个 topic0 可以有多种 indexed 布局
+// 来源：内置的常见事件、ABIRegistry 中加载的所有 ABI（包括从 Sourcify / Etherscan 拉取的）、事件签名文件
+type EventRegistry struct {
+	mu      sync.RWMutex
+	byTopic map[common.Hash][]abi.Event
+}
+
+// NewEventRegistry 创建注册表并加载内置的常见事件
+func NewEventRegistry() *EventRegistry {
+	r := &EventRegistry{byTopic: make(map[common.Hash][]abi.Event)}
+	builtin, err := abi.JSON(strings.NewReader(builtinEventsABI))
+	if err != nil {
+		panic(err) // 内置 ABI 写错了
+	}
+	r.AddABI(&builtin)
+	return r
+}
+
+// builtinEvents 没有 ABIRegistry 时使用的内置事件表
+var builtinEvents = NewEventRegistry()
+
+// AddABI 加入 ABI 中的全部事件，indexed 布局相同的事件只保留一份
+func (r *EventRegistry) AddABI(a *abi.ABI) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, ev := range a.Events {
+		r.add(ev)
+	}
+}
+
+// add 调用方持有锁
+func (r *EventRegistry) add(ev abi.Event) {
+	for _, existing := range r.byTopic[ev.ID] {
+		if indexedLayout(existing) == indexedLayout(ev) {
+			return
+		}
+	}
+	r.byTopic[ev.ID] = append(r.byTopic[ev.ID], ev)
+}
+
+// indexedLayout 每个参数是否 indexed，例如 "110"
+func indexedLayout(ev abi.Event) string {
+	var sb strings.Builder
+	for _, in := range ev.Inputs {
+		if in.Indexed {
+			sb.WriteByte('1')
+		} else {
+			sb.WriteByte('0')
+		}
+	}
+	return sb.String()
+}
+
+// AddSignature 加入文本签名，例如 "Transfer(address,address,uint256)"
+// 文本签名不带参数名和 indexed 信息：参数命名为 arg0、arg1...，
+// 并按"前 N 个参数 indexed"生成所有可能的布局，解码时按 topic 数选择（大多数合约符合这个习惯）
+func (r *EventRegistry) AddSignature(sig string) error {
+	name, argTypes, err := parseSignature(sig)
+	if err != nil {
+		return fmt.Errorf("事件签名错误: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for n := 0; n <= min(3, len(argTypes)); n++ {
+		inputs := make(abi.Arguments, len(argTypes))
+		for i, typ := range argTypes {
+			inputs[i] = abi.Argument{Name: fmt.Sprintf("arg%d", i), Type: typ, Indexed: i < n}
+		}
+		r.add(abi.NewEvent(name, name, false, inputs))
+	}
+	return nil
+}
+
+// LoadSignatures 从文件加载事件签名，每行一个，# 开头为注释
+func (r *EventRegistry) LoadSignatures(path string) (int, error) {
+	return readSignatureFile(path, r.AddSignature)
+}
+
+// parseSignature 解析 "name(type1,type2)" 形式的文本签名，暂不支持 tuple 参数
+func parseSignature(sig string) (string, []abi.Type, error) {
+	open := strings.IndexByte(sig, '(')
+	if open <= 0 || !strings.HasSuffix(sig, ")") {
+		return "", nil, fmt.Errorf("格式错误: %q", sig)
+	}
+	name, list := sig[:open], sig[open+1:len(sig)-1]
+	if strings.ContainsAny(list, "()") {
+		return "", nil, fmt.Errorf("暂不支持 tuple 参数: %q", sig)
+	}
+	var argTypes []abi.Type
+	if list != "" {
+		for _, t := range strings.Split(list, ",") {
+			typ, err := abi.NewType(strings.TrimSpace(t), "", nil)
+			if err != nil {
+				return "", nil, fmt.Errorf("%q 中的类型错误: %w", sig, err)
+			}
+			argTypes = append(argTypes, typ)
+		}
+	}
+	return name, argTypes, nil
+}
+
+// readSignatureFile 逐行读取签名文件交给 add，空行和 # 开头的注释跳过
+func readSignatureFile(path string, add func(string) error) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("读取签名文件失败: %w", err)
+	}
+	defer f.Close()
+	n := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := add(strings.ReplaceAll(line, " ", "")); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, scanner.Err()
+}
+
+// loadSignatures 加载配置中的全部事件签名和函数签名文件
+func loadSignatures(reg *ABIRegistry, cfg ABIConfig) error {
+	for _, p := range cfg.EventSignatures {
+		n, err := reg.Events().LoadSignatures(p)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		log.Printf("✅ 已加载 %d 个事件签名: %s", n, p)
+	}
+	for _, p := range cfg.FunctionSignatures {
+		n, err := reg.Methods().LoadSignatures(p)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		log.Printf("✅ 已加载 %d 个函数签名: %s", n, p)
+	}
+	return nil
+}
+
+// Decode 用 topic0 对应的事件定义逐个尝试解码，返回第一个 topic 数和 data 都吻合的结果
+func (r *EventRegistry) Decode(l types.Log) (*DecodedLog, bool) {
+	if len(l.Topics) == 0 {
+		return nil, false
+	}
+	r.mu.RLock()
+	candidates := r.byTopic[l.Topics[0]]
+	r.mu.RUnlock()
+	for _, ev := range candidates {
+		if d, ok := decodeEvent(ev, l); ok {
+			return d, true
+		}
+	}
+	return nil, false
+}
+
+// decodeEvent 按指定事件定义解码，indexed 参数从 topics 中解出，其余从 data 中解出
+// 动态类型（string / bytes / 数组）的 indexed 参数在 topic 中只有哈希，原值无法还原
+func decodeEvent(ev abi.Event, l types.Log) (*DecodedLog, bool) {
+	var indexed abi.Arguments
+	for _, in := range ev.Inputs {
+		if in.Indexed {
+			indexed = append(indexed, in)
+		}
+	}
+	if len(indexed) != len(l.Topics)-1 {
+		return nil, false
+	}
+	values, err := ev.Inputs.NonIndexed().Unpack(l.Data)
+	if err != nil {
+		return nil, false
+	}
+	topics := make(map[string]interface{})
+	named := make(abi.Arguments, len(indexed))
+	for i, in := range indexed {
+		in.Name = fmt.Sprintf("topic%d", i+1) // 原参数名可能为空或重复
+		named[i] = in
+	}
+	if err := abi.ParseTopicsIntoMap(topics, named, l.Topics[1:]); err != nil {
+		return nil, false
+	}
+
+	d := &DecodedLog{Name: ev.RawName, Sig: ev.Sig, Args: make([]DecodedArg, 0, len(ev.Inputs))}
+	ti, vi := 0, 0
+	for i, in := range ev.Inputs {
+		var v interface{}
+		if in.Indexed {
+			ti++
+			v = topics[fmt.Sprintf("topic%d", ti)]
+		} else {
+			v = values[vi]
+			vi++
+		}
+		name := in.Name
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		d.Args = append(d.Args, DecodedArg{Name: name, Type: in.Type.String(), Value: formatABIValue(v)})
+	}
+	return d, true
+}
+
+// formatABIValue 把 abi 解码出的 Go 值格式化为可读字符串，tuple 按字段名展开
+func formatABIValue(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "<nil>"
+	case common.Address:
+		return x.Hex()
+	case common.Hash:
+		return x.Hex()
+	case *big.Int:
+		return x.String()
+	case []byte:
+		return hexutil.Encode(x)
+	case string:
+		return fmt.Sprintf("%q", x)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 { // bytesN
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return hexutil.Encode(b)
+		}
+		fallthrough
+	case reflect.Slice:
+		parts := make([]string, rv.Len())
+		for i := range parts {
+			parts[i] = formatABIValue(rv.Index(i).Interface())
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case reflect.Struct:
+		parts := make([]string, rv.NumField())
+		for i := range parts {
+			parts[i] = rv.Type().Field(i).Name + ": " + formatABIValue(rv.Field(i).Interface())
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ------------------------------------------------
+// 日志处理流水线：实时订阅到的日志和历史回扫得到的日志都从这里进入，
+// 保证两条路径的解码和输出完全一致
+// ------------------------------------------------
+
+// LogHandler 日志处理函数
+type LogHandler func(types.Log)
+
+// LogPipeline 按注册顺序把日志交给每个处理函数
+type LogPipeline struct {
+	mu       sync.RWMutex
+	handlers []LogHandler
+}
+
+// NewLogPipeline 创建流水线，不传处理函数时默认打印日志
+func NewLogPipeline(handlers ...LogHandler) *LogPipeline {
+	if len(handlers) == 0 {
+		handlers = []LogHandler{PrintLog}
+	}
+	return &LogPipeline{handlers: handlers}
+}
+
+// Register 追加一个处理函数
+func (p *LogPipeline) Register(h LogHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handlers = append(p.handlers, h)
+}
+
+// Handle 处理一条日志
+func (p *LogPipeline) Handle(l types.Log) {
+	p.mu.RLock()
+	handlers := p.handlers
+	p.mu.RUnlock()
+	for _, h := range handlers {
+		h(l)
+	}
+}
+
+// eventName 返回日志的事件签名，无法识别时返回 topic0
+func eventName(reg *ABIRegistry, l types.Log) string {
+	if len(l.Topics) == 0 {
+		return "anonymous"
+	}
+	if d, ok := reg.DecodeLog(l); ok {
+		return d.Sig
+	}
+	return l.Topics[0].Hex()
+}
+
+// erc20TransferTopic ERC-20 / ERC-721 共用的 Transfer 事件
+var erc20TransferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// NewLogPrinter 打印日志的处理函数，reg、tokens、values 都可以为 nil
+// ERC-20 Transfer 会附带按 decimals 换算后的金额（元数据未缓存时先显示原始值），
+// 能定价的 Transfer / Swap 再附带近似的美元价值
+func NewLogPrinter(reg *ABIRegistry, tokens *TokenCache, values *Valuator) LogHandler {
+	return func(l types.Log) {
+		tag := "📜 [Log]"
+		if l.Removed { // 该日志所在区块被重组掉了
+			tag = "⚠️  [Log Removed]"
+		}
+		var event string
+		if d, ok := reg.DecodeLog(l); ok {
+			event = d.String()
+		} else {
+			event = eventName(reg, l)
+		}
+		line := fmt.Sprintf("%s 区块 %d | %s #%d | %s | %s",
+			tag, l.BlockNumber, l.TxHash.Hex(), l.Index, l.Address.Hex(), event)
+		// ERC-721 的 tokenId 是 indexed（4 个 topic），ERC-20 的金额在 data 中（3 个 topic）
+		if len(l.Topics) == 3 && l.Topics[0] == erc20TransferTopic && len(l.Data) == 32 {
+			amount := new(big.Int).SetBytes(l.Data)
+			if meta, ok := tokens.Lookup(l.Address); ok {
+				line += " | " + meta.Format(amount)
+			} else {
+				line += " | " + amount.String()
+			}
+		}
+		if usd, _, ok := values.LogValue(l); ok {
+			line += " ≈ " + formatUSD(usd)
+		}
+		Emit(Event{Type: "log", Text: line, Data: l})
+	}
+}
+
+// PrintLog 只用内置的常见事件解码并打印日志
+func PrintLog(l types.Log) {
+	NewLogPrinter(nil, nil, nil)(l)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	// 初始每次 eth_getLogs 覆盖的区块数
+	LogScanInitialChunk = 2000
+	// 区块数上限，成功时逐步放大但不超过它
+	LogScanMaxChunk = 10000
+	// 连续成功多少次后把区间翻倍
+	LogScanGrowAfter = 5
+)
+
+// rangeErrorHints 各家节点在区间过大 / 结果过多时的报错片段
+// ⚠️ 没有统一的错误码，只能按文案匹配，遇到新的服务商可以继续补充
+var rangeErrorHints = []string{
+	"query returned more than", // Infura / Geth
+	"block range",              // Alchemy、QuickNode 等
+	"range is too large",
+	"exceed maximum block range", // BSC 系
+	"limit exceeded",
+	"response size exceeded",
+	"too many",
+	"timeout", // 大区间扫描超时，缩小区间重试通常能过
+}
+
+// LogScanner 用分段 eth_getLogs 回扫历史日志，区间被节点拒绝时自动缩小，
+// 结果送进与实时日志相同的 LogPipeline
+type LogScanner struct {
+	client   *ethclient.Client
+	pipeline *LogPipeline
+	chunk    uint64
+	progress bool // 是否打印每段的扫描进度
+}
+
+// NewLogScanner 创建历史日志扫描器
+func NewLogScanner(client *ethclient.Client, pipeline *LogPipeline) *LogScanner {
+	return &LogScanner{client: client, pipeline: pipeline, chunk: LogScanInitialChunk, progress: true}
+}
+
+// Scan 扫描 [from, to] 区间内匹配 query（地址、topics）的日志，返回日志数量
+// query 中的 FromBlock / ToBlock 会被忽略
+func (s *LogScanner) Scan(ctx context.Context, query ethereum.FilterQuery, from, to uint64) (int, error) {
+	found, streak := 0, 0
+	for start := from; start <= to; {
+		end := min(start+s.chunk-1, to)
+		query.FromBlock = new(big.Int).SetUint64(start)
+		query.ToBlock = new(big.Int).SetUint64(end)
+
+		var logs []types.Log
+		err := Retry(ctx, "eth_getLogs", func() (err error) {
+			logs, err = s.client.FilterLogs(ctx, query)
+			return err
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return found, ctx.Err()
+			}
+			if !isRangeError(err) || s.chunk == 1 {
+				return found, fmt.Errorf("eth_getLogs 区块 %d - %d 失败: %w", start, end, err)
+			}
+			s.chunk = max(s.chunk/2, 1)
+			streak = 0
+			log.Printf("⚠️  区间 %d - %d 被节点拒绝，缩小到每次 %d 个区块重试", start, end, s.chunk)
+			continue
+		}
+
+		for _, l := range logs {
+			s.pipeline.Handle(l)
+		}
+		found += len(logs)
+		if s.progress {
+			log.Printf("🔍 已扫描区块 %d - %d（%d 条日志，累计 %d 条）", start, end, len(logs), found)
+		}
+		start = end + 1
+
+		if streak++; streak >= LogScanGrowAfter && s.chunk < LogScanMaxChunk {
+			s.chunk = min(s.chunk*2, LogScanMaxChunk)
+			streak = 0
+		}
+	}
+	return found, nil
+}
+
+// isRangeError 判断错误是否由区间过大 / 结果过多引起
+func isRangeError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, hint := range rangeErrorHints {
+		if strings.Contains(msg, hint) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,519 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// 用法:
+//
+//	go run ./monitor [run] [-config monitor.json]       启动实时监控（默认子命令），-pidfile 写入 PID 文件，-arm 允许跟单发送交易
+//	go run ./monitor send -to 0x... -value 0.01         构造、签名并广播一笔交易
+//	go run ./monitor simulate -txs 0x02f8...,0x02f8...  在最新状态上模拟一组有序的已签名交易
+//	go run ./monitor traces -from 19000000 -to 19010000 用 trace_filter 补扫关注地址的历史内部转账
+//	go run ./monitor logs -from 19000000 -to 19010000   用 eth_getLogs 回扫配置中合约的历史日志
+//	go run ./monitor decode [-to 0x...] 0xa9059cbb...   解码 calldata 或交易哈希对应的调用（包括 multicall 内层调用）
+//	go run ./monitor bindgen -out monitor/bindings Router=0x... 为 indexer 合约和 ABI 缓存中的合约生成类型化 Go 绑定
+//	go run ./monitor lite [-config monitor.json]        轻量模式：只订阅区块头，作为出块 / 最终性看门狗
+//	go run ./monitor console [-config monitor.json]     交互式控制台：临时查询交易、区块、余额，解码 calldata
+//	go run ./monitor golden [-update] [-record 0x...]   离线回放 fixtures 中录制的交易，与 golden 输出比较
+//	go run ./monitor bench -session session.jsonl       重放抓取的交易池会话，统计各处理阶段的吞吐量和队列饱和度
+func main() {
+	cmd, args := "run", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	// Ctrl+C / SIGTERM 时取消 ctx，所有子命令统一优雅退出
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var err error
+	switch cmd {
+	case "run":
+		err = runMonitor(ctx, args)
+	case "send":
+		err = runSend(ctx, args)
+	case "simulate":
+		err = runSimulate(ctx, args)
+	case "traces":
+		err = runTraces(ctx, args)
+	case "logs":
+		err = runLogs(ctx, args)
+	case "decode":
+		err = runDecode(ctx, args)
+	case "bindgen":
+		err = runBindgen(ctx, args)
+	case "lite":
+		err = runLite(ctx, args)
+	case "console":
+		err = runConsole(ctx, args)
+	case "golden":
+		err = runGolden(ctx, args)
+	case "bench":
+		err = runBench(ctx, args)
+	default:
+		fmt.Fprintf(os.Stderr, "未知子命令: %s（可选 run / send / simulate / traces / logs / decode / bindgen / lite / console / golden / bench）\n", cmd)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+}
+
+// runMonitor 启动实时监控
+func runMonitor(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	configPath := fs.String("config", DefaultConfigPath, "配置文件路径")
+	pidFile := fs.String("pidfile", "", "写入 PID 文件（守护进程模式），退出时删除")
+	arm := fs.Bool("arm", false, "允许跟单真正发送交易（还需要 copy_trade.armed: true）")
+	fs.Parse(args)
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if cfg.CopyTrade.Armed && !*arm {
+		log.Println("⚠️  copy_trade.armed 已开启，但启动时没有加 -arm，跟单只做 dry-run")
+		cfg.CopyTrade.Armed = false
+	}
+	if *pidFile != "" {
+		remove, err := WritePIDFile(*pidFile)
+		if err != nil {
+			return err
+		}
+		defer remove()
+	}
+	log.Println("开始配置代理并连接到 WebSocket 节点")
+	clients, err := Dial(cfg)
+	if err != nil {
+		return err
+	}
+	defer clients.Close()
+	fmt.Println("✅ 成功建立 RPC WebSocket 连接")
+
+	output, err := NewOutput(cfg.Outputs)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+	if err := output.Start(ctx); err != nil {
+		return err
+	}
+	SetDefaultOutput(output)
+	SetRouting(NewRouter(cfg.Routing))
+	SetExplorerURL(cfg.ExplorerURL)
+	if len(cfg.Routing.Routes) > 0 {
+		fmt.Printf("🚦 提醒路由已开启: %d 条路由\n", len(cfg.Routing.Routes))
+	}
+	// 热加载可能替换了输出，退出时关闭当时在用的那个，之后的输出回到 stdout
+	defer func() {
+		stdout, _ := NewOutput(nil)
+		SetDefaultOutput(stdout).Close()
+	}()
+
+	serverTLS, err := NewServerTLS(cfg.TLS)
+	if err != nil {
+		return err
+	}
+	StartMetricsServer(cfg.MetricsAddr, serverTLS)
+	m := NewMonitor(cfg, clients)
+	m.SetServerTLS(serverTLS)
+	m.SetConfigPath(*configPath)
+	return m.Run(ctx)
+}
+
+// runSend 手动发送一笔交易，用来验证签名配置（例如硬件钱包）是否可用
+func runSend(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("send", flag.ExitOnError)
+	configPath := fs.String("config", DefaultConfigPath, "配置文件路径")
+	to := fs.String("to", "", "接收地址")
+	value := fs.String("value", "0", "转账金额（单位 ETH）")
+	data := fs.String("data", "", "十六进制 calldata（可选）")
+	confirmations := fs.Uint64("confirmations", 0, "广播后等待的确认数，0 表示不等待")
+	fs.Parse(args)
+
+	if !common.IsHexAddress(*to) {
+		return fmt.Errorf("-to 地址格式错误: %q", *to)
+	}
+	wei, err := parseEther(*value)
+	if err != nil {
+		return err
+	}
+	calldata, err := decodeHex(*data)
+	if err != nil {
+		return fmt.Errorf("-data 格式错误: %w", err)
+	}
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	signer, err := NewSigner(cfg.Signer)
+	if err != nil {
+		return err
+	}
+	defer signer.Close()
+
+	clients, err := Dial(cfg)
+	if err != nil {
+		return err
+	}
+	defer clients.Close()
+
+	sender, err := NewSender(ctx, clients.Eth, signer)
+	if err != nil {
+		return err
+	}
+	tx, err := sender.BuildTx(ctx, common.HexToAddress(*to), wei, calldata)
+	if err != nil {
+		return err
+	}
+	signed, err := sender.SignAndSend(ctx, tx)
+	if err != nil || *confirmations == 0 {
+		return err
+	}
+	return waitConfirmations(ctx, clients, signed.Hash(), *confirmations)
+}
+
+// runSimulate 模拟一个候选 bundle，输出每笔交易的执行结果和整体收益
+func runSimulate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	configPath := fs.String("config", DefaultConfigPath, "配置文件路径")
+	rawTxs := fs.String("txs", "", "逗号分隔的已签名原始交易（按执行顺序）")
+	block := fs.Uint64("block", 0, "作为模拟起点的区块高度，0 表示最新区块")
+	profit := fs.String("profit", "", "计算该地址在 bundle 中的 ETH 收益（仅 callMany）")
+	fs.Parse(args)
+
+	var txs []*types.Transaction
+	for _, raw := range strings.Split(*rawTxs, ",") {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+		data, err := decodeHex(raw)
+		if err != nil {
+			return fmt.Errorf("-txs 格式错误: %w", err)
+		}
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(data); err != nil {
+			return fmt.Errorf("解码交易失败: %w", err)
+		}
+		txs = append(txs, tx)
+	}
+	if len(txs) == 0 {
+		return fmt.Errorf("-txs 不能为空")
+	}
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	clients, err := Dial(cfg)
+	if err != nil {
+		return err
+	}
+	defer clients.Close()
+
+	chainID, err := chainIDRetry(ctx, clients.Eth)
+	if err != nil {
+		return fmt.Errorf("查询 chainID 失败: %w", err)
+	}
+	sim, err := NewBundleSimulator(cfg.Simulation, clients.RPC, types.LatestSignerForChainID(chainID))
+	if err != nil {
+		return err
+	}
+	var stateBlock *big.Int
+	if *block > 0 {
+		stateBlock = new(big.Int).SetUint64(*block)
+	}
+	result, err := sim.SimulateBundle(ctx, txs, stateBlock)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🧪 [Bundle Sim] %s | 状态区块 %d | %d 笔交易 | 总 gasUsed %d\n",
+		cfg.Simulation.Method, result.StateBlock, len(result.Txs), result.TotalGasUsed)
+	for i, r := range result.Txs {
+		status := "✅"
+		if r.Error != "" {
+			status = "❌ " + r.Error
+		}
+		fmt.Printf("   %d. %s from=%s gasUsed=%d %s\n", i+1, r.TxHash.Hex(), r.From.Hex(), r.GasUsed, status)
+	}
+	if result.CoinbaseDiff != nil {
+		fmt.Printf("   出块者收益: %.6f ETH\n", weiToEther(result.CoinbaseDiff))
+	}
+	if common.IsHexAddress(*profit) {
+		fmt.Printf("   %s 收益: %.6f ETH\n", *profit, weiToEther(result.Profit(common.HexToAddress(*profit))))
+	}
+	return nil
+}
+
+// runTraces 扫描历史区块中涉及关注地址的内部转账
+func runTraces(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("traces", flag.ExitOnError)
+	configPath := fs.String("config", DefaultConfigPath, "配置文件路径")
+	from := fs.Uint64("from", 0, "起始区块")
+	to := fs.Uint64("to", 0, "结束区块（包含），0 表示最新区块")
+	fs.Parse(args)
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	clients, err := Dial(cfg)
+	if err != nil {
+		return err
+	}
+	defer clients.Close()
+
+	node, err := DetectNode(ctx, clients.RPC)
+	if err != nil {
+		return err
+	}
+	if !node.ParityTrace {
+		return fmt.Errorf("节点 %s 不支持 trace_filter，请连接 Erigon / Nethermind 等归档节点", node.Kind)
+	}
+	end := *to
+	if end == 0 {
+		if end, err = blockNumberRetry(ctx, clients.Eth); err != nil {
+			return fmt.Errorf("查询最新区块失败: %w", err)
+		}
+	}
+	if *from > end {
+		return fmt.Errorf("-from (%d) 不能大于 -to (%d)", *from, end)
+	}
+
+	scanner := NewTraceScanner(NewTracer(node, clients.RPC), cfg.WatchAddresses(), nil)
+	found, err := scanner.Scan(ctx, *from, end)
+	if err != nil {
+		return err
+	}
+	log.Printf("✅ 扫描完成：区块 %d - %d，共 %d 笔内部转账", *from, end, found)
+	return nil
+}
+
+// runLogs 回扫历史日志，输出与实时监控完全一致
+func runLogs(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("logs", flag.ExitOnError)
+	configPath := fs.String("config", DefaultConfigPath, "配置文件路径")
+	from := fs.Uint64("from", 0, "起始区块")
+	to := fs.Uint64("to", 0, "结束区块（包含），0 表示最新区块")
+	fs.Parse(args)
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if len(cfg.Logs.Addresses) == 0 {
+		return fmt.Errorf("请先在配置文件的 logs.addresses 中填写合约地址")
+	}
+	clients, err := Dial(cfg)
+	if err != nil {
+		return err
+	}
+	defer clients.Close()
+
+	end := *to
+	if end == 0 {
+		if end, err = blockNumberRetry(ctx, clients.Eth); err != nil {
+			return fmt.Errorf("查询最新区块失败: %w", err)
+		}
+	}
+	if *from > end {
+		return fmt.Errorf("-from (%d) 不能大于 -to (%d)", *from, end)
+	}
+
+	// 回扫得到的是历史日志，按当前价格估值没有意义，这里不显示美元价值
+	abis := NewABIRegistry()
+	if err := loadSignatures(abis, cfg.ABI); err != nil {
+		return fmt.Errorf("加载签名失败: %w", err)
+	}
+	printer := NewLogPrinter(abis, NewTokenCache(clients.Eth, cfg.TokenCache), nil)
+	scanner := NewLogScanner(clients.Eth, NewLogPipeline(printer))
+	found, err := scanner.Scan(ctx, cfg.Logs.FilterQuery(), *from, end)
+	if err != nil {
+		return err
+	}
+	log.Printf("✅ 回扫完成：区块 %d - %d，共 %d 条日志", *from, end, found)
+	return nil
+}
+
+// runDecode 解码一段 calldata 或一笔交易的调用，输出函数、嵌套 tuple 参数和 multicall 内层调用
+// 参数是 32 字节的交易哈希时连接节点查询交易；-fetch 时从 Sourcify / Etherscan 拉取涉及合约的 ABI
+func runDecode(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("decode", flag.ExitOnError)
+	configPath := fs.String("config", DefaultConfigPath, "配置文件路径")
+	to := fs.String("to", "", "被调用的合约地址（解码交易哈希时不需要）")
+	abiPath := fs.String("abi", "", "被调用合约的 ABI 文件（可选，需要配合 -to）")
+	fetch := fs.Bool("fetch", false, "连接节点并拉取涉及合约的已验证 ABI")
+	asJSON := fs.Bool("json", false, "以 JSON 输出")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("用法: decode [-to 0x...] [-abi file] [-fetch] <calldata | 交易哈希>")
+	}
+	input := fs.Arg(0)
+	isTx := len(strings.TrimPrefix(input, "0x")) == 2*common.HashLength
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	abis := NewABIRegistry()
+	if err := loadSignatures(abis, cfg.ABI); err != nil {
+		return fmt.Errorf("加载签名失败: %w", err)
+	}
+
+	var target *common.Address
+	if *to != "" {
+		if !common.IsHexAddress(*to) {
+			return fmt.Errorf("-to 地址格式错误: %q", *to)
+		}
+		addr := common.HexToAddress(*to)
+		target = &addr
+	}
+	if *abiPath != "" {
+		if target == nil {
+			return fmt.Errorf("-abi 需要配合 -to 使用")
+		}
+		parsed, err := loadABIFile(*abiPath)
+		if err != nil {
+			return err
+		}
+		abis.Register(*target, parsed)
+	}
+
+	var data []byte
+	var fetcher *ABIFetcher
+	if isTx || *fetch {
+		clients, err := Dial(cfg)
+		if err != nil {
+			return err
+		}
+		defer clients.Close()
+		if isTx {
+			var tx *types.Transaction
+			err := Retry(ctx, "eth_getTransactionByHash", func() (err error) {
+				tx, _, err = clients.Eth.TransactionByHash(ctx, common.HexToHash(input))
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("查询交易失败: %w", err)
+			}
+			if tx.To() == nil {
+				return fmt.Errorf("交易 %s 是合约创建，没有可解码的调用", tx.Hash().Hex())
+			}
+			target, data = tx.To(), tx.Data()
+		}
+		if *fetch {
+			chainID, err := chainIDRetry(ctx, clients.Eth)
+			if err != nil {
+				return fmt.Errorf("查询 ChainID 失败: %w", err)
+			}
+			fetcher = NewABIFetcher(cfg.ABI, clients.Eth, abis, chainID)
+		}
+	} else if data, err = decodeHex(input); err != nil {
+		return fmt.Errorf("calldata 格式错误: %w", err)
+	}
+	if len(data) < 4 {
+		return fmt.Errorf("calldata 不足 4 字节，不是函数调用")
+	}
+	if target == nil {
+		target = &common.Address{} // 不知道目标合约时只按 selector 查找
+	}
+
+	call, _ := abis.DecodeCall(target, data)
+	if fetcher != nil {
+		// 先拉取外层合约，解码后再拉取内层调用涉及的合约，然后重新解码
+		if *target != (common.Address{}) {
+			fetcher.Fetch(ctx, *target)
+			call, _ = abis.DecodeCall(target, data)
+		}
+		for _, addr := range call.Targets() {
+			fetcher.Fetch(ctx, addr)
+		}
+		call, _ = abis.DecodeCall(target, data)
+	}
+
+	if *asJSON {
+		out, err := json.MarshalIndent(call, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+	if *target != (common.Address{}) {
+		fmt.Printf("-> %s\n", target.Hex())
+	}
+	fmt.Print(call.Pretty())
+	return nil
+}
+
+// waitConfirmations 订阅新区块并等待交易达到指定确认数
+func waitConfirmations(ctx context.Context, clients *Clients, hash common.Hash, depth uint64) error {
+	heads := make(chan *types.Header)
+	sub, err := clients.Eth.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		return fmt.Errorf("订阅新区块失败: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	waiter := NewReceiptWaiter(clients.Eth)
+	go waiter.Run(ctx)
+	go func() {
+		for {
+			select {
+			case h := <-heads:
+				waiter.NotifyHead(h)
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	log.Printf("⏳ 等待交易 %s 达到 %d 个确认...", hash.Hex(), depth)
+	receipt, err := waiter.WaitMined(ctx, hash, depth)
+	if err != nil {
+		return err
+	}
+	log.Printf("✅ 交易已确认: 区块 %d | status=%d | gasUsed=%d", receipt.BlockNumber, receipt.Status, receipt.GasUsed)
+	return nil
+}
+
+// parseEther 把 "0.01" 这样的 ETH 数量转换为 wei
+func parseEther(s string) (*big.Int, error) {
+	f, ok := new(big.Float).SetPrec(256).SetString(s)
+	if !ok || f.Sign() < 0 {
+		return nil, fmt.Errorf("金额格式错误: %q", s)
+	}
+	wei, _ := f.Mul(f, new(big.Float).SetInt(big.NewInt(params.Ether))).Int(nil)
+	return wei, nil
+}
+
+// decodeHex 解码可选的 0x 前缀十六进制字符串，空字符串返回 nil
+func decodeHex(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(s, "0x") {
+		s = "0x" + s
+	}
+	return hexutil.Decode(s)
+}
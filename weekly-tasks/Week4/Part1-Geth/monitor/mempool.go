@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+)
+
+const (
+	// 交易哈希的去重窗口，超过后清理；同一笔交易在窗口之后再次出现会被当作新交易
+	// ⚠️ 主网每秒上百笔 Pending 交易，窗口越长内存占用越大
+	MempoolDedupWindow = 10 * time.Minute
+	// 各来源统计的输出间隔
+	MempoolReportInterval = 10 * time.Minute
+)
+
+// MempoolConfig 同时订阅多个节点的交易池，合并去重成一条 Pending 交易流
+type MempoolConfig struct {
+	Sources     []EndpointConfig     `json:"sources"`     // 主节点（primary）之外的交易池来源
+	Dwell       bool                 `json:"dwell"`       // 统计所有交易在交易池中的停留时间（需要拉取每个完整区块）
+	Private     bool                 `json:"private"`     // 找出从未出现在交易池中的上链交易，按区块和 builder 统计私有交易占比
+	Impact      bool                 `json:"impact"`      // 在本地估算经过 Uniswap 路由的 Pending swap 的预期输出和价格冲击
+	Aggregators bool                 `json:"aggregators"` // 解码 1inch / 0x / ParaSwap 的 Pending 交易，输出大额兑换的路线
+	Permits     bool                 `json:"permits"`     // 统计交易池中的签名授权（ERC-2612 / Permit2），通过 /api/permits 查询
+	Fees        FeeFilterConfig      `json:"fees"`        // 按 gas 出价过滤输出的 Pending 交易
+	To          ToFilterConfig       `json:"to"`          // 按接收方过滤输出、价格冲击估算和聚合器解码的 Pending 交易
+	Selectors   SelectorFilterConfig `json:"selectors"`   // 按调用的函数过滤，作用范围与 to 相同
+	Match       []MatchRuleConfig    `json:"match"`       // 解码后按参数值匹配，只输出命中规则的交易
+	Filter      string               `json:"filter"`      // 引用 filters 中的过滤链，作用范围与 to 相同
+}
+
+// mempoolSeen 某个来源送达的时间
+type mempoolSeen struct {
+	source int
+	at     time.Time
+}
+
+// mempoolEntry 一笔交易在各来源的送达记录，seen[0] 为最先送达的来源
+type mempoolEntry struct {
+	seen []mempoolSeen
+	full bool // 已经以完整交易发布过（只收到 Hash 时为 false）
+}
+
+// MempoolSourceStats 一个来源的统计
+type MempoolSourceStats struct {
+	Name   string `json:"name"`
+	Seen   int    `json:"seen"`   // 送达的交易数
+	First  int    `json:"first"`  // 最先送达的交易数
+	Unique int    `json:"unique"` // 只有它送达的交易数（去重窗口结束时统计）
+}
+
+// MempoolTxInfo 一笔交易在各来源的首次送达时间
+type MempoolTxInfo struct {
+	Hash      common.Hash          `json:"hash"`
+	FirstSeen time.Time            `json:"first_seen"`
+	Sources   map[string]time.Time `json:"sources"`
+}
+
+// MempoolMerger 合并主节点和 sources 中各节点的 Pending 交易，按哈希去重：
+// 每笔交易只在最先送达时发布到事件总线（只收到 Hash、之后又收到完整交易时再发布一次完整交易），
+// 并记录每个来源的首次送达时间，得到比任何单个节点都完整的交易池视图
+type MempoolMerger struct {
+	sources []EndpointConfig // 下标 0 为主节点
+	bus     *EventBus
+	paused  atomic.Bool // Pending 订阅被关闭时为 true，丢弃所有来源送达的交易
+
+	mu      sync.Mutex
+	entries map[common.Hash]*mempoolEntry
+	stats   []MempoolSourceStats
+}
+
+// NewMempoolMerger 创建合并器，primary 为主节点地址
+func NewMempoolMerger(cfg MempoolConfig, primary string, bus *EventBus) *MempoolMerger {
+	sources := append([]EndpointConfig{{Name: "primary", WSURL: primary}}, cfg.Sources...)
+	m := &MempoolMerger{
+		sources: sources,
+		bus:     bus,
+		entries: make(map[common.Hash]*mempoolEntry),
+		stats:   make([]MempoolSourceStats, len(sources)),
+	}
+	for i, s := range sources {
+		m.stats[i].Name = s.Name
+	}
+	return m
+}
+
+// SetPaused 暂停 / 恢复合并，暂停期间 sources 中的节点仍保持连接，但送达的交易直接丢弃
+func (m *MempoolMerger) SetPaused(paused bool) {
+	m.paused.Store(paused)
+}
+
+// Observe 记录一个来源送达的交易（tx 为 nil 时只有 hash），需要时发布到事件总线
+func (m *MempoolMerger) Observe(source int, hash common.Hash, tx *types.Transaction, at time.Time) {
+	if m.paused.Load() {
+		return
+	}
+	m.mu.Lock()
+	e, ok := m.entries[hash]
+	if !ok {
+		e = &mempoolEntry{}
+		m.entries[hash] = e
+		m.stats[source].First++
+	}
+	for _, s := range e.seen {
+		if s.source == source {
+			m.mu.Unlock()
+			return // 同一个来源重复推送
+		}
+	}
+	e.seen = append(e.seen, mempoolSeen{source, at})
+	m.stats[source].Seen++
+	publish := !ok || (tx != nil && !e.full)
+	if tx != nil {
+		e.full = true
+	}
+	m.mu.Unlock()
+
+	if !publish {
+		return
+	}
+	if tx != nil {
+		m.bus.Publish(BusMessage{Kind: BusPendingTx, Received: at, Tx: tx, Source: m.sources[source].Name})
+	} else {
+		m.bus.Publish(BusMessage{Kind: BusPendingHash, Received: at, Hash: hash, Source: m.sources[source].Name})
+	}
+}
+
+// FirstSeen 交易最早被任一来源看到的时间
+func (m *MempoolMerger) FirstSeen(hash common.Hash) (time.Time, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[hash]
+	if !ok {
+		return time.Time{}, false
+	}
+	return e.seen[0].at, true
+}
+
+// Info 交易在各来源的送达时间
+func (m *MempoolMerger) Info(hash common.Hash) (*MempoolTxInfo, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[hash]
+	if !ok {
+		return nil, false
+	}
+	info := &MempoolTxInfo{Hash: hash, FirstSeen: e.seen[0].at, Sources: make(map[string]time.Time, len(e.seen))}
+	for _, s := range e.seen {
+		info.Sources[m.sources[s.source].Name] = s.at
+	}
+	return info, true
+}
+
+// Run 订阅 sources 中的节点（主节点由 Monitor 的订阅调用 Observe），定期清理和输出统计，直到 ctx 取消
+func (m *MempoolMerger) Run(ctx context.Context) {
+	for i := 1; i < len(m.sources); i++ {
+		go m.runSource(ctx, i)
+	}
+	prune := time.NewTicker(time.Minute)
+	defer prune.Stop()
+	report := time.NewTicker(MempoolReportInterval)
+	defer report.Stop()
+	for {
+		select {
+		case <-prune.C:
+			m.prune(time.Now())
+		case <-report.C:
+			if len(m.sources) > 1 {
+				PrintMempoolSources(m.Stats())
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runSource 订阅一个来源，断开后间隔 PropagationRedial 重连
+func (m *MempoolMerger) runSource(ctx context.Context, idx int) {
+	src := m.sources[idx]
+	for {
+		if err := m.subscribe(ctx, idx); err != nil && ctx.Err() == nil {
+			log.Printf("⚠️  [%s] 交易池订阅中断: %v（%s 后重连）", src.Name, err, PropagationRedial)
+		}
+		select {
+		case <-time.After(PropagationRedial):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// subscribe 优先订阅完整交易，节点不支持时退化为只订阅 Hash
+func (m *MempoolMerger) subscribe(ctx context.Context, idx int) error {
+	dialCtx, cancel := context.WithTimeout(ctx, CONNECTION_TIMEOUT)
+	client, err := dialRPC(dialCtx, m.sources[idx].WSURL)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("连接失败: %w", err)
+	}
+	defer client.Close()
+	geth := gethclient.New(client)
+
+	txs := make(chan *types.Transaction, 256)
+	hashes := make(chan common.Hash, 256)
+	sub, err := geth.SubscribeFullPendingTransactions(ctx, txs)
+	if err != nil {
+		if sub, err = geth.SubscribePendingTransactions(ctx, hashes); err != nil {
+			return fmt.Errorf("订阅 Pending 交易失败: %w", err)
+		}
+	}
+	defer sub.Unsubscribe()
+	for {
+		select {
+		case tx := <-txs:
+			m.Observe(idx, tx.Hash(), tx, time.Now())
+		case hash := <-hashes:
+			m.Observe(idx, hash, nil, time.Now())
+		case err := <-sub.Err():
+			return err
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// prune 清理超出去重窗口的交易，只有一个来源送达的记为该来源独有
+func (m *MempoolMerger) prune(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for hash, e := range m.entries {
+		if now.Sub(e.seen[0].at) < MempoolDedupWindow {
+			continue
+		}
+		if len(e.seen) == 1 {
+			m.stats[e.seen[0].source].Unique++
+		}
+		delete(m.entries, hash)
+	}
+}
+
+// Stats 各来源的统计，按最先送达的次数排序
+func (m *MempoolMerger) Stats() []MempoolSourceStats {
+	m.mu.Lock()
+	stats := append([]MempoolSourceStats(nil), m.stats...)
+	m.mu.Unlock()
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].First > stats[j].First })
+	return stats
+}
+
+// RegisterAPI 注册交易池查询接口
+//
+//	GET /api/mempool/sources     各来源的统计
+//	GET /api/mempool/tx/{hash}   交易在各来源的首次送达时间（去重窗口内）
+func (m *MempoolMerger) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/mempool/sources", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, m.Stats())
+	})
+	api.Handle("GET /api/mempool/tx/{hash}", func(w http.ResponseWriter, r *http.Request) {
+		raw := r.PathValue("hash")
+		if len(strings.TrimPrefix(raw, "0x")) != 64 {
+			writeError(w, http.StatusBadRequest, "交易哈希格式错误")
+			return
+		}
+		info, ok := m.Info(common.HexToHash(raw))
+		if !ok {
+			writeError(w, http.StatusNotFound, "去重窗口内没有见过该交易")
+			return
+		}
+		writeJSON(w, http.StatusOK, info)
+	})
+}
+
+// PrintMempoolSources 默认的来源统计输出
+func PrintMempoolSources(stats []MempoolSourceStats) {
+	var sb strings.Builder
+	summary := fmt.Sprintf("🌊 [Mempool] %d 个来源的 Pending 交易统计", len(stats))
+	sb.WriteString("\n" + summary + "\n")
+	fmt.Fprintf(&sb, "   %-16s %10s %10s %10s\n", "来源", "送达", "最先", "独有")
+	for _, s := range stats {
+		fmt.Fprintf(&sb, "   %-16s %10d %10d %10d\n", s.Name, s.Seen, s.First, s.Unique)
+	}
+	Emit(Event{Type: "mempool_sources", Summary: summary, Text: sb.String(), Data: stats})
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/metrics/prometheus"
+)
+
+// metricsRegistry 监控程序的所有指标都注册在这里，通过 /metrics 以 Prometheus 格式导出
+// 指标名中的 "/" 在导出时会被替换成 "_"，例如 monitor/block/gas_utilization -> monitor_block_gas_utilization
+var metricsRegistry = metrics.NewRegistry()
+
+// StartMetricsServer 在 addr 上启动 Prometheus 指标服务，addr 为空时不启动；tlsCfg 不为 nil 时使用 HTTPS
+func StartMetricsServer(addr string, tlsCfg *tls.Config) {
+	if addr == "" {
+		return
+	}
+	metrics.Enable()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", prometheus.Handler(metricsRegistry))
+	srv := &http.Server{Addr: addr, Handler: mux, TLSConfig: tlsCfg, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		log.Printf("📈 指标服务已启动: %s://%s/metrics", serverScheme(tlsCfg), addr)
+		var err error
+		if tlsCfg != nil {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("⚠️  指标服务异常退出: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,1018 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Monitor 实时监控：订阅新区块与 Pending 交易，并在主循环中分发处理
+type Monitor struct {
+	cfg      *Config
+	clients  *Clients
+	chainID  *big.Int
+	signer   types.Signer // 用于从 Pending 交易中恢复发送者
+	watch    *Watchlist
+	abis     *ABIRegistry
+	tokens   *TokenCache
+	prices   *PriceOracle
+	screener *TokenScreener // 未开启代币风险检查时为 nil
+	values   *Valuator
+	alert    *ValueAlert // 大额转账 / swap 提醒，阈值可以热加载
+	node     *NodeInfo
+	caps     *Capabilities // Run 开始时探测的节点能力
+	tracer   Tracer        // 节点不支持任何 trace 接口时为 nil
+
+	waiter      *ReceiptWaiter
+	inclusion   *InclusionTracker
+	fetcher     *BlockFetcher
+	health      *BlockHealth // 未开启 block_stats 时为 nil
+	headers     *HeaderChain
+	logs        *LogPipeline
+	bus         *EventBus
+	mempool     *MempoolMerger
+	dwell       *DwellTracker        // 未开启 mempool.dwell 时为 nil
+	private     *PrivateFlowDetector // 未开启 mempool.private 时为 nil
+	impact      *SwapImpactEstimator // 未开启 mempool.impact 时为 nil
+	aggregators *AggregatorDecoder   // 未开启 mempool.aggregators 时为 nil
+	fees        *FeeFilter           // 未配置 mempool.fees 时为 nil
+	dests       *ToFilter            // 未配置 mempool.to 时为 nil
+	selectors   *SelectorFilter      // 未配置 mempool.selectors 时为 nil
+	matcher     *CallMatcher         // 未配置 mempool.match 时为 nil
+	throttle    *Throttle            // 未配置 throttle.rules 时为 nil
+	whales      *WhaleAlert          // 未开启 whale.alert 时为 nil
+	filters     *FilterSet           // 命名的过滤链（filter_chain.go）
+	indexer     *Indexer             // 未配置索引合约时为 nil
+	portfolio   *PortfolioMonitor    // 未配置组合钱包时为 nil
+	approvals   *ApprovalMonitor     // 没有关注地址时为 nil
+	sanctions   *SanctionsScreener   // 没有关注地址或名单时为 nil
+	txpool      *TxpoolSeries        // 未开启交易池时间序列时为 nil
+	pool        *EndpointPool        // 未配置 rpc_pool 时为 nil
+
+	prefilter        *TxPrefilter // Pending 交易预筛，只在 "pending" 消费者中使用
+	prefilterVersion uint64       // 预筛条件对应的关注列表版本
+
+	configPath string       // 为空时不支持热加载
+	serverTLS  *tls.Config  // API 服务的 TLS，未配置时为 nil
+	notifier   *Notifier    // 不是由 systemd 启动时为 nil
+	lastHead   atomic.Int64 // 最近一次收到区块头的时间（UnixNano），启动时为启动时间
+	subscribed atomic.Bool  // 订阅全部建立后为 true，主循环退出时恢复为 false
+}
+
+func NewMonitor(cfg *Config, clients *Clients) *Monitor {
+	watch := NewWatchlist(cfg.WatchAddresses())
+	waiter := NewReceiptWaiter(clients.Eth)
+	abis := NewABIRegistry()
+	tokens := NewTokenCache(clients.Eth, cfg.TokenCache)
+	prices := NewPriceOracle(cfg.Prices, clients.Eth)
+	var screener *TokenScreener
+	if cfg.Honeypot.Enabled {
+		screener = NewTokenScreener(cfg.Honeypot, clients.RPC, clients.Eth, cfg.Prices, nil)
+	}
+	values := NewValuator(clients.Eth, tokens, prices, screener)
+	logs := NewLogPipeline(NewLogPrinter(abis, tokens, values))
+	// 阈值为 0 时也注册，热加载时可以再打开
+	valueAlert := NewValueAlert(values, cfg.Prices.AlertUSD)
+	logs.Register(valueAlert.Handle)
+
+	// 需要完整区块和回执的分析器都注册到 fetcher，每个区块只拉取一次
+	fetcher := NewBlockFetcher(clients.Eth)
+	var health *BlockHealth
+	if cfg.BlockStats {
+		health = NewBlockHealth()
+		fetcher.Register(health)
+		fetcher.Register(NewGasLeaderboard())
+		fetcher.Register(NewWhaleTracker(cfg.Whale, watch, nil))
+	}
+	if screener != nil {
+		fetcher.Register(screener)
+	}
+	if len(cfg.RugPull.Tokens) > 0 {
+		fetcher.Register(NewRugPullDetector(cfg.RugPull, clients.Eth, nil))
+	}
+
+	m := &Monitor{
+		cfg:       cfg,
+		clients:   clients,
+		watch:     watch,
+		abis:      abis,
+		tokens:    tokens,
+		prices:    prices,
+		screener:  screener,
+		values:    values,
+		alert:     valueAlert,
+		waiter:    waiter,
+		inclusion: NewInclusionTracker(clients.Eth, waiter),
+		fetcher:   fetcher,
+		health:    health,
+		headers:   NewHeaderChain(clients.Eth, nil),
+		logs:      logs,
+		bus:       NewEventBus(),
+		notifier:  NewNotifier(),
+	}
+	m.mempool = NewMempoolMerger(cfg.Mempool, cfg.WSURL, m.bus)
+	m.lastHead.Store(time.Now().UnixNano())
+	return m
+}
+
+// SetConfigPath 记录配置文件路径，Run 时据此开启热加载，需要在 Run 之前调用
+func (m *Monitor) SetConfigPath(path string) { m.configPath = path }
+
+// SetServerTLS API 服务使用的 TLS（与指标服务共用同一份证书），需要在 Run 之前调用
+func (m *Monitor) SetServerTLS(t *tls.Config) { m.serverTLS = t }
+
+// Waiter 与本监控的区块头订阅联动的回执等待器
+func (m *Monitor) Waiter() *ReceiptWaiter { return m.waiter }
+
+// ABIs 合约 ABI 注册表（索引合约的 ABI + 自动拉取的 ABI）
+func (m *Monitor) ABIs() *ABIRegistry { return m.abis }
+
+// Tokens ERC-20 元数据缓存
+func (m *Monitor) Tokens() *TokenCache { return m.tokens }
+
+// Values 转账 / swap 的美元估值
+func (m *Monitor) Values() *Valuator { return m.values }
+
+// Logs 实时日志与历史回扫共用的处理流水线
+func (m *Monitor) Logs() *LogPipeline { return m.logs }
+
+// Bus 订阅数据的事件总线，其它消费者可以在 Run 之前或之后订阅
+func (m *Monitor) Bus() *EventBus { return m.bus }
+
+// LastHead 最近一次收到新区块头的本地时间（还没有收到时为启动时间）
+func (m *Monitor) LastHead() time.Time { return time.Unix(0, m.lastHead.Load()) }
+
+// Tracer 按节点实现选择的统一 trace 接口，Run 之前或节点不支持时为 nil
+func (m *Monitor) Tracer() Tracer { return m.tracer }
+
+// Capabilities 启动时探测到的节点能力，Run 之前为 nil
+func (m *Monitor) Capabilities() *Capabilities { return m.caps }
+
+// Run 开启订阅并进入主循环，直到 ctx 被取消或订阅异常中断
+func (m *Monitor) Run(ctx context.Context) error {
+	chainID, err := chainIDRetry(ctx, m.clients.Eth)
+	if err != nil {
+		return fmt.Errorf("查询 chainID 失败: %w", err)
+	}
+	m.chainID = chainID
+	m.signer = types.LatestSignerForChainID(chainID)
+
+	// 识别节点实现，再实际探测一遍依赖的接口，不可用的功能在下面直接关闭
+	node, err := DetectNode(ctx, m.clients.RPC)
+	if err != nil {
+		log.Printf("⚠️  识别节点实现失败: %v（trace 能力按探测结果判断）", err)
+		node = &NodeInfo{Kind: NodeUnknown}
+	}
+	m.caps = ProbeCapabilities(ctx, m.clients.RPC)
+	m.caps.Apply(node)
+	m.node = node
+	m.tracer = NewTracer(node, m.clients.RPC)
+	fmt.Printf("🔎 节点: %s\n", node)
+	fmt.Println(m.caps)
+	m.fetcher.SetBlockReceipts(m.caps.BlockReceipts)
+
+	newHeadChan := make(chan *types.Header)        // 接收新区块头
+	pendingTxChan := make(chan *types.Transaction) // 接收完整的 Pending 交易
+	pendingHashChan := make(chan common.Hash)      // 节点不支持完整交易订阅时，退化为只接收 Hash
+
+	// 每个订阅都可以在配置中关闭，运行中通过 API / SIGUSR1 切换
+	subCfg := m.cfg.Subscriptions
+	subs := NewSubscriptionSet(ctx, func(e SubscriptionEvent) {
+		if e.Name == StreamPending {
+			m.mempool.SetPaused(!e.Enabled)
+		}
+		PrintSubscriptionEvent(e)
+	})
+	defer subs.Close()
+	m.mempool.SetPaused(!subCfg.Pending)
+
+	// A. 订阅新区块 (SubscribeNewHead)
+	err = subs.Add(StreamHeads, "区块", subCfg.Heads, func(ctx context.Context) (ethereum.Subscription, error) {
+		sub, err := m.clients.Eth.SubscribeNewHead(ctx, newHeadChan)
+		if err == nil {
+			fmt.Println("🎧 开始监听新区块 (NewHeads)...")
+		}
+		return sub, err
+	})
+	if err != nil {
+		return fmt.Errorf("订阅新区块失败: %w", err)
+	}
+	if !subCfg.Heads {
+		log.Println("⚠️  新区块订阅已在配置中关闭，区块分析和 Watchdog 都不会收到新区块")
+	}
+
+	// B. 订阅待处理交易
+	// 注意：这需要节点支持，Infura 免费版可能有限制
+	var pendingStart func(ctx context.Context) (ethereum.Subscription, error)
+	if m.caps.FullPendingSub || m.caps.PendingHashSub {
+		pendingStart = func(ctx context.Context) (ethereum.Subscription, error) {
+			return m.subscribePending(ctx, pendingTxChan, pendingHashChan)
+		}
+	}
+	switch err := subs.Add(StreamPending, "交易", subCfg.Pending, pendingStart); {
+	case err != nil:
+		log.Printf("⚠️  警告: 订阅 Pending 交易失败: %v（继续运行，只监听区块）", err)
+	case pendingStart == nil:
+		log.Println("⚠️  节点不支持 Pending 交易订阅，只监听区块")
+	case !subCfg.Pending:
+		log.Println("⚠️  Pending 交易订阅已在配置中关闭，可以通过 API 或 SIGUSR1 开启")
+	}
+
+	if err := loadSignatures(m.abis, m.cfg.ABI); err != nil {
+		return fmt.Errorf("加载签名失败: %w", err)
+	}
+	if len(m.cfg.Indexer.Contracts) > 0 {
+		indexer, err := NewIndexer(m.cfg.Indexer, m.clients.Eth)
+		if err != nil {
+			return fmt.Errorf("启动事件索引失败: %w", err)
+		}
+		m.indexer = indexer
+		indexer.LoadABIs(m.abis)
+		go indexer.Run(ctx)
+		fmt.Printf("🗂️  事件索引已启动: %d 个合约 -> %s\n", len(m.cfg.Indexer.Contracts), m.cfg.Indexer.DB)
+	}
+
+	// C. 订阅配置中合约的日志
+	logChan := make(chan types.Log)
+	if err := subs.Add(StreamLogs, "日志", subCfg.Logs, m.logStart(m.cfg.Logs, logChan)); err != nil {
+		return fmt.Errorf("订阅日志失败: %w", err)
+	}
+
+	// D. 定期查询 finalized 区块（没有对应的订阅接口，用轮询代替）
+	subs.Add(StreamFinalized, "最终性", subCfg.Finalized, func(ctx context.Context) (ethereum.Subscription, error) {
+		fmt.Printf("🎧 开始跟踪 finalized 区块（每 %d 秒）...\n", subCfg.FinalizedInterval)
+		return pollFinalized(m.clients.Eth, time.Duration(subCfg.FinalizedInterval)*time.Second, PrintFinalized), nil
+	})
+
+	var abiFetcher *ABIFetcher
+	if m.cfg.ABI.FetchThreshold > 0 {
+		abiFetcher = NewABIFetcher(m.cfg.ABI, m.clients.Eth, m.abis, chainID)
+		m.fetcher.Register(abiFetcher)
+		go abiFetcher.Run(ctx)
+	}
+	proxies := make([]common.Address, 0, len(m.cfg.ABI.WatchProxies))
+	for _, p := range m.cfg.ABI.WatchProxies {
+		proxies = append(proxies, common.HexToAddress(p))
+	}
+	if len(proxies) > 0 {
+		m.fetcher.Register(NewProxyWatcher(m.clients.Eth, m.abis, abiFetcher, proxies, nil))
+	}
+
+	api := NewAPIServer(m.cfg.APIAddr)
+	auth, err := NewAPIAuth(m.cfg.APIAuth)
+	if err != nil {
+		return err
+	}
+	api.SetAuth(auth)
+	api.SetTLS(m.serverTLS)
+	m.registerHealth(api)
+	m.caps.RegisterAPI(api)
+	subs.RegisterAPI(api)
+	go subs.RunSignals(ctx, subCfg.SignalToggle)
+	m.registerReload(ctx, api, subs, logChan)
+	SetPausePolicy(m.cfg.Pause)
+	pause := NewOutputPause(nil)
+	pause.RegisterAPI(api)
+	RegisterRoutingAPI(api)
+	NewAdminAPI(m.watch, m.alert, m.fetcher, m.bus, pause, m.cfg.Outputs, nil).RegisterAPI(api)
+	if m.cfg.APIAddr != "" {
+		dashboard := NewDashboard()
+		m.fetcher.Register(dashboard)
+		m.bus.Subscribe("dashboard", BusPendingBuffer, dashboard.Observe, BusPendingTx, BusPendingHash)
+		dashboard.RegisterAPI(api)
+		SetDashboard(dashboard)
+		defer SetDashboard(nil)
+		go dashboard.Run(ctx)
+	}
+	go pause.RunSignals(ctx)
+	if len(m.cfg.Throttle.Rules) > 0 {
+		store, err := OpenThrottleStore(m.cfg.Throttle.DB)
+		if err != nil {
+			return fmt.Errorf("打开限流状态数据库失败: %w", err)
+		}
+		defer store.Close()
+		m.throttle, err = NewThrottle(m.cfg.Throttle, store, nil)
+		if err != nil {
+			return err
+		}
+		SetThrottle(m.throttle)
+		defer SetThrottle(nil) // 先于关闭数据库执行
+		m.throttle.RegisterAPI(api)
+		go m.throttle.Run(ctx)
+		fmt.Printf("🔕 提醒限流已开启: %d 条规则 -> %s\n", len(m.cfg.Throttle.Rules), m.cfg.Throttle.DB)
+	}
+	rpcUsage.RegisterAPI(api)
+	if m.cfg.Budget.DailyRequests > 0 || m.cfg.Budget.DailyComputeUnits > 0 {
+		budget := NewBudget(m.cfg.Budget, nil)
+		SetBudget(budget)
+		budget.RegisterAPI(api)
+		fmt.Printf("💸 RPC 预算已开启: 每天 %d 次请求 / %d CU（0 表示不限），用到 %.0f%% 开始降级\n",
+			m.cfg.Budget.DailyRequests, m.cfg.Budget.DailyComputeUnits, m.cfg.Budget.DegradeAt*100)
+	}
+	if m.cfg.RPCUsageReportMinutes > 0 {
+		go rpcUsage.Run(ctx, time.Duration(m.cfg.RPCUsageReportMinutes)*time.Minute)
+	}
+	if len(m.cfg.RPCPool.Endpoints) > 0 {
+		pool, err := NewEndpointPool(ctx, m.cfg.RPCPool, m.clients, nil)
+		if err != nil {
+			return fmt.Errorf("连接备用节点失败: %w", err)
+		}
+		defer pool.Close()
+		m.pool = pool
+		m.fetcher.SetEndpoints(pool)
+		m.tokens.SetEndpoints(pool)
+		pool.RegisterAPI(api)
+		go pool.Run(ctx)
+		if m.cfg.RPCPool.Strategy == BalanceWeighted {
+			fmt.Printf("🔌 备用节点已启用: %d 个，区块 / 回执 / 代码查询按权重分流\n", len(m.cfg.RPCPool.Endpoints))
+		} else {
+			fmt.Printf("🔌 备用节点已启用: %d 个，区块拉取在主节点熔断时改发备用节点\n", len(m.cfg.RPCPool.Endpoints))
+		}
+	}
+	m.headers.RegisterAPI(api)
+	if len(m.cfg.Bytecode.Contracts)+len(proxies) > 0 {
+		bytecode := NewBytecodeWatcher(m.cfg.Bytecode, m.clients.Eth, proxies, nil)
+		bytecode.SetEndpoints(m.pool)
+		m.fetcher.Register(bytecode)
+		bytecode.RegisterAPI(api)
+		fmt.Printf("🧬 字节码变化检测已启动: %d 个合约，每 %d 个区块检查一次\n", len(bytecode.contracts), bytecode.interval)
+	}
+	if len(m.cfg.Watch) > 0 {
+		m.approvals = NewApprovalMonitor(m.clients.Eth, m.watch, m.tokens, m.abis, abiFetcher, nil)
+		m.fetcher.Register(m.approvals)
+		m.approvals.RegisterAPI(api)
+	}
+	if len(m.cfg.Watch) > 0 && len(m.cfg.Sanctions.Lists) > 0 {
+		list, err := LoadDenylist(m.cfg.Sanctions.Lists)
+		if err != nil {
+			return fmt.Errorf("加载制裁名单失败: %w", err)
+		}
+		go list.Run(ctx)
+		m.sanctions = NewSanctionsScreener(list, m.watch, nil)
+		m.fetcher.Register(m.sanctions)
+		fmt.Printf("🚫 制裁名单筛查已启动: %d 个地址\n", list.Len())
+	}
+	if len(m.cfg.PnL.Addresses) > 0 {
+		store, err := OpenPnLStore(m.cfg.PnL.DB)
+		if err != nil {
+			return fmt.Errorf("打开 P&L 数据库失败: %w", err)
+		}
+		defer store.Close()
+		addrs := make([]common.Address, 0, len(m.cfg.PnL.Addresses))
+		for _, a := range m.cfg.PnL.Addresses {
+			addrs = append(addrs, common.HexToAddress(a))
+		}
+		pnl, err := NewPnLTracker(m.clients.Eth, store, m.tokens, m.prices, common.HexToAddress(m.cfg.Prices.WETH), addrs)
+		if err != nil {
+			return fmt.Errorf("启动 P&L 跟踪失败: %w", err)
+		}
+		m.fetcher.Register(pnl)
+		pnl.RegisterAPI(api)
+		fmt.Printf("💹 P&L 跟踪已启动: %d 个地址 -> %s\n", len(addrs), m.cfg.PnL.DB)
+	}
+	if len(m.cfg.Portfolio.Wallets) > 0 {
+		m.portfolio = NewPortfolioMonitor(m.cfg.Portfolio, m.clients.Eth, m.tokens, m.prices, nil, nil)
+		m.portfolio.RegisterAPI(api)
+		go m.portfolio.Run(ctx)
+		fmt.Printf("💼 组合监控已启动: %d 个钱包，每 %d 个区块统计一次\n", len(m.cfg.Portfolio.Wallets), m.cfg.Portfolio.Every)
+	}
+	if len(m.cfg.Propagation.Endpoints) > 0 {
+		race := NewPropagationRace(m.cfg.Propagation, m.cfg.WSURL)
+		race.RegisterAPI(api)
+		go race.Run(ctx)
+		fmt.Printf("🏁 传播延迟比较已启动: %d 个节点\n", len(m.cfg.Propagation.Endpoints)+1)
+	}
+	if m.indexer != nil {
+		m.indexer.RegisterAPI(api)
+	}
+	if m.cfg.History.Enabled {
+		store, err := OpenHistoryStore(m.cfg.History.DB)
+		if err != nil {
+			return fmt.Errorf("打开历史数据库失败: %w", err)
+		}
+		defer store.Close()
+		history := NewHistoryRecorder(m.cfg.History, store, m.watch, m.abis)
+		m.fetcher.Register(history)
+		history.RegisterAPI(api)
+		SetHistory(history)
+		defer SetHistory(nil) // 先于关闭数据库执行
+		go history.Run(ctx)
+		fmt.Printf("🗄️  历史记录已开启: 保留 %d 天 -> %s\n", m.cfg.History.Retention, m.cfg.History.DB)
+	}
+	if m.cfg.Search.Enabled {
+		store, err := OpenSearchStore(m.cfg.Search.DB)
+		if err != nil {
+			return fmt.Errorf("打开搜索索引数据库失败: %w", err)
+		}
+		defer store.Close()
+		search := NewSearchIndexer(m.cfg.Search, store, m.abis)
+		m.fetcher.Register(search)
+		search.RegisterAPI(api)
+		go search.Run(ctx)
+		fmt.Printf("🔎 调用 / 事件搜索索引已开启: %d 个合约（0 表示全部） -> %s\n", len(m.cfg.Search.Contracts), m.cfg.Search.DB)
+	}
+	if m.cfg.Txpool.Enabled && !m.caps.TxPool {
+		log.Println("⚠️  节点不支持 txpool_status，交易池时间序列已停用")
+	} else if m.cfg.Txpool.Enabled {
+		store, err := OpenTxpoolStore(m.cfg.Txpool.DB)
+		if err != nil {
+			return fmt.Errorf("打开交易池样本数据库失败: %w", err)
+		}
+		defer store.Close()
+		m.txpool = NewTxpoolSeries(m.cfg.Txpool, m.clients.RPC, store)
+		m.fetcher.Register(m.txpool)
+		m.txpool.RegisterAPI(api)
+		go m.txpool.Run(ctx)
+		fmt.Printf("🚦 交易池时间序列已启动: 每 %d 秒采样一次 -> %s\n", m.cfg.Txpool.Interval, m.cfg.Txpool.DB)
+	}
+	if m.cfg.NodeHealth.Enabled {
+		health := NewNodeHealthMonitor(m.cfg.NodeHealth, m.clients.RPC, nil)
+		health.txpool = m.caps.TxPool
+		health.RegisterAPI(api)
+		go health.Run(ctx)
+		fmt.Printf("🩺 节点健康检查已启动: 每 %d 秒采样一次\n", m.cfg.NodeHealth.Interval)
+	}
+	if m.screener != nil {
+		m.screener.RegisterAPI(api)
+		go m.screener.Run(ctx)
+		fmt.Println("🧪 代币风险检查已启动")
+	}
+	m.initPendingFilters()
+	for _, f := range m.filters.Analyzers() {
+		m.fetcher.Register(f)
+	}
+	m.filters.RegisterAPI(api)
+	SetFilters(m.filters)
+	defer SetFilters(nil)
+	if len(m.cfg.Filters) > 0 {
+		fmt.Printf("🧩 过滤链已加载: %d 条\n", len(m.cfg.Filters))
+	}
+	m.mempool.RegisterAPI(api)
+	if m.fees != nil {
+		if m.cfg.Mempool.Fees.TipPercentile > 0 {
+			m.fetcher.Register(m.fees)
+		}
+		m.fees.RegisterAPI(api)
+		fmt.Println("⛽ 交易池出价过滤已启动")
+	}
+	if m.dests != nil {
+		fmt.Printf("🎯 交易池接收方过滤已启动: 保留 %d 个地址，排除 %d 个地址\n", len(m.cfg.Mempool.To.Include), len(m.cfg.Mempool.To.Exclude))
+	}
+	if m.selectors != nil {
+		fmt.Printf("🧾 交易池函数过滤已启动: 保留 %d 个函数，排除 %d 个函数\n", len(m.cfg.Mempool.Selectors.Include), len(m.cfg.Mempool.Selectors.Exclude))
+	}
+	if m.matcher != nil {
+		fmt.Printf("🎯 交易池字段匹配已启动: %d 条规则\n", len(m.cfg.Mempool.Match))
+	}
+	if m.cfg.Mempool.Dwell {
+		m.dwell = NewDwellTracker()
+		m.fetcher.Register(m.dwell)
+		m.dwell.RegisterAPI(api)
+		m.bus.Subscribe("dwell", BusPendingBuffer, m.dwell.Observe, BusPendingTx, BusPendingHash)
+		fmt.Println("⏳ 交易池停留时间统计已启动")
+	}
+	if m.cfg.Mempool.Private {
+		m.private = NewPrivateFlowDetector(nil, nil)
+		m.fetcher.Register(m.private)
+		m.private.RegisterAPI(api)
+		m.bus.Subscribe("private", BusPendingBuffer, m.private.Observe, BusPendingTx, BusPendingHash)
+		fmt.Println("🕶️  私有交易检测已启动")
+	}
+	var pools *PoolStateCache
+	if len(m.cfg.Pools.Addresses) > 0 {
+		addrs := make([]common.Address, 0, len(m.cfg.Pools.Addresses))
+		for _, a := range m.cfg.Pools.Addresses {
+			addrs = append(addrs, common.HexToAddress(a))
+		}
+		pools = NewPoolStateCache(m.clients.Eth, m.values, addrs)
+		pools.RegisterAPI(api)
+		go pools.Run(ctx)
+		fmt.Printf("💧 池子状态缓存已启动: %d 个池子\n", len(addrs))
+	}
+	if m.cfg.Mempool.Impact {
+		m.impact = NewSwapImpactEstimator(m.clients.Eth, m.signer, m.abis, m.tokens, m.values, nil)
+		m.impact.SetPoolCache(pools)
+		m.bus.Subscribe("impact", BusPendingBuffer, m.filtered(m.impact.Observe), BusPendingTx)
+		fmt.Println("📉 Pending swap 价格冲击估算已启动")
+	}
+	if m.cfg.Mempool.Aggregators {
+		m.aggregators = NewAggregatorDecoder(m.signer, m.abis, m.tokens, m.values, common.HexToAddress(m.cfg.Prices.WETH), nil)
+		m.bus.Subscribe("aggregators", BusPendingBuffer, m.filtered(m.aggregators.Observe), BusPendingTx)
+		fmt.Println("🔀 聚合器路由解码已启动")
+	}
+	if m.cfg.Mempool.Permits {
+		permits := NewPermitStats(m.signer, m.abis)
+		m.bus.Subscribe("permits", BusPendingBuffer, permits.Observe, BusPendingTx)
+		permits.RegisterAPI(api)
+		fmt.Println("✍️  签名授权统计已启动")
+	}
+	if m.cfg.UserOps.Enabled {
+		userops := NewUserOpMonitor(m.cfg.UserOps, m.signer, m.abis, m.watch, nil)
+		m.bus.Subscribe("userops", BusPendingBuffer, userops.Observe, BusPendingTx)
+		userops.RegisterAPI(api)
+		go userops.RunBundler(ctx)
+		stats := NewUserOpStats(m.values, nil)
+		m.fetcher.Register(stats)
+		stats.RegisterAPI(api)
+		fmt.Println("🧩 ERC-4337 UserOperation 监控已启动")
+	}
+	if m.cfg.EIP7702.Enabled {
+		delegations := NewDelegationTracker(m.clients.Eth, m.signer, m.chainID, m.watch, nil)
+		delegations.SetEndpoints(m.pool)
+		go delegations.Seed(ctx)
+		m.fetcher.Register(delegations)
+		m.bus.Subscribe("eip7702", BusPendingBuffer, delegations.Observe, BusPendingTx)
+		delegations.RegisterAPI(api)
+		fmt.Println("🪪 EIP-7702 委托追踪已启动")
+	}
+	if len(m.cfg.Governance.Governors) > 0 {
+		governance := NewGovernanceMonitor(m.cfg.Governance, m.clients.Eth, m.abis, m.watch, nil)
+		governance.Probe(ctx)
+		m.fetcher.Register(governance)
+		governance.RegisterAPI(api)
+		fmt.Printf("🏛️  治理提案监控已启动: %d 个治理合约\n", len(m.cfg.Governance.Governors))
+	}
+	if len(m.cfg.Safe.Safes) > 0 {
+		safes := NewSafeMonitor(m.cfg.Safe, m.clients.Eth, m.signer, m.chainID, m.abis, nil, nil)
+		safes.Sync(ctx, nil)
+		m.fetcher.Register(safes)
+		m.bus.Subscribe("safe", BusPendingBuffer, safes.Observe, BusPendingTx)
+		safes.RegisterAPI(api)
+		fmt.Printf("🔐 Safe 多签监控已启动: %d 个 Safe\n", len(m.cfg.Safe.Safes))
+	}
+	if len(m.cfg.Timelock.Timelocks) > 0 {
+		timelock := NewTimelockMonitor(m.cfg.Timelock, m.clients.Eth, m.abis, m.watch, nil)
+		if err := timelock.Backfill(ctx); err != nil {
+			log.Printf("⚠️  重建时间锁队列失败: %v", err)
+		}
+		m.fetcher.Register(timelock)
+		timelock.RegisterAPI(api)
+		fmt.Printf("⏳ 时间锁队列监控已启动: %d 个时间锁\n", len(m.cfg.Timelock.Timelocks))
+	}
+	if m.cfg.Bridges.Enabled {
+		bridges := NewBridgeMonitor(m.chainID, m.watch, m.tokens, m.values, nil)
+		m.fetcher.Register(bridges)
+		bridges.RegisterAPI(api)
+		fmt.Println("🌉 跨链桥监控已启动")
+	}
+
+	if len(m.cfg.Reports.Windows) > 0 {
+		var onReport func(SummaryReport)
+		if m.cfg.Reports.DigestDir != "" {
+			digest := NewDigestWriter(m.cfg.Reports, m.tokens)
+			onReport = func(r SummaryReport) {
+				PrintSummaryReport(r)
+				digest.Handle(r)
+			}
+		}
+		reports := NewSummaryReporter(m.cfg.Reports, m.watch, onReport)
+		m.fetcher.Register(reports)
+		m.bus.Subscribe("reports", BusPendingBuffer, reports.Observe, BusPendingTx, BusPendingHash)
+		reports.RegisterAPI(api)
+		go reports.Run(ctx)
+		fmt.Printf("📊 定时汇总报告已启动: %d 个窗口\n", len(m.cfg.Reports.Windows))
+	}
+
+	if m.cfg.Whale.Alert.Enabled() {
+		m.whales = NewWhaleAlert(m.cfg.Whale.Alert, m.values, m.abis, m.watch, m.signer, nil)
+		m.fetcher.Register(m.whales)
+		if m.cfg.Whale.Alert.Pending {
+			m.bus.Subscribe("whale", BusPendingBuffer, m.whales.Observe, BusPendingTx)
+		}
+		fmt.Printf("🐋 大额交易提醒已启动: %.2f ETH / %s\n", m.cfg.Whale.Alert.MinETH, formatUSD(m.cfg.Whale.Alert.MinUSD))
+	}
+
+	if len(m.cfg.Profiles) > 0 {
+		profiles := make([]*Profile, 0, len(m.cfg.Profiles))
+		for _, cfg := range m.cfg.Profiles {
+			p := NewProfile(cfg, m.abis, m.signer, nil)
+			m.fetcher.Register(p)
+			// 每个分组独立订阅，慢的分组只会在自己的队列里积压
+			m.bus.Subscribe("profile:"+cfg.Name, BusPendingBuffer, p.Observe, BusPendingTx)
+			profiles = append(profiles, p)
+		}
+		RegisterProfilesAPI(api, profiles)
+		fmt.Printf("🗂️  关注分组已启动: %d 个\n", len(profiles))
+	}
+
+	if len(m.cfg.CrossChain.L2s) > 0 {
+		crosschain := NewCrossChainTracker(m.cfg.CrossChain, m.watch, nil)
+		if err := crosschain.Connect(ctx); err != nil {
+			log.Printf("⚠️  部分 L2 连接失败: %v", err)
+		}
+		m.fetcher.Register(crosschain)
+		go crosschain.Run(ctx)
+		crosschain.RegisterAPI(api)
+		fmt.Printf("🔗 跨链消息跟踪已启动: %d 条 L2\n", crosschain.Chains())
+	}
+	var curve *CurveMonitor
+	if len(m.cfg.Curve.Pools) > 0 {
+		curve = NewCurveMonitor(m.cfg.Curve, m.clients.Eth, m.tokens, m.values, common.HexToAddress(m.cfg.Prices.WETH), nil, nil)
+		m.fetcher.Register(curve)
+		curve.RegisterAPI(api)
+		fmt.Printf("🌀 Curve 池子监控已启动: %d 个池子\n", len(m.cfg.Curve.Pools))
+	}
+	var balancer *BalancerMonitor
+	if len(m.cfg.Balancer.Pools) > 0 {
+		balancer = NewBalancerMonitor(m.cfg.Balancer, m.clients.Eth, m.tokens, m.values, nil)
+		m.fetcher.Register(balancer)
+		balancer.RegisterAPI(api)
+		fmt.Printf("🔷 Balancer 池子监控已启动: %d 个池子\n", len(m.cfg.Balancer.Pools))
+	}
+	if m.cfg.Arb.Enabled {
+		arb := NewArbScanner(m.cfg.Arb, m.tokens, nil)
+		if pools != nil {
+			arb.AddVenues(pools.Venues)
+		}
+		if curve != nil {
+			arb.AddVenues(curve.Venues)
+		}
+		if balancer != nil {
+			arb.AddVenues(balancer.Venues)
+		}
+		m.fetcher.Register(arb)
+		arb.RegisterAPI(api)
+		fmt.Println("⚖️  跨场所价差扫描已启动")
+	}
+	if m.cfg.MEV.CoinbasePayments || m.cfg.MEV.Bundles {
+		payments := NewCoinbasePaymentAnalyzer(nil)
+		if m.tracer == nil {
+			log.Println("⚠️  节点不支持 trace，coinbase 转账只统计顶层转账")
+		}
+		if m.cfg.MEV.CoinbasePayments {
+			m.fetcher.Register(payments)
+			payments.RegisterAPI(api)
+			fmt.Println("💰 coinbase 转账统计已启动")
+		}
+		if m.cfg.MEV.Bundles {
+			bundles := NewBundleDetector(payments, nil)
+			m.fetcher.Register(bundles)
+			bundles.RegisterAPI(api)
+			fmt.Println("📦 bundle 识别已启动")
+		}
+	}
+	if m.cfg.MEV.Sandwich && len(m.cfg.Watch) > 0 {
+		m.fetcher.Register(NewSandwichDetector(m.watch, m.values, nil))
+		fmt.Println("🥪 三明治攻击提醒已启动")
+	}
+	if len(m.cfg.MEV.FrontrunContracts) > 0 {
+		contracts := make([]common.Address, 0, len(m.cfg.MEV.FrontrunContracts))
+		for _, c := range m.cfg.MEV.FrontrunContracts {
+			contracts = append(contracts, common.HexToAddress(c))
+		}
+		frontrun := NewFrontrunDetector(m.signer, contracts, nil)
+		m.fetcher.Register(frontrun)
+		m.bus.Subscribe("frontrun", BusPendingBuffer, frontrun.Observe, BusPendingTx)
+		fmt.Printf("🏃 抢跑检测已启动: %d 个合约\n", len(contracts))
+	}
+	if m.cfg.CopyTrade.Leader != "" {
+		var sender *Sender
+		if m.cfg.CopyTrade.Armed {
+			signer, err := NewSigner(m.cfg.Signer)
+			if err != nil {
+				return fmt.Errorf("创建跟单签名器失败: %w", err)
+			}
+			defer signer.Close()
+			if sender, err = NewSender(ctx, m.clients.Eth, signer); err != nil {
+				return err
+			}
+		}
+		copier := NewCopyTrader(m.cfg.CopyTrade, common.HexToAddress(m.cfg.Prices.WETH), m.clients.RPC, m.clients.Eth, sender, nil)
+		m.fetcher.Register(copier)
+		copier.RegisterAPI(api)
+		if sender != nil {
+			log.Printf("🚨 跟单已 ARMED：将用 %s 真实发送照抄 %s 的交易", sender.From().Hex(), m.cfg.CopyTrade.Leader)
+		} else {
+			fmt.Printf("🪞 跟单 dry-run 已启动: 领投 %s\n", m.cfg.CopyTrade.Leader)
+		}
+	}
+	if m.cfg.Bots.Enabled {
+		store, err := OpenBotStore(m.cfg.Bots.DB)
+		if err != nil {
+			return fmt.Errorf("打开发送者聚类数据库失败: %w", err)
+		}
+		defer store.Close()
+		bots, err := NewBotClassifier(m.cfg.Bots, m.signer, store, nil, nil)
+		if err != nil {
+			return fmt.Errorf("启动机器人识别失败: %w", err)
+		}
+		bots.RegisterAPI(api)
+		m.bus.Subscribe("bots", BusPendingBuffer, bots.Observe, BusPendingTx)
+		fmt.Printf("🤖 机器人识别已启动 -> %s\n", m.cfg.Bots.DB)
+	}
+	if len(m.cfg.Mempool.Sources) > 0 {
+		fmt.Printf("🌊 交易池合并已启动: %d 个来源\n", len(m.cfg.Mempool.Sources)+1)
+	}
+	api.Start(ctx)
+
+	if m.cfg.Checkpoint.Enabled {
+		store, err := OpenCheckpointStore(m.cfg.Checkpoint.DB)
+		if err != nil {
+			return fmt.Errorf("打开断点数据库失败: %w", err)
+		}
+		defer store.Close()
+		m.fetcher.SetCheckpoint(store, m.cfg.Checkpoint.MaxBackfill)
+		fmt.Printf("📌 断点续传已开启 -> %s\n", m.cfg.Checkpoint.DB)
+	}
+
+	m.fetcher.SetWarmup(m.cfg.WarmupBlocks)
+	level, overrides, err := m.cfg.EnrichmentLevels()
+	if err != nil {
+		return err
+	}
+	m.fetcher.SetEnrichment(level, overrides, m.tracer)
+	go m.waiter.Run(ctx)
+	go m.fetcher.Run(ctx)
+	go m.headers.Run(ctx)
+
+	// 主循环只负责发布，输出和各类分析在各自的消费者中处理
+	m.prefilter = NewTxPrefilter("pending", m.signer, m.pendingRule())
+	for _, c := range m.coreConsumers() {
+		m.bus.Subscribe(c.name, c.buffer, c.handler, c.kinds...)
+	}
+	go m.bus.Run(ctx)
+	go m.mempool.Run(ctx)
+
+	fmt.Println("\n📡 监控已启动，按 Ctrl+C 退出...")
+	m.subscribed.Store(true)
+	defer m.subscribed.Store(false)
+	m.notifier.Notify("READY=1\nSTATUS=订阅已建立，正在监控")
+	go m.notifier.RunWatchdog(ctx, m.LastHead)
+	for {
+		select {
+		case header := <-newHeadChan:
+			m.bus.Publish(BusMessage{Kind: BusHead, Header: header})
+		case tx := <-pendingTxChan:
+			m.mempool.Observe(0, tx.Hash(), tx, time.Now())
+		case txHash := <-pendingHashChan:
+			m.mempool.Observe(0, txHash, nil, time.Now())
+
+		case l := <-logChan:
+			m.bus.Publish(BusMessage{Kind: BusLog, Log: &l})
+
+		case err := <-subs.Err():
+			return err
+
+		case <-ctx.Done():
+			fmt.Println("\n🛑 停止监控，正在断开连接...")
+			m.notifier.Notify("STOPPING=1")
+			m.inclusion.PrintReport()
+			return nil
+		}
+	}
+}
+
+// logStart 按 cfg 订阅合约日志，没有配置合约地址时返回 nil（日志订阅不可用）；热加载时用新的 cfg 替换
+func (m *Monitor) logStart(cfg LogsConfig, logs chan types.Log) func(ctx context.Context) (ethereum.Subscription, error) {
+	if len(cfg.Addresses) == 0 {
+		return nil
+	}
+	return func(ctx context.Context) (ethereum.Subscription, error) {
+		sub, err := m.clients.Eth.SubscribeFilterLogs(ctx, cfg.FilterQuery(), logs)
+		if err == nil {
+			fmt.Printf("🎧 开始监听 %d 个合约的日志...\n", len(cfg.Addresses))
+		}
+		return sub, err
+	}
+}
+
+// subscribePending 优先订阅完整交易（可以直接拿到发送者、gas 出价），失败再退化为只订阅 Hash
+// 能力探测已经确认过订阅方式，这里仍保留失败时的退化
+func (m *Monitor) subscribePending(ctx context.Context, txs chan *types.Transaction, hashes chan common.Hash) (ethereum.Subscription, error) {
+	var sub *rpc.ClientSubscription
+	err := errors.New("节点不支持 Pending 交易订阅")
+	if m.caps.FullPendingSub {
+		if sub, err = m.clients.Geth.SubscribeFullPendingTransactions(ctx, txs); err == nil {
+			fmt.Println("🎧 开始监听交易池 (Full Pending Transactions)...")
+			return sub, nil
+		}
+	}
+	if m.caps.PendingHashSub {
+		if sub, err = m.clients.Geth.SubscribePendingTransactions(ctx, hashes); err == nil {
+			fmt.Println("🎧 开始监听交易池 (Pending Transaction Hashes)...")
+			log.Println("⚠️  节点不支持完整交易订阅，关注地址的 Pending 分析将不可用")
+			return sub, nil
+		}
+	}
+	return nil, err
+}
+
+// initPendingFilters 按配置创建过滤链和交易池过滤条件，Run 和 bench 共用
+func (m *Monitor) initPendingFilters() {
+	// 未配置 filters 时也创建：路由规则的 when 表达式需要用它恢复发送者和解码 calldata
+	m.filters = NewFilterSet(m.cfg.Filters, m.signer, m.abis, m.watch)
+	if m.cfg.Mempool.Fees.Enabled() {
+		m.fees = NewFeeFilter(m.cfg.Mempool.Fees)
+	}
+	if m.cfg.Mempool.To.Enabled() {
+		m.dests = NewToFilter(m.cfg.Mempool.To, m.watch)
+	}
+	if m.cfg.Mempool.Selectors.Enabled() {
+		m.selectors = NewSelectorFilter(m.cfg.Mempool.Selectors)
+	}
+	if len(m.cfg.Mempool.Match) > 0 {
+		m.matcher, _ = NewCallMatcher(m.cfg.Mempool.Match) // 已在 LoadConfig 中校验
+	}
+}
+
+// busConsumer 事件总线上的一个消费者
+type busConsumer struct {
+	name    string
+	buffer  int
+	handler func(BusMessage)
+	kinds   []BusKind
+}
+
+// coreConsumers 输出和核心处理的消费者，Run 注册到事件总线；bench 重放会话时使用同一组消费者
+func (m *Monitor) coreConsumers() []busConsumer {
+	return []busConsumer{
+		{name: "printer", buffer: BusPendingBuffer, handler: m.printMessage, kinds: []BusKind{BusHead, BusPendingTx, BusPendingHash}},
+		{name: "heads", buffer: BusHeadBuffer, handler: func(msg BusMessage) {
+			m.handleHead(msg.Header, msg.Received)
+			observeSince(headProcessing, msg.Received)
+		}, kinds: []BusKind{BusHead}},
+		{name: "pending", buffer: BusPendingBuffer, handler: func(msg BusMessage) {
+			m.handlePending(msg)
+			observeSince(pendingProcessing, msg.Received)
+		}, kinds: []BusKind{BusPendingTx, BusPendingHash}},
+		{name: "logs", buffer: BusLogBuffer, handler: func(msg BusMessage) {
+			m.logs.Handle(*msg.Log)
+			observeSince(logProcessing, msg.Received)
+		}, kinds: []BusKind{BusLog}},
+	}
+}
+
+// printMessage 输出新区块和 Pending 交易（"printer" 消费者）
+func (m *Monitor) printMessage(msg BusMessage) {
+	switch msg.Kind {
+	case BusHead:
+		h := msg.Header
+		lag := msg.Received.Sub(time.Unix(int64(h.Time), 0))
+		text := fmt.Sprintf("\n📦 [New Block] Height: %d | Hash: %s | Time: %d | Lag: %.1fs",
+			h.Number, h.Hash().Hex(), h.Time, lag.Seconds())
+		data := map[string]interface{}{"number": h.Number, "hash": h.Hash(), "time": h.Time, "lag_ms": lag.Milliseconds()}
+		if line, health := m.health.OnHead(h); health != nil {
+			text += "\n" + line
+			data["health"] = health
+		}
+		Emit(Event{Type: "new_head", Key: h.Hash().Hex(), Time: msg.Received, Text: text, Data: data})
+	case BusPendingTx:
+		tx := msg.Tx
+		if !m.pendingMatch(tx) || (m.fees != nil && !m.fees.Match(tx)) {
+			return
+		}
+		if m.matcher != nil {
+			m.printPendingMatch(tx, msg.Received)
+			return
+		}
+		if m.fees == nil {
+			Emit(Event{Type: "pending_tx", Key: tx.Hash().Hex(), Time: msg.Received, Text: "🌊 [Pending Tx] " + tx.Hash().Hex(), Data: map[string]interface{}{"hash": tx.Hash()}, tx: tx})
+			return
+		}
+		feeCap, tip := weiToGwei(tx.GasFeeCap()), weiToGwei(tx.GasTipCap())
+		Emit(Event{Type: "pending_tx", Key: tx.Hash().Hex(), Time: msg.Received,
+			Text: fmt.Sprintf("🌊 [Pending Tx] %s | maxFee %.2f gwei | tip %.2f gwei", tx.Hash().Hex(), feeCap, tip),
+			Data: map[string]interface{}{"hash": tx.Hash(), "max_fee_gwei": feeCap, "tip_gwei": tip}, tx: tx})
+	case BusPendingHash:
+		// 只有 Hash 时不知道出价和接收方，配置了过滤时不输出
+		if m.fees != nil || m.dests != nil || m.selectors != nil || m.matcher != nil || m.cfg.Mempool.Filter != "" {
+			return
+		}
+		Emit(Event{Type: "pending_tx", Key: msg.Hash.Hex(), Time: msg.Received, Text: "🌊 [Pending Tx] " + msg.Hash.Hex(), Data: map[string]interface{}{"hash": msg.Hash}})
+	}
+}
+
+// printPendingMatch 解码交易并按 mempool.match 匹配，只输出命中规则的交易
+func (m *Monitor) printPendingMatch(tx *types.Transaction, received time.Time) {
+	call, ok := m.abis.DecodeCall(tx.To(), tx.Data())
+	if !ok {
+		return
+	}
+	rules := m.matcher.Match(call)
+	if len(rules) == 0 {
+		return
+	}
+	summary := fmt.Sprintf("🎯 [Match:%s] %s -> %s", strings.Join(rules, ","), tx.Hash().Hex(), tx.To().Hex())
+	Emit(Event{Type: "pending_match", Key: tx.Hash().Hex(), Time: received, Summary: summary,
+		Text: summary + fmt.Sprintf("\n   🧾 调用 %s", call),
+		Data: map[string]interface{}{"hash": tx.Hash(), "to": tx.To(), "rules": rules, "call": call}, tx: tx})
+}
+
+// pendingMatch 交易是否满足 mempool.to、mempool.selectors 和 mempool.filter 的过滤条件
+func (m *Monitor) pendingMatch(tx *types.Transaction) bool {
+	return (m.dests == nil || m.dests.Match(tx)) && (m.selectors == nil || m.selectors.Match(tx)) &&
+		(m.cfg.Mempool.Filter == "" || m.filters.Match(m.cfg.Mempool.Filter, tx, nil))
+}
+
+// filtered 配置了 mempool.to / mempool.selectors / mempool.filter 时只把满足条件的完整交易交给 fn
+func (m *Monitor) filtered(fn func(BusMessage)) func(BusMessage) {
+	if m.dests == nil && m.selectors == nil && m.cfg.Mempool.Filter == "" {
+		return fn
+	}
+	return func(msg BusMessage) {
+		if msg.Tx != nil && m.pendingMatch(msg.Tx) {
+			fn(msg)
+		}
+	}
+}
+
+// handleHead 处理新区块头（"heads" 消费者）
+func (m *Monitor) handleHead(header *types.Header, received time.Time) {
+	m.lastHead.Store(received.UnixNano())
+	observeHeadLag(header, received)
+	m.headers.NotifyHead(header)
+	m.waiter.NotifyHead(header)
+	m.inclusion.OnHead(header)
+	m.fetcher.NotifyHead(header)
+	if m.indexer != nil {
+		m.indexer.NotifyHead(header)
+	}
+	if m.portfolio != nil {
+		m.portfolio.NotifyHead(header)
+	}
+	if m.impact != nil {
+		m.impact.NotifyHead(header)
+	}
+}
+
+// pendingRule 按当前关注列表生成 Pending 交易的预筛条件：
+// 发送方是关注地址（watched_tx / 授权），接收方是关注地址（名单筛查），或 calldata 中带有 permit（签名者不一定是发送者）
+func (m *Monitor) pendingRule() PrefilterRule {
+	m.prefilterVersion = m.watch.Version()
+	addrs := make([]common.Address, 0, m.watch.Len())
+	for addr := range m.watch.Snapshot() {
+		addrs = append(addrs, addr)
+	}
+	rule := PrefilterRule{Senders: addrs}
+	if m.sanctions != nil {
+		rule.Recipients = addrs
+	}
+	if m.approvals != nil {
+		rule.Embedded = permitSelectors
+	}
+	return rule
+}
+
+// handlePending 处理 Pending 交易（"pending" 消费者），只有 Hash 时无法分析发送者
+func (m *Monitor) handlePending(msg BusMessage) {
+	if m.txpool != nil {
+		m.txpool.ObservePending()
+	}
+	tx := msg.Tx
+	if tx == nil || m.watch.Len() == 0 {
+		return
+	}
+	// 关注列表变化后重建预筛条件；绝大多数交易在这里就被丢弃，不需要恢复发送者
+	if m.watch.Version() != m.prefilterVersion {
+		m.prefilter.Load(m.pendingRule())
+	}
+	if !m.prefilter.Pass(tx) {
+		return
+	}
+	from, err := types.Sender(m.signer, tx)
+	if err != nil {
+		return
+	}
+	if src := m.watch.Source(from); src != "" {
+		text := fmt.Sprintf("👀 [Watched:%s] %s 发出交易 %s (nonce=%d)", src, from.Hex(), tx.Hash().Hex(), tx.Nonce())
+		call, ok := m.abis.DecodeCall(tx.To(), tx.Data())
+		if ok {
+			text += fmt.Sprintf("\n   🧾 调用 %s", call)
+		}
+		var impact []SwapImpact
+		if m.impact != nil && ok {
+			impact = m.impact.Estimate(tx.To(), tx.Value(), call)
+			for _, s := range impact {
+				text += fmt.Sprintf("\n   📉 %s", s)
+			}
+		}
+		var routes []AggregatorRoute
+		if m.aggregators != nil && ok {
+			routes = m.aggregators.Routes(tx.To(), tx.Value(), call)
+			for _, r := range routes {
+				text += fmt.Sprintf("\n   🔀 %s", r)
+			}
+		}
+		Emit(Event{Type: "watched_tx", Key: tx.Hash().Hex(), Text: text, Data: map[string]interface{}{
+			"source": src, "from": from, "hash": tx.Hash(), "nonce": tx.Nonce(), "to": tx.To(), "call": call, "impact": impact, "routes": routes,
+		}, tx: tx, txFrom: &from})
+		m.inclusion.Track(tx, from)
+	}
+	if m.approvals != nil {
+		m.approvals.InspectPending(tx, from)
+	}
+	if m.sanctions != nil {
+		m.sanctions.InspectPending(tx, from)
+	}
+}
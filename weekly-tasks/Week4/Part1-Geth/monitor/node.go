@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// NodeKind 节点实现
+type NodeKind string
+
+const (
+	NodeGeth       NodeKind = "geth"
+	NodeErigon     NodeKind = "erigon"
+	NodeNethermind NodeKind = "nethermind"
+	NodeBesu       NodeKind = "besu"
+	NodeReth       NodeKind = "reth"
+	NodeUnknown    NodeKind = "unknown"
+)
+
+// NodeInfo 节点实现与可用的特有能力
+type NodeInfo struct {
+	ClientVersion string
+	Kind          NodeKind
+	Modules       map[string]string // rpc_modules 返回的已开启模块，节点不支持时为 nil
+
+	// Parity 风格的 trace_* 接口（Erigon / Nethermind / Besu / Reth）
+	ParityTrace bool
+	// Geth 风格的 debug_trace*（callTracer）
+	DebugTrace bool
+	// Nethermind 的 parity_* 接口（例如 parity_pendingTransactions）
+	ParityModule bool
+}
+
+// DetectNode 通过 web3_clientVersion 判断节点实现，再用 rpc_modules 确认哪些特有接口真正开启了
+// 托管节点（Infura/Alchemy）通常不支持 rpc_modules，这时按节点实现的默认能力推断
+func DetectNode(ctx context.Context, client *rpc.Client) (*NodeInfo, error) {
+	info := &NodeInfo{Kind: NodeUnknown}
+	if err := callRetry(ctx, client, &info.ClientVersion, "web3_clientVersion"); err != nil {
+		return nil, fmt.Errorf("查询 web3_clientVersion 失败: %w", err)
+	}
+	info.Kind = parseNodeKind(info.ClientVersion)
+
+	var modules map[string]string
+	if err := callRetry(ctx, client, &modules, "rpc_modules"); err == nil {
+		info.Modules = modules
+	}
+	switch info.Kind {
+	case NodeErigon, NodeBesu, NodeReth:
+		info.ParityTrace = info.hasModule("trace")
+		info.DebugTrace = info.hasModule("debug")
+	case NodeNethermind:
+		info.ParityTrace = info.hasModule("trace")
+		info.DebugTrace = info.hasModule("debug")
+		info.ParityModule = info.hasModule("parity")
+	case NodeGeth:
+		info.DebugTrace = info.hasModule("debug")
+	}
+	return info, nil
+}
+
+// hasModule 模块是否开启；拿不到模块列表时乐观地认为开启，由实际调用的报错兜底
+func (n *NodeInfo) hasModule(name string) bool {
+	if n.Modules == nil {
+		return true
+	}
+	_, ok := n.Modules[name]
+	return ok
+}
+
+// String 用于启动日志
+func (n *NodeInfo) String() string {
+	flag := func(b bool) string {
+		if b {
+			return "✅"
+		}
+		return "❌"
+	}
+	return fmt.Sprintf("%s (%s) | trace_* %s | debug_trace* %s | parity_* %s",
+		n.Kind, n.ClientVersion, flag(n.ParityTrace), flag(n.DebugTrace), flag(n.ParityModule))
+}
+
+// parseNodeKind 从 clientVersion（例如 "erigon/2.60.0/linux-amd64/go1.21.5"）识别节点实现
+func parseNodeKind(version string) NodeKind {
+	v := strings.ToLower(version)
+	for _, kind := range []NodeKind{NodeErigon, NodeNethermind, NodeBesu, NodeReth, NodeGeth} {
+		if strings.HasPrefix(v, string(kind)) {
+			return kind
+		}
+	}
+	return NodeUnknown
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// 节点健康检查的默认间隔（秒）、最少 peer 数和 peer 流失告警阈值
+	DefaultNodeHealthInterval = 30
+	DefaultNodeMinPeers       = 5
+	// ⚠️ 两次采样之间旧 peer 断开的比例，公网节点正常情况下每分钟也会换掉一部分 peer
+	DefaultNodePeerChurnPct = 50.0
+)
+
+// NodeHealthConfig 监控节点本身（而不是链）的健康状况，需要自建节点开启 net / admin / txpool 接口
+type NodeHealthConfig struct {
+	Enabled      bool    `json:"enabled"`
+	Interval     int     `json:"interval"`       // 采样间隔（秒）
+	MinPeers     int     `json:"min_peers"`      // peer 数低于该值时提醒
+	PeerChurnPct float64 `json:"peer_churn_pct"` // 两次采样之间断开的 peer 占比超过该值时提醒，0 表示关闭
+}
+
+// NodeStatus 一次采样的节点状态，不支持的接口对应字段为空
+type NodeStatus struct {
+	Time      time.Time `json:"time"`
+	PeerCount int       `json:"peer_count"`
+	Peers     []string  `json:"peers,omitempty"` // admin_peers 返回的 peer ID
+	Syncing   bool      `json:"syncing"`
+	TxPending *int      `json:"txpool_pending,omitempty"`
+	TxQueued  *int      `json:"txpool_queued,omitempty"`
+}
+
+// NodeHealthAlert 节点异常提醒
+type NodeHealthAlert struct {
+	Kind   string // low_peers / peer_churn / syncing / unreachable
+	Detail string
+	Status NodeStatus
+}
+
+// NodeHealthMonitor 定期查询 net_peerCount、admin_peers（可用时）、txpool_status 和 eth_syncing，
+// peer 数过低、peer 大量流失、节点重新进入同步或接口不可达时提醒
+type NodeHealthMonitor struct {
+	cfg     NodeHealthConfig
+	client  *rpc.Client
+	onAlert func(NodeHealthAlert)
+
+	admin  bool // admin_peers 可用；第一次调用失败后不再尝试
+	txpool bool
+
+	mu       sync.Mutex
+	latest   *NodeStatus
+	prev     map[string]bool
+	alerting map[string]bool // 正在告警中的类型，恢复前不重复提醒
+
+	peers     *metrics.Gauge
+	txPending *metrics.Gauge
+	txQueued  *metrics.Gauge
+}
+
+// NewNodeHealthMonitor 创建节点健康检查，onAlert 为 nil 时输出到控制台
+func NewNodeHealthMonitor(cfg NodeHealthConfig, client *rpc.Client, onAlert func(NodeHealthAlert)) *NodeHealthMonitor {
+	if onAlert == nil {
+		onAlert = PrintNodeHealthAlert
+	}
+	return &NodeHealthMonitor{
+		cfg:       cfg,
+		client:    client,
+		onAlert:   onAlert,
+		admin:     true,
+		txpool:    true,
+		alerting:  make(map[string]bool),
+		peers:     metrics.NewRegisteredGauge("monitor/node/peers", metricsRegistry),
+		txPending: metrics.NewRegisteredGauge("monitor/node/txpool_pending", metricsRegistry),
+		txQueued:  metrics.NewRegisteredGauge("monitor/node/txpool_queued", metricsRegistry),
+	}
+}
+
+// Run 按间隔采样，直到 ctx 取消
+func (n *NodeHealthMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(n.cfg.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		if rpcBudget.Load().Allow("node_health", time.Duration(n.cfg.Interval)*time.Second) {
+			n.check(ctx)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sample 查询一次节点状态；net_peerCount 失败视为节点不可达，其余接口失败时跳过
+func (n *NodeHealthMonitor) sample(ctx context.Context) (*NodeStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, CONNECTION_TIMEOUT)
+	defer cancel()
+	s := &NodeStatus{Time: time.Now()}
+
+	var count hexutil.Uint64
+	if err := callRetry(ctx, n.client, &count, "net_peerCount"); err != nil {
+		return nil, fmt.Errorf("net_peerCount: %w", err)
+	}
+	s.PeerCount = int(count)
+
+	var syncing interface{}
+	if err := callRetry(ctx, n.client, &syncing, "eth_syncing"); err == nil {
+		s.Syncing = syncing != false
+	}
+
+	if n.admin {
+		var peers []struct {
+			ID string `json:"id"`
+		}
+		if err := callRetry(ctx, n.client, &peers, "admin_peers"); err != nil {
+			log.Printf("⚠️  admin_peers 不可用: %v（不再统计 peer 流失）", err)
+			n.admin = false
+		} else {
+			for _, p := range peers {
+				s.Peers = append(s.Peers, p.ID)
+			}
+		}
+	}
+
+	if n.txpool {
+		var pool struct {
+			Pending hexutil.Uint `json:"pending"`
+			Queued  hexutil.Uint `json:"queued"`
+		}
+		if err := callRetry(ctx, n.client, &pool, "txpool_status"); err != nil {
+			log.Printf("⚠️  txpool_status 不可用: %v", err)
+			n.txpool = false
+		} else {
+			pending, queued := int(pool.Pending), int(pool.Queued)
+			s.TxPending, s.TxQueued = &pending, &queued
+		}
+	}
+	return s, nil
+}
+
+// check 采样并判断是否需要提醒
+func (n *NodeHealthMonitor) check(ctx context.Context) {
+	s, err := n.sample(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			n.raise("unreachable", fmt.Sprintf("节点接口不可达: %v", err), NodeStatus{Time: time.Now()})
+		}
+		return
+	}
+	n.clear("unreachable")
+
+	n.peers.Update(int64(s.PeerCount))
+	if s.TxPending != nil {
+		n.txPending.Update(int64(*s.TxPending))
+		n.txQueued.Update(int64(*s.TxQueued))
+	}
+
+	if s.PeerCount < n.cfg.MinPeers {
+		n.raise("low_peers", fmt.Sprintf("peer 数 %d 低于 %d", s.PeerCount, n.cfg.MinPeers), *s)
+	} else {
+		n.clear("low_peers")
+	}
+	if s.Syncing {
+		n.raise("syncing", "节点正在同步，数据可能落后于链上", *s)
+	} else {
+		n.clear("syncing")
+	}
+
+	current := make(map[string]bool, len(s.Peers))
+	for _, id := range s.Peers {
+		current[id] = true
+	}
+	n.mu.Lock()
+	prev := n.prev
+	n.prev, n.latest = current, s
+	n.mu.Unlock()
+	if n.cfg.PeerChurnPct > 0 && len(prev) > 0 && s.Peers != nil {
+		left := 0
+		for id := range prev {
+			if !current[id] {
+				left++
+			}
+		}
+		// peer 流失是瞬时事件，每次超过阈值都提醒
+		if pct := float64(left) / float64(len(prev)) * 100; pct >= n.cfg.PeerChurnPct {
+			n.onAlert(NodeHealthAlert{
+				Kind:   "peer_churn",
+				Detail: fmt.Sprintf("%d 秒内 %d / %d 个 peer 断开（%.0f%%）", n.cfg.Interval, left, len(prev), pct),
+				Status: *s,
+			})
+		}
+	}
+}
+
+// raise 进入告警状态时提醒一次
+func (n *NodeHealthMonitor) raise(kind, detail string, s NodeStatus) {
+	if n.alerting[kind] {
+		return
+	}
+	n.alerting[kind] = true
+	n.onAlert(NodeHealthAlert{Kind: kind, Detail: detail, Status: s})
+}
+
+// clear 恢复正常
+func (n *NodeHealthMonitor) clear(kind string) {
+	if n.alerting[kind] {
+		delete(n.alerting, kind)
+		log.Printf("✅ 节点状态恢复: %s", kind)
+	}
+}
+
+// Latest 最近一次采样
+func (n *NodeHealthMonitor) Latest() *NodeStatus {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.latest
+}
+
+// RegisterAPI 注册节点状态查询接口
+//
+//	GET /api/node 最近一次采样的节点状态
+func (n *NodeHealthMonitor) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/node", func(w http.ResponseWriter, r *http.Request) {
+		s := n.Latest()
+		if s == nil {
+			writeError(w, http.StatusServiceUnavailable, "还没有采样数据")
+			return
+		}
+		writeJSON(w, http.StatusOK, s)
+	})
+}
+
+// PrintNodeHealthAlert 默认的节点异常提醒
+func PrintNodeHealthAlert(a NodeHealthAlert) {
+	parts := []string{fmt.Sprintf("peers %d", a.Status.PeerCount)}
+	if a.Status.TxPending != nil {
+		parts = append(parts, fmt.Sprintf("txpool %d / %d", *a.Status.TxPending, *a.Status.TxQueued))
+	}
+	Emit(Event{Type: "node_alert", Data: a, Text: fmt.Sprintf("🩺 [Node Health] %s | %s | %s", a.Kind, a.Detail, strings.Join(parts, ", "))})
+}
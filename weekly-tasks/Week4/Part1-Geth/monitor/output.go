@@ -0,0 +1,442 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// ------------------------------------------------
+// 输出：各模块产生的事件统一交给 Output，由各个 sink 按自己的配置写出
+// ------------------------------------------------
+
+// 输出格式
+const (
+	FormatPretty = "pretty" // 与以前一致的多行 emoji 输出
+	FormatTable  = "table"  // 紧凑的一行一列：时间 | 类型 | 摘要
+	FormatJSON   = "json"   // 每行一个完整的 JSON 对象，适合写入文件再交给其它程序处理
+	FormatQuiet  = "quiet"  // 只输出一行摘要
+)
+
+// 单个事件类型的详细程度，覆盖格式的默认行为
+const (
+	VerbosityOff     = "off"     // 不输出
+	VerbositySummary = "summary" // 只输出一行摘要（json 中省略 data）
+	VerbosityFull    = "full"    // 完整输出（quiet 中也输出完整文本）
+)
+
+// Event 一条输出事件
+type Event struct {
+	ID      string      // 稳定 ID，为空时 Emit 按类型和 Key 计算
+	Key     string      // 自然键（区块哈希、交易哈希 + 日志序号等，见 eventKey），不含延迟、价格等易变字段
+	Type    string      // 事件类型，例如 "new_head"、"approval_alert"
+	Time    time.Time   // 为零值时 Emit 填入当前时间
+	Summary string      // 一行摘要，为空时取 Text 的第一个非空行
+	Text    string      // 完整的控制台文本（可以多行），为空时使用 Summary
+	Data    interface{} // 结构化数据，json 格式输出
+
+	Severity string   // info / warn / critical，为空时按事件类型取默认级别（severity.go）
+	Category string   // security / mev / node ...，为空时按事件类型取默认分类
+	Outputs  []string // 只发往这些输出（按 name），为空时按路由；关注分组的事件使用
+
+	// 事件对应的交易和已知的发送者，输出和路由规则中的过滤链据此判断，不会输出
+	tx     *types.Transaction
+	txFrom *common.Address
+}
+
+// eventID 由事件类型和自然键计算的 ID：同一个事件重新产生（回补、重启后重新处理区块）时 ID 相同，
+// 消费方据此对重复投递去重；没有自然键的事件（周期报告、状态变化等）每次产生新的 ID
+func eventID(ev Event) string {
+	if ev.Key == "" {
+		return fmt.Sprintf("%s%016x", eventIDPrefix, eventSeq.Add(1))
+	}
+	return hex.EncodeToString(crypto.Keccak256([]byte(ev.Type), []byte{0}, []byte(ev.Key))[:16])
+}
+
+var (
+	// eventIDPrefix 进程启动时随机生成，没有自然键的事件 ID 在重启后也不会与之前的重复
+	eventIDPrefix = func() string {
+		b := make([]byte, 8)
+		rand.Read(b)
+		return hex.EncodeToString(b)
+	}()
+	eventSeq atomic.Uint64
+)
+
+// eventKey 把区块号、哈希、地址等字段用 / 拼成自然键
+func eventKey(parts ...interface{}) string {
+	strs := make([]string, len(parts))
+	for i, p := range parts {
+		strs[i] = fmt.Sprint(p)
+	}
+	return strings.Join(strs, "/")
+}
+
+// eventDigest 由事件类型和内容计算的摘要，内容完全相同的事件摘要相同（限流按内容合并时使用）；
+// Data 无法编码时使用完整文本
+func eventDigest(ev Event) string {
+	body, err := json.Marshal(ev.Data)
+	if ev.Data == nil || err != nil {
+		body = []byte(ev.Summary + "\n" + ev.Text)
+	}
+	return hex.EncodeToString(crypto.Keccak256([]byte(ev.Type), body)[:16])
+}
+
+// summary 一行摘要
+func (e Event) summary() string {
+	if e.Summary != "" {
+		return e.Summary
+	}
+	for _, line := range strings.Split(e.Text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// OutputConfig 一个输出目标
+type OutputConfig struct {
+	Name      string            `json:"name"`      // 日志和指标中显示的名称，默认为 sink 类型加序号
+	Sink      string            `json:"sink"`      // sink 类型，默认 stream
+	Target    string            `json:"target"`    // stream：stdout / stderr / 文件路径（追加写入，可以轮转）
+	Format    string            `json:"format"`    // stream：pretty / table / json / quiet，默认 pretty
+	Verbosity map[string]string `json:"verbosity"` // 事件类型 -> off / summary / full
+	Templates map[string]string `json:"templates"` // 事件类型（* 为全部类型）-> 改写提醒文本的 Go 模板（alert_template.go）
+
+	// 文件目标的轮转与保留，全部为 0 时只追加写入同一个文件
+	MaxSizeMB   int `json:"max_size_mb"`  // 文件超过该大小时轮转
+	RotateHours int `json:"rotate_hours"` // 每隔多少小时轮转（按整点对齐，例如 24 表示每天）
+	MaxFiles    int `json:"max_files"`    // 保留的旧文件个数
+	MaxAgeDays  int `json:"max_age_days"` // 旧文件保留天数
+
+	URL       string `json:"url"`        // webhook：接收 POST 的地址；telegram：Bot API 地址，默认 https://api.telegram.org
+	BatchSize int    `json:"batch_size"` // webhook：每次 POST 的最大事件数，默认 100
+
+	// 对端不可用或处理太慢时，把事件暂存到该目录下的磁盘队列，恢复后按顺序补发；留空时只在内存中缓存
+	SpillDir   string `json:"spill_dir"`
+	SpillMaxMB int    `json:"spill_max_mb"` // 磁盘队列容量，默认 512，超过后丢弃最旧的事件
+
+	BotTokenEnv string `json:"bot_token_env"` // telegram：从该环境变量读取 bot token
+	ChatID      string `json:"chat_id"`       // telegram：接收消息的 chat，可以是群组 ID（负数）或 @频道名
+
+	// 带有交易的事件（pending_tx、watched_tx、profile_tx 等）只有满足该过滤链时才写入，其它事件不受影响
+	Filter string `json:"filter"`
+}
+
+// kind sink 类型，未填写时为 stream
+func (c OutputConfig) kind() string {
+	if c.Sink == "" {
+		return SinkStream
+	}
+	return c.Sink
+}
+
+// validate 检查 sink 类型、格式和详细程度的取值
+func (c OutputConfig) validate() error {
+	if _, ok := sinkFactories[c.kind()]; !ok {
+		return fmt.Errorf("outputs 中的 sink 类型未知: %q", c.Sink)
+	}
+	switch c.kind() {
+	case SinkStream:
+		if c.Target == "" {
+			return fmt.Errorf("outputs 中的 target 不能为空")
+		}
+	case SinkWebhook:
+		if c.URL == "" {
+			return fmt.Errorf("outputs 中 webhook 的 url 不能为空")
+		}
+		if c.BatchSize < 0 || c.SpillMaxMB < 0 {
+			return fmt.Errorf("outputs 中 webhook 的 batch_size 和 spill_max_mb 不能为负数")
+		}
+	case SinkTelegram:
+		if c.BotTokenEnv == "" || c.ChatID == "" {
+			return fmt.Errorf("outputs 中 telegram 的 bot_token_env 和 chat_id 不能为空")
+		}
+	}
+	switch c.Format {
+	case "", FormatPretty, FormatTable, FormatJSON, FormatQuiet:
+	default:
+		return fmt.Errorf("outputs 中 %s 的 format 无效: %q（可选 pretty / table / json / quiet）", c.Target, c.Format)
+	}
+	for typ, v := range c.Verbosity {
+		switch v {
+		case VerbosityOff, VerbositySummary, VerbosityFull:
+		default:
+			return fmt.Errorf("outputs 中 %s 的 verbosity.%s 无效: %q（可选 off / summary / full）", c.Target, typ, v)
+		}
+	}
+	if _, err := ParseAlertTemplates(c.Templates); err != nil {
+		return fmt.Errorf("outputs 中 %s 的 templates 无效: %w", c.Target, err)
+	}
+	if c.MaxSizeMB < 0 || c.RotateHours < 0 || c.MaxFiles < 0 || c.MaxAgeDays < 0 {
+		return fmt.Errorf("outputs 中 %s 的轮转参数不能为负数", c.Target)
+	}
+	if (c.kind() != SinkStream || c.Target == "stdout" || c.Target == "stderr") && c.MaxSizeMB+c.RotateHours+c.MaxFiles+c.MaxAgeDays > 0 {
+		return fmt.Errorf("outputs 中 %s 不是文件，不支持轮转参数", c.Target)
+	}
+	return nil
+}
+
+const (
+	// Output 调用各 sink Flush 的间隔
+	OutputFlushInterval = time.Second
+	// 同一个 sink 持续出错时，错误日志的最短间隔
+	sinkErrorLogInterval = time.Minute
+)
+
+// outputSink 一个已创建的 sink 及其错误统计
+type outputSink struct {
+	name      string
+	sink      Sink
+	templates *AlertTemplates // 未配置模板时为 nil
+	filter    string          // 过滤链名称，为空不过滤
+	errors    *metrics.Counter
+
+	mu      sync.Mutex
+	lastLog time.Time
+
+	// 暂停状态（pause.go）
+	pmu      sync.Mutex
+	paused   bool
+	pausedAt time.Time
+	held     []Event // 暂停期间缓冲的事件
+	dropped  int     // 本次暂停期间丢弃的事件数
+}
+
+// Output 把事件写入全部 sink；某个 sink 出错（包括 panic）只记录错误，不影响其它 sink
+type Output struct {
+	sinks  []*outputSink
+	cancel context.CancelFunc // 停止定期 Flush，Start 之前为 nil
+	closed sync.Once
+}
+
+// NewOutput 按配置创建 sink，cfgs 为空时输出到 stdout（pretty）
+func NewOutput(cfgs []OutputConfig) (*Output, error) {
+	if len(cfgs) == 0 {
+		cfgs = []OutputConfig{{Target: "stdout", Format: FormatPretty}}
+	}
+	o := &Output{}
+	for i, cfg := range cfgs {
+		factory, ok := sinkFactories[cfg.kind()]
+		if !ok {
+			o.Close()
+			return nil, fmt.Errorf("未知的 sink 类型: %q", cfg.Sink)
+		}
+		sink, err := factory(cfg)
+		if err != nil {
+			o.Close()
+			return nil, fmt.Errorf("创建输出 %s 失败: %w", cfg.Target, err)
+		}
+		templates, err := ParseAlertTemplates(cfg.Templates)
+		if err != nil {
+			sink.Close()
+			o.Close()
+			return nil, fmt.Errorf("创建输出 %s 失败: %w", cfg.Target, err)
+		}
+		name := cfg.Name
+		if name == "" {
+			name = fmt.Sprintf("%s%d", cfg.kind(), i)
+		}
+		o.sinks = append(o.sinks, &outputSink{
+			name:      name,
+			sink:      sink,
+			templates: templates,
+			filter:    cfg.Filter,
+			errors:    metrics.GetOrRegisterCounter("monitor/output/"+name+"/errors", metricsRegistry),
+		})
+	}
+	return o, nil
+}
+
+// Start 启动全部 sink，并定期 Flush，直到 ctx 取消
+func (o *Output) Start(ctx context.Context) error {
+	ctx, o.cancel = context.WithCancel(ctx)
+	for _, s := range o.sinks {
+		if err := s.sink.Start(ctx); err != nil {
+			return fmt.Errorf("启动输出 %s 失败: %w", s.name, err)
+		}
+	}
+	go func() {
+		ticker := time.NewTicker(OutputFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				o.Flush()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Emit 写入事件指定的输出，没有指定时按路由写入，未配置路由时写入全部 sink
+func (o *Output) Emit(ev Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	if ev.ID == "" {
+		ev.ID = eventID(ev)
+	}
+	router := currentRouting()
+	ev.classify()
+	if d := currentDashboard(); d != nil {
+		d.publishEvent(ev)
+	}
+	if h := currentHistory(); h != nil {
+		h.RecordEvent(ev)
+	}
+	filters := currentFilters()
+	for _, s := range o.sinks {
+		if s.filter != "" && ev.tx != nil && !filters.Match(s.filter, ev.tx, ev.txFrom) {
+			continue
+		}
+		if len(ev.Outputs) > 0 {
+			if !hasString(ev.Outputs, s.name) {
+				continue
+			}
+		} else if !router.Match(s.name, ev) {
+			continue
+		}
+		out := ev
+		if s.templates != nil {
+			// 模板渲染失败时按原文输出，错误计入该 sink
+			s.call("渲染模板", func() (err error) {
+				out, err = s.templates.Apply(ev)
+				return err
+			})
+		}
+		if s.hold(out) {
+			continue
+		}
+		s.call("写入", func() error { return s.sink.Write(out) })
+	}
+}
+
+// Flush 刷新全部未暂停的 sink
+func (o *Output) Flush() {
+	for _, s := range o.sinks {
+		if !s.isPaused() {
+			s.call("刷新", s.sink.Flush)
+		}
+	}
+}
+
+// Close 停止定期 Flush，刷新并关闭全部 sink；重复调用只关闭一次（热加载替换输出后原来的输出已经关闭）
+func (o *Output) Close() error {
+	o.closed.Do(func() {
+		if o.cancel != nil {
+			o.cancel()
+		}
+		for _, s := range o.sinks {
+			if st := s.pauseStatus(); st.Buffered > 0 {
+				log.Printf("⚠️  输出 %s 仍处于暂停状态，丢弃 %d 个缓冲的事件", s.name, st.Buffered)
+			}
+			s.call("刷新", s.sink.Flush)
+			s.call("关闭", s.sink.Close)
+		}
+	})
+	return nil
+}
+
+// call 调用 sink 的方法，错误和 panic 只计数并（限频）记录日志
+func (s *outputSink) call(op string, f func() error) {
+	var err error
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		err = f()
+	}()
+	if err == nil {
+		return
+	}
+	s.errors.Inc(1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.lastLog) >= sinkErrorLogInterval {
+		s.lastLog = time.Now()
+		log.Printf("⚠️  输出 %s %s失败: %v（累计 %d 次）", s.name, op, err, s.errors.Snapshot().Count())
+	}
+}
+
+// render 按格式和该事件类型的详细程度渲染，返回空字符串表示不输出
+func render(format, v string, ev Event) string {
+	if v == VerbosityOff {
+		return ""
+	}
+	switch format {
+	case FormatJSON:
+		return string(marshalEvent(ev, v)) + "\n"
+	case FormatTable:
+		if v == VerbosityFull {
+			return ev.Time.Format("15:04:05") + "  " + ev.Type + "\n" + fullText(ev)
+		}
+		return fmt.Sprintf("%s  %-20s  %s\n", ev.Time.Format("15:04:05"), ev.Type, ev.summary())
+	case FormatQuiet:
+		if v == VerbosityFull {
+			return fullText(ev)
+		}
+		return ev.summary() + "\n"
+	default:
+		if v == VerbositySummary {
+			return ev.summary() + "\n"
+		}
+		return fullText(ev)
+	}
+}
+
+// fullText 完整文本，保证以换行结尾
+func fullText(ev Event) string {
+	s := ev.Text
+	if s == "" {
+		s = ev.summary()
+	}
+	if !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	return s
+}
+
+// defaultOutput 各模块默认的 PrintX 回调使用的输出，runMonitor 按配置替换，热加载时再次替换
+var (
+	defaultOutputMu  sync.RWMutex
+	defaultOutput, _ = NewOutput(nil)
+)
+
+// SetDefaultOutput 替换默认输出，返回原来的输出；返回时已经没有正在写入原输出的 Emit，可以直接关闭
+// 新输出中与原输出同名的 sink 继承暂停状态和缓冲的事件
+func SetDefaultOutput(o *Output) *Output {
+	defaultOutputMu.Lock()
+	defer defaultOutputMu.Unlock()
+	prev := defaultOutput
+	o.adoptPause(prev)
+	defaultOutput = o
+	return prev
+}
+
+// Emit 写入默认输出，配置了限流规则时先经过限流
+func Emit(ev Event) {
+	if !throttleAllow(&ev) {
+		return
+	}
+	defaultOutputMu.RLock()
+	defer defaultOutputMu.RUnlock()
+	defaultOutput.Emit(ev)
+}
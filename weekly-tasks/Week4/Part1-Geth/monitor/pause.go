@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ------------------------------------------------
+// 暂停 / 恢复输出：下游系统（webhook、日志收集等）维护期间暂停某个或全部 sink，
+// 暂停期间的事件按策略缓冲在内存中（恢复时按顺序补发）或直接丢弃；订阅和分析照常进行，只是不往下游写
+// 可以通过 API 或 SIGUSR2 控制，热加载替换输出后同名 sink 的暂停状态保留
+// ------------------------------------------------
+
+const (
+	PauseBuffer = "buffer" // 暂停期间缓冲事件，恢复时补发
+	PauseDrop   = "drop"   // 暂停期间丢弃事件
+
+	// ⚠️ 默认每个 sink 最多缓冲 10000 个事件，超出后丢弃最早的，避免长时间暂停占满内存
+	DefaultPauseMaxBuffer = 10000
+)
+
+// PauseConfig 暂停输出时对事件的处理
+type PauseConfig struct {
+	Policy    string `json:"policy"`     // buffer（默认）| drop
+	MaxBuffer int    `json:"max_buffer"` // buffer 时每个 sink 最多缓冲的事件数
+}
+
+// outputPause 所有输出共用的暂停策略，Run 时按配置设置
+var outputPause = PauseConfig{Policy: PauseBuffer, MaxBuffer: DefaultPauseMaxBuffer}
+
+// SetPausePolicy 替换暂停策略，需要在暂停任何 sink 之前调用
+func SetPausePolicy(cfg PauseConfig) {
+	outputPause = cfg
+}
+
+// SinkPauseStatus /api/outputs 中一个 sink 的状态
+type SinkPauseStatus struct {
+	Name     string     `json:"name"`
+	Paused   bool       `json:"paused"`
+	Since    *time.Time `json:"since,omitempty"`
+	Buffered int        `json:"buffered"` // 等待补发的事件数
+	Dropped  int        `json:"dropped"`  // 本次暂停期间丢弃的事件数
+}
+
+// PauseEvent 一次暂停 / 恢复
+type PauseEvent struct {
+	Sinks    []string `json:"sinks"`
+	Paused   bool     `json:"paused"`
+	Reason   string   `json:"reason"`   // api / signal
+	Replayed int      `json:"replayed"` // 恢复时补发的事件数
+	Dropped  int      `json:"dropped"`  // 暂停期间丢弃的事件数
+}
+
+// hold 暂停时按策略缓冲或丢弃事件并返回 true，未暂停时返回 false
+func (s *outputSink) hold(ev Event) bool {
+	s.pmu.Lock()
+	defer s.pmu.Unlock()
+	if !s.paused {
+		return false
+	}
+	if outputPause.Policy == PauseDrop {
+		s.dropped++
+		return true
+	}
+	if len(s.held) >= outputPause.MaxBuffer {
+		s.held = s.held[1:]
+		s.dropped++
+	}
+	s.held = append(s.held, ev)
+	return true
+}
+
+// isPaused 暂停期间不 Flush，已经写入 sink 缓冲的事件也等到恢复后再发
+func (s *outputSink) isPaused() bool {
+	s.pmu.Lock()
+	defer s.pmu.Unlock()
+	return s.paused
+}
+
+// pause 暂停，已经暂停时返回 false
+func (s *outputSink) pause() bool {
+	s.pmu.Lock()
+	defer s.pmu.Unlock()
+	if s.paused {
+		return false
+	}
+	s.paused, s.pausedAt, s.dropped = true, time.Now(), 0
+	return true
+}
+
+// resume 按顺序补发缓冲的事件后恢复；补发期间持有锁，新的事件排在补发的事件之后
+func (s *outputSink) resume() (ok bool, replayed, dropped int) {
+	s.pmu.Lock()
+	defer s.pmu.Unlock()
+	if !s.paused {
+		return false, 0, 0
+	}
+	for _, ev := range s.held {
+		s.call("补发", func() error { return s.sink.Write(ev) })
+	}
+	replayed, dropped = len(s.held), s.dropped
+	s.paused, s.held, s.dropped = false, nil, 0
+	return true, replayed, dropped
+}
+
+// pauseStatus 调用方不持有 pmu
+func (s *outputSink) pauseStatus() SinkPauseStatus {
+	s.pmu.Lock()
+	defer s.pmu.Unlock()
+	st := SinkPauseStatus{Name: s.name, Paused: s.paused, Buffered: len(s.held), Dropped: s.dropped}
+	if s.paused {
+		since := s.pausedAt
+		st.Since = &since
+	}
+	return st
+}
+
+// adoptPause 同名 sink 继承 prev 中的暂停状态和缓冲，热加载替换输出时调用
+func (o *Output) adoptPause(prev *Output) {
+	for _, s := range o.sinks {
+		for _, p := range prev.sinks {
+			if p.name != s.name {
+				continue
+			}
+			p.pmu.Lock()
+			s.paused, s.pausedAt, s.held, s.dropped = p.paused, p.pausedAt, p.held, p.dropped
+			p.paused, p.held = false, nil
+			p.pmu.Unlock()
+		}
+	}
+}
+
+// OutputPause 暂停 / 恢复默认输出中的 sink，热加载替换输出后作用于新的输出
+type OutputPause struct {
+	onChange func(PauseEvent)
+}
+
+// NewOutputPause onChange 为 nil 时使用默认输出
+func NewOutputPause(onChange func(PauseEvent)) *OutputPause {
+	if onChange == nil {
+		onChange = PrintPauseEvent
+	}
+	return &OutputPause{onChange: onChange}
+}
+
+// sinks 默认输出中名为 name 的 sink，name 为空时返回全部；调用方持有 defaultOutputMu 的读锁
+func (p *OutputPause) sinks(name string) ([]*outputSink, error) {
+	if name == "" {
+		return defaultOutput.sinks, nil
+	}
+	for _, s := range defaultOutput.sinks {
+		if s.name == name {
+			return []*outputSink{s}, nil
+		}
+	}
+	return nil, fmt.Errorf("未知的输出: %q", name)
+}
+
+// Pause 暂停名为 sink 的输出，sink 为空时暂停全部
+func (p *OutputPause) Pause(sink, reason string) error {
+	defaultOutputMu.RLock()
+	sinks, err := p.sinks(sink)
+	e := PauseEvent{Paused: true, Reason: reason}
+	for _, s := range sinks {
+		if s.pause() {
+			e.Sinks = append(e.Sinks, s.name)
+		}
+	}
+	defaultOutputMu.RUnlock()
+	if err != nil {
+		return err
+	}
+	// Emit 需要读锁，放在释放之后；刚暂停的 sink 会缓冲这条事件
+	if len(e.Sinks) > 0 {
+		p.onChange(e)
+	}
+	return nil
+}
+
+// Resume 恢复名为 sink 的输出并补发缓冲的事件，sink 为空时恢复全部
+func (p *OutputPause) Resume(sink, reason string) error {
+	defaultOutputMu.RLock()
+	sinks, err := p.sinks(sink)
+	e := PauseEvent{Paused: false, Reason: reason}
+	for _, s := range sinks {
+		if ok, replayed, dropped := s.resume(); ok {
+			e.Sinks = append(e.Sinks, s.name)
+			e.Replayed += replayed
+			e.Dropped += dropped
+		}
+	}
+	defaultOutputMu.RUnlock()
+	if err != nil {
+		return err
+	}
+	if len(e.Sinks) > 0 {
+		p.onChange(e)
+	}
+	return nil
+}
+
+// Status 默认输出中每个 sink 的暂停状态
+func (p *OutputPause) Status() []SinkPauseStatus {
+	defaultOutputMu.RLock()
+	defer defaultOutputMu.RUnlock()
+	out := make([]SinkPauseStatus, 0, len(defaultOutput.sinks))
+	for _, s := range defaultOutput.sinks {
+		out = append(out, s.pauseStatus())
+	}
+	return out
+}
+
+// RegisterAPI 注册暂停 / 恢复接口，sink 参数为空时作用于全部输出
+//
+//	GET  /api/outputs               每个输出是否暂停、缓冲和丢弃的事件数
+//	POST /api/outputs/pause?sink=   暂停输出
+//	POST /api/outputs/resume?sink=  恢复输出并补发缓冲的事件
+func (p *OutputPause) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/outputs", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, p.Status())
+	})
+	api.Handle("POST /api/outputs/pause", func(w http.ResponseWriter, r *http.Request) {
+		if err := p.Pause(r.URL.Query().Get("sink"), "api"); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, p.Status())
+	})
+	api.Handle("POST /api/outputs/resume", func(w http.ResponseWriter, r *http.Request) {
+		if err := p.Resume(r.URL.Query().Get("sink"), "api"); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, p.Status())
+	})
+}
+
+// PrintPauseEvent 默认的暂停 / 恢复输出
+func PrintPauseEvent(e PauseEvent) {
+	var summary string
+	if e.Paused {
+		summary = fmt.Sprintf("⏸️  [Outputs] 已暂停 %v（%s），事件将%s", e.Sinks, e.Reason,
+			map[string]string{PauseBuffer: "缓冲到恢复后补发", PauseDrop: "直接丢弃"}[outputPause.Policy])
+	} else {
+		summary = fmt.Sprintf("▶️  [Outputs] 已恢复 %v（%s），补发 %d 个事件，丢弃 %d 个", e.Sinks, e.Reason, e.Replayed, e.Dropped)
+	}
+	Emit(Event{Type: "output_pause", Summary: summary, Text: summary, Data: e})
+}
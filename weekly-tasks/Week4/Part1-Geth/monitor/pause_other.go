@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "context"
+
+// RunSignals 其它平台没有 SIGUSR2，只能通过 API 暂停 / 恢复输出
+func (p *OutputPause) RunSignals(ctx context.Context) {}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// RunSignals 每次收到 SIGUSR2 时切换全部输出：有任一 sink 暂停时全部恢复，否则全部暂停；直到 ctx 取消
+func (p *OutputPause) RunSignals(ctx context.Context) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR2)
+	defer signal.Stop(ch)
+	for {
+		select {
+		case <-ch:
+			paused := false
+			for _, s := range p.Status() {
+				paused = paused || s.Paused
+			}
+			var err error
+			if paused {
+				err = p.Resume("", "signal")
+			} else {
+				err = p.Pause("", "signal")
+			}
+			if err != nil {
+				log.Printf("⚠️  %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
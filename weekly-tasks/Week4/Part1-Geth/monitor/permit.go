@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ------------------------------------------------
+// 签名授权解码：从交易（包括 multicall 等内层调用）中找出 ERC-2612 / DAI permit、
+// Permit2 的 permit / permitTransferFrom，还原签名者把什么额度授权给了谁
+// ------------------------------------------------
+
+const (
+	// /api/permits 中列出的 spender 数量
+	PermitTopSpenders = 20
+)
+
+// permitMethodsABI permit 类函数，加入内置函数表（ERC-2612 permit 已在 builtinMethodsABI 中）
+const permitMethodsABI = `[
+	{"type":"function","name":"permit","inputs":[{"name":"holder","type":"address"},{"name":"spender","type":"address"},{"name":"nonce","type":"uint256"},{"name":"expiry","type":"uint256"},{"name":"allowed","type":"bool"},{"name":"v","type":"uint8"},{"name":"r","type":"bytes32"},{"name":"s","type":"bytes32"}]},
+	{"type":"function","name":"selfPermit","inputs":[{"name":"token","type":"address"},{"name":"value","type":"uint256"},{"name":"deadline","type":"uint256"},{"name":"v","type":"uint8"},{"name":"r","type":"bytes32"},{"name":"s","type":"bytes32"}]},
+	{"type":"function","name":"permit","inputs":[{"name":"owner","type":"address"},{"name":"permitSingle","type":"tuple","components":[{"name":"details","type":"tuple","components":[{"name":"token","type":"address"},{"name":"amount","type":"uint160"},{"name":"expiration","type":"uint48"},{"name":"nonce","type":"uint48"}]},{"name":"spender","type":"address"},{"name":"sigDeadline","type":"uint256"}]},{"name":"signature","type":"bytes"}]},
+	{"type":"function","name":"permit","inputs":[{"name":"owner","type":"address"},{"name":"permitBatch","type":"tuple","components":[{"name":"details","type":"tuple[]","components":[{"name":"token","type":"address"},{"name":"amount","type":"uint160"},{"name":"expiration","type":"uint48"},{"name":"nonce","type":"uint48"}]},{"name":"spender","type":"address"},{"name":"sigDeadline","type":"uint256"}]},{"name":"signature","type":"bytes"}]},
+	{"type":"function","name":"permitTransferFrom","inputs":[{"name":"permit","type":"tuple","components":[{"name":"permitted","type":"tuple","components":[{"name":"token","type":"address"},{"name":"amount","type":"uint256"}]},{"name":"nonce","type":"uint256"},{"name":"deadline","type":"uint256"}]},{"name":"transferDetails","type":"tuple","components":[{"name":"to","type":"address"},{"name":"requestedAmount","type":"uint256"}]},{"name":"owner","type":"address"},{"name":"signature","type":"bytes"}]},
+	{"type":"function","name":"permitTransferFrom","inputs":[{"name":"permit","type":"tuple","components":[{"name":"permitted","type":"tuple[]","components":[{"name":"token","type":"address"},{"name":"amount","type":"uint256"}]},{"name":"nonce","type":"uint256"},{"name":"deadline","type":"uint256"}]},{"name":"transferDetails","type":"tuple[]","components":[{"name":"to","type":"address"},{"name":"requestedAmount","type":"uint256"}]},{"name":"owner","type":"address"},{"name":"signature","type":"bytes"}]},
+	{"type":"function","name":"permitWitnessTransferFrom","inputs":[{"name":"permit","type":"tuple","components":[{"name":"permitted","type":"tuple","components":[{"name":"token","type":"address"},{"name":"amount","type":"uint256"}]},{"name":"nonce","type":"uint256"},{"name":"deadline","type":"uint256"}]},{"name":"transferDetails","type":"tuple","components":[{"name":"to","type":"address"},{"name":"requestedAmount","type":"uint256"}]},{"name":"owner","type":"address"},{"name":"witness","type":"bytes32"},{"name":"witnessTypeString","type":"string"},{"name":"signature","type":"bytes"}]}
+]`
+
+// permitSelectors 全部 permit 类函数的 selector，在 calldata 中都找不到时不用解码
+var permitSelectors = func() [][]byte {
+	out := [][]byte{selectorPermit}
+	for _, m := range mustParseABI(permitMethodsABI).Methods {
+		out = append(out, m.ID)
+	}
+	return out
+}()
+
+// 签名授权的类型
+const (
+	PermitERC2612        = "ERC-2612"
+	PermitDAI            = "DAI permit"
+	PermitSelf           = "selfPermit"
+	Permit2Allowance     = "Permit2"
+	Permit2Transfer      = "Permit2 transfer"
+	Permit2TransferBatch = "Permit2 transfer (batch)"
+)
+
+// SignedPermit 一个签名授权（批量授权按代币拆成多条）
+type SignedPermit struct {
+	Kind      string         `json:"kind"`
+	Owner     common.Address `json:"owner"` // 签名者
+	Token     common.Address `json:"token"`
+	Spender   common.Address `json:"spender"`   // 获得额度的地址；Permit2 transfer 为调用 Permit2 的合约
+	Recipient common.Address `json:"recipient"` // Permit2 transfer 的收款地址，代币直接转给它
+	Amount    *big.Int       `json:"amount"`
+	Unlimited bool           `json:"unlimited"`
+	Deadline  *big.Int       `json:"deadline,omitempty"`
+	Submitter common.Address `json:"submitter"` // 发送交易的地址，与 Owner 不同时说明签名交给了第三方提交
+}
+
+// Transfer 签名是否直接转走代币（而不是只授权额度）
+func (p SignedPermit) Transfer() bool {
+	return p.Kind == Permit2Transfer || p.Kind == Permit2TransferBatch
+}
+
+// DecodePermits 找出交易中的签名授权；from 为交易发送者
+func DecodePermits(abis *ABIRegistry, to *common.Address, data []byte, from common.Address) []SignedPermit {
+	if to == nil || !containsPermitSelector(data) {
+		return nil
+	}
+	call, ok := abis.DecodeCall(to, data)
+	if !ok {
+		return nil
+	}
+	var out []SignedPermit
+	var walk func(c *DecodedCall, target, caller common.Address)
+	walk = func(c *DecodedCall, target, caller common.Address) {
+		if c.Target != nil {
+			target = *c.Target
+		}
+		for _, p := range permitsOf(c, target, caller) {
+			p.Submitter = from
+			p.Unlimited = p.Amount.BitLen() >= UnlimitedApprovalBits
+			out = append(out, p)
+		}
+		for _, in := range c.Inner {
+			// multicall 对自己的调用保持 msg.sender；aggregate / execTransaction 由外层合约发出
+			next := caller
+			if in.Target != nil {
+				next = target
+			}
+			walk(in, target, next)
+		}
+	}
+	walk(call, *to, from)
+	return out
+}
+
+func containsPermitSelector(data []byte) bool {
+	for _, sel := range permitSelectors {
+		if bytes.Contains(data, sel) {
+			return true
+		}
+	}
+	return false
+}
+
+// permitsOf 单个调用中的签名授权；target 为被调用的合约，caller 为它看到的 msg.sender
+func permitsOf(c *DecodedCall, target, caller common.Address) []SignedPermit {
+	addr := func(name string) common.Address {
+		a, _ := c.Arg(name).(common.Address)
+		return a
+	}
+	amount := func(name string) *big.Int {
+		n, _ := c.Arg(name).(*big.Int)
+		return n
+	}
+
+	switch c.Name {
+	case "permit":
+		switch {
+		case c.Arg("permitSingle") != nil || c.Arg("permitBatch") != nil: // Permit2 AllowanceTransfer
+			if target != Permit2Address {
+				return nil
+			}
+			var out []SignedPermit
+			details := reflect.ValueOf(c.Arg("details"))
+			if details.Kind() == reflect.Struct {
+				details = reflect.ValueOf([]interface{}{details.Interface()})
+			}
+			for i := 0; i < details.Len(); i++ {
+				d := reflect.ValueOf(details.Index(i).Interface())
+				out = append(out, SignedPermit{
+					Kind: Permit2Allowance, Owner: addr("owner"), Spender: addr("spender"),
+					Token:    d.FieldByName("Token").Interface().(common.Address),
+					Amount:   d.FieldByName("Amount").Interface().(*big.Int),
+					Deadline: d.FieldByName("Expiration").Interface().(*big.Int),
+				})
+			}
+			return out
+		case c.Arg("holder") != nil: // DAI：allowed 为 true 时授权无限额度
+			p := SignedPermit{Kind: PermitDAI, Owner: addr("holder"), Spender: addr("spender"), Token: target, Amount: new(big.Int), Deadline: amount("expiry")}
+			if allowed, _ := c.Arg("allowed").(bool); allowed {
+				p.Amount = new(big.Int).Set(math.MaxBig256)
+			}
+			return []SignedPermit{p}
+		case c.Arg("value") != nil:
+			return []SignedPermit{{Kind: PermitERC2612, Owner: addr("owner"), Spender: addr("spender"), Token: target, Amount: amount("value"), Deadline: amount("deadline")}}
+		}
+
+	case "selfPermit": // Uniswap 路由：调用者给路由自己授权
+		if c.Arg("token") == nil {
+			return nil
+		}
+		return []SignedPermit{{Kind: PermitSelf, Owner: caller, Spender: target, Token: addr("token"), Amount: amount("value"), Deadline: amount("deadline")}}
+
+	case "permitTransferFrom", "permitWitnessTransferFrom": // Permit2 SignatureTransfer：spender 是调用 Permit2 的地址
+		if target != Permit2Address {
+			return nil
+		}
+		permitted, details := reflect.ValueOf(c.Arg("permitted")), reflect.ValueOf(c.Arg("transferDetails"))
+		if permitted.Kind() == reflect.Struct {
+			return []SignedPermit{{
+				Kind: Permit2Transfer, Owner: addr("owner"), Spender: caller, Deadline: amount("deadline"),
+				Token:     permitted.FieldByName("Token").Interface().(common.Address),
+				Amount:    details.FieldByName("RequestedAmount").Interface().(*big.Int),
+				Recipient: details.FieldByName("To").Interface().(common.Address),
+			}}
+		}
+		var out []SignedPermit
+		for i := 0; i < permitted.Len() && i < details.Len(); i++ {
+			out = append(out, SignedPermit{
+				Kind: Permit2TransferBatch, Owner: addr("owner"), Spender: caller, Deadline: amount("deadline"),
+				Token:     permitted.Index(i).FieldByName("Token").Interface().(common.Address),
+				Amount:    details.Index(i).FieldByName("RequestedAmount").Interface().(*big.Int),
+				Recipient: details.Index(i).FieldByName("To").Interface().(common.Address),
+			})
+		}
+		return out
+	}
+	return nil
+}
+
+// PermitStats 交易池中签名授权的统计：各类型的数量、第三方代为提交的比例、获得额度最多的 spender
+type PermitStats struct {
+	signer types.Signer
+	abis   *ABIRegistry
+
+	mu         sync.Mutex
+	total      uint64
+	thirdParty uint64
+	unlimited  uint64
+	byKind     map[string]uint64
+	bySpender  map[common.Address]uint64
+}
+
+// NewPermitStats 创建统计
+func NewPermitStats(signer types.Signer, abis *ABIRegistry) *PermitStats {
+	return &PermitStats{signer: signer, abis: abis, byKind: make(map[string]uint64), bySpender: make(map[common.Address]uint64)}
+}
+
+// Observe 解码交易池中的签名授权（只处理完整交易）
+func (s *PermitStats) Observe(msg BusMessage) {
+	if msg.Tx == nil || msg.Tx.To() == nil || !containsPermitSelector(msg.Tx.Data()) {
+		return
+	}
+	from, err := types.Sender(s.signer, msg.Tx)
+	if err != nil {
+		return
+	}
+	permits := DecodePermits(s.abis, msg.Tx.To(), msg.Tx.Data(), from)
+	if len(permits) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range permits {
+		s.total++
+		s.byKind[p.Kind]++
+		s.bySpender[p.Spender]++
+		if p.Submitter != p.Owner {
+			s.thirdParty++
+		}
+		if p.Unlimited {
+			s.unlimited++
+		}
+	}
+}
+
+// PermitSpenderCount 某个 spender 获得的签名授权次数
+type PermitSpenderCount struct {
+	Spender common.Address `json:"spender"`
+	Count   uint64         `json:"count"`
+}
+
+// PermitSummary /api/permits 的返回内容
+type PermitSummary struct {
+	Total      uint64               `json:"total"`
+	ThirdParty uint64               `json:"third_party"` // 由签名者以外的地址提交
+	Unlimited  uint64               `json:"unlimited"`
+	ByKind     map[string]uint64    `json:"by_kind"`
+	Spenders   []PermitSpenderCount `json:"top_spenders"`
+}
+
+// Summary 当前的统计
+func (s *PermitStats) Summary() PermitSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sum := PermitSummary{Total: s.total, ThirdParty: s.thirdParty, Unlimited: s.unlimited, ByKind: make(map[string]uint64, len(s.byKind))}
+	for k, n := range s.byKind {
+		sum.ByKind[k] = n
+	}
+	for addr, n := range s.bySpender {
+		sum.Spenders = append(sum.Spenders, PermitSpenderCount{Spender: addr, Count: n})
+	}
+	sort.Slice(sum.Spenders, func(i, j int) bool { return sum.Spenders[i].Count > sum.Spenders[j].Count })
+	if len(sum.Spenders) > PermitTopSpenders {
+		sum.Spenders = sum.Spenders[:PermitTopSpenders]
+	}
+	return sum
+}
+
+// RegisterAPI 注册签名授权统计接口
+//
+//	GET /api/permits 启动以来交易池中签名授权的统计
+func (s *PermitStats) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/permits", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.Summary())
+	})
+}
+
+// describePermit 一行描述，用于提醒
+func describePermit(p SignedPermit) string {
+	s := fmt.Sprintf("%s 签名 %s", p.Owner.Hex(), p.Kind)
+	if p.Transfer() {
+		s += fmt.Sprintf("，代币直接转给 %s", p.Recipient.Hex())
+	}
+	if p.Submitter != p.Owner {
+		s += fmt.Sprintf("，由 %s 提交", p.Submitter.Hex())
+	}
+	return s
+}
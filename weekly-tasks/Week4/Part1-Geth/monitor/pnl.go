@@ -0,0 +1,449 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	// API 默认返回的成交记录条数
+	PnLRecentTrades = 50
+)
+
+var (
+	wethDepositTopic    = crypto.Keccak256Hash([]byte("Deposit(address,uint256)"))
+	wethWithdrawalTopic = crypto.Keccak256Hash([]byte("Withdrawal(address,uint256)"))
+
+	// ethToken P&L 中 ETH 和 WETH 视为同一种资产，用零地址表示
+	ethToken = common.Address{}
+)
+
+// TradeLeg 一笔成交中某个代币的变化，Amount 为负表示卖出
+type TradeLeg struct {
+	Token  common.Address `json:"token"`
+	Symbol string         `json:"symbol"`
+	Amount string         `json:"amount"`
+	USD    float64        `json:"usd"`
+	Priced bool           `json:"priced"`
+
+	raw      *big.Int // 带符号的最小单位数量
+	decimals uint8
+}
+
+// Trade 关注地址的一笔 swap
+type Trade struct {
+	TxHash      common.Hash `json:"tx_hash"`
+	BlockNumber uint64      `json:"block_number"`
+	Time        uint64      `json:"time"`
+	Legs        []TradeLeg  `json:"legs"`
+	ValueUSD    float64     `json:"value_usd"`
+	RealizedUSD float64     `json:"realized_usd"`
+	FeeUSD      float64     `json:"fee_usd"`
+}
+
+// Position 持仓（API 输出）
+type Position struct {
+	Token         common.Address `json:"token"`
+	Symbol        string         `json:"symbol"`
+	Amount        string         `json:"amount"`
+	CostUSD       float64        `json:"cost_usd"`
+	RealizedUSD   float64        `json:"realized_usd"`
+	ValueUSD      float64        `json:"value_usd"`
+	UnrealizedUSD float64        `json:"unrealized_usd"`
+	Priced        bool           `json:"priced"` // 当前价格不可用时 value / unrealized 为 0
+}
+
+// PnLSummary 一个地址的 P&L 汇总（API 输出）
+type PnLSummary struct {
+	Address       common.Address `json:"address"`
+	Trades        int            `json:"trades"`
+	RealizedUSD   float64        `json:"realized_usd"` // 已扣除 gas 费
+	UnrealizedUSD float64        `json:"unrealized_usd"`
+	FeesUSD       float64        `json:"fees_usd"`
+	Positions     []Position     `json:"positions"`
+	RecentTrades  []Trade        `json:"recent_trades,omitempty"`
+}
+
+// pnlPosition 平均成本法下的持仓
+type pnlPosition struct {
+	qty      *big.Int
+	cost     float64 // 当前持仓的总成本（USD）
+	realized float64
+}
+
+type pnlAccount struct {
+	address   common.Address
+	positions map[common.Address]*pnlPosition
+	feesUSD   float64
+	trades    int
+}
+
+func newPnLAccount(addr common.Address) *pnlAccount {
+	return &pnlAccount{address: addr, positions: make(map[common.Address]*pnlPosition)}
+}
+
+func (a *pnlAccount) position(token common.Address) *pnlPosition {
+	p, ok := a.positions[token]
+	if !ok {
+		p = &pnlPosition{qty: new(big.Int)}
+		a.positions[token] = p
+	}
+	return p
+}
+
+// PnLTracker 跟踪关注的交易地址执行的所有 swap，用平均成本法维护每个代币的成本，
+// 计算已实现 / 未实现盈亏，结果持久化到 SQLite 并通过 REST API 查询
+//
+// 一笔交易中关注地址的代币净变化（ERC-20 Transfer、WETH 包装 / 解包、交易附带的 ETH）
+// 同时有转出和转入时视为一笔 swap，因此经过聚合器 / 路由合约的成交也能识别
+type PnLTracker struct {
+	client *ethclient.Client
+	store  *PnLStore
+	tokens *TokenCache
+	prices *PriceOracle
+	weth   common.Address
+
+	mu       sync.Mutex
+	accounts map[common.Address]*pnlAccount
+}
+
+// NewPnLTracker 创建 P&L 跟踪并从数据库恢复之前的持仓
+func NewPnLTracker(client *ethclient.Client, store *PnLStore, tokens *TokenCache, prices *PriceOracle, weth common.Address, addrs []common.Address) (*PnLTracker, error) {
+	t := &PnLTracker{
+		client:   client,
+		store:    store,
+		tokens:   tokens,
+		prices:   prices,
+		weth:     weth,
+		accounts: make(map[common.Address]*pnlAccount),
+	}
+	for _, addr := range addrs {
+		acc, err := store.Load(addr)
+		if err != nil {
+			return nil, err
+		}
+		t.accounts[addr] = acc
+	}
+	return t, nil
+}
+
+// OnBlock 实现 BlockAnalyzer
+func (t *PnLTracker) OnBlock(data *BlockData) {
+	for i, tx := range data.Block.Transactions() {
+		acc, ok := t.accounts[data.Senders[i]]
+		if !ok || data.Receipts[i].Status != types.ReceiptStatusSuccessful {
+			continue
+		}
+		if err := t.processTx(acc, data.Block, tx, data.Receipts[i]); err != nil {
+			log.Printf("⚠️  [P&L] 处理交易 %s 失败: %v", tx.Hash().Hex(), err)
+		}
+	}
+}
+
+// LogFilters 实现 LogFilterer：swap 至少有一侧是关注地址转出或收到的 ERC-20 Transfer
+func (t *PnLTracker) LogFilters() []ethereum.FilterQuery {
+	addrs := make([]common.Address, 0, len(t.accounts))
+	for addr := range t.accounts {
+		addrs = append(addrs, addr)
+	}
+	topics := addressTopics(addrs)
+	return []ethereum.FilterQuery{
+		{Topics: [][]common.Hash{{erc20TransferTopic}, topics}},
+		{Topics: [][]common.Hash{{erc20TransferTopic}, nil, topics}},
+	}
+}
+
+func (t *PnLTracker) processTx(acc *pnlAccount, block *types.Block, tx *types.Transaction, receipt *types.Receipt) error {
+	deltas := t.netDeltas(acc.address, tx, receipt)
+	var in, out bool
+	for _, d := range deltas {
+		in = in || d.Sign() > 0
+		out = out || d.Sign() < 0
+	}
+	if !in || !out {
+		return nil // 不是 swap
+	}
+	if seen, err := t.store.HasTrade(acc.address, tx.Hash()); err != nil || seen {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), CONNECTION_TIMEOUT)
+	defer cancel()
+	trade := &Trade{TxHash: tx.Hash(), BlockNumber: block.NumberU64(), Time: block.Time()}
+	for token, d := range deltas {
+		if d.Sign() == 0 {
+			continue
+		}
+		trade.Legs = append(trade.Legs, t.valueLeg(ctx, token, d))
+	}
+	sort.Slice(trade.Legs, func(i, j int) bool { return trade.Legs[i].raw.Sign() < trade.Legs[j].raw.Sign() })
+	if ethPrice, ok := t.prices.PriceWait(ctx, ethToken); ok {
+		trade.FeeUSD = weiToEther(txFee(receipt)) * ethPrice
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.apply(acc, trade)
+	if err := t.store.SaveTrade(acc, trade); err != nil {
+		return err
+	}
+	Emit(Event{Type: "pnl_trade", Key: eventKey(acc.address, trade.TxHash), Data: trade, Text: fmt.Sprintf("💹 [P&L] %s 在区块 %d 成交 %s | 已实现 %+.2f USD",
+		acc.address.Hex(), trade.BlockNumber, formatUSD(trade.ValueUSD), trade.RealizedUSD)})
+	return nil
+}
+
+// netDeltas 关注地址在这笔交易中各代币的净变化
+func (t *PnLTracker) netDeltas(addr common.Address, tx *types.Transaction, receipt *types.Receipt) map[common.Address]*big.Int {
+	return netTokenDeltas(addr, t.weth, tx, receipt)
+}
+
+// netTokenDeltas 地址在一笔交易中各代币的净变化（ERC-20 Transfer、WETH 包装 / 解包、交易附带的 ETH），
+// WETH 与 ETH 合并记在 ethToken 下
+func netTokenDeltas(addr, weth common.Address, tx *types.Transaction, receipt *types.Receipt) map[common.Address]*big.Int {
+	deltas := make(map[common.Address]*big.Int)
+	add := func(token common.Address, v *big.Int) {
+		if token == weth {
+			token = ethToken
+		}
+		if d, ok := deltas[token]; ok {
+			d.Add(d, v)
+		} else {
+			deltas[token] = new(big.Int).Set(v)
+		}
+	}
+	if tx.Value().Sign() > 0 {
+		add(ethToken, new(big.Int).Neg(tx.Value()))
+	}
+	for _, l := range receipt.Logs {
+		switch {
+		case len(l.Topics) == 3 && l.Topics[0] == erc20TransferTopic && len(l.Data) == 32:
+			from, to := common.BytesToAddress(l.Topics[1].Bytes()), common.BytesToAddress(l.Topics[2].Bytes())
+			amount := new(big.Int).SetBytes(l.Data)
+			if to == addr {
+				add(l.Address, amount)
+			}
+			if from == addr {
+				add(l.Address, new(big.Int).Neg(amount))
+			}
+		// WETH 的 deposit 不产生 Transfer：自己包装时 ETH(-) 和 WETH(+) 在合并后相互抵消
+		case l.Address == weth && len(l.Topics) == 2 && l.Topics[0] == wethDepositTopic && len(l.Data) == 32:
+			if common.BytesToAddress(l.Topics[1].Bytes()) == addr {
+				add(ethToken, new(big.Int).SetBytes(l.Data))
+			}
+		// 路由合约把 WETH 解包后转给用户的 ETH 是内部转账，这里用 Withdrawal 事件近似
+		case l.Address == weth && len(l.Topics) == 2 && l.Topics[0] == wethWithdrawalTopic && len(l.Data) == 32:
+			if common.BytesToAddress(l.Topics[1].Bytes()) != addr {
+				add(ethToken, new(big.Int).SetBytes(l.Data))
+			}
+		}
+	}
+	return deltas
+}
+
+// valueLeg 按当前价格给一个代币变化估值
+func (t *PnLTracker) valueLeg(ctx context.Context, token common.Address, raw *big.Int) TradeLeg {
+	leg := TradeLeg{Token: token, raw: raw, Symbol: "ETH", decimals: 18}
+	if token != ethToken {
+		meta, err := t.tokens.Get(ctx, token)
+		if err != nil || !meta.HasDecimals {
+			leg.Symbol, leg.Amount = meta.Label(), raw.String()
+			return leg
+		}
+		leg.Symbol, leg.decimals = meta.Label(), meta.Decimals
+	}
+	leg.Amount = formatUnits(raw, leg.decimals)
+	if price, ok := t.prices.PriceWait(ctx, token); ok {
+		leg.USD = unitsToFloat(new(big.Int).Abs(raw), leg.decimals) * price
+		leg.Priced = true
+	}
+	return leg
+}
+
+// apply 用平均成本法更新持仓（调用方持有锁）
+//   - 成交价值 = 转入、转出两侧已定价部分的较大值；两侧都无法定价时按卖出部分的成本结转，不产生盈亏
+//   - 卖出的数量超过已知持仓时（例如在跟踪开始前买入），超出部分按成交价作为成本，不产生盈亏
+func (t *PnLTracker) apply(acc *pnlAccount, trade *Trade) {
+	var inUSD, outUSD float64
+	var inUnpriced, outUnpriced int
+	for _, leg := range trade.Legs {
+		switch {
+		case leg.raw.Sign() > 0 && leg.Priced:
+			inUSD += leg.USD
+		case leg.raw.Sign() > 0:
+			inUnpriced++
+		case leg.Priced:
+			outUSD += leg.USD
+		default:
+			outUnpriced++
+		}
+	}
+	value := max(inUSD, outUSD)
+	priced := inUSD > 0 || outUSD > 0
+
+	// 先处理卖出，两侧都无法定价时成交价值 = 卖出部分的成本
+	var basisTotal float64
+	for _, leg := range trade.Legs {
+		if leg.raw.Sign() >= 0 {
+			continue
+		}
+		proceeds := leg.USD
+		if !leg.Priced && outUnpriced > 0 {
+			proceeds = max(value-outUSD, 0) / float64(outUnpriced)
+		}
+		qty := new(big.Int).Neg(leg.raw)
+		p := acc.position(leg.Token)
+		var basis float64
+		if p.qty.Cmp(qty) >= 0 {
+			basis = p.cost * ratio(qty, p.qty)
+			p.cost -= basis
+			p.qty.Sub(p.qty, qty)
+		} else {
+			unknown := new(big.Int).Sub(qty, p.qty)
+			basis = p.cost + proceeds*ratio(unknown, qty)
+			p.cost = 0
+			p.qty.SetInt64(0)
+		}
+		if !priced {
+			proceeds = basis
+		}
+		basisTotal += basis
+		p.realized += proceeds - basis
+		trade.RealizedUSD += proceeds - basis
+	}
+	if !priced {
+		value = basisTotal
+	}
+
+	// 再处理买入，成本按成交价值分配
+	for _, leg := range trade.Legs {
+		if leg.raw.Sign() <= 0 {
+			continue
+		}
+		cost := leg.USD
+		if !leg.Priced && inUnpriced > 0 {
+			cost = max(value-inUSD, 0) / float64(inUnpriced)
+		}
+		p := acc.position(leg.Token)
+		p.qty.Add(p.qty, leg.raw)
+		p.cost += cost
+	}
+
+	trade.ValueUSD = value
+	trade.RealizedUSD -= trade.FeeUSD
+	acc.feesUSD += trade.FeeUSD
+	acc.trades++
+}
+
+// ratio a / b（b 为 0 时返回 1）
+func ratio(a, b *big.Int) float64 {
+	if b.Sign() == 0 {
+		return 1
+	}
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(a), new(big.Float).SetInt(b)).Float64()
+	return f
+}
+
+// unitsToFloat 最小单位数量按 decimals 换算为浮点数（用于估值）
+func unitsToFloat(amount *big.Int, decimals uint8) float64 {
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(amount),
+		new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))).Float64()
+	return f
+}
+
+// Summary 某个地址的 P&L 汇总，未实现盈亏按当前缓存的价格计算
+func (t *PnLTracker) Summary(addr common.Address) (*PnLSummary, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	acc, ok := t.accounts[addr]
+	if !ok {
+		return nil, false
+	}
+	s := &PnLSummary{Address: addr, Trades: acc.trades, FeesUSD: acc.feesUSD, Positions: []Position{}}
+	s.RealizedUSD -= acc.feesUSD
+	for token, p := range acc.positions {
+		s.RealizedUSD += p.realized
+		if p.qty.Sign() == 0 && p.realized == 0 {
+			continue
+		}
+		pos := Position{Token: token, Symbol: "ETH", CostUSD: p.cost, RealizedUSD: p.realized}
+		decimals := uint8(18)
+		if token != ethToken {
+			meta, ok := t.tokens.Lookup(token)
+			pos.Symbol = meta.Label()
+			if !ok || !meta.HasDecimals {
+				pos.Amount = p.qty.String()
+				s.Positions = append(s.Positions, pos)
+				continue
+			}
+			decimals = meta.Decimals
+		}
+		pos.Amount = formatUnits(p.qty, decimals)
+		if price, ok := t.prices.Price(token); ok {
+			pos.Priced = true
+			pos.ValueUSD = unitsToFloat(p.qty, decimals) * price
+			pos.UnrealizedUSD = pos.ValueUSD - p.cost
+			s.UnrealizedUSD += pos.UnrealizedUSD
+		}
+		s.Positions = append(s.Positions, pos)
+	}
+	sort.Slice(s.Positions, func(i, j int) bool { return s.Positions[i].ValueUSD > s.Positions[j].ValueUSD })
+	return s, true
+}
+
+// RegisterAPI 注册 P&L 查询接口
+//
+//	GET /api/pnl                 所有跟踪地址的汇总
+//	GET /api/pnl/{address}?limit 单个地址的汇总、持仓和最近的成交记录
+func (t *PnLTracker) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/pnl", func(w http.ResponseWriter, r *http.Request) {
+		t.mu.Lock()
+		addrs := make([]common.Address, 0, len(t.accounts))
+		for addr := range t.accounts {
+			addrs = append(addrs, addr)
+		}
+		t.mu.Unlock()
+
+		list := make([]*PnLSummary, 0, len(addrs))
+		for _, addr := range addrs {
+			if s, ok := t.Summary(addr); ok {
+				list = append(list, s)
+			}
+		}
+		writeJSON(w, http.StatusOK, list)
+	})
+	api.Handle("GET /api/pnl/{address}", func(w http.ResponseWriter, r *http.Request) {
+		if !common.IsHexAddress(r.PathValue("address")) {
+			writeError(w, http.StatusBadRequest, "地址格式错误")
+			return
+		}
+		addr := common.HexToAddress(r.PathValue("address"))
+		s, ok := t.Summary(addr)
+		if !ok {
+			writeError(w, http.StatusNotFound, "该地址不在 pnl.addresses 中")
+			return
+		}
+		limit := PnLRecentTrades
+		if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 {
+			limit = v
+		}
+		trades, err := t.store.RecentTrades(addr, limit)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		s.RecentTrades = trades
+		writeJSON(w, http.StatusOK, s)
+	})
+}
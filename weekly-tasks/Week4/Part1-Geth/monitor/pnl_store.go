@@ -0,0 +1,147 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const pnlSchema = `
+CREATE TABLE IF NOT EXISTS pnl_accounts (
+	address  TEXT PRIMARY KEY,
+	fees_usd REAL    NOT NULL,
+	trades   INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS pnl_positions (
+	address      TEXT NOT NULL,
+	token        TEXT NOT NULL,
+	quantity     TEXT NOT NULL,
+	cost_usd     REAL NOT NULL,
+	realized_usd REAL NOT NULL,
+	PRIMARY KEY (address, token)
+);
+CREATE TABLE IF NOT EXISTS pnl_trades (
+	address      TEXT    NOT NULL,
+	tx_hash      TEXT    NOT NULL,
+	block_number INTEGER NOT NULL,
+	time         INTEGER NOT NULL,
+	legs         TEXT    NOT NULL,
+	value_usd    REAL    NOT NULL,
+	realized_usd REAL    NOT NULL,
+	fee_usd      REAL    NOT NULL,
+	PRIMARY KEY (address, tx_hash)
+);
+CREATE INDEX IF NOT EXISTS pnl_trades_block ON pnl_trades (address, block_number);`
+
+// PnLStore P&L 的 SQLite 存储，和事件索引共用同一个数据库文件
+type PnLStore struct {
+	db *sql.DB
+}
+
+func OpenPnLStore(path string) (*PnLStore, error) {
+	db, err := openSQLite(path, pnlSchema)
+	if err != nil {
+		return nil, err
+	}
+	return &PnLStore{db: db}, nil
+}
+
+// Load 读取某个地址的账户汇总和持仓
+func (s *PnLStore) Load(addr common.Address) (*pnlAccount, error) {
+	acc := newPnLAccount(addr)
+	err := s.db.QueryRow(`SELECT fees_usd, trades FROM pnl_accounts WHERE address = ?`, addr.Hex()).
+		Scan(&acc.feesUSD, &acc.trades)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("读取 P&L 账户失败: %w", err)
+	}
+	rows, err := s.db.Query(`SELECT token, quantity, cost_usd, realized_usd FROM pnl_positions WHERE address = ?`, addr.Hex())
+	if err != nil {
+		return nil, fmt.Errorf("读取持仓失败: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var token, qty string
+		p := &pnlPosition{}
+		if err := rows.Scan(&token, &qty, &p.cost, &p.realized); err != nil {
+			return nil, err
+		}
+		p.qty, _ = new(big.Int).SetString(qty, 10)
+		if p.qty == nil {
+			p.qty = new(big.Int)
+		}
+		acc.positions[common.HexToAddress(token)] = p
+	}
+	return acc, rows.Err()
+}
+
+// HasTrade 交易是否已经记录过（重组后同一笔交易再次出现时不重复计算）
+func (s *PnLStore) HasTrade(addr common.Address, tx common.Hash) (bool, error) {
+	var n int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM pnl_trades WHERE address = ? AND tx_hash = ?`, addr.Hex(), tx.Hex()).Scan(&n)
+	return n > 0, err
+}
+
+// SaveTrade 在一个事务中写入成交记录、受影响的持仓和账户汇总
+func (s *PnLStore) SaveTrade(acc *pnlAccount, t *Trade) error {
+	legs, err := json.Marshal(t.Legs)
+	if err != nil {
+		return err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT INTO pnl_trades
+		(address, tx_hash, block_number, time, legs, value_usd, realized_usd, fee_usd) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		acc.address.Hex(), t.TxHash.Hex(), t.BlockNumber, t.Time, string(legs), t.ValueUSD, t.RealizedUSD, t.FeeUSD); err != nil {
+		return fmt.Errorf("写入成交记录失败: %w", err)
+	}
+	for _, leg := range t.Legs {
+		p := acc.positions[leg.Token]
+		if _, err := tx.Exec(`INSERT INTO pnl_positions (address, token, quantity, cost_usd, realized_usd) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT (address, token) DO UPDATE SET
+			quantity = excluded.quantity, cost_usd = excluded.cost_usd, realized_usd = excluded.realized_usd`,
+			acc.address.Hex(), leg.Token.Hex(), p.qty.String(), p.cost, p.realized); err != nil {
+			return fmt.Errorf("更新持仓失败: %w", err)
+		}
+	}
+	if _, err := tx.Exec(`INSERT INTO pnl_accounts (address, fees_usd, trades) VALUES (?, ?, ?)
+		ON CONFLICT (address) DO UPDATE SET fees_usd = excluded.fees_usd, trades = excluded.trades`,
+		acc.address.Hex(), acc.feesUSD, acc.trades); err != nil {
+		return fmt.Errorf("更新 P&L 账户失败: %w", err)
+	}
+	return tx.Commit()
+}
+
+// RecentTrades 最近的成交记录，按区块倒序
+func (s *PnLStore) RecentTrades(addr common.Address, limit int) ([]Trade, error) {
+	rows, err := s.db.Query(`SELECT tx_hash, block_number, time, legs, value_usd, realized_usd, fee_usd
+		FROM pnl_trades WHERE address = ? ORDER BY block_number DESC LIMIT ?`, addr.Hex(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询成交记录失败: %w", err)
+	}
+	defer rows.Close()
+	trades := []Trade{}
+	for rows.Next() {
+		var t Trade
+		var hash, legs string
+		if err := rows.Scan(&hash, &t.BlockNumber, &t.Time, &legs, &t.ValueUSD, &t.RealizedUSD, &t.FeeUSD); err != nil {
+			return nil, err
+		}
+		t.TxHash = common.HexToHash(hash)
+		if err := json.Unmarshal([]byte(legs), &t.Legs); err != nil {
+			return nil, err
+		}
+		trades = append(trades, t)
+	}
+	return trades, rows.Err()
+}
+
+func (s *PnLStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ------------------------------------------------
+// 池子状态缓存：配置的 Uniswap V2 / V3 池子的储备 / 价格 / 流动性保存在内存中，
+// 启动时用 eth_call 同步一次，之后只根据 Sync / Swap / Mint / Burn 日志更新，不再每个区块查询
+// ------------------------------------------------
+
+const (
+	// 日志订阅中断后的重连间隔，重连后重新同步全部池子（中断期间的日志已经丢失）
+	PoolResubscribeDelay = 5 * time.Second
+)
+
+var (
+	uniV3MintTopic = crypto.Keccak256Hash([]byte("Mint(address,address,int24,int24,uint128,uint256,uint256)"))
+
+	selectorFee = common.FromHex("0xddca3f43") // fee()
+)
+
+// PoolsConfig 需要维护状态的池子
+type PoolsConfig struct {
+	Addresses []string `json:"addresses"` // Uniswap V2 / V3 池子地址，版本自动识别
+}
+
+// PoolState 池子的状态
+// V2 使用 Reserve0 / Reserve1；V3 使用 SqrtPriceX96 / Liquidity / Tick（Liquidity 只是当前 tick 区间的流动性）
+type PoolState struct {
+	Pool         common.Address `json:"pool"`
+	V3           bool           `json:"v3"`
+	Token0       common.Address `json:"token0"`
+	Token1       common.Address `json:"token1"`
+	Fee          uint32         `json:"fee,omitempty"` // V3 手续费档位（百万分之一）
+	Reserve0     *big.Int       `json:"reserve0,omitempty"`
+	Reserve1     *big.Int       `json:"reserve1,omitempty"`
+	SqrtPriceX96 *big.Int       `json:"sqrt_price_x96,omitempty"`
+	Liquidity    *big.Int       `json:"liquidity,omitempty"`
+	Tick         int64          `json:"tick"`
+	Price        float64        `json:"price"`   // 1 个 token0 最小单位值多少 token1 最小单位
+	Block        uint64         `json:"block"`   // 最近一次更新所在的区块
+	Updates      uint64         `json:"updates"` // 由日志更新的次数
+
+	synced uint64 // eth_call 同步时的区块，不晚于该区块的日志已经包含在状态中
+}
+
+// updatePrice 根据储备或 sqrtPrice 重新计算 Price
+func (s *PoolState) updatePrice() {
+	if s.V3 {
+		p, _ := new(big.Float).Quo(new(big.Float).SetInt(s.SqrtPriceX96), new(big.Float).SetInt(new(big.Int).Lsh(common.Big1, 96))).Float64()
+		s.Price = p * p
+		return
+	}
+	s.Price = 0
+	if s.Reserve0.Sign() > 0 {
+		s.Price = ratio(s.Reserve1, s.Reserve0)
+	}
+}
+
+// apply 用池子的一条日志更新状态，其它事件忽略
+func (s *PoolState) apply(l types.Log) {
+	switch {
+	case !s.V3 && len(l.Topics) == 1 && l.Topics[0] == uniV2SyncTopic && len(l.Data) == 2*32:
+		// V2 的 Swap / Mint / Burn 之后都会紧跟一条 Sync，储备以 Sync 为准
+		s.Reserve0, s.Reserve1 = word(l.Data, 0), word(l.Data, 1)
+	case s.V3 && len(l.Topics) == 3 && l.Topics[0] == uniV3SwapTopic && len(l.Data) == 5*32:
+		s.SqrtPriceX96, s.Liquidity, s.Tick = word(l.Data, 2), word(l.Data, 3), signedWord(l.Data, 4).Int64()
+	case s.V3 && len(l.Topics) == 4 && l.Topics[0] == uniV3MintTopic && len(l.Data) == 4*32:
+		s.addLiquidity(l.Topics[2], l.Topics[3], word(l.Data, 1))
+	case s.V3 && len(l.Topics) == 4 && l.Topics[0] == uniV3BurnTopic && len(l.Data) == 3*32:
+		s.addLiquidity(l.Topics[2], l.Topics[3], new(big.Int).Neg(word(l.Data, 0)))
+	default:
+		return
+	}
+	s.updatePrice()
+	s.Block = l.BlockNumber
+	s.Updates++
+}
+
+// addLiquidity 区间 [tickLower, tickUpper) 包含当前 tick 时，Mint / Burn 改变当前的流动性（与池子合约的判断相同）
+func (s *PoolState) addLiquidity(lower, upper common.Hash, delta *big.Int) {
+	tickLower, tickUpper := signedWord(lower.Bytes(), 0).Int64(), signedWord(upper.Bytes(), 0).Int64()
+	if s.Tick >= tickLower && s.Tick < tickUpper {
+		s.Liquidity = new(big.Int).Add(s.Liquidity, delta)
+	}
+}
+
+// queryPoolState 用 eth_call 查询池子在 block（nil 为最新区块）的状态：V2 查询 getReserves()，V3 查询 slot0() 和 liquidity()
+// 池子不存在或调用失败时返回错误
+func queryPoolState(ctx context.Context, client *ethclient.Client, pool common.Address, v3 bool, block *big.Int) (*PoolState, error) {
+	call := func(sel []byte, words int) ([]byte, error) {
+		var out []byte
+		err := Retry(ctx, "eth_call", func() (err error) {
+			out, err = client.CallContract(ctx, ethereum.CallMsg{To: &pool, Data: sel}, block)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(out) < words*32 {
+			return nil, fmt.Errorf("返回数据长度 %d 不足（不是 Uniswap 池子？）", len(out))
+		}
+		return out, nil
+	}
+	st := &PoolState{Pool: pool, V3: v3}
+	if !v3 {
+		out, err := call(selectorGetReserves, 2)
+		if err != nil {
+			return nil, err
+		}
+		st.Reserve0, st.Reserve1 = word(out, 0), word(out, 1)
+	} else {
+		slot0, err := call(selectorSlot0, 2)
+		if err != nil {
+			return nil, err
+		}
+		liquidity, err := call(selectorLiquidity, 1)
+		if err != nil {
+			return nil, err
+		}
+		st.SqrtPriceX96, st.Tick, st.Liquidity = word(slot0, 0), signedWord(slot0, 1).Int64(), word(liquidity, 0)
+	}
+	st.updatePrice()
+	return st, nil
+}
+
+// PoolStateCache 维护配置的池子状态，供价格和价格冲击计算直接读取
+type PoolStateCache struct {
+	client *ethclient.Client
+	values *Valuator
+	pools  []common.Address
+
+	mu     sync.RWMutex
+	states map[common.Address]*PoolState
+}
+
+// NewPoolStateCache 创建缓存，Run 之后才有数据
+func NewPoolStateCache(client *ethclient.Client, values *Valuator, pools []common.Address) *PoolStateCache {
+	return &PoolStateCache{
+		client: client,
+		values: values,
+		pools:  pools,
+		states: make(map[common.Address]*PoolState),
+	}
+}
+
+// State 池子当前状态的副本，池子不在配置中或尚未同步时返回 false
+// c 为 nil 时总是返回 false
+func (c *PoolStateCache) State(pool common.Address) (PoolState, bool) {
+	if c == nil {
+		return PoolState{}, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	st, ok := c.states[pool]
+	if !ok {
+		return PoolState{}, false
+	}
+	return *st, true
+}
+
+// States 全部已同步池子的状态，按地址排序
+func (c *PoolStateCache) States() []PoolState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make([]PoolState, 0, len(c.states))
+	for _, st := range c.states {
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Pool.Hex() < out[j].Pool.Hex() })
+	return out
+}
+
+// Run 订阅池子的 Sync / Swap / Mint / Burn 日志更新状态，订阅中断后重连，直到 ctx 取消
+func (c *PoolStateCache) Run(ctx context.Context) {
+	for {
+		if err := c.follow(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("⚠️  池子日志订阅中断: %v（%s 后重连）", err, PoolResubscribeDelay)
+		}
+		select {
+		case <-time.After(PoolResubscribeDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// follow 先订阅再同步全部池子：同步期间到达的日志在 channel 中排队，已经包含在同步结果中的按区块号跳过
+func (c *PoolStateCache) follow(ctx context.Context) error {
+	logs := make(chan types.Log, 256)
+	query := ethereum.FilterQuery{
+		Addresses: c.pools,
+		Topics:    [][]common.Hash{{uniV2SyncTopic, uniV3SwapTopic, uniV3MintTopic, uniV3BurnTopic}},
+	}
+	sub, err := c.client.SubscribeFilterLogs(ctx, query, logs)
+	if err != nil {
+		return fmt.Errorf("订阅池子日志失败: %w", err)
+	}
+	defer sub.Unsubscribe()
+	c.syncAll(ctx)
+	for {
+		select {
+		case l := <-logs:
+			c.handleLog(ctx, l)
+		case err := <-sub.Err():
+			return err
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// handleLog 应用一条日志；重组撤销的日志无法回滚增量，重新同步该池子
+func (c *PoolStateCache) handleLog(ctx context.Context, l types.Log) {
+	if l.Removed {
+		c.sync(ctx, l.Address)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st, ok := c.states[l.Address]
+	if !ok || l.BlockNumber <= st.synced {
+		return
+	}
+	st.apply(l)
+}
+
+// syncAll 用 eth_call 同步全部池子
+func (c *PoolStateCache) syncAll(ctx context.Context) {
+	for _, pool := range c.pools {
+		c.sync(ctx, pool)
+	}
+	c.mu.RLock()
+	n := len(c.states)
+	c.mu.RUnlock()
+	fmt.Printf("💧 池子状态已同步: %d / %d 个池子\n", n, len(c.pools))
+}
+
+// sync 在最新区块上查询池子的完整状态，先按 V3 查询，失败再按 V2；第一次同步时顺便查询代币和手续费
+func (c *PoolStateCache) sync(ctx context.Context, pool common.Address) {
+	callCtx, cancel := context.WithTimeout(ctx, CONNECTION_TIMEOUT)
+	defer cancel()
+	head, err := blockNumberRetry(callCtx, c.client)
+	if err != nil {
+		log.Printf("⚠️  同步池子 %s 失败: %v", pool.Hex(), err)
+		return
+	}
+	block := new(big.Int).SetUint64(head)
+
+	c.mu.RLock()
+	prev, known := c.states[pool]
+	c.mu.RUnlock()
+	var st *PoolState
+	if known {
+		st, err = queryPoolState(callCtx, c.client, pool, prev.V3, block)
+	} else if st, err = queryPoolState(callCtx, c.client, pool, true, block); err != nil {
+		st, err = queryPoolState(callCtx, c.client, pool, false, block)
+	}
+	if err != nil {
+		log.Printf("⚠️  同步池子 %s 失败: %v", pool.Hex(), err)
+		return
+	}
+
+	if known {
+		st.Token0, st.Token1, st.Fee, st.Updates = prev.Token0, prev.Token1, prev.Fee, prev.Updates
+	} else {
+		tokens, ok := c.values.PoolTokens(pool)
+		if !ok {
+			log.Printf("⚠️  同步池子 %s 失败: 无法查询 token0 / token1", pool.Hex())
+			return
+		}
+		st.Token0, st.Token1 = tokens[0], tokens[1]
+		if st.V3 {
+			out, err := callContractRetry(callCtx, c.client, ethereum.CallMsg{To: &pool, Data: selectorFee}, block)
+			if err != nil || len(out) < 32 {
+				log.Printf("⚠️  同步池子 %s 失败: 无法查询手续费档位", pool.Hex())
+				return
+			}
+			st.Fee = uint32(word(out, 0).Uint64())
+		}
+	}
+	st.Block, st.synced = head, head
+
+	c.mu.Lock()
+	c.states[pool] = st
+	c.mu.Unlock()
+}
+
+// Venues 已同步的池子作为价差扫描的场所（当前状态的快照）
+func (c *PoolStateCache) Venues() []PriceVenue {
+	states := c.States()
+	out := make([]PriceVenue, len(states))
+	for i, st := range states {
+		out[i] = uniswapVenue{st}
+	}
+	return out
+}
+
+// uniswapVenue Uniswap 池子的边际兑换率：当前价格扣除手续费
+type uniswapVenue struct {
+	state PoolState
+}
+
+func (v uniswapVenue) Name() string {
+	if v.state.V3 {
+		return fmt.Sprintf("UniV3(%.2f%%) %s", float64(v.state.Fee)/1e4, v.state.Pool.Hex())
+	}
+	return "UniV2 " + v.state.Pool.Hex()
+}
+
+func (v uniswapVenue) Tokens() []common.Address {
+	return []common.Address{v.state.Token0, v.state.Token1}
+}
+
+func (v uniswapVenue) Rate(tokenIn, tokenOut common.Address) (float64, bool) {
+	st := v.state
+	if st.Price <= 0 {
+		return 0, false
+	}
+	fee := uint32(UniV2FeePpm)
+	if st.V3 {
+		fee = st.Fee
+	}
+	keep := 1 - float64(fee)/1e6
+	switch {
+	case tokenIn == st.Token0 && tokenOut == st.Token1:
+		return st.Price * keep, true
+	case tokenIn == st.Token1 && tokenOut == st.Token0:
+		return keep / st.Price, true
+	}
+	return 0, false
+}
+
+// RegisterAPI 注册池子状态查询接口
+//
+//	GET /api/pools           全部池子的状态
+//	GET /api/pools/{address} 单个池子的状态
+func (c *PoolStateCache) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/pools", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, c.States())
+	})
+	api.Handle("GET /api/pools/{address}", func(w http.ResponseWriter, r *http.Request) {
+		addr := r.PathValue("address")
+		if !common.IsHexAddress(addr) {
+			writeError(w, http.StatusBadRequest, "地址格式错误")
+			return
+		}
+		st, ok := c.State(common.HexToAddress(addr))
+		if !ok {
+			writeError(w, http.StatusNotFound, "池子不在配置中或尚未同步")
+			return
+		}
+		writeJSON(w, http.StatusOK, st)
+	})
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	// 默认每隔多少个区块统计一次持仓
+	DefaultPortfolioEvery = 10
+)
+
+var selectorBalanceOf = common.FromHex("0x70a08231") // balanceOf(address)
+
+// AssetBalance 某个资产的余额
+type AssetBalance struct {
+	Token  common.Address `json:"token"` // 零地址表示 ETH
+	Symbol string         `json:"symbol"`
+	Amount string         `json:"amount"`
+	USD    float64        `json:"usd"`
+	Priced bool           `json:"priced"`
+}
+
+// WalletBalance 单个钱包的持仓
+type WalletBalance struct {
+	Address  common.Address `json:"address"`
+	TotalUSD float64        `json:"total_usd"`
+	Assets   []AssetBalance `json:"assets"`
+}
+
+// PortfolioSnapshot 某个区块的组合快照
+type PortfolioSnapshot struct {
+	BlockNumber uint64          `json:"block_number"`
+	Time        uint64          `json:"time"`
+	TotalUSD    float64         `json:"total_usd"`
+	PeakUSD     float64         `json:"peak_usd"`
+	DrawdownPct float64         `json:"drawdown_pct"` // 相对历史最高值的回撤
+	Wallets     []WalletBalance `json:"wallets"`
+}
+
+// PortfolioDrawdown 组合回撤超过阈值
+type PortfolioDrawdown struct {
+	BlockNumber uint64
+	PeakUSD     float64
+	TotalUSD    float64
+	Pct         float64
+}
+
+// PortfolioMonitor 每隔 N 个区块汇总一组钱包的 ETH 与 ERC-20 余额及美元价值，
+// 组合总值相对最高点的回撤超过阈值时提醒（回到最高点之前只提醒一次）
+type PortfolioMonitor struct {
+	client      *ethclient.Client
+	tokens      *TokenCache
+	prices      *PriceOracle
+	wallets     []common.Address
+	assets      []common.Address // 需要统计的 ERC-20
+	every       uint64
+	drawdownPct float64
+
+	onSnapshot func(PortfolioSnapshot)
+	onDrawdown func(PortfolioDrawdown)
+
+	mu      sync.Mutex
+	head    *types.Header // 只保留最新的区块头
+	wake    chan struct{}
+	last    uint64
+	latest  *PortfolioSnapshot
+	peak    float64
+	alerted bool
+}
+
+// NewPortfolioMonitor 创建组合监控，回调为 nil 时输出到控制台
+func NewPortfolioMonitor(cfg PortfolioConfig, client *ethclient.Client, tokens *TokenCache, prices *PriceOracle,
+	onSnapshot func(PortfolioSnapshot), onDrawdown func(PortfolioDrawdown)) *PortfolioMonitor {
+	if onSnapshot == nil {
+		onSnapshot = PrintPortfolioSnapshot
+	}
+	if onDrawdown == nil {
+		onDrawdown = PrintPortfolioDrawdown
+	}
+	p := &PortfolioMonitor{
+		client:      client,
+		tokens:      tokens,
+		prices:      prices,
+		every:       cfg.Every,
+		drawdownPct: cfg.DrawdownPct,
+		onSnapshot:  onSnapshot,
+		onDrawdown:  onDrawdown,
+		wake:        make(chan struct{}, 1),
+	}
+	for _, w := range cfg.Wallets {
+		p.wallets = append(p.wallets, common.HexToAddress(w))
+	}
+	for _, t := range cfg.Tokens {
+		p.assets = append(p.assets, common.HexToAddress(t))
+	}
+	return p
+}
+
+// NotifyHead 由区块头订阅调用，不会阻塞主循环
+func (p *PortfolioMonitor) NotifyHead(header *types.Header) {
+	p.mu.Lock()
+	p.head = header
+	p.mu.Unlock()
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run 每隔 every 个区块统计一次，直到 ctx 取消
+func (p *PortfolioMonitor) Run(ctx context.Context) {
+	for {
+		select {
+		case <-p.wake:
+			p.mu.Lock()
+			header := p.head
+			due := p.last == 0 || header.Number.Uint64() >= p.last+p.every
+			p.mu.Unlock()
+			if !due {
+				continue
+			}
+			snap, err := p.snapshot(ctx, header)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("⚠️  统计组合余额失败: %v（下个区块重试）", err)
+				}
+				continue
+			}
+			p.record(snap)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// snapshot 查询 header 所在区块的全部余额
+func (p *PortfolioMonitor) snapshot(ctx context.Context, header *types.Header) (*PortfolioSnapshot, error) {
+	ctx, cancel := context.WithTimeout(ctx, CONNECTION_TIMEOUT)
+	defer cancel()
+
+	block := header.Number
+	snap := &PortfolioSnapshot{BlockNumber: block.Uint64(), Time: header.Time}
+	ethPrice, ethPriced := p.prices.PriceWait(ctx, ethToken)
+	for _, wallet := range p.wallets {
+		wb := WalletBalance{Address: wallet, Assets: []AssetBalance{}}
+		var bal *big.Int
+		err := Retry(ctx, "eth_getBalance", func() (err error) {
+			bal, err = p.client.BalanceAt(ctx, wallet, block)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("查询 %s 的 ETH 余额失败: %w", wallet.Hex(), err)
+		}
+		eth := AssetBalance{Symbol: "ETH", Amount: formatUnits(bal, 18), Priced: ethPriced}
+		if ethPriced {
+			eth.USD = weiToEther(bal) * ethPrice
+		}
+		wb.Assets = append(wb.Assets, eth)
+
+		for _, token := range p.assets {
+			asset, err := p.tokenBalance(ctx, wallet, token, block)
+			if err != nil {
+				return nil, err
+			}
+			if asset != nil {
+				wb.Assets = append(wb.Assets, *asset)
+			}
+		}
+		for _, a := range wb.Assets {
+			wb.TotalUSD += a.USD
+		}
+		snap.TotalUSD += wb.TotalUSD
+		snap.Wallets = append(snap.Wallets, wb)
+	}
+	return snap, nil
+}
+
+// tokenBalance 查询 ERC-20 余额，余额为 0 时返回 nil
+func (p *PortfolioMonitor) tokenBalance(ctx context.Context, wallet, token common.Address, block *big.Int) (*AssetBalance, error) {
+	data := append(append([]byte{}, selectorBalanceOf...), common.LeftPadBytes(wallet.Bytes(), 32)...)
+	out, err := callContractRetry(ctx, p.client, ethereum.CallMsg{To: &token, Data: data}, block)
+	if err != nil {
+		return nil, fmt.Errorf("查询 %s 的 %s 余额失败: %w", wallet.Hex(), token.Hex(), err)
+	}
+	if len(out) < 32 {
+		return nil, fmt.Errorf("%s 的 balanceOf 返回值长度错误", token.Hex())
+	}
+	bal := new(big.Int).SetBytes(out[:32])
+	if bal.Sign() == 0 {
+		return nil, nil
+	}
+	meta, err := p.tokens.Get(ctx, token)
+	asset := &AssetBalance{Token: token, Symbol: meta.Label(), Amount: bal.String()}
+	if err != nil || !meta.HasDecimals {
+		return asset, nil // 没有 decimals 无法估值
+	}
+	asset.Amount = formatUnits(bal, meta.Decimals)
+	if price, ok := p.prices.PriceWait(ctx, token); ok {
+		asset.USD = unitsToFloat(bal, meta.Decimals) * price
+		asset.Priced = true
+	}
+	return asset, nil
+}
+
+// record 更新最高值并检查回撤
+func (p *PortfolioMonitor) record(snap *PortfolioSnapshot) {
+	p.mu.Lock()
+	p.last = snap.BlockNumber
+	if snap.TotalUSD >= p.peak {
+		p.peak = snap.TotalUSD
+		p.alerted = false
+	}
+	snap.PeakUSD = p.peak
+	if p.peak > 0 {
+		snap.DrawdownPct = (p.peak - snap.TotalUSD) / p.peak * 100
+	}
+	alert := p.drawdownPct > 0 && snap.DrawdownPct >= p.drawdownPct && !p.alerted
+	p.alerted = p.alerted || alert
+	p.latest = snap
+	p.mu.Unlock()
+
+	p.onSnapshot(*snap)
+	if alert {
+		p.onDrawdown(PortfolioDrawdown{BlockNumber: snap.BlockNumber, PeakUSD: snap.PeakUSD, TotalUSD: snap.TotalUSD, Pct: snap.DrawdownPct})
+	}
+}
+
+// Latest 最近一次的快照，还没有统计过时返回 nil
+func (p *PortfolioMonitor) Latest() *PortfolioSnapshot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.latest
+}
+
+// RegisterAPI 注册组合查询接口
+//
+//	GET /api/portfolio 最近一次的快照
+func (p *PortfolioMonitor) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/portfolio", func(w http.ResponseWriter, r *http.Request) {
+		snap := p.Latest()
+		if snap == nil {
+			writeError(w, http.StatusServiceUnavailable, "还没有统计过组合余额")
+			return
+		}
+		writeJSON(w, http.StatusOK, snap)
+	})
+}
+
+// PrintPortfolioSnapshot 默认的快照输出
+func PrintPortfolioSnapshot(s PortfolioSnapshot) {
+	var sb strings.Builder
+	summary := fmt.Sprintf("💼 [Portfolio] 区块 %d | 总值 %s | 回撤 %.2f%%", s.BlockNumber, formatUSD(s.TotalUSD), s.DrawdownPct)
+	sb.WriteString("\n" + summary + "\n")
+	for _, w := range s.Wallets {
+		assets := append([]AssetBalance{}, w.Assets...)
+		sort.Slice(assets, func(i, j int) bool { return assets[i].USD > assets[j].USD })
+		parts := make([]string, 0, len(assets))
+		for _, a := range assets {
+			if a.Priced {
+				parts = append(parts, fmt.Sprintf("%s %s (%s)", a.Amount, a.Symbol, formatUSD(a.USD)))
+			} else {
+				parts = append(parts, fmt.Sprintf("%s %s", a.Amount, a.Symbol))
+			}
+		}
+		fmt.Fprintf(&sb, "   %s %10s | %s\n", w.Address.Hex(), formatUSD(w.TotalUSD), strings.Join(parts, ", "))
+	}
+	Emit(Event{Type: "portfolio_snapshot", Key: eventKey(s.BlockNumber), Summary: summary, Text: sb.String(), Data: s})
+}
+
+// PrintPortfolioDrawdown 默认的回撤提醒
+func PrintPortfolioDrawdown(d PortfolioDrawdown) {
+	Emit(Event{Type: "portfolio_drawdown", Key: eventKey(d.BlockNumber), Data: d, Text: fmt.Sprintf("🚨 [Portfolio Drawdown] 区块 %d | 组合总值 %s，较最高点 %s 回撤 %.2f%%",
+		d.BlockNumber, formatUSD(d.TotalUSD), formatUSD(d.PeakUSD), d.Pct)})
+}
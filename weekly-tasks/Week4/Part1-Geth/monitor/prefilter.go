@@ -0,0 +1,199 @@
+package main
+
+// ------------------------------------------------
+// Pending 交易预筛：在恢复发送者、解码 calldata 之前，
+// 先用布隆过滤器 / 位图判断交易是否可能与关注的地址、方法有关，
+// 绝大多数无关交易只需要几次位运算就被丢弃，交易池高峰期每秒上千笔也不会积压；
+// 发送者不做预筛：必须先恢复签名才能知道发送者，恢复之后直接查精确集合
+// ------------------------------------------------
+
+import (
+	"encoding/binary"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// 每个地址占用的位数，k=4 时误报率约 0.25%
+	// ⚠️ 误报只会让交易进入后面的精确检查，不会产生错误的提醒
+	AddressBloomBitsPerAddr = 16
+	// 布隆过滤器的最小位数（512 字节），地址很少时也保持极低的误报率
+	AddressBloomMinBits = 4096
+)
+
+// AddressBloom 地址集合的布隆过滤器
+// 地址本身就是 Keccak 哈希的一部分，直接取其中 4 段作为 4 个位置，不需要再做哈希；
+// 跳过开头 4 字节，避免靠挖出前导零的靓号地址全部落在同一位置
+type AddressBloom struct {
+	bits []uint64
+	mask uint32
+	n    int
+}
+
+// NewAddressBloom 用地址列表创建布隆过滤器，位数取 2 的幂以便用掩码取模
+func NewAddressBloom(addrs []common.Address) *AddressBloom {
+	size := uint32(AddressBloomMinBits)
+	for int(size) < len(addrs)*AddressBloomBitsPerAddr {
+		size <<= 1
+	}
+	b := &AddressBloom{bits: make([]uint64, size/64), mask: size - 1, n: len(addrs)}
+	for _, a := range addrs {
+		for i := 4; i < common.AddressLength; i += 4 {
+			pos := binary.BigEndian.Uint32(a[i:]) & b.mask
+			b.bits[pos/64] |= 1 << (pos % 64)
+		}
+	}
+	return b
+}
+
+// Has 地址可能在集合中；返回 false 时一定不在
+func (b *AddressBloom) Has(addr common.Address) bool {
+	if b == nil || b.n == 0 {
+		return false
+	}
+	for i := 4; i < common.AddressLength; i += 4 {
+		pos := binary.BigEndian.Uint32(addr[i:]) & b.mask
+		if b.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// SelectorSet 4 字节 selector 集合：前两字节和后两字节各用一个 65536 位的位图（共 16KB）
+// 两个位图都命中才算可能存在，同样只会误报
+type SelectorSet struct {
+	hi, lo [1024]uint64
+	n      int
+}
+
+// NewSelectorSet 用 selector 列表创建集合，长度不足 4 字节的忽略
+func NewSelectorSet(selectors [][]byte) *SelectorSet {
+	s := &SelectorSet{}
+	for _, sel := range selectors {
+		if len(sel) < 4 {
+			continue
+		}
+		hi, lo := binary.BigEndian.Uint16(sel), binary.BigEndian.Uint16(sel[2:])
+		s.hi[hi/64] |= 1 << (hi % 64)
+		s.lo[lo/64] |= 1 << (lo % 64)
+		s.n++
+	}
+	return s
+}
+
+func (s *SelectorSet) at(data []byte) bool {
+	hi, lo := binary.BigEndian.Uint16(data), binary.BigEndian.Uint16(data[2:])
+	return s.hi[hi/64]&(1<<(hi%64)) != 0 && s.lo[lo/64]&(1<<(lo%64)) != 0
+}
+
+// Has calldata 开头的 selector 可能在集合中
+func (s *SelectorSet) Has(data []byte) bool {
+	return s != nil && s.n > 0 && len(data) >= 4 && s.at(data)
+}
+
+// Scan calldata 任意位置可能出现集合中的 selector（multicall 等嵌套调用）
+func (s *SelectorSet) Scan(data []byte) bool {
+	if s == nil || s.n == 0 {
+		return false
+	}
+	for i := 0; i+4 <= len(data); i++ {
+		if s.at(data[i:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// PrefilterRule 预筛条件
+// Methods 非空时 calldata 开头的 selector 必须属于其中之一；
+// 之后满足以下任意一条即通过：接收方在 Recipients 中、calldata 中出现 Embedded 中的 selector、发送者在 Senders 中。
+// Recipients / Methods / Embedded 用布隆过滤器 / 位图检查（可能误报）；Senders 是精确匹配，只在前面都没有通过时才恢复发送者
+type PrefilterRule struct {
+	Senders    []common.Address
+	Recipients []common.Address
+	Methods    [][]byte
+	Embedded   [][]byte
+}
+
+// prefilterSets 一次 Load 生成的不可变快照，整体替换，读取时不加锁
+type prefilterSets struct {
+	senders           map[common.Address]struct{} // 恢复发送者之后才能检查，布隆过滤器省不下任何开销，直接用精确集合
+	recipients        *AddressBloom
+	methods, embedded *SelectorSet
+}
+
+// TxPrefilter 一组预筛条件，可以在运行中整体替换
+type TxPrefilter struct {
+	signer types.Signer
+	sets   atomic.Value // *prefilterSets
+
+	passed, rejected *metrics.Counter
+}
+
+// NewTxPrefilter 创建预筛器；name 用于指标名 monitor/prefilter/<name>/...
+func NewTxPrefilter(name string, signer types.Signer, rule PrefilterRule) *TxPrefilter {
+	f := &TxPrefilter{
+		signer:   signer,
+		passed:   metrics.NewRegisteredCounter("monitor/prefilter/"+name+"/passed", metricsRegistry),
+		rejected: metrics.NewRegisteredCounter("monitor/prefilter/"+name+"/rejected", metricsRegistry),
+	}
+	f.Load(rule)
+	return f
+}
+
+// Load 用新的条件替换当前条件
+func (f *TxPrefilter) Load(rule PrefilterRule) {
+	sets := &prefilterSets{
+		senders:    make(map[common.Address]struct{}, len(rule.Senders)),
+		recipients: NewAddressBloom(rule.Recipients),
+	}
+	for _, a := range rule.Senders {
+		sets.senders[a] = struct{}{}
+	}
+	if len(rule.Methods) > 0 {
+		sets.methods = NewSelectorSet(rule.Methods)
+	}
+	if len(rule.Embedded) > 0 {
+		sets.embedded = NewSelectorSet(rule.Embedded)
+	}
+	f.sets.Store(sets)
+}
+
+// Pass 交易是否可能满足条件；返回 false 的交易一定不满足，可以直接丢弃
+// 按开销从小到大检查：selector、接收方、calldata 扫描，最后才恢复发送者（一次 secp256k1 运算，结果缓存在交易上）
+func (f *TxPrefilter) Pass(tx *types.Transaction) bool {
+	ok := f.pass(tx)
+	if ok {
+		f.passed.Inc(1)
+	} else {
+		f.rejected.Inc(1)
+	}
+	return ok
+}
+
+func (f *TxPrefilter) pass(tx *types.Transaction) bool {
+	sets := f.sets.Load().(*prefilterSets)
+	data := tx.Data()
+	if sets.methods != nil && !sets.methods.Has(data) {
+		return false
+	}
+	if to := tx.To(); to != nil && sets.recipients.Has(*to) {
+		return true
+	}
+	if sets.embedded.Scan(data) {
+		return true
+	}
+	if len(sets.senders) == 0 {
+		return false
+	}
+	from, err := types.Sender(f.signer, tx)
+	if err != nil {
+		return false
+	}
+	_, ok := sets.senders[from]
+	return ok
+}
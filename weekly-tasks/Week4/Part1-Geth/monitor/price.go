@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	// 主网 Chainlink ETH/USD 聚合器
+	DefaultETHUSDFeed = "0x5f4eC3Df9cbd43714FE2740f5E3616155c5b8419"
+	// 主网 WETH，按 ETH 价格计价
+	DefaultWETH = "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"
+
+	CoinGeckoTokenPriceAPI = "https://api.coingecko.com/api/v3/simple/token_price/ethereum"
+
+	// 价格缓存有效期，过期后后台刷新（刷新完成前继续使用旧价格）
+	PriceTTL = time.Minute
+	// Chainlink 报价超过该时间没有更新则不采用
+	PriceStaleAfter = 24 * time.Hour
+	// CoinGecko 免费接口限流较严，两次请求之间至少间隔这么久
+	CoinGeckoMinInterval = 3 * time.Second
+)
+
+var (
+	// 主网常见稳定币，按 $1 计价
+	DefaultStablecoins = []string{
+		"0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", // USDC
+		"0xdAC17F958D2ee523a2206206994597C13D831ec7", // USDT
+		"0x6B175474E89094C44Da98b954EedeAC495271d0F", // DAI
+	}
+
+	selectorLatestRoundData = common.FromHex("0xfeaf968c") // latestRoundData()
+)
+
+// priceEntry 缓存的价格
+type priceEntry struct {
+	usd       float64
+	updatedAt time.Time
+	ok        bool // 没有任何报价来源时为 false，同样缓存 PriceTTL，避免反复查询
+}
+
+// PriceOracle 代币的美元价格：稳定币固定 $1，其余优先 Chainlink 链上喂价，可选回退到 CoinGecko
+// 和 TokenCache 一样，Price 不会阻塞调用方，未命中时后台刷新
+type PriceOracle struct {
+	client      *ethclient.Client
+	ethFeed     common.Address
+	weth        common.Address
+	feeds       map[common.Address]common.Address // 代币 -> Chainlink USD 聚合器
+	stablecoins map[common.Address]bool
+	coingecko   bool
+	http        *http.Client
+
+	mu        sync.Mutex
+	prices    map[common.Address]priceEntry // key 为零地址表示 ETH
+	inflight  map[common.Address]bool
+	lastGecko time.Time
+}
+
+// NewPriceOracle 根据配置创建价格源
+func NewPriceOracle(cfg PriceConfig, client *ethclient.Client) *PriceOracle {
+	o := &PriceOracle{
+		client:      client,
+		ethFeed:     common.HexToAddress(cfg.ETHUSDFeed),
+		weth:        common.HexToAddress(cfg.WETH),
+		feeds:       make(map[common.Address]common.Address),
+		stablecoins: make(map[common.Address]bool),
+		coingecko:   cfg.CoinGecko,
+		http:        &http.Client{Timeout: CONNECTION_TIMEOUT},
+		prices:      make(map[common.Address]priceEntry),
+		inflight:    make(map[common.Address]bool),
+	}
+	for token, feed := range cfg.Feeds {
+		o.feeds[common.HexToAddress(token)] = common.HexToAddress(feed)
+	}
+	for _, s := range cfg.Stablecoins {
+		o.stablecoins[common.HexToAddress(s)] = true
+	}
+	return o
+}
+
+// ETHPrice ETH 的美元价格
+func (o *PriceOracle) ETHPrice() (float64, bool) {
+	return o.Price(common.Address{})
+}
+
+// Price 代币的美元价格，token 为零地址表示 ETH；o 为 nil 时总是没有价格
+func (o *PriceOracle) Price(token common.Address) (float64, bool) {
+	if o == nil {
+		return 0, false
+	}
+	if o.stablecoins[token] {
+		return 1, true
+	}
+	if token == o.weth {
+		token = common.Address{}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	e, cached := o.prices[token]
+	if (!cached || time.Since(e.updatedAt) > PriceTTL) && !o.inflight[token] {
+		o.inflight[token] = true
+		go o.refresh(token)
+	}
+	return e.usd, e.ok
+}
+
+// PriceWait 同 Price，但缓存中没有可用价格时同步查询一次，适合在后台 goroutine 中使用
+func (o *PriceOracle) PriceWait(ctx context.Context, token common.Address) (float64, bool) {
+	if usd, ok := o.Price(token); ok || o == nil {
+		return usd, ok
+	}
+	if token == o.weth {
+		token = common.Address{}
+	}
+	usd, err := o.fetch(ctx, token)
+	if err != nil {
+		return 0, false
+	}
+	o.mu.Lock()
+	o.prices[token] = priceEntry{usd: usd, updatedAt: time.Now(), ok: true}
+	o.mu.Unlock()
+	return usd, true
+}
+
+// refresh 查询最新价格并写入缓存
+func (o *PriceOracle) refresh(token common.Address) {
+	ctx, cancel := context.WithTimeout(context.Background(), CONNECTION_TIMEOUT)
+	defer cancel()
+
+	usd, err := o.fetch(ctx, token)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.inflight, token)
+	if err != nil {
+		if prev, ok := o.prices[token]; ok && prev.ok {
+			return // 刷新失败时保留旧价格，下次再试
+		}
+		o.prices[token] = priceEntry{updatedAt: time.Now()}
+		return
+	}
+	o.prices[token] = priceEntry{usd: usd, updatedAt: time.Now(), ok: true}
+}
+
+func (o *PriceOracle) fetch(ctx context.Context, token common.Address) (float64, error) {
+	if token == (common.Address{}) {
+		return o.chainlink(ctx, o.ethFeed)
+	}
+	if feed, ok := o.feeds[token]; ok {
+		usd, err := o.chainlink(ctx, feed)
+		if err == nil {
+			return usd, nil
+		}
+		log.Printf("⚠️  读取 %s 的 Chainlink 报价失败: %v", token.Hex(), err)
+	}
+	if o.coingecko {
+		return o.fetchCoinGecko(ctx, token)
+	}
+	return 0, fmt.Errorf("%s 没有可用的报价来源", token.Hex())
+}
+
+// chainlink 读取聚合器的 latestRoundData 和 decimals
+func (o *PriceOracle) chainlink(ctx context.Context, feed common.Address) (float64, error) {
+	out, err := callContractRetry(ctx, o.client, ethereum.CallMsg{To: &feed, Data: selectorLatestRoundData}, nil)
+	if err != nil {
+		return 0, err
+	}
+	if len(out) < 5*32 {
+		return 0, fmt.Errorf("latestRoundData 返回值长度错误")
+	}
+	dec, err := callContractRetry(ctx, o.client, ethereum.CallMsg{To: &feed, Data: selectorDecimals}, nil)
+	if err != nil || len(dec) < 32 {
+		return 0, fmt.Errorf("读取喂价精度失败: %v", err)
+	}
+	answer := new(big.Int).SetBytes(out[32:64])
+	if out[32]&0x80 != 0 {
+		return 0, fmt.Errorf("报价为负数")
+	}
+	updatedAt := time.Unix(new(big.Int).SetBytes(out[96:128]).Int64(), 0)
+	if time.Since(updatedAt) > PriceStaleAfter {
+		return 0, fmt.Errorf("报价已 %s 未更新", time.Since(updatedAt).Truncate(time.Minute))
+	}
+	decimals := new(big.Int).SetBytes(dec[:32]).Uint64()
+	usd, _ := new(big.Float).Quo(new(big.Float).SetInt(answer),
+		new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), new(big.Int).SetUint64(decimals), nil))).Float64()
+	return usd, nil
+}
+
+func (o *PriceOracle) fetchCoinGecko(ctx context.Context, token common.Address) (float64, error) {
+	o.mu.Lock()
+	if time.Since(o.lastGecko) < CoinGeckoMinInterval {
+		o.mu.Unlock()
+		return 0, fmt.Errorf("CoinGecko 请求过于频繁，稍后重试")
+	}
+	o.lastGecko = time.Now()
+	o.mu.Unlock()
+
+	addr := strings.ToLower(token.Hex())
+	url := fmt.Sprintf("%s?contract_addresses=%s&vs_currencies=usd", CoinGeckoTokenPriceAPI, addr)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := o.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("CoinGecko 返回 HTTP %d", resp.StatusCode)
+	}
+	var prices map[string]struct {
+		USD float64 `json:"usd"`
+	}
+	if err := json.Unmarshal(body, &prices); err != nil {
+		return 0, err
+	}
+	p, ok := prices[addr]
+	if !ok || p.USD <= 0 {
+		return 0, fmt.Errorf("CoinGecko 没有 %s 的报价", token.Hex())
+	}
+	return p.USD, nil
+}
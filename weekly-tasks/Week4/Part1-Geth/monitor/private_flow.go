@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// 交易池中见过的交易保留多久；超过后才上链的公开交易会被误判为私有
+	// ⚠️ 主网一小时约几十万笔 Pending 交易，约占几十 MB 内存
+	PrivateFlowWindow = time.Hour
+	// 交易池订阅连续这么久没有收到任何交易，认为订阅中断过，之后需要重新预热
+	PrivateFlowMaxSilence = 30 * time.Second
+	// 订阅（重新）开始后的预热时间，之前已经在交易池中的交易看不到，这段时间内的区块不做判断
+	PrivateFlowWarmup = 2 * time.Minute
+	// 每隔多少个区块输出一次按 builder 的统计
+	PrivateFlowReportEvery = 100
+)
+
+// PrivateBlock 一个区块的私有交易统计
+type PrivateBlock struct {
+	Number  uint64        `json:"number"`
+	Builder string        `json:"builder"`
+	Txs     int           `json:"txs"`     // 参与统计的交易数（不含 builder 自己发出的交易）
+	Private int           `json:"private"` // 交易池中从未见过的交易数
+	Share   float64       `json:"share"`   // 私有交易占比，百分比
+	Hashes  []common.Hash `json:"hashes"`
+}
+
+// BuilderFlowStats 一个 builder 的私有交易统计
+type BuilderFlowStats struct {
+	Builder string  `json:"builder"`
+	Blocks  int     `json:"blocks"`
+	Txs     int     `json:"txs"`
+	Private int     `json:"private"`
+	Share   float64 `json:"share"` // 百分比
+}
+
+// PrivateFlowDetector 找出上链前从未出现在公开交易池中的交易（私有交易池 / builder 直连 / bundle），
+// 按区块和 builder 统计私有交易占比
+// 只有交易池订阅在区块之前持续在线（预热完成且中间没有中断）时才判断，否则无法区分"私有"和"漏看"
+type PrivateFlowDetector struct {
+	mu                sync.Mutex
+	seen              map[common.Hash]time.Time
+	coveredSince      time.Time // 本次连续订阅开始的时间，零值表示还没有收到过交易
+	lastPending       time.Time
+	builders          map[string]*BuilderFlowStats
+	skipped           int // 订阅不连续、不做判断的区块数
+	blocksSinceReport int
+
+	share *metrics.GaugeFloat64
+
+	onBlock  func(PrivateBlock)
+	onReport func([]BuilderFlowStats)
+}
+
+// NewPrivateFlowDetector 创建检测器，onBlock / onReport 为 nil 时使用默认输出
+func NewPrivateFlowDetector(onBlock func(PrivateBlock), onReport func([]BuilderFlowStats)) *PrivateFlowDetector {
+	if onBlock == nil {
+		onBlock = PrintPrivateBlock
+	}
+	if onReport == nil {
+		onReport = PrintPrivateFlowReport
+	}
+	return &PrivateFlowDetector{
+		seen:     make(map[common.Hash]time.Time),
+		builders: make(map[string]*BuilderFlowStats),
+		share:    metrics.NewRegisteredGaugeFloat64("monitor/mempool/private_share", metricsRegistry),
+		onBlock:  onBlock,
+		onReport: onReport,
+	}
+}
+
+// Observe 记录交易池中出现过的交易，同时维护订阅的连续性
+func (p *PrivateFlowDetector) Observe(msg BusMessage) {
+	hash := msg.Hash
+	if msg.Tx != nil {
+		hash = msg.Tx.Hash()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.coveredSince.IsZero() || msg.Received.Sub(p.lastPending) > PrivateFlowMaxSilence {
+		p.coveredSince = msg.Received
+	}
+	p.lastPending = msg.Received
+	if _, ok := p.seen[hash]; !ok {
+		p.seen[hash] = msg.Received
+	}
+}
+
+// covered 区块时间之前交易池订阅是否持续在线（需持有锁）
+func (p *PrivateFlowDetector) covered(blockTime time.Time) bool {
+	if p.coveredSince.IsZero() || blockTime.Sub(p.coveredSince) < PrivateFlowWarmup {
+		return false
+	}
+	return blockTime.Sub(p.lastPending) <= PrivateFlowMaxSilence
+}
+
+// Enrichment 实现 Enricher：只看交易和发送者，不需要收据
+func (p *PrivateFlowDetector) Enrichment() EnrichLevel { return EnrichTransactions }
+
+// OnBlock 实现 BlockAnalyzer
+func (p *PrivateFlowDetector) OnBlock(data *BlockData) {
+	header := data.Block.Header()
+	blockTime := time.Unix(int64(header.Time), 0)
+	builder := builderName(header)
+	pb := PrivateBlock{Number: header.Number.Uint64(), Builder: builder}
+
+	p.mu.Lock()
+	covered := p.covered(blockTime)
+	for i, tx := range data.Block.Transactions() {
+		_, seen := p.seen[tx.Hash()]
+		delete(p.seen, tx.Hash())
+		// builder 给出块者转账等自己发出的交易本来就不经过交易池，不计入；
+		// blob 交易通常只在节点间按哈希广播，订阅中看不到，也不计入
+		if !covered || data.Senders[i] == header.Coinbase || tx.Type() == types.BlobTxType {
+			continue
+		}
+		pb.Txs++
+		if !seen {
+			pb.Private++
+			pb.Hashes = append(pb.Hashes, tx.Hash())
+		}
+	}
+	for hash, at := range p.seen {
+		if blockTime.Sub(at) > PrivateFlowWindow {
+			delete(p.seen, hash)
+		}
+	}
+	if !covered {
+		p.skipped++
+		p.mu.Unlock()
+		return
+	}
+	if pb.Txs > 0 {
+		pb.Share = float64(pb.Private) / float64(pb.Txs) * 100
+	}
+	s, ok := p.builders[builder]
+	if !ok {
+		s = &BuilderFlowStats{Builder: builder}
+		p.builders[builder] = s
+	}
+	s.Blocks++
+	s.Txs += pb.Txs
+	s.Private += pb.Private
+	p.blocksSinceReport++
+	report := p.blocksSinceReport >= PrivateFlowReportEvery
+	if report {
+		p.blocksSinceReport = 0
+	}
+	p.mu.Unlock()
+
+	p.share.Update(pb.Share)
+	p.onBlock(pb)
+	if report {
+		p.onReport(p.Builders())
+	}
+}
+
+// Builders 各 builder 的统计，按私有交易数排序
+func (p *PrivateFlowDetector) Builders() []BuilderFlowStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]BuilderFlowStats, 0, len(p.builders))
+	for _, s := range p.builders {
+		st := *s
+		if st.Txs > 0 {
+			st.Share = float64(st.Private) / float64(st.Txs) * 100
+		}
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Private > out[j].Private })
+	return out
+}
+
+// RegisterAPI 注册私有交易查询接口
+//
+//	GET /api/mempool/private 各 builder 的私有交易统计
+func (p *PrivateFlowDetector) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/mempool/private", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		skipped := p.skipped
+		p.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"builders": p.Builders(), "skipped_blocks": skipped})
+	})
+}
+
+// builderName 从 extraData 中取 builder 名字（多数 builder 会写入可读的标识），没有时用 fee recipient 地址
+func builderName(header *types.Header) string {
+	name := strings.TrimSpace(strings.Map(func(r rune) rune {
+		if r == unicode.ReplacementChar || !unicode.IsPrint(r) {
+			return -1
+		}
+		return r
+	}, string(header.Extra)))
+	if len(name) < 3 {
+		return header.Coinbase.Hex()
+	}
+	return name
+}
+
+// PrintPrivateBlock 默认的区块私有交易输出
+func PrintPrivateBlock(b PrivateBlock) {
+	summary := fmt.Sprintf("🕶️  [Private Flow] 区块 %d (%s): %d / %d 笔交易未在交易池出现 (%.1f%%)",
+		b.Number, b.Builder, b.Private, b.Txs, b.Share)
+	Emit(Event{Type: "private_flow", Key: eventKey(b.Number, b.Builder), Summary: summary, Text: summary, Data: b})
+}
+
+// PrintPrivateFlowReport 默认的按 builder 统计输出
+func PrintPrivateFlowReport(stats []BuilderFlowStats) {
+	var sb strings.Builder
+	summary := fmt.Sprintf("🕶️  [Private Flow] %d 个 builder 的私有交易占比", len(stats))
+	sb.WriteString("\n" + summary + "\n")
+	fmt.Fprintf(&sb, "   %-32s %8s %10s %10s %8s\n", "builder", "区块", "交易", "私有", "占比")
+	for _, s := range stats {
+		fmt.Fprintf(&sb, "   %-32s %8d %10d %10d %7.1f%%\n", s.Builder, s.Blocks, s.Txs, s.Private, s.Share)
+	}
+	Emit(Event{Type: "private_flow_report", Summary: summary, Text: sb.String(), Data: stats})
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// ------------------------------------------------
+// 关注分组（profiles）：多个团队共用一个监控，例如 treasury / bots / competitors，
+// 每个分组有自己的地址、过滤条件、输出目标和级别；
+// 每个分组是事件总线上独立的订阅者（自己的队列和 goroutine），在共享的交易流上并行匹配，互不阻塞
+// ------------------------------------------------
+
+// 分组匹配交易的方向
+const (
+	ProfileAny  = "any"  // 发出或收到（默认）
+	ProfileFrom = "from" // 只看分组地址发出的交易
+	ProfileTo   = "to"   // 只看发给分组地址的交易
+)
+
+// 匹配发生的阶段
+const (
+	ProfileStagePending = "pending"
+	ProfileStageMined   = "mined"
+)
+
+// ProfileConfig 一个关注分组
+type ProfileConfig struct {
+	Name        string   `json:"name"`
+	Addresses   []string `json:"addresses"`
+	Direction   string   `json:"direction"`     // any（默认）| from | to
+	MinValueETH float64  `json:"min_value_eth"` // 转账金额下限，0 不限
+	Methods     []string `json:"methods"`       // 函数名或 4 字节 selector，为空不限
+	Pending     bool     `json:"pending"`       // 同时匹配交易池中的交易，否则只看已打包的
+	Severity    string   `json:"severity"`      // 该分组事件的级别，默认 warn
+	Outputs     []string `json:"outputs"`       // 只发往这些输出（outputs 中的 name），为空时按 routing
+	Filter      string   `json:"filter"`        // 同时需要满足的过滤链（filters 中的名称），为空不限
+}
+
+// selectorPattern methods 中的 4 字节 selector
+var selectorPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{8}$`)
+
+// validate 检查分组配置，outputs 为配置中的全部输出
+func (c ProfileConfig) validate(outputs []OutputConfig) error {
+	if c.Name == "" {
+		return fmt.Errorf("profiles 中的 name 不能为空")
+	}
+	if len(c.Addresses) == 0 {
+		return fmt.Errorf("profiles 中 %s 的 addresses 不能为空", c.Name)
+	}
+	for _, a := range c.Addresses {
+		if !common.IsHexAddress(a) {
+			return fmt.Errorf("profiles 中 %s 的地址格式错误: %q", c.Name, a)
+		}
+	}
+	switch c.Direction {
+	case "", ProfileAny, ProfileFrom, ProfileTo:
+	default:
+		return fmt.Errorf("profiles 中 %s 的 direction 无效: %q（可选 any / from / to）", c.Name, c.Direction)
+	}
+	if c.MinValueETH < 0 {
+		return fmt.Errorf("profiles 中 %s 的 min_value_eth 不能为负数", c.Name)
+	}
+	for _, m := range c.Methods {
+		if m == "" || (strings.HasPrefix(m, "0x") && !selectorPattern.MatchString(m)) {
+			return fmt.Errorf("profiles 中 %s 的 methods 无效: %q（函数名或 0x 开头的 4 字节 selector）", c.Name, m)
+		}
+	}
+	if c.Severity != "" && severityRank[c.Severity] == 0 {
+		return fmt.Errorf("profiles 中 %s 的 severity 无效: %q（可选 info / warn / critical）", c.Name, c.Severity)
+	}
+	names := make(map[string]bool, len(outputs))
+	for _, o := range outputs {
+		names[o.Name] = true
+	}
+	for _, name := range c.Outputs {
+		if name == "" || !names[name] {
+			return fmt.Errorf("profiles 中 %s 的输出 %q 不在 outputs 中（需要填写 name）", c.Name, name)
+		}
+	}
+	return nil
+}
+
+// ProfileMatch 一笔交易命中分组
+type ProfileMatch struct {
+	Profile  string          `json:"profile"`
+	Stage    string          `json:"stage"` // pending / mined
+	Hash     common.Hash     `json:"hash"`
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to"`
+	ValueETH float64         `json:"value_eth"`
+	Method   string          `json:"method,omitempty"` // 函数名，ABI 未知时为 selector
+	Block    uint64          `json:"block,omitempty"`
+
+	severity string
+	outputs  []string
+	tx       *types.Transaction
+}
+
+// ProfileStatus /api/profiles 中一个分组的状态
+type ProfileStatus struct {
+	Name      string   `json:"name"`
+	Addresses int      `json:"addresses"`
+	Outputs   []string `json:"outputs"`
+	Pending   int64    `json:"pending"` // 命中的交易池交易数
+	Mined     int64    `json:"mined"`   // 命中的已打包交易数
+}
+
+// Profile 一个分组的匹配器
+type Profile struct {
+	cfg       ProfileConfig
+	addrs     map[common.Address]bool
+	names     map[string]bool // methods 中的函数名
+	selectors map[string]bool // methods 中的 selector（小写）
+	minValue  *big.Int
+	abis      *ABIRegistry
+	signer    types.Signer
+	prefilter *TxPrefilter // 交易池交易的预筛，方向为 to 时不需要恢复发送者
+
+	pending, mined atomic.Int64
+
+	onMatch func(ProfileMatch)
+}
+
+// NewProfile 创建分组匹配器；onMatch 为 nil 时使用默认输出
+func NewProfile(cfg ProfileConfig, abis *ABIRegistry, signer types.Signer, onMatch func(ProfileMatch)) *Profile {
+	if onMatch == nil {
+		onMatch = PrintProfileMatch
+	}
+	if cfg.Direction == "" {
+		cfg.Direction = ProfileAny
+	}
+	if cfg.Severity == "" {
+		cfg.Severity = SeverityWarn
+	}
+	minValue, _ := new(big.Float).Mul(big.NewFloat(cfg.MinValueETH), big.NewFloat(params.Ether)).Int(nil)
+	p := &Profile{
+		cfg:       cfg,
+		addrs:     make(map[common.Address]bool, len(cfg.Addresses)),
+		names:     make(map[string]bool),
+		selectors: make(map[string]bool),
+		minValue:  minValue,
+		abis:      abis,
+		signer:    signer,
+		onMatch:   onMatch,
+	}
+	for _, a := range cfg.Addresses {
+		p.addrs[common.HexToAddress(a)] = true
+	}
+	for _, m := range cfg.Methods {
+		if selectorPattern.MatchString(m) {
+			p.selectors[strings.ToLower(m)] = true
+		} else {
+			p.names[m] = true
+		}
+	}
+	p.prefilter = NewTxPrefilter("profile/"+cfg.Name, signer, p.prefilterRule())
+	return p
+}
+
+// prefilterRule 分组的预筛条件；methods 中有函数名时要解码才能判断，只按地址预筛
+func (p *Profile) prefilterRule() PrefilterRule {
+	addrs := make([]common.Address, 0, len(p.addrs))
+	for a := range p.addrs {
+		addrs = append(addrs, a)
+	}
+	var rule PrefilterRule
+	if p.cfg.Direction != ProfileTo {
+		rule.Senders = addrs
+	}
+	if p.cfg.Direction != ProfileFrom {
+		rule.Recipients = addrs
+	}
+	if len(p.names) == 0 {
+		for sel := range p.selectors {
+			rule.Methods = append(rule.Methods, common.FromHex(sel))
+		}
+	}
+	return rule
+}
+
+// Enrichment 只需要交易和发送者
+func (p *Profile) Enrichment() EnrichLevel { return EnrichTransactions }
+
+// OnBlock 匹配区块中的交易
+func (p *Profile) OnBlock(data *BlockData) {
+	for i, tx := range data.Block.Transactions() {
+		if m, ok := p.match(tx, data.Senders[i]); ok {
+			m.Stage, m.Block = ProfileStageMined, data.Block.NumberU64()
+			p.mined.Add(1)
+			p.onMatch(m)
+		}
+	}
+}
+
+// Observe 匹配交易池中的完整交易，作为事件总线的订阅者调用
+func (p *Profile) Observe(msg BusMessage) {
+	if !p.cfg.Pending || msg.Tx == nil || msg.Tx.Value().Cmp(p.minValue) < 0 || !p.prefilter.Pass(msg.Tx) {
+		return
+	}
+	from, err := types.Sender(p.signer, msg.Tx)
+	if err != nil {
+		return
+	}
+	if m, ok := p.match(msg.Tx, from); ok {
+		m.Stage = ProfileStagePending
+		p.pending.Add(1)
+		p.onMatch(m)
+	}
+}
+
+// match 按方向、金额和方法过滤
+func (p *Profile) match(tx *types.Transaction, from common.Address) (ProfileMatch, bool) {
+	to := tx.To()
+	var hit bool
+	switch p.cfg.Direction {
+	case ProfileFrom:
+		hit = p.addrs[from]
+	case ProfileTo:
+		hit = to != nil && p.addrs[*to]
+	default:
+		hit = p.addrs[from] || (to != nil && p.addrs[*to])
+	}
+	if !hit || tx.Value().Cmp(p.minValue) < 0 {
+		return ProfileMatch{}, false
+	}
+	var method string
+	filtered := len(p.names)+len(p.selectors) > 0
+	if data := tx.Data(); len(data) >= 4 {
+		selector := "0x" + hex.EncodeToString(data[:4])
+		method = selector
+		if call, ok := p.abis.DecodeCall(to, data); ok && call.Name != "" {
+			method = call.Name
+		}
+		if filtered && !p.names[method] && !p.selectors[selector] {
+			return ProfileMatch{}, false
+		}
+	} else if filtered { // 纯转账没有方法
+		return ProfileMatch{}, false
+	}
+	if p.cfg.Filter != "" && !currentFilters().Match(p.cfg.Filter, tx, &from) {
+		return ProfileMatch{}, false
+	}
+	return ProfileMatch{
+		Profile:  p.cfg.Name,
+		Hash:     tx.Hash(),
+		From:     from,
+		To:       to,
+		ValueETH: weiToEther(tx.Value()),
+		Method:   method,
+		severity: p.cfg.Severity,
+		outputs:  p.cfg.Outputs,
+		tx:       tx,
+	}, true
+}
+
+// Status 分组的地址数和命中次数
+func (p *Profile) Status() ProfileStatus {
+	return ProfileStatus{
+		Name:      p.cfg.Name,
+		Addresses: len(p.addrs),
+		Outputs:   p.cfg.Outputs,
+		Pending:   p.pending.Load(),
+		Mined:     p.mined.Load(),
+	}
+}
+
+// RegisterProfilesAPI 注册分组状态查询接口
+//
+//	GET /api/profiles 每个分组的地址数、输出目标和命中次数
+func RegisterProfilesAPI(api *APIServer, profiles []*Profile) {
+	api.Handle("GET /api/profiles", func(w http.ResponseWriter, r *http.Request) {
+		out := make([]ProfileStatus, 0, len(profiles))
+		for _, p := range profiles {
+			out = append(out, p.Status())
+		}
+		writeJSON(w, http.StatusOK, out)
+	})
+}
+
+// PrintProfileMatch 默认的分组命中输出，只发往分组配置的输出
+func PrintProfileMatch(m ProfileMatch) {
+	to := "(创建合约)"
+	if m.To != nil {
+		to = m.To.Hex()
+	}
+	where := "交易池"
+	if m.Stage == ProfileStageMined {
+		where = fmt.Sprintf("区块 %d", m.Block)
+	}
+	summary := fmt.Sprintf("🗂️  [Profile:%s] %s | %s -> %s | %.4f ETH | %s", m.Profile, where, m.From.Hex(), to, m.ValueETH, m.Hash.Hex())
+	text := summary
+	if m.Method != "" {
+		text += fmt.Sprintf("\n   🧾 调用 %s", m.Method)
+	}
+	Emit(Event{Type: "profile_tx", Key: eventKey(m.Profile, m.Stage, m.Hash), Summary: summary, Text: text, Data: m, Severity: m.severity, Outputs: m.outputs, tx: m.tx, txFrom: &m.From})
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+)
+
+const (
+	// 同一个区块 / 交易在这么长时间之后不再等待其它节点，统计是否漏掉
+	PropagationWindow = 2 * time.Minute
+	// 每个节点保留的最近延迟样本数
+	PropagationSamples = 1000
+	// 排行榜的输出间隔
+	PropagationReportInterval = 10 * time.Minute
+	// 订阅断开后的重连间隔
+	PropagationRedial = 10 * time.Second
+)
+
+// EndpointConfig 参与比较的一个节点
+type EndpointConfig struct {
+	Name  string `json:"name"`
+	WSURL string `json:"ws_url"`
+}
+
+// PropagationConfig 多节点传播延迟比较，endpoints 为空时不启动（主节点 ws_url 自动参与比较）
+type PropagationConfig struct {
+	Endpoints []EndpointConfig `json:"endpoints"`
+	Pending   bool             `json:"pending"` // 同时比较 Pending 交易哈希（请求量大，按需开启）
+}
+
+// EndpointScore 一个节点的传播延迟统计
+type EndpointScore struct {
+	Name string `json:"name"`
+
+	HeadsSeen   int           `json:"heads_seen"`
+	HeadsFirst  int           `json:"heads_first"`  // 最先送达的次数
+	HeadsMissed int           `json:"heads_missed"` // 其它节点收到、它在窗口内没收到
+	HeadMean    time.Duration `json:"head_mean_behind"`
+	HeadP50     time.Duration `json:"head_p50_behind"`
+	HeadP95     time.Duration `json:"head_p95_behind"`
+
+	TxSeen   int           `json:"tx_seen"`
+	TxFirst  int           `json:"tx_first"`
+	TxMissed int           `json:"tx_missed"`
+	TxMean   time.Duration `json:"tx_mean_behind"`
+	TxP95    time.Duration `json:"tx_p95_behind"`
+}
+
+// raceEntry 一个区块 / 交易的首次送达记录
+type raceEntry struct {
+	kind  byte // 'h' 区块头，'t' 交易
+	first time.Time
+	seen  uint64 // 已送达的节点（按下标的位图）
+}
+
+// raceStats 一个节点的累计数据
+type raceStats struct {
+	heads, headsFirst, headsMissed int
+	txs, txsFirst, txsMissed       int
+	headDelays, txDelays           []time.Duration // 落后于最先送达者的时间，最近 PropagationSamples 个
+}
+
+// PropagationRace 同时订阅多个节点的新区块头（和 Pending 交易），记录每个区块 / 交易由哪个节点最先送达、
+// 其它节点落后多少，生成延迟排行榜，用来选择更快的节点服务商
+type PropagationRace struct {
+	endpoints []EndpointConfig
+	pending   bool
+
+	mu      sync.Mutex
+	entries map[common.Hash]*raceEntry
+	stats   []*raceStats
+}
+
+// NewPropagationRace 创建传播延迟比较，primary 为主节点地址，排在第一位
+func NewPropagationRace(cfg PropagationConfig, primary string) *PropagationRace {
+	endpoints := append([]EndpointConfig{{Name: "primary", WSURL: primary}}, cfg.Endpoints...)
+	r := &PropagationRace{
+		endpoints: endpoints,
+		pending:   cfg.Pending,
+		entries:   make(map[common.Hash]*raceEntry),
+		stats:     make([]*raceStats, len(endpoints)),
+	}
+	for i := range r.stats {
+		r.stats[i] = &raceStats{}
+	}
+	return r
+}
+
+// Run 为每个节点单独建立连接和订阅（与主循环的连接分开，计时不受主循环处理速度影响），直到 ctx 取消
+func (r *PropagationRace) Run(ctx context.Context) {
+	for i := range r.endpoints {
+		go r.runEndpoint(ctx, i)
+	}
+	ticker := time.NewTicker(PropagationReportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.prune(time.Now())
+			PrintPropagationScores(r.Scores())
+		case <-ctx.Done():
+			PrintPropagationScores(r.Scores())
+			return
+		}
+	}
+}
+
+// runEndpoint 订阅一个节点，断开后间隔 PropagationRedial 重连
+func (r *PropagationRace) runEndpoint(ctx context.Context, idx int) {
+	ep := r.endpoints[idx]
+	for {
+		if err := r.subscribe(ctx, idx); err != nil && ctx.Err() == nil {
+			log.Printf("⚠️  [%s] 传播延迟订阅中断: %v（%s 后重连）", ep.Name, err, PropagationRedial)
+		}
+		select {
+		case <-time.After(PropagationRedial):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *PropagationRace) subscribe(ctx context.Context, idx int) error {
+	dialCtx, cancel := context.WithTimeout(ctx, CONNECTION_TIMEOUT)
+	client, err := dialRPC(dialCtx, r.endpoints[idx].WSURL)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("连接失败: %w", err)
+	}
+	defer client.Close()
+
+	heads := make(chan *types.Header, 16)
+	headSub, err := ethclient.NewClient(client).SubscribeNewHead(ctx, heads)
+	if err != nil {
+		return fmt.Errorf("订阅新区块失败: %w", err)
+	}
+	defer headSub.Unsubscribe()
+
+	hashes := make(chan common.Hash, 256)
+	var txErr <-chan error
+	if r.pending {
+		txSub, err := gethclient.New(client).SubscribePendingTransactions(ctx, hashes)
+		if err != nil {
+			return fmt.Errorf("订阅 Pending 交易失败: %w", err)
+		}
+		defer txSub.Unsubscribe()
+		txErr = txSub.Err()
+	}
+
+	for {
+		select {
+		case h := <-heads:
+			r.observe('h', h.Hash(), idx, time.Now())
+		case hash := <-hashes:
+			r.observe('t', hash, idx, time.Now())
+		case err := <-headSub.Err():
+			return err
+		case err := <-txErr:
+			return err
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// observe 记录一次送达：第一个送达的节点记为领先，之后的节点记录落后的时间
+func (r *PropagationRace) observe(kind byte, hash common.Hash, idx int, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.stats[idx]
+	e, ok := r.entries[hash]
+	if !ok {
+		e = &raceEntry{kind: kind, first: at}
+		r.entries[hash] = e
+		if kind == 'h' {
+			s.headsFirst++
+		} else {
+			s.txsFirst++
+		}
+	}
+	if e.seen&(1<<idx) != 0 {
+		return // 重组后同一个区块头可能重复推送
+	}
+	e.seen |= 1 << idx
+	delay := at.Sub(e.first)
+	if kind == 'h' {
+		s.heads++
+		s.headDelays = appendSample(s.headDelays, delay)
+	} else {
+		s.txs++
+		s.txDelays = appendSample(s.txDelays, delay)
+	}
+}
+
+func appendSample(samples []time.Duration, d time.Duration) []time.Duration {
+	if len(samples) >= PropagationSamples {
+		samples = samples[1:]
+	}
+	return append(samples, d)
+}
+
+// prune 丢弃超出窗口的记录，并给窗口内没有送达的节点记一次漏掉
+func (r *PropagationRace) prune(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for hash, e := range r.entries {
+		if now.Sub(e.first) < PropagationWindow {
+			continue
+		}
+		for i, s := range r.stats {
+			if e.seen&(1<<i) != 0 {
+				continue
+			}
+			if e.kind == 'h' {
+				s.headsMissed++
+			} else {
+				s.txsMissed++
+			}
+		}
+		delete(r.entries, hash)
+	}
+}
+
+// Scores 各节点的统计，按最先送达区块头的次数排序
+func (r *PropagationRace) Scores() []EndpointScore {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	scores := make([]EndpointScore, len(r.stats))
+	for i, s := range r.stats {
+		scores[i] = EndpointScore{
+			Name:        r.endpoints[i].Name,
+			HeadsSeen:   s.heads,
+			HeadsFirst:  s.headsFirst,
+			HeadsMissed: s.headsMissed,
+			HeadMean:    mean(s.headDelays),
+			HeadP50:     percentile(s.headDelays, 0.5),
+			HeadP95:     percentile(s.headDelays, 0.95),
+			TxSeen:      s.txs,
+			TxFirst:     s.txsFirst,
+			TxMissed:    s.txsMissed,
+			TxMean:      mean(s.txDelays),
+			TxP95:       percentile(s.txDelays, 0.95),
+		}
+	}
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].HeadsFirst > scores[j].HeadsFirst })
+	return scores
+}
+
+// RegisterAPI 注册排行榜查询接口
+//
+//	GET /api/propagation 各节点的传播延迟统计（延迟单位为纳秒）
+func (r *PropagationRace) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/propagation", func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, r.Scores())
+	})
+}
+
+// PrintPropagationScores 默认的排行榜输出
+func PrintPropagationScores(scores []EndpointScore) {
+	var sb strings.Builder
+	summary := fmt.Sprintf("🏁 [Propagation] %d 个节点的传播延迟排行", len(scores))
+	sb.WriteString("\n" + summary + "\n")
+	fmt.Fprintf(&sb, "   %-16s %8s %8s %6s %10s %10s %10s %8s %8s %10s\n",
+		"节点", "区块头", "最先", "漏掉", "平均落后", "P50", "P95", "交易", "最先", "交易P95")
+	for _, s := range scores {
+		fmt.Fprintf(&sb, "   %-16s %8d %8d %6d %10s %10s %10s %8d %8d %10s\n",
+			s.Name, s.HeadsSeen, s.HeadsFirst, s.HeadsMissed,
+			s.HeadMean.Round(time.Millisecond), s.HeadP50.Round(time.Millisecond), s.HeadP95.Round(time.Millisecond),
+			s.TxSeen, s.TxFirst, s.TxP95.Round(time.Millisecond))
+	}
+	Emit(Event{Type: "propagation_scores", Summary: summary, Text: sb.String(), Data: scores})
+}
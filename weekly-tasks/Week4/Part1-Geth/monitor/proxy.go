@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ProxyKind 代理合约的类型（按存放实现地址的存储槽区分）
+type ProxyKind string
+
+const (
+	ProxyEIP1967    ProxyKind = "eip1967"    // 透明代理 / UUPS 都使用 EIP-1967 的实现槽
+	ProxyBeacon     ProxyKind = "beacon"     // EIP-1967 Beacon 代理，实现地址要再问 beacon
+	ProxyEIP1822    ProxyKind = "eip1822"    // UUPS 早期的 PROXIABLE 槽
+	ProxyZeppelinOS ProxyKind = "zeppelinos" // OpenZeppelin 旧版代理
+)
+
+var (
+	// bytes32(uint256(keccak256("eip1967.proxy.implementation")) - 1)
+	eip1967ImplSlot = common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+	// bytes32(uint256(keccak256("eip1967.proxy.beacon")) - 1)
+	eip1967BeaconSlot = common.HexToHash("0xa3f0ad74e5423aebfd80d3ef4346578335a9a72aeaee59ff6cb3582b35133d50")
+	// keccak256("PROXIABLE")
+	eip1822Slot = common.HexToHash("0xc5f16f0fcc639fa48a6947836d9850f504798523bf8c9a3a87d5876cf622bcf7")
+	// keccak256("org.zeppelinos.proxy.implementation")
+	zeppelinOSSlot = common.HexToHash("0x7050c9e0f4ca769c69bd3a8ef740bc37934f8e2c036e5a723fd8ee048ed3f8c3")
+
+	// beacon 合约的 implementation() 函数选择器
+	beaconImplementationSelector = common.FromHex("0x5c60da1b")
+)
+
+// ProxyInfo 代理合约的解析结果
+type ProxyInfo struct {
+	Proxy          common.Address
+	Implementation common.Address
+	Beacon         common.Address // 仅 Beacon 代理
+	Kind           ProxyKind
+}
+
+// ResolveProxy 依次读取各标准的实现槽，addr 不是代理时返回 nil, nil
+// block 为 nil 表示最新区块
+func ResolveProxy(ctx context.Context, client *ethclient.Client, addr common.Address, block *big.Int) (*ProxyInfo, error) {
+	slots := []struct {
+		slot common.Hash
+		kind ProxyKind
+	}{
+		{eip1967ImplSlot, ProxyEIP1967},
+		{eip1967BeaconSlot, ProxyBeacon},
+		{eip1822Slot, ProxyEIP1822},
+		{zeppelinOSSlot, ProxyZeppelinOS},
+	}
+	for _, s := range slots {
+		var value []byte
+		err := Retry(ctx, "eth_getStorageAt", func() (err error) {
+			value, err = client.StorageAt(ctx, addr, s.slot, block)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("读取 %s 的存储槽失败: %w", addr.Hex(), err)
+		}
+		target := common.BytesToAddress(value)
+		if target == (common.Address{}) {
+			continue
+		}
+		if s.kind != ProxyBeacon {
+			return &ProxyInfo{Proxy: addr, Implementation: target, Kind: s.kind}, nil
+		}
+		out, err := callContractRetry(ctx, client, ethereum.CallMsg{To: &target, Data: beaconImplementationSelector}, block)
+		if err != nil {
+			return nil, fmt.Errorf("查询 beacon %s 的实现地址失败: %w", target.Hex(), err)
+		}
+		if len(out) < 32 {
+			return nil, fmt.Errorf("beacon %s 返回的实现地址格式错误", target.Hex())
+		}
+		return &ProxyInfo{Proxy: addr, Implementation: common.BytesToAddress(out[:32]), Beacon: target, Kind: ProxyBeacon}, nil
+	}
+	return nil, nil
+}
+
+// ProxyUpgrade 关注的代理合约发生了升级
+type ProxyUpgrade struct {
+	BlockNumber uint64
+	Proxy       common.Address
+	Kind        ProxyKind
+	Old, New    common.Address
+}
+
+// ProxyWatcher 每个区块读取关注代理的实现地址，发生变化时发出升级事件，
+// 并把新实现的 ABI 交给 ABIFetcher 拉取，保证解码始终使用最新的实现
+type ProxyWatcher struct {
+	client    *ethclient.Client
+	registry  *ABIRegistry
+	abis      *ABIFetcher // 可以为 nil
+	proxies   []common.Address
+	onUpgrade func(ProxyUpgrade)
+
+	mu      sync.Mutex
+	current map[common.Address]*ProxyInfo
+}
+
+// NewProxyWatcher 创建代理升级监控，onUpgrade 为 nil 时直接打印
+func NewProxyWatcher(client *ethclient.Client, registry *ABIRegistry, abis *ABIFetcher, proxies []common.Address, onUpgrade func(ProxyUpgrade)) *ProxyWatcher {
+	if onUpgrade == nil {
+		onUpgrade = PrintProxyUpgrade
+	}
+	return &ProxyWatcher{
+		client:    client,
+		registry:  registry,
+		abis:      abis,
+		proxies:   proxies,
+		onUpgrade: onUpgrade,
+		current:   make(map[common.Address]*ProxyInfo),
+	}
+}
+
+// Enrichment 实现 Enricher：只读区块高度，实现地址通过 eth_getStorageAt 查询
+func (w *ProxyWatcher) Enrichment() EnrichLevel { return EnrichHeader }
+
+// OnBlock 实现 BlockAnalyzer
+func (w *ProxyWatcher) OnBlock(data *BlockData) {
+	ctx, cancel := context.WithTimeout(context.Background(), CONNECTION_TIMEOUT)
+	defer cancel()
+	number := data.Block.Number()
+
+	for _, proxy := range w.proxies {
+		info, err := ResolveProxy(ctx, w.client, proxy, number)
+		if err != nil {
+			log.Printf("⚠️  读取代理 %s 的实现地址失败: %v", proxy.Hex(), err)
+			continue
+		}
+		if info == nil {
+			continue
+		}
+
+		w.mu.Lock()
+		prev := w.current[proxy]
+		w.current[proxy] = info
+		w.mu.Unlock()
+
+		if prev != nil && prev.Implementation == info.Implementation {
+			continue
+		}
+		w.registry.SetImplementation(proxy, info.Implementation)
+		if w.abis != nil {
+			w.abis.Enqueue(info.Implementation)
+		}
+		if prev == nil {
+			log.Printf("🔗 代理 %s (%s) 当前实现: %s", proxy.Hex(), info.Kind, info.Implementation.Hex())
+			continue
+		}
+		w.onUpgrade(ProxyUpgrade{
+			BlockNumber: number.Uint64(),
+			Proxy:       proxy,
+			Kind:        info.Kind,
+			Old:         prev.Implementation,
+			New:         info.Implementation,
+		})
+	}
+}
+
+// PrintProxyUpgrade 打印代理升级事件
+func PrintProxyUpgrade(u ProxyUpgrade) {
+	Emit(Event{Type: "proxy_upgrade", Key: eventKey(u.Proxy, u.BlockNumber, u.New), Data: u, Text: fmt.Sprintf("🔄 [Proxy Upgraded] 区块 %d | %s (%s) | %s -> %s",
+		u.BlockNumber, u.Proxy.Hex(), u.Kind, u.Old.Hex(), u.New.Hex())})
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ReorgWatchBlocks 达到确认数之后继续跟踪的区块数，超过后认为不会再被重组
+// 64 个区块约等于 2 个 epoch，即主网的最终确定性
+const ReorgWatchBlocks = 64
+
+// ConfirmationEvent 交易确认状态变化
+type ConfirmationEvent struct {
+	TxHash        common.Hash
+	Receipt       *types.Receipt // Revoked 时为重组前的回执
+	Confirmations uint64
+	// Revoked 为 true 表示之前报告过的确认因为重组被撤销（交易被移出主链，或确认数回落到要求以下）
+	// 交易之后重新被打包并达到确认数时，会再次收到 Revoked=false 的事件
+	Revoked bool
+	// Final 为 true 表示交易已进入最终确定区间，waiter 停止跟踪
+	Final bool
+}
+
+// waitEntry 一笔被等待的交易
+type waitEntry struct {
+	depth    uint64
+	notify   func(ConfirmationEvent)
+	receipt  *types.Receipt // 最近一次看到的回执，nil 表示尚未上链（或已被重组掉）
+	reported bool           // 是否已经报告过确认
+}
+
+// ReceiptWaiter 与区块头订阅联动的 WaitMined：
+// 每个新区块到来时检查登记的交易，达到确认数就通知调用方，发生重组时撤销之前的通知
+type ReceiptWaiter struct {
+	client *ethclient.Client
+	heads  chan *types.Header
+
+	mu      sync.Mutex
+	entries map[common.Hash]*waitEntry
+}
+
+func NewReceiptWaiter(client *ethclient.Client) *ReceiptWaiter {
+	return &ReceiptWaiter{
+		client:  client,
+		heads:   make(chan *types.Header, 1),
+		entries: make(map[common.Hash]*waitEntry),
+	}
+}
+
+// WaitFunc 登记一笔交易，状态变化时调用 fn（在 waiter 的 goroutine 中执行，不要阻塞）
+func (w *ReceiptWaiter) WaitFunc(hash common.Hash, depth uint64, fn func(ConfirmationEvent)) {
+	if depth == 0 {
+		depth = 1
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.entries[hash] = &waitEntry{depth: depth, notify: fn}
+}
+
+// Wait 登记一笔交易，通过 channel 接收状态变化
+// 收到 Final 事件后 channel 会被关闭；调用 Cancel 提前取消时 channel 不会关闭
+func (w *ReceiptWaiter) Wait(hash common.Hash, depth uint64) <-chan ConfirmationEvent {
+	ch := make(chan ConfirmationEvent, 4)
+	w.WaitFunc(hash, depth, func(ev ConfirmationEvent) {
+		select {
+		case ch <- ev:
+		default:
+			log.Printf("⚠️  确认事件无人接收，已丢弃: %s", ev.TxHash.Hex())
+		}
+		if ev.Final {
+			close(ch)
+		}
+	})
+	return ch
+}
+
+// Cancel 取消等待
+func (w *ReceiptWaiter) Cancel(hash common.Hash) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.entries, hash)
+}
+
+// WaitMined 阻塞直到交易达到 depth 个确认，返回回执
+// 期间发生的撤销会被忽略（继续等待重新确认）
+func (w *ReceiptWaiter) WaitMined(ctx context.Context, hash common.Hash, depth uint64) (*types.Receipt, error) {
+	done := make(chan *types.Receipt, 1)
+	w.WaitFunc(hash, depth, func(ev ConfirmationEvent) {
+		if ev.Final {
+			return
+		}
+		if ev.Revoked {
+			log.Printf("⚠️  交易 %s 的确认因重组被撤销，继续等待...", ev.TxHash.Hex())
+			return
+		}
+		select {
+		case done <- ev.Receipt:
+		default:
+		}
+	})
+	defer w.Cancel(hash)
+
+	select {
+	case receipt := <-done:
+		return receipt, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// NotifyHead 由区块头订阅调用，不会阻塞主循环
+// 如果上一个区块还没处理完，只保留最新的区块头（检查最新状态即可）
+func (w *ReceiptWaiter) NotifyHead(header *types.Header) {
+	select {
+	case w.heads <- header:
+	default:
+		select {
+		case <-w.heads:
+		default:
+		}
+		w.heads <- header
+	}
+}
+
+// Run 在独立 goroutine 中处理区块头，直到 ctx 取消
+func (w *ReceiptWaiter) Run(ctx context.Context) {
+	for {
+		select {
+		case header := <-w.heads:
+			w.check(ctx, header)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// check 根据最新区块头刷新所有登记交易的状态
+func (w *ReceiptWaiter) check(ctx context.Context, header *types.Header) {
+	w.mu.Lock()
+	hashes := make([]common.Hash, 0, len(w.entries))
+	for h := range w.entries {
+		hashes = append(hashes, h)
+	}
+	w.mu.Unlock()
+
+	head := header.Number.Uint64()
+	for _, hash := range hashes {
+		var receipt *types.Receipt
+		err := Retry(ctx, "eth_getTransactionReceipt", func() (err error) {
+			receipt, err = w.client.TransactionReceipt(ctx, hash)
+			return err
+		})
+		if err != nil && !errors.Is(err, ethereum.NotFound) {
+			log.Printf("⚠️  查询回执失败 %s: %v", hash.Hex(), err)
+			continue
+		}
+
+		w.mu.Lock()
+		entry, ok := w.entries[hash]
+		if !ok { // 查询期间被取消
+			w.mu.Unlock()
+			continue
+		}
+		events := w.update(hash, entry, receipt, head)
+		w.mu.Unlock()
+
+		for _, ev := range events {
+			entry.notify(ev)
+		}
+	}
+}
+
+// update 计算状态变化，返回需要发出的事件（调用方持有锁）
+func (w *ReceiptWaiter) update(hash common.Hash, entry *waitEntry, receipt *types.Receipt, head uint64) []ConfirmationEvent {
+	var events []ConfirmationEvent
+
+	// 1. 交易不在主链上：之前报告过确认则撤销
+	if receipt == nil {
+		if entry.reported {
+			events = append(events, ConfirmationEvent{TxHash: hash, Receipt: entry.receipt, Revoked: true})
+			log.Printf("♻️  [Reorg] 交易 %s 已被移出主链，撤销确认", hash.Hex())
+		}
+		entry.receipt, entry.reported = nil, false
+		return events
+	}
+
+	// 2. 节点返回的区块号比当前头还新（节点之间不同步），下个区块再看
+	if receipt.BlockNumber.Uint64() > head {
+		return nil
+	}
+	confirmations := head - receipt.BlockNumber.Uint64() + 1
+
+	// 3. 交易被重组到了另一个区块，且确认数回落到要求以下
+	moved := entry.receipt != nil && entry.receipt.BlockHash != receipt.BlockHash
+	if entry.reported && moved && confirmations < entry.depth {
+		events = append(events, ConfirmationEvent{TxHash: hash, Receipt: entry.receipt, Confirmations: confirmations, Revoked: true})
+		log.Printf("♻️  [Reorg] 交易 %s 被重新打包到区块 %d，撤销确认", hash.Hex(), receipt.BlockNumber)
+		entry.reported = false
+	}
+	entry.receipt = receipt
+
+	// 4. 达到确认数
+	if !entry.reported && confirmations >= entry.depth {
+		entry.reported = true
+		events = append(events, ConfirmationEvent{TxHash: hash, Receipt: receipt, Confirmations: confirmations})
+	}
+
+	// 5. 进入最终确定区间，停止跟踪
+	if confirmations >= entry.depth+ReorgWatchBlocks {
+		delete(w.entries, hash)
+		events = append(events, ConfirmationEvent{TxHash: hash, Receipt: receipt, Confirmations: confirmations, Final: true})
+	}
+	return events
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ------------------------------------------------
+// 区块回执拉取：优先用一次 eth_getBlockReceipts 拿到整个区块的回执，和区块体、调用树并行请求；
+// 节点不支持时（-32601）退回逐笔 eth_getTransactionReceipt，由一组 worker 并发拉取
+// ------------------------------------------------
+
+const (
+	// 逐笔拉取回执时的并发数
+	// ⚠️ 公共 RPC 通常有每秒请求数限制，并发太高会被限流
+	ReceiptWorkers = 8
+)
+
+// receiptsResult 回执拉取阶段的结果
+type receiptsResult struct {
+	receipts []*types.Receipt
+	err      error
+}
+
+// SetBlockReceipts 按启动时的能力探测设置节点是否支持 eth_getBlockReceipts，需要在 Run 之前调用
+func (f *BlockFetcher) SetBlockReceipts(supported bool) {
+	f.noBlockReceipts = !supported
+}
+
+// startBlockReceipts 在后台用 eth_getBlockReceipts 拉取区块回执，不依赖区块体，可以和 eth_getBlockByHash 同时进行
+// 已知节点不支持时返回 nil，由 awaitReceipts 逐笔拉取
+func (f *BlockFetcher) startBlockReceipts(ctx context.Context, hash common.Hash) <-chan receiptsResult {
+	if f.noBlockReceipts {
+		return nil
+	}
+	ch := make(chan receiptsResult, 1)
+	go func() {
+		var receipts []*types.Receipt
+		err := f.call(ctx, "eth_getBlockReceipts", func(c *ethclient.Client) (err error) {
+			receipts, err = c.BlockReceipts(ctx, rpc.BlockNumberOrHashWithHash(hash, false))
+			return err
+		})
+		ch <- receiptsResult{receipts, err}
+	}()
+	return ch
+}
+
+// awaitReceipts 等待 startBlockReceipts 的结果并和交易逐一对应；节点不支持 eth_getBlockReceipts 时改为逐笔拉取，之后的区块不再尝试
+func (f *BlockFetcher) awaitReceipts(ctx context.Context, block *types.Block, pending <-chan receiptsResult) ([]*types.Receipt, error) {
+	if pending != nil {
+		res := <-pending
+		switch {
+		case res.err == nil:
+			if len(res.receipts) != len(block.Transactions()) {
+				return nil, fmt.Errorf("回执数量 %d 与交易数量 %d 不一致", len(res.receipts), len(block.Transactions()))
+			}
+			return res.receipts, nil
+		case isUnsupported(res.err):
+			log.Printf("⚠️  节点不支持 eth_getBlockReceipts，改为逐笔拉取回执（每个区块 %d 个请求）", len(block.Transactions()))
+			f.noBlockReceipts = true
+		default:
+			return nil, fmt.Errorf("查询区块回执失败: %w", res.err)
+		}
+	}
+	return f.receiptsByTx(ctx, block)
+}
+
+// receiptsByTx 用 ReceiptWorkers 个 worker 逐笔拉取回执，任何一笔失败或不属于该区块（已被重组）时整体失败
+func (f *BlockFetcher) receiptsByTx(ctx context.Context, block *types.Block) ([]*types.Receipt, error) {
+	txs := block.Transactions()
+	receipts := make([]*types.Receipt, len(txs))
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int)
+	errs := make(chan error, 1)
+	var wg sync.WaitGroup
+	for w := 0; w < min(ReceiptWorkers, len(txs)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				var r *types.Receipt
+				err := f.call(ctx, "eth_getTransactionReceipt", func(c *ethclient.Client) (err error) {
+					r, err = c.TransactionReceipt(ctx, txs[i].Hash())
+					return err
+				})
+				if err == nil && r.BlockHash != block.Hash() {
+					err = fmt.Errorf("回执属于区块 %s（已被重组）", r.BlockHash.Hex())
+				}
+				if err != nil {
+					select {
+					case errs <- fmt.Errorf("查询交易 %s 的回执失败: %w", txs[i].Hash().Hex(), err):
+					default:
+					}
+					cancel()
+					return
+				}
+				receipts[i] = r
+			}
+		}()
+	}
+feed:
+	for i := range txs {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		return nil, err
+	default:
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return receipts, nil
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ------------------------------------------------
+// 配置热加载：收到 SIGHUP、调用 POST /api/reload 或（开启 reload_on_change 时）配置文件变化后重新读取配置，
+// 关注地址、日志过滤条件、提醒阈值、限流规则和输出配置直接生效，订阅和内存中的状态都保留；
+// 其它配置项有变化时不会部分生效，结果中列出需要重启的字段并返回错误。新配置校验失败时保持原配置
+// ------------------------------------------------
+
+// 检查配置文件是否变化的间隔
+const ConfigPollInterval = 2 * time.Second
+
+// ReloadResult 一次热加载的结果
+type ReloadResult struct {
+	Reason  string   `json:"reason"`           // signal / api / file
+	Applied []string `json:"applied"`          // 已生效的配置项
+	Restart []string `json:"restart"`          // 与启动时相比有变化、需要重启才能生效的字段，例如 mempool.filter
+	Error   string   `json:"error,omitempty"`  // 读取、校验、应用失败或有需要重启的字段
+	Failed  []string `json:"failed,omitempty"` // 应用失败的配置项
+}
+
+// reloadable 一个可以热加载的配置项
+type reloadable struct {
+	name  string
+	part  func(c *Config) interface{} // 返回这一项在 Config 中的指针，例如 &c.Watch
+	apply func(next *Config) error
+}
+
+// Reloader 持有当前生效的配置，按需重新读取并应用
+type Reloader struct {
+	path  string
+	items []reloadable
+
+	started *Config // 启动时的配置，不能热加载的部分一直以它为准
+
+	mu      sync.Mutex
+	current *Config
+	modTime time.Time
+
+	onReload func(ReloadResult)
+}
+
+// NewReloader 创建热加载器，current 为当前生效的配置；onReload 为 nil 时使用默认输出
+func NewReloader(path string, current *Config, onReload func(ReloadResult)) *Reloader {
+	if onReload == nil {
+		onReload = PrintReloadResult
+	}
+	r := &Reloader{path: path, started: current, current: current, onReload: onReload}
+	if info, err := os.Stat(path); err == nil {
+		r.modTime = info.ModTime()
+	}
+	return r
+}
+
+// Register 注册一个可以热加载的配置项，part 返回这一项在 Config 中的指针，变化时调用 apply；需要在 Run 之前调用
+func (r *Reloader) Register(name string, part func(c *Config) interface{}, apply func(next *Config) error) {
+	r.items = append(r.items, reloadable{name: name, part: part, apply: apply})
+}
+
+// Reload 重新读取配置文件并应用有变化的配置项
+func (r *Reloader) Reload(reason string) ReloadResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res := ReloadResult{Reason: reason}
+	if info, err := os.Stat(r.path); err == nil {
+		r.modTime = info.ModTime() // 通过信号 / API 加载后，文件检查不再重复加载
+	}
+	next, err := LoadConfig(r.path)
+	if err != nil {
+		res.Error = err.Error()
+		r.onReload(res)
+		return res
+	}
+	old := r.current
+	var errs []string
+	for _, item := range r.items {
+		if reflect.DeepEqual(item.part(old), item.part(next)) {
+			continue
+		}
+		if err := item.apply(next); err != nil {
+			res.Failed = append(res.Failed, item.name)
+			errs = append(errs, fmt.Sprintf("%s: %v", item.name, err))
+			// 这一项仍按原配置记录，下次加载时重试
+			reflect.ValueOf(item.part(next)).Elem().Set(reflect.ValueOf(item.part(old)).Elem())
+			continue
+		}
+		res.Applied = append(res.Applied, item.name)
+	}
+
+	// 清掉可以热加载的部分后，与启动时相比仍有变化的字段需要重启
+	oldRest, nextRest := *r.started, *next
+	for _, item := range r.items {
+		for _, c := range []*Config{&oldRest, &nextRest} {
+			v := reflect.ValueOf(item.part(c)).Elem()
+			v.Set(reflect.Zero(v.Type()))
+		}
+	}
+	res.Restart = changedFields("", reflect.ValueOf(oldRest), reflect.ValueOf(nextRest))
+	if len(res.Restart) > 0 {
+		errs = append(errs, "以下配置项需要重启才能生效: "+strings.Join(res.Restart, ", "))
+	}
+	res.Error = strings.Join(errs, "; ")
+	r.current = next
+	r.onReload(res)
+	return res
+}
+
+// changedFields 有变化的字段（JSON 键名，本包的结构体用 . 连接，例如 whale.window）；
+// map、切片和其它包的类型只报告到字段本身
+func changedFields(prefix string, old, next reflect.Value) []string {
+	t := old.Type()
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).IsExported() {
+			continue // 不在配置文件中
+		}
+		ov, nv := old.Field(i), next.Field(i)
+		if reflect.DeepEqual(ov.Interface(), nv.Interface()) {
+			continue
+		}
+		key := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if key == "" {
+			key = t.Field(i).Name
+		}
+		if ov.Kind() == reflect.Struct && ov.Type().PkgPath() == t.PkgPath() {
+			keys = append(keys, changedFields(prefix+key+".", ov, nv)...)
+			continue
+		}
+		keys = append(keys, prefix+key)
+	}
+	return keys
+}
+
+// Run 收到 SIGHUP 时重新加载；watch 为 true 时还定期检查配置文件的修改时间，直到 ctx 取消
+func (r *Reloader) Run(ctx context.Context, watch bool) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	var poll <-chan time.Time
+	if watch {
+		ticker := time.NewTicker(ConfigPollInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+	for {
+		select {
+		case <-hup:
+			r.Reload("signal")
+		case <-poll:
+			if r.fileChanged() {
+				r.Reload("file")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// fileChanged 配置文件的修改时间是否比上次检查时新；编辑器保存时可能短暂删除文件，此时不算变化
+func (r *Reloader) fileChanged() bool {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if info.ModTime().Equal(r.modTime) {
+		return false
+	}
+	r.modTime = info.ModTime()
+	return true
+}
+
+// RegisterAPI 注册热加载接口
+//
+//	POST /api/reload 重新读取配置文件，返回已生效和需要重启的配置项；有失败或需要重启的字段时返回 400
+func (r *Reloader) RegisterAPI(api *APIServer) {
+	api.Handle("POST /api/reload", func(w http.ResponseWriter, req *http.Request) {
+		res := r.Reload("api")
+		status := http.StatusOK
+		if res.Error != "" {
+			status = http.StatusBadRequest
+		}
+		writeJSON(w, status, res)
+	})
+}
+
+// PrintReloadResult 默认的热加载输出
+func PrintReloadResult(res ReloadResult) {
+	var summary string
+	switch {
+	case res.Error != "" && len(res.Failed) == 0 && len(res.Restart) == 0:
+		summary = fmt.Sprintf("🔄 [Reload] 重新加载配置失败（%s）: %s，保持原配置", res.Reason, res.Error)
+	case len(res.Applied) == 0 && res.Error == "":
+		summary = fmt.Sprintf("🔄 [Reload] 配置没有变化（%s）", res.Reason)
+	default:
+		summary = fmt.Sprintf("🔄 [Reload] 配置已重新加载（%s）", res.Reason)
+		if len(res.Applied) > 0 {
+			summary += " | 已生效: " + strings.Join(res.Applied, ", ")
+		}
+		if res.Error != "" {
+			summary += " | ⚠️ " + res.Error
+		}
+	}
+	Emit(Event{Type: "config_reload", Summary: summary, Text: summary, Data: res})
+}
+
+// registerReload 注册可以热加载的配置项并开启热加载，没有配置文件路径时什么也不做
+func (m *Monitor) registerReload(ctx context.Context, api *APIServer, subs *SubscriptionSet, logChan chan types.Log) {
+	if m.configPath == "" {
+		return
+	}
+	// 以文件中的配置为基准：启动时可能改过内存中的配置（例如订阅固定到了更快的节点）
+	base, err := LoadConfig(m.configPath)
+	if err != nil {
+		base = m.cfg
+	}
+	r := NewReloader(m.configPath, base, nil)
+	r.Register("watch", func(c *Config) interface{} { return &c.Watch }, func(next *Config) error {
+		added, removed := m.watch.ReplaceConfig(next.WatchAddresses())
+		log.Printf("👀 关注地址已更新: 新增 %d 个，移除 %d 个", added, removed)
+		return nil
+	})
+	r.Register("logs", func(c *Config) interface{} { return &c.Logs }, func(next *Config) error {
+		start := m.logStart(next.Logs, logChan)
+		if err := subs.Replace(StreamLogs, start); err != nil {
+			return err
+		}
+		// 原来没有配置合约地址（订阅不可用）时按配置开启
+		if start != nil && next.Subscriptions.Logs && !subs.Enabled(StreamLogs) {
+			return subs.Set(StreamLogs, true, "reload")
+		}
+		return nil
+	})
+	r.Register("prices.alert_usd", func(c *Config) interface{} { return &c.Prices.AlertUSD }, func(next *Config) error {
+		m.alert.SetThreshold(next.Prices.AlertUSD)
+		return nil
+	})
+	r.Register("throttle.rules", func(c *Config) interface{} { return &c.Throttle.Rules }, func(next *Config) error {
+		if m.throttle == nil {
+			return fmt.Errorf("启动时没有限流规则，需要重启才能开启")
+		}
+		m.throttle.SetRules(next.Throttle.Rules)
+		return nil
+	})
+	r.Register("whale.alert", func(c *Config) interface{} { return &c.Whale.Alert }, func(next *Config) error {
+		alert := next.Whale.Alert
+		switch {
+		case m.whales == nil || !alert.Enabled():
+			return fmt.Errorf("开启或关闭大额交易提醒需要重启")
+		case alert.Pending != m.cfg.Whale.Alert.Pending:
+			return fmt.Errorf("pending 需要重启才能生效")
+		case alert.Filter != "" && !currentFilters().Has(alert.Filter):
+			return fmt.Errorf("过滤链 %s 还没有加载，修改 filters 后需要重启", alert.Filter)
+		}
+		m.whales.SetConfig(alert)
+		return nil
+	})
+	r.Register("outputs", func(c *Config) interface{} { return &c.Outputs }, func(next *Config) error {
+		output, err := NewOutput(next.Outputs)
+		if err != nil {
+			return err
+		}
+		if err := output.Start(ctx); err != nil {
+			output.Close()
+			return err
+		}
+		SetDefaultOutput(output).Close()
+		return nil
+	})
+	r.Register("routing", func(c *Config) interface{} { return &c.Routing }, func(next *Config) error {
+		SetRouting(NewRouter(next.Routing))
+		return nil
+	})
+	r.RegisterAPI(api)
+	go r.Run(ctx, m.cfg.ReloadOnChange)
+	fmt.Printf("🔄 配置热加载已开启: %s（SIGHUP / POST /api/reload", m.configPath)
+	if m.cfg.ReloadOnChange {
+		fmt.Print(" / 文件变化")
+	}
+	fmt.Println("）")
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ------------------------------------------------
+// RPC 重试：超时、限流（429）、连接重置等临时错误按指数退避 + 随机抖动重试，
+// 参数错误、方法不存在、合约 revert 等永久错误直接返回，服务商的短暂抖动不会变成分析器的失败
+// 订阅不经过这里，断线由 Watchdog / 重连处理
+// ------------------------------------------------
+
+const (
+	DefaultRetryAttempts = 4    // 包括第一次调用
+	DefaultRetryBaseMs   = 200  // 第一次重试前的等待
+	DefaultRetryMaxMs    = 5000 // 单次等待的上限
+)
+
+// RetryConfig RPC 重试策略
+type RetryConfig struct {
+	MaxAttempts int `json:"max_attempts"` // 最多调用次数（包括第一次），1 表示不重试
+	BaseDelayMs int `json:"base_delay_ms"`
+	MaxDelayMs  int `json:"max_delay_ms"`
+}
+
+// rpcRetry 所有非订阅 RPC 调用共用的重试策略，Dial 时按配置设置，为 nil 时使用默认值；各分析器在自己的协程中读取
+var rpcRetry atomic.Pointer[RetryConfig]
+
+var rpcRetries = metrics.NewRegisteredCounter("monitor/rpc/retries", metricsRegistry)
+
+// SetRetryPolicy 替换全局重试策略
+func SetRetryPolicy(cfg RetryConfig) {
+	rpcRetry.Store(&cfg)
+}
+
+// currentRetryPolicy 当前的全局重试策略
+func currentRetryPolicy() RetryConfig {
+	if p := rpcRetry.Load(); p != nil {
+		return *p
+	}
+	return RetryConfig{MaxAttempts: DefaultRetryAttempts, BaseDelayMs: DefaultRetryBaseMs, MaxDelayMs: DefaultRetryMaxMs}
+}
+
+// Retry 调用 fn，遇到临时错误时按全局策略退避重试，返回最后一次的错误
+// what 用于日志，例如 "eth_getBlockByHash"；主节点不是 HTTP 时每次尝试按 what 计入 RPC 用量统计；ctx 取消时立即返回
+func Retry(ctx context.Context, what string, fn func() error) error {
+	return retry(ctx, what, retryCounts.Load(), fn)
+}
+
+// retry count 为 true 时每次尝试都计入 RPC 用量统计
+func retry(ctx context.Context, what string, count bool, fn func() error) error {
+	var err error
+	policy := currentRetryPolicy()
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		err = fn()
+		if count {
+			rpcUsage.Observe(what, time.Since(start), err)
+		}
+		if err == nil || attempt >= policy.MaxAttempts || !isTransient(ctx, err) {
+			return err
+		}
+		delay := policy.backoff(attempt)
+		rpcRetries.Inc(1)
+		log.Printf("🔁 %s 失败: %v（%s 后第 %d 次重试）", what, err, delay, attempt)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// callRetry 带重试的 CallContext，用于直接调用 *rpc.Client 的地方
+func callRetry(ctx context.Context, client *rpc.Client, result interface{}, method string, args ...interface{}) error {
+	return Retry(ctx, method, func() error {
+		return client.CallContext(ctx, result, method, args...)
+	})
+}
+
+// 下面是 ethclient 常用方法的带重试版本，其它方法在调用处直接包一层 Retry
+
+// callContractRetry 带重试的 eth_call
+func callContractRetry(ctx context.Context, client *ethclient.Client, msg ethereum.CallMsg, block *big.Int) (out []byte, err error) {
+	err = Retry(ctx, "eth_call", func() error {
+		out, err = client.CallContract(ctx, msg, block)
+		return err
+	})
+	return out, err
+}
+
+// codeAtRetry 带重试的 eth_getCode
+func codeAtRetry(ctx context.Context, client *ethclient.Client, addr common.Address, block *big.Int) (code []byte, err error) {
+	err = Retry(ctx, "eth_getCode", func() error {
+		code, err = client.CodeAt(ctx, addr, block)
+		return err
+	})
+	return code, err
+}
+
+// blockNumberRetry 带重试的 eth_blockNumber
+func blockNumberRetry(ctx context.Context, client *ethclient.Client) (n uint64, err error) {
+	err = Retry(ctx, "eth_blockNumber", func() error {
+		n, err = client.BlockNumber(ctx)
+		return err
+	})
+	return n, err
+}
+
+// chainIDRetry 带重试的 eth_chainId
+func chainIDRetry(ctx context.Context, client *ethclient.Client) (id *big.Int, err error) {
+	err = Retry(ctx, "eth_chainId", func() error {
+		id, err = client.ChainID(ctx)
+		return err
+	})
+	return id, err
+}
+
+// headerByNumberRetry 带重试的 HeaderByNumber，number 为 nil 时查询最新区块
+func headerByNumberRetry(ctx context.Context, client *ethclient.Client, number *big.Int) (header *types.Header, err error) {
+	err = Retry(ctx, "eth_getBlockByNumber", func() error {
+		header, err = client.HeaderByNumber(ctx, number)
+		return err
+	})
+	return header, err
+}
+
+// backoff 第 attempt 次失败后的等待：base * 2^(attempt-1)，不超过 max，再在 [d/2, d] 之间随机抖动，避免多个调用同时重试
+func (c RetryConfig) backoff(attempt int) time.Duration {
+	d := time.Duration(c.BaseDelayMs) * time.Millisecond << min(attempt-1, 16)
+	d = min(d, time.Duration(c.MaxDelayMs)*time.Millisecond)
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// isTransient 是否值得重试：调用方的 ctx 已经结束时不重试；
+// 单次调用的超时、网络错误、HTTP 429 / 5xx 和常见的限流错误信息视为临时错误
+func isTransient(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == 429 || httpErr.StatusCode >= 500
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32005 { // limit exceeded（EIP-1474）
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"rate limit", "too many requests", "connection reset", "broken pipe", "timeout", "timed out", "temporarily unavailable"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 轮转文件名中的时间格式，按字典序排列即为时间顺序
+const rotateTimeFormat = "20060102-150405"
+
+// RotatingFile 按大小 / 时间轮转的日志文件，旧文件按覆盖的时间段改名为 name-<时间段开始>.ext
+// （同一时间段内按大小轮转出的多个文件依次加 .1、.2 后缀），
+// 超过保留个数或保留天数的旧文件自动删除；重启后继续追加写入原文件，不会丢失历史
+type RotatingFile struct {
+	path     string
+	maxSize  int64         // 0 表示不按大小轮转
+	every    time.Duration // 0 表示不按时间轮转
+	maxFiles int           // 保留的旧文件个数，0 表示不限
+	maxAge   time.Duration // 旧文件的保留时间，0 表示不限
+
+	mu     sync.Mutex
+	f      *os.File
+	size   int64
+	period time.Time // 当前文件所属的时间段（按 every 对齐）
+	since  time.Time // 当前文件开始写入的时间，不按时间轮转时作为轮转后的文件名
+}
+
+// OpenRotatingFile 打开（或创建）日志文件，按配置轮转
+func OpenRotatingFile(path string, cfg OutputConfig) (*RotatingFile, error) {
+	r := &RotatingFile{
+		path:     path,
+		maxSize:  int64(cfg.MaxSizeMB) << 20,
+		every:    time.Duration(cfg.RotateHours) * time.Hour,
+		maxFiles: cfg.MaxFiles,
+		maxAge:   time.Duration(cfg.MaxAgeDays) * 24 * time.Hour,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	r.cleanup()
+	return r, nil
+}
+
+// open 以追加方式打开文件，时间段取文件最后修改的时间，重启后跨过时间段也会轮转；
+// 已有内容的文件不知道开始写入的时间，也按最后修改的时间计。失败时不改变当前打开的文件
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("打开输出文件失败: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("读取输出文件信息失败: %w", err)
+	}
+	r.f, r.size = f, info.Size()
+	r.period = r.periodOf(info.ModTime())
+	r.since = time.Now()
+	if r.size > 0 {
+		r.since = info.ModTime()
+	}
+	return nil
+}
+
+func (r *RotatingFile) periodOf(t time.Time) time.Time {
+	if r.every == 0 {
+		return time.Time{}
+	}
+	return t.Truncate(r.every)
+}
+
+// Write 实现 io.Writer，写入之前检查是否需要轮转；轮转失败时继续写入原文件
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	sizeFull := r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize
+	if sizeFull || (r.every > 0 && !r.periodOf(now).Equal(r.period)) {
+		if err := r.rotate(now); err != nil {
+			log.Printf("⚠️  日志文件轮转失败: %v", err)
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate 把当前文件改名为它覆盖的时间段，然后打开新文件（调用方持有锁）；
+// 新文件打开之前一直保留原来的句柄，打开失败时继续写入已改名的文件，下次写入时重试
+func (r *RotatingFile) rotate(now time.Time) error {
+	if r.size == 0 {
+		r.period, r.since = r.periodOf(now), now // 空文件没有必要改名
+		return nil
+	}
+	covered := r.since
+	if r.every > 0 {
+		covered = r.period
+	}
+	ext := filepath.Ext(r.path)
+	base := strings.TrimSuffix(r.path, ext)
+	dst := fmt.Sprintf("%s-%s%s", base, covered.Format(rotateTimeFormat), ext)
+	for i := 1; fileExists(dst); i++ {
+		dst = fmt.Sprintf("%s-%s.%d%s", base, covered.Format(rotateTimeFormat), i, ext)
+	}
+	if err := r.rename(dst); err != nil {
+		return err
+	}
+	old := r.f
+	if err := r.open(); err != nil {
+		return err
+	}
+	old.Close()
+	r.period = r.periodOf(now)
+	go r.cleanup()
+	return nil
+}
+
+// rename 把当前文件改名为 dst，上次已经改名（之后新文件打开失败）时什么也不做；
+// Windows 不能改名打开着的文件，先关闭再改名，然后重新打开改名后的文件继续持有
+func (r *RotatingFile) rename(dst string) error {
+	if !fileExists(r.path) {
+		return nil
+	}
+	err := os.Rename(r.path, dst)
+	if err == nil || runtime.GOOS != "windows" {
+		return err
+	}
+	r.f.Close()
+	err = os.Rename(r.path, dst)
+	if err != nil {
+		dst = r.path // 改名失败，继续写入原文件
+	}
+	f, openErr := os.OpenFile(dst, os.O_WRONLY|os.O_APPEND, 0o644)
+	if openErr != nil {
+		return fmt.Errorf("重新打开输出文件失败: %w", openErr)
+	}
+	r.f = f
+	return err
+}
+
+// cleanup 删除超过保留个数或保留时间的旧文件
+func (r *RotatingFile) cleanup() {
+	if r.maxFiles == 0 && r.maxAge == 0 {
+		return
+	}
+	ext := filepath.Ext(r.path)
+	old, err := filepath.Glob(strings.TrimSuffix(r.path, ext) + "-*" + ext)
+	if err != nil {
+		return
+	}
+	prefix := strings.TrimSuffix(r.path, ext) + "-"
+	type rotated struct {
+		path   string
+		period time.Time
+		seq    int // 同一时间段内的第几个文件
+	}
+	var files []rotated
+	for _, p := range old { // 只处理轮转产生的文件
+		stamp := strings.TrimSuffix(strings.TrimPrefix(p, prefix), ext)
+		if len(stamp) < len(rotateTimeFormat) {
+			continue
+		}
+		period, err := time.Parse(rotateTimeFormat, stamp[:len(rotateTimeFormat)])
+		if err != nil {
+			continue
+		}
+		seq := 0
+		if rest := stamp[len(rotateTimeFormat):]; rest != "" {
+			if seq, err = strconv.Atoi(strings.TrimPrefix(rest, ".")); err != nil || !strings.HasPrefix(rest, ".") {
+				continue
+			}
+		}
+		files = append(files, rotated{path: p, period: period, seq: seq})
+	}
+	sort.Slice(files, func(i, j int) bool { // 新的在前
+		if !files[i].period.Equal(files[j].period) {
+			return files[i].period.After(files[j].period)
+		}
+		return files[i].seq > files[j].seq
+	})
+	for i, file := range files {
+		p := file.path
+		expired := false
+		if r.maxAge > 0 {
+			if info, err := os.Stat(p); err == nil && time.Since(info.ModTime()) > r.maxAge {
+				expired = true
+			}
+		}
+		if (r.maxFiles > 0 && i >= r.maxFiles) || expired {
+			if err := os.Remove(p); err != nil {
+				log.Printf("⚠️  删除旧日志文件 %s 失败: %v", p, err)
+			}
+		}
+	}
+}
+
+// Close 关闭当前文件
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,152 @@
+package main
+
+// ------------------------------------------------
+// RPC 传输层统计：所有节点连接都通过 dialRPC 建立
+// HTTP 节点通过自定义的 http.RoundTripper 统计，每个实际发出的 JSON-RPC 请求（批量请求中的每一项、Retry 的每次尝试）都计一次；
+// WebSocket / IPC 连接原样交给 go-ethereum（订阅和推送不经过任何中间层），请求由 Retry / poolCall 按方法名计入
+// ------------------------------------------------
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// errNoResult HTTP 响应中缺少某一项请求的结果
+var errNoResult = errors.New("响应中没有该请求的结果")
+
+// retryCounts 主节点（ws_url）不是 HTTP 时为 true：传输层看不到请求，由 Retry 按方法名计入
+// Retry 不知道闭包里用的是哪个连接，按主节点判断（Dial 时设置）
+var retryCounts atomic.Bool
+
+// dialRPC 连接节点；HTTP 节点发出的每个请求都在传输层计入 RPC 用量统计，
+// WebSocket / IPC 直接由 go-ethereum 连接（保留地址中的用户名密码等处理），请求由 Retry / poolCall 计入
+func dialRPC(ctx context.Context, rawURL string) (*rpc.Client, error) {
+	if transportMetered(rawURL) {
+		return rpc.DialOptions(ctx, rawURL, rpc.WithHTTPClient(&http.Client{Transport: newMeteredTransport(nil)}))
+	}
+	return rpc.DialContext(ctx, rawURL)
+}
+
+// transportMetered 节点地址是否走 HTTP，请求已经在传输层统计
+func transportMetered(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+// rpcEnvelope JSON-RPC 消息中统计需要的字段，请求和响应共用
+type rpcEnvelope struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// isCall 带 id 的请求（订阅推送和通知没有 id）
+func (e *rpcEnvelope) isCall() bool {
+	return e.Method != "" && len(e.ID) > 0 && string(e.ID) != "null"
+}
+
+// err 响应中的错误
+func (e *rpcEnvelope) err() error {
+	if e.Error == nil {
+		return nil
+	}
+	return fmt.Errorf("%s (%d)", e.Error.Message, e.Error.Code)
+}
+
+// parseEnvelopes 解析单条或批量消息，格式不对时返回 nil
+func parseEnvelopes(msg []byte) []rpcEnvelope {
+	msg = bytes.TrimLeft(msg, " \t\r\n")
+	if len(msg) > 0 && msg[0] == '[' {
+		var batch []rpcEnvelope
+		if json.Unmarshal(msg, &batch) != nil {
+			return nil
+		}
+		return batch
+	}
+	var one rpcEnvelope
+	if json.Unmarshal(msg, &one) != nil {
+		return nil
+	}
+	return []rpcEnvelope{one}
+}
+
+// meteredTransport 统计经过的 HTTP JSON-RPC 请求
+type meteredTransport struct {
+	base http.RoundTripper
+}
+
+// newMeteredTransport base 为 nil 时使用 http.DefaultTransport（同样读取代理环境变量）
+func newMeteredTransport(base http.RoundTripper) *meteredTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &meteredTransport{base: base}
+}
+
+func (t *meteredTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var calls []rpcEnvelope
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, _ := io.ReadAll(body)
+			body.Close()
+			calls = parseEnvelopes(data)
+		}
+	}
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		observeCalls(calls, start, err)
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		observeCalls(calls, start, fmt.Errorf("HTTP %s", resp.Status))
+		return resp, nil
+	}
+	// 读完整个响应才能按 id 找到每一项的结果，延迟也以读完为准
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		observeCalls(calls, start, err)
+		return resp, nil
+	}
+	elapsed := time.Since(start)
+	results := make(map[string]error, len(calls))
+	for _, r := range parseEnvelopes(data) {
+		results[string(r.ID)] = r.err()
+	}
+	for _, c := range calls {
+		if c.Method == "" {
+			continue
+		}
+		err, ok := results[string(c.ID)]
+		if !ok && c.isCall() {
+			err = errNoResult
+		}
+		rpcUsage.Observe(c.Method, elapsed, err)
+	}
+	return resp, nil
+}
+
+// observeCalls 整个请求失败时每一项都计一次失败
+func observeCalls(calls []rpcEnvelope, start time.Time, err error) {
+	elapsed := time.Since(start)
+	for _, c := range calls {
+		if c.Method != "" {
+			rpcUsage.Observe(c.Method, elapsed, err)
+		}
+	}
+}
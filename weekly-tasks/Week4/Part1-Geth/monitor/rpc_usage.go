@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// ------------------------------------------------
+// 按 JSON-RPC 方法统计调用次数、失败次数和延迟：看清楚服务商账单主要花在哪些方法上、时间耗在哪里
+// HTTP 节点的请求在传输层（rpc_transport.go）计入，WebSocket 节点的请求由 Retry / poolCall 计入（每次尝试都算），
+// 指标名为 monitor/rpc/method/<方法>，
+// 并定期输出一份按调用次数排序的汇总
+// ------------------------------------------------
+
+const (
+	// 默认的汇总间隔（分钟）
+	DefaultRPCUsageReportMinutes = 10
+
+	// 汇总中列出的方法数
+	RPCUsageTopMethods = 10
+
+	// 每个方法保留的最近延迟样本数，用于计算 p95（指标服务没有开启时 Timer 不记录）
+	RPCUsageSamples = 512
+)
+
+// RPCMethodUsage 一个方法的累计统计
+type RPCMethodUsage struct {
+	Method     string        `json:"method"`
+	Calls      uint64        `json:"calls"`
+	Errors     uint64        `json:"errors"`
+	TotalTime  time.Duration `json:"total_time"`
+	AvgLatency time.Duration `json:"avg_latency"`
+	P95Latency time.Duration `json:"p95_latency"` // 最近样本的 p95
+}
+
+// RPCUsageReport 一个汇总周期的统计（只包含本周期的调用）
+type RPCUsageReport struct {
+	Since   time.Time        `json:"since"`
+	Calls   uint64           `json:"calls"`
+	Errors  uint64           `json:"errors"`
+	Methods []RPCMethodUsage `json:"methods"` // 按本周期调用次数从多到少
+}
+
+// rpcMethod 一个方法的计数和延迟分布
+type rpcMethod struct {
+	calls, errors uint64
+	total         time.Duration
+	samples       []time.Duration // 最近 RPCUsageSamples 次的延迟（环形）
+	timer         *metrics.Timer
+	errCounter    *metrics.Counter
+}
+
+// RPCUsage 所有方法的统计，全局只有一个（rpcUsage）
+type RPCUsage struct {
+	mu      sync.Mutex
+	methods map[string]*rpcMethod
+	last    map[string]RPCMethodUsage // 上次汇总时的累计值
+	since   time.Time
+
+	onReport func(RPCUsageReport)
+}
+
+var rpcUsage = NewRPCUsage(nil)
+
+// NewRPCUsage 创建统计，onReport 为 nil 时使用默认输出
+func NewRPCUsage(onReport func(RPCUsageReport)) *RPCUsage {
+	if onReport == nil {
+		onReport = PrintRPCUsageReport
+	}
+	return &RPCUsage{
+		methods:  make(map[string]*rpcMethod),
+		last:     make(map[string]RPCMethodUsage),
+		since:    time.Now(),
+		onReport: onReport,
+	}
+}
+
+// Observe 记录一次调用，由 HTTP 传输层或 Retry / 节点池在调用返回后调用
+func (u *RPCUsage) Observe(method string, elapsed time.Duration, err error) {
+	u.mu.Lock()
+	m, ok := u.methods[method]
+	if !ok {
+		m = &rpcMethod{
+			timer:      metrics.GetOrRegisterTimer("monitor/rpc/method/"+method, metricsRegistry),
+			errCounter: metrics.GetOrRegisterCounter("monitor/rpc/method_errors/"+method, metricsRegistry),
+		}
+		u.methods[method] = m
+	}
+	if len(m.samples) < RPCUsageSamples {
+		m.samples = append(m.samples, elapsed)
+	} else {
+		m.samples[m.calls%RPCUsageSamples] = elapsed
+	}
+	m.calls++
+	m.total += elapsed
+	if err != nil {
+		m.errors++
+	}
+	u.mu.Unlock()
+
+	m.timer.Update(elapsed)
+	rpcBudget.Load().Add(method)
+	if err != nil {
+		m.errCounter.Inc(1)
+	}
+}
+
+// Snapshot 每个方法启动以来的累计统计，按调用次数从多到少
+func (u *RPCUsage) Snapshot() []RPCMethodUsage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	out := make([]RPCMethodUsage, 0, len(u.methods))
+	for name, m := range u.methods {
+		out = append(out, u.usage(name, m))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Calls > out[j].Calls })
+	return out
+}
+
+// usage 调用方持有锁
+func (u *RPCUsage) usage(name string, m *rpcMethod) RPCMethodUsage {
+	s := RPCMethodUsage{Method: name, Calls: m.calls, Errors: m.errors, TotalTime: m.total}
+	if m.calls > 0 {
+		s.AvgLatency = m.total / time.Duration(m.calls)
+	}
+	if len(m.samples) > 0 {
+		sorted := append([]time.Duration(nil), m.samples...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		s.P95Latency = sorted[len(sorted)*95/100]
+	}
+	return s
+}
+
+// Report 本周期（距上次汇总）的统计，并开始新的周期
+func (u *RPCUsage) Report() RPCUsageReport {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	r := RPCUsageReport{Since: u.since}
+	for name, m := range u.methods {
+		cur := u.usage(name, m)
+		prev := u.last[name]
+		u.last[name] = cur
+		d := RPCMethodUsage{Method: name, Calls: cur.Calls - prev.Calls, Errors: cur.Errors - prev.Errors,
+			TotalTime: cur.TotalTime - prev.TotalTime, P95Latency: cur.P95Latency}
+		if d.Calls == 0 {
+			continue
+		}
+		d.AvgLatency = d.TotalTime / time.Duration(d.Calls)
+		r.Calls += d.Calls
+		r.Errors += d.Errors
+		r.Methods = append(r.Methods, d)
+	}
+	sort.Slice(r.Methods, func(i, j int) bool { return r.Methods[i].Calls > r.Methods[j].Calls })
+	u.since = time.Now()
+	return r
+}
+
+// Run 每 interval 输出一次汇总，直到 ctx 取消
+func (u *RPCUsage) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if r := u.Report(); r.Calls > 0 {
+				u.onReport(r)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// RegisterAPI 注册 RPC 用量查询接口
+//
+//	GET /api/rpc-usage 每个方法启动以来的调用次数、失败次数和延迟
+func (u *RPCUsage) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/rpc-usage", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, u.Snapshot())
+	})
+}
+
+// PrintRPCUsageReport 默认的用量汇总输出
+func PrintRPCUsageReport(r RPCUsageReport) {
+	minutes := time.Since(r.Since).Minutes()
+	summary := fmt.Sprintf("📞 [RPC] 最近 %.0f 分钟 %d 次调用（%.1f 次/秒），失败 %d 次", minutes, r.Calls, float64(r.Calls)/(minutes*60), r.Errors)
+	var b strings.Builder
+	b.WriteString(summary)
+	for i, m := range r.Methods {
+		if i == RPCUsageTopMethods {
+			fmt.Fprintf(&b, "\n   ... 其余 %d 个方法", len(r.Methods)-i)
+			break
+		}
+		fmt.Fprintf(&b, "\n   %-28s %7d 次 %5.1f%% | 失败 %d | 平均 %s | p95 %s", m.Method, m.Calls,
+			float64(m.Calls)*100/float64(r.Calls), m.Errors, m.AvgLatency.Round(time.Millisecond), m.P95Latency.Round(time.Millisecond))
+	}
+	Emit(Event{Type: "rpc_usage", Summary: summary, Text: b.String(), Data: r})
+}
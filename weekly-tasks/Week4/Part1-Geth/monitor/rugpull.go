@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	// 默认统计窗口（区块数）
+	DefaultRugWindowBlocks = 5
+	// 默认告警阈值：窗口内被移除的流动性占比
+	DefaultRugThresholdPct = 50.0
+)
+
+var (
+	uniV2SyncTopic = crypto.Keccak256Hash([]byte("Sync(uint112,uint112)"))
+	uniV2BurnTopic = crypto.Keccak256Hash([]byte("Burn(address,uint256,uint256,address)"))
+	uniV3BurnTopic = crypto.Keccak256Hash([]byte("Burn(address,int24,int24,uint128,uint256,uint256)"))
+)
+
+// liquidityRemoval 一次移除流动性
+type liquidityRemoval struct {
+	block    uint64
+	tx       common.Hash
+	lp       common.Address // 交易发送者（Burn 事件里的 sender 通常是路由 / NFT 管理合约）
+	fraction float64        // 移除前池子中关注代币的占比
+}
+
+// RugPullAlert 短时间内池子中关注代币的流动性被大量移除
+type RugPullAlert struct {
+	Pool       common.Address
+	Token      common.Address
+	FromBlock  uint64
+	ToBlock    uint64
+	RemovedPct float64
+	LPs        []common.Address
+	TxHashes   []common.Hash
+}
+
+// RugPullDetector 监控关注代币所在池子（Uniswap V2 / V3）的 Burn 事件，
+// window 个区块内累计移除的流动性超过阈值时提醒，并给出执行移除的 LP 地址
+type RugPullDetector struct {
+	client       *ethclient.Client
+	tokens       map[common.Address]bool
+	window       uint64
+	thresholdPct float64
+	onAlert      func(RugPullAlert)
+
+	pools    map[common.Address][2]common.Address // 池子 -> (token0, token1)，非池子合约为零值
+	removals map[common.Address][]liquidityRemoval
+	alerted  map[common.Address]uint64 // 池子 -> 上次提醒的区块，同一窗口内不重复提醒
+}
+
+// NewRugPullDetector 创建撤池检测，onAlert 为 nil 时输出到控制台
+func NewRugPullDetector(cfg RugPullConfig, client *ethclient.Client, onAlert func(RugPullAlert)) *RugPullDetector {
+	if onAlert == nil {
+		onAlert = PrintRugPullAlert
+	}
+	d := &RugPullDetector{
+		client:       client,
+		tokens:       make(map[common.Address]bool),
+		window:       cfg.WindowBlocks,
+		thresholdPct: cfg.ThresholdPct,
+		onAlert:      onAlert,
+		pools:        make(map[common.Address][2]common.Address),
+		removals:     make(map[common.Address][]liquidityRemoval),
+		alerted:      make(map[common.Address]uint64),
+	}
+	for _, t := range cfg.Tokens {
+		d.tokens[common.HexToAddress(t)] = true
+	}
+	return d
+}
+
+// Warm 实现 Warmer：只记录窗口内的流动性移除，启动后的第一次移除就能和之前的累计
+func (d *RugPullDetector) Warm(data *BlockData) {
+	d.scan(data, false)
+}
+
+// OnBlock 实现 BlockAnalyzer
+func (d *RugPullDetector) OnBlock(data *BlockData) {
+	d.scan(data, true)
+}
+
+// scan 记录区块中关注代币池子的流动性移除，alert 为 true 时检查是否超过阈值
+func (d *RugPullDetector) scan(data *BlockData, alert bool) {
+	block := data.Block.NumberU64()
+	for i, receipt := range data.Receipts {
+		for j, l := range receipt.Logs {
+			if len(l.Topics) == 0 || (l.Topics[0] != uniV2BurnTopic && l.Topics[0] != uniV3BurnTopic) {
+				continue
+			}
+			token, idx, ok := d.watchedToken(l.Address)
+			if !ok {
+				continue
+			}
+			fraction, ok := d.removedFraction(receipt.Logs[:j], *l, token, idx)
+			if !ok || fraction <= 0 {
+				continue
+			}
+			d.removals[l.Address] = append(d.removals[l.Address], liquidityRemoval{
+				block: block, tx: l.TxHash, lp: data.Senders[i], fraction: fraction,
+			})
+			if alert {
+				d.check(l.Address, token, block)
+			}
+		}
+	}
+	d.prune(block)
+}
+
+// LogFilters 实现 LogFilterer：Uniswap V2 / V3 的 Burn 事件（池子地址事先未知，只按 topic 过滤）
+func (d *RugPullDetector) LogFilters() []ethereum.FilterQuery {
+	return []ethereum.FilterQuery{{Topics: [][]common.Hash{{uniV2BurnTopic, uniV3BurnTopic}}}}
+}
+
+// watchedToken 池子中的关注代币及其下标（0 / 1）
+func (d *RugPullDetector) watchedToken(pool common.Address) (common.Address, int, bool) {
+	pair, ok := d.pools[pool]
+	if !ok {
+		pair = d.fetchPool(pool)
+		d.pools[pool] = pair
+	}
+	for i, t := range pair {
+		if d.tokens[t] {
+			return t, i, true
+		}
+	}
+	return common.Address{}, 0, false
+}
+
+// fetchPool 查询 token0 / token1，失败时返回零值（不是池子，或暂时查不到，之后都当作无关合约）
+func (d *RugPullDetector) fetchPool(pool common.Address) [2]common.Address {
+	ctx, cancel := context.WithTimeout(context.Background(), CONNECTION_TIMEOUT)
+	defer cancel()
+	var pair [2]common.Address
+	for i, sel := range [][]byte{selectorToken0, selectorToken1} {
+		out, err := callContractRetry(ctx, d.client, ethereum.CallMsg{To: &pool, Data: sel}, nil)
+		if err != nil || len(out) < 32 {
+			return [2]common.Address{}
+		}
+		pair[i] = common.BytesToAddress(out[:32])
+	}
+	return pair
+}
+
+// removedFraction 本次 Burn 移除的关注代币数量占移除前池子储备的比例
+//   - V2：Burn 之前同一池子的 Sync 给出移除后的储备，移除前 = 移除后 + 移除量
+//   - V3：移除的代币在 collect 之前仍在池子里，用该区块结束时池子的余额作为移除前的储备（近似值）
+func (d *RugPullDetector) removedFraction(before []*types.Log, l types.Log, token common.Address, idx int) (float64, bool) {
+	switch {
+	case l.Topics[0] == uniV2BurnTopic && len(l.Data) == 2*32:
+		removed := word(l.Data, idx)
+		for k := len(before) - 1; k >= 0; k-- {
+			s := before[k]
+			if s.Address == l.Address && len(s.Topics) == 1 && s.Topics[0] == uniV2SyncTopic && len(s.Data) == 2*32 {
+				total := new(big.Int).Add(word(s.Data, idx), removed)
+				return ratio(removed, total), total.Sign() > 0
+			}
+		}
+	case l.Topics[0] == uniV3BurnTopic && len(l.Data) == 3*32:
+		removed := word(l.Data, 1+idx)
+		ctx, cancel := context.WithTimeout(context.Background(), CONNECTION_TIMEOUT)
+		defer cancel()
+		data := append(append([]byte{}, selectorBalanceOf...), common.LeftPadBytes(l.Address.Bytes(), 32)...)
+		out, err := callContractRetry(ctx, d.client, ethereum.CallMsg{To: &token, Data: data}, new(big.Int).SetUint64(l.BlockNumber))
+		if err != nil || len(out) < 32 {
+			return 0, false
+		}
+		balance := new(big.Int).SetBytes(out[:32])
+		return ratio(removed, balance), balance.Sign() > 0
+	}
+	return 0, false
+}
+
+// check 窗口内按顺序累计剩余比例：每次移除后剩下 (1 - fraction)
+func (d *RugPullDetector) check(pool, token common.Address, block uint64) {
+	if last, ok := d.alerted[pool]; ok && block-last < d.window {
+		return
+	}
+	remaining := 1.0
+	var lps []common.Address
+	var txs []common.Hash
+	seen := make(map[common.Address]bool)
+	list := d.removals[pool]
+	for _, r := range list {
+		remaining *= 1 - r.fraction
+		txs = append(txs, r.tx)
+		if !seen[r.lp] {
+			seen[r.lp] = true
+			lps = append(lps, r.lp)
+		}
+	}
+	removed := (1 - remaining) * 100
+	if removed < d.thresholdPct {
+		return
+	}
+	d.alerted[pool] = block
+	d.onAlert(RugPullAlert{
+		Pool: pool, Token: token, FromBlock: list[0].block, ToBlock: block,
+		RemovedPct: removed, LPs: lps, TxHashes: txs,
+	})
+}
+
+// prune 丢弃窗口之外的移除记录
+func (d *RugPullDetector) prune(block uint64) {
+	for pool, list := range d.removals {
+		k := sort.Search(len(list), func(i int) bool { return list[i].block+d.window > block })
+		if k == len(list) {
+			delete(d.removals, pool)
+		} else {
+			d.removals[pool] = list[k:]
+		}
+	}
+}
+
+// PrintRugPullAlert 默认的撤池提醒
+func PrintRugPullAlert(a RugPullAlert) {
+	lps := make([]string, len(a.LPs))
+	for i, lp := range a.LPs {
+		lps[i] = lp.Hex()
+	}
+	Emit(Event{Type: "rug_pull", Key: eventKey(a.Pool, a.FromBlock, a.ToBlock), Data: a, Text: fmt.Sprintf("🚨 [Rug Pull] 区块 %d - %d | 池子 %s 中 %s 的流动性被移除 %.1f%% | LP: %s | %d 笔交易",
+		a.FromBlock, a.ToBlock, a.Pool.Hex(), a.Token.Hex(), a.RemovedPct, strings.Join(lps, ", "), len(a.TxHashes))})
+}
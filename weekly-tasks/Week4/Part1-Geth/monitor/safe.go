@@ -0,0 +1,606 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ------------------------------------------------
+// Safe 多签监控：解码关注 Safe 的 execTransaction 调用和 ExecutionSuccess / ExecutionFailure 事件，
+// 输出内层调用（MultiSend 逐笔展开）、签名的 owner 和门限；交易池中的执行在上链之前就提醒，
+// owner / 门限 / 模块 / guard 的变化单独提醒（模块可以绕过签名直接执行）
+// ------------------------------------------------
+
+const (
+	// /api/safes/{address} 中保留的最近执行数量
+	SafeHistory = 50
+)
+
+const safeABIJSON = `[
+	{"type":"function","name":"getOwners","inputs":[],"outputs":[{"name":"","type":"address[]"}],"stateMutability":"view"},
+	{"type":"function","name":"getThreshold","inputs":[],"outputs":[{"name":"","type":"uint256"}],"stateMutability":"view"},
+	{"type":"function","name":"nonce","inputs":[],"outputs":[{"name":"","type":"uint256"}],"stateMutability":"view"},
+	{"type":"function","name":"VERSION","inputs":[],"outputs":[{"name":"","type":"string"}],"stateMutability":"view"}
+]`
+
+var safeABI = mustParseABI(safeABIJSON)
+
+var (
+	safeExecTransactionSelector = crypto.Keccak256([]byte("execTransaction(address,uint256,bytes,uint8,uint256,uint256,uint256,address,address,bytes)"))[:4]
+	safeMultiSendSelector       = crypto.Keccak256([]byte("multiSend(bytes)"))[:4]
+
+	// 1.3.0 之后 txHash 不是 indexed，1.4.1 的部分事件改成了 indexed，两种都要处理
+	safeExecutionSuccessTopic = crypto.Keccak256Hash([]byte("ExecutionSuccess(bytes32,uint256)"))
+	safeExecutionFailureTopic = crypto.Keccak256Hash([]byte("ExecutionFailure(bytes32,uint256)"))
+	safeAddedOwnerTopic       = crypto.Keccak256Hash([]byte("AddedOwner(address)"))
+	safeRemovedOwnerTopic     = crypto.Keccak256Hash([]byte("RemovedOwner(address)"))
+	safeChangedThresholdTopic = crypto.Keccak256Hash([]byte("ChangedThreshold(uint256)"))
+	safeEnabledModuleTopic    = crypto.Keccak256Hash([]byte("EnabledModule(address)"))
+	safeDisabledModuleTopic   = crypto.Keccak256Hash([]byte("DisabledModule(address)"))
+	safeChangedGuardTopic     = crypto.Keccak256Hash([]byte("ChangedGuard(address)"))
+)
+
+// SafeConfig Safe 多签监控，safes 为空时不启动
+type SafeConfig struct {
+	Safes []string `json:"safes"`
+}
+
+// SafeSigner execTransaction 中的一个签名
+type SafeSigner struct {
+	Owner common.Address `json:"owner"`
+	Kind  string         `json:"kind"`  // eoa / eth_sign / approved_hash（链上 approveHash 或执行者本人）/ contract（EIP-1271）
+	Owned bool           `json:"owned"` // 是否是当前的 owner
+}
+
+// SafeCall Safe 执行的一个调用（MultiSend 展开后有多个）
+type SafeCall struct {
+	To        common.Address `json:"to"`
+	Value     *big.Int       `json:"value"`
+	Operation uint8          `json:"operation"` // 0 CALL, 1 DELEGATECALL
+	Call      string         `json:"call"`
+}
+
+// SafeExecution 一次 execTransaction
+type SafeExecution struct {
+	Safe       common.Address `json:"safe"`
+	SafeTxHash common.Hash    `json:"safe_tx_hash"`
+	Nonce      *big.Int       `json:"nonce,omitempty"` // 只有 Pending 时查询
+	Calls      []SafeCall     `json:"calls"`
+	Signers    []SafeSigner   `json:"signers"`
+	Threshold  int            `json:"threshold"`
+	Owners     int            `json:"owners"`
+	Executor   common.Address `json:"executor"`
+	Success    bool           `json:"success"`
+	Payment    *big.Int       `json:"payment,omitempty"` // 付给 refundReceiver 的 gas 报销
+	Pending    bool           `json:"pending"`
+	Block      uint64         `json:"block,omitempty"`
+	TxHash     common.Hash    `json:"tx_hash"`
+}
+
+// SafeConfigChange owner / 门限 / 模块 / guard 的变化
+type SafeConfigChange struct {
+	Safe    common.Address `json:"safe"`
+	Kind    string         `json:"kind"` // added_owner / removed_owner / changed_threshold / enabled_module / disabled_module / changed_guard
+	Address common.Address `json:"address,omitempty"`
+	Value   uint64         `json:"value,omitempty"` // 新门限
+	Block   uint64         `json:"block"`
+	TxHash  common.Hash    `json:"tx_hash"`
+}
+
+// safeState 一个 Safe 的当前配置
+type safeState struct {
+	Version   string           `json:"version"`
+	Owners    []common.Address `json:"owners"`
+	Threshold int              `json:"threshold"`
+	Recent    []SafeExecution  `json:"recent"`
+}
+
+// safeExecCall calldata 中解码出的一次 execTransaction
+type safeExecCall struct {
+	safe SafeTx
+	sigs []byte
+}
+
+// SafeMonitor 监控关注 Safe 的执行和配置变化
+type SafeMonitor struct {
+	client  *ethclient.Client
+	signer  types.Signer
+	chainID *big.Int
+	abis    *ABIRegistry
+
+	mu    sync.RWMutex
+	safes map[common.Address]*safeState
+
+	onExec   func(SafeExecution)
+	onChange func(SafeConfigChange)
+}
+
+// NewSafeMonitor 创建 Safe 监控，回调为 nil 时使用默认输出
+func NewSafeMonitor(cfg SafeConfig, client *ethclient.Client, signer types.Signer, chainID *big.Int, abis *ABIRegistry, onExec func(SafeExecution), onChange func(SafeConfigChange)) *SafeMonitor {
+	if onExec == nil {
+		onExec = PrintSafeExecution
+	}
+	if onChange == nil {
+		onChange = PrintSafeConfigChange
+	}
+	m := &SafeMonitor{
+		client:   client,
+		signer:   signer,
+		chainID:  chainID,
+		abis:     abis,
+		safes:    make(map[common.Address]*safeState),
+		onExec:   onExec,
+		onChange: onChange,
+	}
+	for _, s := range cfg.Safes {
+		m.safes[common.HexToAddress(s)] = &safeState{}
+	}
+	return m
+}
+
+// Sync 读取每个 Safe 的版本、owner 和门限
+func (m *SafeMonitor) Sync(ctx context.Context, block *big.Int) {
+	for addr := range m.safes {
+		if err := m.sync(ctx, addr, block); err != nil {
+			log.Printf("⚠️  读取 Safe %s 的配置失败: %v", addr.Hex(), err)
+		}
+	}
+}
+
+func (m *SafeMonitor) sync(ctx context.Context, safe common.Address, block *big.Int) error {
+	ctx, cancel := context.WithTimeout(ctx, CONNECTION_TIMEOUT)
+	defer cancel()
+	owners, err := m.call(ctx, safe, block, "getOwners")
+	if err != nil {
+		return err
+	}
+	threshold, err := m.call(ctx, safe, block, "getThreshold")
+	if err != nil {
+		return err
+	}
+	version, _ := m.call(ctx, safe, block, "VERSION") // 很旧的 Safe 没有 VERSION()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := m.safes[safe]
+	first := st.Version == "" && st.Threshold == 0
+	st.Owners, _ = owners.([]common.Address)
+	if t, ok := threshold.(*big.Int); ok {
+		st.Threshold = int(t.Int64())
+	}
+	st.Version, _ = version.(string)
+	if first {
+		log.Printf("🔐 Safe %s (v%s): %d / %d 多签", safe.Hex(), st.Version, st.Threshold, len(st.Owners))
+	}
+	return nil
+}
+
+// call 调用 Safe 的只读函数，返回第一个返回值
+func (m *SafeMonitor) call(ctx context.Context, safe common.Address, block *big.Int, method string) (interface{}, error) {
+	input, err := safeABI.Pack(method)
+	if err != nil {
+		return nil, err
+	}
+	out, err := callContractRetry(ctx, m.client, ethereum.CallMsg{To: &safe, Data: input}, block)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	values, err := safeABI.Unpack(method, out)
+	if err != nil || len(values) == 0 {
+		return nil, fmt.Errorf("%s 返回数据格式错误", method)
+	}
+	return values[0], nil
+}
+
+// OnBlock 实现 BlockAnalyzer：按顺序把 Safe 的执行事件和同一笔交易 calldata 中对它的 execTransaction 对应起来
+func (m *SafeMonitor) OnBlock(data *BlockData) {
+	number := data.Block.Number()
+	for i, tx := range data.Block.Transactions() {
+		r := data.Receipts[i]
+		if r.Status == 0 {
+			continue
+		}
+		var calls map[common.Address][]safeExecCall
+		for _, l := range r.Logs {
+			if _, ok := m.safes[l.Address]; !ok || len(l.Topics) == 0 {
+				continue
+			}
+			switch l.Topics[0] {
+			case safeExecutionSuccessTopic, safeExecutionFailureTopic:
+				if calls == nil {
+					calls = m.execCalls(tx.To(), tx.Data())
+				}
+				hash, payment := safeExecutionLog(l)
+				exec := SafeExecution{
+					Safe: l.Address, SafeTxHash: hash, Payment: payment, Executor: data.Senders[i],
+					Success: l.Topics[0] == safeExecutionSuccessTopic, Block: number.Uint64(), TxHash: tx.Hash(),
+				}
+				// 同一个 Safe 在一笔交易中被执行多次时按出现顺序对应
+				if queue := calls[l.Address]; len(queue) > 0 {
+					m.describe(&exec, queue[0])
+					calls[l.Address] = queue[1:]
+				}
+				m.record(exec)
+				m.onExec(exec)
+			default:
+				if change, ok := safeConfigLog(l); ok {
+					change.Block, change.TxHash = number.Uint64(), tx.Hash()
+					if err := m.sync(context.Background(), l.Address, number); err != nil {
+						log.Printf("⚠️  刷新 Safe %s 的配置失败: %v", l.Address.Hex(), err)
+					}
+					m.onChange(change)
+				}
+			}
+		}
+	}
+}
+
+// Observe 交易池中对关注 Safe 的 execTransaction，上链之前提醒（签名摘要用当前 nonce 计算）
+func (m *SafeMonitor) Observe(msg BusMessage) {
+	tx := msg.Tx
+	if tx == nil || tx.To() == nil || len(tx.Data()) < 4 {
+		return
+	}
+	calls := m.execCalls(tx.To(), tx.Data())
+	if len(calls) == 0 {
+		return
+	}
+	executor, err := types.Sender(m.signer, tx)
+	if err != nil {
+		return
+	}
+	for safe, list := range calls {
+		ctx, cancel := context.WithTimeout(context.Background(), CONNECTION_TIMEOUT)
+		out, err := m.call(ctx, safe, nil, "nonce")
+		cancel()
+		nonce, ok := out.(*big.Int)
+		if err != nil || !ok {
+			continue
+		}
+		for k, c := range list {
+			c.safe.Nonce = new(big.Int).Add(nonce, big.NewInt(int64(k)))
+			c.safe.ChainID = m.domainChainID(safe)
+			hash, err := HashTypedData(NewSafeTxTypedData(c.safe))
+			if err != nil {
+				continue
+			}
+			exec := SafeExecution{Safe: safe, SafeTxHash: hash, Nonce: c.safe.Nonce, Executor: executor, Pending: true, TxHash: tx.Hash()}
+			m.describe(&exec, c)
+			m.onExec(exec)
+		}
+	}
+}
+
+// domainChainID Safe 1.3.0 之前的 domain 中没有 chainId
+func (m *SafeMonitor) domainChainID(safe common.Address) *big.Int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if v := m.safes[safe].Version; v != "" && (strings.HasPrefix(v, "1.0") || strings.HasPrefix(v, "1.1") || strings.HasPrefix(v, "1.2")) {
+		return nil
+	}
+	return m.chainID
+}
+
+// execCalls 找出 calldata（包括 multicall 等展开的内层调用）中对关注 Safe 的 execTransaction
+func (m *SafeMonitor) execCalls(to *common.Address, data []byte) map[common.Address][]safeExecCall {
+	out := make(map[common.Address][]safeExecCall)
+	call, ok := m.abis.DecodeCall(to, data)
+	if !ok {
+		return out
+	}
+	var walk func(c *DecodedCall, target common.Address)
+	walk = func(c *DecodedCall, target common.Address) {
+		if c.Target != nil {
+			target = *c.Target
+		}
+		if _, watched := m.safes[target]; watched && c.Selector == hexutil.Encode(safeExecTransactionSelector) {
+			if e, ok := safeExecArgs(c, target); ok {
+				out[target] = append(out[target], e)
+			}
+			return
+		}
+		for _, in := range c.Inner {
+			walk(in, target)
+		}
+	}
+	walk(call, *to)
+	return out
+}
+
+// safeExecArgs 从解码后的 execTransaction 取出 SafeTx 和签名
+func safeExecArgs(c *DecodedCall, safe common.Address) (safeExecCall, bool) {
+	t := SafeTx{Safe: safe}
+	var ok [9]bool
+	t.To, ok[0] = c.Arg("to").(common.Address)
+	t.Value, ok[1] = c.Arg("value").(*big.Int)
+	t.Data, ok[2] = c.Arg("data").([]byte)
+	t.Operation, ok[3] = c.Arg("operation").(uint8)
+	t.SafeTxGas, ok[4] = c.Arg("safeTxGas").(*big.Int)
+	t.BaseGas, ok[5] = c.Arg("baseGas").(*big.Int)
+	t.GasPrice, ok[6] = c.Arg("gasPrice").(*big.Int)
+	t.GasToken, ok[7] = c.Arg("gasToken").(common.Address)
+	t.RefundReceiver, ok[8] = c.Arg("refundReceiver").(common.Address)
+	for _, v := range ok {
+		if !v {
+			return safeExecCall{}, false
+		}
+	}
+	sigs, _ := c.Arg("signatures").([]byte)
+	return safeExecCall{safe: t, sigs: sigs}, true
+}
+
+// describe 填充内层调用和签名者
+func (m *SafeMonitor) describe(exec *SafeExecution, c safeExecCall) {
+	exec.Calls = m.safeCalls(c.safe.To, c.safe.Value, c.safe.Data, c.safe.Operation)
+
+	m.mu.RLock()
+	st := m.safes[exec.Safe]
+	owners := make(map[common.Address]bool, len(st.Owners))
+	for _, o := range st.Owners {
+		owners[o] = true
+	}
+	exec.Threshold, exec.Owners = st.Threshold, len(st.Owners)
+	m.mu.RUnlock()
+
+	if exec.SafeTxHash == (common.Hash{}) {
+		return
+	}
+	for _, s := range safeSignatures(exec.SafeTxHash, c.sigs, exec.Threshold) {
+		s.Owned = owners[s.Owner]
+		if s.Kind == "approved_hash" && s.Owner == exec.Executor {
+			s.Kind = "executor"
+		}
+		exec.Signers = append(exec.Signers, s)
+	}
+}
+
+// safeCalls 解码 Safe 执行的调用；DELEGATECALL 到 MultiSend 时逐笔展开
+func (m *SafeMonitor) safeCalls(to common.Address, value *big.Int, data []byte, operation uint8) []SafeCall {
+	if operation == 1 && len(data) >= 4 && equalSelector(data, safeMultiSendSelector) {
+		if packed, ok := multiSendPayload(data); ok {
+			var out []SafeCall
+			for _, c := range parseMultiSend(packed) {
+				out = append(out, m.safeCalls(c.To, c.Value, c.data, c.Operation)...)
+			}
+			return out
+		}
+	}
+	c := SafeCall{To: to, Value: value, Operation: operation}
+	if call, ok := m.abis.DecodeCall(&to, data); ok {
+		c.Call = call.String()
+	} else if len(data) == 0 {
+		c.Call = "(转账)"
+	}
+	return []SafeCall{c}
+}
+
+// multiSendPayload multiSend(bytes) 的参数
+func multiSendPayload(data []byte) ([]byte, bool) {
+	if len(data) < 4+64 {
+		return nil, false
+	}
+	offset := new(big.Int).SetBytes(data[4:36])
+	if !offset.IsUint64() || offset.Uint64()+32 > uint64(len(data)-4) {
+		return nil, false
+	}
+	start := 4 + offset.Uint64()
+	size := new(big.Int).SetBytes(data[start : start+32])
+	if !size.IsUint64() || start+32+size.Uint64() > uint64(len(data)) {
+		return nil, false
+	}
+	return data[start+32 : start+32+size.Uint64()], true
+}
+
+// multiSendCall MultiSend 打包的一笔调用
+type multiSendCall struct {
+	Operation uint8
+	To        common.Address
+	Value     *big.Int
+	data      []byte
+}
+
+// parseMultiSend 解析 MultiSend 的紧凑编码：operation(1) ‖ to(20) ‖ value(32) ‖ dataLength(32) ‖ data
+func parseMultiSend(packed []byte) []multiSendCall {
+	var out []multiSendCall
+	for i := 0; i+85 <= len(packed); {
+		size := new(big.Int).SetBytes(packed[i+53 : i+85])
+		if !size.IsUint64() || uint64(i+85)+size.Uint64() > uint64(len(packed)) {
+			break
+		}
+		end := i + 85 + int(size.Uint64())
+		out = append(out, multiSendCall{
+			Operation: packed[i],
+			To:        common.BytesToAddress(packed[i+1 : i+21]),
+			Value:     new(big.Int).SetBytes(packed[i+21 : i+53]),
+			data:      packed[i+85 : end],
+		})
+		i = end
+	}
+	return out
+}
+
+// safeSignatures 按 Safe checkNSignatures 的规则解析签名：
+// v = 0 合约签名（r 为 owner），v = 1 预先批准的哈希（r 为 owner），v > 30 为 eth_sign，其余为 EIP-712 签名
+// 静态部分每个 65 字节，合约签名的动态数据附在后面，按门限或第一个动态数据的偏移确定签名个数
+func safeSignatures(hash common.Hash, sigs []byte, threshold int) []SafeSigner {
+	n := len(sigs) / 65
+	if threshold > 0 && threshold < n {
+		n = threshold
+	}
+	var out []SafeSigner
+	for i := 0; i < n; i++ {
+		sig := sigs[i*65 : i*65+65]
+		r, v := sig[:32], sig[64]
+		switch {
+		case v == 0:
+			out = append(out, SafeSigner{Owner: common.BytesToAddress(r), Kind: "contract"})
+			if s := binary.BigEndian.Uint64(sig[56:64]); s%65 == 0 && int(s/65) < n {
+				n = int(s / 65)
+			}
+		case v == 1:
+			out = append(out, SafeSigner{Owner: common.BytesToAddress(r), Kind: "approved_hash"})
+		default:
+			digest, kind := hash.Bytes(), "eoa"
+			if v > 30 {
+				digest, kind, v = accounts.TextHash(hash.Bytes()), "eth_sign", v-4
+			}
+			normalized := append(common.CopyBytes(sig[:64]), v)
+			normalized, err := normalizeSignature(normalized)
+			if err != nil {
+				continue
+			}
+			pub, err := crypto.SigToPub(digest, normalized)
+			if err != nil {
+				continue
+			}
+			out = append(out, SafeSigner{Owner: crypto.PubkeyToAddress(*pub), Kind: kind})
+		}
+	}
+	return out
+}
+
+// safeExecutionLog ExecutionSuccess / ExecutionFailure 的 txHash 和 payment
+func safeExecutionLog(l *types.Log) (common.Hash, *big.Int) {
+	if len(l.Topics) >= 2 {
+		return l.Topics[1], word(l.Data, 0)
+	}
+	if len(l.Data) < 64 {
+		return common.Hash{}, nil
+	}
+	return common.BytesToHash(l.Data[:32]), word(l.Data, 1)
+}
+
+// safeConfigLog 解析 owner / 门限 / 模块 / guard 变化事件（参数可能是 indexed 也可能不是）
+func safeConfigLog(l *types.Log) (SafeConfigChange, bool) {
+	kinds := map[common.Hash]string{
+		safeAddedOwnerTopic:       "added_owner",
+		safeRemovedOwnerTopic:     "removed_owner",
+		safeChangedThresholdTopic: "changed_threshold",
+		safeEnabledModuleTopic:    "enabled_module",
+		safeDisabledModuleTopic:   "disabled_module",
+		safeChangedGuardTopic:     "changed_guard",
+	}
+	kind, ok := kinds[l.Topics[0]]
+	if !ok {
+		return SafeConfigChange{}, false
+	}
+	var arg common.Hash
+	switch {
+	case len(l.Topics) >= 2:
+		arg = l.Topics[1]
+	case len(l.Data) >= 32:
+		arg = common.BytesToHash(l.Data[:32])
+	default:
+		return SafeConfigChange{}, false
+	}
+	c := SafeConfigChange{Safe: l.Address, Kind: kind}
+	if kind == "changed_threshold" {
+		c.Value = arg.Big().Uint64()
+	} else {
+		c.Address = common.BytesToAddress(arg.Bytes())
+	}
+	return c, true
+}
+
+// record 保存最近的执行
+func (m *SafeMonitor) record(exec SafeExecution) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := m.safes[exec.Safe]
+	st.Recent = append(st.Recent, exec)
+	if len(st.Recent) > SafeHistory {
+		st.Recent = st.Recent[len(st.Recent)-SafeHistory:]
+	}
+}
+
+// RegisterAPI 注册 Safe 查询接口
+//
+//	GET /api/safes/{address} Safe 的版本、owner、门限和最近的执行
+func (m *SafeMonitor) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/safes/{address}", func(w http.ResponseWriter, r *http.Request) {
+		if !common.IsHexAddress(r.PathValue("address")) {
+			writeError(w, http.StatusBadRequest, "地址格式错误")
+			return
+		}
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		st, ok := m.safes[common.HexToAddress(r.PathValue("address"))]
+		if !ok {
+			writeError(w, http.StatusNotFound, "不是关注的 Safe")
+			return
+		}
+		writeJSON(w, http.StatusOK, st)
+	})
+}
+
+// PrintSafeExecution 默认的 Safe 执行输出
+func PrintSafeExecution(e SafeExecution) {
+	where := fmt.Sprintf("区块 %d", e.Block)
+	if e.Pending {
+		where = fmt.Sprintf("Pending nonce %s", e.Nonce)
+	}
+	status := "✅"
+	if !e.Success && !e.Pending {
+		status = "❌ 执行失败"
+	}
+	summary := fmt.Sprintf("🔐 [Safe] %s | %s %s | %d 个调用，%d / %d 签名 | 执行者 %s | %s",
+		where, e.Safe.Hex(), status, len(e.Calls), len(e.Signers), e.Threshold, e.Executor.Hex(), e.TxHash.Hex())
+	var sb strings.Builder
+	sb.WriteString(summary)
+	for i, c := range e.Calls {
+		op := ""
+		if c.Operation == 1 {
+			op = " ⚠️ DELEGATECALL"
+		}
+		fmt.Fprintf(&sb, "\n   #%d -> %s%s", i, c.To.Hex(), op)
+		if c.Value != nil && c.Value.Sign() > 0 {
+			fmt.Fprintf(&sb, " (%s ETH)", formatUnits(c.Value, 18))
+		}
+		if c.Call != "" {
+			sb.WriteString(" " + c.Call)
+		}
+	}
+	for _, s := range e.Signers {
+		mark := ""
+		if !s.Owned {
+			mark = " ⚠️ 不是当前 owner"
+		}
+		fmt.Fprintf(&sb, "\n   ✍️  %s (%s)%s", s.Owner.Hex(), s.Kind, mark)
+	}
+	Emit(Event{Type: "safe_exec", Key: eventKey(e.Safe, e.SafeTxHash, e.TxHash, e.Pending), Summary: summary, Text: sb.String(), Data: e})
+}
+
+// PrintSafeConfigChange 默认的 Safe 配置变化提醒
+func PrintSafeConfigChange(c SafeConfigChange) {
+	var what string
+	switch c.Kind {
+	case "added_owner":
+		what = "添加 owner " + c.Address.Hex()
+	case "removed_owner":
+		what = "移除 owner " + c.Address.Hex()
+	case "changed_threshold":
+		what = fmt.Sprintf("门限改为 %d", c.Value)
+	case "enabled_module":
+		what = "⚠️ 启用模块 " + c.Address.Hex() + "（模块可以不经签名直接执行）"
+	case "disabled_module":
+		what = "停用模块 " + c.Address.Hex()
+	case "changed_guard":
+		what = "⚠️ guard 改为 " + c.Address.Hex()
+	}
+	text := fmt.Sprintf("🔐 [Safe Config] 区块 %d | %s %s | %s", c.Block, c.Safe.Hex(), what, c.TxHash.Hex())
+	Emit(Event{Type: "safe_config", Key: eventKey(c.TxHash, c.Safe, c.Kind, c.Address, c.Value), Summary: text, Text: text, Data: c})
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// 名单文件的检查间隔，文件修改后自动重新加载
+	DenylistReloadInterval = 5 * time.Minute
+)
+
+// 名单文件格式不限（txt / csv / json 都可以），只提取其中所有的 0x 地址
+var denylistAddrRe = regexp.MustCompile(`0x[0-9a-fA-F]{40}`)
+
+// SanctionHit 关注钱包与名单地址之间的交互
+type SanctionHit struct {
+	Pending     bool
+	BlockNumber uint64
+	TxHash      common.Hash
+	Kind        string // "tx"（交易本身）或 "transfer"（ERC-20 转账）
+	Token       common.Address
+	Amount      *big.Int
+	Watched     common.Address
+	Listed      common.Address
+	Outgoing    bool   // true 表示关注钱包转给名单地址
+	List        string // 命中的名单文件
+}
+
+// Denylist 从一个或多个文件加载的地址名单（例如由 OFAC SDN 整理出的以太坊地址）
+type Denylist struct {
+	paths []string
+
+	mu     sync.RWMutex
+	addrs  map[common.Address]string // 地址 -> 名单文件名
+	mtimes map[string]time.Time
+}
+
+// LoadDenylist 加载名单文件，任一文件读取失败都返回错误
+func LoadDenylist(paths []string) (*Denylist, error) {
+	d := &Denylist{paths: paths, mtimes: make(map[string]time.Time)}
+	if err := d.reload(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// reload 重新读取所有文件，成功后整体替换
+func (d *Denylist) reload() error {
+	addrs := make(map[common.Address]string)
+	mtimes := make(map[string]time.Time)
+	for _, p := range d.paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return fmt.Errorf("读取名单 %s 失败: %w", p, err)
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("读取名单 %s 失败: %w", p, err)
+		}
+		for _, m := range denylistAddrRe.FindAll(data, -1) {
+			addrs[common.HexToAddress(string(m))] = filepath.Base(p)
+		}
+		mtimes[p] = info.ModTime()
+	}
+	d.mu.Lock()
+	d.addrs, d.mtimes = addrs, mtimes
+	d.mu.Unlock()
+	return nil
+}
+
+// changed 是否有文件在上次加载之后被修改
+func (d *Denylist) changed() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, p := range d.paths {
+		if info, err := os.Stat(p); err == nil && !info.ModTime().Equal(d.mtimes[p]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Lookup 地址是否在名单中，返回所在的名单文件；d 为 nil 时总是 false
+func (d *Denylist) Lookup(addr common.Address) (string, bool) {
+	if d == nil {
+		return "", false
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	list, ok := d.addrs[addr]
+	return list, ok
+}
+
+// Len 名单中的地址数
+func (d *Denylist) Len() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.addrs)
+}
+
+// Run 定期检查名单文件，修改后重新加载，直到 ctx 取消
+func (d *Denylist) Run(ctx context.Context) {
+	ticker := time.NewTicker(DenylistReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if !d.changed() {
+				continue
+			}
+			if err := d.reload(); err != nil {
+				log.Printf("⚠️  重新加载制裁名单失败: %v（继续使用旧名单）", err)
+				continue
+			}
+			log.Printf("✅ 制裁名单已重新加载: %d 个地址", d.Len())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// SanctionsScreener 检查关注钱包与名单地址之间的交易和 ERC-20 转账（包括交易池中的交易）
+type SanctionsScreener struct {
+	list  *Denylist
+	watch *Watchlist
+	onHit func(SanctionHit)
+}
+
+// NewSanctionsScreener 创建名单筛查，onHit 为 nil 时输出到控制台
+func NewSanctionsScreener(list *Denylist, watch *Watchlist, onHit func(SanctionHit)) *SanctionsScreener {
+	if onHit == nil {
+		onHit = PrintSanctionHit
+	}
+	return &SanctionsScreener{list: list, watch: watch, onHit: onHit}
+}
+
+// match 一方是配置中的关注钱包、另一方在名单中时返回命中记录
+func (s *SanctionsScreener) match(from, to common.Address) (SanctionHit, bool) {
+	if s.watch.Source(from) == WatchSourceConfig {
+		if list, ok := s.list.Lookup(to); ok {
+			return SanctionHit{Watched: from, Listed: to, Outgoing: true, List: list}, true
+		}
+	}
+	if s.watch.Source(to) == WatchSourceConfig {
+		if list, ok := s.list.Lookup(from); ok {
+			return SanctionHit{Watched: to, Listed: from, List: list}, true
+		}
+	}
+	return SanctionHit{}, false
+}
+
+// OnBlock 实现 BlockAnalyzer
+func (s *SanctionsScreener) OnBlock(data *BlockData) {
+	block := data.Block.NumberU64()
+	for i, tx := range data.Block.Transactions() {
+		if tx.To() != nil {
+			if hit, ok := s.match(data.Senders[i], *tx.To()); ok {
+				hit.BlockNumber, hit.TxHash, hit.Kind, hit.Amount = block, tx.Hash(), "tx", tx.Value()
+				s.onHit(hit)
+			}
+		}
+		for _, l := range data.Receipts[i].Logs {
+			if len(l.Topics) != 3 || l.Topics[0] != erc20TransferTopic || len(l.Data) != 32 {
+				continue
+			}
+			from, to := common.BytesToAddress(l.Topics[1].Bytes()), common.BytesToAddress(l.Topics[2].Bytes())
+			if hit, ok := s.match(from, to); ok {
+				hit.BlockNumber, hit.TxHash, hit.Kind = block, tx.Hash(), "transfer"
+				hit.Token, hit.Amount = l.Address, new(big.Int).SetBytes(l.Data)
+				s.onHit(hit)
+			}
+		}
+	}
+}
+
+// InspectPending 检查交易池中的交易，关注钱包即将与名单地址交互时在上链之前提醒
+func (s *SanctionsScreener) InspectPending(tx *types.Transaction, from common.Address) {
+	if tx.To() == nil {
+		return
+	}
+	if hit, ok := s.match(from, *tx.To()); ok {
+		hit.Pending, hit.TxHash, hit.Kind, hit.Amount = true, tx.Hash(), "tx", tx.Value()
+		s.onHit(hit)
+	}
+}
+
+// PrintSanctionHit 默认的名单命中提醒
+func PrintSanctionHit(h SanctionHit) {
+	where := fmt.Sprintf("区块 %d", h.BlockNumber)
+	if h.Pending {
+		where = "Pending"
+	}
+	dir := "收到来自"
+	if h.Outgoing {
+		dir = "转给"
+	}
+	what := fmt.Sprintf("%.4f ETH", weiToEther(h.Amount))
+	if h.Kind == "transfer" {
+		what = fmt.Sprintf("代币 %s 数量 %s", h.Token.Hex(), h.Amount)
+	}
+	Emit(Event{Type: "sanction_hit", Key: eventKey(h.TxHash, h.Pending, h.Kind, h.Token, h.Watched, h.Listed, h.Amount), Data: h, Text: fmt.Sprintf("🚨 [Sanctioned] %s | %s | %s %s 名单地址 %s (%s) | %s",
+		where, h.TxHash.Hex(), h.Watched.Hex(), dir, h.Listed.Hex(), h.List, what)})
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// poolSwap 区块中的一次 Uniswap V2 / V3 swap
+type poolSwap struct {
+	txIndex    int
+	pool       common.Address
+	zeroForOne bool // token0 换 token1
+	in, out    *big.Int
+	recipient  common.Address
+	sender     common.Address  // 交易的发送者
+	to         *common.Address // 交易调用的合约
+}
+
+// parseSwap 解析 Swap 日志，不是 V2 / V3 Swap 时返回 false
+func parseSwap(l *types.Log) (poolSwap, bool) {
+	s := poolSwap{pool: l.Address}
+	switch {
+	case len(l.Topics) == 3 && l.Topics[0] == uniV2SwapTopic && len(l.Data) == 4*32:
+		in0, in1, out0, out1 := word(l.Data, 0), word(l.Data, 1), word(l.Data, 2), word(l.Data, 3)
+		s.zeroForOne = in0.Sign() > 0
+		if s.zeroForOne {
+			s.in, s.out = in0, out1
+		} else {
+			s.in, s.out = in1, out0
+		}
+	case len(l.Topics) == 3 && l.Topics[0] == uniV3SwapTopic && len(l.Data) == 5*32:
+		amount0, amount1 := signedWord(l.Data, 0), signedWord(l.Data, 1)
+		s.zeroForOne = amount0.Sign() > 0
+		if s.zeroForOne {
+			s.in, s.out = amount0, new(big.Int).Neg(amount1)
+		} else {
+			s.in, s.out = amount1, new(big.Int).Neg(amount0)
+		}
+	default:
+		return s, false
+	}
+	s.recipient = common.BytesToAddress(l.Topics[2].Bytes())
+	return s, true
+}
+
+// sameActor 两次 swap 是否来自同一个攻击者：同一个发送者、同一个机器人合约或同一个接收地址
+func (a poolSwap) sameActor(b poolSwap) bool {
+	return a.sender == b.sender || a.recipient == b.recipient ||
+		(a.to != nil && b.to != nil && *a.to == *b.to)
+}
+
+// SandwichAlert 关注地址的 swap 被夹
+type SandwichAlert struct {
+	Block            uint64          `json:"block"`
+	Victim           common.Address  `json:"victim"`
+	VictimTx         common.Hash     `json:"victim_tx"`
+	Pool             common.Address  `json:"pool"`
+	Attacker         common.Address  `json:"attacker"`                    // 前置交易的发送者
+	AttackerContract *common.Address `json:"attacker_contract,omitempty"` // 前置交易调用的合约
+	FrontTx          common.Hash     `json:"front_tx"`
+	BackTx           common.Hash     `json:"back_tx"`
+	Token            common.Address  `json:"token"`  // 攻击者投入并换回的代币
+	Profit           *big.Int        `json:"profit"` // 攻击者换回减投入（代币最小单位），近似为受害者的损失
+	LossUSD          float64         `json:"loss_usd"`
+	Priced           bool            `json:"priced"` // LossUSD 是否可用
+}
+
+// SandwichDetector 在区块中找关注地址被三明治攻击的 swap：
+// 同一个池子里，受害者之前有同方向的 swap、之后有反方向的 swap，且前后两笔属于同一个攻击者
+type SandwichDetector struct {
+	watch  *Watchlist
+	values *Valuator
+
+	onAlert func(SandwichAlert)
+}
+
+// NewSandwichDetector 创建检测器，onAlert 为 nil 时使用默认输出
+func NewSandwichDetector(watch *Watchlist, values *Valuator, onAlert func(SandwichAlert)) *SandwichDetector {
+	if onAlert == nil {
+		onAlert = PrintSandwichAlert
+	}
+	return &SandwichDetector{watch: watch, values: values, onAlert: onAlert}
+}
+
+// OnBlock 实现 BlockAnalyzer
+func (d *SandwichDetector) OnBlock(data *BlockData) {
+	txs := data.Block.Transactions()
+	byPool := make(map[common.Address][]poolSwap)
+	var victims []poolSwap
+	for i, r := range data.Receipts {
+		if r.Status == 0 {
+			continue
+		}
+		for _, l := range r.Logs {
+			s, ok := parseSwap(l)
+			if !ok {
+				continue
+			}
+			s.txIndex, s.sender, s.to = i, data.Senders[i], txs[i].To()
+			byPool[s.pool] = append(byPool[s.pool], s)
+			if d.watch.Contains(s.sender) || d.watch.Contains(s.recipient) {
+				victims = append(victims, s)
+			}
+		}
+	}
+
+	for _, v := range victims {
+		front, back, ok := findSandwich(byPool[v.pool], v)
+		if !ok {
+			continue
+		}
+		alert := SandwichAlert{
+			Block:            data.Block.NumberU64(),
+			Victim:           v.sender,
+			VictimTx:         txs[v.txIndex].Hash(),
+			Pool:             v.pool,
+			Attacker:         front.sender,
+			AttackerContract: front.to,
+			FrontTx:          txs[front.txIndex].Hash(),
+			BackTx:           txs[back.txIndex].Hash(),
+			Profit:           new(big.Int).Sub(back.out, front.in),
+		}
+		if !d.watch.Contains(v.sender) {
+			alert.Victim = v.recipient
+		}
+		if tokens, ok := d.values.PoolTokens(v.pool); ok {
+			// 前置交易投入的是 zeroForOne 方向的输入代币
+			alert.Token = tokens[1]
+			if front.zeroForOne {
+				alert.Token = tokens[0]
+			}
+			if alert.Profit.Sign() > 0 {
+				alert.LossUSD, alert.Priced = d.values.TokenValue(alert.Token, alert.Profit)
+			}
+		}
+		d.onAlert(alert)
+	}
+}
+
+// findSandwich 在同一个池子的 swap 中找夹住 v 的前后两笔：前面最近一笔同方向、后面最近一笔反方向，且属于同一个攻击者
+func findSandwich(swaps []poolSwap, v poolSwap) (front, back poolSwap, ok bool) {
+	for i := len(swaps) - 1; i >= 0; i-- {
+		f := swaps[i]
+		if f.txIndex >= v.txIndex || f.zeroForOne != v.zeroForOne || f.sameActor(v) {
+			continue
+		}
+		for _, b := range swaps {
+			if b.txIndex > v.txIndex && b.zeroForOne != v.zeroForOne && b.sameActor(f) {
+				return f, b, true
+			}
+		}
+	}
+	return front, back, false
+}
+
+// PrintSandwichAlert 默认的三明治攻击提醒
+func PrintSandwichAlert(a SandwichAlert) {
+	loss := "无法估值"
+	if a.Priced {
+		loss = formatUSD(a.LossUSD)
+	}
+	summary := fmt.Sprintf("🚨🥪 [Sandwich] 关注地址 %s 的 swap 被夹！区块 %d | 池子 %s | 攻击者 %s | 估计损失 %s",
+		a.Victim.Hex(), a.Block, a.Pool.Hex(), a.Attacker.Hex(), loss)
+	text := fmt.Sprintf("\n%s\n   前置: %s\n   受害: %s\n   后置: %s\n", summary, a.FrontTx.Hex(), a.VictimTx.Hex(), a.BackTx.Hex())
+	if a.AttackerContract != nil {
+		text += fmt.Sprintf("   攻击合约: %s\n", a.AttackerContract.Hex())
+	}
+	Emit(Event{Type: "sandwich_alert", Key: eventKey(a.VictimTx, a.FrontTx, a.BackTx), Summary: summary, Text: text, Data: a})
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ------------------------------------------------
+// 调用 / 事件搜索：把区块中能解码的函数调用（包括 multicall 等展开的内层调用）和日志按函数名、参数写入 SQLite，
+// 通过 /api/search 组合查询，例如"最近 1000 个区块内路径中包含某个代币的 swapExactETHForTokens"，
+// 实时输出看过就没了的数据变成可以回头调查的数据集
+// ------------------------------------------------
+
+// 搜索记录的类型
+const (
+	SearchCall  = "call"
+	SearchEvent = "event"
+)
+
+const (
+	// 搜索数据默认保留天数
+	DefaultSearchRetention = 7
+)
+
+// SearchConfig 调用 / 事件搜索索引，enabled 为 false 时不启动
+type SearchConfig struct {
+	Enabled   bool     `json:"enabled"`
+	Contracts []string `json:"contracts"`      // 只索引调用这些合约的交易和这些合约的日志，留空索引全部能解码的
+	Events    bool     `json:"events"`         // 同时索引日志，需要每个区块的回执
+	DB        string   `json:"db"`             // SQLite 数据库文件，默认与事件索引共用
+	Retention int      `json:"retention_days"` // 保留天数
+}
+
+// SearchItem 一条搜索结果
+type SearchItem struct {
+	Kind      string          `json:"kind"` // call / event
+	Block     uint64          `json:"block"`
+	Time      time.Time       `json:"time"`
+	TxHash    common.Hash     `json:"tx_hash"`
+	TxIndex   int             `json:"tx_index"`
+	Seq       int             `json:"seq"`            // call：交易内展开后的调用序号（0 为外层）；event：日志序号
+	From      *common.Address `json:"from,omitempty"` // 交易发送者，只有 call 有
+	Contract  common.Address  `json:"contract"`       // 被调用的合约 / 发出日志的合约
+	Name      string          `json:"name"`           // 函数名 / 事件名
+	Signature string          `json:"signature"`
+	Args      json.RawMessage `json:"args"`
+
+	id int64
+}
+
+// searchArg 一个可搜索的参数值：数组展开为多个同名的值，tuple 字段为 参数名.字段名
+type searchArg struct {
+	name  string
+	value string // 小写，字符串去掉引号
+}
+
+// searchArgs 把解码后的参数展开为可搜索的值
+func searchArgs(args []DecodedArg) []searchArg {
+	var out []searchArg
+	for _, a := range args {
+		out = appendSearchValue(out, a.Name, a.Value)
+	}
+	return out
+}
+
+// appendSearchValue 按 formatABIValue 的格式展开：[a, b] 为数组，{k: v} 为 tuple
+func appendSearchValue(out []searchArg, name, value string) []searchArg {
+	switch {
+	case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+		for _, v := range splitTopLevel(value[1 : len(value)-1]) {
+			out = appendSearchValue(out, name, v)
+		}
+	case strings.HasPrefix(value, "{") && strings.HasSuffix(value, "}"):
+		for _, field := range splitTopLevel(value[1 : len(value)-1]) {
+			if k, v, ok := strings.Cut(field, ": "); ok {
+				out = appendSearchValue(out, name+"."+k, v)
+			}
+		}
+	default:
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		out = append(out, searchArg{name: name, value: strings.ToLower(value)})
+	}
+	return out
+}
+
+// splitTopLevel 按最外层的 ", " 分割，忽略括号和字符串内的逗号
+func splitTopLevel(s string) []string {
+	if s == "" {
+		return nil
+	}
+	var parts []string
+	depth, start, quoted := 0, 0, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && quoted:
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0 && i+1 < len(s) && s[i+1] == ' ':
+			parts = append(parts, s[start:i])
+			start = i + 2
+			i++
+		}
+	}
+	return append(parts, s[start:])
+}
+
+const searchSchema = `
+CREATE TABLE IF NOT EXISTS search_items (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	kind         TEXT    NOT NULL,
+	block_number INTEGER NOT NULL,
+	block_hash   TEXT    NOT NULL,
+	time         INTEGER NOT NULL,
+	tx_hash      TEXT    NOT NULL,
+	tx_index     INTEGER NOT NULL,
+	seq          INTEGER NOT NULL,
+	from_addr    TEXT,
+	contract     TEXT    NOT NULL,
+	name         TEXT    NOT NULL,
+	signature    TEXT    NOT NULL,
+	args         TEXT    NOT NULL,
+	UNIQUE (kind, block_hash, tx_index, seq)
+);
+CREATE INDEX IF NOT EXISTS search_items_name ON search_items (kind, name, block_number);
+CREATE INDEX IF NOT EXISTS search_items_contract ON search_items (kind, contract, block_number);
+CREATE INDEX IF NOT EXISTS search_items_block ON search_items (block_number);
+CREATE TABLE IF NOT EXISTS search_args (
+	item  INTEGER NOT NULL,
+	name  TEXT    NOT NULL,
+	value TEXT    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS search_args_value ON search_args (value, name);
+CREATE INDEX IF NOT EXISTS search_args_item ON search_args (item);`
+
+// SearchStore 搜索索引的 SQLite 存储，和事件索引共用同一个数据库文件
+type SearchStore struct {
+	db *sql.DB
+}
+
+func OpenSearchStore(path string) (*SearchStore, error) {
+	db, err := openSQLite(path, searchSchema)
+	if err != nil {
+		return nil, err
+	}
+	return &SearchStore{db: db}, nil
+}
+
+// searchRecord 待写入的一条记录
+type searchRecord struct {
+	item SearchItem
+	args []searchArg
+}
+
+// SaveBlock 写入一个区块的记录；同一高度之前写入的其它区块（重组前的旧区块）的记录先删除，
+// 同一个区块重复写入（重新处理区块）时已有的记录不变
+func (s *SearchStore) SaveBlock(number uint64, hash common.Hash, records []searchRecord) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM search_args WHERE item IN (SELECT id FROM search_items WHERE block_number = ? AND block_hash != ?)`, number, hash.Hex()); err != nil {
+		return fmt.Errorf("删除重组区块的搜索记录失败: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM search_items WHERE block_number = ? AND block_hash != ?`, number, hash.Hex()); err != nil {
+		return fmt.Errorf("删除重组区块的搜索记录失败: %w", err)
+	}
+	for _, r := range records {
+		it := r.item
+		var from interface{}
+		if it.From != nil {
+			from = it.From.Hex()
+		}
+		res, err := tx.Exec(`INSERT OR IGNORE INTO search_items
+			(kind, block_number, block_hash, time, tx_hash, tx_index, seq, from_addr, contract, name, signature, args) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			it.Kind, it.Block, hash.Hex(), it.Time.Unix(), it.TxHash.Hex(), it.TxIndex, it.Seq, from, it.Contract.Hex(), it.Name, it.Signature, string(it.Args))
+		if err != nil {
+			return fmt.Errorf("写入搜索记录失败: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		for _, a := range r.args {
+			if _, err := tx.Exec(`INSERT INTO search_args (item, name, value) VALUES (?, ?, ?)`, id, a.name, a.value); err != nil {
+				return fmt.Errorf("写入搜索参数失败: %w", err)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// SearchFilter /api/search 的过滤条件，为空的字段不限
+type SearchFilter struct {
+	Kind     string
+	Name     string
+	Contract *common.Address
+	From     *common.Address
+	Value    string            // 任一参数（包括数组元素）等于该值
+	Params   map[string]string // 参数名 -> 值，全部满足
+}
+
+// Search 按区块倒序查询
+func (s *SearchStore) Search(f SearchFilter, q historyQuery) (HistoryPage, error) {
+	where := []string{"kind = ?", "block_number BETWEEN ? AND ?", "time BETWEEN ? AND ?"}
+	args := []interface{}{f.Kind, q.FromBlock, q.ToBlock, q.Since.Unix(), q.Until.Unix()}
+	if f.Name != "" {
+		where = append(where, "name = ?")
+		args = append(args, f.Name)
+	}
+	if f.Contract != nil {
+		where = append(where, "contract = ?")
+		args = append(args, f.Contract.Hex())
+	}
+	if f.From != nil {
+		where = append(where, "from_addr = ?")
+		args = append(args, f.From.Hex())
+	}
+	if f.Value != "" {
+		where = append(where, "EXISTS (SELECT 1 FROM search_args a WHERE a.item = search_items.id AND a.value = ?)")
+		args = append(args, strings.ToLower(f.Value))
+	}
+	for name, value := range f.Params {
+		where = append(where, "EXISTS (SELECT 1 FROM search_args a WHERE a.item = search_items.id AND a.name = ? AND a.value = ?)")
+		args = append(args, name, strings.ToLower(value))
+	}
+	if q.Cursor != nil {
+		where = append(where, "(block_number, id) < (?, ?)")
+		args = append(args, q.Cursor.Key, q.Cursor.Index)
+	}
+	args = append(args, q.Limit)
+	rows, err := s.db.Query(`SELECT id, kind, block_number, time, tx_hash, tx_index, seq, from_addr, contract, name, signature, args FROM search_items
+		WHERE `+strings.Join(where, " AND ")+` ORDER BY block_number DESC, id DESC LIMIT ?`, args...)
+	if err != nil {
+		return HistoryPage{}, fmt.Errorf("搜索失败: %w", err)
+	}
+	defer rows.Close()
+	items := []SearchItem{}
+	for rows.Next() {
+		var it SearchItem
+		var secs int64
+		var hash, contract, encoded string
+		var from sql.NullString
+		if err := rows.Scan(&it.id, &it.Kind, &it.Block, &secs, &hash, &it.TxIndex, &it.Seq, &from, &contract, &it.Name, &it.Signature, &encoded); err != nil {
+			return HistoryPage{}, err
+		}
+		it.Time, it.TxHash, it.Contract, it.Args = time.Unix(secs, 0), common.HexToHash(hash), common.HexToAddress(contract), json.RawMessage(encoded)
+		if from.Valid {
+			addr := common.HexToAddress(from.String)
+			it.From = &addr
+		}
+		items = append(items, it)
+	}
+	page := HistoryPage{Items: items}
+	if len(items) == q.Limit {
+		last := items[len(items)-1]
+		page.NextCursor = historyCursor{Key: int64(last.Block), Index: last.id}.encode()
+	}
+	return page, rows.Err()
+}
+
+// Prune 删除某个时间之前的记录
+func (s *SearchStore) Prune(before time.Time) error {
+	if _, err := s.db.Exec(`DELETE FROM search_args WHERE item IN (SELECT id FROM search_items WHERE time < ?)`, before.Unix()); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`DELETE FROM search_items WHERE time < ?`, before.Unix())
+	return err
+}
+
+func (s *SearchStore) Close() error {
+	return s.db.Close()
+}
+
+// SearchIndexer 区块分析器：解码区块中的调用和日志写入搜索索引
+type SearchIndexer struct {
+	cfg       SearchConfig
+	store     *SearchStore
+	abis      *ABIRegistry
+	contracts map[common.Address]bool // 为空时不限
+
+	head atomic.Uint64 // 最新写入的区块，last_blocks 参数以它为准
+}
+
+// NewSearchIndexer 创建搜索索引
+func NewSearchIndexer(cfg SearchConfig, store *SearchStore, abis *ABIRegistry) *SearchIndexer {
+	s := &SearchIndexer{cfg: cfg, store: store, abis: abis, contracts: make(map[common.Address]bool, len(cfg.Contracts))}
+	for _, c := range cfg.Contracts {
+		s.contracts[common.HexToAddress(c)] = true
+	}
+	return s
+}
+
+// Enrichment 索引日志时需要回执
+func (s *SearchIndexer) Enrichment() EnrichLevel {
+	if s.cfg.Events {
+		return EnrichReceipts
+	}
+	return EnrichTransactions
+}
+
+// watched 合约是否在索引范围内
+func (s *SearchIndexer) watched(addr common.Address) bool {
+	return len(s.contracts) == 0 || s.contracts[addr]
+}
+
+// OnBlock 解码并写入区块中的调用和日志
+func (s *SearchIndexer) OnBlock(data *BlockData) {
+	block := data.Block
+	base := SearchItem{Block: block.NumberU64(), Time: time.Unix(int64(block.Time()), 0)}
+	var records []searchRecord
+	for i, tx := range block.Transactions() {
+		if to := tx.To(); to != nil && s.watched(*to) {
+			if call, ok := s.abis.DecodeCall(to, tx.Data()); ok && call.Name != "" {
+				from := data.Senders[i]
+				records = s.appendCalls(records, base, tx, i, &from, *to, call)
+			}
+		}
+		if !s.cfg.Events || i >= len(data.Receipts) || data.Receipts[i] == nil {
+			continue
+		}
+		for _, l := range data.Receipts[i].Logs {
+			if !s.watched(l.Address) {
+				continue
+			}
+			decoded, ok := s.abis.DecodeLog(*l)
+			if !ok {
+				continue
+			}
+			args, _ := json.Marshal(decoded.Args)
+			it := base
+			it.Kind, it.TxHash, it.TxIndex, it.Seq = SearchEvent, tx.Hash(), i, int(l.Index)
+			it.Contract, it.Name, it.Signature, it.Args = l.Address, decoded.Name, decoded.Sig, args
+			records = append(records, searchRecord{item: it, args: searchArgs(decoded.Args)})
+		}
+	}
+	if err := s.store.SaveBlock(block.NumberU64(), block.Hash(), records); err != nil {
+		log.Printf("⚠️  写入区块 %d 的搜索索引失败: %v", block.NumberU64(), err)
+		return
+	}
+	if n := block.NumberU64(); n > s.head.Load() {
+		s.head.Store(n)
+	}
+}
+
+// appendCalls 写入外层调用和展开后的内层调用（深度优先），内层调用的合约为空时与外层相同
+func (s *SearchIndexer) appendCalls(records []searchRecord, base SearchItem, tx *types.Transaction, index int, from *common.Address, to common.Address, call *DecodedCall) []searchRecord {
+	start := len(records)
+	var walk func(c *DecodedCall, contract common.Address)
+	walk = func(c *DecodedCall, contract common.Address) {
+		if c.Name != "" {
+			args, _ := json.Marshal(c.Args)
+			it := base
+			it.Kind, it.TxHash, it.TxIndex, it.Seq, it.From = SearchCall, tx.Hash(), index, len(records)-start, from
+			it.Contract, it.Name, it.Signature, it.Args = contract, c.Name, c.Sig, args
+			records = append(records, searchRecord{item: it, args: searchArgs(c.Args)})
+		}
+		for _, in := range c.Inner {
+			target := contract
+			if in.Target != nil {
+				target = *in.Target
+			}
+			walk(in, target)
+		}
+	}
+	walk(call, to)
+	return records
+}
+
+// Run 每小时清理一次过期记录，直到 ctx 取消
+func (s *SearchIndexer) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		if err := s.store.Prune(time.Now().AddDate(0, 0, -s.cfg.Retention)); err != nil {
+			log.Printf("⚠️  清理搜索索引失败: %v", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// RegisterAPI 注册搜索接口，分页和范围参数见 historyQuery
+//
+//	GET /api/search?kind=call&name=swapExactETHForTokens&value=0x...&last_blocks=1000
+//	  kind         call（默认）| event
+//	  name         函数名 / 事件名
+//	  contract     被调用的合约 / 发出日志的合约
+//	  from         交易发送者（只对 call 有效）
+//	  value        任一参数（包括数组元素、tuple 字段）等于该值
+//	  param        参数名:值，可以重复，例如 param=path:0x...&param=to:0x...
+//	  last_blocks  最近多少个区块，与 from_block 同时出现时取较新的
+func (s *SearchIndexer) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/search", func(w http.ResponseWriter, r *http.Request) {
+		q, err := parseHistoryQuery(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		f, err := s.parseFilter(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if v := r.URL.Query().Get("last_blocks"); v != "" {
+			n, err := strconv.ParseUint(v, 10, 63)
+			if err != nil || n == 0 {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("last_blocks 格式错误: %q", v))
+				return
+			}
+			if head := s.head.Load(); head >= n {
+				q.FromBlock = max(q.FromBlock, head-n+1)
+			}
+		}
+		page, err := s.store.Search(f, q)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, page)
+	})
+}
+
+// parseFilter 解析搜索条件
+func (s *SearchIndexer) parseFilter(r *http.Request) (SearchFilter, error) {
+	v := r.URL.Query()
+	f := SearchFilter{Kind: v.Get("kind"), Name: v.Get("name"), Value: v.Get("value"), Params: make(map[string]string)}
+	switch f.Kind {
+	case "":
+		f.Kind = SearchCall
+	case SearchCall, SearchEvent:
+	default:
+		return f, fmt.Errorf("kind 只能是 call 或 event: %q", f.Kind)
+	}
+	if f.Kind == SearchEvent && !s.cfg.Events {
+		return f, fmt.Errorf("没有开启 search.events，不能搜索事件")
+	}
+	var err error
+	if f.Contract, err = queryAddress(r, "contract"); err != nil {
+		return f, err
+	}
+	if f.From, err = queryAddress(r, "from"); err != nil {
+		return f, err
+	}
+	for _, p := range v["param"] {
+		name, value, ok := strings.Cut(p, ":")
+		if !ok || name == "" || value == "" {
+			return f, fmt.Errorf("param 格式应为 参数名:值: %q", p)
+		}
+		f.Params[name] = value
+	}
+	return f, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+// ------------------------------------------------
+// 交易池函数过滤：按 calldata 开头的 4 字节 selector 保留 / 丢弃 Pending 交易，
+// 例如在整个交易池中只看 transferFrom 和 swapExactTokensForTokens。
+// 函数可以写 selector，也可以写完整的函数签名（启动时计算 Keccak 哈希），不需要 ABI
+// ------------------------------------------------
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// signaturePattern 函数签名，例如 transferFrom(address,address,uint256)
+var signaturePattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*\(.*\)$`)
+
+// SelectorFilterConfig 按函数过滤交易池输出；include 为空时不限制函数
+type SelectorFilterConfig struct {
+	Include []string `json:"include"` // 只保留调用这些函数的交易
+	Exclude []string `json:"exclude"` // 丢弃调用这些函数的交易，优先于 include
+}
+
+// Enabled 是否配置了任何过滤条件
+func (c SelectorFilterConfig) Enabled() bool {
+	return len(c.Include) > 0 || len(c.Exclude) > 0
+}
+
+// validate 检查 selector / 函数签名的格式
+func (c SelectorFilterConfig) validate() error {
+	for _, list := range [][]string{c.Include, c.Exclude} {
+		for _, s := range list {
+			if _, err := parseSelector(s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseSelector 把 0x 开头的 selector 或函数签名转换为 4 字节 selector
+// 签名中的空格会被去掉，参数类型需要写规范形式（uint256 而不是 uint）
+func parseSelector(s string) ([4]byte, error) {
+	var sel [4]byte
+	s = strings.TrimSpace(s)
+	switch {
+	case selectorPattern.MatchString(s):
+		b, _ := hex.DecodeString(s[2:])
+		copy(sel[:], b)
+	case signaturePattern.MatchString(s):
+		copy(sel[:], crypto.Keccak256([]byte(strings.ReplaceAll(s, " ", "")))[:4])
+	default:
+		return sel, fmt.Errorf("无法识别的函数 %q（需要 0x 开头的 4 字节 selector 或完整的函数签名）", s)
+	}
+	return sel, nil
+}
+
+// SelectorFilter 按 selector 过滤 Pending 交易
+type SelectorFilter struct {
+	include map[[4]byte]bool
+	exclude map[[4]byte]bool
+}
+
+// NewSelectorFilter 创建函数过滤器，配置需要先通过 validate
+func NewSelectorFilter(cfg SelectorFilterConfig) *SelectorFilter {
+	f := &SelectorFilter{
+		include: make(map[[4]byte]bool, len(cfg.Include)),
+		exclude: make(map[[4]byte]bool, len(cfg.Exclude)),
+	}
+	for _, s := range cfg.Include {
+		sel, _ := parseSelector(s)
+		f.include[sel] = true
+	}
+	for _, s := range cfg.Exclude {
+		sel, _ := parseSelector(s)
+		f.exclude[sel] = true
+	}
+	return f
+}
+
+// Match 交易调用的函数是否满足条件；纯转账（calldata 不足 4 字节）只在不限制函数时保留
+func (f *SelectorFilter) Match(tx *types.Transaction) bool {
+	data := tx.Data()
+	if len(data) < 4 {
+		return len(f.include) == 0
+	}
+	var sel [4]byte
+	copy(sel[:], data)
+	if f.exclude[sel] {
+		return false
+	}
+	return len(f.include) == 0 || f.include[sel]
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// GasLimitBuffer 在估算出的 gas 上额外加的百分比，防止状态变化导致 out of gas
+const GasLimitBuffer = 20
+
+// Sender 交易发送模块：构造 EIP-1559 交易，交给 TxSigner 签名后广播
+type Sender struct {
+	client  *ethclient.Client
+	signer  TxSigner
+	chainID *big.Int
+}
+
+// NewSender 创建发送模块，chainID 从节点查询，避免配置错链
+func NewSender(ctx context.Context, client *ethclient.Client, signer TxSigner) (*Sender, error) {
+	chainID, err := chainIDRetry(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("查询 chainID 失败: %w", err)
+	}
+	return &Sender{client: client, signer: signer, chainID: chainID}, nil
+}
+
+// From 发送账户地址
+func (s *Sender) From() common.Address { return s.signer.Address() }
+
+// BuildTx 构造一笔未签名的 EIP-1559 交易
+// nonce、gas limit、maxFeePerGas 都从节点实时获取
+func (s *Sender) BuildTx(ctx context.Context, to common.Address, value *big.Int, data []byte) (*types.Transaction, error) {
+	from := s.signer.Address()
+
+	var nonce uint64
+	err := Retry(ctx, "eth_getTransactionCount", func() (err error) {
+		nonce, err = s.client.PendingNonceAt(ctx, from)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("查询 nonce 失败: %w", err)
+	}
+	var tipCap *big.Int
+	err = Retry(ctx, "eth_maxPriorityFeePerGas", func() (err error) {
+		tipCap, err = s.client.SuggestGasTipCap(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("查询小费建议失败: %w", err)
+	}
+	head, err := headerByNumberRetry(ctx, s.client, nil)
+	if err != nil {
+		return nil, fmt.Errorf("查询最新区块失败: %w", err)
+	}
+	// maxFee = 2 * baseFee + tip，可以承受连续几个区块的 baseFee 上涨
+	feeCap := new(big.Int).Add(new(big.Int).Mul(head.BaseFee, big.NewInt(2)), tipCap)
+
+	var gas uint64
+	err = Retry(ctx, "eth_estimateGas", func() (err error) {
+		gas, err = s.client.EstimateGas(ctx, ethereum.CallMsg{
+			From:      from,
+			To:        &to,
+			Value:     value,
+			Data:      data,
+			GasFeeCap: feeCap,
+			GasTipCap: tipCap,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("估算 gas 失败: %w", err)
+	}
+	gas += gas * GasLimitBuffer / 100
+
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   s.chainID,
+		Nonce:     nonce,
+		GasTipCap: tipCap,
+		GasFeeCap: feeCap,
+		Gas:       gas,
+		To:        &to,
+		Value:     value,
+		Data:      data,
+	}), nil
+}
+
+// SignAndSend 签名并广播交易，返回已签名的交易（可以用 Hash() 追踪）
+func (s *Sender) SignAndSend(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+	signed, err := s.signer.SignTx(tx, s.chainID)
+	if err != nil {
+		return nil, err
+	}
+	// 广播不重试：超时的那次节点可能已经收到，重发只会得到 already known，需要由调用方按哈希确认
+	if err := s.client.SendTransaction(ctx, signed); err != nil {
+		return nil, fmt.Errorf("广播交易失败: %w", err)
+	}
+	log.Printf("🚀 交易已广播: %s", signed.Hash().Hex())
+	return signed, nil
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+)
+
+// ------------------------------------------------
+// 提醒级别与路由：每个事件带有级别（info / warn / critical）和分类（security / mev / node ...），
+// 未指定时按事件类型取默认值；routing 按 级别 + 分类 决定事件发往哪些输出，
+// 例如 critical 发到 telegram，info 只留在日志文件
+// ------------------------------------------------
+
+// 提醒级别，由低到高
+const (
+	SeverityInfo     = "info"
+	SeverityWarn     = "warn"
+	SeverityCritical = "critical"
+)
+
+// severityRank 级别的高低，未知级别为 0
+var severityRank = map[string]int{SeverityInfo: 1, SeverityWarn: 2, SeverityCritical: 3}
+
+// 事件分类
+const (
+	CategorySecurity   = "security"   // 授权、制裁、撤池、合约升级等资产安全相关
+	CategoryMEV        = "mev"        // 三明治、抢跑、bundle、机器人
+	CategoryNode       = "node"       // 节点健康、熔断、RPC 用量
+	CategoryValue      = "value"      // 大额转账、关注地址、持仓
+	CategoryGovernance = "governance" // 治理提案、timelock、多签
+	CategoryMarket     = "market"     // DEX 成交、跨链、gas
+	CategoryChain      = "chain"      // 区块、交易、日志等原始数据和定时报告
+	CategorySystem     = "system"     // 监控程序自身的状态变化
+	CategoryOther      = "other"      // 未登记的事件类型
+)
+
+// eventSeverity 各事件类型的默认级别，未登记的为 info
+var eventSeverity = map[string]string{
+	"sanction_hit":       SeverityCritical,
+	"rug_pull":           SeverityCritical,
+	"bytecode_change":    SeverityCritical,
+	"proxy_upgrade":      SeverityCritical,
+	"header_integrity":   SeverityCritical,
+	"portfolio_drawdown": SeverityCritical,
+	"safe_config":        SeverityCritical,
+
+	"approval_alert":    SeverityWarn,
+	"token_risk":        SeverityWarn,
+	"delegation":        SeverityWarn,
+	"sandwich_alert":    SeverityWarn,
+	"frontrun":          SeverityWarn,
+	"bot_detected":      SeverityWarn,
+	"copy_trade":        SeverityWarn,
+	"arb_opportunity":   SeverityWarn,
+	"node_alert":        SeverityWarn,
+	"breaker":           SeverityWarn,
+	"budget":            SeverityWarn,
+	"large_value":       SeverityWarn,
+	"watched_tx":        SeverityWarn,
+	"profile_tx":        SeverityWarn,
+	"whale_tx":          SeverityWarn,
+	"governance":        SeverityWarn,
+	"timelock":          SeverityWarn,
+	"safe_exec":         SeverityWarn,
+	"curve_param":       SeverityWarn,
+	"internal_transfer": SeverityWarn,
+}
+
+// eventCategory 各事件类型的分类，未登记的为 other
+var eventCategory = map[string]string{
+	"sanction_hit":    CategorySecurity,
+	"rug_pull":        CategorySecurity,
+	"bytecode_change": CategorySecurity,
+	"proxy_upgrade":   CategorySecurity,
+	"approval_alert":  CategorySecurity,
+	"token_risk":      CategorySecurity,
+	"delegation":      CategorySecurity,
+
+	"sandwich_alert":      CategoryMEV,
+	"frontrun":            CategoryMEV,
+	"bundle":              CategoryMEV,
+	"coinbase_payments":   CategoryMEV,
+	"bot_detected":        CategoryMEV,
+	"bot_tx":              CategoryMEV,
+	"arb_opportunity":     CategoryMEV,
+	"private_flow":        CategoryMEV,
+	"private_flow_report": CategoryMEV,
+	"copy_trade":          CategoryMEV,
+
+	"node_alert":         CategoryNode,
+	"header_integrity":   CategoryNode,
+	"breaker":            CategoryNode,
+	"budget":             CategoryNode,
+	"lite":               CategoryNode,
+	"rpc_usage":          CategoryNode,
+	"propagation_scores": CategoryNode,
+	"mempool_sources":    CategoryNode,
+
+	"large_value":        CategoryValue,
+	"watched_tx":         CategoryValue,
+	"profile_tx":         CategoryValue,
+	"internal_transfer":  CategoryValue,
+	"whale_report":       CategoryValue,
+	"whale_tx":           CategoryValue,
+	"portfolio_drawdown": CategoryValue,
+	"portfolio_snapshot": CategoryValue,
+	"pnl_trade":          CategoryValue,
+
+	"governance":  CategoryGovernance,
+	"timelock":    CategoryGovernance,
+	"safe_config": CategoryGovernance,
+	"safe_exec":   CategoryGovernance,
+	"curve_param": CategoryGovernance,
+
+	"aggregator_swap": CategoryMarket,
+	"balancer_swap":   CategoryMarket,
+	"curve_exchange":  CategoryMarket,
+	"pending_swap":    CategoryMarket,
+	"pending_match":   CategoryMarket,
+	"crosschain":      CategoryMarket,
+	"bridge":          CategoryMarket,
+	"userop":          CategoryMarket,
+	"userop_stats":    CategoryMarket,
+	"gas_leaderboard": CategoryMarket,
+
+	"new_head":         CategoryChain,
+	"finalized":        CategoryChain,
+	"pending_tx":       CategoryChain,
+	"log":              CategoryChain,
+	"included":         CategoryChain,
+	"inclusion_report": CategoryChain,
+	"dwell_report":     CategoryChain,
+	"summary_report":   CategoryChain,
+	"digest":           CategoryChain,
+
+	"config_reload": CategorySystem,
+	"admin":         CategorySystem,
+	"output_pause":  CategorySystem,
+	"subscription":  CategorySystem,
+}
+
+// severityOf 事件类型在当前路由下的级别
+func severityOf(typ string) string {
+	return currentRouting().severityOf(typ)
+}
+
+// categoryOf 事件类型的分类
+func categoryOf(typ string) string {
+	if c, ok := eventCategory[typ]; ok {
+		return c
+	}
+	return CategoryOther
+}
+
+// classify 补上事件的级别和分类
+func (e *Event) classify() {
+	if e.Severity == "" {
+		e.Severity = severityOf(e.Type)
+	}
+	if e.Category == "" {
+		e.Category = categoryOf(e.Type)
+	}
+}
+
+// RouteRule 路由矩阵的一行：级别不低于 min_severity 且分类匹配的事件发往 outputs
+type RouteRule struct {
+	MinSeverity string   `json:"min_severity"` // info（默认）| warn | critical
+	Categories  []string `json:"categories"`   // 为空时匹配全部分类
+	Outputs     []string `json:"outputs"`      // outputs 中的 name
+	Filter      string   `json:"filter"`       // 只匹配带有交易且交易满足该过滤链的事件，为空不限
+	When        string   `json:"when"`         // CEL 表达式（cel.go），可以使用 event / data / tx / from / call，为空不限
+}
+
+// RoutingConfig 提醒级别与路由
+type RoutingConfig struct {
+	Severity map[string]string `json:"severity"` // 事件类型 -> 级别，覆盖默认级别
+	Routes   []RouteRule       `json:"routes"`
+}
+
+// validate 检查级别、分类和输出名称
+func (c RoutingConfig) validate(outputs []OutputConfig) error {
+	for typ, s := range c.Severity {
+		if severityRank[s] == 0 {
+			return fmt.Errorf("routing.severity.%s 无效: %q（可选 info / warn / critical）", typ, s)
+		}
+	}
+	names := make(map[string]bool, len(outputs))
+	for _, o := range outputs {
+		names[o.Name] = true
+	}
+	for i, r := range c.Routes {
+		if r.MinSeverity != "" && severityRank[r.MinSeverity] == 0 {
+			return fmt.Errorf("routing.routes[%d] 的 min_severity 无效: %q（可选 info / warn / critical）", i, r.MinSeverity)
+		}
+		for _, cat := range r.Categories {
+			if !knownCategory(cat) {
+				return fmt.Errorf("routing.routes[%d] 的分类未知: %q", i, cat)
+			}
+		}
+		if len(r.Outputs) == 0 {
+			return fmt.Errorf("routing.routes[%d] 的 outputs 不能为空", i)
+		}
+		if r.When != "" {
+			if _, err := compileCEL(r.When, true); err != nil {
+				return fmt.Errorf("routing.routes[%d] 的 when: %w", i, err)
+			}
+		}
+		for _, name := range r.Outputs {
+			if name == "" || !names[name] {
+				return fmt.Errorf("routing.routes[%d] 中的输出 %q 不在 outputs 中（需要填写 name）", i, name)
+			}
+		}
+	}
+	return nil
+}
+
+// knownCategory 分类是否存在
+func knownCategory(cat string) bool {
+	if cat == CategoryOther {
+		return true
+	}
+	for _, c := range eventCategory {
+		if c == cat {
+			return true
+		}
+	}
+	return false
+}
+
+// RouteStatus /api/routing 的内容
+type RouteStatus struct {
+	Routes   []RouteRule       `json:"routes"`
+	Routed   []string          `json:"routed"`   // 只接收路由命中事件的输出，其余输出接收全部事件
+	Severity map[string]string `json:"severity"` // 全部已登记事件类型的生效级别
+}
+
+// alertRouting 全局路由，未配置时为 nil（全部输出接收全部事件）
+var (
+	alertRoutingMu sync.RWMutex
+	alertRouting   *Router
+)
+
+// SetRouting 替换全局路由，热加载时调用
+func SetRouting(r *Router) {
+	alertRoutingMu.Lock()
+	defer alertRoutingMu.Unlock()
+	alertRouting = r
+}
+
+// Router 按级别和分类选择输出
+//   - 出现在某条路由 outputs 中的输出只接收命中这些路由的事件
+//   - 没有出现在任何路由中的输出接收全部事件（例如保存全部事件的日志文件）
+type Router struct {
+	cfg    RoutingConfig
+	routed map[string]bool
+	when   []cel.Program // 与 cfg.Routes 按下标对应，未配置 when 时为 nil
+}
+
+// NewRouter 按配置创建路由
+func NewRouter(cfg RoutingConfig) *Router {
+	r := &Router{cfg: cfg, routed: make(map[string]bool), when: make([]cel.Program, len(cfg.Routes))}
+	for i, rule := range cfg.Routes {
+		for _, name := range rule.Outputs {
+			r.routed[name] = true
+		}
+		if rule.When != "" {
+			r.when[i], _ = compileCEL(rule.When, true)
+		}
+	}
+	return r
+}
+
+// severityOf 事件类型的级别，routing.severity 中的覆盖优先，未登记的为 info
+func (r *Router) severityOf(typ string) string {
+	if r != nil {
+		if s, ok := r.cfg.Severity[typ]; ok {
+			return s
+		}
+	}
+	if s, ok := eventSeverity[typ]; ok {
+		return s
+	}
+	return SeverityInfo
+}
+
+// Match 事件是否发往该输出；ev 需要已经补上级别和分类
+func (r *Router) Match(sink string, ev Event) bool {
+	if r == nil || !r.routed[sink] {
+		return true
+	}
+	for i, rule := range r.cfg.Routes {
+		if severityRank[ev.Severity] < severityRank[rule.MinSeverity] {
+			continue
+		}
+		if len(rule.Categories) > 0 && !hasString(rule.Categories, ev.Category) {
+			continue
+		}
+		if rule.Filter != "" && (ev.tx == nil || !currentFilters().Match(rule.Filter, ev.tx, ev.txFrom)) {
+			continue
+		}
+		if !hasString(rule.Outputs, sink) {
+			continue
+		}
+		if r.when[i] == nil || evalCEL(r.when[i], celEventVars(currentFilters(), ev)) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasString list 中是否有 s
+func hasString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Status 路由配置和各事件类型的生效级别
+func (r *Router) Status() RouteStatus {
+	st := RouteStatus{Routes: []RouteRule{}, Severity: make(map[string]string, len(eventCategory))}
+	for typ := range eventCategory {
+		st.Severity[typ] = r.severityOf(typ)
+	}
+	if r == nil {
+		return st
+	}
+	for typ := range r.cfg.Severity {
+		st.Severity[typ] = r.severityOf(typ)
+	}
+	st.Routes = r.cfg.Routes
+	for name := range r.routed {
+		st.Routed = append(st.Routed, name)
+	}
+	sort.Strings(st.Routed)
+	return st
+}
+
+// currentRouting 当前路由
+func currentRouting() *Router {
+	alertRoutingMu.RLock()
+	defer alertRoutingMu.RUnlock()
+	return alertRouting
+}
+
+// RegisterRoutingAPI 注册路由查询接口，热加载后返回新的路由
+//
+//	GET /api/routing 路由矩阵、受路由限制的输出和各事件类型的级别
+func RegisterRoutingAPI(api *APIServer) {
+	api.Handle("GET /api/routing", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, currentRouting().Status())
+	})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// TxSigner 签名器：发送模块只依赖这个接口，不关心私钥放在哪里
+type TxSigner interface {
+	// Address 签名账户的地址（用于查询 nonce、估算 gas）
+	Address() common.Address
+	// SignTx 对交易签名，返回带签名的新交易
+	SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+	// SignTypedData 对 EIP-712 结构化数据签名，返回 v 为 27/28 的 65 字节签名
+	SignTypedData(td apitypes.TypedData) ([]byte, error)
+	// Close 释放底层资源（例如 USB 设备连接）
+	Close() error
+}
+
+// NewSigner 根据配置创建签名器
+func NewSigner(cfg SignerConfig) (TxSigner, error) {
+	switch cfg.Type {
+	case "ledger", "trezor":
+		return newHardwareSigner(cfg.Type, cfg.DerivationPath)
+	case "key":
+		return newKeySigner(cfg.KeyEnv)
+	case "":
+		return nil, fmt.Errorf("未配置 signer.type（可选 ledger / trezor / key）")
+	default:
+		return nil, fmt.Errorf("未知的 signer.type: %q", cfg.Type)
+	}
+}
+
+// keySigner 使用本地私钥签名
+// ⚠️ 私钥会常驻内存，只建议在测试网或本地开发时使用
+type keySigner struct {
+	key  *ecdsa.PrivateKey
+	addr common.Address
+}
+
+func newKeySigner(env string) (*keySigner, error) {
+	if env == "" {
+		return nil, fmt.Errorf("signer.type 为 key 时必须配置 signer.key_env")
+	}
+	hexKey := strings.TrimPrefix(os.Getenv(env), "0x")
+	if hexKey == "" {
+		return nil, fmt.Errorf("环境变量 %s 为空", env)
+	}
+	key, err := crypto.HexToECDSA(hexKey)
+	if err != nil {
+		return nil, fmt.Errorf("解析私钥失败: %w", err)
+	}
+	return &keySigner{key: key, addr: crypto.PubkeyToAddress(key.PublicKey)}, nil
+}
+
+func (s *keySigner) Address() common.Address { return s.addr }
+
+func (s *keySigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
+
+func (s *keySigner) SignTypedData(td apitypes.TypedData) ([]byte, error) {
+	hash, err := HashTypedData(td)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := crypto.Sign(hash.Bytes(), s.key)
+	if err != nil {
+		return nil, err
+	}
+	sig[64] += 27
+	return sig, nil
+}
+
+func (s *keySigner) Close() error { return nil }
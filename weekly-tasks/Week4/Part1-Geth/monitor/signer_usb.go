@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/usbwallet"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+const (
+	// 等待硬件钱包插入并被识别的最长时间
+	HardwareWaitTimeout = 30 * time.Second
+	// 轮询 USB 设备列表的间隔
+	HardwarePollInterval = 500 * time.Millisecond
+)
+
+// hardwareSigner 通过 Ledger / Trezor 签名
+// 交易在设备屏幕上确认后才会被签名，私钥永远不离开硬件
+type hardwareSigner struct {
+	wallet  accounts.Wallet
+	account accounts.Account
+}
+
+// newHardwareSigner 打开第一台检测到的硬件钱包，并派生出指定路径的账户
+func newHardwareSigner(kind, path string) (*hardwareSigner, error) {
+	derivation := accounts.DefaultBaseDerivationPath
+	if path != "" {
+		parsed, err := accounts.ParseDerivationPath(path)
+		if err != nil {
+			return nil, fmt.Errorf("解析派生路径失败: %w", err)
+		}
+		derivation = parsed
+	}
+
+	var hub *usbwallet.Hub
+	var err error
+	switch kind {
+	case "ledger":
+		hub, err = usbwallet.NewLedgerHub()
+	case "trezor":
+		// 新固件的 Trezor 走 WebUSB，旧固件走 HID，先尝试 WebUSB
+		hub, err = usbwallet.NewTrezorHubWithWebUSB()
+		if err != nil || len(hub.Wallets()) == 0 {
+			if hidHub, hidErr := usbwallet.NewTrezorHubWithHID(); hidErr == nil && (err != nil || len(hidHub.Wallets()) > 0) {
+				hub, err = hidHub, nil
+			}
+		}
+	default:
+		return nil, fmt.Errorf("不支持的硬件钱包类型: %q", kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("初始化 %s USB Hub 失败: %w", kind, err)
+	}
+
+	wallet, err := waitForWallet(hub)
+	if err != nil {
+		return nil, err
+	}
+	if err := openWallet(wallet); err != nil {
+		return nil, err
+	}
+
+	// pin=true：把派生出的账户固定在钱包里，后续 SignTx 才能找到它
+	account, err := wallet.Derive(derivation, true)
+	if err != nil {
+		wallet.Close()
+		return nil, fmt.Errorf("派生账户失败 (%s): %w", derivation, err)
+	}
+	log.Printf("🔐 硬件钱包已就绪: %s | 账户 %s (%s)", wallet.URL(), account.Address.Hex(), derivation)
+	return &hardwareSigner{wallet: wallet, account: account}, nil
+}
+
+// waitForWallet 轮询 Hub 直到发现设备或超时
+func waitForWallet(hub *usbwallet.Hub) (accounts.Wallet, error) {
+	deadline := time.Now().Add(HardwareWaitTimeout)
+	notified := false
+	for {
+		if wallets := hub.Wallets(); len(wallets) > 0 {
+			return wallets[0], nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s 内未检测到硬件钱包，请确认设备已连接并解锁", HardwareWaitTimeout)
+		}
+		if !notified {
+			log.Println("⏳ 等待硬件钱包连接...")
+			notified = true
+		}
+		time.Sleep(HardwarePollInterval)
+	}
+}
+
+// openWallet 打开设备连接
+// Ledger 需要在设备上进入 Ethereum 应用；Trezor 可能要求输入 PIN 和 passphrase
+func openWallet(wallet accounts.Wallet) error {
+	err := wallet.Open("")
+	if errors.Is(err, usbwallet.ErrTrezorPINNeeded) {
+		// Trezor 屏幕上显示打乱的数字键盘，用户按位置输入 1-9
+		pin, perr := prompt("🔢 请对照 Trezor 屏幕上的键盘位置输入 PIN: ")
+		if perr != nil {
+			return perr
+		}
+		err = wallet.Open(pin)
+	}
+	if errors.Is(err, usbwallet.ErrTrezorPassphraseNeeded) {
+		passphrase, perr := prompt("🔑 请输入 Trezor passphrase（没有则直接回车）: ")
+		if perr != nil {
+			return perr
+		}
+		err = wallet.Open(passphrase)
+	}
+	if err != nil {
+		return fmt.Errorf("打开硬件钱包失败: %w", err)
+	}
+	return nil
+}
+
+// prompt 从标准输入读取一行
+func prompt(msg string) (string, error) {
+	fmt.Print(msg)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("读取输入失败: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func (s *hardwareSigner) Address() common.Address { return s.account.Address }
+
+func (s *hardwareSigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	log.Printf("👆 请在硬件钱包上确认交易 (nonce=%d, to=%v)", tx.Nonce(), tx.To())
+	signed, err := s.wallet.SignTx(s.account, tx, chainID)
+	if err != nil {
+		return nil, fmt.Errorf("硬件钱包签名失败: %w", err)
+	}
+	return signed, nil
+}
+
+// SignTypedData 通过设备的 EIP-712 签名功能签名
+// 设备只收到 domainSeparator 和 messageHash，目前仅 Ledger 支持
+func (s *hardwareSigner) SignTypedData(td apitypes.TypedData) ([]byte, error) {
+	_, rawData, err := apitypes.TypedDataAndHash(td)
+	if err != nil {
+		return nil, fmt.Errorf("计算 EIP-712 哈希失败: %w", err)
+	}
+	log.Printf("👆 请在硬件钱包上确认 EIP-712 签名 (%s)", td.PrimaryType)
+	sig, err := s.wallet.SignData(s.account, accounts.MimetypeTypedData, []byte(rawData))
+	if err != nil {
+		return nil, fmt.Errorf("硬件钱包签名失败: %w", err)
+	}
+	if sig[64] < 27 {
+		sig[64] += 27
+	}
+	return sig, nil
+}
+
+func (s *hardwareSigner) Close() error {
+	return s.wallet.Close()
+}
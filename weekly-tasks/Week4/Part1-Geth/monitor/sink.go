@@ -0,0 +1,520 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ------------------------------------------------
+// Sink：输出目标的统一接口，新的输出（数据库、Kafka、webhook 等）实现 Sink 并在 sinkFactories 中注册即可，
+// Output 负责生命周期管理和错误隔离
+// ------------------------------------------------
+
+// Sink 一个输出目标
+//   - Start 在监控启动时调用一次，可以在这里建立连接；NewOutput 之后、Start 之前 Write 也必须可用
+//   - Write 不应阻塞太久，需要网络的 sink 应先缓存在内存中，由 Flush 批量发送
+//   - Flush 由 Output 定期调用，也会在 Close 之前调用
+//   - 各方法可能在不同的 goroutine 中调用，需要自己加锁
+type Sink interface {
+	Start(ctx context.Context) error
+	Write(ev Event) error
+	Flush() error
+	Close() error
+}
+
+// SinkFactory 按配置创建 sink
+type SinkFactory func(cfg OutputConfig) (Sink, error)
+
+// sinkFactories outputs 中 sink 字段可选的类型
+var sinkFactories = map[string]SinkFactory{
+	SinkStream:   newStreamSink,
+	SinkWebhook:  newWebhookSink,
+	SinkTelegram: newTelegramSink,
+}
+
+// 内置的 sink 类型
+const (
+	SinkStream   = "stream"   // stdout / stderr / 文件，按 format 渲染
+	SinkWebhook  = "webhook"  // 按批 POST JSON 数组到 url
+	SinkTelegram = "telegram" // 通过 Telegram Bot 发送到 chat_id，通常配合 routing 只发 critical
+)
+
+// RegisterSink 注册新的 sink 类型，需要在 LoadConfig 之前调用
+func RegisterSink(kind string, f SinkFactory) {
+	sinkFactories[kind] = f
+}
+
+// ------------------------------------------------
+// stream：写入 stdout / stderr / 可轮转的文件
+// ------------------------------------------------
+
+type streamSink struct {
+	mu        sync.Mutex
+	w         io.Writer
+	closer    io.Closer // 文件目标需要关闭，stdout / stderr 为 nil
+	format    string
+	verbosity map[string]string
+}
+
+func newStreamSink(cfg OutputConfig) (Sink, error) {
+	s := &streamSink{format: cfg.Format, verbosity: cfg.Verbosity}
+	if s.format == "" {
+		s.format = FormatPretty
+	}
+	switch cfg.Target {
+	case "stdout":
+		s.w = os.Stdout
+	case "stderr":
+		s.w = os.Stderr
+	default:
+		f, err := OpenRotatingFile(cfg.Target, cfg)
+		if err != nil {
+			return nil, err
+		}
+		s.w, s.closer = f, f
+	}
+	return s, nil
+}
+
+func (s *streamSink) Start(ctx context.Context) error { return nil }
+
+func (s *streamSink) Write(ev Event) error {
+	text := render(s.format, s.verbosity[ev.Type], ev)
+	if text == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := io.WriteString(s.w, text)
+	return err
+}
+
+func (s *streamSink) Flush() error { return nil }
+
+func (s *streamSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// ------------------------------------------------
+// webhook：缓存在内存中，Flush 时按批 POST 到 url
+// 配置了 spill_dir 时，内存缓存满了或发送失败的事件写入磁盘队列，恢复后按顺序补发
+// ------------------------------------------------
+
+const (
+	// 每次 POST 的最大事件数默认值
+	DefaultWebhookBatch = 100
+	// ⚠️ 对端长时间不可用时内存中最多保留的事件数，没有磁盘队列时超过后丢弃最旧的
+	WebhookMaxBuffer = 10000
+	// 每次 Flush 最多从磁盘队列补发的批数，积压很多时分多次补发，不长时间占用 Flush
+	WebhookDrainBatches = 20
+)
+
+type webhookSink struct {
+	url       string
+	batch     int
+	verbosity map[string]string
+	http      *http.Client
+	spill     *SpillQueue // 未配置 spill_dir 时为 nil
+
+	flushMu sync.Mutex // 定时 Flush 与 Close 之前的 Flush 不能同时进行
+	mu      sync.Mutex
+	buf     []json.RawMessage
+	dropped int
+}
+
+func newWebhookSink(cfg OutputConfig) (Sink, error) {
+	batch := cfg.BatchSize
+	if batch == 0 {
+		batch = DefaultWebhookBatch
+	}
+	s := &webhookSink{
+		url:       cfg.URL,
+		batch:     batch,
+		verbosity: cfg.Verbosity,
+		http:      &http.Client{Timeout: CONNECTION_TIMEOUT},
+	}
+	if cfg.SpillDir != "" {
+		q, err := OpenSpillQueue(cfg.SpillDir, cfg.SpillMaxMB)
+		if err != nil {
+			return nil, err
+		}
+		s.spill = q
+	}
+	return s, nil
+}
+
+func (s *webhookSink) Start(ctx context.Context) error { return nil }
+
+func (s *webhookSink) Write(ev Event) error {
+	v := s.verbosity[ev.Type]
+	if v == VerbosityOff {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var err error
+	if len(s.buf) >= WebhookMaxBuffer && s.spill != nil { // 发送跟不上，整批转到磁盘
+		if err = s.spill.Push(s.buf); err == nil {
+			s.buf = nil
+		}
+	}
+	// 没有磁盘队列或写入磁盘失败：缓存保留，丢弃最旧的一条
+	if len(s.buf) >= WebhookMaxBuffer {
+		s.buf = s.buf[1:]
+		s.dropped++
+	}
+	s.buf = append(s.buf, marshalEvent(ev, v))
+	return err
+}
+
+// requeue 把没有写进磁盘队列的事件放回缓存最前面，超过上限时丢弃最旧的并计入 dropped
+// （Push 写到一半失败时整批放回，已写入的部分之后会重复投递）
+func (s *webhookSink) requeue(pending []json.RawMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buf = append(pending, s.buf...)
+	if over := len(s.buf) - WebhookMaxBuffer; over > 0 {
+		s.buf = s.buf[over:]
+		s.dropped += over
+	}
+}
+
+// Flush 按批发送缓存的事件，发送失败的一批及之后的事件留在缓存中（或写入磁盘队列）下次重试
+func (s *webhookSink) Flush() error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+	if s.spill != nil {
+		return s.flushSpill()
+	}
+	s.mu.Lock()
+	pending := s.buf
+	s.buf = nil
+	dropped := s.dropped
+	s.dropped = 0
+	s.mu.Unlock()
+
+	var err error
+	for len(pending) > 0 {
+		n := min(len(pending), s.batch)
+		var unacked []json.RawMessage
+		if unacked, err = s.post(pending[:n]); err != nil {
+			pending = append(unacked, pending[n:]...)
+			break
+		}
+		pending = pending[n:]
+	}
+	if len(pending) > 0 {
+		s.mu.Lock()
+		s.buf = append(pending, s.buf...)
+		if over := len(s.buf) - WebhookMaxBuffer; over > 0 {
+			s.buf = s.buf[over:]
+			dropped += over
+		}
+		s.mu.Unlock()
+	}
+	if err == nil && dropped > 0 {
+		err = fmt.Errorf("缓存已满，丢弃了 %d 条事件", dropped)
+	}
+	return err
+}
+
+// flushSpill 先补发磁盘队列中的旧事件，再发送内存中的新事件，保持顺序；
+// 对端不可用或积压没有补发完时，内存中的事件追加到磁盘队列
+func (s *webhookSink) flushSpill() error {
+	s.mu.Lock()
+	pending := s.buf
+	s.buf = nil
+	dropped := s.dropped
+	s.dropped = 0
+	s.mu.Unlock()
+
+	err := s.drainSpill(pending)
+	if err == nil && dropped > 0 {
+		err = fmt.Errorf("磁盘队列写入失败，丢弃了 %d 条事件", dropped)
+	}
+	return err
+}
+
+// drainSpill 先发送磁盘队列中的事件，再发送 pending；写入磁盘队列失败的事件放回缓存
+func (s *webhookSink) drainSpill(pending []json.RawMessage) error {
+	push := func(records []json.RawMessage) error {
+		if err := s.spill.Push(records); err != nil {
+			s.requeue(records)
+			return err
+		}
+		return nil
+	}
+	for i := 0; i < WebhookDrainBatches && s.spill.Len() > 0; i++ {
+		records, err := s.spill.Peek(s.batch)
+		if err == nil {
+			var unacked []json.RawMessage
+			unacked, err = s.post(records)
+			if len(unacked) < len(records) { // 至少确认了一部分：取出整批，未确认的重新排到队尾
+				if popErr := s.spill.Pop(len(records)); popErr != nil {
+					s.requeue(pending)
+					return popErr
+				}
+				if pushErr := s.spill.Push(unacked); pushErr != nil {
+					s.requeue(append(unacked, pending...))
+					return pushErr
+				}
+			}
+		}
+		if err != nil {
+			if pushErr := push(pending); pushErr != nil {
+				return pushErr
+			}
+			return err
+		}
+	}
+	if s.spill.Len() > 0 {
+		return push(pending)
+	}
+	for len(pending) > 0 {
+		n := min(len(pending), s.batch)
+		if unacked, err := s.post(pending[:n]); err != nil {
+			pending = append(unacked, pending[n:]...)
+			if pushErr := push(pending); pushErr != nil {
+				return pushErr
+			}
+			log.Printf("💾 webhook %s 发送失败或未确认，%d 条事件已写入磁盘队列", s.url, len(pending))
+			return err
+		}
+		pending = pending[n:]
+	}
+	return nil
+}
+
+// post 发送一批事件，返回未确认的事件（请求失败时为整批）
+// 对端返回 2xx 时默认整批确认；响应体为 {"ack": ["<id>", ...]} 时只确认其中列出的事件，其余的之后重新投递
+func (s *webhookSink) post(records []json.RawMessage) ([]json.RawMessage, error) {
+	var body bytes.Buffer
+	body.WriteByte('[')
+	for i, r := range records {
+		if i > 0 {
+			body.WriteByte(',')
+		}
+		body.Write(r)
+	}
+	body.WriteByte(']')
+	resp, err := s.http.Post(s.url, "application/json", &body)
+	if err != nil {
+		return records, fmt.Errorf("webhook 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode/100 != 2 {
+		if len(data) > 512 {
+			data = data[:512]
+		}
+		return records, fmt.Errorf("webhook 返回 HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	var reply struct {
+		Ack *[]string `json:"ack"`
+	}
+	if json.Unmarshal(data, &reply) != nil || reply.Ack == nil {
+		return nil, nil
+	}
+	acked := make(map[string]bool, len(*reply.Ack))
+	for _, id := range *reply.Ack {
+		acked[id] = true
+	}
+	var unacked []json.RawMessage
+	invalid := 0
+	for _, r := range records {
+		var rec struct {
+			ID string `json:"id"`
+		}
+		if json.Unmarshal(r, &rec) != nil || rec.ID == "" {
+			// 没有 id 的记录无法被确认，不能当作已送达
+			invalid++
+			unacked = append(unacked, r)
+			continue
+		}
+		if !acked[rec.ID] {
+			unacked = append(unacked, r)
+		}
+	}
+	if invalid > 0 {
+		return unacked, fmt.Errorf("webhook 无法确认 %d 条缺少 id 的事件", invalid)
+	}
+	if len(unacked) > 0 {
+		return unacked, fmt.Errorf("webhook 只确认了 %d / %d 条事件，其余的稍后重新投递", len(records)-len(unacked), len(records))
+	}
+	return nil, nil
+}
+
+func (s *webhookSink) Close() error {
+	if s.spill == nil {
+		return nil
+	}
+	return s.spill.Close()
+}
+
+// ------------------------------------------------
+// telegram：缓存事件摘要，Flush 时合并成尽量少的消息通过 Bot API 发送
+// ------------------------------------------------
+
+const (
+	TelegramAPI = "https://api.telegram.org"
+	// Telegram 单条消息的长度上限是 4096 字符，留一些余量
+	TelegramMaxMessage = 4000
+	// ⚠️ Bot API 不可用时内存中最多保留的事件数，超过后丢弃最旧的
+	TelegramMaxBuffer = 1000
+)
+
+// severityIcon telegram 消息中各级别的前缀
+var severityIcon = map[string]string{SeverityInfo: "ℹ️", SeverityWarn: "⚠️", SeverityCritical: "🚨"}
+
+type telegramSink struct {
+	url       string // 含 bot token 的 sendMessage 地址，不能出现在日志中
+	chatID    string
+	verbosity map[string]string
+	http      *http.Client
+
+	flushMu sync.Mutex
+	mu      sync.Mutex
+	buf     []string
+	dropped int
+}
+
+func newTelegramSink(cfg OutputConfig) (Sink, error) {
+	token := os.Getenv(cfg.BotTokenEnv)
+	if token == "" {
+		return nil, fmt.Errorf("环境变量 %s 中没有 Telegram bot token", cfg.BotTokenEnv)
+	}
+	base := cfg.URL
+	if base == "" {
+		base = TelegramAPI
+	}
+	return &telegramSink{
+		url:       strings.TrimSuffix(base, "/") + "/bot" + token + "/sendMessage",
+		chatID:    cfg.ChatID,
+		verbosity: cfg.Verbosity,
+		http:      &http.Client{Timeout: CONNECTION_TIMEOUT},
+	}, nil
+}
+
+func (s *telegramSink) Start(ctx context.Context) error { return nil }
+
+// Write 只缓存一行摘要（verbosity 为 full 时缓存完整文本），前面加上级别图标
+func (s *telegramSink) Write(ev Event) error {
+	v := s.verbosity[ev.Type]
+	if v == VerbosityOff {
+		return nil
+	}
+	text := ev.summary()
+	if v == VerbosityFull {
+		text = strings.TrimRight(fullText(ev), "\n")
+	}
+	if icon := severityIcon[ev.Severity]; icon != "" {
+		text = icon + " " + text
+	}
+	if len(text) > TelegramMaxMessage {
+		text = strings.ToValidUTF8(text[:TelegramMaxMessage], "")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.buf) >= TelegramMaxBuffer {
+		s.buf = s.buf[1:]
+		s.dropped++
+	}
+	s.buf = append(s.buf, text)
+	return nil
+}
+
+// Flush 把缓存的事件按长度上限拼成消息依次发送，发送失败的消息及之后的事件留到下次
+func (s *telegramSink) Flush() error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+	s.mu.Lock()
+	pending := s.buf
+	s.buf = nil
+	dropped := s.dropped
+	s.dropped = 0
+	s.mu.Unlock()
+
+	var err error
+	for len(pending) > 0 {
+		n, size := 0, 0
+		for n < len(pending) && (n == 0 || size+1+len(pending[n]) <= TelegramMaxMessage) {
+			size += 1 + len(pending[n])
+			n++
+		}
+		if err = s.send(strings.Join(pending[:n], "\n")); err != nil {
+			break
+		}
+		pending = pending[n:]
+	}
+	if len(pending) > 0 {
+		s.mu.Lock()
+		s.buf = append(pending, s.buf...)
+		if over := len(s.buf) - TelegramMaxBuffer; over > 0 {
+			s.buf = s.buf[over:]
+			dropped += over
+		}
+		s.mu.Unlock()
+	}
+	if err == nil && dropped > 0 {
+		err = fmt.Errorf("缓存已满，丢弃了 %d 条事件", dropped)
+	}
+	return err
+}
+
+// send 发送一条消息；错误信息中不包含 url（其中有 bot token）
+func (s *telegramSink) send(text string) error {
+	body, _ := json.Marshal(map[string]string{"chat_id": s.chatID, "text": text})
+	resp, err := s.http.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return fmt.Errorf("telegram 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("telegram 返回 HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return nil
+}
+
+func (s *telegramSink) Close() error { return nil }
+
+// marshalEvent 事件的 JSON 编码，json 格式和 webhook 共用；verbosity 为 summary 时省略 data，
+// data 无法编码时也省略，保证总能输出
+func marshalEvent(ev Event, verbosity string) []byte {
+	rec := struct {
+		ID       string      `json:"id"`
+		Type     string      `json:"type"`
+		Time     time.Time   `json:"time"`
+		Severity string      `json:"severity,omitempty"`
+		Category string      `json:"category,omitempty"`
+		Summary  string      `json:"summary"`
+		Data     interface{} `json:"data,omitempty"`
+	}{ev.ID, ev.Type, ev.Time, ev.Severity, ev.Category, ev.summary(), ev.Data}
+	if verbosity == VerbositySummary {
+		rec.Data = nil
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		rec.Data = nil
+		b, _ = json.Marshal(rec)
+	}
+	return b
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// 每个段文件的大小上限，写满后换新文件；读取时整个段读入内存
+	SpillSegmentSize = 4 << 20
+	// 磁盘队列的默认容量上限（MB），超过后删除最旧的段
+	DefaultSpillMaxMB = 512
+	spillExt          = ".spill"
+	spillOffsetFile   = "offset"
+)
+
+// SpillQueue 基于本地文件的 FIFO 队列，sink 不可用或处理太慢时把事件写到磁盘，恢复后按顺序取出重新发送
+// 目录中每个段文件每行一条记录（JSON），offset 文件记录最旧的段已经取出了多少条；
+// 进程在取出之后、更新 offset 之前退出时，重启后这些记录会再发送一次（至少一次）
+type SpillQueue struct {
+	dir      string
+	maxBytes int64
+
+	mu     sync.Mutex
+	segs   []uint64       // 现有段的序号，升序
+	counts map[uint64]int // 每个段的记录数
+	sizes  map[uint64]int64
+	total  int64 // 全部段的字节数
+	length int   // 未取出的记录数
+
+	w     *os.File // 正在写入的段（序号为 segs 的最后一个），还没有写入时为 nil
+	wSeq  uint64
+	wSize int64
+
+	head    []json.RawMessage // 已读入内存的最旧的段
+	headSeq uint64
+	offset  int // head 中已经取出的条数
+}
+
+// OpenSpillQueue 打开（不存在时创建）磁盘队列，maxMB 为 0 时使用默认容量
+func OpenSpillQueue(dir string, maxMB int) (*SpillQueue, error) {
+	if maxMB == 0 {
+		maxMB = DefaultSpillMaxMB
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建磁盘队列目录失败: %w", err)
+	}
+	q := &SpillQueue{
+		dir:      dir,
+		maxBytes: int64(maxMB) << 20,
+		counts:   make(map[uint64]int),
+		sizes:    make(map[uint64]int64),
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取磁盘队列目录失败: %w", err)
+	}
+	for _, e := range entries {
+		seq, err := strconv.ParseUint(strings.TrimSuffix(e.Name(), spillExt), 10, 64)
+		if err != nil || !strings.HasSuffix(e.Name(), spillExt) {
+			continue
+		}
+		n, size, err := countLines(q.segPath(seq))
+		if err != nil {
+			return nil, err
+		}
+		q.segs = append(q.segs, seq)
+		q.counts[seq], q.sizes[seq] = n, size
+		q.total += size
+		q.length += n
+	}
+	sort.Slice(q.segs, func(i, j int) bool { return q.segs[i] < q.segs[j] })
+
+	// 恢复最旧的段的读取位置
+	if data, err := os.ReadFile(filepath.Join(dir, spillOffsetFile)); err == nil && len(q.segs) > 0 {
+		var seq uint64
+		var off int
+		if _, err := fmt.Sscanf(string(data), "%d %d", &seq, &off); err == nil && seq == q.segs[0] {
+			if err := q.loadHead(); err != nil {
+				return nil, err
+			}
+			q.offset = min(off, len(q.head))
+			q.length -= q.offset
+		}
+	}
+	if q.length > 0 {
+		log.Printf("💾 磁盘队列 %s 中有 %d 条未发送的记录", dir, q.length)
+	}
+	return q, nil
+}
+
+func (q *SpillQueue) segPath(seq uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%012d%s", seq, spillExt))
+}
+
+// Len 未取出的记录数
+func (q *SpillQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.length
+}
+
+// Push 追加记录，超过容量时删除最旧的段（丢弃其中的记录）
+func (q *SpillQueue) Push(records []json.RawMessage) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, r := range records {
+		if q.w == nil || q.wSize >= SpillSegmentSize {
+			if err := q.roll(); err != nil {
+				return err
+			}
+		}
+		if _, err := q.w.Write(append(append([]byte{}, r...), '\n')); err != nil {
+			return fmt.Errorf("写入磁盘队列失败: %w", err)
+		}
+		size := int64(len(r) + 1)
+		q.wSize += size
+		q.sizes[q.wSeq] += size
+		q.counts[q.wSeq]++
+		q.total += size
+		q.length++
+	}
+	for q.total > q.maxBytes && len(q.segs) > 1 {
+		seq := q.segs[0]
+		dropped := q.counts[seq]
+		if q.head != nil && q.headSeq == seq {
+			dropped -= q.offset
+		}
+		log.Printf("⚠️  磁盘队列 %s 超过容量，丢弃最旧的 %d 条记录", q.dir, dropped)
+		q.removeHead()
+		q.length -= dropped
+	}
+	return nil
+}
+
+// roll 关闭当前段，开始写入新的段（调用方持有锁）
+func (q *SpillQueue) roll() error {
+	if q.w != nil {
+		q.w.Close()
+		q.w = nil
+	}
+	seq := uint64(1)
+	if len(q.segs) > 0 {
+		seq = q.segs[len(q.segs)-1] + 1
+	}
+	f, err := os.OpenFile(q.segPath(seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("创建磁盘队列文件失败: %w", err)
+	}
+	q.w, q.wSeq, q.wSize = f, seq, 0
+	q.segs = append(q.segs, seq)
+	return nil
+}
+
+// Peek 按顺序返回最多 n 条记录，不会取出；需要跨段时只返回当前段剩余的部分
+func (q *SpillQueue) Peek(n int) ([]json.RawMessage, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.length == 0 {
+		return nil, nil
+	}
+	for q.head == nil || q.offset >= len(q.head) {
+		if q.head != nil { // 空段（例如上次换段后没有写入就退出）
+			q.removeHead()
+		}
+		if err := q.loadHead(); err != nil {
+			return nil, err
+		}
+	}
+	end := min(q.offset+n, len(q.head))
+	return q.head[q.offset:end], nil
+}
+
+// loadHead 把最旧的段读入内存；如果它正在写入，先换一个新段，保证读取的段不再变化（调用方持有锁）
+func (q *SpillQueue) loadHead() error {
+	seq := q.segs[0]
+	if q.w != nil && q.wSeq == seq {
+		if err := q.roll(); err != nil {
+			return err
+		}
+	}
+	data, err := os.ReadFile(q.segPath(seq))
+	if err != nil {
+		return fmt.Errorf("读取磁盘队列文件失败: %w", err)
+	}
+	q.head, q.headSeq, q.offset = nil, seq, 0
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(line) > 0 {
+			q.head = append(q.head, json.RawMessage(line))
+		}
+	}
+	return nil
+}
+
+// Pop 取出 Peek 返回的前 n 条记录，最旧的段全部取出后删除
+func (q *SpillQueue) Pop(n int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.head == nil {
+		return nil
+	}
+	n = min(n, len(q.head)-q.offset)
+	q.offset += n
+	q.length -= n
+	if q.offset >= len(q.head) {
+		q.removeHead()
+		if err := os.Remove(filepath.Join(q.dir, spillOffsetFile)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(filepath.Join(q.dir, spillOffsetFile), []byte(fmt.Sprintf("%d %d\n", q.headSeq, q.offset)), 0o644)
+}
+
+// removeHead 删除最旧的段（调用方持有锁，并负责调整 length）
+func (q *SpillQueue) removeHead() {
+	seq := q.segs[0]
+	if q.w != nil && q.wSeq == seq {
+		q.w.Close()
+		q.w = nil
+	}
+	if err := os.Remove(q.segPath(seq)); err != nil && !os.IsNotExist(err) {
+		log.Printf("⚠️  删除磁盘队列文件失败: %v", err)
+	}
+	q.total -= q.sizes[seq]
+	delete(q.sizes, seq)
+	delete(q.counts, seq)
+	q.segs = q.segs[1:]
+	if q.head != nil && q.headSeq == seq {
+		q.head, q.offset = nil, 0
+	}
+}
+
+// Close 关闭正在写入的段
+func (q *SpillQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.w == nil {
+		return nil
+	}
+	err := q.w.Close()
+	q.w = nil
+	return err
+}
+
+// countLines 统计段文件的记录数和大小
+func countLines(path string) (int, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("读取磁盘队列文件失败: %w", err)
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64<<10), SpillSegmentSize+(1<<20))
+	n := 0
+	for sc.Scan() {
+		if len(sc.Bytes()) > 0 {
+			n++
+		}
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	return n, info.Size(), sc.Err()
+}
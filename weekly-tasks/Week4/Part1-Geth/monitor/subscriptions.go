@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ------------------------------------------------
+// 可切换的订阅集合：新区块、Pending 交易、合约日志、finalized 区块各自可以在配置中关闭，
+// 运行中也可以通过 API 或 SIGUSR1 开关，例如交易池风暴时临时关掉 Pending 订阅而不用重启
+// 关闭即退订，重新开启时重新订阅；订阅异常中断仍然让 Run 返回，由外层重启
+// ------------------------------------------------
+
+const (
+	StreamHeads     = "heads"
+	StreamPending   = "pending"
+	StreamLogs      = "logs"
+	StreamFinalized = "finalized"
+
+	// 查询 finalized 区块的默认间隔（秒），约一个 slot
+	DefaultFinalizedInterval = 12
+)
+
+// streamNames 所有可切换的订阅，按输出顺序
+var streamNames = []string{StreamHeads, StreamPending, StreamLogs, StreamFinalized}
+
+// SubscriptionsConfig 启动时开启哪些订阅
+type SubscriptionsConfig struct {
+	Heads             bool     `json:"heads"`
+	Pending           bool     `json:"pending"`
+	Logs              bool     `json:"logs"`               // 还需要 logs.addresses 不为空
+	Finalized         bool     `json:"finalized"`          // 定期查询 finalized 区块，前进时输出
+	FinalizedInterval int      `json:"finalized_interval"` // 查询 finalized 区块的间隔（秒）
+	SignalToggle      []string `json:"signal_toggle"`      // 收到 SIGUSR1 时切换的订阅
+}
+
+// SubscriptionStatus /api/subscriptions 中一个订阅的状态
+type SubscriptionStatus struct {
+	Name      string     `json:"name"`
+	Available bool       `json:"available"` // false 表示节点或配置不支持，无法开启
+	Enabled   bool       `json:"enabled"`
+	Since     *time.Time `json:"since,omitempty"` // 最近一次运行中切换的时间
+}
+
+// SubscriptionEvent 运行中的一次开关
+type SubscriptionEvent struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Reason  string `json:"reason"` // api / signal
+}
+
+// subscriptionStream 一个可切换的订阅
+type subscriptionStream struct {
+	name  string
+	label string                                                   // 用于错误信息，例如 "区块"
+	start func(ctx context.Context) (ethereum.Subscription, error) // nil 表示不可用
+	sub   ethereum.Subscription                                    // 关闭时为 nil
+	since time.Time
+}
+
+// SubscriptionSet 管理所有可切换的订阅
+type SubscriptionSet struct {
+	ctx  context.Context
+	errs chan error
+
+	mu      sync.Mutex
+	streams map[string]*subscriptionStream
+
+	onChange func(SubscriptionEvent)
+}
+
+// NewSubscriptionSet 创建订阅集合，订阅都绑定在 ctx 上；onChange 为 nil 时使用默认输出
+func NewSubscriptionSet(ctx context.Context, onChange func(SubscriptionEvent)) *SubscriptionSet {
+	if onChange == nil {
+		onChange = PrintSubscriptionEvent
+	}
+	return &SubscriptionSet{
+		ctx:      ctx,
+		errs:     make(chan error, len(streamNames)),
+		streams:  make(map[string]*subscriptionStream),
+		onChange: onChange,
+	}
+}
+
+// Add 注册一个订阅，enabled 时立即订阅并返回订阅的错误；start 为 nil 表示节点或配置不支持，之后也无法开启
+func (s *SubscriptionSet) Add(name, label string, enabled bool, start func(ctx context.Context) (ethereum.Subscription, error)) error {
+	st := &subscriptionStream{name: name, label: label, start: start}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.streams[name] = st
+	if !enabled || start == nil {
+		return nil
+	}
+	return s.subscribeLocked(st)
+}
+
+// subscribeLocked 订阅并在后台等待中断，调用方持有锁
+func (s *SubscriptionSet) subscribeLocked(st *subscriptionStream) error {
+	sub, err := st.start(s.ctx)
+	if err != nil {
+		return err
+	}
+	st.sub = sub
+	go func() {
+		// 退订时 Err 被关闭，读到 nil
+		if err := <-sub.Err(); err != nil {
+			select {
+			case s.errs <- fmt.Errorf("%s订阅异常中断: %w", st.label, err):
+			default:
+			}
+		}
+	}()
+	return nil
+}
+
+// Set 开启或关闭订阅，状态没有变化时什么也不做
+func (s *SubscriptionSet) Set(name string, enabled bool, reason string) error {
+	s.mu.Lock()
+	st, ok := s.streams[name]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("未知的订阅: %q", name)
+	}
+	if st.start == nil {
+		s.mu.Unlock()
+		return fmt.Errorf("订阅 %s 不可用（节点不支持或没有配置）", name)
+	}
+	if enabled == (st.sub != nil) {
+		s.mu.Unlock()
+		return nil
+	}
+	if enabled {
+		if err := s.subscribeLocked(st); err != nil {
+			s.mu.Unlock()
+			return fmt.Errorf("订阅 %s 失败: %w", name, err)
+		}
+	} else {
+		st.sub.Unsubscribe()
+		st.sub = nil
+	}
+	st.since = time.Now()
+	s.mu.Unlock()
+
+	s.onChange(SubscriptionEvent{Name: name, Enabled: enabled, Reason: reason})
+	return nil
+}
+
+// Replace 替换订阅的 start（例如热加载后日志过滤条件变了），订阅开启时用新的 start 重新订阅；
+// start 为 nil 时订阅变为不可用并退订
+func (s *SubscriptionSet) Replace(name string, start func(ctx context.Context) (ethereum.Subscription, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.streams[name]
+	if !ok {
+		return fmt.Errorf("未知的订阅: %q", name)
+	}
+	active := st.sub != nil
+	if active {
+		st.sub.Unsubscribe()
+		st.sub = nil
+	}
+	st.start = start
+	if !active || start == nil {
+		return nil
+	}
+	if err := s.subscribeLocked(st); err != nil {
+		return fmt.Errorf("重新订阅 %s 失败: %w", name, err)
+	}
+	return nil
+}
+
+// Toggle 切换订阅的开关
+func (s *SubscriptionSet) Toggle(name, reason string) error {
+	return s.Set(name, !s.Enabled(name), reason)
+}
+
+// Enabled 订阅当前是否开启
+func (s *SubscriptionSet) Enabled(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.streams[name]
+	return ok && st.sub != nil
+}
+
+// Err 任一订阅异常中断时收到错误
+func (s *SubscriptionSet) Err() <-chan error { return s.errs }
+
+// Close 退订所有订阅
+func (s *SubscriptionSet) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, st := range s.streams {
+		if st.sub != nil {
+			st.sub.Unsubscribe()
+			st.sub = nil
+		}
+	}
+}
+
+// Status 每个订阅当前的状态
+func (s *SubscriptionSet) Status() []SubscriptionStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]SubscriptionStatus, 0, len(s.streams))
+	for _, name := range streamNames {
+		st, ok := s.streams[name]
+		if !ok {
+			continue
+		}
+		status := SubscriptionStatus{Name: name, Available: st.start != nil, Enabled: st.sub != nil}
+		if !st.since.IsZero() {
+			since := st.since
+			status.Since = &since
+		}
+		out = append(out, status)
+	}
+	return out
+}
+
+// RegisterAPI 注册订阅开关接口
+//
+//	GET  /api/subscriptions                 每个订阅是否可用、是否开启
+//	POST /api/subscriptions/{name}/enable   开启订阅
+//	POST /api/subscriptions/{name}/disable  关闭订阅
+func (s *SubscriptionSet) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.Status())
+	})
+	set := func(enabled bool) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if err := s.Set(r.PathValue("name"), enabled, "api"); err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			writeJSON(w, http.StatusOK, s.Status())
+		}
+	}
+	api.Handle("POST /api/subscriptions/{name}/enable", set(true))
+	api.Handle("POST /api/subscriptions/{name}/disable", set(false))
+}
+
+// PrintSubscriptionEvent 默认的订阅开关输出
+func PrintSubscriptionEvent(e SubscriptionEvent) {
+	var summary string
+	if e.Enabled {
+		summary = fmt.Sprintf("🎛️  [Subscriptions] 已开启 %s 订阅（%s）", e.Name, e.Reason)
+	} else {
+		summary = fmt.Sprintf("🎛️  [Subscriptions] 已关闭 %s 订阅（%s）", e.Name, e.Reason)
+		if e.Name == StreamHeads {
+			summary += "，Watchdog 和健康检查会把它视为出块停滞"
+		}
+	}
+	Emit(Event{Type: "subscription", Summary: summary, Text: summary, Data: e})
+}
+
+// pollFinalized 每 interval 查询一次 finalized 区块，前进时调用 onFinalized；
+// 返回的订阅退订时停止，查询失败只记录日志（节点不支持 finalized 标签时也不会中断 Run）
+func pollFinalized(client *ethclient.Client, interval time.Duration, onFinalized func(*types.Header)) ethereum.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			<-quit
+			cancel()
+		}()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var last uint64
+		var lastErr string
+		for {
+			if rpcBudget.Load().Allow(StreamFinalized, interval) {
+				var h *types.Header
+				err := Retry(ctx, "eth_getBlockByNumber", func() (err error) {
+					h, err = client.HeaderByNumber(ctx, big.NewInt(int64(rpc.FinalizedBlockNumber)))
+					return err
+				})
+				switch {
+				case err != nil && ctx.Err() == nil && err.Error() != lastErr:
+					log.Printf("⚠️  查询 finalized 区块失败: %v", err)
+					lastErr = err.Error()
+				case err == nil && h.Number.Uint64() > last:
+					last, lastErr = h.Number.Uint64(), ""
+					onFinalized(h)
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-quit:
+				return nil
+			}
+		}
+	})
+}
+
+// PrintFinalized 默认的 finalized 区块输出
+func PrintFinalized(h *types.Header) {
+	text := fmt.Sprintf("🏁 [Finalized] Height: %d | Hash: %s", h.Number, h.Hash().Hex())
+	Emit(Event{Type: "finalized", Key: h.Hash().Hex(), Summary: text, Text: text, Data: map[string]interface{}{"number": h.Number, "hash": h.Hash(), "time": h.Time}})
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package main
+
+import (
+	"context"
+	"log"
+)
+
+// RunSignals 其它平台没有 SIGUSR1，只能通过 API 开关订阅
+func (s *SubscriptionSet) RunSignals(ctx context.Context, names []string) {
+	if len(names) > 0 {
+		log.Printf("⚠️  当前平台不支持 SIGUSR1，signal_toggle 不生效，请使用 /api/subscriptions")
+	}
+}
@@ -0,0 +1,33 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// RunSignals 每次收到 SIGUSR1 时切换 names 中的订阅，直到 ctx 取消
+func (s *SubscriptionSet) RunSignals(ctx context.Context, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1)
+	defer signal.Stop(ch)
+	for {
+		select {
+		case <-ch:
+			for _, name := range names {
+				if err := s.Toggle(name, "signal"); err != nil {
+					log.Printf("⚠️  %v", err)
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
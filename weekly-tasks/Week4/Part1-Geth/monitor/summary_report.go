@@ -0,0 +1,366 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ------------------------------------------------
+// 定时汇总报告：按配置的窗口（例如每小时、每天）聚合区块数、平均 Gas、交易池吞吐、
+// 调用最多的合约和关注地址的活动，窗口结束时作为一个事件输出到所有 sink（包括 webhook 等通知渠道）
+// 窗口按 UTC 整点对齐，多个窗口共用同一份区块 / Pending 数据，各自独立累计
+// ------------------------------------------------
+
+const (
+	// 默认列出调用次数最多的前 10 个合约
+	DefaultReportTop = 10
+)
+
+// ReportsConfig 定时汇总报告
+type ReportsConfig struct {
+	Windows []int `json:"windows"` // 汇总窗口（分钟），例如 [60, 1440] 为每小时和每天各一份；为空时不输出
+	Top     int   `json:"top"`     // 列出调用次数最多的前几个合约
+
+	// 摘要文件（digest.go），digest_dir 为空时不生成
+	DigestDir     string `json:"digest_dir"`
+	DigestFormat  string `json:"digest_format"`  // markdown（默认）| html
+	DigestWindows []int  `json:"digest_windows"` // 哪些窗口生成摘要，为空时全部生成
+}
+
+// ContractCalls 窗口内一个合约被直接调用的次数
+type ContractCalls struct {
+	Address common.Address `json:"address"`
+	Calls   int            `json:"calls"`
+}
+
+// WatchedActivity 窗口内一个关注地址的交易
+type WatchedActivity struct {
+	Address     common.Address `json:"address"`
+	Sent        int            `json:"sent"`
+	Received    int            `json:"received"`
+	SentETH     float64        `json:"sent_eth"`
+	ReceivedETH float64        `json:"received_eth"`
+}
+
+// SummaryReport 一个窗口的汇总
+type SummaryReport struct {
+	Window        string            `json:"window"` // 例如 1h / 1d / 30m
+	Minutes       int               `json:"minutes"`
+	From          time.Time         `json:"from"`
+	To            time.Time         `json:"to"`
+	Partial       bool              `json:"partial"` // 窗口没有完整覆盖（启动后的第一个窗口，或 /api/reports 中进行中的窗口）
+	FirstBlock    uint64            `json:"first_block"`
+	LastBlock     uint64            `json:"last_block"`
+	Blocks        int               `json:"blocks"`
+	Txs           int               `json:"txs"`
+	AvgGasUsed    uint64            `json:"avg_gas_used"`
+	AvgGasPct     float64           `json:"avg_gas_pct"` // 平均 gasUsed / gasLimit（%）
+	AvgBaseFee    float64           `json:"avg_base_fee_gwei"`
+	Pending       int               `json:"pending"`            // 交易池中看到的交易数
+	PendingPerMin float64           `json:"pending_per_minute"` // 交易池吞吐
+	TopContracts  []ContractCalls   `json:"top_contracts"`
+	Watched       []WatchedActivity `json:"watched,omitempty"`
+}
+
+// watchedTally 一个关注地址的累计
+type watchedTally struct {
+	sent, received    int
+	valueOut, valueIn *big.Int
+}
+
+// summaryWindow 一个窗口的累计，start 按 UTC 整点对齐，since 为实际开始累计的时间
+type summaryWindow struct {
+	length time.Duration
+	start  time.Time
+	since  time.Time
+
+	blocks, txs, pending int
+	first, last          uint64
+	gasUsed, gasLimit    uint64
+	baseFee              float64 // 有 baseFee 的区块的 gwei 之和
+	baseFeeBlocks        int
+	contracts            map[common.Address]int
+	watched              map[common.Address]*watchedTally
+}
+
+func newSummaryWindow(length time.Duration, now time.Time) *summaryWindow {
+	w := &summaryWindow{length: length}
+	w.reset(now.Truncate(length), now)
+	return w
+}
+
+// reset 从 start 开始新的窗口
+func (w *summaryWindow) reset(start, since time.Time) {
+	*w = summaryWindow{
+		length:    w.length,
+		start:     start,
+		since:     since,
+		contracts: make(map[common.Address]int),
+		watched:   make(map[common.Address]*watchedTally),
+	}
+}
+
+// end 窗口结束时间
+func (w *summaryWindow) end() time.Time { return w.start.Add(w.length) }
+
+// tally 关注地址的累计，没有时创建
+func (w *summaryWindow) tally(addr common.Address) *watchedTally {
+	t, ok := w.watched[addr]
+	if !ok {
+		t = &watchedTally{valueOut: new(big.Int), valueIn: new(big.Int)}
+		w.watched[addr] = t
+	}
+	return t
+}
+
+// SummaryReporter 按窗口汇总区块和交易池数据，窗口结束时输出报告
+type SummaryReporter struct {
+	cfg      ReportsConfig
+	watch    *Watchlist
+	onReport func(SummaryReport)
+
+	mu      sync.Mutex
+	windows []*summaryWindow
+}
+
+// NewSummaryReporter 创建汇总报告，onReport 为 nil 时使用默认输出
+func NewSummaryReporter(cfg ReportsConfig, watch *Watchlist, onReport func(SummaryReport)) *SummaryReporter {
+	if onReport == nil {
+		onReport = PrintSummaryReport
+	}
+	r := &SummaryReporter{cfg: cfg, watch: watch, onReport: onReport}
+	now := time.Now()
+	for _, minutes := range cfg.Windows {
+		r.windows = append(r.windows, newSummaryWindow(time.Duration(minutes)*time.Minute, now))
+	}
+	return r
+}
+
+// Enrichment 实现 Enricher：只需要交易和发送者，gas 数据来自区块头
+func (r *SummaryReporter) Enrichment() EnrichLevel { return EnrichTransactions }
+
+// OnBlock 实现 BlockAnalyzer
+func (r *SummaryReporter) OnBlock(data *BlockData) {
+	block := data.Block
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, w := range r.windows {
+		if w.blocks == 0 {
+			w.first = block.NumberU64()
+		}
+		w.blocks++
+		w.last = block.NumberU64()
+		w.txs += len(block.Transactions())
+		w.gasUsed += block.GasUsed()
+		w.gasLimit += block.GasLimit()
+		if block.BaseFee() != nil {
+			w.baseFee += weiToGwei(block.BaseFee())
+			w.baseFeeBlocks++
+		}
+		for i, tx := range block.Transactions() {
+			if tx.To() != nil && len(tx.Data()) >= 4 {
+				w.contracts[*tx.To()]++
+			}
+			if r.watch == nil {
+				continue
+			}
+			if from := data.Senders[i]; r.watch.Contains(from) {
+				t := w.tally(from)
+				t.sent++
+				t.valueOut.Add(t.valueOut, tx.Value())
+			}
+			if tx.To() != nil && r.watch.Contains(*tx.To()) {
+				t := w.tally(*tx.To())
+				t.received++
+				t.valueIn.Add(t.valueIn, tx.Value())
+			}
+		}
+	}
+}
+
+// Observe 统计交易池吞吐（"reports" 消费者）
+func (r *SummaryReporter) Observe(BusMessage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, w := range r.windows {
+		w.pending++
+	}
+}
+
+// Run 在每个窗口结束时输出报告，直到 ctx 取消
+func (r *SummaryReporter) Run(ctx context.Context) {
+	if len(r.windows) == 0 {
+		return
+	}
+	for {
+		timer := time.NewTimer(time.Until(r.nextEnd()))
+		select {
+		case now := <-timer.C:
+			for _, report := range r.rotate(now) {
+				r.onReport(report)
+			}
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// nextEnd 最早结束的窗口的结束时间
+func (r *SummaryReporter) nextEnd() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	next := r.windows[0].end()
+	for _, w := range r.windows[1:] {
+		if w.end().Before(next) {
+			next = w.end()
+		}
+	}
+	return next
+}
+
+// rotate 结束所有到期的窗口并开始下一个；系统休眠等原因错过了多个窗口时，从当前所在的窗口重新开始
+func (r *SummaryReporter) rotate(now time.Time) []SummaryReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var reports []SummaryReport
+	for _, w := range r.windows {
+		if now.Before(w.end()) {
+			continue
+		}
+		report := r.build(w, w.end())
+		reports = append(reports, report)
+		start := now.Truncate(w.length)
+		w.reset(start, start)
+	}
+	return reports
+}
+
+// build 生成窗口到 to 为止的报告（调用方持有锁）
+func (r *SummaryReporter) build(w *summaryWindow, to time.Time) SummaryReport {
+	report := SummaryReport{
+		Window:     windowLabel(w.length),
+		Minutes:    int(w.length / time.Minute),
+		From:       w.since,
+		To:         to,
+		Partial:    w.since.After(w.start) || to.Before(w.end()),
+		FirstBlock: w.first,
+		LastBlock:  w.last,
+		Blocks:     w.blocks,
+		Txs:        w.txs,
+		Pending:    w.pending,
+	}
+	if w.blocks > 0 {
+		report.AvgGasUsed = w.gasUsed / uint64(w.blocks)
+	}
+	if w.gasLimit > 0 {
+		report.AvgGasPct = 100 * float64(w.gasUsed) / float64(w.gasLimit)
+	}
+	if w.baseFeeBlocks > 0 {
+		report.AvgBaseFee = w.baseFee / float64(w.baseFeeBlocks)
+	}
+	if minutes := to.Sub(w.since).Minutes(); minutes > 0 {
+		report.PendingPerMin = float64(w.pending) / minutes
+	}
+
+	contracts := make([]ContractCalls, 0, len(w.contracts))
+	for addr, calls := range w.contracts {
+		contracts = append(contracts, ContractCalls{Address: addr, Calls: calls})
+	}
+	sort.Slice(contracts, func(i, j int) bool {
+		if contracts[i].Calls != contracts[j].Calls {
+			return contracts[i].Calls > contracts[j].Calls
+		}
+		return contracts[i].Address.Hex() < contracts[j].Address.Hex()
+	})
+	if len(contracts) > r.cfg.Top {
+		contracts = contracts[:r.cfg.Top]
+	}
+	report.TopContracts = contracts
+
+	for addr, t := range w.watched {
+		report.Watched = append(report.Watched, WatchedActivity{
+			Address:     addr,
+			Sent:        t.sent,
+			Received:    t.received,
+			SentETH:     weiToEther(t.valueOut),
+			ReceivedETH: weiToEther(t.valueIn),
+		})
+	}
+	sort.Slice(report.Watched, func(i, j int) bool {
+		a, b := report.Watched[i], report.Watched[j]
+		if a.Sent+a.Received != b.Sent+b.Received {
+			return a.Sent+a.Received > b.Sent+b.Received
+		}
+		return a.Address.Hex() < b.Address.Hex()
+	})
+	return report
+}
+
+// Current 每个窗口进行中的汇总
+func (r *SummaryReporter) Current() []SummaryReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	out := make([]SummaryReport, 0, len(r.windows))
+	for _, w := range r.windows {
+		out = append(out, r.build(w, now))
+	}
+	return out
+}
+
+// RegisterAPI 注册汇总报告查询接口
+//
+//	GET /api/reports 每个窗口进行中的汇总（尚未输出的部分）
+func (r *SummaryReporter) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/reports", func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, r.Current())
+	})
+}
+
+// windowLabel 窗口长度的简写，例如 1d / 1h / 30m
+func windowLabel(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	default:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+}
+
+// PrintSummaryReport 默认的汇总报告输出
+func PrintSummaryReport(r SummaryReport) {
+	summary := fmt.Sprintf("📊 [Summary %s] %s - %s | %d 个区块 | %d 笔交易 | Gas 利用率 %.1f%% | BaseFee %.2f gwei | Pending %.0f 笔/分钟",
+		r.Window, r.From.Format("01-02 15:04"), r.To.Format("01-02 15:04"), r.Blocks, r.Txs, r.AvgGasPct, r.AvgBaseFee, r.PendingPerMin)
+	var sb strings.Builder
+	sb.WriteString("\n" + summary + "\n")
+	if r.Partial {
+		sb.WriteString("   ⚠️  窗口不完整（启动后的第一个窗口）\n")
+	}
+	if r.Blocks > 0 {
+		fmt.Fprintf(&sb, "   区块 %d - %d，平均每块 %d gas\n", r.FirstBlock, r.LastBlock, r.AvgGasUsed)
+	}
+	if len(r.TopContracts) > 0 {
+		sb.WriteString("   调用最多的合约:\n")
+		for i, c := range r.TopContracts {
+			fmt.Fprintf(&sb, "   %2d. %s %6d 次\n", i+1, c.Address.Hex(), c.Calls)
+		}
+	}
+	if len(r.Watched) > 0 {
+		sb.WriteString("   关注地址活动:\n")
+		for _, a := range r.Watched {
+			fmt.Fprintf(&sb, "   👀 %s 发出 %d 笔（%.4f ETH）| 收到 %d 笔（%.4f ETH）\n", a.Address.Hex(), a.Sent, a.SentETH, a.Received, a.ReceivedETH)
+		}
+	}
+	Emit(Event{Type: "summary_report", Summary: summary, Text: sb.String(), Data: r})
+}
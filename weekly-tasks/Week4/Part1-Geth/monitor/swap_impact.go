@@ -0,0 +1,391 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ------------------------------------------------
+// Pending swap 的价格冲击：解码交易池中经过 Uniswap 路由的 swap，用池子当前的状态在本地按
+// V2 恒定乘积 / V3 sqrtPrice 公式算出预期输出和价格冲击，常见情况下不需要 RPC 模拟
+// ------------------------------------------------
+
+const (
+	// 价格冲击达到该值（基点）的 Pending swap 输出 pending_swap 事件
+	// ⚠️ 调得太低会输出大量小额 swap
+	SwapImpactAlertBps = 100
+	// Uniswap V2 的手续费（百万分之一）
+	UniV2FeePpm = 3000
+)
+
+var (
+	// 主网 Uniswap factory 与池子合约的 init code hash，用 CREATE2 在本地算出池子地址
+	uniV2Factory      = common.HexToAddress("0x5C69bEe701ef814a2B6a3EDD4B1652CB9cc5aA6f")
+	uniV2InitCodeHash = common.FromHex("0x96e8ac4277198ff8b6f785478aa9a39f403cb768dd02cbee326c3e7da348845f")
+	uniV3Factory      = common.HexToAddress("0x1F98431c8aD98523631AE4a59f267346ea31F984")
+	uniV3InitCodeHash = common.FromHex("0xe34f199b19b2b4f47f68442619d555527d244f78a3297ea89325f843f87b8b54")
+
+	// 只估算这些路由的调用：其它分叉的路由使用自己的 factory，按 Uniswap 算出的池子不对
+	uniswapRouters = map[common.Address]bool{
+		common.HexToAddress(DefaultUniswapV2Router):                       true, // V2 Router02
+		common.HexToAddress("0xE592427A0AEce92De3Edee1F18E0157C05861564"): true, // V3 SwapRouter
+		common.HexToAddress("0x68b3465833fb72A70ecDF485E0e4C7bD8665Fc45"): true, // SwapRouter02（V2 + V3）
+	}
+
+	selectorGetReserves = common.FromHex("0x0902f1ac") // getReserves()
+	selectorSlot0       = common.FromHex("0x3850c7bd") // slot0()
+	selectorLiquidity   = common.FromHex("0x1a686502") // liquidity()
+)
+
+// swapHop swap 路径中的一跳
+type swapHop struct {
+	tokenIn, tokenOut common.Address
+	v3                bool
+	fee               uint32 // V3 池子的手续费档位（百万分之一）
+}
+
+// pool 这一跳使用的 Uniswap 池子地址
+func (h swapHop) pool() common.Address {
+	if h.v3 {
+		return uniV3PoolAddress(h.tokenIn, h.tokenOut, h.fee)
+	}
+	return uniV2PairAddress(h.tokenIn, h.tokenOut)
+}
+
+// zeroForOne 是否用 token0 换 token1（池子中地址小的是 token0）
+func (h swapHop) zeroForOne() bool {
+	return bytes.Compare(h.tokenIn.Bytes(), h.tokenOut.Bytes()) < 0
+}
+
+// SwapImpact 一笔 swap 按池子当前状态算出的预期结果
+type SwapImpact struct {
+	Function    string           `json:"function"`
+	Path        []common.Address `json:"path"`
+	Pools       []common.Address `json:"pools"`
+	AmountIn    *big.Int         `json:"amount_in"`
+	ExpectedOut *big.Int         `json:"expected_out"`
+	MinOut      *big.Int         `json:"min_out"`
+	ImpactBps   float64          `json:"impact_bps"`   // 成交价相对池子当前价格（已扣除手续费）的偏离
+	SlippageBps float64          `json:"slippage_bps"` // 用户允许的滑点：最少收到的数量相对预期输出，也是夹子能拿走的空间
+	Approx      bool             `json:"approx"`       // 经过 V3 池子：按当前区间的流动性计算，跨越 tick 时只是近似
+	ValueUSD    float64          `json:"value_usd"`
+	Priced      bool             `json:"priced"` // ValueUSD 是否可用
+	Desc        string           `json:"desc"`   // 例如 "1.5 WETH -> 4500.2 USDC"
+}
+
+// String 渲染为一行，用于事件文本
+func (s SwapImpact) String() string {
+	text := fmt.Sprintf("%s: %s，价格冲击 %.2f%%，允许滑点 %.2f%%", s.Function, s.Desc, s.ImpactBps/100, s.SlippageBps/100)
+	if s.Priced {
+		text += "，约 " + formatUSD(s.ValueUSD)
+	}
+	if s.Approx {
+		text += "（V3 近似）"
+	}
+	return text
+}
+
+// PendingSwap 交易池中一笔价格冲击较大的 swap 交易
+type PendingSwap struct {
+	Hash   common.Hash    `json:"hash"`
+	From   common.Address `json:"from"`
+	Router common.Address `json:"router"`
+	Swaps  []SwapImpact   `json:"swaps"`
+}
+
+// SwapImpactEstimator 估算 Pending swap 的预期输出和价格冲击
+// 不在状态缓存中的池子每个区块只查询一次，新区块到来时失效
+type SwapImpactEstimator struct {
+	client *ethclient.Client
+	signer types.Signer
+	abis   *ABIRegistry
+	tokens *TokenCache
+	values *Valuator
+	cache  *PoolStateCache // 可为 nil
+
+	mu     sync.Mutex
+	states map[common.Address]*PoolState // nil 表示池子不存在或查询失败
+
+	onSwap func(PendingSwap)
+}
+
+// NewSwapImpactEstimator 创建估算器，onSwap 为 nil 时使用默认输出
+func NewSwapImpactEstimator(client *ethclient.Client, signer types.Signer, abis *ABIRegistry, tokens *TokenCache, values *Valuator, onSwap func(PendingSwap)) *SwapImpactEstimator {
+	if onSwap == nil {
+		onSwap = PrintPendingSwap
+	}
+	return &SwapImpactEstimator{
+		client: client,
+		signer: signer,
+		abis:   abis,
+		tokens: tokens,
+		values: values,
+		states: make(map[common.Address]*PoolState),
+		onSwap: onSwap,
+	}
+}
+
+// SetPoolCache 优先使用由日志维护的池子状态，缓存中的池子不再需要 eth_call
+func (e *SwapImpactEstimator) SetPoolCache(cache *PoolStateCache) {
+	e.cache = cache
+}
+
+// NotifyHead 新区块到来，缓存的池子状态失效
+func (e *SwapImpactEstimator) NotifyHead(*types.Header) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.states = make(map[common.Address]*PoolState)
+}
+
+// Observe 估算交易池中的 swap，价格冲击达到 SwapImpactAlertBps 时输出（只处理完整交易）
+func (e *SwapImpactEstimator) Observe(msg BusMessage) {
+	tx := msg.Tx
+	if tx == nil || tx.To() == nil || !uniswapRouters[*tx.To()] {
+		return
+	}
+	call, ok := e.abis.DecodeCall(tx.To(), tx.Data())
+	if !ok {
+		return
+	}
+	swaps := e.Estimate(tx.To(), tx.Value(), call)
+	alert := false
+	for _, s := range swaps {
+		alert = alert || s.ImpactBps >= SwapImpactAlertBps
+	}
+	if !alert {
+		return
+	}
+	from, err := types.Sender(e.signer, tx)
+	if err != nil {
+		return
+	}
+	e.onSwap(PendingSwap{Hash: tx.Hash(), From: from, Router: *tx.To(), Swaps: swaps})
+}
+
+// Estimate 计算已解码调用中每一笔 swap（包括 multicall 展开的内层调用）的预期结果
+// to 不是 Uniswap 路由、不是按输入数量成交的 swap，或池子状态查询失败时跳过；value 为交易附带的 ETH
+func (e *SwapImpactEstimator) Estimate(to *common.Address, value *big.Int, call *DecodedCall) []SwapImpact {
+	if to == nil || call == nil || !uniswapRouters[*to] {
+		return nil
+	}
+	var out []SwapImpact
+	var walk func(*DecodedCall)
+	walk = func(c *DecodedCall) {
+		if c.Target != nil {
+			return
+		}
+		if hops, amountIn, minOut, ok := swapIntent(c, value); ok {
+			if s, ok := e.simulate(c.Name, hops, amountIn, minOut); ok {
+				out = append(out, s)
+			}
+		}
+		for _, in := range c.Inner {
+			walk(in)
+		}
+	}
+	walk(call)
+	return out
+}
+
+// swapIntent 从路由调用中取出路径、输入数量和最少输出
+func swapIntent(c *DecodedCall, value *big.Int) (hops []swapHop, amountIn, minOut *big.Int, ok bool) {
+	switch c.Name {
+	case "swapExactTokensForTokens", "swapExactTokensForETH", "swapExactETHForTokens":
+		path, _ := c.Arg("path").([]common.Address)
+		for i := 0; i+1 < len(path); i++ {
+			hops = append(hops, swapHop{tokenIn: path[i], tokenOut: path[i+1]})
+		}
+		amountIn, _ = c.Arg("amountIn").(*big.Int)
+		if c.Name == "swapExactETHForTokens" {
+			amountIn = value
+		}
+		minOut, _ = c.Arg("amountOutMin").(*big.Int)
+	case "exactInputSingle":
+		tokenIn, _ := c.Arg("tokenIn").(common.Address)
+		tokenOut, _ := c.Arg("tokenOut").(common.Address)
+		if fee, ok := c.Arg("fee").(*big.Int); ok {
+			hops = []swapHop{{tokenIn: tokenIn, tokenOut: tokenOut, v3: true, fee: uint32(fee.Uint64())}}
+		}
+		amountIn, _ = c.Arg("amountIn").(*big.Int)
+		minOut, _ = c.Arg("amountOutMinimum").(*big.Int)
+	case "exactInput":
+		path, _ := c.Arg("path").([]byte)
+		hops = parseV3Path(path)
+		amountIn, _ = c.Arg("amountIn").(*big.Int)
+		minOut, _ = c.Arg("amountOutMinimum").(*big.Int)
+	}
+	// SwapRouter02 中 amountIn 为 0 表示使用路由合约中的余额（multicall 中前一步的输出），无法估算
+	ok = len(hops) > 0 && amountIn != nil && amountIn.Sign() > 0 && minOut != nil
+	return hops, amountIn, minOut, ok
+}
+
+// parseV3Path 解析 V3 的编码路径：token(20) | fee(3) | token(20) | fee(3) | ...
+func parseV3Path(path []byte) []swapHop {
+	if len(path) < 43 || (len(path)-20)%23 != 0 {
+		return nil
+	}
+	var hops []swapHop
+	for i := 0; i+43 <= len(path); i += 23 {
+		hops = append(hops, swapHop{
+			tokenIn:  common.BytesToAddress(path[i : i+20]),
+			tokenOut: common.BytesToAddress(path[i+23 : i+43]),
+			v3:       true,
+			fee:      uint32(path[i+20])<<16 | uint32(path[i+21])<<8 | uint32(path[i+22]),
+		})
+	}
+	return hops
+}
+
+// simulate 沿路径逐跳计算输出，价格冲击按每一跳"实际输出 / 按当前价格的理想输出"的乘积计算
+func (e *SwapImpactEstimator) simulate(function string, hops []swapHop, amountIn, minOut *big.Int) (SwapImpact, bool) {
+	s := SwapImpact{Function: function, AmountIn: amountIn, MinOut: minOut, Path: []common.Address{hops[0].tokenIn}}
+	amount, rate := amountIn, 1.0
+	for _, h := range hops {
+		pool := h.pool()
+		st, ok := e.poolState(pool, h.v3)
+		if !ok {
+			return s, false
+		}
+		var out, ideal *big.Int
+		if h.v3 {
+			out, ideal = uniV3AmountOut(amount, st.SqrtPriceX96, st.Liquidity, h.fee, h.zeroForOne())
+			s.Approx = true
+		} else if h.zeroForOne() {
+			out, ideal = uniV2AmountOut(amount, st.Reserve0, st.Reserve1)
+		} else {
+			out, ideal = uniV2AmountOut(amount, st.Reserve1, st.Reserve0)
+		}
+		if out.Sign() <= 0 {
+			return s, false
+		}
+		rate *= ratio(out, ideal)
+		amount = out
+		s.Path = append(s.Path, h.tokenOut)
+		s.Pools = append(s.Pools, pool)
+	}
+	s.ExpectedOut = amount
+	s.ImpactBps = max(0, (1-rate)*1e4)
+	s.SlippageBps = max(0, (1-ratio(minOut, amount))*1e4)
+	s.ValueUSD, s.Priced = e.values.TokenValue(s.Path[0], amountIn)
+	s.Desc = e.tokens.Meta(s.Path[0]).Format(amountIn) + " -> " + e.tokens.Meta(s.Path[len(s.Path)-1]).Format(amount)
+	return s, true
+}
+
+// poolState 池子的当前状态：配置了状态缓存且缓存中有该池子时直接使用，否则用 eth_call 查询，每个区块每个池子只查询一次
+func (e *SwapImpactEstimator) poolState(pool common.Address, v3 bool) (*PoolState, bool) {
+	if st, ok := e.cache.State(pool); ok {
+		return &st, true
+	}
+	e.mu.Lock()
+	st, ok := e.states[pool]
+	e.mu.Unlock()
+	if ok {
+		return st, st != nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), CONNECTION_TIMEOUT)
+	st, _ = queryPoolState(ctx, e.client, pool, v3, nil)
+	cancel()
+	e.mu.Lock()
+	e.states[pool] = st
+	e.mu.Unlock()
+	return st, st != nil
+}
+
+// uniV2AmountOut V2 恒定乘积的输出（与 Router 的 getAmountOut 相同，0.3% 手续费），
+// ideal 为扣除手续费后按当前价格 reserveOut / reserveIn 成交的输出
+func uniV2AmountOut(amountIn, reserveIn, reserveOut *big.Int) (out, ideal *big.Int) {
+	if reserveIn.Sign() == 0 || reserveOut.Sign() == 0 {
+		return new(big.Int), new(big.Int)
+	}
+	withFee := new(big.Int).Mul(amountIn, big.NewInt(1e6-UniV2FeePpm))
+	num := new(big.Int).Mul(withFee, reserveOut)
+	denom := new(big.Int).Mul(reserveIn, big.NewInt(1e6))
+	ideal = new(big.Int).Quo(num, denom)
+	out = num.Quo(num, denom.Add(denom, withFee))
+	return out, ideal
+}
+
+// uniV3AmountOut 假设整笔 swap 都在当前 tick 区间内（流动性不变）时 V3 池子的输出：
+//
+//	token0 换 token1：sqrtP' = L·sqrtP / (L + Δx·sqrtP)，Δy = L·(sqrtP - sqrtP')
+//	token1 换 token0：sqrtP' = sqrtP + Δy / L，Δx = L·(sqrtP' - sqrtP) / (sqrtP·sqrtP')
+//
+// 其中 sqrtP = sqrtPriceX96 / 2^96；ideal 为扣除手续费后按当前价格成交的输出
+func uniV3AmountOut(amountIn, sqrtPriceX96, liquidity *big.Int, feePpm uint32, zeroForOne bool) (out, ideal *big.Int) {
+	if sqrtPriceX96.Sign() == 0 || liquidity.Sign() == 0 {
+		return new(big.Int), new(big.Int)
+	}
+	in := new(big.Int).Mul(amountIn, big.NewInt(int64(1e6-feePpm)))
+	in.Quo(in, big.NewInt(1e6))
+	lx96 := new(big.Int).Lsh(liquidity, 96)
+	priceX192 := new(big.Int).Mul(sqrtPriceX96, sqrtPriceX96)
+	if zeroForOne {
+		// sqrtP' = L·2^96·sqrtPX96 / (L·2^96 + Δx·sqrtPX96)
+		denom := new(big.Int).Add(lx96, new(big.Int).Mul(in, sqrtPriceX96))
+		next := new(big.Int).Mul(lx96, sqrtPriceX96)
+		next.Quo(next, denom)
+		out = new(big.Int).Sub(sqrtPriceX96, next)
+		out.Mul(out, liquidity).Rsh(out, 96)
+		ideal = new(big.Int).Mul(in, priceX192)
+		ideal.Rsh(ideal, 192)
+		return out, ideal
+	}
+	// sqrtPX96' = sqrtPX96 + Δy·2^96 / L
+	next := new(big.Int).Lsh(in, 96)
+	next.Quo(next, liquidity).Add(next, sqrtPriceX96)
+	out = new(big.Int).Sub(next, sqrtPriceX96)
+	out.Mul(out, lx96).Quo(out, next).Quo(out, sqrtPriceX96)
+	ideal = new(big.Int).Lsh(in, 192)
+	ideal.Quo(ideal, priceX192)
+	return out, ideal
+}
+
+// uniV2PairAddress V2 交易对的 CREATE2 地址
+func uniV2PairAddress(a, b common.Address) common.Address {
+	t0, t1 := sortTokens(a, b)
+	salt := crypto.Keccak256(t0.Bytes(), t1.Bytes())
+	return crypto.CreateAddress2(uniV2Factory, [32]byte(salt), uniV2InitCodeHash)
+}
+
+// uniV3PoolAddress V3 池子的 CREATE2 地址，salt 为 abi.encode(token0, token1, fee)
+func uniV3PoolAddress(a, b common.Address, fee uint32) common.Address {
+	t0, t1 := sortTokens(a, b)
+	salt := crypto.Keccak256(
+		common.LeftPadBytes(t0.Bytes(), 32),
+		common.LeftPadBytes(t1.Bytes(), 32),
+		common.LeftPadBytes(big.NewInt(int64(fee)).Bytes(), 32),
+	)
+	return crypto.CreateAddress2(uniV3Factory, [32]byte(salt), uniV3InitCodeHash)
+}
+
+// sortTokens 按地址排序，小的是池子的 token0
+func sortTokens(a, b common.Address) (common.Address, common.Address) {
+	if bytes.Compare(a.Bytes(), b.Bytes()) > 0 {
+		return b, a
+	}
+	return a, b
+}
+
+// PrintPendingSwap 默认的 Pending swap 输出
+func PrintPendingSwap(p PendingSwap) {
+	var sb strings.Builder
+	worst := 0.0
+	for _, s := range p.Swaps {
+		worst = max(worst, s.ImpactBps)
+	}
+	summary := fmt.Sprintf("📉 [Pending Swap] %s 的 swap 价格冲击 %.2f%% | %s", p.From.Hex(), worst/100, p.Hash.Hex())
+	sb.WriteString("\n" + summary + "\n")
+	for _, s := range p.Swaps {
+		fmt.Fprintf(&sb, "   %s\n", s)
+	}
+	Emit(Event{Type: "pending_swap", Key: p.Hash.Hex(), Summary: summary, Text: sb.String(), Data: p})
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ------------------------------------------------
+// 提醒限流与合并：按事件类型配置规则，例如 node_alert 每 10 分钟最多输出一次，
+// 窗口内被压下的相同提醒在窗口结束时合并成一条带次数的 alert_throttled 事件
+// 限流状态保存在 SQLite 中，重启后窗口内的提醒不会全部重新触发
+// ------------------------------------------------
+
+const (
+	ThrottleByType = "type" // 同一类型的事件共用一个窗口
+	ThrottleByID   = "id"   // 内容完全相同（类型和 Data 相同，见 eventDigest）的事件共用一个窗口
+
+	// 检查窗口是否结束、输出合并事件的间隔
+	ThrottleFlushInterval = 10 * time.Second
+)
+
+// ThrottleRule 一个事件类型的限流规则
+type ThrottleRule struct {
+	Type            string `json:"type"`             // 事件类型，例如 node_alert
+	Key             string `json:"key"`              // type（默认）| id
+	IntervalSeconds int    `json:"interval_seconds"` // 窗口内最多输出一次
+}
+
+// ThrottleConfig 提醒限流
+type ThrottleConfig struct {
+	Rules []ThrottleRule `json:"rules"`
+	DB    string         `json:"db"` // 限流状态的 SQLite 文件，默认与索引共用
+}
+
+// ThrottleStatus /api/throttle 中一个窗口的状态
+type ThrottleStatus struct {
+	Key        string    `json:"key"`
+	LastFired  time.Time `json:"last_fired"`
+	Until      time.Time `json:"until"`
+	Suppressed int       `json:"suppressed"` // 窗口内被压下的次数
+}
+
+// ThrottleCollapse 窗口结束时合并的提醒
+type ThrottleCollapse struct {
+	Type       string    `json:"type"`
+	Key        string    `json:"key"`
+	Suppressed int       `json:"suppressed"`
+	Since      time.Time `json:"since"`
+	Last       string    `json:"last"` // 最后一条被压下的提醒摘要
+}
+
+// throttleState 一个窗口
+type throttleState struct {
+	typ         string
+	last        time.Time
+	interval    time.Duration
+	suppressed  int
+	lastSummary string
+}
+
+// alertThrottle 全局限流，未配置规则时为 nil（不限流）
+var (
+	alertThrottleMu sync.RWMutex
+	alertThrottle   *Throttle
+)
+
+// SetThrottle 设置全局限流，Emit 据此过滤事件
+func SetThrottle(t *Throttle) {
+	alertThrottleMu.Lock()
+	defer alertThrottleMu.Unlock()
+	alertThrottle = t
+}
+
+// throttleAllow 经过全局限流；持有读锁直到判断结束，SetThrottle(nil) 返回后不会再有写入旧限流状态库的调用
+// 合并事件在释放读锁之后输出：默认输出会再次调用 Emit，同一个 goroutine 重复加读锁在有写者等待时会死锁
+func throttleAllow(ev *Event) bool {
+	alertThrottleMu.RLock()
+	t := alertThrottle
+	allowed, collapse := t.Allow(ev)
+	alertThrottleMu.RUnlock()
+	if collapse != nil {
+		t.onCollapse(*collapse)
+	}
+	return allowed
+}
+
+// Throttle 按规则限流事件
+type Throttle struct {
+	store *ThrottleStore // 可以为 nil，此时重启后状态丢失
+
+	mu     sync.Mutex
+	rules  map[string]ThrottleRule // 可以热加载
+	states map[string]*throttleState
+
+	onCollapse func(ThrottleCollapse)
+}
+
+// NewThrottle 创建限流器并从 store 恢复未结束的窗口；onCollapse 为 nil 时使用默认输出
+func NewThrottle(cfg ThrottleConfig, store *ThrottleStore, onCollapse func(ThrottleCollapse)) (*Throttle, error) {
+	if onCollapse == nil {
+		onCollapse = PrintThrottleCollapse
+	}
+	t := &Throttle{
+		rules:      make(map[string]ThrottleRule, len(cfg.Rules)),
+		store:      store,
+		states:     make(map[string]*throttleState),
+		onCollapse: onCollapse,
+	}
+	for _, r := range cfg.Rules {
+		t.rules[r.Type] = r
+	}
+	if store == nil {
+		return t, nil
+	}
+	rows, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, row := range rows {
+		rule, ok := t.rules[row.typ]
+		if !ok {
+			// 规则已经删除
+			if err := store.Delete(row.key); err != nil {
+				log.Printf("⚠️  %v", err)
+			}
+			continue
+		}
+		st := &throttleState{
+			typ:         row.typ,
+			last:        row.last,
+			interval:    time.Duration(rule.IntervalSeconds) * time.Second,
+			suppressed:  row.suppressed,
+			lastSummary: row.lastSummary,
+		}
+		if now.Sub(st.last) < st.interval || st.suppressed > 0 {
+			t.states[row.key] = st
+		} else if err := store.Delete(row.key); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+	}
+	return t, nil
+}
+
+// Allow 事件是否输出；被限流时计入窗口并返回 false。没有 Time 的事件在这里补上
+// 窗口已经结束但还没来得及 flush 时一并返回上一个窗口的合并事件，由调用方在事件之前输出（不在这里调用 onCollapse）
+func (t *Throttle) Allow(ev *Event) (bool, *ThrottleCollapse) {
+	if t == nil {
+		return true, nil
+	}
+	rule, ok := t.rule(ev.Type)
+	if !ok {
+		return true, nil
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	key := ev.Type
+	if rule.Key == ThrottleByID {
+		key += "/" + eventDigest(*ev)
+	}
+	return t.allow(key, rule, ev)
+}
+
+// rule 事件类型对应的规则
+func (t *Throttle) rule(typ string) (ThrottleRule, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rule, ok := t.rules[typ]
+	return rule, ok
+}
+
+// SetRules 替换限流规则；进行中的窗口按新的间隔结束，删除了规则的类型等窗口结束后不再限流
+func (t *Throttle) SetRules(rules []ThrottleRule) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rules = make(map[string]ThrottleRule, len(rules))
+	for _, r := range rules {
+		t.rules[r.Type] = r
+	}
+	for _, st := range t.states {
+		if r, ok := t.rules[st.typ]; ok {
+			st.interval = time.Duration(r.IntervalSeconds) * time.Second
+		}
+	}
+}
+
+// allow 计入窗口，上一个窗口有未输出的合并时一并返回
+func (t *Throttle) allow(key string, rule ThrottleRule, ev *Event) (bool, *ThrottleCollapse) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st, ok := t.states[key]
+	if ok && ev.Time.Sub(st.last) < st.interval {
+		st.suppressed++
+		st.lastSummary = ev.summary()
+		t.save(key, st) // 重启后窗口结束时仍会输出合并事件
+		return false, nil
+	}
+	var collapse *ThrottleCollapse
+	if ok && st.suppressed > 0 {
+		collapse = &ThrottleCollapse{Type: st.typ, Key: key, Suppressed: st.suppressed, Since: st.last, Last: st.lastSummary}
+	}
+	st = &throttleState{typ: ev.Type, last: ev.Time, interval: time.Duration(rule.IntervalSeconds) * time.Second}
+	t.states[key] = st
+	t.save(key, st)
+	return true, collapse
+}
+
+// save 持久化窗口，失败只记录日志（调用方持有锁）
+func (t *Throttle) save(key string, st *throttleState) {
+	if t.store == nil {
+		return
+	}
+	if err := t.store.Save(key, st); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+}
+
+// Run 定期结束到期的窗口：有被压下的提醒时输出合并事件，没有时清理状态，直到 ctx 取消
+func (t *Throttle) Run(ctx context.Context) {
+	ticker := time.NewTicker(ThrottleFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			for _, c := range t.flush(now) {
+				t.onCollapse(c)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// flush 结束到期的窗口，返回需要输出的合并事件
+func (t *Throttle) flush(now time.Time) []ThrottleCollapse {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var out []ThrottleCollapse
+	for key, st := range t.states {
+		if now.Sub(st.last) < st.interval {
+			continue
+		}
+		if st.suppressed > 0 {
+			out = append(out, ThrottleCollapse{Type: st.typ, Key: key, Suppressed: st.suppressed, Since: st.last, Last: st.lastSummary})
+		}
+		delete(t.states, key)
+		if t.store != nil {
+			if err := t.store.Delete(key); err != nil {
+				log.Printf("⚠️  %v", err)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// Status 当前所有窗口
+func (t *Throttle) Status() []ThrottleStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]ThrottleStatus, 0, len(t.states))
+	for key, st := range t.states {
+		out = append(out, ThrottleStatus{Key: key, LastFired: st.last, Until: st.last.Add(st.interval), Suppressed: st.suppressed})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// RegisterAPI 注册限流状态查询接口
+//
+//	GET /api/throttle 进行中的限流窗口和被压下的次数
+func (t *Throttle) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/throttle", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, t.Status())
+	})
+}
+
+// PrintThrottleCollapse 默认的合并事件输出
+func PrintThrottleCollapse(c ThrottleCollapse) {
+	summary := fmt.Sprintf("🔕 [Throttle] %s 自 %s 起又出现 %d 次（已合并）| 最后一条: %s",
+		c.Type, c.Since.Format("15:04:05"), c.Suppressed, c.Last)
+	// 合并事件沿用原提醒的级别和分类，按同样的路由发送
+	Emit(Event{Type: "alert_throttled", Summary: summary, Text: summary, Data: c, Severity: severityOf(c.Type), Category: categoryOf(c.Type)})
+}
+
+const throttleSchema = `
+CREATE TABLE IF NOT EXISTS throttle (
+	key        TEXT PRIMARY KEY,
+	type       TEXT    NOT NULL,
+	last_fired INTEGER NOT NULL,
+	suppressed INTEGER NOT NULL,
+	last_summary TEXT NOT NULL
+);`
+
+// throttleRow 一个保存的窗口
+type throttleRow struct {
+	key, typ    string
+	last        time.Time
+	suppressed  int
+	lastSummary string
+}
+
+// ThrottleStore 限流状态的 SQLite 存储
+type ThrottleStore struct {
+	db *sql.DB
+}
+
+func OpenThrottleStore(path string) (*ThrottleStore, error) {
+	db, err := openSQLite(path, throttleSchema)
+	if err != nil {
+		return nil, err
+	}
+	return &ThrottleStore{db: db}, nil
+}
+
+// Load 读取全部窗口
+func (s *ThrottleStore) Load() ([]throttleRow, error) {
+	rows, err := s.db.Query(`SELECT key, type, last_fired, suppressed, last_summary FROM throttle`)
+	if err != nil {
+		return nil, fmt.Errorf("读取限流状态失败: %w", err)
+	}
+	defer rows.Close()
+	var out []throttleRow
+	for rows.Next() {
+		var r throttleRow
+		var last int64
+		if err := rows.Scan(&r.key, &r.typ, &last, &r.suppressed, &r.lastSummary); err != nil {
+			return nil, fmt.Errorf("读取限流状态失败: %w", err)
+		}
+		r.last = time.UnixMilli(last)
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// Save 更新一个窗口
+func (s *ThrottleStore) Save(key string, st *throttleState) error {
+	if _, err := s.db.Exec(`INSERT INTO throttle (key, type, last_fired, suppressed, last_summary) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET
+		last_fired = excluded.last_fired, suppressed = excluded.suppressed, last_summary = excluded.last_summary`,
+		key, st.typ, st.last.UnixMilli(), st.suppressed, st.lastSummary); err != nil {
+		return fmt.Errorf("保存限流状态失败: %w", err)
+	}
+	return nil
+}
+
+// Delete 删除已结束的窗口
+func (s *ThrottleStore) Delete(key string) error {
+	if _, err := s.db.Exec(`DELETE FROM throttle WHERE key = ?`, key); err != nil {
+		return fmt.Errorf("删除限流状态失败: %w", err)
+	}
+	return nil
+}
+
+func (s *ThrottleStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,471 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ------------------------------------------------
+// 时间锁队列监控：跟踪 OpenZeppelin TimelockController 的 CallScheduled 和 Compound Timelock 的 QueueTransaction，
+// 维护待执行操作的队列和 ETA，涉及关注合约的操作在执行前 N 小时倒计时提醒、到期可以执行时再提醒一次
+// ------------------------------------------------
+
+const (
+	// 启动时回扫多少个区块重建队列（约 1 周，时间锁的延迟通常是 2 - 7 天）
+	DefaultTimelockBackfillBlocks = 50_400
+)
+
+// DefaultTimelockAlertHours 默认在执行前 24 小时和 1 小时提醒
+var DefaultTimelockAlertHours = []float64{24, 1}
+
+// OZ getTimestamp(id) 的特殊返回值
+const (
+	timelockUnset = 0 // 不存在或已取消
+	timelockDone  = 1 // 已执行
+)
+
+const timelockABIJSON = `[
+	{"type":"event","name":"CallScheduled","inputs":[{"name":"id","type":"bytes32","indexed":true},{"name":"index","type":"uint256","indexed":true},{"name":"target","type":"address","indexed":false},{"name":"value","type":"uint256","indexed":false},{"name":"data","type":"bytes","indexed":false},{"name":"predecessor","type":"bytes32","indexed":false},{"name":"delay","type":"uint256","indexed":false}]},
+	{"type":"event","name":"CallExecuted","inputs":[{"name":"id","type":"bytes32","indexed":true},{"name":"index","type":"uint256","indexed":true},{"name":"target","type":"address","indexed":false},{"name":"value","type":"uint256","indexed":false},{"name":"data","type":"bytes","indexed":false}]},
+	{"type":"event","name":"Cancelled","inputs":[{"name":"id","type":"bytes32","indexed":true}]},
+	{"type":"event","name":"QueueTransaction","inputs":[{"name":"txHash","type":"bytes32","indexed":true},{"name":"target","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false},{"name":"signature","type":"string","indexed":false},{"name":"data","type":"bytes","indexed":false},{"name":"eta","type":"uint256","indexed":false}]},
+	{"type":"event","name":"ExecuteTransaction","inputs":[{"name":"txHash","type":"bytes32","indexed":true},{"name":"target","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false},{"name":"signature","type":"string","indexed":false},{"name":"data","type":"bytes","indexed":false},{"name":"eta","type":"uint256","indexed":false}]},
+	{"type":"event","name":"CancelTransaction","inputs":[{"name":"txHash","type":"bytes32","indexed":true},{"name":"target","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false},{"name":"signature","type":"string","indexed":false},{"name":"data","type":"bytes","indexed":false},{"name":"eta","type":"uint256","indexed":false}]},
+	{"type":"function","name":"getTimestamp","inputs":[{"name":"id","type":"bytes32"}],"outputs":[{"name":"","type":"uint256"}],"stateMutability":"view"},
+	{"type":"function","name":"queuedTransactions","inputs":[{"name":"txHash","type":"bytes32"}],"outputs":[{"name":"","type":"bool"}],"stateMutability":"view"}
+]`
+
+var timelockABI = mustParseABI(timelockABIJSON)
+
+// 时间锁提醒的类型
+const (
+	TimelockScheduled = "scheduled"
+	TimelockCountdown = "countdown"
+	TimelockReady     = "ready"
+	TimelockExecuted  = "executed"
+	TimelockCancelled = "cancelled"
+)
+
+// TimelockConfig 时间锁队列监控，timelocks 为空时不启动
+type TimelockConfig struct {
+	Timelocks []string `json:"timelocks"`
+	// 额外关注的目标合约；关注列表中的地址也算
+	Targets []string `json:"targets"`
+	// 执行前多少小时提醒，可以有多个
+	AlertHours     []float64 `json:"alert_hours"`
+	BackfillBlocks uint64    `json:"backfill_blocks"`
+}
+
+// TimelockCall 操作中的一个调用（OZ 的 scheduleBatch 有多个）
+type TimelockCall struct {
+	Target common.Address `json:"target"`
+	Value  *big.Int       `json:"value"`
+	Call   string         `json:"call"`
+}
+
+// TimelockOp 队列中的一个操作
+type TimelockOp struct {
+	Timelock common.Address   `json:"timelock"`
+	ID       common.Hash      `json:"id"` // OZ 的 operation id / Compound 的 txHash
+	Calls    []TimelockCall   `json:"calls"`
+	ETA      uint64           `json:"eta"` // 可以执行的时间戳
+	Block    uint64           `json:"block"`
+	TxHash   common.Hash      `json:"tx_hash"`
+	Watched  []common.Address `json:"watched,omitempty"`
+	compound bool
+	alerted  int  // 已经提醒过的倒计时档位数（AlertHours 从大到小）
+	ready    bool // 已经提醒过可以执行
+}
+
+// TimelockAlert 一次时间锁提醒
+type TimelockAlert struct {
+	Kind      string        `json:"kind"`
+	Block     uint64        `json:"block"`
+	Remaining time.Duration `json:"remaining,omitempty"` // 倒计时提醒时距离 ETA 的时间
+	Op        TimelockOp    `json:"op"`
+}
+
+// TimelockMonitor 维护时间锁的待执行队列
+type TimelockMonitor struct {
+	cfg       TimelockConfig
+	client    *ethclient.Client
+	abis      *ABIRegistry
+	watch     *Watchlist
+	timelocks map[common.Address]bool
+	targets   map[common.Address]bool
+	hours     []float64 // 从大到小
+
+	mu  sync.RWMutex
+	ops map[string]*TimelockOp // timelock/id
+
+	onAlert func(TimelockAlert)
+}
+
+// NewTimelockMonitor 创建时间锁监控，onAlert 为 nil 时使用默认输出
+func NewTimelockMonitor(cfg TimelockConfig, client *ethclient.Client, abis *ABIRegistry, watch *Watchlist, onAlert func(TimelockAlert)) *TimelockMonitor {
+	if onAlert == nil {
+		onAlert = PrintTimelockAlert
+	}
+	m := &TimelockMonitor{
+		cfg:       cfg,
+		client:    client,
+		abis:      abis,
+		watch:     watch,
+		timelocks: make(map[common.Address]bool),
+		targets:   make(map[common.Address]bool),
+		hours:     append([]float64(nil), cfg.AlertHours...),
+		ops:       make(map[string]*TimelockOp),
+		onAlert:   onAlert,
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(m.hours)))
+	for _, a := range cfg.Timelocks {
+		m.timelocks[common.HexToAddress(a)] = true
+	}
+	for _, a := range cfg.Targets {
+		m.targets[common.HexToAddress(a)] = true
+	}
+	return m
+}
+
+// Backfill 回扫最近 backfill_blocks 个区块的调度事件重建队列，再向合约确认每个操作是否仍在排队及其 ETA
+func (m *TimelockMonitor) Backfill(ctx context.Context) error {
+	head, err := blockNumberRetry(ctx, m.client)
+	if err != nil {
+		return fmt.Errorf("查询最新区块失败: %w", err)
+	}
+	from := uint64(0)
+	if head > m.cfg.BackfillBlocks {
+		from = head - m.cfg.BackfillBlocks
+	}
+	query := ethereum.FilterQuery{Topics: [][]common.Hash{{
+		timelockABI.Events["CallScheduled"].ID, timelockABI.Events["CallExecuted"].ID, timelockABI.Events["Cancelled"].ID,
+		timelockABI.Events["QueueTransaction"].ID, timelockABI.Events["ExecuteTransaction"].ID, timelockABI.Events["CancelTransaction"].ID,
+	}}}
+	for addr := range m.timelocks {
+		query.Addresses = append(query.Addresses, addr)
+	}
+	scanner := NewLogScanner(m.client, NewLogPipeline(func(l types.Log) { m.handleLog(&l, 0) }))
+	scanner.progress = false
+	if _, err := scanner.Scan(ctx, query, from, head); err != nil {
+		return err
+	}
+
+	// OZ 的 ETA 是调度区块的时间戳 + delay，回扫时直接问合约；同时排除已执行 / 已取消但事件不在回扫范围内的操作
+	m.mu.RLock()
+	ops := make([]*TimelockOp, 0, len(m.ops))
+	for _, op := range m.ops {
+		ops = append(ops, op)
+	}
+	m.mu.RUnlock()
+	block := new(big.Int).SetUint64(head)
+	for _, op := range ops {
+		var eta uint64
+		var queued bool
+		if op.compound {
+			out, err := m.call(ctx, op.Timelock, block, "queuedTransactions", op.ID)
+			if err != nil {
+				continue
+			}
+			queued, _ = out.(bool)
+			eta = op.ETA
+		} else {
+			out, err := m.call(ctx, op.Timelock, block, "getTimestamp", op.ID)
+			if err != nil {
+				continue
+			}
+			ts, _ := out.(*big.Int)
+			queued = ts != nil && ts.Uint64() > timelockDone
+			if queued {
+				eta = ts.Uint64()
+			}
+		}
+		m.mu.Lock()
+		if queued {
+			op.ETA = eta
+		} else {
+			delete(m.ops, opKey(op.Timelock, op.ID))
+		}
+		m.mu.Unlock()
+	}
+	m.mu.RLock()
+	log.Printf("⏳ 时间锁队列已重建: %d 个待执行操作（回扫区块 %d - %d）", len(m.ops), from, head)
+	m.mu.RUnlock()
+	return nil
+}
+
+// call 调用时间锁的只读函数，返回第一个返回值
+func (m *TimelockMonitor) call(ctx context.Context, timelock common.Address, block *big.Int, method string, args ...interface{}) (interface{}, error) {
+	input, err := timelockABI.Pack(method, args...)
+	if err != nil {
+		return nil, err
+	}
+	out, err := callContractRetry(ctx, m.client, ethereum.CallMsg{To: &timelock, Data: input}, block)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	values, err := timelockABI.Unpack(method, out)
+	if err != nil || len(values) == 0 {
+		return nil, fmt.Errorf("%s 返回数据格式错误", method)
+	}
+	return values[0], nil
+}
+
+// OnBlock 实现 BlockAnalyzer：先应用区块中的事件，再按区块时间检查倒计时
+func (m *TimelockMonitor) OnBlock(data *BlockData) {
+	now := data.Block.Time()
+	var scheduled []string
+	seen := make(map[string]bool)
+	for _, r := range data.Receipts {
+		if r.Status == 0 {
+			continue
+		}
+		for _, l := range r.Logs {
+			if !m.timelocks[l.Address] || len(l.Topics) < 2 {
+				continue
+			}
+			key, alert := m.handleLog(l, now)
+			if alert != nil {
+				m.onAlert(*alert)
+			}
+			if key != "" && !seen[key] {
+				seen[key] = true
+				scheduled = append(scheduled, key)
+			}
+		}
+	}
+	// scheduleBatch 的每个调用各有一条 CallScheduled，整个区块处理完再按操作提醒
+	for _, key := range scheduled {
+		m.mu.RLock()
+		op := m.ops[key]
+		var snap TimelockOp
+		if op != nil {
+			snap = op.snapshot()
+		}
+		m.mu.RUnlock()
+		if op != nil && len(snap.Watched) > 0 {
+			m.onAlert(TimelockAlert{Kind: TimelockScheduled, Block: snap.Block, Op: snap})
+		}
+	}
+	m.countdown(data.Block.NumberU64(), now)
+}
+
+// handleLog 处理时间锁的一条日志，返回新调度的操作，或执行 / 取消涉及关注合约的操作时的提醒
+// now 为区块时间戳（回扫时为 0，ETA 之后向合约查询）
+func (m *TimelockMonitor) handleLog(l *types.Log, now uint64) (string, *TimelockAlert) {
+	ev, err := timelockABI.EventByID(l.Topics[0])
+	if err != nil || len(l.Topics) < 2 {
+		return "", nil
+	}
+	values, err := timelockABI.Unpack(ev.Name, l.Data)
+	if err != nil {
+		return "", nil
+	}
+	id := l.Topics[1]
+	key := opKey(l.Address, id)
+
+	switch ev.Name {
+	case "CallScheduled", "QueueTransaction":
+		var call TimelockCall
+		var targets []common.Address
+		var eta uint64
+		if ev.Name == "CallScheduled" {
+			target, _ := values[0].(common.Address)
+			value, _ := values[1].(*big.Int)
+			data, _ := values[2].([]byte)
+			delay, _ := values[4].(*big.Int)
+			call, targets = m.decode(target, value, "", data)
+			if now > 0 && delay != nil {
+				eta = now + delay.Uint64()
+			}
+		} else {
+			if len(l.Topics) < 3 {
+				return "", nil
+			}
+			value, _ := values[0].(*big.Int)
+			signature, _ := values[1].(string)
+			data, _ := values[2].([]byte)
+			e, _ := values[3].(*big.Int)
+			call, targets = m.decode(common.BytesToAddress(l.Topics[2].Bytes()), value, signature, data)
+			if e != nil {
+				eta = e.Uint64()
+			}
+		}
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		op := m.ops[key]
+		if op == nil {
+			op = &TimelockOp{Timelock: l.Address, ID: id, ETA: eta, Block: l.BlockNumber, TxHash: l.TxHash, compound: ev.Name == "QueueTransaction"}
+			m.ops[key] = op
+		}
+		op.Calls = append(op.Calls, call)
+		m.markWatched(op, targets)
+		return key, nil
+	case "CallExecuted", "ExecuteTransaction", "Cancelled", "CancelTransaction":
+		// OZ 批量执行每个调用一条 CallExecuted，只在第一条时移出队列
+		m.mu.Lock()
+		op := m.ops[key]
+		delete(m.ops, key)
+		m.mu.Unlock()
+		if op == nil || len(op.Watched) == 0 {
+			return "", nil
+		}
+		kind := TimelockExecuted
+		if ev.Name == "Cancelled" || ev.Name == "CancelTransaction" {
+			kind = TimelockCancelled
+		}
+		return "", &TimelockAlert{Kind: kind, Block: l.BlockNumber, Op: op.snapshot()}
+	}
+	return "", nil
+}
+
+// decode 解码一个调用，Compound 风格的 signature 需要补上 selector；同时返回调用目标和内层调用的目标
+func (m *TimelockMonitor) decode(target common.Address, value *big.Int, signature string, data []byte) (TimelockCall, []common.Address) {
+	if value == nil {
+		value = new(big.Int)
+	}
+	c := TimelockCall{Target: target, Value: value}
+	targets := []common.Address{target}
+	if signature != "" {
+		data = append(crypto.Keccak256([]byte(signature))[:4], data...)
+	}
+	if call, ok := m.abis.DecodeCall(&target, data); ok {
+		c.Call = call.String()
+		targets = append(targets, call.Targets()...)
+	} else if len(data) == 0 {
+		c.Call = "(转账)"
+	}
+	return c, targets
+}
+
+// markWatched 标记操作涉及的关注合约（调用方持有锁）
+func (m *TimelockMonitor) markWatched(op *TimelockOp, targets []common.Address) {
+	for _, addr := range targets {
+		if !m.targets[addr] && !m.watch.Contains(addr) {
+			continue
+		}
+		dup := false
+		for _, w := range op.Watched {
+			dup = dup || w == addr
+		}
+		if !dup {
+			op.Watched = append(op.Watched, addr)
+		}
+	}
+}
+
+// countdown 涉及关注合约的操作：跨过某个提醒档位时提醒（同时跨过多个档位只提醒最近的一个），到期时提醒可以执行
+func (m *TimelockMonitor) countdown(block, now uint64) {
+	var alerts []TimelockAlert
+	m.mu.Lock()
+	for _, op := range m.ops {
+		if len(op.Watched) == 0 || op.ETA == 0 || op.ready {
+			continue
+		}
+		if now >= op.ETA {
+			op.ready = true
+			alerts = append(alerts, TimelockAlert{Kind: TimelockReady, Block: block, Op: op.snapshot()})
+			continue
+		}
+		remaining := time.Duration(op.ETA-now) * time.Second
+		crossed := op.alerted
+		for crossed < len(m.hours) && remaining <= time.Duration(m.hours[crossed]*float64(time.Hour)) {
+			crossed++
+		}
+		if crossed > op.alerted {
+			op.alerted = crossed
+			alerts = append(alerts, TimelockAlert{Kind: TimelockCountdown, Block: block, Remaining: remaining, Op: op.snapshot()})
+		}
+	}
+	m.mu.Unlock()
+	for _, a := range alerts {
+		m.onAlert(a)
+	}
+}
+
+// snapshot 复制操作（调用方持有锁）
+func (op *TimelockOp) snapshot() TimelockOp {
+	c := *op
+	c.Calls = append([]TimelockCall(nil), op.Calls...)
+	c.Watched = append([]common.Address(nil), op.Watched...)
+	return c
+}
+
+func opKey(timelock common.Address, id common.Hash) string {
+	return timelock.Hex() + "/" + id.Hex()
+}
+
+// Queue 待执行的操作，按 ETA 排序
+func (m *TimelockMonitor) Queue() []TimelockOp {
+	m.mu.RLock()
+	out := make([]TimelockOp, 0, len(m.ops))
+	for _, op := range m.ops {
+		out = append(out, op.snapshot())
+	}
+	m.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].ETA < out[j].ETA })
+	return out
+}
+
+// RegisterAPI 注册时间锁队列接口
+//
+//	GET /api/timelock 所有待执行的操作（按 ETA 排序）
+func (m *TimelockMonitor) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/timelock", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, m.Queue())
+	})
+}
+
+// PrintTimelockAlert 默认的时间锁提醒
+func PrintTimelockAlert(a TimelockAlert) {
+	op := a.Op
+	var what string
+	switch a.Kind {
+	case TimelockScheduled:
+		what = fmt.Sprintf("新操作排队，ETA %s", time.Unix(int64(op.ETA), 0).Format("2006-01-02 15:04:05"))
+		if op.ETA == 0 {
+			what = "新操作排队"
+		}
+	case TimelockCountdown:
+		what = fmt.Sprintf("⚠️ %s 后可以执行", a.Remaining.Round(time.Minute))
+	case TimelockReady:
+		what = "⚠️ 现在可以执行"
+	case TimelockExecuted:
+		what = "已执行"
+	case TimelockCancelled:
+		what = "已取消"
+	}
+	watched := make([]string, len(op.Watched))
+	for i, w := range op.Watched {
+		watched[i] = w.Hex()
+	}
+	summary := fmt.Sprintf("⏳ [Timelock] 区块 %d | %s 操作 %s | %s | 涉及 %s",
+		a.Block, op.Timelock.Hex(), shortHash(op.ID), what, strings.Join(watched, ", "))
+	var sb strings.Builder
+	sb.WriteString(summary)
+	if a.Kind != TimelockExecuted && a.Kind != TimelockCancelled {
+		for i, c := range op.Calls {
+			fmt.Fprintf(&sb, "\n   #%d -> %s", i, c.Target.Hex())
+			if c.Value.Sign() > 0 {
+				fmt.Fprintf(&sb, " (%s ETH)", formatUnits(c.Value, 18))
+			}
+			if c.Call != "" {
+				sb.WriteString(" " + c.Call)
+			}
+		}
+	}
+	Emit(Event{Type: "timelock", Key: eventKey(a.Op.Timelock, a.Op.ID, a.Kind, a.Block), Summary: summary, Text: sb.String(), Data: a})
+}
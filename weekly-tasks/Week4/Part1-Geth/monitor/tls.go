@@ -0,0 +1,119 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// ------------------------------------------------
+// TLS：API 和指标服务直接用 HTTPS 监听，简单部署不需要额外的反向代理；
+// 配置 client_ca 后要求客户端证书（mTLS），只有持有该 CA 签发证书的调用方才能建立连接
+// 证书文件更新（例如自动续期）后不需要重启，下一次握手时重新加载
+// ------------------------------------------------
+
+const (
+	// 检查证书文件是否更新的最短间隔
+	TLSReloadInterval = time.Minute
+)
+
+// TLSConfig 内置 HTTP 服务的 TLS，cert_file 为空时使用明文 HTTP
+type TLSConfig struct {
+	CertFile string `json:"cert_file"` // PEM 证书（可以包含中间证书）
+	KeyFile  string `json:"key_file"`  // PEM 私钥
+	ClientCA string `json:"client_ca"` // 校验客户端证书的 CA，留空不要求客户端证书
+}
+
+// Enabled 是否启用 TLS
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != ""
+}
+
+// validate 证书和私钥需要同时配置
+func (c TLSConfig) validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return fmt.Errorf("tls.cert_file 和 tls.key_file 需要同时配置")
+	}
+	if c.ClientCA != "" && c.CertFile == "" {
+		return fmt.Errorf("tls.client_ca 需要同时配置 cert_file 和 key_file")
+	}
+	return nil
+}
+
+// certReloader 按文件修改时间重新加载证书
+type certReloader struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+// load 读取证书和私钥
+func (r *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("加载 TLS 证书失败: %w", err)
+	}
+	info, err := os.Stat(r.certFile)
+	if err != nil {
+		return fmt.Errorf("加载 TLS 证书失败: %w", err)
+	}
+	r.cert, r.modTime = &cert, info.ModTime()
+	return nil
+}
+
+// GetCertificate 握手时调用；证书文件变化时重新加载，加载失败继续使用原来的证书
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.checked) >= TLSReloadInterval {
+		r.checked = time.Now()
+		if info, err := os.Stat(r.certFile); err == nil && !info.ModTime().Equal(r.modTime) {
+			if err := r.load(); err != nil {
+				log.Printf("⚠️  %v，继续使用原来的证书", err)
+			} else {
+				log.Printf("🔐 TLS 证书已重新加载: %s", r.certFile)
+			}
+		}
+	}
+	return r.cert, nil
+}
+
+// NewServerTLS 按配置创建 *tls.Config，未启用时返回 nil；启动时加载失败直接返回错误
+func NewServerTLS(cfg TLSConfig) (*tls.Config, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+	r := &certReloader{certFile: cfg.CertFile, keyFile: cfg.KeyFile, checked: time.Now()}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	t := &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: r.GetCertificate}
+	if cfg.ClientCA != "" {
+		pem, err := os.ReadFile(cfg.ClientCA)
+		if err != nil {
+			return nil, fmt.Errorf("读取 tls.client_ca 失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls.client_ca 中没有有效的 PEM 证书: %s", cfg.ClientCA)
+		}
+		t.ClientCAs = pool
+		t.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return t, nil
+}
+
+// serverScheme 日志中显示的协议
+func serverScheme(t *tls.Config) string {
+	if t == nil {
+		return "http"
+	}
+	return "https"
+}
@@ -0,0 +1,83 @@
+package main
+
+// ------------------------------------------------
+// 交易池接收方过滤：只保留 / 丢弃发往指定地址（路由合约、关注的合约）的 Pending 交易，
+// 在解码、估算价格冲击等耗时的处理之前检查，只需要一次 map 查找
+// ------------------------------------------------
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ToFilterConfig 按接收方过滤交易池输出；include 为空且 watched 为 false 时不限制接收方
+type ToFilterConfig struct {
+	Include []string `json:"include"` // 只保留发往这些地址的交易
+	Exclude []string `json:"exclude"` // 丢弃发往这些地址的交易，优先于 include
+	Watched bool     `json:"watched"` // 发往关注列表中地址的交易也保留（与 include 合并）
+}
+
+// Enabled 是否配置了任何过滤条件
+func (c ToFilterConfig) Enabled() bool {
+	return len(c.Include) > 0 || len(c.Exclude) > 0 || c.Watched
+}
+
+// validate 检查地址格式
+func (c ToFilterConfig) validate() error {
+	for _, list := range [][]string{c.Include, c.Exclude} {
+		for _, a := range list {
+			if !common.IsHexAddress(a) {
+				return fmt.Errorf("地址格式错误: %q", a)
+			}
+		}
+	}
+	return nil
+}
+
+// ToFilter 按接收方过滤 Pending 交易
+type ToFilter struct {
+	include  map[common.Address]bool
+	exclude  map[common.Address]bool
+	watch    *Watchlist // watched 为 false 时为 nil
+	restrict bool       // 是否只保留 include / 关注地址
+}
+
+// NewToFilter 创建接收方过滤器
+func NewToFilter(cfg ToFilterConfig, watch *Watchlist) *ToFilter {
+	f := &ToFilter{
+		include:  make(map[common.Address]bool, len(cfg.Include)),
+		exclude:  make(map[common.Address]bool, len(cfg.Exclude)),
+		restrict: len(cfg.Include) > 0 || cfg.Watched,
+	}
+	for _, a := range cfg.Include {
+		f.include[common.HexToAddress(a)] = true
+	}
+	for _, a := range cfg.Exclude {
+		f.exclude[common.HexToAddress(a)] = true
+	}
+	if cfg.Watched {
+		f.watch = watch
+	}
+	return f
+}
+
+// Match 交易的接收方是否满足条件；创建合约的交易没有接收方，只在不限制接收方时保留
+func (f *ToFilter) Match(tx *types.Transaction) bool {
+	return f.MatchAddress(tx.To())
+}
+
+// MatchAddress 地址是否满足条件，过滤链中的 from 条件也使用它
+func (f *ToFilter) MatchAddress(to *common.Address) bool {
+	if to == nil {
+		return !f.restrict
+	}
+	if f.exclude[*to] {
+		return false
+	}
+	if !f.restrict || f.include[*to] {
+		return true
+	}
+	return f.watch != nil && f.watch.Contains(*to)
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// 拉取元数据失败（不是合约、节点出错）后，这段时间内不再重试，期间 Lookup 未命中、Get 返回上次的错误
+const TokenFailureTTL = 5 * time.Minute
+
+var (
+	selectorName     = common.FromHex("0x06fdde03") // name()
+	selectorSymbol   = common.FromHex("0x95d89b41") // symbol()
+	selectorDecimals = common.FromHex("0x313ce567") // decimals()
+
+	abiString, _ = abi.NewType("string", "", nil)
+)
+
+// TokenMeta ERC-20 元数据
+type TokenMeta struct {
+	Address  common.Address `json:"address"`
+	Name     string         `json:"name"`
+	Symbol   string         `json:"symbol"`
+	Decimals uint8          `json:"decimals"`
+	// 合约没有 decimals()（或调用失败）时为 false，金额按原始单位显示
+	HasDecimals bool `json:"has_decimals"`
+}
+
+// Label 用于展示的代币名：优先 symbol，没有时用缩短的地址
+func (m TokenMeta) Label() string {
+	if m.Symbol != "" {
+		return m.Symbol
+	}
+	hex := m.Address.Hex()
+	return hex[:6] + "…" + hex[len(hex)-4:]
+}
+
+// Format 按 decimals 缩放金额并带上代币名，例如 "1234.5 USDC"
+func (m TokenMeta) Format(amount *big.Int) string {
+	if !m.HasDecimals {
+		return amount.String() + " " + m.Label() + "(raw)"
+	}
+	return formatUnits(amount, m.Decimals) + " " + m.Label()
+}
+
+// TokenCache 按需拉取 ERC-20 元数据并持久化到 JSON 文件，重启后不再重复请求
+type TokenCache struct {
+	client *ethclient.Client
+	pool   *EndpointPool // 未配置备用节点时为 nil
+	path   string        // 为空时只缓存在内存中
+
+	mu       sync.Mutex
+	tokens   map[common.Address]TokenMeta
+	inflight map[common.Address]bool
+	failed   map[common.Address]tokenFailure // 只在内存中，不写入缓存文件
+}
+
+// tokenFailure 缓存的拉取失败，和 PriceOracle 没有报价时一样缓存一段时间，避免反复查询
+type tokenFailure struct {
+	err error
+	at  time.Time
+}
+
+// recentFailure 上次拉取失败且还在 TokenFailureTTL 内时返回当时的错误（调用方持有锁）
+func (c *TokenCache) recentFailure(addr common.Address) error {
+	if f, ok := c.failed[addr]; ok && time.Since(f.at) < TokenFailureTTL {
+		return f.err
+	}
+	return nil
+}
+
+// NewTokenCache 创建元数据缓存并加载已有的缓存文件
+func NewTokenCache(client *ethclient.Client, path string) *TokenCache {
+	c := &TokenCache{
+		client:   client,
+		path:     path,
+		tokens:   make(map[common.Address]TokenMeta),
+		inflight: make(map[common.Address]bool),
+		failed:   make(map[common.Address]tokenFailure),
+	}
+	if path == "" {
+		return c
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("⚠️  读取代币缓存失败: %v", err)
+		}
+		return c
+	}
+	var list []TokenMeta
+	if err := json.Unmarshal(data, &list); err != nil {
+		log.Printf("⚠️  代币缓存无法解析，已忽略: %v", err)
+		return c
+	}
+	for _, m := range list {
+		c.tokens[m.Address] = m
+	}
+	return c
+}
+
+// SetEndpoints 元数据查询改由 pool 选择节点
+func (c *TokenCache) SetEndpoints(pool *EndpointPool) {
+	c.pool = pool
+}
+
+// Lookup 不阻塞地查询缓存；未命中时在后台拉取，本次返回 false，适合在主循环里调用
+// 最近拉取失败过的地址在 TokenFailureTTL 内不再拉取；c 为 nil 时总是未命中
+func (c *TokenCache) Lookup(addr common.Address) (TokenMeta, bool) {
+	if c == nil {
+		return TokenMeta{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if m, ok := c.tokens[addr]; ok {
+		return m, true
+	}
+	if !c.inflight[addr] && c.recentFailure(addr) == nil {
+		c.inflight[addr] = true
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), CONNECTION_TIMEOUT)
+			defer cancel()
+			if _, err := c.Get(ctx, addr); err != nil {
+				log.Printf("⚠️  获取代币 %s 的元数据失败: %v", addr.Hex(), err)
+			}
+		}()
+	}
+	return TokenMeta{}, false
+}
+
+// Meta 用于显示的元数据，同 Lookup 不阻塞：未命中时只带地址（Label 显示为缩短的地址）；
+// 原生 ETH 的占位地址（0xEeee…）直接按 ETH 显示
+func (c *TokenCache) Meta(addr common.Address) TokenMeta {
+	if addr == curveETH {
+		return TokenMeta{Address: addr, Symbol: "ETH", Decimals: 18, HasDecimals: true}
+	}
+	meta, _ := c.Lookup(addr)
+	meta.Address = addr
+	return meta
+}
+
+// Get 查询元数据，未命中时同步拉取并写入缓存；最近拉取失败过的地址在 TokenFailureTTL 内直接返回上次的错误
+func (c *TokenCache) Get(ctx context.Context, addr common.Address) (TokenMeta, error) {
+	c.mu.Lock()
+	if m, ok := c.tokens[addr]; ok {
+		c.mu.Unlock()
+		return m, nil
+	}
+	if err := c.recentFailure(addr); err != nil {
+		c.mu.Unlock()
+		return TokenMeta{Address: addr}, err
+	}
+	c.mu.Unlock()
+
+	m, err := c.fetch(ctx, addr)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.inflight, addr)
+	if err != nil {
+		c.failed[addr] = tokenFailure{err: err, at: time.Now()}
+		return m, err
+	}
+	delete(c.failed, addr)
+	c.tokens[addr] = m
+	c.save()
+	return m, nil
+}
+
+// fetch 分别调用 name() / symbol() / decimals()，单个函数缺失不影响其它字段
+func (c *TokenCache) fetch(ctx context.Context, addr common.Address) (TokenMeta, error) {
+	m := TokenMeta{Address: addr}
+	call := func(data []byte) (out []byte, err error) {
+		err = poolCall(ctx, c.pool, c.client, "eth_call", func(client *ethclient.Client) error {
+			out, err = client.CallContract(ctx, ethereum.CallMsg{To: &addr, Data: data}, nil)
+			return err
+		})
+		return out, err
+	}
+
+	var code []byte
+	err := poolCall(ctx, c.pool, c.client, "eth_getCode", func(client *ethclient.Client) (err error) {
+		code, err = client.CodeAt(ctx, addr, nil)
+		return err
+	})
+	if err != nil {
+		return m, fmt.Errorf("查询合约代码失败: %w", err)
+	}
+	if len(code) == 0 {
+		return m, fmt.Errorf("%s 不是合约", addr.Hex())
+	}
+	if out, err := call(selectorName); err == nil {
+		m.Name = decodeStringOrBytes32(out)
+	}
+	if out, err := call(selectorSymbol); err == nil {
+		m.Symbol = decodeStringOrBytes32(out)
+	}
+	if out, err := call(selectorDecimals); err == nil && len(out) >= 32 {
+		// 个别合约返回 uint256，超过 255 的当作非标准处理
+		if d := new(big.Int).SetBytes(out[:32]); d.IsUint64() && d.Uint64() <= 255 {
+			m.Decimals = uint8(d.Uint64())
+			m.HasDecimals = true
+		}
+	}
+	return m, nil
+}
+
+// decodeStringOrBytes32 解码 string 返回值，兼容 MKR 等老合约返回 bytes32 的写法
+func decodeStringOrBytes32(out []byte) string {
+	if len(out) > 32 {
+		if vals, err := (abi.Arguments{{Type: abiString}}).Unpack(out); err == nil {
+			return sanitizeTokenString(vals[0].(string))
+		}
+	}
+	if len(out) == 32 {
+		return sanitizeTokenString(string(bytes.TrimRight(out, "\x00")))
+	}
+	return ""
+}
+
+// sanitizeTokenString 去掉不可打印字符，防止恶意代币名破坏终端输出
+func sanitizeTokenString(s string) string {
+	if !utf8.ValidString(s) {
+		return ""
+	}
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, s)
+	if r := []rune(s); len(r) > 64 {
+		s = string(r[:64])
+	}
+	return strings.TrimSpace(s)
+}
+
+// save 把缓存写回文件：先写临时文件再重命名，避免中途退出留下半个文件（调用方持有锁）
+func (c *TokenCache) save() {
+	if c.path == "" {
+		return
+	}
+	list := make([]TokenMeta, 0, len(c.tokens))
+	for _, m := range c.tokens {
+		list = append(list, m)
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		log.Printf("⚠️  写入代币缓存失败: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		log.Printf("⚠️  写入代币缓存失败: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// 每次 trace_filter 覆盖的区块数，区间太大节点容易超时
+	TraceScanChunk = 1000
+	// 每页返回的 trace 条数（after/count 分页）
+	TraceScanPageSize = 500
+)
+
+// InternalTransfer 合约内部发生的 ETH 转账（普通交易的 value 已经能从区块里看到，这里只关心内部调用）
+type InternalTransfer struct {
+	BlockNumber  uint64
+	TxHash       common.Hash
+	TraceAddress []int
+	From         common.Address
+	To           common.Address
+	Value        *big.Int
+	Type         string
+}
+
+// TraceScanner 用 trace_filter 扫描历史区块，补齐实时订阅没有看到的、涉及关注地址的内部转账
+// 需要连接支持 trace_filter 的归档节点（Erigon / Nethermind 等）
+type TraceScanner struct {
+	tracer     Tracer
+	addrs      []common.Address
+	onTransfer func(InternalTransfer)
+}
+
+// NewTraceScanner 创建扫描器，onTransfer 为 nil 时直接打印
+func NewTraceScanner(tracer Tracer, addrs []common.Address, onTransfer func(InternalTransfer)) *TraceScanner {
+	if onTransfer == nil {
+		onTransfer = PrintInternalTransfer
+	}
+	return &TraceScanner{tracer: tracer, addrs: addrs, onTransfer: onTransfer}
+}
+
+// Scan 扫描 [from, to] 区间，返回找到的内部转账数量
+func (s *TraceScanner) Scan(ctx context.Context, from, to uint64) (int, error) {
+	if len(s.addrs) == 0 {
+		return 0, fmt.Errorf("没有需要扫描的地址（请在 watch 中配置）")
+	}
+	found := 0
+	for start := from; start <= to; start += TraceScanChunk {
+		end := min(start+TraceScanChunk-1, to)
+		// fromAddress 与 toAddress 之间是"或"关系，一次查询同时覆盖转入和转出
+		q := TraceFilterQuery{
+			FromBlock:   start,
+			ToBlock:     end,
+			FromAddress: s.addrs,
+			ToAddress:   s.addrs,
+			Count:       TraceScanPageSize,
+		}
+		for {
+			if err := ctx.Err(); err != nil {
+				return found, err
+			}
+			calls, raw, err := s.tracer.TraceFilter(ctx, q)
+			if err != nil {
+				return found, fmt.Errorf("扫描区块 %d - %d 失败: %w", start, end, err)
+			}
+			for _, c := range calls {
+				if !c.IsInternal() || c.Value.Sign() == 0 || c.Error != "" {
+					continue
+				}
+				s.onTransfer(InternalTransfer{
+					BlockNumber:  c.BlockNumber,
+					TxHash:       c.TxHash,
+					TraceAddress: c.TraceAddress,
+					From:         c.From,
+					To:           c.To,
+					Value:        c.Value,
+					Type:         c.Type,
+				})
+				found++
+			}
+			// 按节点返回的原始条数判断是否还有下一页：出块奖励等记录已经被过滤掉，满页看起来也可能不满
+			if uint64(raw) < q.Count {
+				break
+			}
+			q.After += q.Count
+		}
+		log.Printf("🔍 已扫描区块 %d - %d（累计 %d 笔内部转账）", start, end, found)
+	}
+	return found, nil
+}
+
+// PrintInternalTransfer 打印一笔内部转账
+func PrintInternalTransfer(t InternalTransfer) {
+	Emit(Event{Type: "internal_transfer", Key: eventKey(t.TxHash, t.TraceAddress), Data: t, Text: fmt.Sprintf("💸 [Internal Transfer] 区块 %d | %s %v | %s -> %s | %.6f ETH (%s)",
+		t.BlockNumber, t.TxHash.Hex(), t.TraceAddress, t.From.Hex(), t.To.Hex(), weiToEther(t.Value), t.Type)})
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ------------------------------------------------
+// 统一的 trace 接口：分析器只面对 InternalCall，不关心连的是哪种节点
+//   - parityTracer: Erigon / Nethermind / Besu / Reth 的 trace_*
+//   - gethTracer:   Geth 的 debug_trace* + callTracer（不支持 trace_filter）
+// ------------------------------------------------
+
+// ErrTraceNotSupported 当前节点不支持该 trace 接口
+var ErrTraceNotSupported = errors.New("当前节点不支持该 trace 接口")
+
+// InternalCall 调用树中的一次调用（已展平）
+type InternalCall struct {
+	BlockNumber  uint64
+	TxHash       common.Hash
+	TxIndex      int
+	TraceAddress []int  // 在调用树中的位置，空表示交易的顶层调用
+	Type         string // call / delegatecall / staticcall / callcode / create / create2 / selfdestruct
+	From         common.Address
+	To           common.Address // create 时为新合约地址，selfdestruct 时为退款地址
+	Value        *big.Int
+	Input        []byte
+	GasUsed      uint64
+	Error        string
+}
+
+// IsInternal 是否为合约内部发起的调用（非交易顶层调用）
+func (c *InternalCall) IsInternal() bool { return len(c.TraceAddress) > 0 }
+
+// TraceFilterQuery trace_filter 的查询条件，From/To 地址之间是"或"关系
+type TraceFilterQuery struct {
+	FromBlock, ToBlock uint64
+	FromAddress        []common.Address
+	ToAddress          []common.Address
+	After              uint64 // 分页偏移
+	Count              uint64 // 分页大小，0 表示不限制
+}
+
+// Tracer 统一的 trace 接口
+type Tracer interface {
+	Name() string
+	TraceTransaction(ctx context.Context, hash common.Hash) ([]InternalCall, error)
+	TraceBlock(ctx context.Context, number uint64) ([]InternalCall, error)
+	// TraceFilter 需要归档节点，不支持时返回 ErrTraceNotSupported
+	// 返回的调用去掉了出块奖励等不属于交易的记录，raw 为节点返回的原始条数，分页按它判断是否还有下一页
+	TraceFilter(ctx context.Context, q TraceFilterQuery) (calls []InternalCall, raw int, err error)
+}
+
+// NewTracer 根据节点能力选择实现，节点不支持任何 trace 接口时返回 nil
+func NewTracer(info *NodeInfo, client *rpc.Client) Tracer {
+	switch {
+	case info.ParityTrace:
+		return &parityTracer{client: client, name: string(info.Kind)}
+	case info.DebugTrace:
+		return &gethTracer{client: client}
+	default:
+		return nil
+	}
+}
+
+// ------------------------------------------------
+// Parity 风格 trace_*
+// ------------------------------------------------
+
+type parityTracer struct {
+	client *rpc.Client
+	name   string
+}
+
+// parityTrace trace_* 返回的单条记录
+type parityTrace struct {
+	Action struct {
+		CallType      string          `json:"callType"`
+		From          *common.Address `json:"from"`
+		To            *common.Address `json:"to"`
+		Value         *hexutil.Big    `json:"value"`
+		Input         hexutil.Bytes   `json:"input"`
+		Init          hexutil.Bytes   `json:"init"`
+		Address       *common.Address `json:"address"`       // selfdestruct
+		RefundAddress *common.Address `json:"refundAddress"` // selfdestruct
+		Balance       *hexutil.Big    `json:"balance"`       // selfdestruct
+	} `json:"action"`
+	Result *struct {
+		GasUsed hexutil.Uint64  `json:"gasUsed"`
+		Address *common.Address `json:"address"` // create
+	} `json:"result"`
+	BlockNumber         uint64       `json:"blockNumber"`
+	TransactionHash     *common.Hash `json:"transactionHash"`
+	TransactionPosition *int         `json:"transactionPosition"`
+	TraceAddress        []int        `json:"traceAddress"`
+	Type                string       `json:"type"`
+	Error               string       `json:"error"`
+}
+
+func (t *parityTracer) Name() string { return t.name + " trace_*" }
+
+func (t *parityTracer) TraceTransaction(ctx context.Context, hash common.Hash) ([]InternalCall, error) {
+	calls, _, err := t.call(ctx, "trace_transaction", hash)
+	return calls, err
+}
+
+func (t *parityTracer) TraceBlock(ctx context.Context, number uint64) ([]InternalCall, error) {
+	calls, _, err := t.call(ctx, "trace_block", hexutil.Uint64(number))
+	return calls, err
+}
+
+func (t *parityTracer) TraceFilter(ctx context.Context, q TraceFilterQuery) ([]InternalCall, int, error) {
+	filter := map[string]interface{}{
+		"fromBlock": hexutil.Uint64(q.FromBlock),
+		"toBlock":   hexutil.Uint64(q.ToBlock),
+	}
+	if len(q.FromAddress) > 0 {
+		filter["fromAddress"] = q.FromAddress
+	}
+	if len(q.ToAddress) > 0 {
+		filter["toAddress"] = q.ToAddress
+	}
+	if q.After > 0 {
+		filter["after"] = q.After
+	}
+	if q.Count > 0 {
+		filter["count"] = q.Count
+	}
+	return t.call(ctx, "trace_filter", filter)
+}
+
+// call 调用 trace_* 并转换结果，同时返回节点返回的原始条数（过滤出块奖励之前）
+func (t *parityTracer) call(ctx context.Context, method string, args ...interface{}) ([]InternalCall, int, error) {
+	var traces []parityTrace
+	if err := callRetry(ctx, t.client, &traces, method, args...); err != nil {
+		if isMethodNotFound(err) {
+			return nil, 0, ErrTraceNotSupported
+		}
+		return nil, 0, fmt.Errorf("%s 失败: %w", method, err)
+	}
+	calls := make([]InternalCall, 0, len(traces))
+	for _, tr := range traces {
+		if tr.Type == "reward" || tr.TransactionHash == nil {
+			continue // 出块奖励不属于任何交易
+		}
+		c := InternalCall{
+			BlockNumber:  tr.BlockNumber,
+			TxHash:       *tr.TransactionHash,
+			TraceAddress: tr.TraceAddress,
+			Type:         tr.Action.CallType,
+			Value:        new(big.Int),
+			Input:        tr.Action.Input,
+			Error:        tr.Error,
+		}
+		if tr.TransactionPosition != nil {
+			c.TxIndex = *tr.TransactionPosition
+		}
+		if tr.Action.Value != nil {
+			c.Value = tr.Action.Value.ToInt()
+		}
+		if tr.Result != nil {
+			c.GasUsed = uint64(tr.Result.GasUsed)
+		}
+		switch tr.Type {
+		case "create":
+			c.Type = "create"
+			c.Input = tr.Action.Init
+			if tr.Result != nil && tr.Result.Address != nil {
+				c.To = *tr.Result.Address
+			}
+		case "suicide", "selfdestruct":
+			c.Type = "selfdestruct"
+			if tr.Action.Address != nil {
+				c.From = *tr.Action.Address
+			}
+			if tr.Action.RefundAddress != nil {
+				c.To = *tr.Action.RefundAddress
+			}
+			if tr.Action.Balance != nil {
+				c.Value = tr.Action.Balance.ToInt()
+			}
+		}
+		if tr.Action.From != nil {
+			c.From = *tr.Action.From
+		}
+		if tr.Action.To != nil {
+			c.To = *tr.Action.To
+		}
+		calls = append(calls, c)
+	}
+	return calls, len(traces), nil
+}
+
+// ------------------------------------------------
+// Geth debug_trace* + callTracer
+// ------------------------------------------------
+
+type gethTracer struct {
+	client *rpc.Client
+}
+
+// callFrame callTracer 输出的调用树节点
+type callFrame struct {
+	Type    string          `json:"type"`
+	From    common.Address  `json:"from"`
+	To      *common.Address `json:"to"`
+	Value   *hexutil.Big    `json:"value"`
+	GasUsed hexutil.Uint64  `json:"gasUsed"`
+	Input   hexutil.Bytes   `json:"input"`
+	Error   string          `json:"error"`
+	Calls   []callFrame     `json:"calls"`
+}
+
+var callTracerConfig = map[string]interface{}{"tracer": "callTracer"}
+
+func (t *gethTracer) Name() string { return "geth debug_trace*" }
+
+func (t *gethTracer) TraceTransaction(ctx context.Context, hash common.Hash) ([]InternalCall, error) {
+	var frame callFrame
+	if err := callRetry(ctx, t.client, &frame, "debug_traceTransaction", hash, callTracerConfig); err != nil {
+		if isMethodNotFound(err) {
+			return nil, ErrTraceNotSupported
+		}
+		return nil, fmt.Errorf("debug_traceTransaction 失败: %w", err)
+	}
+	// callTracer 不返回交易位置，单独查一次
+	var pos struct {
+		BlockNumber      *hexutil.Uint64 `json:"blockNumber"`
+		TransactionIndex *hexutil.Uint64 `json:"transactionIndex"`
+	}
+	if err := callRetry(ctx, t.client, &pos, "eth_getTransactionByHash", hash); err != nil {
+		return nil, fmt.Errorf("查询交易位置失败: %w", err)
+	}
+	base := InternalCall{TxHash: hash}
+	if pos.BlockNumber != nil && pos.TransactionIndex != nil {
+		base.BlockNumber = uint64(*pos.BlockNumber)
+		base.TxIndex = int(*pos.TransactionIndex)
+	}
+	return flattenCallFrame(base, &frame, nil, nil), nil
+}
+
+func (t *gethTracer) TraceBlock(ctx context.Context, number uint64) ([]InternalCall, error) {
+	var results []struct {
+		TxHash common.Hash `json:"txHash"`
+		Result callFrame   `json:"result"`
+		Error  string      `json:"error"`
+	}
+	if err := callRetry(ctx, t.client, &results, "debug_traceBlockByNumber", hexutil.Uint64(number), callTracerConfig); err != nil {
+		if isMethodNotFound(err) {
+			return nil, ErrTraceNotSupported
+		}
+		return nil, fmt.Errorf("debug_traceBlockByNumber 失败: %w", err)
+	}
+	var calls []InternalCall
+	for i, r := range results {
+		base := InternalCall{BlockNumber: number, TxHash: r.TxHash, TxIndex: i}
+		calls = flattenCallFrame(base, &r.Result, nil, calls)
+	}
+	return calls, nil
+}
+
+// TraceFilter Geth 没有按地址索引的 trace，无法高效地扫描区间
+func (t *gethTracer) TraceFilter(ctx context.Context, q TraceFilterQuery) ([]InternalCall, int, error) {
+	return nil, 0, ErrTraceNotSupported
+}
+
+// flattenCallFrame 按深度优先把调用树展平，traceAddress 与 Parity 格式保持一致
+func flattenCallFrame(base InternalCall, f *callFrame, addr []int, out []InternalCall) []InternalCall {
+	c := base
+	c.TraceAddress = addr
+	c.Type = strings.ToLower(f.Type)
+	c.From = f.From
+	c.Value = new(big.Int)
+	c.Input = f.Input
+	c.GasUsed = uint64(f.GasUsed)
+	c.Error = f.Error
+	if f.To != nil {
+		c.To = *f.To
+	}
+	if f.Value != nil {
+		c.Value = f.Value.ToInt()
+	}
+	out = append(out, c)
+	for i := range f.Calls {
+		child := append(append([]int(nil), addr...), i)
+		out = flattenCallFrame(base, &f.Calls[i], child, out)
+	}
+	return out
+}
+
+// isMethodNotFound JSON-RPC -32601：节点没有开启对应的模块
+func isMethodNotFound(err error) bool {
+	var rpcErr rpc.Error
+	return errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32601
+}
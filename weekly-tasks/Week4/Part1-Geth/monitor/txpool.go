@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// 交易池采样间隔（秒）和样本保留天数的默认值
+	DefaultTxpoolInterval  = 15
+	DefaultTxpoolRetention = 7
+	// GET /api/txpool 默认返回的时间范围（分钟）
+	TxpoolDefaultRange = 60
+)
+
+// TxpoolConfig 交易池拥堵时间序列，需要节点开启 txpool 接口
+type TxpoolConfig struct {
+	Enabled   bool   `json:"enabled"`
+	Interval  int    `json:"interval"`       // 采样间隔（秒）
+	DB        string `json:"db"`             // 样本写入的 SQLite 文件，默认与索引共用
+	Retention int    `json:"retention_days"` // 样本保留天数
+}
+
+// TxpoolSample 一个采样周期的交易池状态，速率为每秒笔数
+type TxpoolSample struct {
+	Time     time.Time `json:"time"`
+	Pending  int       `json:"pending"`
+	Queued   int       `json:"queued"`
+	Arrivals int       `json:"arrivals"` // 本周期订阅收到的新 Pending 交易
+	Included int       `json:"included"` // 本周期新区块中打包的交易
+	Evicted  int       `json:"evicted"`  // 估算：上期数量 + 新增 - 打包 - 本期数量
+
+	ArrivalRate  float64 `json:"arrival_rate"`
+	EvictionRate float64 `json:"eviction_rate"`
+}
+
+// TxpoolSeries 定期查询 txpool_status，结合 Pending 订阅和新区块估算交易的进出速率，
+// 写入指标和 SQLite，用来观察交易池的拥堵情况
+// ⚠️ 打包数包含私有订单流（不经过公开交易池），驱逐数会因此偏低，只适合看趋势
+type TxpoolSeries struct {
+	cfg    TxpoolConfig
+	client *rpc.Client
+	store  *TxpoolStore
+
+	arrivals atomic.Int64
+	included atomic.Int64
+	prev     *TxpoolSample
+
+	pending      *metrics.Gauge
+	queued       *metrics.Gauge
+	arrivalRate  *metrics.GaugeFloat64
+	evictionRate *metrics.GaugeFloat64
+}
+
+// NewTxpoolSeries 创建交易池时间序列，store 为样本存储
+func NewTxpoolSeries(cfg TxpoolConfig, client *rpc.Client, store *TxpoolStore) *TxpoolSeries {
+	return &TxpoolSeries{
+		cfg:          cfg,
+		client:       client,
+		store:        store,
+		pending:      metrics.NewRegisteredGauge("monitor/txpool/pending", metricsRegistry),
+		queued:       metrics.NewRegisteredGauge("monitor/txpool/queued", metricsRegistry),
+		arrivalRate:  metrics.NewRegisteredGaugeFloat64("monitor/txpool/arrival_rate", metricsRegistry),
+		evictionRate: metrics.NewRegisteredGaugeFloat64("monitor/txpool/eviction_rate", metricsRegistry),
+	}
+}
+
+// ObservePending 订阅收到一笔新的 Pending 交易，在主循环中调用
+func (s *TxpoolSeries) ObservePending() {
+	s.arrivals.Add(1)
+}
+
+// Enrichment 实现 Enricher：只看交易哈希，不需要收据
+func (s *TxpoolSeries) Enrichment() EnrichLevel { return EnrichTransactions }
+
+// OnBlock 实现 BlockAnalyzer，累计新区块中打包的交易数
+func (s *TxpoolSeries) OnBlock(data *BlockData) {
+	s.included.Add(int64(len(data.Block.Transactions())))
+}
+
+// Run 按间隔采样，每小时清理一次过期样本，直到 ctx 取消
+func (s *TxpoolSeries) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(s.cfg.Interval) * time.Second)
+	defer ticker.Stop()
+	lastPrune := time.Time{}
+	for {
+		// 预算降级时按更长的间隔采样
+		if rpcBudget.Load().Allow("txpool", time.Duration(s.cfg.Interval)*time.Second) {
+			if err := s.sample(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("⚠️  交易池采样失败: %v", err)
+			}
+		}
+		if time.Since(lastPrune) > time.Hour {
+			lastPrune = time.Now()
+			if err := s.store.Prune(lastPrune.AddDate(0, 0, -s.cfg.Retention)); err != nil {
+				log.Printf("⚠️  清理交易池样本失败: %v", err)
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sample 查询一次交易池大小，和上一次采样比较得到本周期的进出速率
+func (s *TxpoolSeries) sample(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, CONNECTION_TIMEOUT)
+	defer cancel()
+	var pool struct {
+		Pending hexutil.Uint `json:"pending"`
+		Queued  hexutil.Uint `json:"queued"`
+	}
+	if err := callRetry(ctx, s.client, &pool, "txpool_status"); err != nil {
+		return fmt.Errorf("txpool_status: %w", err)
+	}
+	cur := &TxpoolSample{
+		Time:     time.Now(),
+		Pending:  int(pool.Pending),
+		Queued:   int(pool.Queued),
+		Arrivals: int(s.arrivals.Swap(0)),
+		Included: int(s.included.Swap(0)),
+	}
+	s.pending.Update(int64(cur.Pending))
+	s.queued.Update(int64(cur.Queued))
+
+	prev := s.prev
+	s.prev = cur
+	if prev == nil {
+		return nil // 第一次采样没有上一期，速率无意义
+	}
+	cur.Evicted = max(0, prev.Pending+prev.Queued+cur.Arrivals-cur.Included-cur.Pending-cur.Queued)
+	if secs := cur.Time.Sub(prev.Time).Seconds(); secs > 0 {
+		cur.ArrivalRate = float64(cur.Arrivals) / secs
+		cur.EvictionRate = float64(cur.Evicted) / secs
+	}
+	s.arrivalRate.Update(cur.ArrivalRate)
+	s.evictionRate.Update(cur.EvictionRate)
+	return s.store.Save(cur)
+}
+
+// RegisterAPI 注册时间序列查询接口
+//
+//	GET /api/txpool?minutes=60 最近一段时间的样本，按时间升序
+func (s *TxpoolSeries) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/txpool", func(w http.ResponseWriter, r *http.Request) {
+		minutes := TxpoolDefaultRange
+		if v, err := strconv.Atoi(r.URL.Query().Get("minutes")); err == nil && v > 0 {
+			minutes = v
+		}
+		samples, err := s.store.Since(time.Now().Add(-time.Duration(minutes) * time.Minute))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, samples)
+	})
+}
+
+const txpoolSchema = `
+CREATE TABLE IF NOT EXISTS txpool_samples (
+	time          INTEGER PRIMARY KEY,
+	pending       INTEGER NOT NULL,
+	queued        INTEGER NOT NULL,
+	arrivals      INTEGER NOT NULL,
+	included      INTEGER NOT NULL,
+	evicted       INTEGER NOT NULL,
+	arrival_rate  REAL    NOT NULL,
+	eviction_rate REAL    NOT NULL
+);`
+
+// TxpoolStore 交易池样本的 SQLite 存储，和事件索引共用同一个数据库文件
+type TxpoolStore struct {
+	db *sql.DB
+}
+
+func OpenTxpoolStore(path string) (*TxpoolStore, error) {
+	db, err := openSQLite(path, txpoolSchema)
+	if err != nil {
+		return nil, err
+	}
+	return &TxpoolStore{db: db}, nil
+}
+
+// Save 写入一个样本（time 为毫秒时间戳）
+func (s *TxpoolStore) Save(t *TxpoolSample) error {
+	if _, err := s.db.Exec(`INSERT OR REPLACE INTO txpool_samples
+		(time, pending, queued, arrivals, included, evicted, arrival_rate, eviction_rate) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.Time.UnixMilli(), t.Pending, t.Queued, t.Arrivals, t.Included, t.Evicted, t.ArrivalRate, t.EvictionRate); err != nil {
+		return fmt.Errorf("写入交易池样本失败: %w", err)
+	}
+	return nil
+}
+
+// Since 查询某个时间之后的样本，按时间升序
+func (s *TxpoolStore) Since(since time.Time) ([]TxpoolSample, error) {
+	rows, err := s.db.Query(`SELECT time, pending, queued, arrivals, included, evicted, arrival_rate, eviction_rate
+		FROM txpool_samples WHERE time >= ? ORDER BY time`, since.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("查询交易池样本失败: %w", err)
+	}
+	defer rows.Close()
+	samples := []TxpoolSample{}
+	for rows.Next() {
+		var t TxpoolSample
+		var ms int64
+		if err := rows.Scan(&ms, &t.Pending, &t.Queued, &t.Arrivals, &t.Included, &t.Evicted, &t.ArrivalRate, &t.EvictionRate); err != nil {
+			return nil, err
+		}
+		t.Time = time.UnixMilli(ms)
+		samples = append(samples, t)
+	}
+	return samples, rows.Err()
+}
+
+// Prune 删除某个时间之前的样本
+func (s *TxpoolStore) Prune(before time.Time) error {
+	_, err := s.db.Exec(`DELETE FROM txpool_samples WHERE time < ?`, before.UnixMilli())
+	return err
+}
+
+func (s *TxpoolStore) Close() error {
+	return s.db.Close()
+}
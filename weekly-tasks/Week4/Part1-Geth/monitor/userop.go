@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ------------------------------------------------
+// ERC-4337 UserOperation 监控：解码交易池中 bundler 提交的 handleOps，
+// 或轮询 bundler 的 debug_bundler_dumpMempool（alt-mempool），
+// 还原每个 UserOp 的智能账户、paymaster 和实际调用的合约
+// ------------------------------------------------
+
+const (
+	// 轮询 bundler 交易池的间隔
+	UserOpPollInterval = 3 * time.Second
+	// /api/userops 保留的最近 UserOp 数量，同时作为去重窗口
+	UserOpHistory = 500
+)
+
+// userOpAccountABI 常见智能账户的执行函数，加入内置函数表
+// 参数名按 innerCalls 的约定取 target / data，单个调用会展开成内层调用
+const userOpAccountABI = `[
+	{"type":"function","name":"execute","inputs":[{"name":"target","type":"address"},{"name":"value","type":"uint256"},{"name":"data","type":"bytes"}]},
+	{"type":"function","name":"execute","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"},{"name":"data","type":"bytes"},{"name":"operation","type":"uint8"}]},
+	{"type":"function","name":"executeUserOp","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"},{"name":"data","type":"bytes"},{"name":"operation","type":"uint8"}]},
+	{"type":"function","name":"executeBatch","inputs":[{"name":"dest","type":"address[]"},{"name":"func","type":"bytes[]"}]},
+	{"type":"function","name":"executeBatch","inputs":[{"name":"dest","type":"address[]"},{"name":"value","type":"uint256[]"},{"name":"func","type":"bytes[]"}]}
+]`
+
+var (
+	// 主网 EntryPoint
+	EntryPointV06 = common.HexToAddress("0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789")
+	EntryPointV07 = common.HexToAddress("0x0000000071727De22E5E9d8BAf0edAc6f37da032")
+
+	entryPointABI = mustParseABI(`[
+		{"type":"function","name":"handleOps","inputs":[{"name":"ops","type":"tuple[]","components":[{"name":"sender","type":"address"},{"name":"nonce","type":"uint256"},{"name":"initCode","type":"bytes"},{"name":"callData","type":"bytes"},{"name":"callGasLimit","type":"uint256"},{"name":"verificationGasLimit","type":"uint256"},{"name":"preVerificationGas","type":"uint256"},{"name":"maxFeePerGas","type":"uint256"},{"name":"maxPriorityFeePerGas","type":"uint256"},{"name":"paymasterAndData","type":"bytes"},{"name":"signature","type":"bytes"}]},{"name":"beneficiary","type":"address"}]},
+		{"type":"function","name":"handleOpsPacked","inputs":[{"name":"ops","type":"tuple[]","components":[{"name":"sender","type":"address"},{"name":"nonce","type":"uint256"},{"name":"initCode","type":"bytes"},{"name":"callData","type":"bytes"},{"name":"accountGasLimits","type":"bytes32"},{"name":"preVerificationGas","type":"uint256"},{"name":"gasFees","type":"bytes32"},{"name":"paymasterAndData","type":"bytes"},{"name":"signature","type":"bytes"}]},{"name":"beneficiary","type":"address"}]}
+	]`)
+	// v0.7 的 handleOps 参数是 PackedUserOperation，函数名同样是 handleOps（上面改名只是为了放进同一个 ABI）
+	entryPointHandleOps = map[common.Address]abi.Method{
+		EntryPointV06: entryPointABI.Methods["handleOps"],
+		EntryPointV07: packedHandleOps(),
+	}
+)
+
+// packedHandleOps v0.7 handleOps((address,uint256,bytes,bytes,bytes32,uint256,bytes32,bytes,bytes)[],address)
+func packedHandleOps() abi.Method {
+	m := entryPointABI.Methods["handleOpsPacked"]
+	return abi.NewMethod("handleOps", "handleOps", abi.Function, m.StateMutability, false, false, m.Inputs, nil)
+}
+
+// UserOpConfig UserOperation 监控
+type UserOpConfig struct {
+	Enabled    bool   `json:"enabled"`
+	BundlerURL string `json:"bundler_url"` // 可选：支持 debug_bundler_dumpMempool 的 bundler，能在上链之前看到 UserOp
+}
+
+// UserOperation 解码后的 UserOp
+type UserOperation struct {
+	EntryPoint   common.Address   `json:"entry_point"`
+	Sender       common.Address   `json:"sender"` // 智能账户
+	Nonce        *big.Int         `json:"nonce"`
+	Factory      common.Address   `json:"factory,omitempty"`   // 账户尚未部署时 initCode 中的工厂
+	Paymaster    common.Address   `json:"paymaster,omitempty"` // 零地址表示账户自己付 gas
+	MaxFeePerGas *big.Int         `json:"max_fee_per_gas"`
+	Call         *DecodedCall     `json:"call,omitempty"`
+	Targets      []common.Address `json:"targets"` // 账户实际调用的合约
+	Source       string           `json:"source"`  // handleOps（交易池中的 bundle 交易）或 bundler
+	Bundler      common.Address   `json:"bundler,omitempty"`
+	TxHash       common.Hash      `json:"tx_hash,omitempty"`
+	Watched      string           `json:"watched,omitempty"` // 智能账户在 watch 中时为来源
+	Seen         time.Time        `json:"seen"`
+
+	callData []byte
+}
+
+// UserOpMonitor 汇总两个来源的 UserOp，按 (sender, nonce) 去重
+type UserOpMonitor struct {
+	cfg    UserOpConfig
+	signer types.Signer
+	abis   *ABIRegistry
+	watch  *Watchlist
+
+	mu     sync.Mutex
+	seen   map[string]bool
+	recent []UserOperation
+
+	onUserOp func(UserOperation)
+}
+
+// NewUserOpMonitor 创建监控，onUserOp 为 nil 时使用默认输出
+func NewUserOpMonitor(cfg UserOpConfig, signer types.Signer, abis *ABIRegistry, watch *Watchlist, onUserOp func(UserOperation)) *UserOpMonitor {
+	if onUserOp == nil {
+		onUserOp = PrintUserOperation
+	}
+	return &UserOpMonitor{cfg: cfg, signer: signer, abis: abis, watch: watch, seen: make(map[string]bool), onUserOp: onUserOp}
+}
+
+// Observe 解码交易池中发给 EntryPoint 的 handleOps（只处理完整交易）
+func (m *UserOpMonitor) Observe(msg BusMessage) {
+	tx := msg.Tx
+	if tx == nil || tx.To() == nil {
+		return
+	}
+	method, ok := entryPointHandleOps[*tx.To()]
+	if !ok || !equalSelector(tx.Data(), method.ID) {
+		return
+	}
+	ops, err := decodeHandleOps(*tx.To(), method, tx.Data())
+	if err != nil {
+		return
+	}
+	bundler, err := types.Sender(m.signer, tx)
+	if err != nil {
+		return
+	}
+	for _, op := range ops {
+		op.Source, op.Bundler, op.TxHash = "handleOps", bundler, tx.Hash()
+		m.record(op)
+	}
+}
+
+// decodeHandleOps 按 EntryPoint 版本解码 handleOps 中的 UserOp
+func decodeHandleOps(entryPoint common.Address, method abi.Method, data []byte) ([]UserOperation, error) {
+	values, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil, fmt.Errorf("解码 handleOps 失败: %w", err)
+	}
+	var out []UserOperation
+	ops := reflect.ValueOf(values[0])
+	for i := 0; i < ops.Len(); i++ {
+		o := ops.Index(i)
+		field := func(name string) interface{} { return o.FieldByName(name).Interface() }
+		var maxFee *big.Int
+		if entryPoint == EntryPointV06 {
+			maxFee = field("MaxFeePerGas").(*big.Int)
+		} else {
+			// v0.7 gasFees = maxPriorityFeePerGas (16 字节) | maxFeePerGas (16 字节)
+			fees := field("GasFees").([32]byte)
+			maxFee = new(big.Int).SetBytes(fees[16:])
+		}
+		out = append(out, newUserOperation(entryPoint, field("Sender").(common.Address), field("Nonce").(*big.Int),
+			field("InitCode").([]byte), field("CallData").([]byte), field("PaymasterAndData").([]byte), maxFee))
+	}
+	return out, nil
+}
+
+// newUserOperation initCode 和 paymasterAndData 的前 20 字节分别是工厂和 paymaster
+func newUserOperation(entryPoint, sender common.Address, nonce *big.Int, initCode, callData, paymasterAndData []byte, maxFee *big.Int) UserOperation {
+	op := UserOperation{EntryPoint: entryPoint, Sender: sender, Nonce: nonce, MaxFeePerGas: maxFee, callData: callData}
+	if len(initCode) >= 20 {
+		op.Factory = common.BytesToAddress(initCode[:20])
+	}
+	if len(paymasterAndData) >= 20 {
+		op.Paymaster = common.BytesToAddress(paymasterAndData[:20])
+	}
+	return op
+}
+
+// record 去重、解码账户的调用后输出
+func (m *UserOpMonitor) record(op UserOperation) {
+	key := op.EntryPoint.Hex() + op.Sender.Hex() + op.Nonce.String()
+	m.mu.Lock()
+	if m.seen[key] {
+		m.mu.Unlock()
+		return
+	}
+	m.seen[key] = true
+	m.mu.Unlock()
+
+	op.Call, _ = m.abis.DecodeCall(&op.Sender, op.callData)
+	op.Targets = userOpTargets(op.Call)
+	op.Watched = m.watch.Source(op.Sender)
+	m.mu.Lock()
+	op.Seen = time.Now()
+	m.recent = append(m.recent, op)
+	if over := len(m.recent) - UserOpHistory; over > 0 {
+		for _, old := range m.recent[:over] {
+			delete(m.seen, old.EntryPoint.Hex()+old.Sender.Hex()+old.Nonce.String())
+		}
+		m.recent = m.recent[over:]
+	}
+	m.mu.Unlock()
+	m.onUserOp(op)
+}
+
+// userOpTargets 账户调用的合约：execute 展开的内层调用，以及 executeBatch 的 dest
+func userOpTargets(call *DecodedCall) []common.Address {
+	targets := []common.Address{}
+	if call == nil {
+		return targets
+	}
+	seen := make(map[common.Address]bool)
+	dest, _ := call.Arg("dest").([]common.Address)
+	for _, addr := range append(call.Targets(), dest...) {
+		if !seen[addr] {
+			seen[addr] = true
+			targets = append(targets, addr)
+		}
+	}
+	return targets
+}
+
+// RunBundler 轮询 bundler 的交易池直到 ctx 取消；未配置 bundler_url 时直接返回
+func (m *UserOpMonitor) RunBundler(ctx context.Context) {
+	if m.cfg.BundlerURL == "" {
+		return
+	}
+	client, err := dialRPC(ctx, m.cfg.BundlerURL)
+	if err != nil {
+		log.Printf("⚠️  连接 bundler 失败: %v", err)
+		return
+	}
+	defer client.Close()
+
+	ticker := time.NewTicker(UserOpPollInterval)
+	defer ticker.Stop()
+	warned := false
+	for {
+		for entryPoint := range entryPointHandleOps {
+			if err := m.pollBundler(ctx, client, entryPoint); err != nil && !warned {
+				log.Printf("⚠️  读取 bundler 交易池失败（需要 bundler 开放 debug_bundler_dumpMempool）: %v", err)
+				warned = true
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// rpcUserOp bundler 返回的 UserOp，兼容 v0.6（initCode / paymasterAndData）和 v0.7（factory / paymaster 拆开）的字段
+type rpcUserOp struct {
+	Sender           common.Address  `json:"sender"`
+	Nonce            *hexutil.Big    `json:"nonce"`
+	InitCode         hexutil.Bytes   `json:"initCode"`
+	Factory          *common.Address `json:"factory"`
+	CallData         hexutil.Bytes   `json:"callData"`
+	MaxFeePerGas     *hexutil.Big    `json:"maxFeePerGas"`
+	PaymasterAndData hexutil.Bytes   `json:"paymasterAndData"`
+	Paymaster        *common.Address `json:"paymaster"`
+}
+
+func (m *UserOpMonitor) pollBundler(ctx context.Context, client *rpc.Client, entryPoint common.Address) error {
+	ctx, cancel := context.WithTimeout(ctx, CONNECTION_TIMEOUT)
+	defer cancel()
+	var ops []rpcUserOp
+	if err := callRetry(ctx, client, &ops, "debug_bundler_dumpMempool", entryPoint); err != nil {
+		return err
+	}
+	for _, o := range ops {
+		if o.Nonce == nil || o.MaxFeePerGas == nil {
+			continue
+		}
+		initCode, paymasterAndData := []byte(o.InitCode), []byte(o.PaymasterAndData)
+		if o.Factory != nil {
+			initCode = o.Factory.Bytes()
+		}
+		if o.Paymaster != nil {
+			paymasterAndData = o.Paymaster.Bytes()
+		}
+		op := newUserOperation(entryPoint, o.Sender, o.Nonce.ToInt(), initCode, o.CallData, paymasterAndData, o.MaxFeePerGas.ToInt())
+		op.Source = "bundler"
+		m.record(op)
+	}
+	return nil
+}
+
+// Recent 最近的 UserOp，最新的在前
+func (m *UserOpMonitor) Recent(limit int) []UserOperation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]UserOperation, 0, min(limit, len(m.recent)))
+	for i := len(m.recent) - 1; i >= 0 && len(out) < limit; i-- {
+		out = append(out, m.recent[i])
+	}
+	return out
+}
+
+// RegisterAPI 注册 UserOp 查询接口
+//
+//	GET /api/userops 最近的 UserOp
+func (m *UserOpMonitor) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/userops", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, m.Recent(UserOpHistory))
+	})
+}
+
+// PrintUserOperation 默认的 UserOp 输出
+func PrintUserOperation(op UserOperation) {
+	tag := "[UserOp]"
+	if op.Watched != "" {
+		tag = fmt.Sprintf("[UserOp][Watched:%s]", op.Watched)
+	}
+	payer := "自付 gas"
+	if op.Paymaster != (common.Address{}) {
+		payer = "paymaster " + op.Paymaster.Hex()
+	}
+	summary := fmt.Sprintf("🧩 %s 账户 %s (nonce=%s) | %s | 来源 %s", tag, op.Sender.Hex(), op.Nonce, payer, op.Source)
+	text := summary
+	if op.Factory != (common.Address{}) {
+		text += fmt.Sprintf("\n   🏭 首次部署，工厂 %s", op.Factory.Hex())
+	}
+	if op.Call != nil {
+		text += fmt.Sprintf("\n   🧾 调用 %s", op.Call)
+	}
+	Emit(Event{Type: "userop", Key: eventKey(op.EntryPoint, op.Sender, op.Nonce, op.Source), Summary: summary, Text: text, Data: op})
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ------------------------------------------------
+// Bundler / paymaster 统计：从上链的 handleOps 交易和 UserOperationEvent 统计
+// 每个 bundler 打包的 UserOp 数量和 bundle 失败率、每个 paymaster 代付的 gas、每个 EntryPoint 的 UserOp 失败率
+// ------------------------------------------------
+
+const (
+	// 每隔多少个区块输出一次统计（约 1 小时）
+	UserOpStatsReportBlocks = 300
+	// 统计输出中每类列出的数量
+	UserOpStatsTop = 5
+)
+
+// UserOperationEvent(bytes32 indexed userOpHash, address indexed sender, address indexed paymaster, uint256 nonce, bool success, uint256 actualGasCost, uint256 actualGasUsed)
+// v0.6 和 v0.7 相同
+var userOpEventTopic = crypto.Keccak256Hash([]byte("UserOperationEvent(bytes32,address,address,uint256,bool,uint256,uint256)"))
+
+// BundlerStats 一个 bundler 的统计
+type BundlerStats struct {
+	Address       common.Address `json:"address"`
+	Bundles       uint64         `json:"bundles"`        // 上链的 handleOps 交易
+	FailedBundles uint64         `json:"failed_bundles"` // 整笔回滚的 handleOps（通常是被抢先打包）
+	Ops           uint64         `json:"ops"`
+	GasFeesWei    *big.Int       `json:"gas_fees_wei"`  // bundler 支付的交易手续费
+	CollectedWei  *big.Int       `json:"collected_wei"` // UserOp 支付的 actualGasCost 合计
+}
+
+// PaymasterStats 一个 paymaster 的统计
+type PaymasterStats struct {
+	Address      common.Address `json:"address"`
+	Ops          uint64         `json:"ops"`
+	Failed       uint64         `json:"failed"`
+	SponsoredWei *big.Int       `json:"sponsored_wei"` // 代付的 actualGasCost 合计
+	SponsoredUSD float64        `json:"sponsored_usd"`
+}
+
+// EntryPointStats 一个 EntryPoint 的统计
+type EntryPointStats struct {
+	Address     common.Address `json:"address"`
+	Ops         uint64         `json:"ops"`
+	Failed      uint64         `json:"failed"`
+	Sponsored   uint64         `json:"sponsored"` // 有 paymaster 的 UserOp
+	FailureRate float64        `json:"failure_rate"`
+}
+
+// UserOpStatsSummary /api/userops/stats 的返回内容
+type UserOpStatsSummary struct {
+	FromBlock   uint64            `json:"from_block"`
+	ToBlock     uint64            `json:"to_block"`
+	EntryPoints []EntryPointStats `json:"entry_points"`
+	Bundlers    []BundlerStats    `json:"bundlers"`
+	Paymasters  []PaymasterStats  `json:"paymasters"`
+}
+
+// UserOpStats 统计启动以来上链的 UserOp
+type UserOpStats struct {
+	values *Valuator
+
+	mu          sync.Mutex
+	fromBlock   uint64
+	toBlock     uint64
+	entryPoints map[common.Address]*EntryPointStats
+	bundlers    map[common.Address]*BundlerStats
+	paymasters  map[common.Address]*PaymasterStats
+
+	onReport func(UserOpStatsSummary)
+}
+
+// NewUserOpStats 创建统计，onReport 为 nil 时使用默认输出
+func NewUserOpStats(values *Valuator, onReport func(UserOpStatsSummary)) *UserOpStats {
+	if onReport == nil {
+		onReport = PrintUserOpStats
+	}
+	return &UserOpStats{
+		values:      values,
+		entryPoints: make(map[common.Address]*EntryPointStats),
+		bundlers:    make(map[common.Address]*BundlerStats),
+		paymasters:  make(map[common.Address]*PaymasterStats),
+		onReport:    onReport,
+	}
+}
+
+// OnBlock 实现 BlockAnalyzer
+func (s *UserOpStats) OnBlock(data *BlockData) {
+	number := data.Block.NumberU64()
+	s.mu.Lock()
+	if s.fromBlock == 0 {
+		s.fromBlock = number
+	}
+	s.toBlock = number
+	for i, tx := range data.Block.Transactions() {
+		if tx.To() == nil {
+			continue
+		}
+		if _, ok := entryPointHandleOps[*tx.To()]; !ok {
+			continue
+		}
+		r := data.Receipts[i]
+		b := s.bundler(data.Senders[i])
+		b.Bundles++
+		b.GasFeesWei.Add(b.GasFeesWei, new(big.Int).Mul(new(big.Int).SetUint64(r.GasUsed), r.EffectiveGasPrice))
+		if r.Status == 0 {
+			b.FailedBundles++
+			continue
+		}
+		for _, l := range r.Logs {
+			if l.Address != *tx.To() || len(l.Topics) != 4 || l.Topics[0] != userOpEventTopic || len(l.Data) != 4*32 {
+				continue
+			}
+			success := word(l.Data, 1).Sign() != 0
+			cost := word(l.Data, 2)
+
+			ep := s.entryPoints[l.Address]
+			if ep == nil {
+				ep = &EntryPointStats{Address: l.Address}
+				s.entryPoints[l.Address] = ep
+			}
+			ep.Ops++
+			b.Ops++
+			b.CollectedWei.Add(b.CollectedWei, cost)
+			if !success {
+				ep.Failed++
+			}
+			paymaster := common.BytesToAddress(l.Topics[3].Bytes())
+			if paymaster == (common.Address{}) {
+				continue
+			}
+			ep.Sponsored++
+			p := s.paymasters[paymaster]
+			if p == nil {
+				p = &PaymasterStats{Address: paymaster, SponsoredWei: new(big.Int)}
+				s.paymasters[paymaster] = p
+			}
+			p.Ops++
+			p.SponsoredWei.Add(p.SponsoredWei, cost)
+			if !success {
+				p.Failed++
+			}
+		}
+	}
+	report := number%UserOpStatsReportBlocks == 0 && len(s.bundlers) > 0
+	s.mu.Unlock()
+
+	if report {
+		s.onReport(s.Summary())
+	}
+}
+
+// bundler 调用方持有锁
+func (s *UserOpStats) bundler(addr common.Address) *BundlerStats {
+	b := s.bundlers[addr]
+	if b == nil {
+		b = &BundlerStats{Address: addr, GasFeesWei: new(big.Int), CollectedWei: new(big.Int)}
+		s.bundlers[addr] = b
+	}
+	return b
+}
+
+// Summary 当前的统计，bundler 按 UserOp 数量、paymaster 按代付金额排序
+func (s *UserOpStats) Summary() UserOpStatsSummary {
+	s.mu.Lock()
+	sum := UserOpStatsSummary{FromBlock: s.fromBlock, ToBlock: s.toBlock}
+	for _, ep := range s.entryPoints {
+		e := *ep
+		if e.Ops > 0 {
+			e.FailureRate = float64(e.Failed) / float64(e.Ops)
+		}
+		sum.EntryPoints = append(sum.EntryPoints, e)
+	}
+	for _, b := range s.bundlers {
+		c := *b
+		c.GasFeesWei, c.CollectedWei = new(big.Int).Set(b.GasFeesWei), new(big.Int).Set(b.CollectedWei)
+		sum.Bundlers = append(sum.Bundlers, c)
+	}
+	for _, p := range s.paymasters {
+		c := *p
+		c.SponsoredWei = new(big.Int).Set(p.SponsoredWei)
+		sum.Paymasters = append(sum.Paymasters, c)
+	}
+	s.mu.Unlock()
+
+	for i := range sum.Paymasters {
+		sum.Paymasters[i].SponsoredUSD, _ = s.values.ETHValue(sum.Paymasters[i].SponsoredWei)
+	}
+	sort.Slice(sum.EntryPoints, func(i, j int) bool { return sum.EntryPoints[i].Ops > sum.EntryPoints[j].Ops })
+	sort.Slice(sum.Bundlers, func(i, j int) bool { return sum.Bundlers[i].Ops > sum.Bundlers[j].Ops })
+	sort.Slice(sum.Paymasters, func(i, j int) bool { return sum.Paymasters[i].SponsoredWei.Cmp(sum.Paymasters[j].SponsoredWei) > 0 })
+	return sum
+}
+
+// RegisterAPI 注册 bundler / paymaster 统计接口
+//
+//	GET /api/userops/stats 启动以来的 bundler、paymaster 和 EntryPoint 统计
+func (s *UserOpStats) RegisterAPI(api *APIServer) {
+	api.Handle("GET /api/userops/stats", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.Summary())
+	})
+}
+
+// PrintUserOpStats 默认的统计输出
+func PrintUserOpStats(sum UserOpStatsSummary) {
+	var sb strings.Builder
+	summary := fmt.Sprintf("🧩 [AA Stats] 区块 %d - %d", sum.FromBlock, sum.ToBlock)
+	sb.WriteString("\n" + summary + "\n")
+	for _, ep := range sum.EntryPoints {
+		fmt.Fprintf(&sb, "   EntryPoint %s: %d 个 UserOp，失败 %.1f%%，paymaster 代付 %d 个\n", ep.Address.Hex(), ep.Ops, ep.FailureRate*100, ep.Sponsored)
+	}
+	for i, b := range sum.Bundlers {
+		if i == UserOpStatsTop {
+			break
+		}
+		fmt.Fprintf(&sb, "   Bundler %s: %d 个 bundle（回滚 %d），%d 个 UserOp，手续费 %s ETH，收取 %s ETH\n",
+			b.Address.Hex(), b.Bundles, b.FailedBundles, b.Ops, formatUnits(b.GasFeesWei, 18), formatUnits(b.CollectedWei, 18))
+	}
+	for i, p := range sum.Paymasters {
+		if i == UserOpStatsTop {
+			break
+		}
+		fmt.Fprintf(&sb, "   Paymaster %s: 代付 %d 个 UserOp，%s ETH (%s)\n", p.Address.Hex(), p.Ops, formatUnits(p.SponsoredWei, 18), formatUSD(p.SponsoredUSD))
+	}
+	Emit(Event{Type: "userop_stats", Summary: summary, Text: sb.String(), Data: sum})
+}
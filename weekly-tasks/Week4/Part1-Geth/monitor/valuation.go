@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+var (
+	uniV2SwapTopic = crypto.Keccak256Hash([]byte("Swap(address,uint256,uint256,uint256,uint256,address)"))
+	uniV3SwapTopic = crypto.Keccak256Hash([]byte("Swap(address,address,int256,int256,uint160,uint128,int24)"))
+
+	selectorToken0 = common.FromHex("0x0dfe1681") // token0()
+	selectorToken1 = common.FromHex("0xd21220a7") // token1()
+)
+
+// Valuator 用代币元数据和价格源估算转账 / swap 的美元价值（观察时刻的价格，只是近似值）
+type Valuator struct {
+	client *ethclient.Client
+	tokens *TokenCache
+	prices *PriceOracle
+	screen *TokenScreener // 可为 nil；被判定为危险的代币不估值（骗局代币的报价没有意义）
+
+	mu       sync.Mutex
+	pools    map[common.Address][2]common.Address // 池子 -> (token0, token1)
+	inflight map[common.Address]bool
+}
+
+func NewValuator(client *ethclient.Client, tokens *TokenCache, prices *PriceOracle, screen *TokenScreener) *Valuator {
+	return &Valuator{
+		client:   client,
+		tokens:   tokens,
+		prices:   prices,
+		screen:   screen,
+		pools:    make(map[common.Address][2]common.Address),
+		inflight: make(map[common.Address]bool),
+	}
+}
+
+// ETHValue wei 的美元价值
+func (v *Valuator) ETHValue(wei *big.Int) (float64, bool) {
+	if v == nil {
+		return 0, false
+	}
+	price, ok := v.prices.ETHPrice()
+	if !ok {
+		return 0, false
+	}
+	return weiToEther(wei) * price, true
+}
+
+// TokenValue 代币最小单位金额的美元价值，元数据或价格未就绪时返回 false
+func (v *Valuator) TokenValue(token common.Address, amount *big.Int) (float64, bool) {
+	if v.screen.Dangerous(token) {
+		return 0, false
+	}
+	meta, ok := v.tokens.Lookup(token)
+	if !ok || !meta.HasDecimals {
+		return 0, false
+	}
+	price, ok := v.prices.Price(token)
+	if !ok {
+		return 0, false
+	}
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(amount),
+		new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(meta.Decimals)), nil))).Float64()
+	return f * price, true
+}
+
+// LogValue 估算 ERC-20 Transfer、Uniswap V2 / V3 Swap 日志的美元价值
+// swap 两侧分别估值后取较大的一侧，只要有一侧能定价就能得到结果
+func (v *Valuator) LogValue(l types.Log) (usd float64, kind string, ok bool) {
+	if v == nil || len(l.Topics) == 0 {
+		return 0, "", false
+	}
+	switch {
+	case l.Topics[0] == erc20TransferTopic && len(l.Topics) == 3 && len(l.Data) == 32:
+		usd, ok = v.TokenValue(l.Address, new(big.Int).SetBytes(l.Data))
+		return usd, "transfer", ok
+
+	case l.Topics[0] == uniV2SwapTopic && len(l.Data) == 4*32:
+		tokens, found := v.poolTokens(l.Address)
+		if !found {
+			return 0, "", false
+		}
+		// amount0In + amount0Out 中只有一个非零，直接相加得到 token0 的成交量
+		amount0 := new(big.Int).Add(word(l.Data, 0), word(l.Data, 2))
+		amount1 := new(big.Int).Add(word(l.Data, 1), word(l.Data, 3))
+		usd, ok = v.maxValue(tokens, amount0, amount1)
+		return usd, "swap", ok
+
+	case l.Topics[0] == uniV3SwapTopic && len(l.Data) == 5*32:
+		tokens, found := v.poolTokens(l.Address)
+		if !found {
+			return 0, "", false
+		}
+		// V3 的 amount 是 int256，正数表示流入池子，取绝对值
+		amount0 := new(big.Int).Abs(signedWord(l.Data, 0))
+		amount1 := new(big.Int).Abs(signedWord(l.Data, 1))
+		usd, ok = v.maxValue(tokens, amount0, amount1)
+		return usd, "swap", ok
+	}
+	return 0, "", false
+}
+
+func (v *Valuator) maxValue(tokens [2]common.Address, amount0, amount1 *big.Int) (float64, bool) {
+	usd0, ok0 := v.TokenValue(tokens[0], amount0)
+	usd1, ok1 := v.TokenValue(tokens[1], amount1)
+	return max(usd0, usd1), ok0 || ok1
+}
+
+// poolTokens 查询池子的 token0 / token1，未命中时后台拉取
+func (v *Valuator) poolTokens(pool common.Address) ([2]common.Address, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if t, ok := v.pools[pool]; ok {
+		return t, true
+	}
+	if !v.inflight[pool] {
+		v.inflight[pool] = true
+		go v.fetchPoolTokens(pool)
+	}
+	return [2]common.Address{}, false
+}
+
+// PoolTokens 查询池子的 token0 / token1，缓存未命中时同步查询
+func (v *Valuator) PoolTokens(pool common.Address) ([2]common.Address, bool) {
+	if v == nil {
+		return [2]common.Address{}, false
+	}
+	v.mu.Lock()
+	t, ok := v.pools[pool]
+	v.mu.Unlock()
+	if ok {
+		return t, true
+	}
+	v.fetchPoolTokens(pool)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	t, ok = v.pools[pool]
+	return t, ok
+}
+
+func (v *Valuator) fetchPoolTokens(pool common.Address) {
+	ctx, cancel := context.WithTimeout(context.Background(), CONNECTION_TIMEOUT)
+	defer cancel()
+
+	var tokens [2]common.Address
+	var err error
+	for i, sel := range [][]byte{selectorToken0, selectorToken1} {
+		var out []byte
+		if out, err = callContractRetry(ctx, v.client, ethereum.CallMsg{To: &pool, Data: sel}, nil); err != nil || len(out) < 32 {
+			break
+		}
+		tokens[i] = common.BytesToAddress(out[:32])
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.inflight, pool)
+	if err != nil {
+		log.Printf("⚠️  查询池子 %s 的代币失败: %v", pool.Hex(), err)
+		return
+	}
+	v.pools[pool] = tokens
+}
+
+// word 读取 data 中第 i 个 32 字节的无符号整数
+func word(data []byte, i int) *big.Int {
+	return new(big.Int).SetBytes(data[i*32 : (i+1)*32])
+}
+
+// signedWord 读取 data 中第 i 个 32 字节的 int256（补码）
+func signedWord(data []byte, i int) *big.Int {
+	v := word(data, i)
+	if data[i*32]&0x80 != 0 {
+		v.Sub(v, new(big.Int).Lsh(common.Big1, 256))
+	}
+	return v
+}
+
+// formatUSD 金额展示，例如 $1.23M / $45.6K / $789.00
+func formatUSD(usd float64) string {
+	switch {
+	case usd >= 1e9:
+		return fmt.Sprintf("$%.2fB", usd/1e9)
+	case usd >= 1e6:
+		return fmt.Sprintf("$%.2fM", usd/1e6)
+	case usd >= 1e3:
+		return fmt.Sprintf("$%.1fK", usd/1e3)
+	default:
+		return fmt.Sprintf("$%.2f", usd)
+	}
+}
+
+// ValueAlert 美元价值超过阈值的转账 / swap 发出提醒，阈值可以在运行中修改（热加载）
+type ValueAlert struct {
+	v *Valuator
+
+	mu        sync.RWMutex
+	threshold float64 // 0 表示关闭
+}
+
+// NewValueAlert 创建提醒，thresholdUSD 为 0 时不提醒
+func NewValueAlert(v *Valuator, thresholdUSD float64) *ValueAlert {
+	return &ValueAlert{v: v, threshold: thresholdUSD}
+}
+
+// Threshold 当前阈值
+func (a *ValueAlert) Threshold() float64 {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.threshold
+}
+
+// SetThreshold 修改阈值，0 表示关闭
+func (a *ValueAlert) SetThreshold(thresholdUSD float64) {
+	a.mu.Lock()
+	a.threshold = thresholdUSD
+	a.mu.Unlock()
+}
+
+// Handle 作为 LogHandler 注册到日志流水线
+func (a *ValueAlert) Handle(l types.Log) {
+	a.mu.RLock()
+	threshold := a.threshold
+	a.mu.RUnlock()
+	if l.Removed || threshold <= 0 {
+		return
+	}
+	usd, kind, ok := a.v.LogValue(l)
+	if !ok || usd < threshold {
+		return
+	}
+	Emit(Event{
+		Type: "large_value",
+		Key:  eventKey(l.TxHash, l.Index),
+		Text: fmt.Sprintf("🚨 [Large %s] %s | 区块 %d | %s | 合约 %s",
+			kind, formatUSD(usd), l.BlockNumber, l.TxHash.Hex(), l.Address.Hex()),
+		Data: map[string]interface{}{
+			"kind": kind, "usd": usd, "block_number": l.BlockNumber, "tx_hash": l.TxHash, "address": l.Address,
+		},
+	})
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// 关注地址的来源
+const (
+	WatchSourceConfig = "config" // 配置文件中的 watch
+	WatchSourceShadow = "shadow" // 运行中由分析器自动发现（影子关注列表）
+	WatchSourceAPI    = "api"    // 运行中通过管理接口添加，热加载时保留
+)
+
+// Watchlist 线程安全的关注地址集合
+// 分析器在各自的 goroutine 中读写，所以不能直接用 map
+type Watchlist struct {
+	mu      sync.RWMutex
+	addrs   map[common.Address]string // 地址 -> 来源
+	version atomic.Uint64             // 每次增删加 1，预筛等缓存据此判断是否需要重建
+}
+
+func NewWatchlist(addrs []common.Address) *Watchlist {
+	w := &Watchlist{addrs: make(map[common.Address]string, len(addrs))}
+	for _, a := range addrs {
+		w.addrs[a] = WatchSourceConfig
+	}
+	return w
+}
+
+// Contains 地址是否在关注列表中（包括影子关注）
+func (w *Watchlist) Contains(addr common.Address) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	_, ok := w.addrs[addr]
+	return ok
+}
+
+// Source 返回地址的来源，不在列表中返回空字符串
+func (w *Watchlist) Source(addr common.Address) string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.addrs[addr]
+}
+
+// AddShadow 把地址加入影子关注列表，地址已存在时返回 false
+func (w *Watchlist) AddShadow(addr common.Address) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.addrs[addr]; ok {
+		return false
+	}
+	w.addrs[addr] = WatchSourceShadow
+	w.version.Add(1)
+	return true
+}
+
+// Len 关注地址总数
+func (w *Watchlist) Len() int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return len(w.addrs)
+}
+
+// CountShadow 影子关注地址数量
+func (w *Watchlist) CountShadow() int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	n := 0
+	for _, src := range w.addrs {
+		if src == WatchSourceShadow {
+			n++
+		}
+	}
+	return n
+}
+
+// BySource 指定来源的全部地址
+func (w *Watchlist) BySource(source string) []common.Address {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	var list []common.Address
+	for addr, src := range w.addrs {
+		if src == source {
+			list = append(list, addr)
+		}
+	}
+	return list
+}
+
+// ReplaceConfig 用新的配置地址替换来源为 config 的地址（热加载），影子关注的地址保留；
+// 新配置中包含已经是影子关注的地址时改记为 config
+func (w *Watchlist) ReplaceConfig(addrs []common.Address) (added, removed int) {
+	next := make(map[common.Address]bool, len(addrs))
+	for _, a := range addrs {
+		next[a] = true
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for addr, src := range w.addrs {
+		if src == WatchSourceConfig && !next[addr] {
+			delete(w.addrs, addr)
+			removed++
+		}
+	}
+	for addr := range next {
+		if w.addrs[addr] != WatchSourceConfig {
+			w.addrs[addr] = WatchSourceConfig
+			added++
+		}
+	}
+	if added+removed > 0 {
+		w.version.Add(1)
+	}
+	return added, removed
+}
+
+// Add 以指定来源加入地址，地址已存在时返回 false（来源不变）
+func (w *Watchlist) Add(addr common.Address, source string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.addrs[addr]; ok {
+		return false
+	}
+	w.addrs[addr] = source
+	w.version.Add(1)
+	return true
+}
+
+// Remove 移除地址，返回原来的来源，不在列表中返回空字符串
+func (w *Watchlist) Remove(addr common.Address) string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	src, ok := w.addrs[addr]
+	if ok {
+		delete(w.addrs, addr)
+		w.version.Add(1)
+	}
+	return src
+}
+
+// Version 关注列表的版本号，地址有增删时变化
+func (w *Watchlist) Version() uint64 {
+	return w.version.Load()
+}
+
+// Snapshot 全部地址及来源的副本
+func (w *Watchlist) Snapshot() map[common.Address]string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	out := make(map[common.Address]string, len(w.addrs))
+	for addr, src := range w.addrs {
+		out[addr] = src
+	}
+	return out
+}
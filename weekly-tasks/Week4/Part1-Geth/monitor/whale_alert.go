@@ -0,0 +1,216 @@
+package main
+
+// ------------------------------------------------
+// 大额交易提醒：交易池或区块中单笔交易转移的 ETH 超过阈值（数量或美元价值）时立即提醒，
+// 附带发送 / 接收方是否关注地址、调用的函数和美元价值。与定期输出的巨鲸报告（whale_report.go）互相独立
+// ------------------------------------------------
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	// 默认超过阈值多少倍时提醒级别为 critical
+	DefaultWhaleCriticalMultiple = 10
+)
+
+// 大额交易所处的阶段
+const (
+	WhaleStagePending = "pending"
+	WhaleStageMined   = "mined"
+)
+
+// WhaleAlertConfig 单笔大额交易提醒，min_eth 和 min_usd 满足任意一个即提醒，都为 0 时关闭
+type WhaleAlertConfig struct {
+	MinETH           float64 `json:"min_eth"`           // 交易附带的 ETH 下限
+	MinUSD           float64 `json:"min_usd"`           // 交易附带 ETH 的美元价值下限，ETH 价格未就绪时不按美元判断
+	Pending          bool    `json:"pending"`           // 同时检查交易池中的交易
+	CriticalMultiple float64 `json:"critical_multiple"` // 超过阈值的倍数达到该值时级别为 critical，默认 DefaultWhaleCriticalMultiple
+	Filter           string  `json:"filter"`            // 只提醒同时满足该过滤链的交易，为空不限
+}
+
+// Enabled 是否配置了阈值
+func (c WhaleAlertConfig) Enabled() bool {
+	return c.MinETH > 0 || c.MinUSD > 0
+}
+
+// WhaleTx 一笔大额交易
+type WhaleTx struct {
+	Stage     string          `json:"stage"`
+	Hash      common.Hash     `json:"hash"`
+	From      common.Address  `json:"from"`
+	To        *common.Address `json:"to"` // 创建合约时为 nil
+	ValueETH  float64         `json:"value_eth"`
+	USD       float64         `json:"usd,omitempty"` // ETH 价格未就绪时为 0
+	Method    string          `json:"method,omitempty"`
+	Block     uint64          `json:"block,omitempty"`
+	FromWatch string          `json:"from_watch,omitempty"` // 发送方在关注列表中的来源
+	ToWatch   string          `json:"to_watch,omitempty"`
+
+	severity string
+	tx       *types.Transaction
+}
+
+// WhaleAlert 按交易附带的 ETH 判断大额交易
+type WhaleAlert struct {
+	mu     sync.RWMutex
+	cfg    WhaleAlertConfig // 阈值和过滤链可以热加载
+	minWei *big.Int         // min_eth 换算的 wei，未配置时为 nil
+
+	values  *Valuator
+	abis    *ABIRegistry
+	watch   *Watchlist
+	signer  types.Signer
+	onAlert func(WhaleTx)
+}
+
+// NewWhaleAlert 创建大额交易提醒；onAlert 为 nil 时使用默认输出
+func NewWhaleAlert(cfg WhaleAlertConfig, values *Valuator, abis *ABIRegistry, watch *Watchlist, signer types.Signer, onAlert func(WhaleTx)) *WhaleAlert {
+	if onAlert == nil {
+		onAlert = PrintWhaleTx
+	}
+	a := &WhaleAlert{values: values, abis: abis, watch: watch, signer: signer, onAlert: onAlert}
+	a.SetConfig(cfg)
+	return a
+}
+
+// SetConfig 修改阈值和过滤链；pending 决定是否订阅交易池，只在创建时生效
+func (a *WhaleAlert) SetConfig(cfg WhaleAlertConfig) {
+	if cfg.CriticalMultiple <= 0 {
+		cfg.CriticalMultiple = DefaultWhaleCriticalMultiple
+	}
+	var minWei *big.Int
+	if cfg.MinETH > 0 {
+		minWei, _ = new(big.Float).Mul(big.NewFloat(cfg.MinETH), big.NewFloat(params.Ether)).Int(nil)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cfg, a.minWei = cfg, minWei
+}
+
+// config 当前的配置和 min_eth 换算的 wei
+func (a *WhaleAlert) config() (WhaleAlertConfig, *big.Int) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.cfg, a.minWei
+}
+
+// Enrichment 只需要交易和发送者
+func (a *WhaleAlert) Enrichment() EnrichLevel { return EnrichTransactions }
+
+// OnBlock 检查区块中的交易
+func (a *WhaleAlert) OnBlock(data *BlockData) {
+	for i, tx := range data.Block.Transactions() {
+		if w, ok := a.check(tx, data.Senders[i]); ok {
+			w.Stage, w.Block = WhaleStageMined, data.Block.NumberU64()
+			a.onAlert(w)
+		}
+	}
+}
+
+// Observe 检查交易池中的完整交易，作为事件总线的订阅者调用
+// 先按金额判断，绝大多数交易不需要恢复发送者
+func (a *WhaleAlert) Observe(msg BusMessage) {
+	if msg.Tx == nil {
+		return
+	}
+	cfg, minWei := a.config()
+	if _, _, large := a.value(cfg, minWei, msg.Tx.Value()); !large {
+		return
+	}
+	from, err := types.Sender(a.signer, msg.Tx)
+	if err != nil {
+		return
+	}
+	if w, ok := a.check(msg.Tx, from); ok {
+		w.Stage = WhaleStagePending
+		a.onAlert(w)
+	}
+}
+
+// value 金额是否超过阈值，同时返回超过的倍数（取 ETH 和美元中较大的）和美元价值
+func (a *WhaleAlert) value(cfg WhaleAlertConfig, minWei, wei *big.Int) (usd, multiple float64, large bool) {
+	if wei.Sign() == 0 {
+		return 0, 0, false
+	}
+	if minWei != nil && wei.Cmp(minWei) >= 0 {
+		large, multiple = true, weiToEther(wei)/cfg.MinETH
+	}
+	usd, priced := a.values.ETHValue(wei)
+	if priced && cfg.MinUSD > 0 && usd >= cfg.MinUSD {
+		large = true
+		if m := usd / cfg.MinUSD; m > multiple {
+			multiple = m
+		}
+	}
+	return usd, multiple, large
+}
+
+// check 生成大额交易的提醒内容
+func (a *WhaleAlert) check(tx *types.Transaction, from common.Address) (WhaleTx, bool) {
+	cfg, minWei := a.config()
+	usd, multiple, large := a.value(cfg, minWei, tx.Value())
+	if !large {
+		return WhaleTx{}, false
+	}
+	if cfg.Filter != "" && !currentFilters().Match(cfg.Filter, tx, &from) {
+		return WhaleTx{}, false
+	}
+	w := WhaleTx{
+		Hash:      tx.Hash(),
+		From:      from,
+		To:        tx.To(),
+		ValueETH:  weiToEther(tx.Value()),
+		USD:       usd,
+		FromWatch: a.watch.Source(from),
+		severity:  SeverityWarn,
+		tx:        tx,
+	}
+	if w.To != nil {
+		w.ToWatch = a.watch.Source(*w.To)
+	}
+	if data := tx.Data(); len(data) >= 4 {
+		w.Method = fmt.Sprintf("%#x", data[:4])
+		if call, ok := a.abis.DecodeCall(tx.To(), data); ok && call.Name != "" {
+			w.Method = call.Name
+		}
+	}
+	if multiple >= cfg.CriticalMultiple {
+		w.severity = SeverityCritical
+	}
+	return w, true
+}
+
+// PrintWhaleTx 默认的大额交易提醒
+func PrintWhaleTx(w WhaleTx) {
+	to := "(创建合约)"
+	if w.To != nil {
+		to = w.To.Hex()
+	}
+	where := "交易池"
+	if w.Stage == WhaleStageMined {
+		where = fmt.Sprintf("区块 %d", w.Block)
+	}
+	amount := fmt.Sprintf("%.4f ETH", w.ValueETH)
+	if w.USD > 0 {
+		amount += " (" + formatUSD(w.USD) + ")"
+	}
+	summary := fmt.Sprintf("🐋 [Whale] %s | %s | %s -> %s | %s", where, amount, w.From.Hex(), to, w.Hash.Hex())
+	text := summary
+	if w.Method != "" {
+		text += fmt.Sprintf("\n   🧾 调用 %s", w.Method)
+	}
+	if w.FromWatch != "" {
+		text += fmt.Sprintf("\n   👀 发送方是关注地址 (%s)", w.FromWatch)
+	}
+	if w.ToWatch != "" {
+		text += fmt.Sprintf("\n   👀 接收方是关注地址 (%s)", w.ToWatch)
+	}
+	Emit(Event{Type: "whale_tx", Key: eventKey(w.Stage, w.Hash), Summary: summary, Text: text, Data: w, Severity: w.severity, tx: w.tx, txFrom: &w.From})
+}
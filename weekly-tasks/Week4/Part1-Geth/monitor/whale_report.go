@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	// 默认统计窗口（区块数），300 个区块约 1 小时
+	DefaultWhaleWindow = 300
+	// 默认每隔多少个区块输出一次巨鲸报告
+	DefaultWhaleReportEvery = 50
+	// 默认报告中每个榜单的条目数
+	DefaultWhaleTop = 10
+)
+
+// SpenderStats 一个发送者在窗口内的汇总
+type SpenderStats struct {
+	From    common.Address
+	Value   *big.Int // 转出的 ETH（wei）
+	Fees    *big.Int // 支付的手续费（wei），包括 blob 费用
+	TxCount int
+}
+
+func newSpenderStats(from common.Address) *SpenderStats {
+	return &SpenderStats{From: from, Value: new(big.Int), Fees: new(big.Int)}
+}
+
+// WhaleReport 一次巨鲸报告
+type WhaleReport struct {
+	FromBlock, ToBlock uint64
+	TopByValue         []SpenderStats
+	TopByFees          []SpenderStats
+	NewShadow          []common.Address // 本周期新加入影子关注列表的地址
+}
+
+// WhaleTracker 按发送者聚合最近窗口内转出的 ETH 和支付的手续费，定期输出巨鲸报告；
+// 可选地把窗口内转出超过阈值的新地址自动加入影子关注列表
+type WhaleTracker struct {
+	cfg      WhaleConfig
+	watch    *Watchlist
+	onReport func(WhaleReport)
+
+	mu        sync.Mutex
+	blocks    []map[common.Address]*SpenderStats // 窗口内每个区块的聚合，最旧的在前
+	numbers   []uint64
+	totals    map[common.Address]*SpenderStats
+	newShadow []common.Address
+	counter   int
+}
+
+// NewWhaleTracker 创建巨鲸统计，onReport 为 nil 时直接打印报告
+func NewWhaleTracker(cfg WhaleConfig, watch *Watchlist, onReport func(WhaleReport)) *WhaleTracker {
+	if onReport == nil {
+		onReport = PrintWhaleReport
+	}
+	return &WhaleTracker{
+		cfg:      cfg,
+		watch:    watch,
+		onReport: onReport,
+		totals:   make(map[common.Address]*SpenderStats),
+	}
+}
+
+// Warm 实现 Warmer：只填充滚动窗口，不发现影子地址、不计入报告周期
+func (w *WhaleTracker) Warm(data *BlockData) {
+	w.mu.Lock()
+	w.push(spenderUsage(data), data.Block.NumberU64())
+	w.mu.Unlock()
+}
+
+// OnBlock 实现 BlockAnalyzer
+func (w *WhaleTracker) OnBlock(data *BlockData) {
+	usage := spenderUsage(data)
+
+	w.mu.Lock()
+	w.push(usage, data.Block.NumberU64())
+	w.discoverShadow(usage)
+	w.counter++
+	var report *WhaleReport
+	if w.counter%w.cfg.ReportEvery == 0 {
+		report = w.buildReport()
+	}
+	w.mu.Unlock()
+
+	if report != nil {
+		w.onReport(*report)
+	}
+}
+
+// push 把一个区块加入滚动窗口，移出最旧的区块（调用方持有锁）
+func (w *WhaleTracker) push(usage map[common.Address]*SpenderStats, number uint64) {
+	w.apply(usage, 1)
+	w.blocks = append(w.blocks, usage)
+	w.numbers = append(w.numbers, number)
+	if len(w.blocks) > w.cfg.Window {
+		w.apply(w.blocks[0], -1)
+		w.blocks, w.numbers = w.blocks[1:], w.numbers[1:]
+	}
+}
+
+// spenderUsage 按发送者汇总一个区块的转出金额、手续费和交易数
+func spenderUsage(data *BlockData) map[common.Address]*SpenderStats {
+	usage := make(map[common.Address]*SpenderStats)
+	for i, tx := range data.Block.Transactions() {
+		from := data.Senders[i]
+		s, ok := usage[from]
+		if !ok {
+			s = newSpenderStats(from)
+			usage[from] = s
+		}
+		s.Value.Add(s.Value, tx.Value())
+		s.Fees.Add(s.Fees, txFee(data.Receipts[i]))
+		s.TxCount++
+	}
+	return usage
+}
+
+// txFee 交易实际支付的手续费 = gasUsed * effectiveGasPrice + blobGasUsed * blobGasPrice
+func txFee(r *types.Receipt) *big.Int {
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(r.GasUsed), r.EffectiveGasPrice)
+	if r.BlobGasUsed > 0 && r.BlobGasPrice != nil {
+		fee.Add(fee, new(big.Int).Mul(new(big.Int).SetUint64(r.BlobGasUsed), r.BlobGasPrice))
+	}
+	return fee
+}
+
+// apply 把一个区块的聚合加到（sign=1）或减出（sign=-1）总量（调用方持有锁）
+func (w *WhaleTracker) apply(usage map[common.Address]*SpenderStats, sign int) {
+	for from, s := range usage {
+		t, ok := w.totals[from]
+		if !ok {
+			t = newSpenderStats(from)
+			w.totals[from] = t
+		}
+		if sign > 0 {
+			t.Value.Add(t.Value, s.Value)
+			t.Fees.Add(t.Fees, s.Fees)
+			t.TxCount += s.TxCount
+		} else {
+			t.Value.Sub(t.Value, s.Value)
+			t.Fees.Sub(t.Fees, s.Fees)
+			t.TxCount -= s.TxCount
+		}
+		if t.TxCount == 0 {
+			delete(w.totals, from)
+		}
+	}
+}
+
+// discoverShadow 本区块出现的发送者中，窗口内转出超过阈值的加入影子关注列表（调用方持有锁）
+func (w *WhaleTracker) discoverShadow(usage map[common.Address]*SpenderStats) {
+	if w.cfg.ShadowThresholdETH <= 0 || w.watch == nil {
+		return
+	}
+	threshold, _ := new(big.Float).Mul(big.NewFloat(w.cfg.ShadowThresholdETH), big.NewFloat(params.Ether)).Int(nil)
+	for from := range usage {
+		t := w.totals[from]
+		if t == nil || t.Value.Cmp(threshold) < 0 {
+			continue
+		}
+		if w.watch.AddShadow(from) {
+			w.newShadow = append(w.newShadow, from)
+			log.Printf("🐋 新发现巨鲸 %s：近 %d 个区块转出 %.2f ETH，已加入影子关注列表",
+				from.Hex(), len(w.blocks), weiToEther(t.Value))
+		}
+	}
+}
+
+// buildReport 生成报告并清空本周期的新影子地址（调用方持有锁）
+func (w *WhaleTracker) buildReport() *WhaleReport {
+	all := make([]SpenderStats, 0, len(w.totals))
+	for _, s := range w.totals {
+		all = append(all, SpenderStats{From: s.From, Value: new(big.Int).Set(s.Value), Fees: new(big.Int).Set(s.Fees), TxCount: s.TxCount})
+	}
+	top := func(less func(a, b SpenderStats) bool) []SpenderStats {
+		sorted := append([]SpenderStats(nil), all...)
+		sort.Slice(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+		if len(sorted) > w.cfg.Top {
+			sorted = sorted[:w.cfg.Top]
+		}
+		return sorted
+	}
+	report := &WhaleReport{
+		FromBlock:  w.numbers[0],
+		ToBlock:    w.numbers[len(w.numbers)-1],
+		TopByValue: top(func(a, b SpenderStats) bool { return a.Value.Cmp(b.Value) > 0 }),
+		TopByFees:  top(func(a, b SpenderStats) bool { return a.Fees.Cmp(b.Fees) > 0 }),
+		NewShadow:  w.newShadow,
+	}
+	w.newShadow = nil
+	return report
+}
+
+// PrintWhaleReport 打印巨鲸报告
+func PrintWhaleReport(r WhaleReport) {
+	var sb strings.Builder
+	summary := fmt.Sprintf("🐋 [Whale Report] 区块 %d - %d", r.FromBlock, r.ToBlock)
+	sb.WriteString("\n" + summary + "\n")
+	sb.WriteString("   转出 ETH 最多:\n")
+	for i, s := range r.TopByValue {
+		if s.Value.Sign() == 0 {
+			break
+		}
+		fmt.Fprintf(&sb, "   %2d. %s %12.4f ETH  %4d 笔\n", i+1, s.From.Hex(), weiToEther(s.Value), s.TxCount)
+	}
+	sb.WriteString("   支付手续费最多:\n")
+	for i, s := range r.TopByFees {
+		fmt.Fprintf(&sb, "   %2d. %s %12.6f ETH  %4d 笔\n", i+1, s.From.Hex(), weiToEther(s.Fees), s.TxCount)
+	}
+	if len(r.NewShadow) > 0 {
+		fmt.Fprintf(&sb, "   本周期新加入影子关注列表: %d 个地址\n", len(r.NewShadow))
+	}
+	Emit(Event{Type: "whale_report", Summary: summary, Text: sb.String(), Data: r})
+}